@@ -0,0 +1,134 @@
+/*
+Copyright © 2025 Joshua Pare <jpare@omniview.dev>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package cmd
+
+import (
+	"strings"
+
+	"github.com/omniviewdev/registry-cli/pkg"
+	"github.com/omniviewdev/registry-cli/pkg/console"
+	"github.com/spf13/cobra"
+)
+
+// accessCmd represents the access command
+var accessCmd = &cobra.Command{
+	Use:   "access",
+	Short: "Manage who may see and install private plugins",
+	Long: `Manage the access lists of plugins, the principals allowed to see and install them. A
+plugin with an access list is private: 'registry-cli serve' only serves it to the principals on
+the list, and 'registry-cli presign' only presigns its builds for them, so public and private
+plugins can share a bucket:
+
+  registry-cli access set acme-billing team-payments team-finance -b my-registry
+
+The access list is recorded in the indexes, which stay readable by anyone who can read the
+bucket, so private plugins need a bucket only the proxy (and the publishers) can read.`,
+}
+
+// accessSetCmd represents the access set command
+var accessSetCmd = &cobra.Command{
+	Use:   "set [plugin] [principal]...",
+	Short: "Make a plugin private to the given principals",
+	Args:  cobra.MinimumNArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		indexer, err := newAccessIndexer(cmd)
+		if err != nil {
+			return err
+		}
+		access, err := indexer.SetAccess(cmd.Context(), args[0], args[1:])
+		if err != nil {
+			return err
+		}
+		console.Printf("✅ %s is private to %s\n", args[0], strings.Join(access, ", "))
+		return nil
+	},
+}
+
+// accessPublicCmd represents the access public command
+var accessPublicCmd = &cobra.Command{
+	Use:   "public [plugin]",
+	Short: "Make a private plugin public again",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		indexer, err := newAccessIndexer(cmd)
+		if err != nil {
+			return err
+		}
+		if _, err := indexer.SetAccess(cmd.Context(), args[0], nil); err != nil {
+			return err
+		}
+		console.Printf("✅ %s is public\n", args[0])
+		return nil
+	},
+}
+
+// accessListCmd represents the access list command
+var accessListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the private plugins and their access lists",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		indexer, err := newAccessIndexer(cmd)
+		if err != nil {
+			return err
+		}
+		private, err := indexer.PrivatePlugins(cmd.Context())
+		if err != nil {
+			return err
+		}
+		if len(private) == 0 {
+			console.Println("All plugins are public")
+			return nil
+		}
+		for _, plugin := range private {
+			console.Printf("%s  %s\n", plugin.ID, strings.Join(plugin.Access, ", "))
+		}
+		return nil
+	},
+}
+
+func newAccessIndexer(cmd *cobra.Command) (*pkg.Indexer, error) {
+	return pkg.NewIndexer(cmd.Context(), pkg.IndexerOpts{
+		Bucket:     bucket,
+		Provider:   provider,
+		Endpoint:   endpoint,
+		Pacing:     pacing,
+		SigningKey: signingKey,
+		LockMode:   lockMode,
+		LockTable:  lockTable,
+		IndexTable: indexTable,
+		Layout:     layout,
+	})
+}
+
+func init() {
+	rootCmd.AddCommand(accessCmd)
+	accessCmd.AddCommand(accessSetCmd)
+	accessCmd.AddCommand(accessPublicCmd)
+	accessCmd.AddCommand(accessListCmd)
+
+	accessCmd.PersistentFlags().
+		StringVarP(&bucket, "bucket", "b", "", "bucket serving the registry")
+	accessCmd.PersistentFlags().
+		StringVar(&signingKey, "signing-key", "", "path to the key to sign indexes with (or REGISTRY_SIGNING_KEY)")
+	accessCmd.PersistentFlags().
+		StringVar(&lockMode, "lock", "", "lock the indexes while updating them, with 's3' or 'dynamodb' (or REGISTRY_LOCK)")
+	accessCmd.PersistentFlags().
+		StringVar(&lockTable, "lock-table", "", "DynamoDB table to hold the lock in with --lock dynamodb (or REGISTRY_LOCK_TABLE)")
+	accessCmd.PersistentFlags().
+		StringVar(&indexTable, "index-table", "", "DynamoDB table holding the index records, materialized into the bucket indexes (or REGISTRY_INDEX_TABLE)")
+}
@@ -0,0 +1,126 @@
+/*
+Copyright © 2025 Joshua Pare <jpare@omniview.dev>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/omniviewdev/registry-cli/pkg"
+	"github.com/omniviewdev/registry-cli/pkg/console"
+	"github.com/spf13/cobra"
+)
+
+var (
+	benchSize          string
+	benchPartSizes     []string
+	benchConcurrencies []int
+)
+
+// benchUploadCmd represents the bench upload command
+var benchUploadCmd = &cobra.Command{
+	Use:   "upload",
+	Short: "Measure the upload throughput to the bucket and recommend publish settings",
+	Long: `Upload an object of --size random bytes to the bucket in a single request, and in
+parts of each of --part-sizes with each of --concurrency parts at once, measuring the
+throughput of each. The objects are uploaded under bench/ and deleted once measured.
+
+  registry-cli bench upload --bucket my-registry --size 500MB
+
+The fastest setting is recommended as the part size and concurrency to publish with. Settings
+within 5% of the fastest are as good, of which the one with the fewest requests at once wins.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		size, err := pkg.ParseSize(benchSize)
+		if err != nil {
+			return fmt.Errorf("Invalid --size: %w", err)
+		}
+		partSizes := make([]int64, 0, len(benchPartSizes))
+		for _, s := range benchPartSizes {
+			partSize, err := pkg.ParseSize(s)
+			if err != nil {
+				return fmt.Errorf("Invalid --part-sizes: %w", err)
+			}
+			partSizes = append(partSizes, partSize)
+		}
+
+		publisher, err := pkg.NewPublisher(cmd.Context(), pkg.PublisherOpts{
+			Bucket:   bucket,
+			Provider: provider,
+			Endpoint: endpoint,
+			Pacing:   pacing,
+			Layout:   layout,
+		})
+		if err != nil {
+			return err
+		}
+
+		results, err := publisher.BenchUpload(cmd.Context(), pkg.BenchOpts{
+			Size:          size,
+			PartSizes:     partSizes,
+			Concurrencies: benchConcurrencies,
+		})
+		if err != nil {
+			return err
+		}
+
+		console.Printf("\n%-12s %12s %10s %14s\n", "PART SIZE", "CONCURRENCY", "TIME", "THROUGHPUT")
+		for _, result := range results {
+			part := "single"
+			if result.PartSize > 0 {
+				part = formatBytes(result.PartSize)
+			}
+			if result.Err != nil {
+				console.Printf("%-12s %12d ❌ %v\n", part, result.Concurrency, result.Err)
+				continue
+			}
+			console.Printf("%-12s %12d %10s %14s\n",
+				part, result.Concurrency, result.Duration.Round(time.Millisecond),
+				formatBytes(int64(result.Throughput))+"/s")
+		}
+
+		best, ok := pkg.BestBenchResult(results)
+		if !ok {
+			return fmt.Errorf("Every upload failed, check access to the bucket")
+		}
+		console.Println()
+		if best.PartSize == 0 {
+			console.Printf("✅ Uploads are fastest in a single request (%s/s), the default: "+
+				"leave --part-size unset\n", formatBytes(int64(best.Throughput)))
+			return nil
+		}
+		console.Printf("✅ Uploads are fastest in parts of %s, %d at once (%s/s). Publish with:\n\n",
+			formatBytes(best.PartSize), best.Concurrency, formatBytes(int64(best.Throughput)))
+		console.Printf("  --part-size %d --upload-concurrency %d\n\n", best.PartSize, best.Concurrency)
+		console.Println("or set them in the config file:")
+		console.Printf("\n  upload_part_size: %d\n  upload_concurrency: %d\n",
+			best.PartSize, best.Concurrency)
+		return nil
+	},
+}
+
+func init() {
+	benchCmd.AddCommand(benchUploadCmd)
+
+	benchUploadCmd.Flags().StringVarP(&bucket, "bucket", "b", "", "bucket to upload to")
+	benchUploadCmd.Flags().
+		StringVar(&benchSize, "size", "100MB", "size of the object to upload with each setting, e.g. 500MB")
+	benchUploadCmd.Flags().
+		StringSliceVar(&benchPartSizes, "part-sizes", []string{"8MiB", "16MiB", "64MiB"}, "part sizes to upload in")
+	benchUploadCmd.Flags().
+		IntSliceVar(&benchConcurrencies, "concurrency", []int{2, 5, 10}, "parts to upload at once, tried with each part size")
+}
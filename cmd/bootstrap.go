@@ -0,0 +1,90 @@
+/*
+Copyright © 2025 Joshua Pare <jpare@omniview.dev>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package cmd
+
+import (
+	"github.com/omniviewdev/registry-cli/pkg"
+	"github.com/omniviewdev/registry-cli/pkg/console"
+	"github.com/spf13/cobra"
+)
+
+var (
+	bootstrapAccess          string
+	bootstrapDistributionARN string
+	bootstrapCORSOrigins     []string
+	bootstrapNoncurrentDays  int32
+)
+
+// bootstrapCmd represents the bootstrap command
+var bootstrapCmd = &cobra.Command{
+	Use:   "bootstrap",
+	Short: "Provision the bucket for a new registry",
+	Long: `Create and configure a bucket to host a registry: versioning, read access, CORS,
+lifecycle rules and an empty (signed) registry index.
+
+  registry-cli bootstrap --bucket my-registry --signing-key registry.key
+
+By default the registry is served publicly from the bucket. To serve it through CloudFront
+instead, create a distribution with an origin access control (OAC) for the bucket and pass its
+ARN; the bucket stays private and only the distribution can read it:
+
+  registry-cli bootstrap --bucket my-registry --access cloudfront \
+    --distribution-arn arn:aws:cloudfront::123456789012:distribution/EDFDVBD6EXAMPLE
+
+Bootstrapping an existing registry reapplies the configuration and keeps its index.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		indexer, err := pkg.NewIndexer(cmd.Context(), pkg.IndexerOpts{
+			Bucket:     bucket,
+			Provider:   provider,
+			Endpoint:   endpoint,
+			Pacing:     pacing,
+			SigningKey: signingKey,
+			Layout:     layout,
+		})
+		if err != nil {
+			return err
+		}
+
+		if err := indexer.Bootstrap(cmd.Context(), pkg.BootstrapOpts{
+			Access:                bootstrapAccess,
+			DistributionARN:       bootstrapDistributionARN,
+			CORSOrigins:           bootstrapCORSOrigins,
+			NoncurrentVersionDays: bootstrapNoncurrentDays,
+		}); err != nil {
+			return err
+		}
+		console.Printf("✅ Registry bucket %s is ready\n", bucket)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(bootstrapCmd)
+
+	bootstrapCmd.Flags().StringVarP(&bucket, "bucket", "b", "", "bucket to create the registry in")
+	bootstrapCmd.Flags().
+		StringVar(&signingKey, "signing-key", "", "path to the key to sign the registry index with (or REGISTRY_SIGNING_KEY)")
+	bootstrapCmd.Flags().
+		StringVar(&bootstrapAccess, "access", pkg.AccessPublic, "how the registry is served, 'public' or 'cloudfront'")
+	bootstrapCmd.Flags().
+		StringVar(&bootstrapDistributionARN, "distribution-arn", "", "CloudFront distribution allowed to read the bucket with --access cloudfront")
+	bootstrapCmd.Flags().
+		StringSliceVar(&bootstrapCORSOrigins, "cors-origin", nil, "origins allowed to read the registry from a browser (default any)")
+	bootstrapCmd.Flags().
+		Int32Var(&bootstrapNoncurrentDays, "noncurrent-days", pkg.DefaultNoncurrentVersionDays, "days to keep overwritten versions of objects for")
+}
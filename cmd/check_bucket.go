@@ -0,0 +1,91 @@
+/*
+Copyright © 2025 Joshua Pare <jpare@omniview.dev>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/omniviewdev/registry-cli/pkg"
+	"github.com/omniviewdev/registry-cli/pkg/console"
+	"github.com/spf13/cobra"
+)
+
+var checkBucketOrigins []string
+
+// checkBucketCmd represents the check-bucket command
+var checkBucketCmd = &cobra.Command{
+	Use:   "check-bucket",
+	Short: "Audit the registry bucket configuration",
+	Long: `Inspect the registry bucket for the settings clients need (read access and CORS for
+browsers) and the ones that keep it safe to operate (versioning and encryption), reporting any
+gaps along with how to fix them:
+
+  registry-cli check-bucket --bucket my-registry --origin https://omniview.example.com
+
+Exits with an error when a required setting is missing.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		indexer, err := pkg.NewIndexer(cmd.Context(), pkg.IndexerOpts{
+			Bucket:   bucket,
+			Provider: provider,
+			Endpoint: endpoint,
+			Pacing:   pacing,
+			Layout:   layout,
+		})
+		if err != nil {
+			return err
+		}
+
+		findings, err := indexer.AuditBucket(cmd.Context(), pkg.AuditOpts{
+			Origins: checkBucketOrigins,
+		})
+		if err != nil {
+			return err
+		}
+
+		var problems, warnings int
+		for _, finding := range findings {
+			switch finding.Status {
+			case pkg.FindingOK:
+				console.Printf("✅ %s: %s\n", finding.Check, finding.Message)
+				continue
+			case pkg.FindingWarning:
+				warnings++
+				console.Printf("⚠️  %s: %s\n", finding.Check, finding.Message)
+			default:
+				problems++
+				console.Printf("❌ %s: %s\n", finding.Check, finding.Message)
+			}
+			if finding.Remediation != "" {
+				console.Printf("   fix: %s\n", finding.Remediation)
+			}
+		}
+
+		if problems > 0 {
+			return fmt.Errorf("Bucket %s has %d problems and %d warnings", bucket, problems, warnings)
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(checkBucketCmd)
+
+	checkBucketCmd.Flags().StringVarP(&bucket, "bucket", "b", "", "bucket to check")
+	checkBucketCmd.Flags().
+		StringSliceVar(&checkBucketOrigins, "origin", nil, "browser origins that must be able to read the registry (default any)")
+}
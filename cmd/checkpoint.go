@@ -0,0 +1,57 @@
+/*
+Copyright © 2025 Joshua Pare <jpare@omniview.dev>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package cmd
+
+import (
+	"github.com/omniviewdev/registry-cli/pkg"
+	"github.com/omniviewdev/registry-cli/pkg/console"
+)
+
+var (
+	resume    bool
+	stateFile string
+)
+
+// openCheckpoint opens the state file of a run of a bulk operation, at --state-file or at the
+// default path for the run.
+func openCheckpoint(operation string, run ...string) (*pkg.Checkpoint, error) {
+	path := stateFile
+	if path == "" {
+		path = pkg.CheckpointPath(operation, run...)
+	}
+	checkpoint, err := pkg.OpenCheckpoint(path, operation, run, resume)
+	if err != nil {
+		return nil, err
+	}
+	if n := checkpoint.Resumed(); n > 0 {
+		console.Printf("Resuming from %s (%d steps already done)\n", checkpoint.Path(), n)
+	}
+	return checkpoint, nil
+}
+
+// finishCheckpoint removes the state file once the run succeeded, or points at --resume when it
+// failed.
+func finishCheckpoint(checkpoint *pkg.Checkpoint, err error) error {
+	if err != nil {
+		console.Printf(
+			"⚠️ Progress was saved to %s, rerun with --resume to carry on\n",
+			checkpoint.Path(),
+		)
+		return err
+	}
+	return checkpoint.Finish()
+}
@@ -0,0 +1,89 @@
+/*
+Copyright © 2025 Joshua Pare <jpare@omniview.dev>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/omniviewdev/registry-cli/pkg/console"
+	"github.com/omniviewdev/registry-cli/pkg/packager"
+	"github.com/omniviewdev/registry-cli/pkg/types"
+	"github.com/spf13/cobra"
+)
+
+var (
+	convertTo  string
+	convertOut string
+)
+
+// convertCmd represents the meta convert command
+var convertCmd = &cobra.Command{
+	Use:   "convert [file]",
+	Short: "Convert plugin metadata between YAML and JSON",
+	Long: `Convert a plugin metadata file between the YAML and JSON formats. Field order is
+preserved, as are comments when converting to YAML. Reads the plugin metadata file found in
+the current directory (or its parents) when no file is given, and writes to stdout unless
+--out is set.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var path string
+		if len(args) > 0 {
+			path = args[0]
+		} else {
+			found, err := packager.FindMetadataFile(".")
+			if err != nil {
+				return err
+			}
+			path = found
+		}
+
+		to, err := types.ParseMetaFormat(convertTo)
+		if err != nil {
+			return err
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read metadata file: %w", err)
+		}
+
+		out, err := types.ConvertMetadata(data, to)
+		if err != nil {
+			return err
+		}
+
+		if convertOut == "" {
+			_, err = os.Stdout.Write(out)
+			return err
+		}
+		if err := os.WriteFile(convertOut, out, 0644); err != nil {
+			return fmt.Errorf("failed to write converted metadata: %w", err)
+		}
+		console.Printf("Converted %s → %s\n", path, convertOut)
+		return nil
+	},
+}
+
+func init() {
+	metaCmd.AddCommand(convertCmd)
+
+	convertCmd.Flags().
+		StringVarP(&convertTo, "to", "t", "json", "Format to convert to (json or yaml)")
+	convertCmd.Flags().
+		StringVarP(&convertOut, "out", "o", "", "Path to write the converted file to. Defaults to stdout")
+}
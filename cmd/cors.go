@@ -0,0 +1,111 @@
+/*
+Copyright © 2025 Joshua Pare <jpare@omniview.dev>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/omniviewdev/registry-cli/pkg"
+	"github.com/omniviewdev/registry-cli/pkg/console"
+	"github.com/spf13/cobra"
+)
+
+var corsOrigins []string
+
+// corsCmd represents the cors command
+var corsCmd = &cobra.Command{
+	Use:   "cors",
+	Short: "Apply or verify the CORS rules for browser-based clients",
+	Long: `Manage the CORS rules the Omniview web UI needs to fetch the indexes and tarballs
+straight from the bucket. Other CORS rules on the bucket are left alone.`,
+}
+
+// corsApplyCmd represents the cors apply command
+var corsApplyCmd = &cobra.Command{
+	Use:   "apply",
+	Short: "Apply the CORS rules to the bucket",
+	Long: `Apply the CORS rule that lets browsers on the given origins read the registry:
+
+  registry-cli cors apply --bucket my-registry --origin https://omniview.example.com`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		indexer, err := pkg.NewIndexer(cmd.Context(), pkg.IndexerOpts{
+			Bucket:   bucket,
+			Provider: provider,
+			Endpoint: endpoint,
+			Pacing:   pacing,
+		})
+		if err != nil {
+			return err
+		}
+		if err := indexer.ApplyCORS(cmd.Context(), corsOrigins); err != nil {
+			return err
+		}
+		console.Printf("✅ Applied CORS rules to %s\n", bucket)
+		return nil
+	},
+}
+
+// corsVerifyCmd represents the cors verify command
+var corsVerifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Verify the CORS rules of the bucket",
+	Long: `Check that the CORS rules of the bucket let browsers on the given origins read the
+registry, listing anything that's missing:
+
+  registry-cli cors verify --bucket my-registry --origin https://omniview.example.com`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		indexer, err := pkg.NewIndexer(cmd.Context(), pkg.IndexerOpts{
+			Bucket:   bucket,
+			Provider: provider,
+			Endpoint: endpoint,
+			Pacing:   pacing,
+		})
+		if err != nil {
+			return err
+		}
+		gaps, err := indexer.VerifyCORS(cmd.Context(), corsOrigins)
+		if err != nil {
+			return err
+		}
+		if len(gaps) > 0 {
+			for _, gap := range gaps {
+				console.Printf("❌ %s\n", gap)
+			}
+			return fmt.Errorf("CORS rules of %s are incomplete, run 'registry-cli cors apply' to fix them", bucket)
+		}
+
+		origins := "any origin"
+		if len(corsOrigins) > 0 {
+			origins = strings.Join(corsOrigins, ", ")
+		}
+		console.Printf("✅ CORS rules allow the registry to be read from %s\n", origins)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(corsCmd)
+	corsCmd.AddCommand(corsApplyCmd)
+	corsCmd.AddCommand(corsVerifyCmd)
+
+	corsCmd.PersistentFlags().StringVarP(&bucket, "bucket", "b", "", "bucket serving the registry")
+	corsCmd.PersistentFlags().
+		StringSliceVar(&corsOrigins, "origin", nil, "origins the Omniview web UI is served from (default any)")
+}
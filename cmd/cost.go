@@ -0,0 +1,128 @@
+/*
+Copyright © 2025 Joshua Pare <jpare@omniview.dev>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/omniviewdev/registry-cli/pkg"
+	"github.com/omniviewdev/registry-cli/pkg/console"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var costDownloads int
+
+// costCmd represents the cost command
+var costCmd = &cobra.Command{
+	Use:   "cost",
+	Short: "Estimate the monthly storage and egress cost of the registry",
+	Long: `Sum the size of the objects in the bucket per plugin and storage class, and estimate
+the monthly cost of storing them and of serving --downloads downloads of the latest version of
+each plugin.
+
+Prices default to the S3 list prices of us-east-1, and can be overridden in the config file:
+
+  pricing:
+    egress: 0.09
+    storage:
+      STANDARD: 0.023
+      GLACIER_IR: 0.004`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		pricing := pkg.DefaultPricing()
+		if err := viper.UnmarshalKey("pricing", &pricing); err != nil {
+			return fmt.Errorf("Invalid pricing configuration: %w", err)
+		}
+
+		indexer, err := pkg.NewIndexer(cmd.Context(), pkg.IndexerOpts{
+			Bucket:   bucket,
+			Provider: provider,
+			Endpoint: endpoint,
+			Pacing:   pacing,
+			Layout:   layout,
+		})
+		if err != nil {
+			return err
+		}
+
+		usage, err := indexer.Usage(cmd.Context())
+		if err != nil {
+			return err
+		}
+
+		var totalBytes int64
+		var totalStorage, totalEgress float64
+
+		console.Printf("%-30s %8s %12s %-30s %10s %10s\n",
+			"PLUGIN", "OBJECTS", "SIZE", "STORAGE CLASSES", "STORAGE", "EGRESS")
+		for _, u := range usage {
+			storage := u.StorageCost(pricing)
+			egress := u.EgressCost(pricing, costDownloads)
+			totalBytes += u.TotalBytes()
+			totalStorage += storage
+			totalEgress += egress
+
+			console.Printf("%-30s %8d %12s %-30s %10s %10s\n",
+				u.Plugin, u.Objects, formatBytes(u.TotalBytes()), storageClasses(u),
+				formatDollars(storage), formatDollars(egress))
+		}
+
+		console.Printf("\nTotal stored: %s\n", formatBytes(totalBytes))
+		console.Printf("Estimated monthly storage cost: %s\n", formatDollars(totalStorage))
+		console.Printf("Estimated monthly egress cost:  %s (%d downloads per plugin)\n",
+			formatDollars(totalEgress), costDownloads)
+		console.Printf("Estimated monthly total:        %s\n", formatDollars(totalStorage+totalEgress))
+		return nil
+	},
+}
+
+func storageClasses(u pkg.PluginUsage) string {
+	classes := make([]string, 0, len(u.Bytes))
+	for class := range u.Bytes {
+		classes = append(classes, class)
+	}
+	sort.Strings(classes)
+	return strings.Join(classes, ",")
+}
+
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+func formatDollars(f float64) string {
+	return fmt.Sprintf("$%.2f", f)
+}
+
+func init() {
+	rootCmd.AddCommand(costCmd)
+
+	costCmd.Flags().StringVarP(&bucket, "bucket", "b", "", "bucket of the registry")
+	costCmd.Flags().
+		IntVar(&costDownloads, "downloads", 1000, "expected monthly downloads of each plugin, for the egress estimate")
+}
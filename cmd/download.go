@@ -0,0 +1,126 @@
+/*
+Copyright © 2025 Joshua Pare <jpare@omniview.dev>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"slices"
+
+	"github.com/omniviewdev/registry-cli/pkg/client"
+	"github.com/omniviewdev/registry-cli/pkg/console"
+	"github.com/omniviewdev/registry-cli/pkg/types"
+	"github.com/spf13/cobra"
+)
+
+var (
+	downloadPlatform string
+	downloadOut      string
+	downloadRetries  int
+)
+
+// downloadCmd represents the download command
+var downloadCmd = &cobra.Command{
+	Use:   "download [plugin] [version]",
+	Short: "Download a published plugin",
+	Long: `Download fetches the build of a plugin version (the latest when no version is given) for
+this platform, or the one given with --platform, and checks it against the checksum in the
+signed index. Interrupted downloads are resumed where they stopped, including by running the
+command again:
+
+  registry-cli download kubernetes 0.2.0 --out ./plugins
+
+The builds of private registries are decrypted once verified, with the keys of install.`,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		registries, err := newRegistries()
+		if err != nil {
+			return err
+		}
+
+		index, c, err := registries.PluginIndex(cmd.Context(), args[0])
+		if err != nil {
+			return err
+		}
+
+		versionInfo := index.LatestVersion
+		if len(args) > 1 {
+			idx := slices.IndexFunc(index.Versions, func(v types.PluginVersionInformation) bool {
+				return v.Version == args[1]
+			})
+			if idx == -1 {
+				return fmt.Errorf("Version %s of %s was not found", args[1], args[0])
+			}
+			versionInfo = index.Versions[idx]
+		}
+
+		downloadPlatform = types.NormalizePlatform(downloadPlatform)
+		info, ok := versionInfo.Architecture(downloadPlatform)
+		if !ok {
+			return fmt.Errorf(
+				"Version %s of %s has no %s build",
+				versionInfo.Version,
+				args[0],
+				downloadPlatform,
+			)
+		}
+
+		if err := os.MkdirAll(downloadOut, 0o755); err != nil {
+			return fmt.Errorf("Couldn't create %s: %w", downloadOut, err)
+		}
+		dest := filepath.Join(downloadOut, path.Base(info.DownloadURL))
+
+		decrypter, err := newDecrypter(cmd.Context())
+		if err != nil {
+			return err
+		}
+
+		console.Printf("Downloading %s[%s] for %s...\n", args[0], versionInfo.Version, downloadPlatform)
+		err = c.Download(cmd.Context(), info, dest, client.DownloadOpts{
+			Retries: downloadRetries,
+			OnRetry: func(attempt int, offset int64, err error) {
+				console.Printf(
+					"⚠️ %v, resuming from %s (attempt %d)\n",
+					err,
+					formatBytes(offset),
+					attempt,
+				)
+			},
+			Decrypter: decrypter,
+		})
+		if err != nil {
+			return err
+		}
+		console.Printf("✅ Downloaded and verified %s\n", dest)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(downloadCmd)
+
+	downloadCmd.Flags().
+		StringVar(&downloadPlatform, "platform", runtime.GOOS+"_"+runtime.GOARCH, "platform to download the build for (e.g. linux_amd64)")
+	downloadCmd.Flags().StringVarP(&downloadOut, "out", "o", ".", "directory to download to")
+	downloadCmd.Flags().
+		IntVar(&downloadRetries, "retries", client.DefaultDownloadRetries, "how many times to resume an interrupted download")
+	downloadCmd.Flags().
+		StringSliceVar(&decryptionKeys, "decryption-key", nil, "file holding a shared key or age identities to decrypt the builds of private registries with, in addition to 'decryption_keys' in the config")
+}
@@ -0,0 +1,102 @@
+/*
+Copyright © 2025 Joshua Pare <jpare@omniview.dev>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/omniviewdev/registry-cli/pkg"
+	"github.com/omniviewdev/registry-cli/pkg/console"
+	"github.com/spf13/cobra"
+)
+
+var (
+	exportPlugins []string
+	exportOut     string
+)
+
+// exportCmd represents the export command
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export plugins from a registry into a bundle for air-gapped environments",
+	Long: `Export the selected plugins from the registry into a single bundle file that can be
+carried into an air-gapped environment and loaded with 'registry-cli import'. Every version is
+exported unless versions are pinned with plugin@version:
+
+  registry-cli export --registry https://registry.omniview.dev \
+    --plugins kubernetes,aws@1.2.0 -o bundle.tar
+
+The bundle holds a manifest with the plugin indexes and checksums, followed by every artifact.
+Artifacts are checked against the registry's checksums as they are exported. Progress is saved
+as artifacts are written, so an export that failed part way can be carried on with --resume.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(exportPlugins) == 0 {
+			return fmt.Errorf("Select the plugins to export with --plugins")
+		}
+
+		source, err := newRegistryClient()
+		if err != nil {
+			return err
+		}
+
+		out, err := filepath.Abs(exportOut)
+		if err != nil {
+			return fmt.Errorf("Failed to create bundle: %w", err)
+		}
+		checkpoint, err := openCheckpoint(
+			"export",
+			append([]string{source.URL(""), out}, exportPlugins...)...,
+		)
+		if err != nil {
+			return err
+		}
+
+		targets := pkg.ParseMirrorTargets(exportPlugins)
+		manifest, err := pkg.ExportBundleFile(
+			cmd.Context(),
+			source,
+			source.URL(""),
+			targets,
+			exportOut,
+			checkpoint,
+		)
+		if err := finishCheckpoint(checkpoint, err); err != nil {
+			return err
+		}
+
+		versions := 0
+		for _, plugin := range manifest.Plugins {
+			versions += len(plugin.Versions)
+		}
+		console.Printf("✅ Exported %d versions of %d plugins to %s\n", versions, len(manifest.Plugins), exportOut)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(exportCmd)
+
+	exportCmd.Flags().
+		StringSliceVar(&exportPlugins, "plugins", nil, "Plugins to export, optionally pinned as plugin@version")
+	exportCmd.Flags().
+		BoolVar(&resume, "resume", false, "resume the export from where an earlier run failed")
+	exportCmd.Flags().
+		StringVar(&stateFile, "state-file", "", "path of the file progress is saved to (defaults to one in the user cache directory)")
+	exportCmd.Flags().StringVarP(&exportOut, "out", "o", "bundle.tar", "path to write the bundle to")
+}
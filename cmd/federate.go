@@ -0,0 +1,140 @@
+/*
+Copyright © 2025 Joshua Pare <jpare@omniview.dev>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/omniviewdev/registry-cli/pkg"
+	"github.com/omniviewdev/registry-cli/pkg/client"
+	"github.com/omniviewdev/registry-cli/pkg/console"
+	"github.com/omniviewdev/registry-cli/pkg/signing"
+	"github.com/omniviewdev/registry-cli/pkg/types"
+	"github.com/spf13/cobra"
+)
+
+var (
+	federateUpstreams []string
+	federateOut       string
+)
+
+// federateCmd represents the federate command
+var federateCmd = &cobra.Command{
+	Use:   "federate",
+	Short: "Build a catalog of the plugins of several registries",
+	Long: `Federate merges the plugins of several upstream registries into one federated index,
+for organizations aggregating internal and public plugins into one catalog. Each plugin is
+listed from the highest priority registry that has it, referencing that registry as its source,
+with absolute URLs to its index and downloads.
+
+Upstreams are given highest priority first, or default to the configured registries:
+
+  registry-cli federate --upstream https://plugins.example.com \
+    --upstream https://registry.omniview.dev -o federation.json
+
+With --bucket the index is uploaded to the root of the bucket as ` + types.FederationPath + `
+instead. The index is signed when a signing key is given.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var registries client.Registries
+		if len(federateUpstreams) == 0 {
+			var err error
+			if registries, err = newRegistries(); err != nil {
+				return err
+			}
+		}
+		for _, upstream := range federateUpstreams {
+			c, err := newClient(upstream, "")
+			if err != nil {
+				return err
+			}
+			registries = append(registries, c)
+		}
+
+		federation, err := registries.Federate(cmd.Context())
+		if err != nil {
+			return err
+		}
+
+		if bucket != "" {
+			indexer, err := pkg.NewIndexer(cmd.Context(), pkg.IndexerOpts{
+				Bucket:     bucket,
+				Provider:   provider,
+				Endpoint:   endpoint,
+				Pacing:     pacing,
+				SigningKey: signingKey,
+				Layout:     layout,
+			})
+			if err != nil {
+				return err
+			}
+			if err := indexer.PublishFederation(cmd.Context(), federation); err != nil {
+				return err
+			}
+			console.Printf(
+				"✅ Federated %d plugins from %d registries into %s\n",
+				len(federation.Plugins),
+				len(registries),
+				types.FederationPath,
+			)
+			return nil
+		}
+
+		b, err := json.MarshalIndent(federation, "", "  ")
+		if err != nil {
+			return fmt.Errorf("Failed to encode federated index: %w", err)
+		}
+		if err := os.WriteFile(federateOut, b, 0o644); err != nil {
+			return fmt.Errorf("Failed to write federated index: %w", err)
+		}
+		if signingKey == "" {
+			signingKey = os.Getenv("REGISTRY_SIGNING_KEY")
+		}
+		if signingKey != "" {
+			key, err := signing.LoadPrivateKey(signingKey)
+			if err != nil {
+				return err
+			}
+			sig := key.Sign(b, types.FederationPath)
+			if err := os.WriteFile(federateOut+signing.SignatureExt, sig, 0o644); err != nil {
+				return fmt.Errorf("Failed to write signature: %w", err)
+			}
+		}
+		console.Printf(
+			"✅ Federated %d plugins from %d registries into %s\n",
+			len(federation.Plugins),
+			len(registries),
+			federateOut,
+		)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(federateCmd)
+
+	federateCmd.Flags().
+		StringArrayVar(&federateUpstreams, "upstream", nil, "URL of a registry to federate, highest priority first (default is the configured registries)")
+	federateCmd.Flags().
+		StringVarP(&federateOut, "out", "o", types.FederationPath, "path to write the federated index to")
+	federateCmd.Flags().
+		StringVarP(&bucket, "bucket", "b", "", "bucket to upload the federated index to, instead of writing it out")
+	federateCmd.Flags().
+		StringVar(&signingKey, "signing-key", "", "path to the key to sign the federated index with (or REGISTRY_SIGNING_KEY)")
+}
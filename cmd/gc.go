@@ -0,0 +1,174 @@
+/*
+Copyright © 2025 Joshua Pare <jpare@omniview.dev>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package cmd
+
+import (
+	"errors"
+	"time"
+
+	"github.com/omniviewdev/registry-cli/pkg"
+	"github.com/omniviewdev/registry-cli/pkg/console"
+	"github.com/spf13/cobra"
+)
+
+var (
+	gcDryRun       bool
+	gcTransition   string
+	gcStaleUploads time.Duration
+)
+
+// gcCmd represents the gc command
+var gcCmd = &cobra.Command{
+	Use:   "gc",
+	Short: "Remove plugin versions the retention policy no longer keeps",
+	Long: `Enforce the registry retention policy (see 'registry-cli policy'), removing old
+versions from the indexes and deleting their artifacts from the bucket. The latest version of
+a plugin is never removed.
+
+  registry-cli gc --bucket my-registry --dry-run
+
+With --transition, old versions are kept and their artifacts are moved to a cheaper storage
+class instead:
+
+  registry-cli gc --bucket my-registry --transition GLACIER_IR
+
+Progress is saved as artifacts are deleted (or moved), so a collection that failed part way
+can be carried on with --resume.
+
+Multipart uploads left incomplete for longer than --stale-uploads, by publishes that were
+killed or lost their connection, are aborted too, as their parts are billed for until they are.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		indexer, err := pkg.NewIndexer(cmd.Context(), pkg.IndexerOpts{
+			Bucket:     bucket,
+			Provider:   provider,
+			Endpoint:   endpoint,
+			Pacing:     pacing,
+			SigningKey: signingKey,
+			LockMode:   lockMode,
+			LockTable:  lockTable,
+			IndexTable: indexTable,
+			Layout:     layout,
+		})
+		if err != nil {
+			return err
+		}
+
+		transitionTo, err := pkg.ParseStorageClass(gcTransition)
+		if err != nil {
+			return err
+		}
+
+		var checkpoint *pkg.Checkpoint
+		if !gcDryRun {
+			checkpoint, err = openCheckpoint("gc", bucket, string(transitionTo))
+			if err != nil {
+				return err
+			}
+		}
+
+		removals, err := indexer.CollectGarbage(cmd.Context(), pkg.GCOpts{
+			DryRun:       gcDryRun,
+			TransitionTo: transitionTo,
+			Checkpoint:   checkpoint,
+		})
+		if err := finishCheckpoint(checkpoint, err); err != nil {
+			return err
+		}
+		var uploads []pkg.StaleUpload
+		if gcStaleUploads > 0 {
+			uploads, err = indexer.AbortStaleUploads(cmd.Context(), gcStaleUploads, gcDryRun)
+			// buckets without multipart uploads have none to abort, unless asked for explicitly
+			if errors.Is(err, pkg.ErrNotSupported) && !cmd.Flags().Changed("stale-uploads") {
+				err = nil
+			}
+			if err != nil {
+				return err
+			}
+		}
+		if len(removals) == 0 && len(uploads) == 0 {
+			console.Println("✅ Nothing to remove")
+			return nil
+		}
+
+		dryRun := ""
+		if gcDryRun {
+			dryRun = " (dry run, nothing was changed)"
+		}
+		if len(removals) > 0 {
+			for _, removal := range removals {
+				console.Printf("  %s %s: %s\n", removal.Plugin, removal.Version, removal.Reason)
+			}
+			action := "Removed"
+			if transitionTo != "" {
+				action = "Moved to " + string(transitionTo) + ":"
+			}
+			printGCResult("%s %d versions%s\n", action, len(removals), dryRun)
+		}
+		if len(uploads) > 0 {
+			var size int64
+			for _, upload := range uploads {
+				size += upload.Size
+				console.Printf(
+					"  incomplete upload of %s started %s: %d parts, %s\n",
+					upload.Key,
+					upload.Initiated.Local().Format(time.DateTime),
+					upload.Parts,
+					formatBytes(upload.Size),
+				)
+			}
+			printGCResult(
+				"Aborted %d incomplete uploads, freeing %s%s\n",
+				len(uploads),
+				formatBytes(size),
+				dryRun,
+			)
+		}
+		return nil
+	},
+}
+
+// printGCResult prints a result of the collection, marked done unless it's a dry run.
+func printGCResult(format string, args ...any) {
+	if !gcDryRun {
+		format = "✅ " + format
+	}
+	console.Printf(format, args...)
+}
+
+func init() {
+	rootCmd.AddCommand(gcCmd)
+
+	gcCmd.Flags().StringVarP(&bucket, "bucket", "b", "", "bucket to collect garbage in")
+	gcCmd.Flags().
+		StringVar(&signingKey, "signing-key", "", "path to the key to sign indexes with (or REGISTRY_SIGNING_KEY)")
+	gcCmd.Flags().
+		StringVar(&lockMode, "lock", "", "lock the indexes while updating them, with 's3' or 'dynamodb' (or REGISTRY_LOCK)")
+	gcCmd.Flags().
+		StringVar(&lockTable, "lock-table", "", "DynamoDB table to hold the lock in with --lock dynamodb (or REGISTRY_LOCK_TABLE)")
+	gcCmd.Flags().
+		StringVar(&indexTable, "index-table", "", "DynamoDB table holding the index records, materialized into the bucket indexes (or REGISTRY_INDEX_TABLE)")
+	gcCmd.Flags().
+		StringVar(&gcTransition, "transition", "", "storage class to move old versions to instead of deleting them (e.g. STANDARD_IA, GLACIER_IR)")
+	gcCmd.Flags().
+		BoolVar(&resume, "resume", false, "resume the collection from where an earlier run failed")
+	gcCmd.Flags().
+		StringVar(&stateFile, "state-file", "", "path of the file progress is saved to (defaults to one in the user cache directory)")
+	gcCmd.Flags().
+		DurationVar(&gcStaleUploads, "stale-uploads", pkg.DefaultStaleUploadAge, "abort the multipart uploads left incomplete for longer than this, 0 to leave them")
+	gcCmd.Flags().BoolVar(&gcDryRun, "dry-run", false, "show what would be removed without removing it")
+}
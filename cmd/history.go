@@ -0,0 +1,106 @@
+/*
+Copyright © 2025 Joshua Pare <jpare@omniview.dev>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/omniviewdev/registry-cli/pkg"
+	"github.com/omniviewdev/registry-cli/pkg/console"
+	"github.com/spf13/cobra"
+)
+
+var (
+	historyShow  string
+	historyLimit int
+)
+
+// historyCmd represents the history command
+var historyCmd = &cobra.Command{
+	Use:   "history [plugin]",
+	Short: "List and view past states of an index",
+	Long: `Every index write stores a snapshot of the index under /_history/ in the bucket, along
+with who made the change and a summary of it. History lists the snapshots of a plugin's index,
+or of the registry index when no plugin is given, most recent first:
+
+  registry-cli history kubernetes --bucket my-registry
+
+Pass a snapshot ID with --show to print the index as it was at that point:
+
+  registry-cli history kubernetes --bucket my-registry --show 20250101T120000.000000000Z`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		indexer, err := pkg.NewIndexer(cmd.Context(), pkg.IndexerOpts{
+			Bucket:   bucket,
+			Provider: provider,
+			Endpoint: endpoint,
+			Pacing:   pacing,
+			Layout:   layout,
+		})
+		if err != nil {
+			return err
+		}
+
+		var plugin string
+		if len(args) > 0 {
+			plugin = args[0]
+		}
+
+		if historyShow != "" {
+			snapshot, err := indexer.Snapshot(cmd.Context(), plugin, historyShow)
+			if err != nil {
+				return err
+			}
+			console.Printf("# %s by %s: %s\n", snapshot.Time.Format("2006-01-02 15:04:05 MST"), snapshot.Actor, snapshot.Summary)
+
+			if len(snapshot.Index) == 0 {
+				console.Println("(the index was removed)")
+				return nil
+			}
+
+			var out bytes.Buffer
+			if err := json.Indent(&out, snapshot.Index, "", "  "); err != nil {
+				return fmt.Errorf("Snapshot %s holds an invalid index: %w", historyShow, err)
+			}
+			console.Println(out.String())
+			return nil
+		}
+
+		snapshots, err := indexer.History(cmd.Context(), plugin, historyLimit)
+		if err != nil {
+			return err
+		}
+		if len(snapshots) == 0 {
+			console.Println("No history recorded")
+			return nil
+		}
+		for _, snapshot := range snapshots {
+			console.Printf("%s  %-24s %s\n", snapshot.ID, snapshot.Actor, snapshot.Summary)
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(historyCmd)
+
+	historyCmd.Flags().StringVarP(&bucket, "bucket", "b", "", "bucket to read the history from")
+	historyCmd.Flags().StringVar(&historyShow, "show", "", "ID of a snapshot to print")
+	historyCmd.Flags().IntVarP(&historyLimit, "limit", "n", 20, "number of snapshots to list, 0 for all")
+}
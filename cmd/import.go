@@ -0,0 +1,98 @@
+/*
+Copyright © 2025 Joshua Pare <jpare@omniview.dev>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/omniviewdev/registry-cli/pkg"
+	"github.com/omniviewdev/registry-cli/pkg/console"
+	"github.com/spf13/cobra"
+)
+
+// importCmd represents the import command
+var importCmd = &cobra.Command{
+	Use:   "import <bundle>",
+	Short: "Import a bundle created with 'registry-cli export' into a bucket",
+	Long: `Load a bundle created with 'registry-cli export' into your bucket:
+
+  registry-cli import bundle.tar --bucket my-registry
+
+Every artifact is checked against the checksums in the bundle manifest before it is uploaded,
+and the indexes are only updated once all artifacts have been imported.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		f, err := os.Open(args[0])
+		if err != nil {
+			return fmt.Errorf("Failed to open bundle: %w", err)
+		}
+		defer f.Close()
+
+		indexer, err := pkg.NewIndexer(cmd.Context(), pkg.IndexerOpts{
+			Bucket:     bucket,
+			Provider:   provider,
+			Endpoint:   endpoint,
+			Pacing:     pacing,
+			SigningKey: signingKey,
+			LockMode:   lockMode,
+			LockTable:  lockTable,
+			IndexTable: indexTable,
+			BaseURL:    baseURL,
+			Layout:     layout,
+		})
+		if err != nil {
+			return err
+		}
+
+		publisher, err := pkg.NewPublisher(cmd.Context(), pkg.PublisherOpts{
+			Bucket:   bucket,
+			Provider: provider,
+			Endpoint: endpoint,
+			Pacing:   pacing,
+			Layout:   layout,
+		})
+		if err != nil {
+			return err
+		}
+
+		manifest, err := pkg.ImportBundle(cmd.Context(), f, publisher, indexer)
+		if err != nil {
+			return err
+		}
+
+		console.Printf("✅ Imported %d plugins from %s (exported from %s on %s)\n",
+			len(manifest.Plugins), args[0], manifest.Source, manifest.Created.Format("2006-01-02"))
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(importCmd)
+
+	importCmd.Flags().StringVarP(&bucket, "bucket", "b", "", "bucket to import into")
+	importCmd.Flags().
+		StringVar(&signingKey, "signing-key", "", "path to the key to sign indexes with (or REGISTRY_SIGNING_KEY)")
+	importCmd.Flags().
+		StringVar(&lockMode, "lock", "", "lock the indexes while updating them, with 's3' or 'dynamodb' (or REGISTRY_LOCK)")
+	importCmd.Flags().
+		StringVar(&lockTable, "lock-table", "", "DynamoDB table to hold the lock in with --lock dynamodb (or REGISTRY_LOCK_TABLE)")
+	importCmd.Flags().
+		StringVar(&indexTable, "index-table", "", "DynamoDB table holding the index records, materialized into the bucket indexes (or REGISTRY_INDEX_TABLE)")
+	importCmd.Flags().
+		StringVar(&baseURL, "base-url", "", "URL the bucket is served from, to write absolute download URLs into the index (or REGISTRY_BASE_URL)")
+}
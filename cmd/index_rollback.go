@@ -0,0 +1,116 @@
+/*
+Copyright © 2025 Joshua Pare <jpare@omniview.dev>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package cmd
+
+import (
+	"github.com/omniviewdev/registry-cli/pkg"
+	"github.com/omniviewdev/registry-cli/pkg/console"
+	"github.com/spf13/cobra"
+)
+
+var (
+	rollbackPlugin string
+	rollbackTo     string
+)
+
+// indexRollbackCmd represents the index rollback command
+var indexRollbackCmd = &cobra.Command{
+	Use:   "rollback",
+	Short: "Restore a previous revision of an index",
+	Long: `Restore a previous revision of the registry index, or of a plugin's index with --plugin,
+from the revisions kept by S3 object versioning (see 'registry-cli bootstrap'). Without --to the
+available revisions are listed.
+
+--to takes a revision's version ID, or a time to restore the last revision written by then:
+
+  registry-cli index rollback --bucket my-registry --plugin kubernetes
+  registry-cli index rollback --bucket my-registry --plugin kubernetes --to 2025-01-01T12:00:00Z
+
+The restored index is signed and written like any other update, so pass --signing-key for a
+signed registry.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		indexer, err := pkg.NewIndexer(cmd.Context(), pkg.IndexerOpts{
+			Bucket:     bucket,
+			Provider:   provider,
+			Endpoint:   endpoint,
+			Pacing:     pacing,
+			SigningKey: signingKey,
+			LockMode:   lockMode,
+			LockTable:  lockTable,
+			IndexTable: indexTable,
+			Layout:     layout,
+		})
+		if err != nil {
+			return err
+		}
+
+		if rollbackTo == "" {
+			revisions, err := indexer.IndexRevisions(cmd.Context(), rollbackPlugin)
+			if err != nil {
+				return err
+			}
+			if len(revisions) == 0 {
+				console.Println("No revisions found")
+				return nil
+			}
+			for _, revision := range revisions {
+				current := ""
+				if revision.IsLatest {
+					current = " (current)"
+				}
+				console.Printf(
+					"%s  %s  %8d bytes%s\n",
+					revision.LastModified.UTC().Format("2006-01-02T15:04:05Z"),
+					revision.VersionID,
+					revision.Size,
+					current,
+				)
+			}
+			return nil
+		}
+
+		revision, err := indexer.RollbackIndex(cmd.Context(), rollbackPlugin, rollbackTo)
+		if err != nil {
+			return err
+		}
+		console.Printf(
+			"✅ Rolled back to revision %s from %s\n",
+			revision.VersionID,
+			revision.LastModified.UTC().Format("2006-01-02T15:04:05Z"),
+		)
+		return nil
+	},
+}
+
+func init() {
+	indexCmd.AddCommand(indexRollbackCmd)
+
+	indexRollbackCmd.Flags().StringVarP(&bucket, "bucket", "b", "", "bucket holding the registry")
+	indexRollbackCmd.Flags().
+		StringVar(&rollbackPlugin, "plugin", "", "plugin whose index to roll back, instead of the registry index")
+	indexRollbackCmd.Flags().
+		StringVar(&rollbackTo, "to", "", "version ID of the revision, or the time, to roll back to")
+	indexRollbackCmd.Flags().
+		StringVar(&signingKey, "signing-key", "", "path to the key to sign the restored index with (or REGISTRY_SIGNING_KEY)")
+	indexRollbackCmd.Flags().
+		StringVar(&lockMode, "lock", "", "lock the indexes while updating them, with 's3' or 'dynamodb' (or REGISTRY_LOCK)")
+	indexRollbackCmd.Flags().
+		StringVar(&lockTable, "lock-table", "", "DynamoDB table to hold the lock in with --lock dynamodb (or REGISTRY_LOCK_TABLE)")
+	indexRollbackCmd.Flags().
+		StringVar(&indexTable, "index-table", "", "DynamoDB table holding the index records, materialized into the bucket indexes (or REGISTRY_INDEX_TABLE)")
+}
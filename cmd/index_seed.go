@@ -0,0 +1,65 @@
+/*
+Copyright © 2025 Joshua Pare <jpare@omniview.dev>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package cmd
+
+import (
+	"github.com/omniviewdev/registry-cli/pkg"
+	"github.com/omniviewdev/registry-cli/pkg/console"
+	"github.com/spf13/cobra"
+)
+
+// indexSeedCmd represents the index seed command
+var indexSeedCmd = &cobra.Command{
+	Use:   "seed",
+	Short: "Seed the index table from the indexes in the bucket",
+	Long: `Copy every plugin and version in the bucket indexes into the DynamoDB index table, so an
+existing registry can switch over to it. Once seeded, pass --index-table (or set
+REGISTRY_INDEX_TABLE) everywhere the indexes are updated.
+
+The table needs a string partition key named "pk" and a string sort key named "sk":
+
+  registry-cli index seed --bucket my-registry --index-table registry-index`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		indexer, err := pkg.NewIndexer(cmd.Context(), pkg.IndexerOpts{
+			Bucket:     bucket,
+			Provider:   provider,
+			Endpoint:   endpoint,
+			Pacing:     pacing,
+			IndexTable: indexTable,
+			Layout:     layout,
+		})
+		if err != nil {
+			return err
+		}
+
+		seeded, err := indexer.SeedRecords(cmd.Context())
+		if err != nil {
+			return err
+		}
+		console.Printf("✅ Seeded %d plugins into the index table\n", seeded)
+		return nil
+	},
+}
+
+func init() {
+	indexCmd.AddCommand(indexSeedCmd)
+
+	indexSeedCmd.Flags().StringVarP(&bucket, "bucket", "b", "", "bucket to read the indexes from")
+	indexSeedCmd.Flags().
+		StringVar(&indexTable, "index-table", "", "DynamoDB table to seed (or REGISTRY_INDEX_TABLE)")
+}
@@ -0,0 +1,132 @@
+/*
+Copyright © 2025 Joshua Pare <jpare@omniview.dev>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package cmd
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/omniviewdev/registry-cli/pkg/console"
+	"github.com/omniviewdev/registry-cli/pkg/types"
+	"github.com/spf13/cobra"
+)
+
+// infoCmd represents the info command
+var infoCmd = &cobra.Command{
+	Use:   "info [plugin] [version]",
+	Short: "Show information about a published plugin",
+	Long: `Info shows a plugin's details and versions from the registry, along with the platforms
+and the Omniview core versions a version was tested against (the latest when no version is
+given). With several registries configured, the plugin is shown from the highest priority
+registry that has it:
+
+  registry-cli info kubernetes 0.2.0`,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		registries, err := newRegistries()
+		if err != nil {
+			return err
+		}
+
+		index, registry, err := registries.PluginIndex(cmd.Context(), args[0])
+		if err != nil {
+			return err
+		}
+
+		version := index.LatestVersion.Version
+		if len(args) > 1 {
+			version = args[1]
+		}
+		var versionInfo types.PluginVersionInformation
+		if idx := slices.IndexFunc(index.Versions, func(v types.PluginVersionInformation) bool {
+			return v.Version == version
+		}); idx != -1 {
+			versionInfo = index.Versions[idx]
+		} else if len(args) > 1 {
+			return fmt.Errorf("Version %s of %s was not found", args[1], args[0])
+		}
+
+		console.Printf("%s (%s)\n", index.Name, index.ID)
+		if index.Description != "" {
+			console.Printf("  %s\n", index.Description)
+		}
+		console.Println()
+		if len(registries) > 1 {
+			console.Printf("Registry:        %s\n", registry.URL(""))
+		}
+		console.Printf("Latest version:  %s\n", index.LatestVersion.Version)
+
+		versions := make([]string, 0, len(index.Versions))
+		for _, v := range index.Versions {
+			if v.Yanked {
+				versions = append(versions, v.Version+" (yanked)")
+				continue
+			}
+			versions = append(versions, v.Version)
+		}
+		slices.SortFunc(versions, func(a, b string) int {
+			return types.CompareVersions(strings.Fields(b)[0], strings.Fields(a)[0])
+		})
+		console.Printf("Versions:        %s\n", strings.Join(versions, ", "))
+
+		if versionInfo.Version == "" {
+			return nil
+		}
+
+		console.Println()
+		console.Printf("Version %s\n", versionInfo.Version)
+		if versionInfo.Yanked {
+			console.Println("  ⚠️  this version has been yanked")
+		}
+		if !versionInfo.Created.IsZero() {
+			console.Printf("  Published:     %s\n", versionInfo.Created.Format("2006-01-02 15:04 MST"))
+		}
+
+		var tested, failed []string
+		for _, core := range versionInfo.Compatibility.Versions() {
+			if versionInfo.Compatibility[core] == types.CompatibilityFailed {
+				failed = append(failed, core)
+				continue
+			}
+			tested = append(tested, core)
+		}
+		if len(tested) == 0 {
+			tested = []string{"unknown"}
+		}
+		console.Printf("  Tested with:   %s\n", strings.Join(tested, ", "))
+		if len(failed) > 0 {
+			console.Printf("  ⚠️  Failed with: %s\n", strings.Join(failed, ", "))
+		}
+
+		archs := make([]string, 0, len(versionInfo.Architectures))
+		for arch := range versionInfo.Architectures {
+			archs = append(archs, arch)
+		}
+		sort.Strings(archs)
+		console.Println("  Platforms:")
+		for _, arch := range archs {
+			console.Printf("    %-14s %s\n", arch, formatBytes(versionInfo.Architectures[arch].Size))
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(infoCmd)
+}
@@ -0,0 +1,179 @@
+/*
+Copyright © 2025 Joshua Pare <jpare@omniview.dev>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"time"
+
+	"github.com/omniviewdev/registry-cli/pkg/client"
+	"github.com/omniviewdev/registry-cli/pkg/console"
+	"github.com/omniviewdev/registry-cli/pkg/progress"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	installDir         string
+	installLockfile    string
+	installPlatform    string
+	installConcurrency int
+	decryptionKeys     []string
+)
+
+// installCmd represents the install command
+var installCmd = &cobra.Command{
+	Use:   "install [plugin[@version]...]",
+	Short: "Install plugins from the registry",
+	Long: `Install downloads the builds of plugins for this platform, verifies them against the
+checksums in the signed indexes (and their signatures, when signed) and extracts them into the
+plugin directory, several at a time. Nothing is extracted from a build that fails
+verification. Plugins are installed at their latest version unless one is given, or pinned in
+a lockfile:
+
+  registry-cli install kubernetes@0.2.0 docker
+  registry-cli install --lockfile plugins.lock
+
+The lockfile lists the plugins to install, in YAML or JSON:
+
+  plugins:
+    - id: kubernetes
+      version: 0.2.0
+
+The plugins each plugin depends on are installed along with it, before it, at a version
+satisfying every plugin depending on them. The lockfile is updated with every plugin installed,
+dependencies included, so later installs get the same versions. With several registries
+configured, each plugin is installed from the highest priority registry that has it.
+
+The builds of private registries, encrypted when they were published, are decrypted once
+verified with the keys given with --decryption-key (or 'decryption_keys' in the config): files
+holding the shared key or age identities they were encrypted to. Builds encrypted to AWS KMS
+keys are decrypted with the default AWS credentials.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var requests []client.InstallRequest
+		if installLockfile != "" {
+			lock, err := client.LoadLockfile(installLockfile)
+			if err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return err
+			}
+			requests = append(requests, lock.Plugins...)
+		}
+		for _, arg := range args {
+			requests = append(requests, client.ParseInstallRequest(arg))
+		}
+		if len(requests) == 0 {
+			return fmt.Errorf("No plugins to install. Pass plugins or --lockfile")
+		}
+
+		if installDir == "" {
+			installDir = viper.GetString("plugin_dir")
+		}
+		if installDir == "" {
+			home, err := os.UserHomeDir()
+			if err != nil {
+				return fmt.Errorf("No plugin directory. Pass --dir: %w", err)
+			}
+			installDir = filepath.Join(home, ".omniview", "plugins")
+		}
+
+		registries, err := newRegistries()
+		if err != nil {
+			return err
+		}
+
+		decrypter, err := newDecrypter(cmd.Context())
+		if err != nil {
+			return err
+		}
+
+		tracker := progress.New(console.Stdout)
+		results, err := registries.Install(cmd.Context(), requests, client.InstallOpts{
+			Dir:         installDir,
+			Platform:    installPlatform,
+			Concurrency: installConcurrency,
+			Download:    client.DownloadOpts{Decrypter: decrypter},
+			Tracker:     tracker,
+		})
+		tracker.Stop()
+		if err != nil {
+			return err
+		}
+
+		console.Println()
+		failed := 0
+		for _, result := range results {
+			if result.Err != nil {
+				console.Printf("❌ %-24s %v\n", result.Plugin, result.Err)
+				var mismatch *client.ChecksumError
+				if errors.As(result.Err, &mismatch) {
+					console.Printf("   expected sha256: %s\n", mismatch.Expected)
+					console.Printf("   actual sha256:   %s\n", mismatch.Actual)
+				}
+				failed++
+				continue
+			}
+			console.Printf(
+				"✅ %-24s %-12s %s (%s)\n",
+				result.Plugin,
+				result.Version,
+				result.Path,
+				result.Duration.Round(time.Millisecond),
+			)
+		}
+
+		if failed > 0 {
+			return fmt.Errorf("%d of %d plugins failed to install", failed, len(results))
+		}
+		console.Printf("Installed %d plugins into %s\n", len(results), installDir)
+
+		if installLockfile == "" {
+			return nil
+		}
+		var lock client.Lockfile
+		for _, result := range results {
+			lock.Plugins = append(
+				lock.Plugins,
+				client.InstallRequest{Plugin: result.Plugin, Version: result.Version},
+			)
+		}
+		if err := lock.Save(installLockfile); err != nil {
+			return err
+		}
+		console.Printf("Recorded %d plugins in %s\n", len(lock.Plugins), installLockfile)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(installCmd)
+
+	installCmd.Flags().
+		StringVar(&installDir, "dir", "", "directory to install the plugins into (default is 'plugin_dir' in the config file, or ~/.omniview/plugins)")
+	installCmd.Flags().
+		StringVar(&installLockfile, "lockfile", "", "lockfile listing the plugins to install, updated with every plugin installed")
+	installCmd.Flags().
+		StringVar(&installPlatform, "platform", runtime.GOOS+"_"+runtime.GOARCH, "platform to install the builds for (e.g. linux_amd64)")
+	installCmd.Flags().
+		IntVarP(&installConcurrency, "concurrency", "j", client.DefaultInstallConcurrency, "how many plugins to install at once")
+	installCmd.Flags().
+		StringSliceVar(&decryptionKeys, "decryption-key", nil, "file holding a shared key or age identities to decrypt the builds of private registries with, in addition to 'decryption_keys' in the config")
+}
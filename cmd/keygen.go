@@ -0,0 +1,91 @@
+/*
+Copyright © 2025 Joshua Pare <jpare@omniview.dev>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/omniviewdev/registry-cli/pkg/console"
+	"github.com/omniviewdev/registry-cli/pkg/signing"
+	"github.com/spf13/cobra"
+)
+
+var (
+	keygenOut   string
+	keygenForce bool
+)
+
+// keygenCmd represents the keygen command
+var keygenCmd = &cobra.Command{
+	Use:   "keygen",
+	Short: "Generate a key pair for signing registry indexes",
+	Long: `Generate an Ed25519 key pair for signing registry index files. The private key is
+written to <out>.key and should be kept secret (pass it to publish with --signing-key or the
+REGISTRY_SIGNING_KEY environment variable). The public key is written to <out>.pub in the
+minisign format, for distributing to registry consumers.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		key, err := signing.GenerateKey()
+		if err != nil {
+			return err
+		}
+		privPath, pubPath, err := writeKeyPair(key, keygenOut, keygenForce)
+		if err != nil {
+			return err
+		}
+
+		console.Printf("Generated key %s\n", key.Public().ID)
+		console.Printf("  private key: %s\n", privPath)
+		console.Printf("  public key:  %s\n", pubPath)
+		return nil
+	},
+}
+
+// writeKeyPair writes the private key to <out>.key and the public key to <out>.pub, refusing to
+// overwrite existing files unless forced.
+func writeKeyPair(key *signing.PrivateKey, out string, force bool) (string, string, error) {
+	privPath, pubPath := out+".key", out+".pub"
+	if !force {
+		for _, path := range []string{privPath, pubPath} {
+			if _, err := os.Stat(path); err == nil {
+				return "", "", fmt.Errorf("%s already exists. Use --force to overwrite it", path)
+			}
+		}
+	}
+
+	privPEM, err := key.PEM()
+	if err != nil {
+		return "", "", err
+	}
+	if err := os.WriteFile(privPath, privPEM, 0600); err != nil {
+		return "", "", fmt.Errorf("failed to write private key: %w", err)
+	}
+	if err := os.WriteFile(pubPath, key.Public().File(), 0644); err != nil {
+		return "", "", fmt.Errorf("failed to write public key: %w", err)
+	}
+	return privPath, pubPath, nil
+}
+
+func init() {
+	rootCmd.AddCommand(keygenCmd)
+
+	keygenCmd.Flags().
+		StringVarP(&keygenOut, "out", "o", "registry", "Path prefix to write the key pair to")
+	keygenCmd.Flags().
+		BoolVarP(&keygenForce, "force", "f", false, "Overwrite existing key files")
+}
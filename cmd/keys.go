@@ -0,0 +1,212 @@
+/*
+Copyright © 2025 Joshua Pare <jpare@omniview.dev>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package cmd
+
+import (
+	"time"
+
+	"github.com/omniviewdev/registry-cli/pkg"
+	"github.com/omniviewdev/registry-cli/pkg/console"
+	"github.com/omniviewdev/registry-cli/pkg/signing"
+	"github.com/omniviewdev/registry-cli/pkg/types"
+	"github.com/spf13/cobra"
+)
+
+var (
+	keysNextKey string
+	keysOut     string
+	keysForce   bool
+	keysGrace   time.Duration
+)
+
+// keysCmd represents the keys command
+var keysCmd = &cobra.Command{
+	Use:   "keys",
+	Short: "Manage the keys the registry is signed with",
+	Long: `Manage the keys the registry's files are signed with, listed in the bucket as
+` + types.TrustedKeysPath + ` for clients to follow rotations of the signing key.`,
+}
+
+// keysRotateCmd represents the keys rotate command
+var keysRotateCmd = &cobra.Command{
+	Use:   "rotate",
+	Short: "Replace the signing key of the registry",
+	Long: `Rotate replaces the signing key of the registry (--signing-key) with a new key, generated
+into <out>.key and <out>.pub, or given with --next-key:
+
+  registry-cli keys rotate -b my-registry --signing-key registry.key --out registry-2026
+
+The new key is cross-signed with the current one and added to the trusted keys in the bucket,
+and every signed file of the registry is signed again with it. Sign with the new key from then
+on.
+
+Clients pinning the current key follow the rotation: they trust the new key through the
+signature of the current one. The current key keeps being trusted for a grace period (--grace,
+30 days by default), while caches of files signed with it expire. Once the grace period is
+over clients refuse it, even when pinned, so they must pin the new key before then. When the
+registry has no trusted keys, clients only verify against their pinned keys.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		indexer, err := newKeysIndexer(cmd)
+		if err != nil {
+			return err
+		}
+
+		var next *signing.PrivateKey
+		if keysNextKey != "" {
+			if next, err = signing.LoadPrivateKey(keysNextKey); err != nil {
+				return err
+			}
+		} else {
+			if next, err = signing.GenerateKey(); err != nil {
+				return err
+			}
+			privPath, pubPath, err := writeKeyPair(next, keysOut, keysForce)
+			if err != nil {
+				return err
+			}
+			console.Printf("Generated key %s\n", next.Public().ID)
+			console.Printf("  private key: %s\n", privPath)
+			console.Printf("  public key:  %s\n", pubPath)
+		}
+
+		rotation, err := indexer.RotateKey(cmd.Context(), next, keysGrace)
+		if err != nil {
+			return err
+		}
+		console.Printf(
+			"✅ Rotated the signing key from %s to %s, signed %d files again\n",
+			rotation.Previous.ID,
+			rotation.Next.ID,
+			len(rotation.Resigned),
+		)
+		console.Printf(
+			"  ⚠️  %s is trusted until %s, pin %s in clients before then\n",
+			rotation.Previous.ID,
+			rotation.Retires.Format(time.DateOnly),
+			rotation.Next.ID,
+		)
+		return nil
+	},
+}
+
+// keysResignCmd represents the keys resign command
+var keysResignCmd = &cobra.Command{
+	Use:   "resign",
+	Short: "Sign every signed file of the registry again",
+	Long: `Resign signs every signed file of the registry again with the signing key, to finish a
+rotation that was interrupted:
+
+  registry-cli keys resign -b my-registry --signing-key registry-2026.key`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		indexer, err := newKeysIndexer(cmd)
+		if err != nil {
+			return err
+		}
+		resigned, err := indexer.Resign(cmd.Context())
+		if err != nil {
+			return err
+		}
+		console.Printf("✅ Signed %d files again\n", len(resigned))
+		return nil
+	},
+}
+
+// keysListCmd represents the keys list command
+var keysListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the keys the registry is signed with",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		indexer, err := newKeysIndexer(cmd)
+		if err != nil {
+			return err
+		}
+		keys, err := indexer.TrustedKeys(cmd.Context())
+		if err != nil {
+			return err
+		}
+		if len(keys.Keys) == 0 {
+			console.Println("No trusted keys, the signing key was never rotated")
+			return nil
+		}
+		now := time.Now()
+		for _, key := range keys.Keys {
+			switch {
+			case key.Retired(now):
+				console.Printf(
+					"❌ %s  added %s, retired %s\n",
+					key.ID,
+					key.Added.Format(time.DateOnly),
+					key.Retires.Format(time.DateOnly),
+				)
+			case key.Retires != nil:
+				console.Printf(
+					"⚠️ %s  added %s, retires %s\n",
+					key.ID,
+					key.Added.Format(time.DateOnly),
+					key.Retires.Format(time.DateOnly),
+				)
+			default:
+				console.Printf("✅ %s  added %s\n", key.ID, key.Added.Format(time.DateOnly))
+			}
+		}
+		return nil
+	},
+}
+
+func newKeysIndexer(cmd *cobra.Command) (*pkg.Indexer, error) {
+	return pkg.NewIndexer(cmd.Context(), pkg.IndexerOpts{
+		Bucket:     bucket,
+		Provider:   provider,
+		Endpoint:   endpoint,
+		Pacing:     pacing,
+		SigningKey: signingKey,
+		LockMode:   lockMode,
+		LockTable:  lockTable,
+		IndexTable: indexTable,
+		Layout:     layout,
+	})
+}
+
+func init() {
+	rootCmd.AddCommand(keysCmd)
+	keysCmd.AddCommand(keysRotateCmd)
+	keysCmd.AddCommand(keysResignCmd)
+	keysCmd.AddCommand(keysListCmd)
+
+	keysCmd.PersistentFlags().
+		StringVarP(&bucket, "bucket", "b", "", "bucket serving the registry")
+	keysCmd.PersistentFlags().
+		StringVar(&signingKey, "signing-key", "", "path to the current signing key (or REGISTRY_SIGNING_KEY)")
+	keysCmd.PersistentFlags().
+		StringVar(&lockMode, "lock", "", "lock the indexes while signing them, with 's3' or 'dynamodb' (or REGISTRY_LOCK)")
+	keysCmd.PersistentFlags().
+		StringVar(&lockTable, "lock-table", "", "DynamoDB table to hold the lock in with --lock dynamodb (or REGISTRY_LOCK_TABLE)")
+	keysCmd.PersistentFlags().
+		StringVar(&indexTable, "index-table", "", "DynamoDB table holding the index records, materialized into the bucket indexes (or REGISTRY_INDEX_TABLE)")
+
+	keysRotateCmd.Flags().
+		StringVar(&keysNextKey, "next-key", "", "path to the key to rotate to, instead of generating one")
+	keysRotateCmd.Flags().
+		StringVarP(&keysOut, "out", "o", "registry-next", "path prefix to write the generated key pair to")
+	keysRotateCmd.Flags().
+		BoolVarP(&keysForce, "force", "f", false, "overwrite existing key files")
+	keysRotateCmd.Flags().
+		DurationVar(&keysGrace, "grace", pkg.DefaultKeyGracePeriod, "how long clients keep trusting the current key")
+}
@@ -0,0 +1,85 @@
+/*
+Copyright © 2025 Joshua Pare <jpare@omniview.dev>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/omniviewdev/registry-cli/pkg/console"
+	"github.com/omniviewdev/registry-cli/pkg/packager"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var lintStrict bool
+
+// lintCmd represents the lint command
+var lintCmd = &cobra.Command{
+	Use:   "lint [plugin-dir]",
+	Short: "Check the layout of a plugin project against what packaging expects",
+	Long: `Check the layout of a plugin project against what packaging expects, before the first
+package attempt: the metadata file is valid, go.mod has a sane module path, the ./pkg
+entrypoint is a main package, ui/package.json has the scripts building the UI, and the
+.gitignore keeps the build output, ui/dist and ui/node_modules out of the repository.
+
+Every issue is printed along with how to fix it. Unlike 'package --check', toolchains aren't
+looked for, so it can run anywhere. Lints the current directory when no directory is given.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		pluginDir := "."
+		if len(args) > 0 {
+			pluginDir = args[0]
+		}
+		if metaFile == "" {
+			metaFile = viper.GetString("metadata_file")
+		}
+
+		issues := packager.Lint(packager.PackOpts{
+			PluginDir:    pluginDir,
+			OutDir:       outdir,
+			MetadataFile: metaFile,
+		})
+
+		var failed int
+		for _, issue := range issues {
+			mark := "❌"
+			if issue.Warning && !lintStrict {
+				mark = "⚠️"
+			} else {
+				failed++
+			}
+			console.Printf("%s [%s] %s\n", mark, issue.Check, issue.Problem)
+			console.Printf("   fix: %s\n", issue.Fix)
+		}
+		if failed > 0 {
+			return fmt.Errorf("Plugin failed %d of the lint checks", failed)
+		}
+		console.Println("✅ Plugin layout looks good")
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(lintCmd)
+
+	lintCmd.Flags().
+		StringVarP(&outdir, "out", "o", "build", "output directory the plugin is packaged into, which should be git ignored")
+	lintCmd.Flags().
+		StringVar(&metaFile, "metadata-file", "", "plugin metadata file to lint with. Defaults to the first one found in the plugin directory or its parents (or 'metadata_file' in the config)")
+	lintCmd.Flags().
+		BoolVar(&lintStrict, "strict", false, "fail on warnings too")
+}
@@ -0,0 +1,245 @@
+/*
+Copyright © 2025 Joshua Pare <jpare@omniview.dev>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/omniviewdev/registry-cli/pkg"
+	"github.com/omniviewdev/registry-cli/pkg/console"
+	"github.com/omniviewdev/registry-cli/pkg/types"
+	"github.com/spf13/cobra"
+)
+
+var (
+	maintainerMethod    string
+	maintainerGitHub    string
+	maintainerDomain    string
+	maintainerChallenge string
+	maintainerRenew     bool
+)
+
+// maintainerCmd represents the maintainer command
+var maintainerCmd = &cobra.Command{
+	Use:   "maintainer",
+	Short: "Manage the verification records of plugin maintainers",
+	Long: `Manage the records of which maintainers proved their identity, kept in the bucket as
+` + types.MaintainersPath + ` for the Omniview marketplace to show verified maintainers.`,
+}
+
+// maintainerVerifyCmd represents the maintainer verify command
+var maintainerVerifyCmd = &cobra.Command{
+	Use:   "verify [email]",
+	Short: "Verify the identity of a maintainer",
+	Long: `Verify a maintainer in two steps. The first run issues a challenge for the maintainer to
+prove their identity with, recording them as pending, and prints what they need to do:
+
+  dns     publish a TXT record under the domain of their email address
+  github  create a public gist of their GitHub account (--github) with the challenge
+          in its description
+  email   receive the challenge at their email address, and send it back
+
+Once they have, run it again to check the challenge and record them as verified. For the
+email method, pass the challenge they sent back with --challenge:
+
+  registry-cli maintainer verify jane@example.com --method dns -b my-registry
+  registry-cli maintainer verify jane@example.com -b my-registry
+
+Use --renew to issue a new challenge in place of a pending one.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		email := args[0]
+		indexer, err := newMaintainerIndexer(cmd)
+		if err != nil {
+			return err
+		}
+
+		maintainers, err := indexer.Maintainers(cmd.Context())
+		if err != nil {
+			return err
+		}
+		record, ok := maintainers.Find(email)
+		if ok && record.Status == types.MaintainerVerified && !maintainerRenew {
+			console.Printf("✅ %s is already verified (%s %s)\n", email, record.Method, record.Subject)
+			return nil
+		}
+
+		sameMethod := maintainerMethod == "" || maintainerMethod == string(record.Method)
+		if ok && !maintainerRenew && sameMethod {
+			record, err := indexer.CompleteVerification(cmd.Context(), email, maintainerChallenge)
+			if errors.Is(err, pkg.ErrChallengeNotMet) {
+				console.Printf("❌ %v\n", err)
+				return fmt.Errorf(
+					"Maintainer %s isn't verified yet, run with --renew for a new challenge",
+					email,
+				)
+			}
+			if err != nil {
+				return err
+			}
+			console.Printf("✅ Verified %s (%s %s)\n", record.Email, record.Method, record.Subject)
+			return nil
+		}
+
+		if maintainerMethod == "" {
+			return fmt.Errorf("Must supply --method to request the verification of %s", email)
+		}
+		method, err := types.ParseVerificationMethod(maintainerMethod)
+		if err != nil {
+			return err
+		}
+		subject := maintainerDomain
+		if method == types.VerifyGitHub {
+			subject = maintainerGitHub
+		}
+		record, challenge, err := indexer.RequestVerification(cmd.Context(), email, method, subject)
+		if err != nil {
+			return err
+		}
+
+		console.Printf(
+			"🆕 Requested the verification of %s, pending until the challenge is met:\n\n",
+			record.Email,
+		)
+		switch method {
+		case types.VerifyDNS:
+			console.Printf("  Add a TXT record to the %s domain:\n\n", record.Subject)
+			console.Printf(
+				"    %s  TXT  \"%s%s\"\n",
+				types.DNSChallengeName(record.Subject),
+				types.ChallengePrefix,
+				challenge,
+			)
+		case types.VerifyGitHub:
+			console.Printf(
+				"  Create a public gist on the %s GitHub account, described as:\n\n",
+				record.Subject,
+			)
+			console.Printf("    %s%s\n", types.ChallengePrefix, challenge)
+		case types.VerifyEmail:
+			console.Printf("  Email this challenge to %s, and have it sent back:\n\n", record.Subject)
+			console.Printf("    %s\n", challenge)
+		}
+		console.Printf("\nThen run 'registry-cli maintainer verify %s' again", record.Email)
+		if method == types.VerifyEmail {
+			console.Printf(" with --challenge")
+		}
+		console.Println(".")
+		return nil
+	},
+}
+
+// maintainerListCmd represents the maintainer list command
+var maintainerListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the verification records of the maintainers",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		indexer, err := newMaintainerIndexer(cmd)
+		if err != nil {
+			return err
+		}
+		maintainers, err := indexer.Maintainers(cmd.Context())
+		if err != nil {
+			return err
+		}
+		if len(maintainers.Maintainers) == 0 {
+			console.Println("No maintainer verification records")
+			return nil
+		}
+		for _, record := range maintainers.Maintainers {
+			if record.Status != types.MaintainerVerified {
+				console.Printf(
+					"⚠️ %s  %s %s  pending since %s\n",
+					record.Email,
+					record.Method,
+					record.Subject,
+					record.Requested.Format("2006-01-02"),
+				)
+				continue
+			}
+			console.Printf(
+				"✅ %s  %s %s  verified %s by %s\n",
+				record.Email,
+				record.Method,
+				record.Subject,
+				record.Verified.Format("2006-01-02"),
+				record.VerifiedBy,
+			)
+		}
+		return nil
+	},
+}
+
+// maintainerRevokeCmd represents the maintainer revoke command
+var maintainerRevokeCmd = &cobra.Command{
+	Use:   "revoke [email]",
+	Short: "Remove the verification record of a maintainer",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		indexer, err := newMaintainerIndexer(cmd)
+		if err != nil {
+			return err
+		}
+		if err := indexer.RevokeMaintainer(cmd.Context(), args[0]); err != nil {
+			return err
+		}
+		console.Printf("✅ Revoked the verification of %s\n", args[0])
+		return nil
+	},
+}
+
+func newMaintainerIndexer(cmd *cobra.Command) (*pkg.Indexer, error) {
+	return pkg.NewIndexer(cmd.Context(), pkg.IndexerOpts{
+		Bucket:     bucket,
+		Provider:   provider,
+		Endpoint:   endpoint,
+		Pacing:     pacing,
+		SigningKey: signingKey,
+		LockMode:   lockMode,
+		LockTable:  lockTable,
+		Layout:     layout,
+	})
+}
+
+func init() {
+	rootCmd.AddCommand(maintainerCmd)
+	maintainerCmd.AddCommand(maintainerVerifyCmd)
+	maintainerCmd.AddCommand(maintainerListCmd)
+	maintainerCmd.AddCommand(maintainerRevokeCmd)
+
+	maintainerCmd.PersistentFlags().
+		StringVarP(&bucket, "bucket", "b", "", "bucket serving the registry")
+	maintainerCmd.PersistentFlags().
+		StringVar(&signingKey, "signing-key", "", "key to sign the maintainer records with (or REGISTRY_SIGNING_KEY)")
+	maintainerCmd.PersistentFlags().
+		StringVar(&lockMode, "lock", "", "lock the records while updating them, with 's3' or 'dynamodb' (or REGISTRY_LOCK)")
+	maintainerCmd.PersistentFlags().
+		StringVar(&lockTable, "lock-table", "", "DynamoDB table to hold the lock in with --lock dynamodb (or REGISTRY_LOCK_TABLE)")
+
+	maintainerVerifyCmd.Flags().
+		StringVar(&maintainerMethod, "method", "", "how the maintainer proves their identity: dns, github or email")
+	maintainerVerifyCmd.Flags().
+		StringVar(&maintainerGitHub, "github", "", "GitHub account of the maintainer, with --method github")
+	maintainerVerifyCmd.Flags().
+		StringVar(&maintainerDomain, "domain", "", "domain to verify with --method dns. Defaults to the domain of the email address")
+	maintainerVerifyCmd.Flags().
+		StringVar(&maintainerChallenge, "challenge", "", "challenge the maintainer sent back, with --method email")
+	maintainerVerifyCmd.Flags().
+		BoolVar(&maintainerRenew, "renew", false, "issue a new challenge in place of the pending (or met) one")
+}
@@ -0,0 +1,97 @@
+/*
+Copyright © 2025 Joshua Pare <jpare@omniview.dev>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/omniviewdev/registry-cli/pkg/console"
+	"github.com/omniviewdev/registry-cli/pkg/packager"
+	"github.com/spf13/cobra"
+)
+
+var (
+	metaInitOut      string
+	metaInitForce    bool
+	metaInitTemplate string
+	metaInitDir      string
+)
+
+// metaInitCmd represents the meta init command
+var metaInitCmd = &cobra.Command{
+	Use:   "init",
+	Short: "Interactively create a plugin.yaml",
+	Long: `Prompt for the plugin ID, name, capabilities, maintainers and theme colors, validating
+each answer as it goes, and write the result out as a valid plugin.yaml.
+
+With --template, a new plugin project is scaffolded from a template repository instead. The
+template is cloned into --dir (a directory named after the plugin ID by default), and these
+placeholders are replaced with the answers in its files and file names:
+
+  ` + strings.Join(packager.TemplatePlaceholders, "\n  ") + `
+
+The answers are also written into the template's plugin metadata file, keeping its packaging
+settings:
+
+  registry-cli meta init --template github.com/org/plugin-template`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if _, err := os.Stat(metaInitOut); err == nil && !metaInitForce && metaInitTemplate == "" {
+			return fmt.Errorf("%s already exists. Use --force to overwrite it", metaInitOut)
+		}
+
+		meta, err := packager.PromptPluginMetadata(
+			packager.NewPrompter(cmd.InOrStdin(), cmd.OutOrStdout()),
+		)
+		if err != nil {
+			return err
+		}
+
+		if metaInitTemplate != "" {
+			dir := metaInitDir
+			if dir == "" {
+				dir = meta.ID
+			}
+			if err := packager.HydrateTemplate(metaInitTemplate, dir, meta); err != nil {
+				return err
+			}
+			console.Printf("✅ Created %s from %s\n", dir, metaInitTemplate)
+			return nil
+		}
+
+		if err := meta.Save(metaInitOut); err != nil {
+			return err
+		}
+		console.Printf("✅ Wrote %s\n", metaInitOut)
+		return nil
+	},
+}
+
+func init() {
+	metaCmd.AddCommand(metaInitCmd)
+
+	metaInitCmd.Flags().
+		StringVarP(&metaInitOut, "out", "o", "plugin.yaml", "Path to write the plugin metadata to")
+	metaInitCmd.Flags().
+		BoolVarP(&metaInitForce, "force", "f", false, "Overwrite the metadata file if it already exists")
+	metaInitCmd.Flags().
+		StringVar(&metaInitTemplate, "template", "", "Template repository to scaffold the plugin from (e.g. github.com/org/plugin-template)")
+	metaInitCmd.Flags().
+		StringVar(&metaInitDir, "dir", "", "Directory to scaffold the plugin into with --template. Defaults to the plugin ID")
+}
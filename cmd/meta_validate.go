@@ -0,0 +1,101 @@
+/*
+Copyright © 2025 Joshua Pare <jpare@omniview.dev>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/omniviewdev/registry-cli/pkg/console"
+	"github.com/omniviewdev/registry-cli/pkg/packager"
+	"github.com/spf13/cobra"
+)
+
+var metaValidatePreview string
+
+// metaValidateCmd represents the meta validate command
+var metaValidateCmd = &cobra.Command{
+	Use:   "validate [file]",
+	Short: "Validate a plugin metadata file",
+	Long: `Validate a plugin metadata file against the schema and the fields the registry requires,
+including that every theme color is a hex color (#rgb, #rrggbb or #rrggbbaa). Validates the
+plugin metadata file found in the current directory (or its parents) when no file is given.
+
+With --theme-preview, the theme colors are previewed as ANSI swatches in the terminal (ansi),
+or as an HTML snippet written to stdout (html):
+
+  registry-cli meta validate --theme-preview html > theme.html`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if metaValidatePreview != "" && !slices.Contains(packager.ThemePreviews, metaValidatePreview) {
+			return fmt.Errorf(
+				"Unknown --theme-preview %q, expected one of %s",
+				metaValidatePreview,
+				strings.Join(packager.ThemePreviews, ", "),
+			)
+		}
+
+		var path string
+		if len(args) > 0 {
+			path = args[0]
+		} else {
+			found, err := packager.FindMetadataFile(".")
+			if err != nil {
+				return err
+			}
+			path = found
+		}
+
+		meta, err := packager.LoadPluginMetadata(path)
+		if err == nil {
+			err = meta.Validate()
+		}
+		if err != nil {
+			for _, line := range strings.Split(err.Error(), "\n") {
+				console.Printf("❌ %s\n", line)
+			}
+			return fmt.Errorf("Invalid plugin metadata in %s", path)
+		}
+
+		switch metaValidatePreview {
+		case "":
+		case packager.ThemePreviewHTML:
+			// straight to stdout, so it can be redirected to a file
+			if err := packager.PreviewTheme(os.Stdout, meta.Theme, metaValidatePreview); err != nil {
+				return err
+			}
+			return nil
+		default:
+			if meta.Theme == nil || len(meta.Theme.Colors) == 0 {
+				console.Println("⚠️ The plugin has no theme colors to preview")
+			} else if err := packager.PreviewTheme(console.Stdout, meta.Theme, metaValidatePreview); err != nil {
+				return err
+			}
+		}
+		console.Printf("✅ %s is valid\n", path)
+		return nil
+	},
+}
+
+func init() {
+	metaCmd.AddCommand(metaValidateCmd)
+
+	metaValidateCmd.Flags().
+		StringVar(&metaValidatePreview, "theme-preview", "", "Preview the theme colors, as 'ansi' swatches or an 'html' snippet")
+}
@@ -0,0 +1,125 @@
+/*
+Copyright © 2025 Joshua Pare <jpare@omniview.dev>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/omniviewdev/registry-cli/pkg"
+	"github.com/omniviewdev/registry-cli/pkg/client"
+	"github.com/omniviewdev/registry-cli/pkg/console"
+	"github.com/spf13/cobra"
+)
+
+var (
+	mirrorUpstreamURL string
+	mirrorPlugins     []string
+)
+
+// mirrorUpstreamCmd represents the mirror upstream command
+var mirrorUpstreamCmd = &cobra.Command{
+	Use:   "upstream",
+	Short: "Mirror plugins from the official registry into a private bucket",
+	Long: `Pull the selected plugins from the official Omniview registry (or another upstream
+with --upstream) into your bucket. Every version is mirrored unless versions are pinned with
+plugin@version, which can be repeated to pin several versions:
+
+  registry-cli mirror upstream --bucket my-registry --plugins kubernetes,aws@1.2.0,aws@1.3.0
+
+Artifacts are checked against the upstream checksums before being uploaded, and the download
+URLs in the mirrored indexes are rewritten to point at your bucket. Progress is saved as
+artifacts are mirrored, so a mirror that failed part way can be carried on with --resume.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(mirrorPlugins) == 0 {
+			return fmt.Errorf("Select the plugins to mirror with --plugins")
+		}
+
+		upstream, err := newClient(mirrorUpstreamURL, "")
+		if err != nil {
+			return err
+		}
+
+		indexer, err := pkg.NewIndexer(cmd.Context(), pkg.IndexerOpts{
+			Bucket:     bucket,
+			Provider:   provider,
+			Endpoint:   endpoint,
+			Pacing:     pacing,
+			SigningKey: signingKey,
+			LockMode:   lockMode,
+			LockTable:  lockTable,
+			IndexTable: indexTable,
+			BaseURL:    baseURL,
+			Layout:     layout,
+		})
+		if err != nil {
+			return err
+		}
+
+		publisher, err := pkg.NewPublisher(cmd.Context(), pkg.PublisherOpts{
+			Bucket:   bucket,
+			Provider: provider,
+			Endpoint: endpoint,
+			Pacing:   pacing,
+			Layout:   layout,
+		})
+		if err != nil {
+			return err
+		}
+
+		checkpoint, err := openCheckpoint(
+			"mirror",
+			append([]string{bucket, mirrorUpstreamURL}, mirrorPlugins...)...,
+		)
+		if err != nil {
+			return err
+		}
+
+		targets := pkg.ParseMirrorTargets(mirrorPlugins)
+		mirror := pkg.NewMirror(upstream, publisher, indexer).WithCheckpoint(checkpoint)
+		if err := finishCheckpoint(checkpoint, mirror.Run(cmd.Context(), targets)); err != nil {
+			return err
+		}
+
+		console.Printf("Mirrored %d plugins from %s\n", len(targets), mirrorUpstreamURL)
+		return nil
+	},
+}
+
+func init() {
+	mirrorCmd.AddCommand(mirrorUpstreamCmd)
+
+	mirrorUpstreamCmd.Flags().
+		StringVar(&mirrorUpstreamURL, "upstream", client.OfficialRegistryURL, "URL of the registry to mirror from")
+	mirrorUpstreamCmd.Flags().
+		StringSliceVar(&mirrorPlugins, "plugins", nil, "Plugins to mirror, optionally pinned as plugin@version")
+	mirrorUpstreamCmd.Flags().StringVarP(&bucket, "bucket", "b", "", "bucket to mirror into")
+	mirrorUpstreamCmd.Flags().
+		StringVar(&signingKey, "signing-key", "", "path to the key to sign indexes with (or REGISTRY_SIGNING_KEY)")
+	mirrorUpstreamCmd.Flags().
+		StringVar(&lockMode, "lock", "", "lock the indexes while updating them, with 's3' or 'dynamodb' (or REGISTRY_LOCK)")
+	mirrorUpstreamCmd.Flags().
+		StringVar(&lockTable, "lock-table", "", "DynamoDB table to hold the lock in with --lock dynamodb (or REGISTRY_LOCK_TABLE)")
+	mirrorUpstreamCmd.Flags().
+		StringVar(&indexTable, "index-table", "", "DynamoDB table holding the index records, materialized into the bucket indexes (or REGISTRY_INDEX_TABLE)")
+	mirrorUpstreamCmd.Flags().
+		StringVar(&baseURL, "base-url", "", "URL the bucket is served from, to write absolute download URLs into the index (or REGISTRY_BASE_URL)")
+	mirrorUpstreamCmd.Flags().
+		BoolVar(&resume, "resume", false, "resume the mirror from where an earlier run failed")
+	mirrorUpstreamCmd.Flags().
+		StringVar(&stateFile, "state-file", "", "path of the file progress is saved to (defaults to one in the user cache directory)")
+}
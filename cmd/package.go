@@ -18,19 +18,53 @@ package cmd
 
 import (
 	"fmt"
+	"maps"
 	"path/filepath"
+	"slices"
+	"strings"
 
 	"github.com/omniviewdev/registry-cli/pkg"
+	"github.com/omniviewdev/registry-cli/pkg/console"
 	"github.com/omniviewdev/registry-cli/pkg/packager"
 	"github.com/omniviewdev/registry-cli/pkg/types"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 )
 
 var (
-	clean   bool
-	outdir  string
-	version string
-	publish bool
+	clean      bool
+	checkOnly  bool
+	outdir     string
+	version    string
+	writeVer   bool
+	stamp      bool
+	pinDeps    bool
+	publish    bool
+	reportPath string
+	junitPath  string
+
+	goCache     string
+	goModCache  string
+	goCacheProg string
+
+	commit        string
+	ldflagsID     string
+	ldflagsVer    string
+	ldflagsCommit string
+
+	orgDefaults string
+	metaFile    string
+
+	compatCore    string
+	compatVersion string
+	compatImages  []string
+	compatArgs    []string
+
+	scan       bool
+	scanUI     bool
+	scanFailOn string
+
+	packPlatforms []string
 )
 
 // packageCmd represents the package command
@@ -38,7 +72,17 @@ var packageCmd = &cobra.Command{
 	Use:   "package [path]",
 	Short: "Package a plugin for distribution",
 	Long: `Package compiles the necessary binaries and files into the proper
-location for uploading to the Omniview Plugin Registry.`,
+location for uploading to the Omniview Plugin Registry.
+
+The platforms and build settings can be checked into the plugin repository, in its
+.registry-cli.yaml, with the flags given taking precedence:
+
+  platforms: [linux_amd64, darwin_arm64]
+  build:
+    out: dist
+    stamp: true
+    ldflags:
+      version: main.version`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		switch len(args) {
 		case 0:
@@ -53,65 +97,354 @@ location for uploading to the Omniview Plugin Registry.`,
 			return fmt.Errorf("Must supply a bucket when --publish is set to true")
 		}
 
+		startPorcelain()
+
+		if err := applyBuildSettings(cmd); err != nil {
+			return err
+		}
+		platforms, err := packagePlatforms(cmd)
+		if err != nil {
+			return err
+		}
+		postHooks, err := postPublishHooks()
+		if err != nil {
+			return err
+		}
+
+		if metaFile == "" {
+			metaFile = viper.GetString("metadata_file")
+		}
+		if orgDefaults == "" {
+			orgDefaults = viper.GetString("org_defaults")
+		}
+		var defaults *packager.OrgDefaults
+		if orgDefaults != "" {
+			var err error
+			if defaults, err = packager.LoadOrgDefaults(orgDefaults); err != nil {
+				return err
+			}
+		}
+
+		var compatTests []packager.CompatTest
+		if compatCore != "" {
+			if compatVersion == "" {
+				return fmt.Errorf("Must supply --compat-version when testing against --compat-core")
+			}
+			compatTests = append(compatTests, packager.CompatTest{
+				CoreVersion: compatVersion,
+				Core:        compatCore,
+				Args:        compatArgs,
+			})
+		}
+		for _, image := range compatImages {
+			compatTests = append(compatTests, packager.CompatTest{
+				Image: image,
+				Args:  compatArgs,
+			})
+		}
+
+		failOn, err := types.ParseSeverity(scanFailOn)
+		if err != nil {
+			return fmt.Errorf("Invalid --scan-fail-on: %w", err)
+		}
+
+		report := types.NewPublishReport()
 		opts := packager.PackOpts{
-			PluginDir: args[0],
-			OutDir:    outdir,
-			Version:   version,
-			Clean:     clean,
+			PluginDir:    args[0],
+			OutDir:       outdir,
+			Version:      version,
+			WriteVersion: writeVer,
+			Stamp:        stamp,
+			Clean:        clean,
+			Report:       report,
+			JUnitReport:  junitPath,
+			GoCache:      goCache,
+			GoModCache:   goModCache,
+			GoCacheProg:  goCacheProg,
+			Commit:       commit,
+			LDFlags: packager.LDFlagVars{
+				ID:      ldflagsID,
+				Version: ldflagsVer,
+				Commit:  ldflagsCommit,
+			},
+			OrgDefaults:  defaults,
+			MetadataFile: metaFile,
+			CompatTests:  compatTests,
+			Platforms:    platforms,
+		}
+		if scan || scanUI {
+			opts.Scan = &packager.ScanOpts{UI: scanUI}
+		}
+		if pinDeps {
+			opts.PinDependencies = func(requires map[string]string) (map[string]string, error) {
+				registries, err := newRegistries()
+				if err != nil {
+					return nil, err
+				}
+				return registries.Pin(cmd.Context(), requires)
+			}
+		}
+
+		if checkOnly {
+			return checkPackage(opts)
 		}
 
-		meta, err := packager.RunPackCommand(opts)
+		result, err := packager.RunPackCommand(opts)
 		if err != nil {
 			return err
 		}
+		printPackResult(result)
+		printScanResult(result.Scan, failOn)
+		meta := result.Metadata
 
 		if !publish {
+			printPorcelain(report)
 			return nil
 		}
 
-		fmt.Println("Publishing to registry...")
+		report.Plugin = meta.ID
+		report.Version = meta.Version
+		if reportPath == "" {
+			reportPath = filepath.Join(args[0], outdir, "publish-report.json")
+		}
 
-		// we're going to also publish to the registry
-		publishOpts := types.PublishOpts{
-			Plugin:       meta.ID,
-			Version:      meta.Version,
-			MetadataPath: filepath.Join(args[0], "plugin.yaml"),
-			DarwinAMD64:  filepath.Join(outdir, "darwin_amd64.tar.gz"),
-			DarwinARM64:  filepath.Join(outdir, "darwin_arm64.tar.gz"),
-			WindowsAMD64: filepath.Join(outdir, "windows_amd64.tar.gz"),
-			WindowsARM64: filepath.Join(outdir, "windows_arm64.tar.gz"),
-			LinuxAMD64:   filepath.Join(outdir, "linux_amd64.tar.gz"),
-			LinuxARM64:   filepath.Join(outdir, "linux_arm64.tar.gz"),
+		if blocking := result.Scan.AtLeast(failOn); len(blocking) > 0 {
+			err = fmt.Errorf(
+				"Not publishing, the scan found %d vulnerabilities of %s severity or higher",
+				len(blocking),
+				failOn,
+			)
+		} else {
+			err = publishPackage(cmd, args[0], meta, result, report, postHooks)
+		}
+		report.Finish(err)
+		if err == nil {
+			printPorcelain(report)
 		}
+		if writeErr := report.Write(reportPath); writeErr != nil {
+			console.Printf("Failed to write publish report: %v\n", writeErr)
+		} else {
+			console.Printf("Wrote publish report to %s\n", reportPath)
+		}
+		return err
+	},
+}
 
-		indexer, err := pkg.NewIndexer(cmd.Context(), pkg.IndexerOpts{
-			Bucket: bucket,
-		})
-		if err != nil {
-			return err
+// printPackResult prints the outcome of each platform of the packaging.
+func printPackResult(result *packager.PackResult) {
+	for _, platform := range result.Platforms {
+		if platform.Err != nil {
+			console.Printf("❌ Build failed for %s: %v\n", platform.Platform.Key(), platform.Err)
+			continue
 		}
+		console.Printf("✅ Packaged %s → %s\n", platform.Platform.Key(), platform.Artifact)
+	}
 
-		publisher, err := pkg.NewPublisher(cmd.Context(), pkg.PublisherOpts{
-			Bucket: bucket,
-		})
-		if err != nil {
-			return err
+	console.Printf("\nSuccessfully packaged plugin for distribution\n")
+	console.Printf("Build logs are in %s\n", result.LogDir)
+}
+
+// printScanResult prints the findings of the vulnerability scans, if they were run.
+func printScanResult(scan *types.ScanReport, failOn types.Severity) {
+	if scan == nil {
+		return
+	}
+	if len(scan.Findings) == 0 {
+		console.Printf("✅ No known vulnerabilities found by %s\n", strings.Join(scan.Scanners, ", "))
+		return
+	}
+	for _, finding := range scan.Findings {
+		mark := "⚠️"
+		if finding.Severity.AtLeast(failOn) {
+			mark = "❌"
 		}
+		console.Printf(
+			"%s [%s] %s in %s: %s\n",
+			mark,
+			finding.Severity,
+			finding.ID,
+			finding.Package,
+			finding.Summary,
+		)
+		if finding.Fixed != "" {
+			console.Printf("   fixed in %s\n", finding.Fixed)
+		}
+	}
+}
 
-		if err := publisher.Publish(cmd.Context(), publishOpts); err != nil {
-			return err
+// checkPackage validates the plugin and prints its build plan, without building anything.
+func checkPackage(opts packager.PackOpts) error {
+	plan, err := packager.CheckPackage(opts)
+	if plan != nil {
+		console.Printf("Plugin:     %s@%s\n", plan.PluginID, plan.Version)
+		platforms := make([]string, 0, len(plan.Platforms))
+		for _, plat := range plan.Platforms {
+			platforms = append(platforms, plat.Key())
+		}
+		console.Printf("Platforms:  %s\n", strings.Join(platforms, ", "))
+		if plan.BuildCommand != "" {
+			console.Printf("Binary:     %s\n", plan.BuildCommand)
+		} else {
+			console.Printf("Binary:     go build %s\n", plan.Entrypoint)
+		}
+		if plan.LDFlags != "" {
+			console.Printf("LDFlags:    %s\n", plan.LDFlags)
+		}
+		for _, step := range plan.UI {
+			target := "all platforms"
+			if len(plan.UI) > 1 {
+				keys := make([]string, 0, len(step.Platforms))
+				for _, plat := range step.Platforms {
+					keys = append(keys, plat.Key())
+				}
+				target = strings.Join(keys, ", ")
+			}
+			console.Printf("UI:         %s (in %s) for %s\n", strings.Join(step.Command, " "), plan.UIDir, target)
 		}
-		if err := indexer.UpdateIndex(cmd.Context(), publishOpts); err != nil {
+		for _, tool := range slices.Sorted(maps.Keys(plan.Toolchains)) {
+			console.Printf("Toolchain:  %s (%s)\n", tool, plan.Toolchains[tool])
+		}
+	}
+	if err != nil {
+		for _, line := range strings.Split(err.Error(), "\n") {
+			console.Printf("❌ %s\n", line)
+		}
+		return fmt.Errorf("Plugin failed the package checks")
+	}
+	console.Println("✅ Plugin is ready to package")
+	return nil
+}
+
+// publishPackage publishes the freshly packaged plugin to the registry, recording the results
+// into the report. Only the platforms that built are published.
+func publishPackage(
+	cmd *cobra.Command,
+	pluginDir string,
+	meta *packager.PluginMetadata,
+	result *packager.PackResult,
+	report *types.PublishReport,
+	postHooks []pkg.PostPublishHook,
+) error {
+	artifacts := result.Packaged()
+	if len(artifacts) == 0 {
+		return fmt.Errorf("No builds to publish, every platform failed to build")
+	}
+	if len(artifacts) < len(result.Platforms) {
+		console.Printf(
+			"⚠️ Only publishing %d of %d platforms, the others failed to build\n",
+			len(artifacts),
+			len(result.Platforms),
+		)
+	}
+	console.Println("Publishing to registry...")
+
+	compatibility, err := types.ParseTestedWith(testedWith)
+	if err != nil {
+		return err
+	}
+	compatibility = report.Compatibility.Merge(compatibility)
+
+	// we're going to also publish to the registry
+	publishOpts := types.PublishOpts{
+		Plugin:        meta.ID,
+		Version:       meta.Version,
+		MetadataPath:  filepath.Join(pluginDir, outdir, "plugin.yaml"),
+		Compatibility: compatibility,
+		Report:        report,
+		Artifacts:     make(map[string]types.Artifact, len(artifacts)),
+	}
+	for _, artifact := range artifacts {
+		if err := publishOpts.SetPlatform(artifact.Platform.Key(), artifact.Artifact); err != nil {
 			return err
 		}
+		if artifact.Checksum != "" {
+			publishOpts.Artifacts[artifact.Platform.Key()] = types.Artifact{
+				Checksum: artifact.Checksum,
+				Size:     artifact.Size,
+			}
+		}
+	}
+
+	indexer, err := pkg.NewIndexer(cmd.Context(), pkg.IndexerOpts{
+		Bucket:     bucket,
+		Provider:   provider,
+		Endpoint:   endpoint,
+		Pacing:     pacing,
+		SigningKey: signingKey,
+		LockMode:   lockMode,
+		LockTable:  lockTable,
+		IndexTable: indexTable,
+		BaseURL:    baseURL,
+		Layout:     layout,
+	})
+	if err != nil {
+		return err
+	}
+
+	hooks, err := publishHooks()
+	if err != nil {
+		return err
+	}
+	partSize, concurrency, err := uploadSettings(cmd)
+	if err != nil {
+		return err
+	}
+	publisher, err := pkg.NewPublisher(cmd.Context(), pkg.PublisherOpts{
+		Bucket:                 bucket,
+		Provider:               provider,
+		Endpoint:               endpoint,
+		Pacing:                 pacing,
+		StorageClass:           storageClass,
+		PrereleaseStorageClass: prereleaseStorageClass,
+		Hooks:                  hooks,
+		Moderated:              pending,
+		PartSize:               partSize,
+		Concurrency:            concurrency,
+		OnPartialFailure:       partialFailurePolicy(),
+		EncryptTo:              encryptionRecipients(),
+		Layout:                 layout,
+	})
+	if err != nil {
+		return err
+	}
+
+	err = pkg.PublishVersion(cmd.Context(), publisher, indexer, publishOpts)
+	if err := checkPartialPublish(err); err != nil {
+		return err
+	}
 
-		fmt.Printf(
+	if publisher.Moderated() {
+		console.Printf(
+			"Submitted new plugin version for review: %s[%s]\n",
+			publishOpts.Plugin,
+			publishOpts.Version,
+		)
+	} else {
+		console.Printf(
 			"Published new plugin version: %s[%s]\n",
 			publishOpts.Plugin,
 			publishOpts.Version,
 		)
-		return nil
-	},
+	}
+	runPostPublishHooks(cmd, postHooks, publishOpts, err, publisher.Moderated())
+	return nil
+}
+
+// packagePlatforms returns the platforms to package for, given with --platform or set with
+// 'platforms' in the config. Empty packages for every supported platform.
+func packagePlatforms(cmd *cobra.Command) ([]packager.Platform, error) {
+	if !cmd.Flags().Changed("platform") {
+		configured, err := configuredPlatforms()
+		if err != nil {
+			return nil, err
+		}
+		packPlatforms = configured
+	}
+	platforms, err := packager.ParsePlatforms(packPlatforms)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid --platform: %w", err)
+	}
+	return platforms, nil
 }
 
 func init() {
@@ -119,13 +452,100 @@ func init() {
 
 	packageCmd.Flags().
 		BoolVarP(&clean, "clean", "c", true, "Clean the output directory before packaging")
+	packageCmd.Flags().
+		BoolVar(&checkOnly, "check", false, "Validate the plugin and print the build plan without building anything")
 	packageCmd.Flags().
 		StringVarP(&outdir, "out", "o", "build", "Output directory for the plugin packages")
 	packageCmd.Flags().
 		StringVarP(&version, "version", "v", "", "Version to use for the build. Defaults to what is in the plugin.yaml")
+	packageCmd.Flags().
+		BoolVar(&writeVer, "write-version", false, "Write the --version back into the source plugin.yaml, instead of only the packaged copy")
+	packageCmd.Flags().
+		BoolVar(&stamp, "stamp", false, "Stamp the commit and build time into the packaged plugin.yaml, leaving the source one untouched")
+	packageCmd.Flags().
+		BoolVar(&pinDeps, "pin-dependencies", false, "Pin the dependencies to the exact versions they resolve to in the configured registries, recorded in the packaged plugin.yaml")
+
+	packageCmd.Flags().
+		StringSliceVar(&packPlatforms, "platform", nil, "Platforms to package for, e.g. linux_amd64,darwin_arm64 (default is 'platforms' in the config file, or every supported platform)")
+
+	packageCmd.Flags().
+		StringVar(&goCache, "gocache", "", "Shared GOCACHE directory to use for the binary builds")
+	packageCmd.Flags().
+		StringVar(&goModCache, "gomodcache", "", "Shared GOMODCACHE directory to use for the binary builds")
+	packageCmd.Flags().
+		StringVar(&goCacheProg, "gocacheprog", "", "GOCACHEPROG command to use as a remote build cache backend")
+
+	packageCmd.Flags().
+		StringVar(&commit, "commit", "", "Commit to embed in the binaries. Defaults to the checked out git commit")
+	packageCmd.Flags().
+		StringVar(&ldflagsID, "ldflags-id-var", packager.DefaultLDFlagVars.ID, "Variable to inject the plugin ID into")
+	packageCmd.Flags().
+		StringVar(&ldflagsVer, "ldflags-version-var", packager.DefaultLDFlagVars.Version, "Variable to inject the plugin version into")
+	packageCmd.Flags().
+		StringVar(&ldflagsCommit, "ldflags-commit-var", packager.DefaultLDFlagVars.Commit, "Variable to inject the commit into")
+
+	packageCmd.Flags().
+		StringVar(&orgDefaults, "org-defaults", "", "Org defaults file that plugin.yaml inherits from. Can also be set with 'org_defaults' in the config")
+	packageCmd.Flags().
+		StringVar(&metaFile, "metadata-file", "", "Plugin metadata file to package with. Defaults to the first plugin.yaml, plugin.json or omniview.yaml found in the plugin directory or its parents. Can also be set with 'metadata_file' in the config")
+
+	packageCmd.Flags().
+		StringVar(&compatCore, "compat-core", "", "Omniview core binary to run a handshake test of the plugin against after building")
+	packageCmd.Flags().
+		StringVar(&compatVersion, "compat-version", "", "Core version to record the --compat-core result under (e.g. 1.0.x)")
+	packageCmd.Flags().
+		StringSliceVar(&compatImages, "compat-image", nil, "Omniview core container images to run a handshake test against, recorded under their tags")
+	packageCmd.Flags().
+		StringSliceVar(&compatArgs, "compat-args", nil, "Arguments to run the core's handshake test with, {plugin} being the plugin directory. Defaults to 'plugin,handshake,{plugin}'")
 
+	packageCmd.Flags().
+		BoolVar(&scan, "scan", false, "Scan the plugin module for known vulnerabilities with govulncheck before building, recording the findings in the publish report")
+	packageCmd.Flags().
+		BoolVar(&scanUI, "scan-ui", false, "Also audit the UI dependencies with pnpm audit (implies --scan)")
+	packageCmd.Flags().
+		StringVar(&scanFailOn, "scan-fail-on", string(types.SeverityCritical), "Severity of the scan findings that stop a publish: low, moderate, high or critical")
+
+	packageCmd.Flags().
+		BoolVar(&porcelain, "porcelain", false, "Only print tab separated artifact, checksum, size and uploaded lines for scripts")
 	packageCmd.Flags().
 		BoolVarP(&publish, "publish", "p", false, "Publish the builds to the registry after building")
 	packageCmd.Flags().
 		StringVarP(&bucket, "bucket", "b", "", "Bucket to use when running with the 'publish' flag")
+	packageCmd.Flags().
+		StringVar(&signingKey, "signing-key", "", "Key to sign the registry indexes with when publishing (or REGISTRY_SIGNING_KEY)")
+	packageCmd.Flags().
+		StringVar(&lockMode, "lock", "", "Lock the indexes while updating them, with 's3' or 'dynamodb' (or REGISTRY_LOCK)")
+	packageCmd.Flags().
+		StringVar(&lockTable, "lock-table", "", "DynamoDB table to hold the lock in with --lock dynamodb (or REGISTRY_LOCK_TABLE)")
+	packageCmd.Flags().
+		StringVar(&indexTable, "index-table", "", "DynamoDB table holding the index records, materialized into the bucket indexes (or REGISTRY_INDEX_TABLE)")
+	packageCmd.Flags().
+		StringVar(&baseURL, "base-url", "", "URL the bucket is served from, to write absolute download URLs into the index when publishing (or REGISTRY_BASE_URL)")
+	packageCmd.Flags().
+		StringSliceVar(&testedWith, "tested-with", nil, "Omniview core versions the release was tested against when publishing (e.g. 0.9.x,1.0.x)")
+	packageCmd.Flags().
+		StringArrayVar(&hookCommands, "hook", nil, "Shell command vetting each build before anything is published, given the tarball as $1. Adds to 'publish_hooks' in the config")
+	packageCmd.Flags().
+		StringArrayVar(&hookURLs, "hook-url", nil, "URL POSTed each build before anything is published, vetoing it with a non-2xx response. Adds to 'publish_hooks' in the config")
+	packageCmd.Flags().
+		BoolVar(&pending, "pending", false, "Upload into the pending area for a registry admin to approve with 'registry-cli review approve' (or REGISTRY_MODERATED=true)")
+	packageCmd.Flags().
+		StringVar(&storageClass, "storage-class", "", "S3 storage class to upload the builds with when publishing (e.g. STANDARD_IA)")
+	packageCmd.Flags().
+		StringVar(&prereleaseStorageClass, "prerelease-storage-class", "", "S3 storage class to upload prerelease builds with. Defaults to --storage-class")
+	packageCmd.Flags().
+		StringVar(&partSize, "part-size", "", "Upload builds larger than this in parts of this size when publishing, e.g. 64MiB (default is 'upload_part_size' in the config file, or a single request; see 'registry-cli bench upload')")
+	packageCmd.Flags().
+		IntVar(&uploadConcurrency, "upload-concurrency", 0, "Parts of a build to upload at once with --part-size (default is 'upload_concurrency' in the config file, or 5)")
+	packageCmd.Flags().
+		BoolVar(&atomicPublish, "atomic", false, "When some builds fail to upload, delete the ones that were uploaded (by default they're left for a retry to overwrite)")
+	packageCmd.Flags().
+		BoolVar(&bestEffortPublish, "best-effort", false, "When some builds fail to upload, publish the ones that were uploaded without the failed platforms")
+	packageCmd.MarkFlagsMutuallyExclusive("atomic", "best-effort")
+	packageCmd.Flags().
+		StringArrayVar(&encryptTo, "encrypt-to", nil, "Encrypt the builds before publishing them to an age public key, an AWS KMS key as kms:<key>, or a file holding a shared key or age public keys. Adds to 'encrypt_to' in the config")
+	packageCmd.Flags().
+		StringVar(&reportPath, "report", "", "Path to write the publish report to. Defaults to <plugin>/<out>/publish-report.json")
+	packageCmd.Flags().
+		StringVar(&junitPath, "junit", "", "Path to write a JUnit XML report of the platform and UI builds to")
 }
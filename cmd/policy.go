@@ -0,0 +1,98 @@
+/*
+Copyright © 2025 Joshua Pare <jpare@omniview.dev>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/omniviewdev/registry-cli/pkg"
+	"github.com/omniviewdev/registry-cli/pkg/console"
+	"github.com/spf13/cobra"
+)
+
+var (
+	policyKeepVersions      int
+	policyPrereleaseTTLDays int
+)
+
+// policyCmd represents the policy command
+var policyCmd = &cobra.Command{
+	Use:   "policy",
+	Short: "Show or update the registry retention policy",
+	Long: `Show the retention policy stored in the registry (policy.json), or update it with
+the flags. The policy is enforced by 'registry-cli gc', and publishing warns about versions it
+will remove. A value of 0 disables that part of the policy.
+
+  registry-cli policy --bucket my-registry --keep-versions 10 --prerelease-ttl-days 30`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		indexer, err := pkg.NewIndexer(cmd.Context(), pkg.IndexerOpts{
+			Bucket:   bucket,
+			Provider: provider,
+			Endpoint: endpoint,
+			Pacing:   pacing,
+			Layout:   layout,
+		})
+		if err != nil {
+			return err
+		}
+
+		policy, err := indexer.Policy(cmd.Context())
+		if err != nil {
+			return err
+		}
+
+		keep, ttl := cmd.Flags().Changed("keep-versions"), cmd.Flags().Changed("prerelease-ttl-days")
+		if keep || ttl {
+			if policyKeepVersions < 0 || policyPrereleaseTTLDays < 0 {
+				return fmt.Errorf("Policy values can't be negative")
+			}
+			if keep {
+				policy.KeepVersions = policyKeepVersions
+			}
+			if ttl {
+				policy.PrereleaseTTLDays = policyPrereleaseTTLDays
+			}
+			if err := indexer.SetPolicy(cmd.Context(), policy); err != nil {
+				return err
+			}
+			console.Println("✅ Updated retention policy")
+		}
+
+		if policy.IsZero() {
+			console.Println("No retention policy, all versions are kept")
+			return nil
+		}
+		if policy.KeepVersions > 0 {
+			console.Printf("Keep versions:       %d most recent\n", policy.KeepVersions)
+		}
+		if policy.PrereleaseTTLDays > 0 {
+			console.Printf("Prerelease TTL:      %d days\n", policy.PrereleaseTTLDays)
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(policyCmd)
+
+	policyCmd.Flags().StringVarP(&bucket, "bucket", "b", "", "bucket of the registry")
+	policyCmd.Flags().
+		IntVar(&policyKeepVersions, "keep-versions", 0, "number of most recent versions of each plugin to keep")
+	policyCmd.Flags().
+		IntVar(&policyPrereleaseTTLDays, "prerelease-ttl-days", 0, "number of days to keep prerelease versions for")
+}
@@ -0,0 +1,70 @@
+/*
+Copyright © 2025 Joshua Pare <jpare@omniview.dev>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package cmd
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+
+	"github.com/omniviewdev/registry-cli/pkg/console"
+	"github.com/omniviewdev/registry-cli/pkg/types"
+)
+
+// porcelain silences the usual output for stable, machine readable lines
+var porcelain bool
+
+// startPorcelain silences the usual output when --porcelain is set.
+func startPorcelain() {
+	if porcelain {
+		console.SetQuiet(true)
+	}
+}
+
+// printPorcelain prints the results of the report as tab separated lines of kind, platform
+// and value, with the kinds being artifact, checksum, size and uploaded:
+//
+//	artifact	linux_amd64	build/linux_amd64.tar.gz
+//	checksum	linux_amd64	5f2b...
+//	size	linux_amd64	1048576
+//	uploaded	linux_amd64	s3://my-registry/my-plugin/1.0.0/linux_amd64.tar.gz
+func printPorcelain(report *types.PublishReport) {
+	if !porcelain {
+		return
+	}
+	for _, platform := range slices.Sorted(maps.Keys(report.Platforms)) {
+		result := report.Platforms[platform]
+		for _, line := range []struct{ kind, value string }{
+			{"artifact", result.Artifact},
+			{"checksum", result.Checksum},
+			{"size", sizeString(result.Size)},
+			{"uploaded", result.UploadedURL},
+		} {
+			if line.value != "" {
+				fmt.Fprintf(os.Stdout, "%s\t%s\t%s\n", line.kind, platform, line.value)
+			}
+		}
+	}
+}
+
+func sizeString(size int64) string {
+	if size == 0 {
+		return ""
+	}
+	return fmt.Sprint(size)
+}
@@ -0,0 +1,95 @@
+/*
+Copyright © 2025 Joshua Pare <jpare@omniview.dev>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package cmd
+
+import (
+	"runtime"
+	"time"
+
+	"github.com/omniviewdev/registry-cli/pkg"
+	"github.com/omniviewdev/registry-cli/pkg/console"
+	"github.com/spf13/cobra"
+)
+
+var (
+	presignPlatform  string
+	presignPrincipal string
+	presignExpires   time.Duration
+)
+
+// presignCmd represents the presign command
+var presignCmd = &cobra.Command{
+	Use:   "presign [plugin] [version]",
+	Short: "Print a presigned URL downloading the build of a plugin",
+	Long: `Presign prints a URL downloading the build of a plugin from the bucket that expires after
+--expires, for handing a build out of a bucket that isn't public. The version is a semver
+constraint, the latest version when left out:
+
+  registry-cli presign acme-billing 1.4.x --for team-payments -b my-registry
+
+Builds of private plugins are only presigned for the principals on their access list (see
+'registry-cli access'), given with --for. Only buckets of the s3 provider can be presigned.`,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		indexer, err := pkg.NewIndexer(cmd.Context(), pkg.IndexerOpts{
+			Bucket:     bucket,
+			Provider:   provider,
+			Endpoint:   endpoint,
+			Pacing:     pacing,
+			IndexTable: indexTable,
+			BaseURL:    baseURL,
+			Layout:     layout,
+		})
+		if err != nil {
+			return err
+		}
+
+		version := ""
+		if len(args) > 1 {
+			version = args[1]
+		}
+		url, err := indexer.Presign(
+			cmd.Context(),
+			args[0],
+			version,
+			presignPlatform,
+			presignPrincipal,
+			presignExpires,
+		)
+		if err != nil {
+			return err
+		}
+		console.Println(url)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(presignCmd)
+
+	presignCmd.Flags().StringVarP(&bucket, "bucket", "b", "", "bucket serving the registry")
+	presignCmd.Flags().
+		StringVar(&presignPlatform, "platform", runtime.GOOS+"_"+runtime.GOARCH, "platform of the build (e.g. linux_amd64)")
+	presignCmd.Flags().
+		StringVar(&presignPrincipal, "for", "", "principal the URL is for, who must be on the access list of a private plugin")
+	presignCmd.Flags().
+		DurationVar(&presignExpires, "expires", time.Hour, "how long the URL is valid for (at most 168h)")
+	presignCmd.Flags().
+		StringVar(&indexTable, "index-table", "", "DynamoDB table holding the index records, materialized into the bucket indexes (or REGISTRY_INDEX_TABLE)")
+	presignCmd.Flags().
+		StringVar(&baseURL, "base-url", "", "URL the bucket is served from, to find the builds the index gives absolute download URLs of (or REGISTRY_BASE_URL)")
+}
@@ -0,0 +1,239 @@
+/*
+Copyright © 2025 Joshua Pare <jpare@omniview.dev>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/omniviewdev/registry-cli/pkg"
+	"github.com/omniviewdev/registry-cli/pkg/console"
+	"github.com/omniviewdev/registry-cli/pkg/packager"
+	"github.com/omniviewdev/registry-cli/pkg/types"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// projectConfigName is the config file a plugin repository checks in, with the settings of
+// the team publishing it: its hooks, platforms and build settings. For example:
+//
+//	platforms: [linux_amd64, darwin_arm64]
+//	build:
+//	  out: dist
+//	  stamp: true
+//	hooks:
+//	  pre_publish:
+//	    - command: ./scripts/scan.sh
+//	  post_publish:
+//	    - url: https://deploy.example.com/plugins
+//	  notifications:
+//	    - url: $SLACK_WEBHOOK_URL
+const projectConfigName = ".registry-cli.yaml"
+
+// projectSettings are the sections of the config a project config file sets. Anything else in
+// it is ignored: what the user trusts, like the pinned keys and registries, isn't up to files
+// checked into the repositories they run in.
+var projectSettings = []string{"hooks", "platforms", "build"}
+
+// mergeProjectConfig merges the project config file of the working directory's repository over
+// the user's config file. Flags still take precedence over both.
+func mergeProjectConfig() error {
+	dir, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	path, err := loadProjectConfig(viper.GetViper(), dir)
+	if err != nil || path == "" {
+		return err
+	}
+	fmt.Fprintln(os.Stderr, "Using project config file:", path)
+	return nil
+}
+
+// loadProjectConfig merges the project settings of the closest project config file from dir
+// over the config, returning the path of the file, or an empty path when there's none.
+func loadProjectConfig(config *viper.Viper, dir string) (string, error) {
+	path := findProjectConfig(dir)
+	if path == "" {
+		return "", nil
+	}
+	if used := config.ConfigFileUsed(); used != "" && sameFile(used, path) {
+		// the user's config file, when running from the home directory
+		return "", nil
+	}
+
+	project := viper.New()
+	project.SetConfigFile(path)
+	project.SetConfigType("yaml")
+	if err := project.ReadInConfig(); err != nil {
+		return "", fmt.Errorf("Invalid project config file %s: %w", path, err)
+	}
+	settings := make(map[string]any)
+	for key, value := range project.AllSettings() {
+		if !slices.Contains(projectSettings, key) {
+			fmt.Fprintf(
+				os.Stderr,
+				"Ignoring %s in the project config file %s, only %s are read from it\n",
+				key,
+				path,
+				strings.Join(projectSettings, ", "),
+			)
+			continue
+		}
+		settings[key] = value
+	}
+	if err := config.MergeConfigMap(settings); err != nil {
+		return "", fmt.Errorf("Invalid project config file %s: %w", path, err)
+	}
+	return path, nil
+}
+
+// findProjectConfig returns the path of the closest project config file from dir up to the
+// root of its repository, looking in dir alone when it isn't in a repository. It's empty when
+// there's none.
+func findProjectConfig(dir string) string {
+	return packager.FindInRepository(dir, projectConfigName)
+}
+
+func sameFile(a, b string) bool {
+	infoA, errA := os.Stat(a)
+	infoB, errB := os.Stat(b)
+	return errA == nil && errB == nil && os.SameFile(infoA, infoB)
+}
+
+// configuredPlatforms returns the platforms set with 'platforms' in the config, as os_arch
+// keys, or nil when unset.
+func configuredPlatforms() ([]string, error) {
+	keys := viper.GetStringSlice("platforms")
+	if len(keys) == 0 {
+		return nil, nil
+	}
+	platforms, err := packager.ParsePlatforms(keys)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid platforms configuration: %w", err)
+	}
+	configured := make([]string, 0, len(platforms))
+	for _, platform := range platforms {
+		configured = append(configured, platform.Key())
+	}
+	return configured, nil
+}
+
+// buildSettings are the flags of the package command that can be set in the 'build' section of
+// the config, mapped to their keys
+var buildSettings = map[string]string{
+	"out":                 "build.out",
+	"gocache":             "build.gocache",
+	"gomodcache":          "build.gomodcache",
+	"gocacheprog":         "build.gocacheprog",
+	"ldflags-id-var":      "build.ldflags.id",
+	"ldflags-version-var": "build.ldflags.version",
+	"ldflags-commit-var":  "build.ldflags.commit",
+	"stamp":               "build.stamp",
+	"pin-dependencies":    "build.pin_dependencies",
+	"scan":                "build.scan",
+	"scan-ui":             "build.scan_ui",
+	"scan-fail-on":        "build.scan_fail_on",
+}
+
+// applyBuildSettings sets the flags of the command that weren't given from the 'build' section
+// of the config.
+func applyBuildSettings(cmd *cobra.Command) error {
+	for flag, key := range buildSettings {
+		if cmd.Flags().Changed(flag) || !viper.IsSet(key) {
+			continue
+		}
+		if err := cmd.Flags().Set(flag, viper.GetString(key)); err != nil {
+			return fmt.Errorf("Invalid %s configuration: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// hookConfigs returns the hooks configured with a key of the config.
+func hookConfigs(key string) ([]pkg.HookConfig, error) {
+	var configs []pkg.HookConfig
+	if err := viper.UnmarshalKey(key, &configs); err != nil {
+		return nil, fmt.Errorf("invalid %s configuration: %w", key, err)
+	}
+	return configs, nil
+}
+
+// postPublishHooks returns the hooks run after a publish, configured with 'hooks.post_publish'
+// and 'hooks.notifications'.
+func postPublishHooks() ([]pkg.PostPublishHook, error) {
+	var hooks []pkg.PostPublishHook
+	configs, err := hookConfigs("hooks.post_publish")
+	if err != nil {
+		return nil, err
+	}
+	for _, config := range configs {
+		hook, err := config.PostPublishHook()
+		if err != nil {
+			return nil, fmt.Errorf("invalid hooks.post_publish configuration: %w", err)
+		}
+		hooks = append(hooks, hook)
+	}
+
+	if configs, err = hookConfigs("hooks.notifications"); err != nil {
+		return nil, err
+	}
+	for _, config := range configs {
+		hook, err := config.Notification()
+		if err != nil {
+			return nil, fmt.Errorf("invalid hooks.notifications configuration: %w", err)
+		}
+		hooks = append(hooks, hook)
+	}
+	return hooks, nil
+}
+
+// runPostPublishHooks runs the hooks after a publish, warning about the ones that failed as the
+// version is published regardless. The platforms a best effort publish left out aren't part of
+// the event.
+func runPostPublishHooks(
+	cmd *cobra.Command,
+	hooks []pkg.PostPublishHook,
+	opts types.PublishOpts,
+	publishErr error,
+	pending bool,
+) {
+	if len(hooks) == 0 {
+		return
+	}
+	var failed []string
+	var partial *pkg.PartialPublishError
+	if errors.As(publishErr, &partial) {
+		failed = partial.Failed
+	}
+	event := pkg.PublishEvent{Plugin: opts.Plugin, Version: opts.Version, Pending: pending}
+	for _, release := range opts.ToReleases() {
+		if !slices.Contains(failed, release.OSArch()) {
+			event.Platforms = append(event.Platforms, release.OSArch())
+		}
+	}
+
+	if err := pkg.RunPostPublishHooks(cmd.Context(), hooks, event); err != nil {
+		console.Printf(
+			"⚠️ Some post-publish hooks failed:\n%s\n",
+			strings.TrimSpace(err.Error()),
+		)
+	}
+}
@@ -0,0 +1,128 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+// testRepository creates a repository with the project config file at its root, returning the
+// directory of the repository and a directory within it.
+func testRepository(t *testing.T, config string) (string, string) {
+	t.Helper()
+	repo := filepath.Join(t.TempDir(), "plugin")
+	dir := filepath.Join(repo, "ui", "src")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(repo, ".git"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(repo, projectConfigName)
+	if err := os.WriteFile(path, []byte(config), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return repo, dir
+}
+
+func TestLoadProjectConfig(t *testing.T) {
+	repo, dir := testRepository(t, `
+platforms: [linux_amd64]
+build:
+  out: dist
+hooks:
+  pre_publish:
+    - command: ./scan.sh
+registry: https://attacker.example.com
+registry_token: stolen
+registries: [https://attacker.example.com]
+decryption_keys: [attacker.key]
+trust:
+  keys: [attacker.pub]
+  require_signatures: false
+`)
+	config := viper.New()
+	if err := config.MergeConfigMap(map[string]any{
+		"registry":   "https://registry.example.com",
+		"registries": []string{"https://registry.example.com"},
+		"trust": map[string]any{
+			"keys":               []string{"registry.pub"},
+			"require_signatures": true,
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	path, err := loadProjectConfig(config, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if path != filepath.Join(repo, projectConfigName) {
+		t.Fatalf("loaded %q", path)
+	}
+
+	// the project settings are merged
+	if got := config.GetStringSlice("platforms"); !slices.Equal(got, []string{"linux_amd64"}) {
+		t.Errorf("platforms %v", got)
+	}
+	if got := config.GetString("build.out"); got != "dist" {
+		t.Errorf("build.out %q", got)
+	}
+	if !config.IsSet("hooks.pre_publish") {
+		t.Error("hooks.pre_publish isn't set")
+	}
+
+	// what the user trusts isn't
+	if got := config.GetString("registry"); got != "https://registry.example.com" {
+		t.Errorf("registry %q", got)
+	}
+	registries := config.GetStringSlice("registries")
+	if !slices.Equal(registries, []string{"https://registry.example.com"}) {
+		t.Errorf("registries %v", registries)
+	}
+	if got := config.GetStringSlice("trust.keys"); !slices.Equal(got, []string{"registry.pub"}) {
+		t.Errorf("trust.keys %v", got)
+	}
+	if !config.GetBool("trust.require_signatures") {
+		t.Error("trust.require_signatures was turned off")
+	}
+	for _, key := range []string{"registry_token", "decryption_keys"} {
+		if config.IsSet(key) {
+			t.Errorf("%s was set", key)
+		}
+	}
+}
+
+func TestFindProjectConfig(t *testing.T) {
+	repo, dir := testRepository(t, "platforms: [linux_amd64]\n")
+	if got := findProjectConfig(dir); got != filepath.Join(repo, projectConfigName) {
+		t.Errorf("found %q from within the repository", got)
+	}
+
+	// the search stops at the root of the repository
+	parent := filepath.Dir(repo)
+	if err := os.WriteFile(filepath.Join(parent, projectConfigName), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(filepath.Join(repo, projectConfigName)); err != nil {
+		t.Fatal(err)
+	}
+	if got := findProjectConfig(dir); got != "" {
+		t.Errorf("found %q above the repository", got)
+	}
+
+	// outside of a repository, only the directory itself is searched
+	outside := filepath.Join(parent, "outside")
+	if err := os.Mkdir(outside, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if got := findProjectConfig(outside); got != "" {
+		t.Errorf("found %q above a directory outside of a repository", got)
+	}
+	if got := findProjectConfig(parent); got != filepath.Join(parent, projectConfigName) {
+		t.Errorf("found %q in the directory", got)
+	}
+}
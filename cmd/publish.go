@@ -17,11 +17,17 @@ along with this program. If not, see <http://www.gnu.org/licenses/>.
 package cmd
 
 import (
+	"errors"
 	"fmt"
+	"os"
+	"slices"
+	"strings"
 
 	"github.com/omniviewdev/registry-cli/pkg"
+	"github.com/omniviewdev/registry-cli/pkg/console"
 	"github.com/omniviewdev/registry-cli/pkg/types"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 )
 
 var (
@@ -33,6 +39,34 @@ var (
 	windows_amd64 string
 	linux_arm64   string
 	linux_amd64   string
+	builds        []string
+	fromGitHub    string
+	fromGoRelease string
+	signingKey    string
+
+	storageClass           string
+	prereleaseStorageClass string
+
+	lockMode  string
+	lockTable string
+
+	indexTable string
+	baseURL    string
+
+	testedWith []string
+
+	hookCommands []string
+	hookURLs     []string
+
+	pending bool
+
+	partSize          string
+	uploadConcurrency int
+
+	atomicPublish     bool
+	bestEffortPublish bool
+
+	encryptTo []string
 )
 
 // publishCmd represents the publish command
@@ -40,7 +74,48 @@ var publishCmd = &cobra.Command{
 	Use:   "publish [plugin] [version]",
 	Short: "Publish a new version of your plugin",
 	Long: `Push a new version of a plugin to the registry. This action updates
-the indexes within the registry to show the new version.`,
+the indexes within the registry to show the new version.
+
+Builds are given per platform, either with the flag of each platform or with --build, which
+also takes the names other build systems give architectures (x86_64 and aarch64 are amd64 and
+arm64):
+
+  registry-cli publish my-plugin 1.0.0 -b my-registry -m plugin.yaml \
+    --build linux-x86_64=dist/linux-x86_64.tar.gz --build darwin-aarch64=dist/darwin-aarch64.tar.gz
+
+When some builds fail to upload, the publish fails and the builds that were uploaded are left
+in the bucket. With --atomic they're deleted instead, and with --best-effort the version is
+published with the builds that were uploaded, leaving out the platforms that failed.
+
+With --from-github, the builds are downloaded from the assets of an existing GitHub release,
+for plugins built with goreleaser. The platform tarballs are found by their names (e.g.
+my-plugin_1.0.0_linux_x86_64.tar.gz) and verified against the checksums.txt of the release.
+Set GITHUB_TOKEN for private repositories:
+
+  registry-cli publish my-plugin 1.0.0 -b my-registry -m plugin.yaml \
+    --from-github my-org/my-plugin@v1.0.0
+
+With --from-goreleaser, the builds are the tarballs goreleaser archived into its dist
+directory, as listed in its artifacts.json, so the publish can run right after goreleaser:
+
+  goreleaser release --clean
+  registry-cli publish my-plugin 1.0.0 -b my-registry -m plugin.yaml --from-goreleaser dist/
+
+Builds given with the flags of their platforms or --build take precedence over the builds
+of a release, which are only taken for the 'platforms' of the config when set.
+
+The hooks of the config, e.g. of the .registry-cli.yaml checked into the plugin repository,
+run around the publish: 'hooks.pre_publish' vet each build like --hook does, and
+'hooks.post_publish' and 'hooks.notifications' run once the version is published.
+
+For private registries, --encrypt-to (or 'encrypt_to' in the config) encrypts the builds
+before they're uploaded, once the hooks have vetted them, to age public keys, AWS KMS keys or
+a key shared with the installers, which install and download decrypt them with:
+
+  openssl rand -hex 32 > registry.key
+  registry-cli publish my-plugin 1.0.0 -b my-registry -m plugin.yaml \
+    --linux_amd64 dist/linux_amd64.tar.gz \
+    --encrypt-to registry.key --encrypt-to kms:alias/registry`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		switch len(args) {
 		case 0:
@@ -55,40 +130,119 @@ the indexes within the registry to show the new version.`,
 			)
 		}
 
+		startPorcelain()
+
+		compatibility, err := types.ParseTestedWith(testedWith)
+		if err != nil {
+			return err
+		}
+
+		report := types.NewPublishReport()
 		opts := types.PublishOpts{
-			Plugin:       args[0],
-			Version:      args[1],
-			MetadataPath: metadata,
-			DarwinAMD64:  darwin_amd64,
-			DarwinARM64:  darwin_arm64,
-			WindowsAMD64: windows_amd64,
-			WindowsARM64: windows_arm64,
-			LinuxAMD64:   linux_amd64,
-			LinuxARM64:   linux_arm64,
+			Plugin:        args[0],
+			Version:       args[1],
+			MetadataPath:  metadata,
+			DarwinAMD64:   darwin_amd64,
+			DarwinARM64:   darwin_arm64,
+			WindowsAMD64:  windows_amd64,
+			WindowsARM64:  windows_arm64,
+			LinuxAMD64:    linux_amd64,
+			LinuxARM64:    linux_arm64,
+			Compatibility: compatibility,
+			Report:        report,
+		}
+
+		for _, build := range builds {
+			platform, path, ok := strings.Cut(build, "=")
+			if !ok {
+				return fmt.Errorf("Invalid --build %q, expected <platform>=<path>", build)
+			}
+			if err := opts.SetPlatform(platform, path); err != nil {
+				return err
+			}
+		}
+
+		if fromGitHub != "" {
+			dir, err := addGitHubBuilds(cmd, &opts)
+			if err != nil {
+				return err
+			}
+			defer os.RemoveAll(dir)
+		}
+		if fromGoRelease != "" {
+			builds, err := pkg.LoadGoReleaserBuilds(fromGoRelease)
+			if err != nil {
+				return err
+			}
+			if err := addBuilds(&opts, builds.Paths, builds.Artifacts, "goreleaser's"); err != nil {
+				return err
+			}
+		}
+
+		if err := opts.Validate(); err != nil {
+			return err
 		}
+		printPlatforms(opts)
 
 		indexer, err := pkg.NewIndexer(cmd.Context(), pkg.IndexerOpts{
-			Bucket: bucket,
+			Bucket:     bucket,
+			Provider:   provider,
+			Endpoint:   endpoint,
+			Pacing:     pacing,
+			SigningKey: signingKey,
+			LockMode:   lockMode,
+			LockTable:  lockTable,
+			IndexTable: indexTable,
+			BaseURL:    baseURL,
+			Layout:     layout,
 		})
 		if err != nil {
 			return err
 		}
 
+		hooks, err := publishHooks()
+		if err != nil {
+			return err
+		}
+		postHooks, err := postPublishHooks()
+		if err != nil {
+			return err
+		}
+		partSize, concurrency, err := uploadSettings(cmd)
+		if err != nil {
+			return err
+		}
 		publisher, err := pkg.NewPublisher(cmd.Context(), pkg.PublisherOpts{
-			Bucket: bucket,
+			Bucket:                 bucket,
+			Provider:               provider,
+			Endpoint:               endpoint,
+			Pacing:                 pacing,
+			StorageClass:           storageClass,
+			PrereleaseStorageClass: prereleaseStorageClass,
+			Hooks:                  hooks,
+			Moderated:              pending,
+			PartSize:               partSize,
+			Concurrency:            concurrency,
+			OnPartialFailure:       partialFailurePolicy(),
+			EncryptTo:              encryptionRecipients(),
+			Layout:                 layout,
 		})
 		if err != nil {
 			return err
 		}
 
-		if err := publisher.Publish(cmd.Context(), opts); err != nil {
-			return err
-		}
-		if err := indexer.UpdateIndex(cmd.Context(), opts); err != nil {
+		err = pkg.PublishVersion(cmd.Context(), publisher, indexer, opts)
+		if err := checkPartialPublish(err); err != nil {
 			return err
 		}
 
-		fmt.Printf("published new version: %v\n", opts)
+		if publisher.Moderated() {
+			console.Printf("submitted new version for review: %v\n", opts)
+		} else {
+			console.Printf("published new version: %v\n", opts)
+		}
+		runPostPublishHooks(cmd, postHooks, opts, err, publisher.Moderated())
+		printPorcelain(report)
 		return nil
 	},
 }
@@ -97,6 +251,41 @@ func init() {
 	rootCmd.AddCommand(publishCmd)
 
 	publishCmd.Flags().StringVarP(&bucket, "bucket", "b", "", "bucket to upload to")
+	publishCmd.Flags().
+		StringVar(&signingKey, "signing-key", "", "path to the key to sign indexes with (or REGISTRY_SIGNING_KEY)")
+	publishCmd.Flags().
+		StringVar(&lockMode, "lock", "", "lock the indexes while updating them, with 's3' or 'dynamodb' (or REGISTRY_LOCK)")
+	publishCmd.Flags().
+		StringVar(&lockTable, "lock-table", "", "DynamoDB table to hold the lock in with --lock dynamodb (or REGISTRY_LOCK_TABLE)")
+	publishCmd.Flags().
+		StringVar(&indexTable, "index-table", "", "DynamoDB table holding the index records, materialized into the bucket indexes (or REGISTRY_INDEX_TABLE)")
+	publishCmd.Flags().
+		StringVar(&baseURL, "base-url", "", "URL the bucket is served from, to write absolute download URLs into the index (or REGISTRY_BASE_URL)")
+	publishCmd.Flags().
+		StringSliceVar(&testedWith, "tested-with", nil, "Omniview core versions the release was tested against (e.g. 0.9.x,1.0.x)")
+	publishCmd.Flags().
+		StringVar(&storageClass, "storage-class", "", "S3 storage class to upload the builds with (e.g. STANDARD_IA)")
+	publishCmd.Flags().
+		StringVar(&prereleaseStorageClass, "prerelease-storage-class", "", "S3 storage class to upload prerelease builds with. Defaults to --storage-class")
+	publishCmd.Flags().
+		StringArrayVar(&hookCommands, "hook", nil, "shell command vetting each build before anything is uploaded, given the tarball as $1. Adds to 'publish_hooks' in the config")
+	publishCmd.Flags().
+		StringArrayVar(&hookURLs, "hook-url", nil, "URL POSTed each build before anything is uploaded, vetoing it with a non-2xx response. Adds to 'publish_hooks' in the config")
+	publishCmd.Flags().
+		BoolVar(&pending, "pending", false, "upload into the pending area for a registry admin to approve with 'registry-cli review approve' (or REGISTRY_MODERATED=true)")
+	publishCmd.Flags().
+		StringVar(&partSize, "part-size", "", "upload builds larger than this in parts of this size, e.g. 64MiB (default is 'upload_part_size' in the config file, or a single request; see 'registry-cli bench upload')")
+	publishCmd.Flags().
+		IntVar(&uploadConcurrency, "upload-concurrency", 0, "parts of a build to upload at once with --part-size (default is 'upload_concurrency' in the config file, or 5)")
+	publishCmd.Flags().
+		BoolVar(&atomicPublish, "atomic", false, "when some builds fail to upload, delete the ones that were uploaded (by default they're left for a retry to overwrite)")
+	publishCmd.Flags().
+		BoolVar(&bestEffortPublish, "best-effort", false, "when some builds fail to upload, publish the ones that were uploaded without the failed platforms")
+	publishCmd.MarkFlagsMutuallyExclusive("atomic", "best-effort")
+	publishCmd.Flags().
+		StringArrayVar(&encryptTo, "encrypt-to", nil, "encrypt the builds before uploading them to an age public key, an AWS KMS key as kms:<key>, or a file holding a shared key or age public keys. Adds to 'encrypt_to' in the config")
+	publishCmd.Flags().
+		BoolVar(&porcelain, "porcelain", false, "only print tab separated artifact, checksum, size and uploaded lines for scripts")
 	publishCmd.Flags().StringVarP(&metadata, "metadata", "m", "", "path to plugin metadata file")
 	publishCmd.Flags().StringVar(&darwin_arm64, "darwin_arm64", "", "path to a darwin/arm64 build")
 	publishCmd.Flags().StringVar(&darwin_amd64, "darwin_amd64", "", "path to a darwin/amd64 build")
@@ -106,4 +295,168 @@ func init() {
 		StringVar(&windows_amd64, "windows_amd64", "", "path to a windows/amd64 build")
 	publishCmd.Flags().StringVar(&linux_arm64, "linux_arm64", "", "path to a linux/arm64 build")
 	publishCmd.Flags().StringVar(&linux_amd64, "linux_amd64", "", "path to a linux/amd64 build")
+	publishCmd.Flags().
+		StringArrayVar(&builds, "build", nil, "path to the build of a platform as <platform>=<path>, where the platform may be named by other build systems (e.g. linux-x86_64=dist/plugin.tar.gz)")
+	publishCmd.Flags().
+		StringVar(&fromGitHub, "from-github", "", "publish the builds of a GitHub release, given as owner/repo@tag (with GITHUB_TOKEN for private repositories)")
+	publishCmd.Flags().
+		StringVar(&fromGoRelease, "from-goreleaser", "", "publish the tarballs goreleaser archived into a dist directory, listed in its artifacts.json")
+	publishCmd.MarkFlagsMutuallyExclusive("from-github", "from-goreleaser")
+}
+
+// publishHooks returns the publish hooks configured with 'publish_hooks' and
+// 'hooks.pre_publish', followed by the ones given with --hook and --hook-url.
+func publishHooks() ([]pkg.PublishHook, error) {
+	configs, err := hookConfigs("publish_hooks")
+	if err != nil {
+		return nil, err
+	}
+	prePublish, err := hookConfigs("hooks.pre_publish")
+	if err != nil {
+		return nil, err
+	}
+	configs = append(configs, prePublish...)
+	for _, command := range hookCommands {
+		configs = append(configs, pkg.HookConfig{Command: command})
+	}
+	for _, url := range hookURLs {
+		configs = append(configs, pkg.HookConfig{URL: url})
+	}
+
+	hooks := make([]pkg.PublishHook, 0, len(configs))
+	for _, config := range configs {
+		hook, err := config.Hook()
+		if err != nil {
+			return nil, fmt.Errorf("invalid publish_hooks configuration: %w", err)
+		}
+		hooks = append(hooks, hook)
+	}
+	return hooks, nil
+}
+
+// uploadSettings returns the part size and concurrency to upload builds with, from --part-size
+// and --upload-concurrency or 'upload_part_size' and 'upload_concurrency' in the config.
+func uploadSettings(cmd *cobra.Command) (int64, int, error) {
+	size := partSize
+	if !cmd.Flags().Changed("part-size") {
+		size = viper.GetString("upload_part_size")
+	}
+	var bytes int64
+	if size != "" {
+		var err error
+		if bytes, err = pkg.ParseSize(size); err != nil {
+			return 0, 0, fmt.Errorf("Invalid part size: %w", err)
+		}
+	}
+	concurrency := uploadConcurrency
+	if !cmd.Flags().Changed("upload-concurrency") {
+		concurrency = viper.GetInt("upload_concurrency")
+	}
+	return bytes, concurrency, nil
+}
+
+// encryptionRecipients returns the recipients to encrypt the builds to, configured with
+// 'encrypt_to' followed by the ones given with --encrypt-to.
+func encryptionRecipients() []string {
+	return append(viper.GetStringSlice("encrypt_to"), encryptTo...)
+}
+
+// partialFailurePolicy returns what a publish does when some builds fail to upload, as set with
+// --atomic or --best-effort.
+func partialFailurePolicy() pkg.PartialFailurePolicy {
+	switch {
+	case atomicPublish:
+		return pkg.PartialFailureAtomic
+	case bestEffortPublish:
+		return pkg.PartialFailureBestEffort
+	}
+	return pkg.PartialFailureAbort
+}
+
+// checkPartialPublish warns about the platforms a best effort publish left out, rather than
+// failing it.
+func checkPartialPublish(err error) error {
+	var partial *pkg.PartialPublishError
+	if !errors.As(err, &partial) {
+		return err
+	}
+	console.Printf(
+		"⚠️ Published without the %s builds, which failed to upload:\n%v\n",
+		strings.Join(partial.Failed, ", "),
+		partial.Err,
+	)
+	return nil
+}
+
+// addGitHubBuilds downloads the builds of the GitHub release given with --from-github into the
+// publish options, for the platforms no build was given for. The temporary directory holding
+// them is returned, for the caller to remove.
+func addGitHubBuilds(cmd *cobra.Command, opts *types.PublishOpts) (string, error) {
+	release, err := pkg.ParseGitHubRelease(fromGitHub)
+	if err != nil {
+		return "", err
+	}
+	console.Printf("Downloading the builds of %s...\n", release)
+	builds, err := pkg.DownloadGitHubRelease(cmd.Context(), release, os.Getenv("GITHUB_TOKEN"))
+	if err != nil {
+		return "", err
+	}
+
+	if err := addBuilds(opts, builds.Paths, builds.Artifacts, "the release's"); err != nil {
+		os.RemoveAll(builds.Dir)
+		return "", err
+	}
+	return builds.Dir, nil
+}
+
+// addBuilds adds the builds of a release to the publish options, along with their checksums
+// and sizes, for the platforms no build was given for. When 'platforms' is configured, the
+// builds of other platforms are left out.
+func addBuilds(
+	opts *types.PublishOpts,
+	paths map[string]string,
+	artifacts map[string]types.Artifact,
+	source string,
+) error {
+	configured, err := configuredPlatforms()
+	if err != nil {
+		return err
+	}
+	given := make(map[string]bool)
+	for _, release := range opts.ToReleases() {
+		given[release.OSArch()] = true
+	}
+	if opts.Artifacts == nil {
+		opts.Artifacts = make(map[string]types.Artifact)
+	}
+	for _, platform := range types.Platforms {
+		path, ok := paths[platform]
+		switch {
+		case !ok:
+		case given[platform]:
+			console.Printf("⚠️ Using the given %s build instead of %s\n", platform, source)
+		case configured != nil && !slices.Contains(configured, platform):
+			console.Printf("⚠️ Leaving out %s %s build, not a configured platform\n", source, platform)
+		default:
+			// the platforms of the release are supported ones
+			_ = opts.SetPlatform(platform, path)
+			opts.Artifacts[platform] = artifacts[platform]
+		}
+	}
+	return nil
+}
+
+// printPlatforms prints which platforms the publish includes builds for.
+func printPlatforms(opts types.PublishOpts) {
+	included := make(map[string]bool)
+	for _, release := range opts.ToReleases() {
+		included[release.OSArch()] = true
+	}
+	for _, platform := range types.Platforms {
+		if included[platform] {
+			console.Printf("✅ Including %s\n", platform)
+		} else {
+			console.Printf("⚠️ Excluding %s, no build given\n", platform)
+		}
+	}
 }
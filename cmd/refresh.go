@@ -0,0 +1,100 @@
+/*
+Copyright © 2025 Joshua Pare <jpare@omniview.dev>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package cmd
+
+import (
+	"strings"
+
+	"github.com/omniviewdev/registry-cli/pkg"
+	"github.com/omniviewdev/registry-cli/pkg/console"
+	"github.com/spf13/cobra"
+)
+
+var refreshDryRun bool
+
+// refreshCmd represents the refresh command
+var refreshCmd = &cobra.Command{
+	Use:   "refresh [plugin] [version]",
+	Short: "Recompute the checksums of a published version and rewrite its index entry",
+	Long: `Refresh re-downloads the artifacts of a published version from the bucket, recomputes
+their checksums and sizes, rewrites their checksum files and rewrites the version's index entry,
+signing the indexes again:
+
+  registry-cli refresh kubernetes 0.2.0 --bucket my-registry --signing-key registry.key
+
+Use it after rotating the signing key, or to backfill the fields added by newer versions of the
+index schema (like checksum_url) into versions published before them.`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		indexer, err := pkg.NewIndexer(cmd.Context(), pkg.IndexerOpts{
+			Bucket:     bucket,
+			Provider:   provider,
+			Endpoint:   endpoint,
+			Pacing:     pacing,
+			SigningKey: signingKey,
+			LockMode:   lockMode,
+			LockTable:  lockTable,
+			IndexTable: indexTable,
+			BaseURL:    baseURL,
+			Layout:     layout,
+		})
+		if err != nil {
+			return err
+		}
+
+		refresh, err := indexer.RefreshVersion(cmd.Context(), args[0], args[1], refreshDryRun)
+		if err != nil {
+			return err
+		}
+
+		for _, artifact := range refresh.Artifacts {
+			if len(artifact.Changed) == 0 {
+				console.Printf("  %s: up to date\n", artifact.Arch)
+				continue
+			}
+			console.Printf(
+				"  ⚠️  %s: updated %s\n",
+				artifact.Arch,
+				strings.Join(artifact.Changed, ", "),
+			)
+		}
+		dryRun := ""
+		if refreshDryRun {
+			dryRun = " (dry run, nothing was changed)"
+		}
+		console.Printf("✅ Refreshed %s[%s]%s\n", refresh.Plugin, refresh.Version, dryRun)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(refreshCmd)
+
+	refreshCmd.Flags().StringVarP(&bucket, "bucket", "b", "", "bucket the version is published to")
+	refreshCmd.Flags().
+		BoolVar(&refreshDryRun, "dry-run", false, "show what would be updated without updating it")
+	refreshCmd.Flags().
+		StringVar(&signingKey, "signing-key", "", "path to the key to sign indexes with (or REGISTRY_SIGNING_KEY)")
+	refreshCmd.Flags().
+		StringVar(&lockMode, "lock", "", "lock the indexes while updating them, with 's3' or 'dynamodb' (or REGISTRY_LOCK)")
+	refreshCmd.Flags().
+		StringVar(&lockTable, "lock-table", "", "DynamoDB table to hold the lock in with --lock dynamodb (or REGISTRY_LOCK_TABLE)")
+	refreshCmd.Flags().
+		StringVar(&indexTable, "index-table", "", "DynamoDB table holding the index records, materialized into the bucket indexes (or REGISTRY_INDEX_TABLE)")
+	refreshCmd.Flags().
+		StringVar(&baseURL, "base-url", "", "URL the bucket is served from, to write absolute download URLs into the index (or REGISTRY_BASE_URL)")
+}
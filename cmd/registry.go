@@ -0,0 +1,139 @@
+/*
+Copyright © 2025 Joshua Pare <jpare@omniview.dev>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/omniviewdev/registry-cli/pkg/client"
+	"github.com/omniviewdev/registry-cli/pkg/encryption"
+	"github.com/omniviewdev/registry-cli/pkg/signing"
+	"github.com/spf13/viper"
+)
+
+// newRegistryClient creates a client for the configured registry (the highest priority one when
+// several are configured), pinning the trusted keys configured for it (plus any passed with
+// --trusted-key). Its requests are authorized with 'registry_token' (or REGISTRY_TOKEN), or the
+// token of the registry in 'registries'.
+func newRegistryClient() (*client.Client, error) {
+	url, token := registryURL, viper.GetString("registry_token")
+	if url == "" {
+		url = viper.GetString("registry")
+	}
+	if url == "" {
+		configs, err := registryConfigs()
+		if err != nil {
+			return nil, err
+		}
+		if len(configs) > 0 {
+			url, token = configs[0].URL, configs[0].Token
+		}
+	}
+	if url == "" {
+		return nil, fmt.Errorf(
+			"No registry configured. Pass --registry or set 'registry' in the config file",
+		)
+	}
+	return newClient(url, token)
+}
+
+// newRegistries creates clients for the registries configured with 'registries' in the config
+// file, highest priority first, or for the single configured registry:
+//
+//	registries:
+//	  - url: https://plugins.example.com
+//	    priority: 10
+//	    token: 3f1c9a...
+//	  - url: https://registry.omniview.dev
+func newRegistries() (client.Registries, error) {
+	configs, err := registryConfigs()
+	if err != nil {
+		return nil, err
+	}
+	if registryURL != "" || len(configs) == 0 {
+		c, err := newRegistryClient()
+		if err != nil {
+			return nil, err
+		}
+		return client.Registries{c}, nil
+	}
+
+	registries := make(client.Registries, 0, len(configs))
+	for _, config := range configs {
+		c, err := newClient(config.URL, config.Token)
+		if err != nil {
+			return nil, err
+		}
+		registries = append(registries, c)
+	}
+	return registries, nil
+}
+
+// registryConfigs returns the registries configured with 'registries', highest priority first.
+func registryConfigs() ([]client.RegistryConfig, error) {
+	var configs []client.RegistryConfig
+	if err := viper.UnmarshalKey("registries", &configs); err != nil {
+		return nil, fmt.Errorf("invalid registries configuration: %w", err)
+	}
+	for _, config := range configs {
+		if config.URL == "" {
+			return nil, fmt.Errorf("invalid registries configuration: registry without a url")
+		}
+	}
+	client.SortRegistries(configs)
+	return configs, nil
+}
+
+// newClient creates a client for the registry at url, pinning the trusted keys configured for
+// it (plus any passed with --trusted-key) and remembering when the signed files it accepts were
+// signed, so older ones aren't accepted later. Requests to it are authorized with the token when
+// one is given.
+func newClient(url, token string) (*client.Client, error) {
+	var trust client.TrustConfig
+	if err := viper.UnmarshalKey("trust", &trust); err != nil {
+		return nil, fmt.Errorf("invalid trust configuration: %w", err)
+	}
+	var given []signing.PublicKey
+	for _, path := range trustedKeys {
+		key, err := signing.LoadPublicKey(path)
+		if err != nil {
+			return nil, err
+		}
+		given = append(given, key)
+	}
+	keys, err := trust.KeysFor(url, given...)
+	if err != nil {
+		return nil, err
+	}
+
+	return client.New(client.ClientOpts{
+		BaseURL:        url,
+		Token:          token,
+		TrustedKeys:    keys,
+		TimestampsFile: client.DefaultTimestampsFile(url),
+	})
+}
+
+// newDecrypter creates the decrypter of the encrypted builds of private registries, with the
+// keys configured with 'decryption_keys' plus any passed with --decryption-key.
+func newDecrypter(ctx context.Context) (*encryption.Decrypter, error) {
+	return encryption.NewDecrypter(
+		ctx,
+		append(viper.GetStringSlice("decryption_keys"), decryptionKeys...),
+	)
+}
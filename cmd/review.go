@@ -0,0 +1,161 @@
+/*
+Copyright © 2025 Joshua Pare <jpare@omniview.dev>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package cmd
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/omniviewdev/registry-cli/pkg"
+	"github.com/omniviewdev/registry-cli/pkg/console"
+	"github.com/omniviewdev/registry-cli/pkg/types"
+	"github.com/spf13/cobra"
+)
+
+// reviewCmd represents the review command
+var reviewCmd = &cobra.Command{
+	Use:   "review",
+	Short: "Review the releases awaiting approval in a moderated registry",
+	Long: `Review the releases published with --pending (or REGISTRY_MODERATED=true). Their builds
+are held under ` + types.PendingArtifactsPrefix + ` in the bucket, and they're left out of the
+indexes until a registry admin approves them:
+
+  registry-cli review list -b my-registry
+  registry-cli review approve my-plugin 1.2.0 -b my-registry
+
+To keep publishers from skipping the review, only grant them write access to the pending/
+prefix of the bucket.`,
+}
+
+// reviewListCmd represents the review list command
+var reviewListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the releases awaiting review",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		indexer, err := newReviewIndexer(cmd)
+		if err != nil {
+			return err
+		}
+		releases, err := indexer.PendingReleases(cmd.Context())
+		if err != nil {
+			return err
+		}
+		if len(releases) == 0 {
+			console.Println("No releases awaiting review")
+			return nil
+		}
+		for _, release := range releases {
+			console.Printf(
+				"🆕 %s %s  submitted %s by %s  (%s)\n",
+				release.Plugin,
+				release.Version.Version,
+				release.Submitted.Format("2006-01-02 15:04"),
+				release.SubmittedBy,
+				strings.Join(slices.Sorted(maps.Keys(release.Version.Architectures)), ", "),
+			)
+		}
+		return nil
+	},
+}
+
+// reviewApproveCmd represents the review approve command
+var reviewApproveCmd = &cobra.Command{
+	Use:   "approve [plugin] [version]",
+	Short: "Approve a release, publishing it into the registry",
+	Long: `Approve a release awaiting review: its builds are moved out of the pending area to
+where a publish would have put them, and it's added to the plugin and registry indexes.`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		indexer, err := newReviewIndexer(cmd)
+		if err != nil {
+			return err
+		}
+		release, err := indexer.Approve(cmd.Context(), args[0], args[1])
+		if err != nil {
+			return fmt.Errorf("Couldn't approve %s %s: %w", args[0], args[1], err)
+		}
+		console.Printf(
+			"✅ Approved %s %s, submitted by %s\n",
+			release.Plugin,
+			release.Version.Version,
+			release.SubmittedBy,
+		)
+		return nil
+	},
+}
+
+// reviewRejectCmd represents the review reject command
+var reviewRejectCmd = &cobra.Command{
+	Use:   "reject [plugin] [version]",
+	Short: "Reject a release, deleting its builds from the pending area",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		indexer, err := newReviewIndexer(cmd)
+		if err != nil {
+			return err
+		}
+		release, err := indexer.Reject(cmd.Context(), args[0], args[1])
+		if err != nil {
+			return fmt.Errorf("Couldn't reject %s %s: %w", args[0], args[1], err)
+		}
+		console.Printf(
+			"❌ Rejected %s %s, submitted by %s\n",
+			release.Plugin,
+			release.Version.Version,
+			release.SubmittedBy,
+		)
+		return nil
+	},
+}
+
+func newReviewIndexer(cmd *cobra.Command) (*pkg.Indexer, error) {
+	return pkg.NewIndexer(cmd.Context(), pkg.IndexerOpts{
+		Bucket:     bucket,
+		Provider:   provider,
+		Endpoint:   endpoint,
+		Pacing:     pacing,
+		SigningKey: signingKey,
+		LockMode:   lockMode,
+		LockTable:  lockTable,
+		IndexTable: indexTable,
+		BaseURL:    baseURL,
+		Layout:     layout,
+	})
+}
+
+func init() {
+	rootCmd.AddCommand(reviewCmd)
+	reviewCmd.AddCommand(reviewListCmd)
+	reviewCmd.AddCommand(reviewApproveCmd)
+	reviewCmd.AddCommand(reviewRejectCmd)
+
+	reviewCmd.PersistentFlags().
+		StringVarP(&bucket, "bucket", "b", "", "bucket serving the registry")
+	reviewCmd.PersistentFlags().
+		StringVar(&signingKey, "signing-key", "", "path to the key to sign indexes with (or REGISTRY_SIGNING_KEY)")
+	reviewCmd.PersistentFlags().
+		StringVar(&lockMode, "lock", "", "lock the indexes while updating them, with 's3' or 'dynamodb' (or REGISTRY_LOCK)")
+	reviewCmd.PersistentFlags().
+		StringVar(&lockTable, "lock-table", "", "DynamoDB table to hold the lock in with --lock dynamodb (or REGISTRY_LOCK_TABLE)")
+	reviewCmd.PersistentFlags().
+		StringVar(&indexTable, "index-table", "", "DynamoDB table holding the index records, materialized into the bucket indexes (or REGISTRY_INDEX_TABLE)")
+	reviewCmd.PersistentFlags().
+		StringVar(&baseURL, "base-url", "", "URL the bucket is served from, to write absolute download URLs into the index (or REGISTRY_BASE_URL)")
+}
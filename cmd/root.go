@@ -17,14 +17,40 @@ along with this program. If not, see <http://www.gnu.org/licenses/>.
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"time"
 
+	"github.com/omniviewdev/registry-cli/pkg"
+	"github.com/omniviewdev/registry-cli/pkg/console"
+	"github.com/omniviewdev/registry-cli/pkg/types"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
 
-var cfgFile string
+var (
+	cfgFile     string
+	registryURL string
+	trustedKeys []string
+	colorMode   string
+
+	artifactLayout string
+	indexLayout    string
+	layout         types.Layout
+
+	provider      string
+	s3Rate        float64
+	s3Concurrency int
+	pacing        pkg.Pacing
+
+	endpointURL string
+	region      string
+	pathStyle   bool
+	endpoint    pkg.Endpoint
+
+	otlpEndpoint string
+)
 
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
@@ -39,6 +65,14 @@ var rootCmd = &cobra.Command{
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() {
 	err := rootCmd.Execute()
+
+	// export what the command recorded before exiting, without holding it up for long
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	if err := pkg.StopTelemetry(ctx); err != nil {
+		console.Printf("⚠️ %v\n", err)
+	}
+	cancel()
+
 	if err != nil {
 		os.Exit(1)
 	}
@@ -52,7 +86,32 @@ func init() {
 	// will be global for your application.
 
 	rootCmd.PersistentFlags().
-		StringVar(&cfgFile, "config", "", "config file (default is $HOME/.registry-cli.yaml)")
+		StringVar(&cfgFile, "config", "", "config file (default is $HOME/.registry-cli.yaml), which the hooks, platforms and build settings of the .registry-cli.yaml of the plugin repository are merged over")
+	rootCmd.PersistentFlags().
+		StringVar(&registryURL, "registry", "", "URL of the registry to read from (default is 'registry' in the config file)")
+	rootCmd.PersistentFlags().
+		StringSliceVar(&trustedKeys, "trusted-key", nil, "public key file to trust for registry indexes, in addition to the configured keys")
+	rootCmd.PersistentFlags().
+		StringVar(&colorMode, "color", console.ColorAuto, "when to use colors and emoji: auto, always or never (auto honors NO_COLOR, or 'color' in the config file)")
+	rootCmd.PersistentFlags().
+		StringVar(&artifactLayout, "artifact-layout", "", "template of the bucket keys of the artifacts (default is 'artifact_layout' in the config file, or "+types.DefaultArtifactLayout+")")
+	rootCmd.PersistentFlags().
+		StringVar(&indexLayout, "index-layout", "", "template of the bucket keys of the plugin indexes (default is 'index_layout' in the config file, or "+types.DefaultIndexLayout+")")
+
+	rootCmd.PersistentFlags().
+		StringVar(&provider, "provider", "", "where the registry bucket is hosted, s3 (or an S3 compatible store), gcs with Application Default Credentials, azure with AZURE_STORAGE_ACCOUNT and the default Azure credential, or file for a local directory given as the bucket (default is 'provider' in the config file, or s3)")
+	rootCmd.PersistentFlags().
+		StringVar(&endpointURL, "endpoint", "", "endpoint of the S3 compatible store hosting the bucket, e.g. http://localhost:9000 for MinIO or https://<account>.r2.cloudflarestorage.com for R2 (default is 'endpoint' in the config file, or AWS_ENDPOINT_URL)")
+	rootCmd.PersistentFlags().
+		StringVar(&region, "region", "", "region of the bucket, auto for R2 (default is 'region' in the config file, or the region of the AWS configuration)")
+	rootCmd.PersistentFlags().
+		BoolVar(&pathStyle, "path-style", false, "address the bucket by path rather than by host name, as most S3 compatible stores require (default is 'path_style' in the config file, or AWS_S3_FORCE_PATH_STYLE)")
+	rootCmd.PersistentFlags().
+		Float64Var(&s3Rate, "s3-rate", 0, "most bucket requests to make per second, to stay under the provider's throttling (default is 's3_rate' in the config file, or unlimited)")
+	rootCmd.PersistentFlags().
+		IntVar(&s3Concurrency, "s3-concurrency", 0, "most bucket requests to have in flight at once (default is 's3_concurrency' in the config file, or unlimited)")
+	rootCmd.PersistentFlags().
+		StringVar(&otlpEndpoint, "otlp-endpoint", "", "OTLP/HTTP endpoint to export metrics and traces to, e.g. http://localhost:4318 (default is 'otlp_endpoint' in the config file, or OTEL_EXPORTER_OTLP_ENDPOINT)")
 
 	// Cobra also supports local flags, which will only run
 	// when this action is called directly.
@@ -81,4 +140,55 @@ func initConfig() {
 	if err := viper.ReadInConfig(); err == nil {
 		fmt.Fprintln(os.Stderr, "Using config file:", viper.ConfigFileUsed())
 	}
+	cobra.CheckErr(mergeProjectConfig())
+
+	if !rootCmd.PersistentFlags().Changed("color") && viper.IsSet("color") {
+		colorMode = viper.GetString("color")
+	}
+	cobra.CheckErr(console.SetColorMode(colorMode))
+
+	if artifactLayout == "" {
+		artifactLayout = viper.GetString("artifact_layout")
+	}
+	if indexLayout == "" {
+		indexLayout = viper.GetString("index_layout")
+	}
+	var err error
+	layout, err = types.ParseLayout(types.Layout{Artifact: artifactLayout, Index: indexLayout})
+	cobra.CheckErr(err)
+
+	if provider == "" {
+		provider = viper.GetString("provider")
+	}
+	provider, err = pkg.ParseProvider(provider)
+	cobra.CheckErr(err)
+
+	if endpointURL == "" {
+		endpointURL = viper.GetString("endpoint")
+	}
+	if region == "" {
+		region = viper.GetString("region")
+	}
+	if !rootCmd.PersistentFlags().Changed("path-style") {
+		pathStyle = viper.GetBool("path_style")
+	}
+	endpoint = pkg.Endpoint{URL: endpointURL, Region: region, PathStyle: pathStyle}
+	cobra.CheckErr(endpoint.Validate())
+
+	if !rootCmd.PersistentFlags().Changed("s3-rate") {
+		s3Rate = viper.GetFloat64("s3_rate")
+	}
+	if !rootCmd.PersistentFlags().Changed("s3-concurrency") {
+		s3Concurrency = viper.GetInt("s3_concurrency")
+	}
+	pacing = pkg.Pacing{Rate: s3Rate, Concurrency: s3Concurrency}
+	cobra.CheckErr(pacing.Validate())
+
+	if otlpEndpoint == "" {
+		otlpEndpoint = viper.GetString("otlp_endpoint")
+	}
+	cobra.CheckErr(pkg.StartTelemetry(context.Background(), pkg.Telemetry{
+		Endpoint: otlpEndpoint,
+		Headers:  viper.GetStringMapString("otlp_headers"),
+	}))
 }
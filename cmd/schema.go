@@ -0,0 +1,89 @@
+/*
+Copyright © 2025 Joshua Pare <jpare@omniview.dev>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/omniviewdev/registry-cli/pkg/packager"
+	"github.com/omniviewdev/registry-cli/pkg/schema"
+	"github.com/omniviewdev/registry-cli/pkg/types"
+	"github.com/spf13/cobra"
+)
+
+// schemaCmd represents the schema command
+var schemaCmd = &cobra.Command{
+	Use:   "schema",
+	Short: "Print the JSON Schema for plugin.yaml",
+	Long: `Print the JSON Schema describing the plugin.yaml metadata file. Point your editor's
+YAML language server at the output to get completion and validation while editing.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		out, err := packager.PluginMetadataSchema().JSON()
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(out))
+		return nil
+	},
+}
+
+// schemaRegistryCmd represents the schema registry command
+var schemaRegistryCmd = &cobra.Command{
+	Use:   "registry [document]",
+	Short: "Print the JSON Schemas of the indexes a registry serves",
+	Long: `Print the JSON Schemas of the documents a registry serves, for clients such as the
+Omniview app or web frontends to generate types from and validate responses with: the
+registry index, the plugin indexes, the latest version pointers and the builds they list. The
+description of each gives where it is in the bucket, following the layout of the registry
+(see --artifact-layout and --index-layout).
+
+Without a document, the schemas are printed as the definitions of a single schema. Otherwise
+the schema of the document is printed on its own, one of:
+` + strings.Join(types.RegistryDocuments, ", ") + `.
+
+The schemas allow properties they don't list, so documents written by newer versions of
+registry-cli still validate.`,
+	Args:      cobra.MatchAll(cobra.MaximumNArgs(1), cobra.OnlyValidArgs),
+	ValidArgs: types.RegistryDocuments,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		documents := types.RegistrySchema(layout)
+		out := &schema.Schema{
+			Schema:      schema.Draft,
+			Title:       "Omniview plugin registry",
+			Description: "The documents served by an Omniview plugin registry.",
+			Defs:        documents,
+		}
+		if len(args) == 1 {
+			out = documents[args[0]]
+			out.Schema = schema.Draft
+		}
+
+		b, err := out.JSON()
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(b))
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(schemaCmd)
+	schemaCmd.AddCommand(schemaRegistryCmd)
+}
@@ -0,0 +1,77 @@
+/*
+Copyright © 2025 Joshua Pare <jpare@omniview.dev>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/omniviewdev/registry-cli/pkg/client"
+	"github.com/omniviewdev/registry-cli/pkg/console"
+	"github.com/spf13/cobra"
+)
+
+// searchCmd represents the search command
+var searchCmd = &cobra.Command{
+	Use:   "search [query]",
+	Short: "Search the registry for plugins",
+	Long: `Search lists the plugins in the registry whose id, name or description contains the
+query (every plugin when no query is given). With several registries configured, their
+plugins are merged, each listed from the highest priority registry that has it:
+
+  registry-cli search kube`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		registries, err := newRegistries()
+		if err != nil {
+			return err
+		}
+
+		plugins, err := registries.Plugins(cmd.Context())
+		if err != nil {
+			return err
+		}
+
+		var query string
+		if len(args) > 0 {
+			query = strings.ToLower(args[0])
+		}
+		var matches []client.RegistryPlugin
+		for _, plugin := range plugins {
+			if strings.Contains(strings.ToLower(plugin.ID), query) ||
+				strings.Contains(strings.ToLower(plugin.Name), query) ||
+				strings.Contains(strings.ToLower(plugin.Description), query) {
+				matches = append(matches, plugin)
+			}
+		}
+		if len(matches) == 0 {
+			return fmt.Errorf("No plugins found matching %q", query)
+		}
+
+		for _, plugin := range matches {
+			console.Printf("%-24s %-12s %s\n", plugin.ID, plugin.LatestVersion.Version, plugin.Description)
+			if len(registries) > 1 {
+				console.Printf("%-24s from %s\n", "", plugin.Source)
+			}
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(searchCmd)
+}
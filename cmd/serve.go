@@ -0,0 +1,179 @@
+/*
+Copyright © 2025 Joshua Pare <jpare@omniview.dev>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/omniviewdev/registry-cli/pkg/client"
+	"github.com/omniviewdev/registry-cli/pkg/console"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	serveAddr        string
+	serveCacheDir    string
+	serveIndexTTL    time.Duration
+	serveArtifactTTL time.Duration
+	serveTokensFile  string
+)
+
+// serveCmd represents the serve command
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve a local caching proxy of the registry",
+	Long: `Serve runs an HTTP server in front of the registry, caching the indexes and artifacts
+it serves on disk. Files are read through from the registry on first use and refreshed once
+their TTL passes, so a team can point Omniview at a LAN-local mirror without replicating the
+whole registry. Cached files are served stale when the registry can't be reached:
+
+  registry-cli serve --registry https://registry.omniview.dev --cache-dir /var/cache/registry
+
+Prometheus metrics are served on /metrics: the requests served, the artifacts downloaded by
+plugin and the cache hit rates.
+
+Private plugins, given an access list with 'registry-cli access', are only served to the
+principals on it. Requests are made as a principal with a bearer token, from the file given with
+--tokens-file holding a principal and its token per line:
+
+  # principal  token
+  team-payments  3f1c9a...
+
+Clients send their token with 'registry_token' in the config file (or REGISTRY_TOKEN), or the
+'token' of the registry in 'registries'. The files of private plugins are not found for anyone
+else, and private plugins are left out of the registry index served to them when it isn't
+signed (signed indexes are served whole, as clients would refuse them filtered). The registry
+itself then needs to be readable by the proxy only, e.g. a bucket served to the address of the
+proxy only.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := newRegistryClient()
+		if err != nil {
+			return err
+		}
+
+		if serveCacheDir == "" {
+			serveCacheDir = viper.GetString("cache_dir")
+		}
+		if serveCacheDir == "" {
+			dir, err := os.UserCacheDir()
+			if err != nil {
+				return fmt.Errorf("No cache directory. Pass --cache-dir: %w", err)
+			}
+			serveCacheDir = filepath.Join(dir, "registry-cli")
+		}
+
+		if serveTokensFile == "" {
+			serveTokensFile = viper.GetString("tokens_file")
+		}
+		var tokens map[string]string
+		if serveTokensFile != "" {
+			if tokens, err = loadTokens(serveTokensFile); err != nil {
+				return err
+			}
+		}
+
+		proxy, err := c.NewProxy(client.ProxyOpts{
+			CacheDir:    serveCacheDir,
+			IndexTTL:    serveIndexTTL,
+			ArtifactTTL: serveArtifactTTL,
+			Tokens:      tokens,
+			OnError: func(err error) {
+				fmt.Fprintf(console.Stderr, "⚠️  %v\n", err)
+			},
+		})
+		if err != nil {
+			return err
+		}
+
+		ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt)
+		defer stop()
+
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", proxy.Metrics())
+		mux.Handle("/", proxy)
+		server := &http.Server{Addr: serveAddr, Handler: mux}
+		go func() {
+			<-ctx.Done()
+			shutdown, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			server.Shutdown(shutdown)
+		}()
+
+		console.Printf("Serving %s on %s, caching in %s\n", c.URL(""), serveAddr, serveCacheDir)
+		if err := server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+			return err
+		}
+		return nil
+	},
+}
+
+// loadTokens reads the bearer tokens of the principals from a file holding a principal and its
+// token per line, mapping the tokens to their principal.
+func loadTokens(path string) (map[string]string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("Couldn't read the tokens: %w", err)
+	}
+	tokens := make(map[string]string)
+	for n, line := range strings.Split(string(b), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf(
+				"Invalid token on line %d of %s, expected a principal and its token",
+				n+1,
+				path,
+			)
+		}
+		if _, ok := tokens[fields[1]]; ok {
+			return nil, fmt.Errorf(
+				"Token on line %d of %s is already given to another principal",
+				n+1,
+				path,
+			)
+		}
+		tokens[fields[1]] = fields[0]
+	}
+	return tokens, nil
+}
+
+func init() {
+	rootCmd.AddCommand(serveCmd)
+
+	serveCmd.Flags().StringVar(&serveAddr, "addr", ":8080", "address to listen on")
+	serveCmd.Flags().
+		StringVar(&serveCacheDir, "cache-dir", "", "directory to cache the registry in (default is 'cache_dir' in the config file, or the user cache directory)")
+	serveCmd.Flags().
+		DurationVar(&serveIndexTTL, "index-ttl", client.DefaultIndexTTL, "how long to serve cached indexes before refreshing them")
+	serveCmd.Flags().
+		DurationVar(&serveArtifactTTL, "artifact-ttl", client.DefaultArtifactTTL, "how long to serve cached artifacts before refreshing them")
+	serveCmd.Flags().
+		StringVar(&serveTokensFile, "tokens-file", "", "file of the principals and their bearer tokens, for private plugins (default is 'tokens_file' in the config file)")
+}
@@ -0,0 +1,33 @@
+/*
+Copyright © 2025 Joshua Pare <jpare@omniview.dev>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// siteCmd represents the site command
+var siteCmd = &cobra.Command{
+	Use:   "site",
+	Short: "Manage the static registry website",
+	Long: `Render the registry into a static website that can be browsed without Omniview,
+and publish it alongside the registry.`,
+}
+
+func init() {
+	rootCmd.AddCommand(siteCmd)
+}
@@ -0,0 +1,96 @@
+/*
+Copyright © 2025 Joshua Pare <jpare@omniview.dev>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package cmd
+
+import (
+	"path/filepath"
+
+	"github.com/omniviewdev/registry-cli/pkg"
+	"github.com/omniviewdev/registry-cli/pkg/console"
+	"github.com/omniviewdev/registry-cli/pkg/site"
+	"github.com/spf13/cobra"
+)
+
+var (
+	siteOut             string
+	siteTitle           string
+	siteArtifactBaseURL string
+	siteUpload          bool
+)
+
+// siteGenerateCmd represents the site generate command
+var siteGenerateCmd = &cobra.Command{
+	Use:   "generate",
+	Short: "Generate a static website for the registry",
+	Long: `Render the indexes in the bucket into a static website with a page for every plugin,
+listing its versions, downloads and install instructions:
+
+  registry-cli site generate --bucket my-registry --out site
+
+With --upload the site is also uploaded to the /site/ folder of the bucket. Download links are
+relative to that folder unless --artifact-base-url is given.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		indexer, err := pkg.NewIndexer(cmd.Context(), pkg.IndexerOpts{
+			Bucket:   bucket,
+			Provider: provider,
+			Endpoint: endpoint,
+			Pacing:   pacing,
+			Layout:   layout,
+		})
+		if err != nil {
+			return err
+		}
+
+		registry, plugins, err := indexer.Indexes(cmd.Context())
+		if err != nil {
+			return err
+		}
+
+		files, err := site.Generate(registry, plugins, siteOut, site.Opts{
+			Title:           siteTitle,
+			ArtifactBaseURL: siteArtifactBaseURL,
+		})
+		if err != nil {
+			return err
+		}
+
+		out, _ := filepath.Abs(siteOut)
+		console.Printf("✅ Generated %d pages for %d plugins in %s\n", len(files)-1, len(plugins), out)
+
+		if !siteUpload {
+			return nil
+		}
+		if err := indexer.PublishSite(cmd.Context(), siteOut, files); err != nil {
+			return err
+		}
+		console.Printf("✅ Uploaded site to %s\n", pkg.SitePrefix)
+		return nil
+	},
+}
+
+func init() {
+	siteCmd.AddCommand(siteGenerateCmd)
+
+	siteGenerateCmd.Flags().StringVarP(&bucket, "bucket", "b", "", "bucket to read the indexes from")
+	siteGenerateCmd.Flags().StringVarP(&siteOut, "out", "o", "site", "directory to write the site to")
+	siteGenerateCmd.Flags().StringVar(&siteTitle, "title", "", "title of the site")
+	siteGenerateCmd.Flags().
+		StringVar(&siteArtifactBaseURL, "artifact-base-url", "", "base URL to prefix relative download links with")
+	siteGenerateCmd.Flags().
+		BoolVar(&siteUpload, "upload", false, "upload the site to the /site/ folder of the bucket")
+}
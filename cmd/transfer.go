@@ -0,0 +1,105 @@
+/*
+Copyright © 2025 Joshua Pare <jpare@omniview.dev>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package cmd
+
+import (
+	"github.com/omniviewdev/registry-cli/pkg"
+	"github.com/omniviewdev/registry-cli/pkg/console"
+	"github.com/spf13/cobra"
+)
+
+var (
+	transferPrune  bool
+	transferDryRun bool
+)
+
+// transferCmd represents the transfer command
+var transferCmd = &cobra.Command{
+	Use:   "transfer [old-id] [new-id]",
+	Short: "Move a plugin to a new ID",
+	Long: `Transfer renames a plugin: its builds are copied to the keys of the new ID, its index is
+rewritten under the new ID, and the registry index lists it under the new ID only:
+
+  registry-cli transfer k8s kubernetes --bucket my-registry
+
+A tombstone is left in place of the index of the old ID, naming the new ID as its successor,
+so installs of the old ID migrate to the new one. The builds at the old keys are kept for existing installs
+to keep downloading them, delete them with --prune once they've migrated. Publish new versions
+under the new ID, publishes to the old one are refused.`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		indexer, err := pkg.NewIndexer(cmd.Context(), pkg.IndexerOpts{
+			Bucket:     bucket,
+			Provider:   provider,
+			Endpoint:   endpoint,
+			Pacing:     pacing,
+			SigningKey: signingKey,
+			LockMode:   lockMode,
+			LockTable:  lockTable,
+			IndexTable: indexTable,
+			BaseURL:    baseURL,
+			Layout:     layout,
+		})
+		if err != nil {
+			return err
+		}
+
+		transfer, err := indexer.TransferPlugin(
+			cmd.Context(),
+			args[0],
+			args[1],
+			transferPrune,
+			transferDryRun,
+		)
+		if err != nil {
+			return err
+		}
+
+		dryRun, copied := "", "copied"
+		if transferDryRun {
+			dryRun, copied = " (dry run, nothing was changed)", "would copy"
+		}
+		for _, artifact := range transfer.Artifacts {
+			console.Printf("  %s %s to %s\n", copied, artifact.From, artifact.To)
+		}
+		if transfer.Pruned {
+			console.Printf("  deleted the %d builds at the old keys\n", len(transfer.Artifacts))
+		}
+		console.Printf("✅ Transferred %s to %s%s\n", transfer.From, transfer.To, dryRun)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(transferCmd)
+
+	transferCmd.Flags().StringVarP(&bucket, "bucket", "b", "", "bucket serving the registry")
+	transferCmd.Flags().
+		BoolVar(&transferPrune, "prune", false, "delete the builds at the old keys once they're copied")
+	transferCmd.Flags().
+		BoolVar(&transferDryRun, "dry-run", false, "show what would be copied without changing anything")
+	transferCmd.Flags().
+		StringVar(&signingKey, "signing-key", "", "path to the key to sign indexes with (or REGISTRY_SIGNING_KEY)")
+	transferCmd.Flags().
+		StringVar(&lockMode, "lock", "", "lock the indexes while updating them, with 's3' or 'dynamodb' (or REGISTRY_LOCK)")
+	transferCmd.Flags().
+		StringVar(&lockTable, "lock-table", "", "DynamoDB table to hold the lock in with --lock dynamodb (or REGISTRY_LOCK_TABLE)")
+	transferCmd.Flags().
+		StringVar(&indexTable, "index-table", "", "DynamoDB table holding the index records, materialized into the bucket indexes (or REGISTRY_INDEX_TABLE)")
+	transferCmd.Flags().
+		StringVar(&baseURL, "base-url", "", "URL the bucket is served from, to write absolute download URLs into the index (or REGISTRY_BASE_URL)")
+}
@@ -0,0 +1,124 @@
+/*
+Copyright © 2025 Joshua Pare <jpare@omniview.dev>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/omniviewdev/registry-cli/pkg"
+	"github.com/omniviewdev/registry-cli/pkg/console"
+	"github.com/spf13/cobra"
+)
+
+var (
+	unpublishUndo   bool
+	unpublishDryRun bool
+)
+
+// unpublishCmd represents the unpublish command
+var unpublishCmd = &cobra.Command{
+	Use:   "unpublish [plugin] [version]",
+	Short: "Remove a published plugin version",
+	Long: `Unpublish removes a version of a plugin from the indexes and deletes its artifacts:
+
+  registry-cli unpublish kubernetes 0.2.0 --bucket my-registry
+
+With --undo, the most recent publish of the plugin is reverted instead, for quick recovery from
+a mistake: the index is restored to its state before the publish (from the index history, or
+the S3 object versions of the index), and the artifacts of the versions it added are deleted.
+
+  registry-cli unpublish kubernetes --undo --bucket my-registry`,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if unpublishUndo && len(args) > 1 {
+			return fmt.Errorf("--undo reverts the most recent publish, don't give a version")
+		}
+		if !unpublishUndo && len(args) < 2 {
+			return fmt.Errorf(
+				"Missing version string. Please provide as the second argument to 'unpublish', or pass --undo",
+			)
+		}
+
+		indexer, err := pkg.NewIndexer(cmd.Context(), pkg.IndexerOpts{
+			Bucket:     bucket,
+			Provider:   provider,
+			Endpoint:   endpoint,
+			Pacing:     pacing,
+			SigningKey: signingKey,
+			LockMode:   lockMode,
+			LockTable:  lockTable,
+			IndexTable: indexTable,
+			Layout:     layout,
+		})
+		if err != nil {
+			return err
+		}
+
+		dryRun, deleted := "", "deleted"
+		if unpublishDryRun {
+			dryRun, deleted = " (dry run, nothing was changed)", "would delete"
+		}
+
+		if !unpublishUndo {
+			removal, err := indexer.Unpublish(cmd.Context(), args[0], args[1], unpublishDryRun)
+			if err != nil {
+				return err
+			}
+			for _, artifact := range removal.Artifacts {
+				console.Printf("  %s %s\n", deleted, artifact)
+			}
+			console.Printf("✅ Unpublished %s[%s]%s\n", removal.Plugin, removal.Version, dryRun)
+			return nil
+		}
+
+		undo, err := indexer.UndoPublish(cmd.Context(), args[0], unpublishDryRun)
+		if err != nil {
+			return err
+		}
+		if len(undo.Removed) > 0 {
+			console.Printf("  removed %s\n", strings.Join(undo.Removed, ", "))
+		}
+		if len(undo.Reverted) > 0 {
+			console.Printf("  reverted %s to their previous entries\n", strings.Join(undo.Reverted, ", "))
+			console.Println("  ⚠️  their artifacts were overwritten by the publish and weren't restored")
+		}
+		for _, artifact := range undo.Artifacts {
+			console.Printf("  %s %s\n", deleted, artifact)
+		}
+		console.Printf("✅ Undid the last publish of %s, restored from %s%s\n", undo.Plugin, undo.RestoredFrom, dryRun)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(unpublishCmd)
+
+	unpublishCmd.Flags().StringVarP(&bucket, "bucket", "b", "", "bucket to unpublish from")
+	unpublishCmd.Flags().
+		BoolVar(&unpublishUndo, "undo", false, "revert the most recent publish of the plugin")
+	unpublishCmd.Flags().
+		BoolVar(&unpublishDryRun, "dry-run", false, "show what would be removed without removing it")
+	unpublishCmd.Flags().
+		StringVar(&signingKey, "signing-key", "", "path to the key to sign indexes with (or REGISTRY_SIGNING_KEY)")
+	unpublishCmd.Flags().
+		StringVar(&lockMode, "lock", "", "lock the indexes while updating them, with 's3' or 'dynamodb' (or REGISTRY_LOCK)")
+	unpublishCmd.Flags().
+		StringVar(&lockTable, "lock-table", "", "DynamoDB table to hold the lock in with --lock dynamodb (or REGISTRY_LOCK_TABLE)")
+	unpublishCmd.Flags().
+		StringVar(&indexTable, "index-table", "", "DynamoDB table holding the index records, materialized into the bucket indexes (or REGISTRY_INDEX_TABLE)")
+}
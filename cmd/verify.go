@@ -0,0 +1,125 @@
+/*
+Copyright © 2025 Joshua Pare <jpare@omniview.dev>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package cmd
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+
+	"github.com/omniviewdev/registry-cli/pkg/console"
+	"github.com/omniviewdev/registry-cli/pkg/types"
+	"github.com/spf13/cobra"
+)
+
+var verifyPlatforms []string
+
+// verifyCmd represents the verify command
+var verifyCmd = &cobra.Command{
+	Use:   "verify [plugin] [version]",
+	Short: "Verify a published plugin against the trusted registry keys",
+	Long: `Verify fetches the registry and plugin indexes, checking their signatures against the
+trusted keys configured for the registry, then downloads each artifact of the version (the
+latest when no version is given) and checks it against the checksum in the signed index, and
+that it extracts safely.
+
+Trusted keys are configured per registry in the config file:
+
+  registry: https://plugins.example.com
+  trust:
+    require_signatures: true
+    registries:
+      - url: https://plugins.example.com
+        keys:
+          - RWQ2xr6F1ovniaL+/ywVAAHJUQnNlzJrYXnPfdipjuDSj+c6A3sV2lTf
+          - /etc/omniview/registry.pub
+
+When the registry rotates its signing key ('registry-cli keys rotate'), the new key is trusted
+through the signature of a pinned key until the pinned key retires at the end of the grace
+period of the rotation. Pin the new key before then.`,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := newRegistryClient()
+		if err != nil {
+			return err
+		}
+
+		registryIndex, err := c.RegistryIndex(cmd.Context())
+		if err != nil {
+			return err
+		}
+		console.Println("✅ Registry index verified")
+
+		if !slices.ContainsFunc(registryIndex.Plugins, func(p types.RegistryIndexPlugins) bool {
+			return p.ID == args[0]
+		}) {
+			return fmt.Errorf("Plugin %s is not listed in the registry index", args[0])
+		}
+
+		index, err := c.PluginIndex(cmd.Context(), args[0])
+		if err != nil {
+			return err
+		}
+		console.Printf("✅ Plugin index for %s verified\n", args[0])
+
+		versionInfo := index.LatestVersion
+		if len(args) > 1 {
+			idx := slices.IndexFunc(index.Versions, func(v types.PluginVersionInformation) bool {
+				return v.Version == args[1]
+			})
+			if idx == -1 {
+				return fmt.Errorf("Version %s of %s was not found", args[1], args[0])
+			}
+			versionInfo = index.Versions[idx]
+		}
+
+		platforms := make([]string, 0, len(verifyPlatforms))
+		for _, platform := range verifyPlatforms {
+			platforms = append(platforms, types.NormalizePlatform(platform))
+		}
+		archs := make([]string, 0, len(versionInfo.Architectures))
+		for arch := range versionInfo.Architectures {
+			if len(platforms) == 0 || slices.Contains(platforms, types.NormalizePlatform(arch)) {
+				archs = append(archs, arch)
+			}
+		}
+		sort.Strings(archs)
+
+		failed := 0
+		for _, arch := range archs {
+			if err := c.VerifyArtifact(cmd.Context(), versionInfo.Architectures[arch]); err != nil {
+				console.Printf("❌ %s: %v\n", arch, err)
+				failed++
+				continue
+			}
+			console.Printf("✅ %s artifact verified\n", arch)
+		}
+
+		if failed > 0 {
+			return fmt.Errorf("%d of %d artifacts failed verification", failed, len(archs))
+		}
+		console.Printf("Verified %s[%s]\n", args[0], versionInfo.Version)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(verifyCmd)
+
+	verifyCmd.Flags().
+		StringSliceVar(&verifyPlatforms, "platform", nil, "Only verify these platforms (e.g. linux_amd64). Defaults to all")
+}
@@ -0,0 +1,107 @@
+/*
+Copyright © 2025 Joshua Pare <jpare@omniview.dev>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"time"
+
+	"github.com/omniviewdev/registry-cli/pkg/client"
+	"github.com/omniviewdev/registry-cli/pkg/console"
+	"github.com/spf13/cobra"
+)
+
+var (
+	watchInterval time.Duration
+	watchExec     string
+	watchJSON     bool
+)
+
+// watchCmd represents the watch command
+var watchCmd = &cobra.Command{
+	Use:   "watch [plugin...]",
+	Short: "Watch the registry for new releases",
+	Long: `Watch polls the registry and prints each new version as it appears, until interrupted.
+Given plugins, every new version of them is reported (backports included), otherwise every
+plugin in the registry is watched for new latest versions.
+
+Use --json for one JSON object per release, or --exec to run a command for each release with
+REGISTRY_PLUGIN and REGISTRY_VERSION set, to trigger downstream automation:
+
+  registry-cli watch kubernetes --exec './deploy.sh'`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := newRegistryClient()
+		if err != nil {
+			return err
+		}
+
+		ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt)
+		defer stop()
+
+		if !watchJSON {
+			console.Printf("Watching for new releases every %s...\n", watchInterval)
+		}
+		return c.Watch(
+			ctx,
+			client.WatchOpts{Plugins: args, Interval: watchInterval},
+			func(release client.Release) error {
+				if watchJSON {
+					b, err := json.Marshal(release)
+					if err != nil {
+						return err
+					}
+					fmt.Println(string(b))
+				} else {
+					console.Printf("🆕 %s %s\n", release.Plugin, release.Version)
+				}
+
+				if watchExec == "" {
+					return nil
+				}
+				hook := exec.CommandContext(ctx, "sh", "-c", watchExec)
+				hook.Env = append(
+					os.Environ(),
+					"REGISTRY_PLUGIN="+release.Plugin,
+					"REGISTRY_VERSION="+release.Version,
+				)
+				hook.Stdout = os.Stdout
+				hook.Stderr = os.Stderr
+				if err := hook.Run(); err != nil {
+					fmt.Fprintf(console.Stderr, "❌ --exec failed for %s %s: %v\n", release.Plugin, release.Version, err)
+				}
+				return nil
+			},
+			func(err error) {
+				fmt.Fprintf(console.Stderr, "⚠️  couldn't poll the registry: %v\n", err)
+			},
+		)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(watchCmd)
+
+	watchCmd.Flags().
+		DurationVar(&watchInterval, "interval", client.DefaultWatchInterval, "how often to poll the registry")
+	watchCmd.Flags().
+		StringVar(&watchExec, "exec", "", "command to run for each new release, with REGISTRY_PLUGIN and REGISTRY_VERSION set")
+	watchCmd.Flags().BoolVar(&watchJSON, "json", false, "print each release as a JSON object")
+}
@@ -0,0 +1,82 @@
+/*
+Copyright © 2025 Joshua Pare <jpare@omniview.dev>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package cmd
+
+import (
+	"github.com/omniviewdev/registry-cli/pkg"
+	"github.com/omniviewdev/registry-cli/pkg/console"
+	"github.com/spf13/cobra"
+)
+
+var yankUndo bool
+
+// yankCmd represents the yank command
+var yankCmd = &cobra.Command{
+	Use:   "yank [plugin] [version]",
+	Short: "Keep a published plugin version from being installed as the latest",
+	Long: `Yank marks a version of a plugin as yanked, e.g. one found broken after it was
+published. Yanked versions are never picked as the latest version nor resolved from a version
+range, but stay downloadable, so installs pinning them exactly keep working:
+
+  registry-cli yank kubernetes 0.2.0 --bucket my-registry
+
+With --undo, the version is no longer yanked.
+
+  registry-cli yank kubernetes 0.2.0 --undo --bucket my-registry`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		indexer, err := pkg.NewIndexer(cmd.Context(), pkg.IndexerOpts{
+			Bucket:     bucket,
+			Provider:   provider,
+			Endpoint:   endpoint,
+			Pacing:     pacing,
+			SigningKey: signingKey,
+			LockMode:   lockMode,
+			LockTable:  lockTable,
+			IndexTable: indexTable,
+			Layout:     layout,
+		})
+		if err != nil {
+			return err
+		}
+
+		if err := indexer.Yank(cmd.Context(), args[0], args[1], !yankUndo); err != nil {
+			return err
+		}
+		if yankUndo {
+			console.Printf("✅ %s[%s] is no longer yanked\n", args[0], args[1])
+			return nil
+		}
+		console.Printf("✅ Yanked %s[%s]\n", args[0], args[1])
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(yankCmd)
+
+	yankCmd.Flags().StringVarP(&bucket, "bucket", "b", "", "bucket serving the registry")
+	yankCmd.Flags().BoolVar(&yankUndo, "undo", false, "no longer yank the version")
+	yankCmd.Flags().
+		StringVar(&signingKey, "signing-key", "", "path to the key to sign indexes with (or REGISTRY_SIGNING_KEY)")
+	yankCmd.Flags().
+		StringVar(&lockMode, "lock", "", "lock the indexes while updating them, with 's3' or 'dynamodb' (or REGISTRY_LOCK)")
+	yankCmd.Flags().
+		StringVar(&lockTable, "lock-table", "", "DynamoDB table to hold the lock in with --lock dynamodb (or REGISTRY_LOCK_TABLE)")
+	yankCmd.Flags().
+		StringVar(&indexTable, "index-table", "", "DynamoDB table holding the index records, materialized into the bucket indexes (or REGISTRY_INDEX_TABLE)")
+}
@@ -0,0 +1,129 @@
+//go:build integration
+
+package integration
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// newS3 creates a client for the store under test.
+func newS3(t *testing.T) *s3.Client {
+	t.Helper()
+	cfg, err := config.LoadDefaultConfig(context.Background())
+	if err != nil {
+		t.Fatalf("failed to load aws config: %v", err)
+	}
+	return s3.NewFromConfig(cfg, func(o *s3.Options) { o.UsePathStyle = true })
+}
+
+// createBucket creates an empty bucket for the test.
+func createBucket(t *testing.T, client *s3.Client) string {
+	t.Helper()
+	bucket := fmt.Sprintf("registry-it-%d", time.Now().UnixNano())
+	_, err := client.CreateBucket(context.Background(), &s3.CreateBucketInput{
+		Bucket: aws.String(bucket),
+	})
+	if err != nil {
+		t.Fatalf("failed to create bucket %s: %v", bucket, err)
+	}
+	return bucket
+}
+
+// listKeys returns the sorted keys of every object in the bucket.
+func listKeys(t *testing.T, client *s3.Client, bucket string) []string {
+	t.Helper()
+	var keys []string
+	paginator := s3.NewListObjectsV2Paginator(client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(bucket),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(context.Background())
+		if err != nil {
+			t.Fatalf("failed to list bucket: %v", err)
+		}
+		for _, object := range page.Contents {
+			keys = append(keys, aws.ToString(object.Key))
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// getObject reads an object from the bucket.
+func getObject(t *testing.T, client *s3.Client, bucket, key string) []byte {
+	t.Helper()
+	result, err := client.GetObject(context.Background(), &s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		t.Fatalf("failed to get %s: %v", key, err)
+	}
+	defer result.Body.Close()
+
+	b, err := io.ReadAll(result.Body)
+	if err != nil {
+		t.Fatalf("failed to read %s: %v", key, err)
+	}
+	return b
+}
+
+// getJSON decodes a JSON object from the bucket into v.
+func getJSON(t *testing.T, client *s3.Client, bucket, key string, v any) {
+	t.Helper()
+	if err := json.Unmarshal(getObject(t, client, bucket, key), v); err != nil {
+		t.Fatalf("failed to decode %s: %v", key, err)
+	}
+}
+
+func sha256Hex(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+// runCLI runs registry-cli in dir, failing the test if it fails.
+func runCLI(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	if err := tryCLI(t, dir, args...); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// tryCLI runs registry-cli in dir, returning an error if it fails. Unlike runCLI, it's safe
+// to call from other goroutines.
+func tryCLI(t *testing.T, dir string, args ...string) error {
+	t.Helper()
+	cmd := exec.Command(cli, args...)
+	cmd.Dir = dir
+	cmd.Env = os.Environ()
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("registry-cli %v failed: %v\n%s", args, err, out)
+	}
+	t.Logf("registry-cli %v\n%s", args, out)
+	return nil
+}
+
+// copyFixture copies a plugin fixture into a temporary directory, as packaging writes into it.
+func copyFixture(t *testing.T, name string) string {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.CopyFS(dir, os.DirFS("testdata/"+name)); err != nil {
+		t.Fatalf("failed to copy fixture %s: %v", name, err)
+	}
+	return dir
+}
@@ -0,0 +1,58 @@
+//go:build integration
+
+package integration
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/omniviewdev/registry-cli/pkg/types"
+)
+
+// TestConcurrentPublishLock publishes several plugins at once with the S3 lock, checking no
+// publish loses another's registry index update.
+func TestConcurrentPublishLock(t *testing.T) {
+	client := newS3(t)
+	bucket := createBucket(t, client)
+
+	const plugins = 4
+	dir := t.TempDir()
+
+	var wg sync.WaitGroup
+	for n := range plugins {
+		id := fmt.Sprintf("concurrent-%d", n)
+		artifact := filepath.Join(dir, id+".tar.gz")
+		if err := os.WriteFile(artifact, []byte(id), 0644); err != nil {
+			t.Fatal(err)
+		}
+		metadata := filepath.Join(dir, id+".yaml")
+		if err := os.WriteFile(metadata, []byte("id: "+id+"\nname: "+id+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := tryCLI(t, dir, "publish", id, "1.0.0", "--bucket", bucket,
+				"--lock", "s3", "--metadata", metadata, "--linux_amd64", artifact)
+			if err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	var registry types.RegistryIndex
+	getJSON(t, client, bucket, "index.json", &registry)
+	if len(registry.Plugins) != plugins {
+		t.Errorf("registry index has %d plugins, want %d", len(registry.Plugins), plugins)
+	}
+	for _, key := range listKeys(t, client, bucket) {
+		if key == ".locks/index.lock" {
+			t.Errorf("lock was not released")
+		}
+	}
+}
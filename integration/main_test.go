@@ -0,0 +1,112 @@
+//go:build integration
+
+// Package integration runs the CLI end to end against an S3 compatible store. Run it with
+//
+//	go test -tags integration ./integration/...
+//
+// The tests use the store at AWS_ENDPOINT_URL (e.g. LocalStack on http://localhost:4566).
+// When it isn't set, a throwaway MinIO container is started with docker, and the tests are
+// skipped if docker isn't available.
+package integration
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const minioImage = "minio/minio:latest"
+
+// cli is the path to the registry-cli binary under test
+var cli string
+
+func TestMain(m *testing.M) {
+	os.Exit(run(m))
+}
+
+func run(m *testing.M) int {
+	if os.Getenv("AWS_ENDPOINT_URL") == "" {
+		stop, err := startMinIO()
+		if err != nil {
+			fmt.Printf("skipping integration tests: %v\n", err)
+			return 0
+		}
+		defer stop()
+	}
+	setDefaultEnv("AWS_ACCESS_KEY_ID", "minioadmin")
+	setDefaultEnv("AWS_SECRET_ACCESS_KEY", "minioadmin")
+	setDefaultEnv("AWS_REGION", "us-east-1")
+	setDefaultEnv("AWS_S3_FORCE_PATH_STYLE", "true")
+
+	dir, err := os.MkdirTemp("", "registry-cli-integration-*")
+	if err != nil {
+		fmt.Println(err)
+		return 1
+	}
+	defer os.RemoveAll(dir)
+
+	cli = filepath.Join(dir, "registry-cli")
+	build := exec.Command("go", "build", "-o", cli, "..")
+	if out, err := build.CombinedOutput(); err != nil {
+		fmt.Printf("failed to build registry-cli: %v\n%s", err, out)
+		return 1
+	}
+
+	return m.Run()
+}
+
+func setDefaultEnv(key, value string) {
+	if os.Getenv(key) == "" {
+		os.Setenv(key, value)
+	}
+}
+
+// startMinIO starts a MinIO container on a random port, pointing AWS_ENDPOINT_URL at it.
+func startMinIO() (func(), error) {
+	if _, err := exec.LookPath("docker"); err != nil {
+		return nil, fmt.Errorf("AWS_ENDPOINT_URL is not set and docker is not available")
+	}
+
+	out, err := exec.Command(
+		"docker", "run", "--rm", "-d", "-p", "127.0.0.1::9000", minioImage, "server", "/data",
+	).Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start minio: %w", err)
+	}
+	id := strings.TrimSpace(string(out))
+	stop := func() { _ = exec.Command("docker", "stop", id).Run() }
+
+	out, err = exec.Command("docker", "port", id, "9000/tcp").Output()
+	if err != nil {
+		stop()
+		return nil, fmt.Errorf("failed to get minio port: %w", err)
+	}
+	endpoint := "http://" + strings.TrimSpace(strings.Split(string(out), "\n")[0])
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	for {
+		resp, err := http.Get(endpoint + "/minio/health/live")
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode == http.StatusOK {
+				break
+			}
+		}
+		select {
+		case <-ctx.Done():
+			stop()
+			return nil, fmt.Errorf("minio did not become ready at %s", endpoint)
+		case <-time.After(500 * time.Millisecond):
+		}
+	}
+
+	os.Setenv("AWS_ENDPOINT_URL", endpoint)
+	return stop, nil
+}
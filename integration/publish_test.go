@@ -0,0 +1,182 @@
+//go:build integration
+
+package integration
+
+import (
+	"os/exec"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/omniviewdev/registry-cli/pkg"
+	"github.com/omniviewdev/registry-cli/pkg/types"
+)
+
+const fixturePlugin = "integration-test"
+
+var platforms = []string{
+	"darwin_amd64",
+	"darwin_arm64",
+	"linux_amd64",
+	"linux_arm64",
+	"windows_amd64",
+	"windows_arm64",
+}
+
+func TestPackagePublish(t *testing.T) {
+	if _, err := exec.LookPath("pnpm"); err != nil {
+		t.Skip("pnpm is required to package the fixture UI")
+	}
+
+	client := newS3(t)
+	bucket := createBucket(t, client)
+	dir := copyFixture(t, "plugin")
+
+	for _, version := range []string{"1.0.0", "1.1.0"} {
+		runCLI(t, dir, "package", ".", "--publish", "--bucket", bucket,
+			"--version", version, "--commit", "0123456789abcdef")
+	}
+
+	t.Run("bucket layout", func(t *testing.T) {
+		want := []string{
+			"badges/" + fixturePlugin + "/version.json",
+			types.ChannelPath(fixturePlugin, types.ChannelBeta),
+			types.ChannelPath(fixturePlugin, types.ChannelStable),
+			"index.json",
+			fixturePlugin + "/index.json",
+			fixturePlugin + "/latest.json",
+		}
+		for _, version := range []string{"1.0.0", "1.1.0"} {
+			for _, platform := range platforms {
+				release := releaseFor(version, platform)
+				want = append(want, types.Layout{}.ArtifactPath(release), types.Layout{}.ArtifactPath(release)+types.ChecksumExt)
+			}
+		}
+		slices.Sort(want)
+
+		got := slices.DeleteFunc(listKeys(t, client, bucket), func(key string) bool {
+			return strings.HasPrefix(key, pkg.HistoryPrefix)
+		})
+		if !slices.Equal(got, want) {
+			t.Errorf("unexpected bucket layout\ngot:  %v\nwant: %v", got, want)
+		}
+	})
+
+	t.Run("plugin index", func(t *testing.T) {
+		var index types.PluginIndex
+		getJSON(t, client, bucket, fixturePlugin+"/index.json", &index)
+
+		if index.ID != fixturePlugin || index.Name != "Integration Test" {
+			t.Errorf("unexpected plugin details: %q %q", index.ID, index.Name)
+		}
+		if index.LatestVersion.Version != "1.1.0" {
+			t.Errorf("latest version is %q, want 1.1.0", index.LatestVersion.Version)
+		}
+		var versions []string
+		for _, v := range index.Versions {
+			versions = append(versions, v.Version)
+		}
+		if !slices.Equal(versions, []string{"1.0.0", "1.1.0"}) {
+			t.Fatalf("versions are %v, want [1.0.0 1.1.0]", versions)
+		}
+
+		for _, version := range index.Versions {
+			if version.Metadata.ID != fixturePlugin || version.Metadata.Version != version.Version {
+				t.Errorf("%s: unexpected metadata %+v", version.Version, version.Metadata)
+			}
+			for _, platform := range platforms {
+				info, ok := version.Architectures[platform]
+				if !ok {
+					t.Errorf("%s: missing %s", version.Version, platform)
+					continue
+				}
+				want := types.Layout{}.ArtifactPath(releaseFor(version.Version, platform))
+				if info.DownloadURL != want {
+					t.Errorf("%s %s: download url %q, want %q", version.Version, platform, info.DownloadURL, want)
+				}
+				artifact := getObject(t, client, bucket, info.DownloadURL)
+				if sum := sha256Hex(artifact); info.Checksum != sum {
+					t.Errorf("%s %s: checksum %s, artifact is %s", version.Version, platform, info.Checksum, sum)
+				}
+				if info.Size != int64(len(artifact)) {
+					t.Errorf("%s %s: size %d, artifact is %d", version.Version, platform, info.Size, len(artifact))
+				}
+			}
+		}
+	})
+
+	t.Run("registry index", func(t *testing.T) {
+		var registry types.RegistryIndex
+		getJSON(t, client, bucket, "index.json", &registry)
+
+		if len(registry.Plugins) != 1 {
+			t.Fatalf("registry has %d plugins, want 1", len(registry.Plugins))
+		}
+		plugin := registry.Plugins[0]
+		if plugin.ID != fixturePlugin || plugin.LatestVersion.Version != "1.1.0" {
+			t.Errorf("unexpected registry entry %s@%s", plugin.ID, plugin.LatestVersion.Version)
+		}
+	})
+
+	t.Run("latest pointer", func(t *testing.T) {
+		var latest types.LatestVersion
+		getJSON(t, client, bucket, types.LatestVersionPath(fixturePlugin), &latest)
+
+		if latest.Version != "1.1.0" || len(latest.Checksums) != len(platforms) {
+			t.Errorf("unexpected latest pointer %+v", latest)
+		}
+	})
+
+	t.Run("channels", func(t *testing.T) {
+		for _, channel := range types.Channels {
+			var pointer types.LatestVersion
+			getJSON(t, client, bucket, types.ChannelPath(fixturePlugin, channel), &pointer)
+
+			if pointer.Version != "1.1.0" || pointer.Channel != channel {
+				t.Errorf("unexpected %s pointer %+v", channel, pointer)
+			}
+		}
+	})
+
+	t.Run("history", func(t *testing.T) {
+		prefix := pkg.HistoryPrefix + fixturePlugin + "/index/"
+		var snapshots []string
+		for _, key := range listKeys(t, client, bucket) {
+			if strings.HasPrefix(key, prefix) {
+				snapshots = append(snapshots, key)
+			}
+		}
+		if len(snapshots) != 2 {
+			t.Fatalf("plugin index has %d snapshots, want 2", len(snapshots))
+		}
+
+		var snapshot pkg.Snapshot
+		getJSON(t, client, bucket, snapshots[1], &snapshot)
+		if snapshot.Summary != "publish: added 1.1.0" || snapshot.Actor == "" {
+			t.Errorf("unexpected snapshot %s by %q: %q", snapshot.ID, snapshot.Actor, snapshot.Summary)
+		}
+	})
+
+	t.Run("badge", func(t *testing.T) {
+		var badge types.Badge
+		getJSON(t, client, bucket, types.VersionBadgePath(fixturePlugin), &badge)
+
+		if badge.SchemaVersion != 1 || badge.Message != "v1.1.0" {
+			t.Errorf("unexpected badge %+v", badge)
+		}
+	})
+}
+
+func releaseFor(version, platform string) types.Release {
+	for idx := range platform {
+		if platform[idx] == '_' {
+			return types.Release{
+				Plugin:  fixturePlugin,
+				Version: version,
+				OS:      platform[:idx],
+				Arch:    platform[idx+1:],
+			}
+		}
+	}
+	panic("invalid platform " + platform)
+}
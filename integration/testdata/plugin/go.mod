@@ -0,0 +1,3 @@
+module example.com/integration-test
+
+go 1.24
@@ -0,0 +1,13 @@
+package main
+
+import "fmt"
+
+var (
+	pluginID      string
+	pluginVersion string
+	pluginCommit  string
+)
+
+func main() {
+	fmt.Println(pluginID, pluginVersion, pluginCommit)
+}
@@ -0,0 +1,114 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/omniviewdev/registry-cli/pkg/types"
+)
+
+// ErrAccessDenied is returned when a principal isn't on the access list of a private plugin.
+var ErrAccessDenied = errors.New("access denied")
+
+// SetAccess sets the principals allowed to see and install a plugin through the proxy and
+// presigned URLs, recorded in its index and its entry in the registry index, returning the
+// access list set. No principals makes the plugin public again. The indexes stay readable by
+// anyone who can read the bucket, so private plugins need a bucket that isn't public.
+func (i *Indexer) SetAccess(
+	ctx context.Context,
+	plugin string,
+	principals []string,
+) ([]string, error) {
+	access := make([]string, 0, len(principals))
+	for _, principal := range principals {
+		principal = strings.TrimSpace(principal)
+		if principal == "" || strings.ContainsAny(principal, " \t\n") {
+			return nil, fmt.Errorf("invalid principal %q", principal)
+		}
+		access = append(access, principal)
+	}
+	slices.Sort(access)
+	access = slices.Compact(access)
+
+	if len(access) == 0 {
+		access = nil
+	}
+
+	err := i.withLock(ctx, func() error {
+		index, err := i.loadPluginIndex(ctx, plugin)
+		if err != nil {
+			return err
+		}
+		if len(index.Versions) == 0 {
+			return fmt.Errorf("%s has no versions", plugin)
+		}
+		before := index
+		index.Access = access
+		return i.commitPluginIndex(ctx, "access", before, index)
+	})
+	return access, err
+}
+
+// PrivatePlugins returns the entries of the registry index of the plugins with an access list.
+func (i *Indexer) PrivatePlugins(ctx context.Context) ([]types.RegistryIndexPlugins, error) {
+	registry, err := i.getRegistryIndex(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var private []types.RegistryIndexPlugins
+	for _, plugin := range registry.Plugins {
+		if plugin.Private() {
+			private = append(private, plugin)
+		}
+	}
+	return private, nil
+}
+
+// Presign returns a URL downloading the build of a plugin for the platform that expires after
+// the given duration, for a principal on the access list of the plugin when it's private. The
+// version is a semver constraint, the latest version when empty.
+func (i *Indexer) Presign(
+	ctx context.Context,
+	plugin, version, platform, principal string,
+	expires time.Duration,
+) (string, error) {
+	presigner, err := storeFeature[presignStore](i.objects, "presigning downloads")
+	if err != nil {
+		return "", err
+	}
+	index, err := i.loadPluginIndex(ctx, plugin)
+	if err != nil {
+		return "", err
+	}
+	switch {
+	case index.Allows(principal):
+	case principal == "":
+		return "", fmt.Errorf("%s is private to its access list: %w", plugin, ErrAccessDenied)
+	default:
+		return "", fmt.Errorf(
+			"%s isn't on the access list of %s: %w",
+			principal,
+			plugin,
+			ErrAccessDenied,
+		)
+	}
+	resolved, ok := index.Resolve(version)
+	if !ok {
+		return "", fmt.Errorf("%s %s: %w", plugin, version, ErrVersionNotFound)
+	}
+	platform = types.NormalizePlatform(platform)
+	info, ok := resolved.Architectures[platform]
+	if !ok {
+		return "", fmt.Errorf("%s %s has no %s build", plugin, resolved.Version, platform)
+	}
+
+	url, err := presigner.PresignGet(ctx, i.artifactKey(info.DownloadURL), expires)
+	if err != nil {
+		return "", fmt.Errorf("couldn't presign %s %s: %v", plugin, resolved.Version, err)
+	}
+	return url, nil
+}
@@ -0,0 +1,199 @@
+package pkg
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// FindingStatus is the outcome of a bucket check
+type FindingStatus string
+
+const (
+	FindingOK      FindingStatus = "ok"
+	FindingWarning FindingStatus = "warning"
+	FindingProblem FindingStatus = "problem"
+)
+
+// Finding is the result of checking one setting of the registry bucket.
+type Finding struct {
+	Check  string
+	Status FindingStatus
+
+	// Message describes what was found
+	Message string
+
+	// Remediation hints at how to fix a warning or problem
+	Remediation string
+}
+
+// AuditOpts configures a bucket audit.
+type AuditOpts struct {
+	// Origins are the browser origins that must be able to read the registry. Defaults to any
+	// origin.
+	Origins []string
+}
+
+func (o *AuditOpts) Defaulter() {
+	if len(o.Origins) == 0 {
+		o.Origins = []string{"*"}
+	}
+}
+
+// AuditBucket inspects the registry bucket for the settings clients rely on (read access and
+// CORS) and the ones that keep it safe to operate (versioning and encryption).
+func (i *Indexer) AuditBucket(ctx context.Context, opts AuditOpts) ([]Finding, error) {
+	opts.Defaulter()
+
+	bucket, err := storeFeature[bucketStore](i.objects, "checking the bucket settings")
+	if err != nil {
+		return nil, err
+	}
+	if err := bucket.CheckBucket(ctx); err != nil {
+		return []Finding{{
+			Check:       "bucket",
+			Status:      FindingProblem,
+			Message:     fmt.Sprintf("couldn't access bucket %s: %v", i.bucket, err),
+			Remediation: "create it with 'registry-cli bootstrap', or check your credentials",
+		}}, nil
+	}
+
+	checks := []func(context.Context, bucketStore) (Finding, error){
+		i.auditVersioning,
+		i.auditEncryption,
+		i.auditReadAccess,
+		func(ctx context.Context, _ bucketStore) (Finding, error) {
+			return i.auditCORS(ctx, opts.Origins)
+		},
+	}
+
+	findings := make([]Finding, 0, len(checks))
+	for _, check := range checks {
+		finding, err := check(ctx, bucket)
+		if err != nil {
+			return nil, err
+		}
+		findings = append(findings, finding)
+	}
+	return findings, nil
+}
+
+func (i *Indexer) auditVersioning(ctx context.Context, bucket bucketStore) (Finding, error) {
+	finding := Finding{Check: "versioning"}
+
+	versioning, err := bucket.Versioning(ctx)
+	if err != nil {
+		return finding, fmt.Errorf("couldn't get versioning of %s: %v", i.bucket, err)
+	}
+
+	if versioning {
+		finding.Status = FindingOK
+		finding.Message = "versioning is enabled"
+		return finding, nil
+	}
+	finding.Status = FindingWarning
+	finding.Message = "versioning is off, overwritten indexes can't be recovered"
+	finding.Remediation = fmt.Sprintf(
+		"aws s3api put-bucket-versioning --bucket %s --versioning-configuration Status=Enabled",
+		i.bucket,
+	)
+	return finding, nil
+}
+
+func (i *Indexer) auditEncryption(ctx context.Context, bucket bucketStore) (Finding, error) {
+	finding := Finding{Check: "encryption"}
+
+	algorithm, err := bucket.Encryption(ctx)
+	if err != nil {
+		return finding, fmt.Errorf("couldn't get encryption of %s: %v", i.bucket, err)
+	}
+
+	if algorithm != "" {
+		finding.Status = FindingOK
+		finding.Message = fmt.Sprintf("objects are encrypted with %s", algorithm)
+		return finding, nil
+	}
+	finding.Status = FindingWarning
+	finding.Message = "default encryption isn't configured"
+	finding.Remediation = fmt.Sprintf(
+		"aws s3api put-bucket-encryption --bucket %s --server-side-encryption-configuration "+
+			`'{"Rules":[{"ApplyServerSideEncryptionByDefault":{"SSEAlgorithm":"AES256"}}]}'`,
+		i.bucket,
+	)
+	return finding, nil
+}
+
+// auditReadAccess checks that the registry can be read, either publicly or through a CloudFront
+// distribution.
+func (i *Indexer) auditReadAccess(ctx context.Context, bucket bucketStore) (Finding, error) {
+	finding := Finding{Check: "read access"}
+
+	policy, public, err := bucket.Policy(ctx)
+	if err != nil {
+		return finding, fmt.Errorf("couldn't get policy of %s: %v", i.bucket, err)
+	}
+	if public {
+		finding.Status = FindingOK
+		finding.Message = "the bucket is publicly readable"
+		return finding, nil
+	}
+	if grantsCloudFrontRead(policy) {
+		finding.Status = FindingOK
+		finding.Message = "the bucket is readable by a CloudFront distribution"
+		return finding, nil
+	}
+
+	finding.Status = FindingProblem
+	finding.Message = "the bucket isn't publicly readable and no CloudFront distribution can read it"
+	finding.Remediation = fmt.Sprintf(
+		"run 'registry-cli bootstrap --bucket %s' (or with --access cloudfront --distribution-arn ...)",
+		i.bucket,
+	)
+	return finding, nil
+}
+
+// grantsCloudFrontRead reports whether a bucket policy lets CloudFront get objects.
+func grantsCloudFrontRead(policy string) bool {
+	var document struct {
+		Statement []struct {
+			Effect    string          `json:"Effect"`
+			Principal json.RawMessage `json:"Principal"`
+			Action    json.RawMessage `json:"Action"`
+		} `json:"Statement"`
+	}
+	if err := json.Unmarshal([]byte(policy), &document); err != nil {
+		return false
+	}
+	for _, statement := range document.Statement {
+		if statement.Effect == "Allow" &&
+			strings.Contains(string(statement.Principal), "cloudfront.amazonaws.com") &&
+			(strings.Contains(string(statement.Action), "s3:GetObject") ||
+				strings.Contains(string(statement.Action), "s3:*")) {
+			return true
+		}
+	}
+	return false
+}
+
+func (i *Indexer) auditCORS(ctx context.Context, origins []string) (Finding, error) {
+	finding := Finding{Check: "CORS"}
+
+	gaps, err := i.VerifyCORS(ctx, origins)
+	if err != nil {
+		return finding, err
+	}
+	if len(gaps) > 0 {
+		finding.Status = FindingProblem
+		finding.Message = strings.Join(gaps, ", ")
+		finding.Remediation = fmt.Sprintf(
+			"run 'registry-cli cors apply --bucket %s --origin ...' to apply the CORS rules",
+			i.bucket,
+		)
+		return finding, nil
+	}
+
+	finding.Status = FindingOK
+	finding.Message = "CORS rules allow the registry to be read from " + strings.Join(origins, ", ")
+	return finding, nil
+}
@@ -0,0 +1,401 @@
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/streaming"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/bloberror"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blockblob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/container"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// azureCopyPollInterval is how often a copy the blob service is still making is checked on
+const azureCopyPollInterval = time.Second
+
+// azureStore is the ObjectStore of a container of an Azure Storage account, the bucket of the
+// registry being the container, with the same layout of blobs. Objects are block blobs, and
+// the storage classes map to the closest access tiers. The blob service is strongly consistent,
+// so objects can be read back as soon as they're stored.
+//
+// Builds larger than the part size are uploaded as blocks committed once they're all staged.
+// The blocks of an upload that failed are never committed, and the blob service discards
+// uncommitted blocks after a week, so there are no incomplete uploads for gc to abort. The
+// container settings, blob versions and presigned URLs of S3 aren't translated, and fail with
+// ErrNotSupported.
+type azureStore struct {
+	container *container.Client
+}
+
+// newAzureStore returns the ObjectStore of the container, authorized with the connection string
+// in AZURE_STORAGE_CONNECTION_STRING, or else with the default Azure credential (environment,
+// managed identity, or az login) for the account in AZURE_STORAGE_ACCOUNT.
+func newAzureStore(bucket string, pacer *requestPacer) (*azureStore, error) {
+	if bucket == "" {
+		return nil, errors.New("no container given, give its name as the bucket")
+	}
+	service, err := newAzureServiceClient(azureClientOptions(pacer))
+	if err != nil {
+		return nil, err
+	}
+	return &azureStore{container: service.ServiceClient().NewContainerClient(bucket)}, nil
+}
+
+// azureClientOptions applies the retry policy of the store, and the pacing of the pacer unless
+// it's nil, to the requests of a client of the blob service.
+func azureClientOptions(pacer *requestPacer) *azblob.ClientOptions {
+	opts := &azblob.ClientOptions{}
+	opts.Retry = policy.RetryOptions{
+		MaxRetries:    storeMaxAttempts - 1,
+		MaxRetryDelay: storeMaxBackoff,
+	}
+	if pacer != nil {
+		// every attempt waits its turn
+		opts.PerRetryPolicies = append(opts.PerRetryPolicies, azurePacing{pacer})
+	}
+	return opts
+}
+
+// azurePacing paces the requests of a client of the blob service.
+type azurePacing struct {
+	pacer *requestPacer
+}
+
+func (p azurePacing) Do(req *policy.Request) (*http.Response, error) {
+	done, err := p.pacer.wait(req.Raw().Context())
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	return req.Next()
+}
+
+// newAzureServiceClient creates a client of the blob service of the storage account.
+func newAzureServiceClient(opts *azblob.ClientOptions) (*azblob.Client, error) {
+	if connectionString := os.Getenv("AZURE_STORAGE_CONNECTION_STRING"); connectionString != "" {
+		client, err := azblob.NewClientFromConnectionString(connectionString, opts)
+		if err != nil {
+			return nil, fmt.Errorf("invalid AZURE_STORAGE_CONNECTION_STRING: %v", err)
+		}
+		return client, nil
+	}
+
+	account := os.Getenv("AZURE_STORAGE_ACCOUNT")
+	if account == "" {
+		return nil, errors.New(
+			"no Azure storage account, set AZURE_STORAGE_ACCOUNT or AZURE_STORAGE_CONNECTION_STRING",
+		)
+	}
+	credential, err := azidentity.NewDefaultAzureCredential(nil)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't find Azure credentials, have you run az login? %v", err)
+	}
+	client, err := azblob.NewClient(
+		fmt.Sprintf("https://%s.blob.core.windows.net/", account),
+		credential,
+		opts,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't create Azure Blob Storage client: %v", err)
+	}
+	return client, nil
+}
+
+func (s *azureStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	download, err := s.container.NewBlobClient(key).DownloadStream(ctx, nil)
+	if err != nil {
+		return nil, azureNotFound(err, key)
+	}
+	return download.Body, nil
+}
+
+func (s *azureStore) Put(ctx context.Context, key string, b []byte, opts PutOptions) error {
+	_, err := s.container.NewBlockBlobClient(key).Upload(
+		ctx,
+		streaming.NopCloser(bytes.NewReader(b)),
+		&blockblob.UploadOptions{HTTPHeaders: blobHeaders(opts.ContentType)},
+	)
+	return err
+}
+
+func (s *azureStore) PutIfAbsent(ctx context.Context, key string, b []byte) (string, error) {
+	uploaded, err := s.container.NewBlockBlobClient(key).Upload(
+		ctx,
+		streaming.NopCloser(bytes.NewReader(b)),
+		&blockblob.UploadOptions{AccessConditions: ifNoneMatch(azcore.ETagAny)},
+	)
+	if err != nil {
+		return "", azurePreconditionFailed(err)
+	}
+	return etagOf(uploaded.ETag), nil
+}
+
+// Upload uploads the object in a single request, or in blocks of the part size when it's
+// larger, which are staged Concurrency at once and committed once they all are. The blob
+// service checks every request against a CRC64 of its body; it computes no sha256 checksum,
+// which is left to the caller.
+func (s *azureStore) Upload(
+	ctx context.Context,
+	key string,
+	body io.ReadSeeker,
+	size int64,
+	opts UploadOptions,
+) (string, error) {
+	tier, err := accessTierOf(opts.StorageClass)
+	if err != nil {
+		return "", err
+	}
+	client := s.container.NewBlockBlobClient(key)
+
+	if opts.PartSize == 0 || size <= opts.PartSize {
+		_, err := client.Upload(ctx, streaming.NopCloser(body), &blockblob.UploadOptions{
+			HTTPHeaders:             blobHeaders(opts.ContentType),
+			Tier:                    tier,
+			TransactionalValidation: blob.TransferValidationTypeComputeCRC64(),
+		})
+		return "", err
+	}
+
+	// the staged blocks of a failed upload are left uncommitted, for the service to discard
+	_, err = client.UploadStream(ctx, body, &blockblob.UploadStreamOptions{
+		BlockSize:               opts.PartSize,
+		Concurrency:             opts.Concurrency,
+		HTTPHeaders:             blobHeaders(opts.ContentType),
+		AccessTier:              tier,
+		TransactionalValidation: blob.TransferValidationTypeComputeCRC64(),
+	})
+	return "", err
+}
+
+func (s *azureStore) Head(ctx context.Context, key string) (ObjectInfo, error) {
+	props, err := s.container.NewBlobClient(key).GetProperties(ctx, nil)
+	if err != nil {
+		return ObjectInfo{}, azureNotFound(err, key)
+	}
+	info := ObjectInfo{
+		Key:          key,
+		ETag:         etagOf(props.ETag),
+		StorageClass: s3types.StorageClassStandard,
+	}
+	if props.ContentLength != nil {
+		info.Size = *props.ContentLength
+	}
+	if props.LastModified != nil {
+		info.LastModified = *props.LastModified
+	}
+	if props.AccessTier != nil {
+		info.StorageClass = storageClassOf(blob.AccessTier(*props.AccessTier))
+	}
+	return info, nil
+}
+
+// List lists the blobs of the container, which the blob service lists in the order of their
+// names.
+func (s *azureStore) List(ctx context.Context, prefix string) ([]ObjectInfo, error) {
+	var objects []ObjectInfo
+	pager := s.container.NewListBlobsFlatPager(&container.ListBlobsFlatOptions{
+		Prefix: optional(prefix),
+	})
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range page.Segment.BlobItems {
+			object := ObjectInfo{StorageClass: s3types.StorageClassStandard}
+			if item.Name != nil {
+				object.Key = *item.Name
+			}
+			if props := item.Properties; props != nil {
+				object.ETag = etagOf(props.ETag)
+				if props.ContentLength != nil {
+					object.Size = *props.ContentLength
+				}
+				if props.LastModified != nil {
+					object.LastModified = *props.LastModified
+				}
+				if props.AccessTier != nil {
+					object.StorageClass = storageClassOf(*props.AccessTier)
+				}
+			}
+			objects = append(objects, object)
+		}
+	}
+	return objects, nil
+}
+
+// Copy copies a blob of the container, waiting for the blob service to finish copying.
+func (s *azureStore) Copy(ctx context.Context, src, dst string) error {
+	source := s.container.NewBlobClient(src)
+	target := s.container.NewBlobClient(dst)
+	copied, err := target.StartCopyFromURL(ctx, source.URL(), nil)
+	if err != nil {
+		return azureNotFound(err, src)
+	}
+	status := copied.CopyStatus
+	for status != nil && *status == blob.CopyStatusTypePending {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(azureCopyPollInterval):
+		}
+		props, err := target.GetProperties(ctx, nil)
+		if err != nil {
+			return err
+		}
+		status = props.CopyStatus
+	}
+	if status != nil && *status != blob.CopyStatusTypeSuccess {
+		return fmt.Errorf("copying %s to %s failed: %s", src, dst, *status)
+	}
+	return nil
+}
+
+// Transition moves the blob to the access tier of the storage class.
+func (s *azureStore) Transition(ctx context.Context, key string, class s3types.StorageClass) error {
+	tier, err := accessTierOf(class)
+	if err != nil {
+		return err
+	}
+	if tier == nil {
+		tier = to(blob.AccessTierHot)
+	}
+	_, err = s.container.NewBlobClient(key).SetTier(ctx, *tier, nil)
+	return azureNotFound(err, key)
+}
+
+func (s *azureStore) Delete(ctx context.Context, key string) error {
+	_, err := s.container.NewBlobClient(key).Delete(ctx, nil)
+	if bloberror.HasCode(err, bloberror.BlobNotFound) {
+		return nil
+	}
+	return err
+}
+
+func (s *azureStore) DeleteIfMatch(ctx context.Context, key, etag string) error {
+	_, err := s.container.NewBlobClient(key).Delete(ctx, &blob.DeleteOptions{
+		AccessConditions: &blob.AccessConditions{
+			ModifiedAccessConditions: &blob.ModifiedAccessConditions{
+				IfMatch: to(azcore.ETag(etag)),
+			},
+		},
+	})
+	if bloberror.HasCode(err, bloberror.BlobNotFound) {
+		return nil
+	}
+	return azurePreconditionFailed(err)
+}
+
+// Location returns the URL of the blob at key.
+func (s *azureStore) Location(key string) string {
+	return s.container.NewBlobClient(key).URL()
+}
+
+func (s *azureStore) Provider() string {
+	return ProviderAzure
+}
+
+// storageClassTiers maps the storage classes to the access tiers closest in cost and access
+var storageClassTiers = map[s3types.StorageClass]blob.AccessTier{
+	s3types.StorageClassStandard:           blob.AccessTierHot,
+	s3types.StorageClassReducedRedundancy:  blob.AccessTierHot,
+	s3types.StorageClassIntelligentTiering: blob.AccessTierHot,
+	s3types.StorageClassStandardIa:         blob.AccessTierCool,
+	s3types.StorageClassOnezoneIa:          blob.AccessTierCool,
+	s3types.StorageClassGlacierIr:          blob.AccessTierCold,
+	s3types.StorageClassGlacier:            blob.AccessTierArchive,
+	s3types.StorageClassDeepArchive:        blob.AccessTierArchive,
+}
+
+// accessTierOf returns the access tier of a storage class, nil for the default tier of the
+// account when the class is empty.
+func accessTierOf(class s3types.StorageClass) (*blob.AccessTier, error) {
+	if class == "" {
+		return nil, nil
+	}
+	tier, ok := storageClassTiers[class]
+	if !ok {
+		return nil, unsupported(ProviderAzure, "the "+string(class)+" storage class")
+	}
+	return &tier, nil
+}
+
+func storageClassOf(tier blob.AccessTier) s3types.StorageClass {
+	switch tier {
+	case blob.AccessTierCool:
+		return s3types.StorageClassStandardIa
+	case blob.AccessTierCold:
+		return s3types.StorageClassGlacierIr
+	case blob.AccessTierArchive:
+		return s3types.StorageClassGlacier
+	default:
+		return s3types.StorageClassStandard
+	}
+}
+
+// ifNoneMatch is the condition of a write that the blob's entity tag isn't etag, or that
+// there's no blob for azcore.ETagAny.
+func ifNoneMatch(etag azcore.ETag) *blob.AccessConditions {
+	return &blob.AccessConditions{
+		ModifiedAccessConditions: &blob.ModifiedAccessConditions{IfNoneMatch: &etag},
+	}
+}
+
+func blobHeaders(contentType string) *blob.HTTPHeaders {
+	if contentType == "" {
+		return nil
+	}
+	return &blob.HTTPHeaders{BlobContentType: &contentType}
+}
+
+func etagOf(etag *azcore.ETag) string {
+	if etag == nil {
+		return ""
+	}
+	return string(*etag)
+}
+
+func optional(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+func to[T any](v T) *T {
+	return &v
+}
+
+// azureNotFound reports the errors of the blob service for missing blobs as ErrObjectNotFound.
+func azureNotFound(err error, key string) error {
+	if bloberror.HasCode(err, bloberror.BlobNotFound) {
+		return fmt.Errorf("%s: %w", key, ErrObjectNotFound)
+	}
+	return err
+}
+
+// azurePreconditionFailed reports the errors of the blob service for failed conditional writes
+// as ErrPreconditionFailed.
+func azurePreconditionFailed(err error) error {
+	if bloberror.HasCode(
+		err,
+		bloberror.ConditionNotMet,
+		bloberror.BlobAlreadyExists,
+		bloberror.TargetConditionNotMet,
+	) {
+		return fmt.Errorf("%w: %v", ErrPreconditionFailed, err)
+	}
+	return err
+}
@@ -0,0 +1,537 @@
+package pkg
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/container"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// fakeBlob is a blob of a fakeBlobService.
+type fakeBlob struct {
+	b        []byte
+	etag     string
+	tier     string
+	modified time.Time
+}
+
+// fakeRequest is a request a fakeBlobService served.
+type fakeRequest struct {
+	method string
+	key    string
+	comp   string
+	header http.Header
+}
+
+// fakeBlobService serves the blob service requests of the store for a single container, named
+// registry, holding its blobs in memory. It lists blobs two at a time, so listing pages.
+type fakeBlobService struct {
+	mu       sync.Mutex
+	blobs    map[string]fakeBlob
+	blocks   map[string][]byte
+	version  int
+	requests []fakeRequest
+}
+
+// testAzureStore returns a store of a fakeBlobService, and the service.
+func testAzureStore(t *testing.T) (*azureStore, *fakeBlobService) {
+	t.Helper()
+	service := &fakeBlobService{blobs: make(map[string]fakeBlob), blocks: make(map[string][]byte)}
+	server := httptest.NewServer(service)
+	t.Cleanup(server.Close)
+	client, err := container.NewClientWithNoCredential(server.URL+"/registry", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &azureStore{container: client}, service
+}
+
+// served returns the requests served with the method, for any comp when comp is "*".
+func (f *fakeBlobService) served(method, comp string) []fakeRequest {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var requests []fakeRequest
+	for _, r := range f.requests {
+		if r.method == method && (comp == "*" || r.comp == comp) {
+			requests = append(requests, r)
+		}
+	}
+	return requests
+}
+
+func (f *fakeBlobService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	key, _ := strings.CutPrefix(strings.TrimPrefix(r.URL.Path, "/registry"), "/")
+	query := r.URL.Query()
+	f.requests = append(f.requests, fakeRequest{
+		method: r.Method,
+		key:    key,
+		comp:   query.Get("comp"),
+		header: r.Header.Clone(),
+	})
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	existing, exists := f.blobs[key]
+	if match := r.Header.Get("If-Match"); match != "" && exists && match != existing.etag {
+		f.fail(w, http.StatusPreconditionFailed, "ConditionNotMet")
+		return
+	}
+	if r.Header.Get("If-None-Match") == "*" && exists {
+		f.fail(w, http.StatusConflict, "BlobAlreadyExists")
+		return
+	}
+
+	switch {
+	case r.Method == http.MethodGet && query.Get("restype") == "container":
+		f.list(w, query.Get("prefix"), query.Get("marker"))
+	case r.Method == http.MethodPut && query.Get("comp") == "block":
+		f.blocks[query.Get("blockid")] = body
+		w.WriteHeader(http.StatusCreated)
+	case r.Method == http.MethodPut && query.Get("comp") == "blocklist":
+		var list struct {
+			Latest []string `xml:"Latest"`
+		}
+		if err := xml.Unmarshal(body, &list); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		var b []byte
+		for _, id := range list.Latest {
+			b = append(b, f.blocks[id]...)
+		}
+		f.put(w, key, b, r.Header.Get("x-ms-access-tier"))
+	case r.Method == http.MethodPut && query.Get("comp") == "tier":
+		if !exists {
+			f.fail(w, http.StatusNotFound, "BlobNotFound")
+			return
+		}
+		existing.tier = r.Header.Get("x-ms-access-tier")
+		f.blobs[key] = existing
+		w.WriteHeader(http.StatusOK)
+	case r.Method == http.MethodPut && r.Header.Get("x-ms-copy-source") != "":
+		source, err := url.Parse(r.Header.Get("x-ms-copy-source"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		copied, ok := f.blobs[strings.TrimPrefix(source.Path, "/registry/")]
+		if !ok {
+			f.fail(w, http.StatusNotFound, "BlobNotFound")
+			return
+		}
+		w.Header().Set("x-ms-copy-status", "success")
+		f.put(w, key, copied.b, copied.tier)
+	case r.Method == http.MethodPut:
+		f.put(w, key, body, r.Header.Get("x-ms-access-tier"))
+	case r.Method == http.MethodGet, r.Method == http.MethodHead:
+		if !exists {
+			f.fail(w, http.StatusNotFound, "BlobNotFound")
+			return
+		}
+		w.Header().Set("ETag", existing.etag)
+		w.Header().Set("Last-Modified", existing.modified.Format(http.TimeFormat))
+		w.Header().Set("Content-Length", fmt.Sprint(len(existing.b)))
+		w.Header().Set("x-ms-blob-type", "BlockBlob")
+		w.Header().Set("x-ms-access-tier", existing.tier)
+		w.WriteHeader(http.StatusOK)
+		if r.Method == http.MethodGet {
+			w.Write(existing.b)
+		}
+	case r.Method == http.MethodDelete:
+		if !exists {
+			f.fail(w, http.StatusNotFound, "BlobNotFound")
+			return
+		}
+		delete(f.blobs, key)
+		w.WriteHeader(http.StatusAccepted)
+	default:
+		http.Error(w, "unexpected request", http.StatusBadRequest)
+	}
+}
+
+// put stores a blob, the tier being Hot when empty.
+func (f *fakeBlobService) put(w http.ResponseWriter, key string, b []byte, tier string) {
+	if tier == "" {
+		tier = "Hot"
+	}
+	f.version++
+	blob := fakeBlob{
+		b:        b,
+		etag:     fmt.Sprintf(`"0x%d"`, f.version),
+		tier:     tier,
+		modified: time.Now().UTC(),
+	}
+	f.blobs[key] = blob
+	w.Header().Set("ETag", blob.etag)
+	w.Header().Set("Last-Modified", blob.modified.Format(http.TimeFormat))
+	if w.Header().Get("x-ms-copy-status") != "" {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+}
+
+// list lists the blobs from the marker on, two at a time.
+func (f *fakeBlobService) list(w http.ResponseWriter, prefix, marker string) {
+	var keys []string
+	for key := range f.blobs {
+		if strings.HasPrefix(key, prefix) && key >= marker {
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(keys)
+	var next string
+	if len(keys) > 2 {
+		next = keys[2]
+		keys = keys[:2]
+	}
+
+	var blobs strings.Builder
+	for _, key := range keys {
+		blob := f.blobs[key]
+		fmt.Fprintf(
+			&blobs,
+			"<Blob><Name>%s</Name><Properties><Last-Modified>%s</Last-Modified>"+
+				"<Etag>%s</Etag><Content-Length>%d</Content-Length><BlobType>BlockBlob</BlobType>"+
+				"<AccessTier>%s</AccessTier></Properties></Blob>",
+			key,
+			blob.modified.Format(http.TimeFormat),
+			blob.etag,
+			len(blob.b),
+			blob.tier,
+		)
+	}
+	w.Header().Set("Content-Type", "application/xml")
+	fmt.Fprintf(
+		w,
+		`<?xml version="1.0" encoding="utf-8"?><EnumerationResults ContainerName="registry">`+
+			"<Prefix>%s</Prefix><Blobs>%s</Blobs><NextMarker>%s</NextMarker></EnumerationResults>",
+		prefix,
+		blobs.String(),
+		next,
+	)
+}
+
+// fail responds with the error code of the blob service.
+func (f *fakeBlobService) fail(w http.ResponseWriter, status int, code string) {
+	w.Header().Set("x-ms-error-code", code)
+	w.WriteHeader(status)
+}
+
+func TestAzureStore(t *testing.T) {
+	ctx := t.Context()
+	objects, service := testAzureStore(t)
+
+	if err := objects.Put(ctx, "demo/index.json", []byte("index"), PutOptions{
+		ContentType: "application/json",
+	}); err != nil {
+		t.Fatal(err)
+	}
+	put := service.served(http.MethodPut, "")
+	if got := put[0].header.Get("x-ms-blob-content-type"); got != "application/json" {
+		t.Fatalf("put content type %q, want application/json", got)
+	}
+	if got := read(t, objects, "demo/index.json"); string(got) != "index" {
+		t.Fatalf("read %q, want %q", got, "index")
+	}
+	location, err := url.Parse(objects.Location("demo/index.json"))
+	if err != nil || location.Path != "/registry/demo/index.json" {
+		t.Fatalf("located the blob at %v, want its URL: %v", location, err)
+	}
+	if _, err := objects.Get(ctx, "demo/missing.json"); !errors.Is(err, ErrObjectNotFound) {
+		t.Fatalf("got %v, want %v", err, ErrObjectNotFound)
+	}
+	if _, err := objects.Head(ctx, "demo/missing.json"); !errors.Is(err, ErrObjectNotFound) {
+		t.Fatalf("got %v, want %v", err, ErrObjectNotFound)
+	}
+
+	// PutIfAbsent and DeleteIfMatch guard the lock
+	etag, err := objects.PutIfAbsent(ctx, lockKey, []byte("held"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := objects.PutIfAbsent(ctx, lockKey, []byte("taken")); !errors.Is(
+		err,
+		ErrPreconditionFailed,
+	) {
+		t.Fatalf("got %v, want %v", err, ErrPreconditionFailed)
+	}
+	for _, r := range service.served(http.MethodPut, "") {
+		if r.key == lockKey && r.header.Get("If-None-Match") != "*" {
+			t.Fatalf("put the lock with If-None-Match %q", r.header.Get("If-None-Match"))
+		}
+	}
+	info, err := objects.Head(ctx, lockKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.ETag != etag || info.Size != int64(len("held")) {
+		t.Fatalf("described %+v, want etag %s", info, etag)
+	}
+	if err := objects.DeleteIfMatch(ctx, lockKey, `"other"`); !errors.Is(
+		err,
+		ErrPreconditionFailed,
+	) {
+		t.Fatalf("got %v, want %v", err, ErrPreconditionFailed)
+	}
+	if err := objects.DeleteIfMatch(ctx, lockKey, etag); err != nil {
+		t.Fatal(err)
+	}
+	deleted := service.served(http.MethodDelete, "")
+	if got := deleted[len(deleted)-1].header.Get("If-Match"); got != etag {
+		t.Fatalf("deleted the lock with If-Match %q, want %q", got, etag)
+	}
+	if err := objects.DeleteIfMatch(ctx, lockKey, etag); err != nil {
+		t.Fatalf("deleting a missing object failed: %v", err)
+	}
+	if err := objects.Delete(ctx, lockKey); err != nil {
+		t.Fatalf("deleting a missing object failed: %v", err)
+	}
+
+	if err := objects.Copy(ctx, "demo/index.json", "demo/1.0.0/index.json"); err != nil {
+		t.Fatal(err)
+	}
+	if err := objects.Copy(ctx, "demo/missing.json", "demo/1.0.0/index.json"); !errors.Is(
+		err,
+		ErrObjectNotFound,
+	) {
+		t.Fatalf("got %v, want %v", err, ErrObjectNotFound)
+	}
+	if err := objects.Put(ctx, "index.json", nil, PutOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"demo/latest.json", "demo/1.0.0/latest.json"} {
+		if err := objects.Put(ctx, key, nil, PutOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// listed over several pages
+	listed, err := objects.List(ctx, "demo/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var keys []string
+	for _, object := range listed {
+		keys = append(keys, object.Key)
+		if object.StorageClass != s3types.StorageClassStandard || object.ETag == "" {
+			t.Fatalf("listed %+v", object)
+		}
+	}
+	want := []string{
+		"demo/1.0.0/index.json",
+		"demo/1.0.0/latest.json",
+		"demo/index.json",
+		"demo/latest.json",
+	}
+	if !slices.Equal(keys, want) {
+		t.Fatalf("listed %v, want %v", keys, want)
+	}
+	if pages := service.served(http.MethodGet, "list"); len(pages) != 2 {
+		t.Fatalf("listed %d pages, want 2", len(pages))
+	}
+}
+
+func TestAzureStoreUpload(t *testing.T) {
+	// the blob service is sent blocks of at least a MiB
+	build := bytes.Repeat([]byte("plugin build "), 200_000)
+	const partSize = 1 << 20
+
+	tests := []struct {
+		name       string
+		opts       UploadOptions
+		wantTier   string
+		wantBlocks int
+		wantErr    error
+	}{
+		{name: "single"},
+		{name: "large part", opts: UploadOptions{PartSize: int64(len(build))}},
+		{
+			name:     "infrequent access",
+			opts:     UploadOptions{StorageClass: s3types.StorageClassStandardIa},
+			wantTier: "Cool",
+		},
+		{
+			name:       "blocks",
+			opts:       UploadOptions{PartSize: partSize, Concurrency: 2},
+			wantBlocks: 3,
+		},
+		{
+			name: "archived blocks",
+			opts: UploadOptions{
+				StorageClass: s3types.StorageClassDeepArchive,
+				PartSize:     partSize,
+			},
+			wantTier:   "Archive",
+			wantBlocks: 3,
+		},
+		{
+			name:    "unmapped class",
+			opts:    UploadOptions{StorageClass: s3types.StorageClassExpressOnezone},
+			wantErr: ErrNotSupported,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objects, service := testAzureStore(t)
+			key := "demo/1.0.0/linux-amd64.tar.gz"
+			_, err := objects.Upload(
+				t.Context(),
+				key,
+				bytes.NewReader(build),
+				int64(len(build)),
+				tt.opts,
+			)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) ||
+					!strings.Contains(err.Error(), "not supported by the azure provider") {
+					t.Fatalf("got %v, want %v", err, tt.wantErr)
+				}
+				if served := service.served(http.MethodPut, "*"); len(served) != 0 {
+					t.Fatalf("sent %d requests for an upload that can't be made", len(served))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			blocks := service.served(http.MethodPut, "block")
+			if len(blocks) != tt.wantBlocks {
+				t.Fatalf("staged %d blocks, want %d", len(blocks), tt.wantBlocks)
+			}
+			committed := service.served(http.MethodPut, "")
+			if tt.wantBlocks > 0 {
+				committed = service.served(http.MethodPut, "blocklist")
+			}
+			if len(committed) != 1 {
+				t.Fatalf("committed the blob %d times, want once", len(committed))
+			}
+			if got := committed[0].header.Get("x-ms-access-tier"); got != tt.wantTier {
+				t.Fatalf("uploaded to tier %q, want %q", got, tt.wantTier)
+			}
+			validated := append(blocks, service.served(http.MethodPut, "")...)
+			for _, r := range validated {
+				if r.header.Get("x-ms-content-crc64") == "" {
+					t.Fatal("uploaded without a CRC64 of the content")
+				}
+			}
+			if b := read(t, objects, key); !bytes.Equal(b, build) {
+				t.Fatal("the uploaded build differs from the build")
+			}
+		})
+	}
+}
+
+func TestAzureStoreTransition(t *testing.T) {
+	tests := []struct {
+		class     s3types.StorageClass
+		wantTier  string
+		wantClass s3types.StorageClass
+	}{
+		{
+			class:     s3types.StorageClassStandard,
+			wantTier:  "Hot",
+			wantClass: s3types.StorageClassStandard,
+		},
+		{
+			class:     s3types.StorageClassStandardIa,
+			wantTier:  "Cool",
+			wantClass: s3types.StorageClassStandardIa,
+		},
+		{
+			class:     s3types.StorageClassGlacierIr,
+			wantTier:  "Cold",
+			wantClass: s3types.StorageClassGlacierIr,
+		},
+		{
+			class:     s3types.StorageClassGlacier,
+			wantTier:  "Archive",
+			wantClass: s3types.StorageClassGlacier,
+		},
+		{
+			class:     s3types.StorageClassDeepArchive,
+			wantTier:  "Archive",
+			wantClass: s3types.StorageClassGlacier,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.class), func(t *testing.T) {
+			ctx := t.Context()
+			objects, service := testAzureStore(t)
+			if err := objects.Put(ctx, "demo/index.json", nil, PutOptions{}); err != nil {
+				t.Fatal(err)
+			}
+			if err := objects.Transition(ctx, "demo/index.json", tt.class); err != nil {
+				t.Fatal(err)
+			}
+			tiered := service.served(http.MethodPut, "tier")
+			if len(tiered) != 1 || tiered[0].header.Get("x-ms-access-tier") != tt.wantTier {
+				t.Fatalf("set tier %v, want %s", tiered, tt.wantTier)
+			}
+			info, err := objects.Head(ctx, "demo/index.json")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.StorageClass != tt.wantClass {
+				t.Fatalf("described class %s, want %s", info.StorageClass, tt.wantClass)
+			}
+		})
+	}
+
+	objects, _ := testAzureStore(t)
+	err := objects.Transition(t.Context(), "demo/missing.json", s3types.StorageClassGlacier)
+	if !errors.Is(err, ErrObjectNotFound) {
+		t.Fatalf("got %v, want %v", err, ErrObjectNotFound)
+	}
+}
+
+func TestAzureProviderUnsupported(t *testing.T) {
+	ctx := t.Context()
+	objects, _ := testAzureStore(t)
+	indexer := &Indexer{objects: objects, bucket: "registry"}
+
+	tests := []struct {
+		name string
+		run  func() error
+	}{
+		{name: "bootstrap", run: func() error {
+			return indexer.Bootstrap(ctx, BootstrapOpts{})
+		}},
+		{name: "cors", run: func() error { return indexer.ApplyCORS(ctx, nil) }},
+		{name: "stale uploads", run: func() error {
+			_, err := indexer.AbortStaleUploads(ctx, time.Hour, true)
+			return err
+		}},
+		{name: "revisions", run: func() error {
+			_, err := indexer.IndexRevisions(ctx, "demo")
+			return err
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.run()
+			if !errors.Is(err, ErrNotSupported) ||
+				!strings.Contains(err.Error(), "not supported by the azure provider") {
+				t.Fatalf("got %v, want it not supported by the azure provider", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,230 @@
+package pkg
+
+import (
+	"cmp"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	mrand "math/rand/v2"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/omniviewdev/registry-cli/pkg/console"
+)
+
+// BenchPrefix prefixes the keys of the objects uploaded by benchmarks, which are deleted once
+// measured.
+const BenchPrefix = "bench/"
+
+var (
+	// DefaultBenchPartSizes are the part sizes benchmarked by default
+	DefaultBenchPartSizes = []int64{8 << 20, 16 << 20, 64 << 20}
+
+	// DefaultBenchConcurrencies are the upload concurrencies benchmarked by default
+	DefaultBenchConcurrencies = []int{2, 5, 10}
+)
+
+// BenchOpts configures an upload benchmark.
+type BenchOpts struct {
+	// Size is the size of the object uploaded with each setting
+	Size int64
+
+	// PartSizes are the part sizes to upload with, along with a single request. Defaults to
+	// DefaultBenchPartSizes.
+	PartSizes []int64
+
+	// Concurrencies are the upload concurrencies to try each part size with. Defaults to
+	// DefaultBenchConcurrencies.
+	Concurrencies []int
+}
+
+// BenchResult is the throughput measured for an upload setting.
+type BenchResult struct {
+	// PartSize is the part size uploaded with, zero for a single request
+	PartSize int64
+
+	// Concurrency is how many parts were uploaded at once
+	Concurrency int
+
+	Duration time.Duration
+
+	// Throughput is in bytes per second
+	Throughput float64
+
+	Err error
+}
+
+// BenchUpload measures the throughput of uploads to the bucket of the publisher, with a single
+// request and in parts of each of the part sizes with each of the concurrencies. The results
+// are in the order they were measured, see BestBenchResult for the setting to publish with.
+func (p *Publisher) BenchUpload(ctx context.Context, opts BenchOpts) ([]BenchResult, error) {
+	if opts.Size <= 0 {
+		return nil, errors.New("the benchmark size must be positive")
+	}
+	if len(opts.PartSizes) == 0 {
+		opts.PartSizes = DefaultBenchPartSizes
+	}
+	if len(opts.Concurrencies) == 0 {
+		opts.Concurrencies = DefaultBenchConcurrencies
+	}
+
+	path, err := benchFile(opts.Size)
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(path)
+
+	settings := []BenchResult{{Concurrency: 1}}
+	for _, partSize := range opts.PartSizes {
+		if partSize >= opts.Size {
+			// uploaded in a single request, like the first setting
+			continue
+		}
+		for _, concurrency := range opts.Concurrencies {
+			settings = append(settings, BenchResult{PartSize: partSize, Concurrency: concurrency})
+		}
+	}
+
+	results := make([]BenchResult, 0, len(settings))
+	for _, setting := range settings {
+		if err := ctx.Err(); err != nil {
+			return results, err
+		}
+		console.Printf("uploading %d bytes %s...\n", opts.Size, setting)
+		result := p.benchUpload(ctx, path, opts.Size, setting)
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+func (p *Publisher) benchUpload(
+	ctx context.Context,
+	path string,
+	size int64,
+	setting BenchResult,
+) BenchResult {
+	file, err := os.Open(path)
+	if err != nil {
+		setting.Err = err
+		return setting
+	}
+	defer file.Close()
+
+	suffix := make([]byte, 8)
+	rand.Read(suffix)
+	key := BenchPrefix + hex.EncodeToString(suffix)
+	start := time.Now()
+	_, err = p.objects.Upload(ctx, key, file, size, UploadOptions{
+		ContentType: "application/octet-stream",
+		PartSize:    setting.PartSize,
+		Concurrency: setting.Concurrency,
+	})
+	setting.Duration = time.Since(start)
+	if err != nil {
+		setting.Err = err
+		return setting
+	}
+	setting.Throughput = float64(size) / setting.Duration.Seconds()
+
+	// removed even when the benchmark is interrupted
+	cleanup, cancel := context.WithTimeout(context.WithoutCancel(ctx), time.Minute)
+	defer cancel()
+	if err := p.objects.Delete(cleanup, key); err != nil {
+		setting.Err = fmt.Errorf("couldn't delete %s: %v", key, err)
+	}
+	return setting
+}
+
+// String describes the upload setting.
+func (r BenchResult) String() string {
+	if r.PartSize == 0 {
+		return "in a single request"
+	}
+	return fmt.Sprintf("in parts of %d bytes, %d at once", r.PartSize, r.Concurrency)
+}
+
+// BestBenchResult returns the setting with the highest throughput. Settings within 5% of it
+// are as good, of which the one with the fewest requests in flight is preferred.
+func BestBenchResult(results []BenchResult) (BenchResult, bool) {
+	var best BenchResult
+	for _, result := range results {
+		if result.Err == nil && result.Throughput > best.Throughput {
+			best = result
+		}
+	}
+	if best.Throughput == 0 {
+		return best, false
+	}
+
+	candidates := slices.DeleteFunc(slices.Clone(results), func(r BenchResult) bool {
+		return r.Err != nil || r.Throughput < best.Throughput*0.95
+	})
+	slices.SortStableFunc(candidates, func(a, b BenchResult) int {
+		if a.Concurrency != b.Concurrency {
+			return cmp.Compare(a.Concurrency, b.Concurrency)
+		}
+		// larger parts make for fewer requests
+		return cmp.Compare(b.requestSize(), a.requestSize())
+	})
+	return candidates[0], true
+}
+
+// requestSize is how many bytes are uploaded in each request.
+func (r BenchResult) requestSize() int64 {
+	if r.PartSize == 0 {
+		return math.MaxInt64
+	}
+	return r.PartSize
+}
+
+// benchFile writes a file of random bytes of the size to upload, so neither compression nor
+// deduplication along the way flatters the throughput.
+func benchFile(size int64) (string, error) {
+	f, err := os.CreateTemp("", "registry-bench-*")
+	if err != nil {
+		return "", fmt.Errorf("couldn't create benchmark file: %w", err)
+	}
+	defer f.Close()
+
+	var seed [32]byte
+	rand.Read(seed[:])
+	if _, err := io.CopyN(f, mrand.NewChaCha8(seed), size); err != nil {
+		os.Remove(f.Name())
+		return "", fmt.Errorf("couldn't write benchmark file: %w", err)
+	}
+	return f.Name(), nil
+}
+
+// ParseSize parses a size in bytes, with an optional unit: B, KB, MB, GB or TB (powers of
+// 1000), or KiB, MiB, GiB or TiB (powers of 1024), e.g. 500MB or 64MiB.
+func ParseSize(s string) (int64, error) {
+	units := []struct {
+		suffix     string
+		multiplier int64
+	}{
+		{"KIB", 1 << 10}, {"MIB", 1 << 20}, {"GIB", 1 << 30}, {"TIB", 1 << 40},
+		{"KB", 1e3}, {"MB", 1e6}, {"GB", 1e9}, {"TB", 1e12},
+		{"B", 1},
+	}
+
+	number := strings.ToUpper(strings.TrimSpace(s))
+	multiplier := int64(1)
+	for _, unit := range units {
+		if trimmed, ok := strings.CutSuffix(number, unit.suffix); ok {
+			number, multiplier = strings.TrimSpace(trimmed), unit.multiplier
+			break
+		}
+	}
+	n, err := strconv.ParseFloat(number, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return int64(n * float64(multiplier)), nil
+}
@@ -0,0 +1,223 @@
+package pkg
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/omniviewdev/registry-cli/pkg/console"
+	"github.com/omniviewdev/registry-cli/pkg/types"
+)
+
+const (
+	// AccessPublic serves the registry straight from the bucket, readable by anyone
+	AccessPublic = "public"
+
+	// AccessCloudFront keeps the bucket private and grants read access to a CloudFront
+	// distribution through origin access control (OAC)
+	AccessCloudFront = "cloudfront"
+
+	// DefaultNoncurrentVersionDays is how long overwritten index versions are kept for
+	DefaultNoncurrentVersionDays = 30
+)
+
+type BootstrapOpts struct {
+	// Access is how the registry is served, AccessPublic or AccessCloudFront
+	Access string
+
+	// DistributionARN is the CloudFront distribution allowed to read the bucket with
+	// AccessCloudFront
+	DistributionARN string
+
+	// CORSOrigins are the origins allowed to read the registry from a browser. Defaults to
+	// any origin.
+	CORSOrigins []string
+
+	// NoncurrentVersionDays is how many days overwritten objects (previous versions of the
+	// indexes) are kept for before they expire
+	NoncurrentVersionDays int32
+}
+
+func (o *BootstrapOpts) Defaulter() {
+	if o.Access == "" {
+		o.Access = AccessPublic
+	}
+	if len(o.CORSOrigins) == 0 {
+		o.CORSOrigins = []string{"*"}
+	}
+	if o.NoncurrentVersionDays == 0 {
+		o.NoncurrentVersionDays = DefaultNoncurrentVersionDays
+	}
+}
+
+// Bootstrap provisions the bucket for a new registry: it creates the bucket if needed, turns
+// on versioning, configures access, CORS and lifecycle rules, and stores an empty (signed)
+// registry index. It's safe to run against an existing registry, which keeps its index.
+func (i *Indexer) Bootstrap(ctx context.Context, opts BootstrapOpts) error {
+	opts.Defaulter()
+
+	bucket, err := storeFeature[bucketStore](i.objects, "bootstrapping a bucket")
+	if err != nil {
+		return err
+	}
+
+	var policy string
+	switch opts.Access {
+	case AccessPublic:
+		policy = publicReadPolicy(i.bucket)
+	case AccessCloudFront:
+		if opts.DistributionARN == "" {
+			return errors.New("a distribution ARN is required to serve the registry with cloudfront")
+		}
+		policy = cloudFrontPolicy(i.bucket, opts.DistributionARN)
+	default:
+		return fmt.Errorf("unknown access mode %q", opts.Access)
+	}
+
+	steps := []struct {
+		name string
+		run  func(context.Context) error
+	}{
+		{"creating bucket", func(ctx context.Context) error {
+			return i.createBucket(ctx, bucket)
+		}},
+		{"enabling versioning", func(ctx context.Context) error {
+			return i.enableVersioning(ctx, bucket)
+		}},
+		{"configuring access", func(ctx context.Context) error {
+			return i.configureAccess(ctx, bucket, opts.Access == AccessPublic, policy)
+		}},
+		{"configuring CORS", func(ctx context.Context) error {
+			return i.ApplyCORS(ctx, opts.CORSOrigins)
+		}},
+		{"configuring lifecycle rules", func(ctx context.Context) error {
+			return i.configureLifecycle(ctx, bucket, opts.NoncurrentVersionDays)
+		}},
+		{"creating registry index", i.createRegistryIndex},
+	}
+	for _, step := range steps {
+		console.Printf("%s...\n", step.name)
+		if err := step.run(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (i *Indexer) createBucket(ctx context.Context, bucket bucketStore) error {
+	if err := bucket.CreateBucket(ctx); err != nil {
+		return fmt.Errorf("couldn't create bucket %s: %v", i.bucket, err)
+	}
+	return nil
+}
+
+func (i *Indexer) enableVersioning(ctx context.Context, bucket bucketStore) error {
+	if err := bucket.EnableVersioning(ctx); err != nil {
+		return fmt.Errorf("couldn't enable versioning on %s: %v", i.bucket, err)
+	}
+	return nil
+}
+
+// configureAccess sets the bucket policy, making the bucket public when the policy does.
+func (i *Indexer) configureAccess(
+	ctx context.Context,
+	bucket bucketStore,
+	public bool,
+	policy string,
+) error {
+	if err := bucket.SetPolicy(ctx, policy, public); err != nil {
+		return fmt.Errorf("couldn't set the bucket policy on %s: %v", i.bucket, err)
+	}
+	return nil
+}
+
+func (i *Indexer) configureLifecycle(
+	ctx context.Context,
+	bucket bucketStore,
+	noncurrentDays int32,
+) error {
+	err := bucket.SetLifecycle(ctx, []s3types.LifecycleRule{
+		{
+			ID:     aws.String("abort-incomplete-uploads"),
+			Status: s3types.ExpirationStatusEnabled,
+			Filter: &s3types.LifecycleRuleFilter{Prefix: aws.String("")},
+			AbortIncompleteMultipartUpload: &s3types.AbortIncompleteMultipartUpload{
+				DaysAfterInitiation: aws.Int32(7),
+			},
+		},
+		{
+			ID:     aws.String("expire-noncurrent-versions"),
+			Status: s3types.ExpirationStatusEnabled,
+			Filter: &s3types.LifecycleRuleFilter{Prefix: aws.String("")},
+			NoncurrentVersionExpiration: &s3types.NoncurrentVersionExpiration{
+				NoncurrentDays: aws.Int32(noncurrentDays),
+			},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("couldn't configure lifecycle rules on %s: %v", i.bucket, err)
+	}
+	return nil
+}
+
+// createRegistryIndex stores an empty registry index, unless the bucket already has one.
+func (i *Indexer) createRegistryIndex(ctx context.Context) error {
+	_, err := i.objects.Head(ctx, "index.json")
+	if err == nil {
+		console.Println("registry index already exists, leaving it as is")
+		return nil
+	}
+	if !errors.Is(err, ErrObjectNotFound) {
+		return fmt.Errorf("couldn't check for the registry index: %v", err)
+	}
+
+	_, err = i.setRegistryIndex(
+		ctx,
+		types.RegistryIndex{Plugins: []types.RegistryIndexPlugins{}},
+		"bootstrapped registry",
+	)
+	return err
+}
+
+type policyStatement struct {
+	Sid       string         `json:"Sid"`
+	Effect    string         `json:"Effect"`
+	Principal any            `json:"Principal"`
+	Action    string         `json:"Action"`
+	Resource  string         `json:"Resource"`
+	Condition map[string]any `json:"Condition,omitempty"`
+}
+
+func bucketPolicy(statement policyStatement) string {
+	b, _ := json.Marshal(map[string]any{
+		"Version":   "2012-10-17",
+		"Statement": []policyStatement{statement},
+	})
+	return string(b)
+}
+
+func publicReadPolicy(bucket string) string {
+	return bucketPolicy(policyStatement{
+		Sid:       "PublicRead",
+		Effect:    "Allow",
+		Principal: "*",
+		Action:    "s3:GetObject",
+		Resource:  "arn:aws:s3:::" + bucket + "/*",
+	})
+}
+
+func cloudFrontPolicy(bucket, distributionARN string) string {
+	return bucketPolicy(policyStatement{
+		Sid:       "CloudFrontRead",
+		Effect:    "Allow",
+		Principal: map[string]string{"Service": "cloudfront.amazonaws.com"},
+		Action:    "s3:GetObject",
+		Resource:  "arn:aws:s3:::" + bucket + "/*",
+		Condition: map[string]any{
+			"StringEquals": map[string]string{"AWS:SourceArn": distributionARN},
+		},
+	})
+}
@@ -0,0 +1,449 @@
+package pkg
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/omniviewdev/registry-cli/pkg/client"
+	"github.com/omniviewdev/registry-cli/pkg/console"
+	"github.com/omniviewdev/registry-cli/pkg/types"
+)
+
+// BundleManifestName is the name of the manifest entry, always the first entry of a bundle.
+const BundleManifestName = "manifest.json"
+
+// BundleManifest describes the contents of an export bundle.
+type BundleManifest struct {
+	// Created is when the bundle was exported
+	Created time.Time `json:"created"`
+
+	// Source is the registry the bundle was exported from
+	Source string `json:"source"`
+
+	// Plugins holds the index of each exported plugin, limited to the exported versions. The
+	// download URLs are the paths of the artifacts within the bundle (see bundlePath).
+	Plugins []types.PluginIndex `json:"plugins"`
+}
+
+// artifacts returns the checksum of every artifact in the manifest, keyed by path.
+func (m *BundleManifest) artifacts() map[string]string {
+	artifacts := make(map[string]string)
+	for _, plugin := range m.Plugins {
+		for _, version := range plugin.Versions {
+			for _, info := range version.Architectures {
+				artifacts[info.DownloadURL] = info.Checksum
+			}
+		}
+	}
+	return artifacts
+}
+
+// ExportBundle writes a self-contained bundle of the targeted plugins from the source registry
+// to w. The bundle is a tar archive holding a manifest followed by every artifact, each of
+// which is checked against the source's checksums as it is exported.
+func ExportBundle(
+	ctx context.Context,
+	source *client.Client,
+	sourceURL string,
+	targets []MirrorTarget,
+	w io.Writer,
+) (*BundleManifest, error) {
+	plan, err := planBundle(ctx, source, sourceURL, targets)
+	if err != nil {
+		return nil, err
+	}
+	if err := writeBundle(ctx, source, plan, w, nil); err != nil {
+		return nil, err
+	}
+	return plan.Manifest, nil
+}
+
+// ExportBundleFile writes the bundle ExportBundle writes to the file at path, recording each
+// entry in the checkpoint as it's written. When the checkpoint was resumed, the bundle planned
+// by the earlier run is kept, the file is cut back to the last entry that was fully written and
+// the export carries on from there.
+func ExportBundleFile(
+	ctx context.Context,
+	source *client.Client,
+	sourceURL string,
+	targets []MirrorTarget,
+	path string,
+	checkpoint *Checkpoint,
+) (*BundleManifest, error) {
+	var plan bundlePlan
+	if !checkpoint.Done("plan", &plan) {
+		planned, err := planBundle(ctx, source, sourceURL, targets)
+		if err != nil {
+			return nil, err
+		}
+		plan = *planned
+		if err := checkpoint.Complete("plan", plan); err != nil {
+			return nil, err
+		}
+	}
+
+	// the end of the last entry an earlier run wrote
+	var offset int64
+	for _, step := range checkpoint.Steps("exported/") {
+		var end int64
+		if checkpoint.Done(step, &end) {
+			offset = max(offset, end)
+		}
+	}
+
+	flag := os.O_WRONLY | os.O_CREATE
+	if offset == 0 {
+		flag |= os.O_TRUNC
+	}
+	f, err := os.OpenFile(path, flag, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't open bundle: %w", err)
+	}
+	defer f.Close()
+	if offset > 0 {
+		if err := f.Truncate(offset); err != nil {
+			return nil, fmt.Errorf("couldn't resume bundle: %w", err)
+		}
+		if _, err := f.Seek(offset, io.SeekStart); err != nil {
+			return nil, fmt.Errorf("couldn't resume bundle: %w", err)
+		}
+	}
+
+	w := &countingWriter{w: f, n: offset}
+	if err := writeBundle(ctx, source, &plan, w, checkpoint); err != nil {
+		return nil, err
+	}
+	if err := f.Close(); err != nil {
+		return nil, fmt.Errorf("couldn't write bundle: %w", err)
+	}
+	return plan.Manifest, nil
+}
+
+// bundlePlan is what a bundle holds: its manifest, along with the original indexes the
+// artifacts are downloaded from.
+type bundlePlan struct {
+	Manifest  *BundleManifest     `json:"manifest"`
+	Originals []types.PluginIndex `json:"originals"`
+}
+
+// planBundle selects the versions of the targets to export, building the manifest of the
+// bundle.
+func planBundle(
+	ctx context.Context,
+	source *client.Client,
+	sourceURL string,
+	targets []MirrorTarget,
+) (*bundlePlan, error) {
+	manifest := &BundleManifest{
+		Created: time.Now().UTC(),
+		Source:  sourceURL,
+	}
+
+	originals := make([]types.PluginIndex, 0, len(targets))
+	for _, target := range targets {
+		index, err := source.PluginIndex(ctx, target.Plugin)
+		if err != nil {
+			return nil, err
+		}
+		versions, err := target.selectVersions(index)
+		if err != nil {
+			return nil, err
+		}
+
+		// keep the original index to download from, rewriting the bundled one to the
+		// bundle paths
+		originals = append(originals, index)
+		exported := index
+		exported.Versions = make([]types.PluginVersionInformation, 0, len(versions))
+		for _, version := range versions {
+			bundled := version
+			bundled.Architectures = make(
+				map[string]types.PluginArchitectureInformation,
+				len(version.Architectures),
+			)
+			for arch, info := range version.Architectures {
+				release, err := releaseFor(index.ID, version.Version, arch)
+				if err != nil {
+					return nil, err
+				}
+				info.DownloadURL = bundlePath(release)
+				// bundles don't hold the checksum files, they're uploaded again on import
+				info.ChecksumURL = ""
+				bundled.Architectures[arch] = info
+			}
+			exported.Versions = append(exported.Versions, bundled)
+			if version.Version == index.LatestVersion.Version {
+				exported.LatestVersion = bundled
+			}
+		}
+		if len(exported.Versions) > 0 && exported.LatestVersion.Version != index.LatestVersion.Version {
+			exported.LatestVersion = exported.Versions[len(exported.Versions)-1]
+		}
+		exported.RegistryIndexPlugins.LatestVersion = exported.LatestVersion
+
+		manifest.Plugins = append(manifest.Plugins, exported)
+	}
+	return &bundlePlan{Manifest: manifest, Originals: originals}, nil
+}
+
+// writeBundle writes the manifest and every artifact of the plan to w. With a checkpoint, w must
+// be a countingWriter: the end of each entry is recorded once it's written, and the entries an
+// earlier run wrote are skipped.
+func writeBundle(
+	ctx context.Context,
+	source *client.Client,
+	plan *bundlePlan,
+	w io.Writer,
+	checkpoint *Checkpoint,
+) error {
+	tw := tar.NewWriter(w)
+	// entry records an entry as written, once its padding is flushed to w
+	entry := func(name string) error {
+		if checkpoint == nil {
+			return nil
+		}
+		if err := tw.Flush(); err != nil {
+			return fmt.Errorf("couldn't write %s to bundle: %w", name, err)
+		}
+		return checkpoint.Complete("exported/"+name, w.(*countingWriter).n)
+	}
+
+	if !checkpoint.Done("exported/"+BundleManifestName, nil) {
+		b, err := json.MarshalIndent(plan.Manifest, "", "  ")
+		if err != nil {
+			return fmt.Errorf("couldn't encode bundle manifest: %w", err)
+		}
+		err = writeTarEntry(tw, BundleManifestName, int64(len(b)), bytes.NewReader(b))
+		if err != nil {
+			return err
+		}
+		if err := entry(BundleManifestName); err != nil {
+			return err
+		}
+	}
+
+	for idx, index := range plan.Originals {
+		for _, bundled := range plan.Manifest.Plugins[idx].Versions {
+			original := index.Versions[slices.IndexFunc(
+				index.Versions,
+				func(v types.PluginVersionInformation) bool { return v.Version == bundled.Version },
+			)]
+			for _, arch := range sortedArchs(original) {
+				name := bundled.Architectures[arch].DownloadURL
+				if checkpoint.Done("exported/"+name, nil) {
+					continue
+				}
+				console.Printf("exporting %s[%s] %s...\n", index.ID, original.Version, arch)
+				if err := exportArtifact(
+					ctx,
+					source,
+					tw,
+					original.Architectures[arch],
+					name,
+				); err != nil {
+					return err
+				}
+				if err := entry(name); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("couldn't finalize bundle: %w", err)
+	}
+	return nil
+}
+
+// countingWriter counts the bytes written through it, from n.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+func exportArtifact(
+	ctx context.Context,
+	source *client.Client,
+	tw *tar.Writer,
+	info types.PluginArchitectureInformation,
+	name string,
+) error {
+	path, err := downloadArtifact(ctx, source, info)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(path)
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	stat, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	return writeTarEntry(tw, name, stat.Size(), f)
+}
+
+func writeTarEntry(tw *tar.Writer, name string, size int64, r io.Reader) error {
+	header := &tar.Header{
+		Name:    name,
+		Mode:    0644,
+		Size:    size,
+		ModTime: time.Now(),
+	}
+	if err := tw.WriteHeader(header); err != nil {
+		return fmt.Errorf("couldn't write %s to bundle: %w", name, err)
+	}
+	if _, err := io.Copy(tw, r); err != nil {
+		return fmt.Errorf("couldn't write %s to bundle: %w", name, err)
+	}
+	return nil
+}
+
+// ImportBundle reads a bundle written by ExportBundle, verifying every artifact against the
+// manifest checksums before uploading it, and then merges the bundled versions into the
+// registry indexes. Nothing is indexed unless every artifact in the manifest was imported.
+func ImportBundle(
+	ctx context.Context,
+	r io.Reader,
+	publisher *Publisher,
+	indexer *Indexer,
+) (*BundleManifest, error) {
+	tr := tar.NewReader(r)
+
+	header, err := tr.Next()
+	if err != nil {
+		return nil, fmt.Errorf("couldn't read bundle: %w", err)
+	}
+	if header.Name != BundleManifestName {
+		return nil, fmt.Errorf("invalid bundle: expected %s first, got %s", BundleManifestName, header.Name)
+	}
+
+	var manifest BundleManifest
+	if err := json.NewDecoder(tr).Decode(&manifest); err != nil {
+		return nil, fmt.Errorf("invalid bundle manifest: %w", err)
+	}
+
+	expected := manifest.artifacts()
+	imported := make(map[string]string, len(expected))
+
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("couldn't read bundle: %w", err)
+		}
+
+		checksum, ok := expected[header.Name]
+		if !ok {
+			return nil, fmt.Errorf("invalid bundle: unexpected entry %s", header.Name)
+		}
+		key, err := importArtifact(ctx, publisher, tr, header.Name, checksum)
+		if err != nil {
+			return nil, err
+		}
+		imported[header.Name] = key
+	}
+
+	for path := range expected {
+		if _, ok := imported[path]; !ok {
+			return nil, fmt.Errorf("invalid bundle: artifact %s is missing", path)
+		}
+	}
+
+	for _, plugin := range manifest.Plugins {
+		// point the versions at where the artifacts were uploaded in this registry's layout
+		versions := make([]types.PluginVersionInformation, 0, len(plugin.Versions))
+		for _, version := range plugin.Versions {
+			archs := make(map[string]types.PluginArchitectureInformation, len(version.Architectures))
+			for arch, info := range version.Architectures {
+				info.DownloadURL = imported[info.DownloadURL]
+				info.ChecksumURL = info.DownloadURL + types.ChecksumExt
+				archs[arch] = info
+			}
+			version.Architectures = archs
+			versions = append(versions, version)
+		}
+		if err := indexer.ImportVersions(ctx, plugin, versions); err != nil {
+			return nil, err
+		}
+	}
+	return &manifest, nil
+}
+
+func importArtifact(
+	ctx context.Context,
+	publisher *Publisher,
+	r io.Reader,
+	name, checksum string,
+) (string, error) {
+	release, err := releaseFromBundlePath(name)
+	if err != nil {
+		return "", err
+	}
+
+	f, err := os.CreateTemp("", "registry-import-*.tar.gz")
+	if err != nil {
+		return "", fmt.Errorf("couldn't create temporary file: %w", err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(f, h), r); err != nil {
+		return "", fmt.Errorf("couldn't extract %s: %w", name, err)
+	}
+	if sum := hex.EncodeToString(h.Sum(nil)); !strings.EqualFold(sum, checksum) {
+		return "", fmt.Errorf("checksum mismatch for %s: expected %s, got %s", name, checksum, sum)
+	}
+
+	release.Path = f.Name()
+	release.Created = time.Now()
+	return publisher.Upload(ctx, release)
+}
+
+// bundlePath returns the path of a release's artifact within a bundle. It doesn't follow the
+// registry layout, so bundles can be imported into registries with other layouts.
+func bundlePath(release types.Release) string {
+	return fmt.Sprintf("%s/%s/%s-%s.tar.gz", release.Plugin, release.Version, release.OS, release.Arch)
+}
+
+// releaseFromBundlePath parses a release from its path within a bundle (see bundlePath).
+func releaseFromBundlePath(path string) (types.Release, error) {
+	parts := strings.Split(path, "/")
+	if len(parts) != 3 {
+		return types.Release{}, fmt.Errorf("unexpected artifact path %s", path)
+	}
+	osArch, ok := strings.CutSuffix(parts[2], ".tar.gz")
+	if !ok {
+		return types.Release{}, fmt.Errorf("unexpected artifact path %s", path)
+	}
+	osName, arch, ok := strings.Cut(osArch, "-")
+	if !ok {
+		return types.Release{}, fmt.Errorf("unexpected artifact path %s", path)
+	}
+	return types.Release{Plugin: parts[0], Version: parts[1], OS: osName, Arch: arch}, nil
+}
@@ -0,0 +1,199 @@
+package pkg
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Checkpoint records the progress of a bulk operation (e.g. mirror, gc or export) in a state
+// file as each step completes, so a run that failed part way can be resumed where it stopped
+// instead of starting over. A nil checkpoint records nothing.
+type Checkpoint struct {
+	path string
+
+	mu    sync.Mutex
+	state checkpointState
+}
+
+type checkpointState struct {
+	// Operation is the operation the progress is of
+	Operation string `json:"operation"`
+
+	// Run identifies the run of the operation (its bucket, targets, ...), so the progress of
+	// another run is never resumed
+	Run string `json:"run"`
+
+	Started time.Time `json:"started"`
+	Updated time.Time `json:"updated"`
+
+	// Steps holds the completed steps, along with what the operation needs to know of them to
+	// resume
+	Steps map[string]json.RawMessage `json:"steps"`
+}
+
+// CheckpointPath returns the default path of the state file of a run of an operation, in the
+// user's cache directory. The run is any set of strings identifying it, e.g. its bucket and
+// targets.
+func CheckpointPath(operation string, run ...string) string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	sum := sha256.Sum256([]byte(strings.Join(run, "\n")))
+	name := fmt.Sprintf("%s-%s.json", operation, hex.EncodeToString(sum[:6]))
+	return filepath.Join(dir, "registry-cli", "checkpoints", name)
+}
+
+// OpenCheckpoint opens the state file of a run of an operation at path. When resuming, the
+// progress recorded by an earlier run is loaded, otherwise the run starts over. Resuming
+// without a state file starts over too.
+func OpenCheckpoint(path, operation string, run []string, resume bool) (*Checkpoint, error) {
+	c := &Checkpoint{
+		path: path,
+		state: checkpointState{
+			Operation: operation,
+			Run:       strings.Join(run, "\n"),
+			Started:   time.Now().UTC(),
+			Steps:     make(map[string]json.RawMessage),
+		},
+	}
+	if !resume {
+		return c, nil
+	}
+
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("couldn't read state file: %w", err)
+	}
+	var state checkpointState
+	if err := json.Unmarshal(b, &state); err != nil {
+		return nil, fmt.Errorf("invalid state file %s: %w", path, err)
+	}
+	if state.Operation != c.state.Operation || state.Run != c.state.Run {
+		return nil, fmt.Errorf(
+			"state file %s is of another %s run, can't resume from it",
+			path,
+			state.Operation,
+		)
+	}
+	if state.Steps == nil {
+		state.Steps = make(map[string]json.RawMessage)
+	}
+	c.state = state
+	return c, nil
+}
+
+// Path returns the path of the state file.
+func (c *Checkpoint) Path() string {
+	if c == nil {
+		return ""
+	}
+	return c.path
+}
+
+// Resumed returns how many steps were completed before, by earlier runs.
+func (c *Checkpoint) Resumed() int {
+	if c == nil {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.state.Steps)
+}
+
+// Done reports whether the step was completed, decoding what was recorded of it into v when
+// it was (v may be nil).
+func (c *Checkpoint) Done(step string, v any) bool {
+	if c == nil {
+		return false
+	}
+	c.mu.Lock()
+	raw, ok := c.state.Steps[step]
+	c.mu.Unlock()
+	if !ok {
+		return false
+	}
+	if v != nil && json.Unmarshal(raw, v) != nil {
+		// redo the step rather than resume from what can't be read
+		return false
+	}
+	return true
+}
+
+// Steps returns the completed steps starting with the prefix, sorted.
+func (c *Checkpoint) Steps(prefix string) []string {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var steps []string
+	for step := range c.state.Steps {
+		if strings.HasPrefix(step, prefix) {
+			steps = append(steps, step)
+		}
+	}
+	slices.Sort(steps)
+	return steps
+}
+
+// Complete records the step as completed, along with v, saving the state file.
+func (c *Checkpoint) Complete(step string, v any) error {
+	if c == nil {
+		return nil
+	}
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("couldn't record %s in the state file: %w", step, err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.state.Steps[step] = raw
+	c.state.Updated = time.Now().UTC()
+	return c.save()
+}
+
+// Finish removes the state file, once the run has completed.
+func (c *Checkpoint) Finish() error {
+	if c == nil {
+		return nil
+	}
+	if err := os.Remove(c.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("couldn't remove state file: %w", err)
+	}
+	return nil
+}
+
+// save writes the state file, through a temporary file so a crash never leaves it half
+// written.
+func (c *Checkpoint) save() error {
+	b, err := json.Marshal(c.state)
+	if err != nil {
+		return fmt.Errorf("couldn't encode state file: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0o755); err != nil {
+		return fmt.Errorf("couldn't create state file directory: %w", err)
+	}
+	tmp := c.path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o644); err != nil {
+		return fmt.Errorf("couldn't write state file: %w", err)
+	}
+	if err := os.Rename(tmp, c.path); err != nil {
+		return fmt.Errorf("couldn't write state file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,324 @@
+// Package client reads plugin registries over HTTP, verifying index signatures against a set of
+// trusted keys.
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/omniviewdev/registry-cli/pkg/signing"
+	"github.com/omniviewdev/registry-cli/pkg/types"
+)
+
+// OfficialRegistryURL is the URL of the official Omniview plugin registry.
+const OfficialRegistryURL = "https://registry.omniview.dev"
+
+// maxRedirects is how many transfers of a plugin are followed to its index
+const maxRedirects = 5
+
+// ErrNotFound is returned when the requested file doesn't exist in the registry.
+var ErrNotFound = errors.New("not found in registry")
+
+// Client reads from a plugin registry served over HTTP (a public bucket, bucket website or CDN).
+type Client struct {
+	baseURL     *url.URL
+	http        *http.Client
+	token       string
+	trustedKeys []signing.PublicKey
+
+	// trusted are the trusted keys of the registry, fetched when keysFetched, retiring when
+	// the earliest retirement of each accepted says, as kept in timestamps
+	keysMu      sync.Mutex
+	trusted     types.TrustedKeys
+	keysFetched time.Time
+
+	// timestamps are the signing times of the signed files last accepted and the retirements
+	// of the keys accepted
+	timestamps *timestamps
+
+	// layout is the object key layout of the registry, once fetched
+	layoutMu sync.Mutex
+	layout   *types.Layout
+}
+
+type ClientOpts struct {
+	// BaseURL is the URL the root of the registry is served from
+	BaseURL string
+
+	// HTTPClient is the client to make requests with. Defaults to http.DefaultClient.
+	HTTPClient *http.Client
+
+	// Token is the bearer token the requests to the registry are authorized with, for proxies
+	// serving private plugins (see ProxyOpts.Tokens)
+	Token string
+
+	// TrustedKeys are the keys indexes must be signed with, along with the keys the registry
+	// rotated to from them until they retire. When empty, signatures are not checked.
+	TrustedKeys []signing.PublicKey
+
+	// TimestampsFile is the file remembering when the signed files last accepted were signed
+	// and the retirements of the keys accepted, so older copies replayed later are refused and
+	// retired keys stay retired across runs (see DefaultTimestampsFile). They are only
+	// remembered for the life of the client when empty.
+	TimestampsFile string
+}
+
+// New creates a new registry client
+func New(opts ClientOpts) (*Client, error) {
+	if opts.BaseURL == "" {
+		return nil, errors.New("a registry URL is required")
+	}
+	base, err := url.Parse(strings.TrimSuffix(opts.BaseURL, "/") + "/")
+	if err != nil {
+		return nil, fmt.Errorf("invalid registry URL %q: %w", opts.BaseURL, err)
+	}
+	if opts.HTTPClient == nil {
+		opts.HTTPClient = http.DefaultClient
+	}
+
+	return &Client{
+		baseURL:     base,
+		http:        opts.HTTPClient,
+		token:       opts.Token,
+		trustedKeys: opts.TrustedKeys,
+		timestamps:  &timestamps{path: opts.TimestampsFile},
+	}, nil
+}
+
+// URL resolves a path (or download URL) in the registry to an absolute URL.
+func (c *Client) URL(path string) string {
+	ref, err := url.Parse(path)
+	if err != nil {
+		return c.baseURL.String() + strings.TrimPrefix(path, "/")
+	}
+	if ref.IsAbs() {
+		return ref.String()
+	}
+	return c.baseURL.ResolveReference(&url.URL{Path: strings.TrimPrefix(ref.Path, "/")}).String()
+}
+
+// Open opens a file in the registry for reading. The caller must close the body.
+func (c *Client) Open(ctx context.Context, path string) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.URL(path), nil)
+	if err != nil {
+		return nil, err
+	}
+	c.authorize(req)
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't fetch %s: %w", path, err)
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusOK:
+		return resp.Body, nil
+	case resp.StatusCode == http.StatusNotFound ||
+		// S3 returns forbidden for missing keys when listing isn't allowed
+		resp.StatusCode == http.StatusForbidden:
+		resp.Body.Close()
+		return nil, fmt.Errorf("%s: %w", path, ErrNotFound)
+	default:
+		resp.Body.Close()
+		return nil, fmt.Errorf("couldn't fetch %s: unexpected status %s", path, resp.Status)
+	}
+}
+
+// authorize authorizes a request to the registry with the token of the client. Requests to
+// other hosts, e.g. builds served from a CDN, are left as they are so the token doesn't leak.
+func (c *Client) authorize(req *http.Request) {
+	if c.token != "" && req.URL.Host == c.baseURL.Host {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+}
+
+// Fetch reads a file from the registry.
+func (c *Client) Fetch(ctx context.Context, path string) ([]byte, error) {
+	body, err := c.Open(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	b, err := io.ReadAll(body)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't read %s: %w", path, err)
+	}
+	return b, nil
+}
+
+// FetchVerified reads a file from the registry, verifying its signature against the trusted
+// keys when the client has any. The signature must be for the file at path, and no older than
+// the one of the copy of the file last accepted.
+func (c *Client) FetchVerified(ctx context.Context, path string) ([]byte, error) {
+	b, err := c.Fetch(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	if len(c.trustedKeys) == 0 {
+		return b, nil
+	}
+
+	sig, err := c.Fetch(ctx, path+signing.SignatureExt)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return nil, err
+	}
+	verified, err := c.verify(ctx, path, b, sig)
+	if err == nil {
+		err = c.timestamps.accept(path, verified.Timestamp)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("refusing to use %s: %w", path, err)
+	}
+	return b, nil
+}
+
+// RegistryIndex fetches the registry index.
+func (c *Client) RegistryIndex(ctx context.Context) (types.RegistryIndex, error) {
+	var index types.RegistryIndex
+	if err := c.fetchIndex(ctx, "index.json", &index); err != nil {
+		return types.RegistryIndex{}, err
+	}
+	return index, nil
+}
+
+// Layout fetches the object key layout of the registry, which registries not using the default
+// one publish at types.LayoutPath. It's only fetched once for the life of the client.
+func (c *Client) Layout(ctx context.Context) (types.Layout, error) {
+	c.layoutMu.Lock()
+	defer c.layoutMu.Unlock()
+	if c.layout != nil {
+		return *c.layout, nil
+	}
+
+	var layout types.Layout
+	b, err := c.FetchVerified(ctx, types.LayoutPath)
+	switch {
+	case errors.Is(err, ErrNotFound):
+	case err != nil:
+		return types.Layout{}, fmt.Errorf("couldn't read the layout of the registry: %w", err)
+	default:
+		if err := json.Unmarshal(b, &layout); err != nil {
+			return types.Layout{}, fmt.Errorf("couldn't decode %s: %w", types.LayoutPath, err)
+		}
+		if layout, err = types.ParseLayout(layout); err != nil {
+			return types.Layout{}, fmt.Errorf("invalid layout in %s: %w", types.LayoutPath, err)
+		}
+	}
+	c.layout = &layout
+	return layout, nil
+}
+
+// PluginIndex fetches the index for a plugin. The tombstone left at the old ID of a transferred
+// plugin is followed to the index of its new ID, which the returned index has. A plugin removed
+// from the registry isn't found.
+func (c *Client) PluginIndex(ctx context.Context, plugin string) (types.PluginIndex, error) {
+	layout, err := c.Layout(ctx)
+	if err != nil {
+		return types.PluginIndex{}, err
+	}
+	for redirects := 0; ; redirects++ {
+		index := types.PluginIndex{}
+		index.ID = plugin
+		if err := c.fetchIndex(ctx, layout.IndexPath(plugin), &index); err != nil {
+			return types.PluginIndex{}, err
+		}
+		switch {
+		case index.Tombstone == nil:
+			return index, nil
+		case index.Tombstone.Successor == "":
+			return types.PluginIndex{}, fmt.Errorf(
+				"%s was removed (%s): %w",
+				plugin,
+				index.Tombstone.Reason,
+				ErrNotFound,
+			)
+		case redirects == maxRedirects:
+			return types.PluginIndex{}, fmt.Errorf("%s was transferred too many times", plugin)
+		}
+		plugin = index.Tombstone.Successor
+	}
+}
+
+// LatestVersion fetches the latest version pointer for a plugin. It is much smaller than the
+// plugin index, so prefer it when only checking for updates.
+func (c *Client) LatestVersion(ctx context.Context, plugin string) (types.LatestVersion, error) {
+	var latest types.LatestVersion
+	if err := c.fetchJSON(ctx, types.LatestVersionPath(plugin), &latest); err != nil {
+		return types.LatestVersion{}, err
+	}
+	return latest, nil
+}
+
+// Channel fetches the pointer to the version a release channel (types.ChannelStable or
+// types.ChannelBeta) of a plugin resolves to.
+func (c *Client) Channel(ctx context.Context, plugin, channel string) (types.LatestVersion, error) {
+	var pointer types.LatestVersion
+	if err := c.fetchJSON(ctx, types.ChannelPath(plugin, channel), &pointer); err != nil {
+		return types.LatestVersion{}, err
+	}
+	return pointer, nil
+}
+
+func (c *Client) fetchJSON(ctx context.Context, path string, v any) error {
+	b, err := c.FetchVerified(ctx, path)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(b, v); err != nil {
+		return fmt.Errorf("couldn't decode %s: %w", path, err)
+	}
+	return nil
+}
+
+// fetchIndex fetches an index like fetchJSON, failing with types.ErrUnsupportedSchema rather
+// than misreading an index written for a newer registry-cli.
+func (c *Client) fetchIndex(ctx context.Context, path string, v any) error {
+	b, err := c.FetchVerified(ctx, path)
+	if err != nil {
+		return err
+	}
+	if err := types.DecodeIndex(b, v); err != nil {
+		return fmt.Errorf("couldn't decode %s: %w", path, err)
+	}
+	return nil
+}
+
+// VerifyArtifact downloads an artifact listed in a (verified) plugin index and checks it
+// against the checksum recorded in the index, and that it would extract safely.
+func (c *Client) VerifyArtifact(
+	ctx context.Context,
+	info types.PluginArchitectureInformation,
+) error {
+	body, err := c.Open(ctx, info.DownloadURL)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	// check the archive as it's hashed
+	pr, pw := io.Pipe()
+	checked := make(chan error, 1)
+	go func() {
+		err := CheckArchive(pr)
+		io.Copy(io.Discard, pr)
+		checked <- err
+	}()
+	err = VerifyChecksum(io.TeeReader(body, pw), info.Checksum)
+	pw.Close()
+	archiveErr := <-checked
+	if err != nil {
+		return fmt.Errorf("%s: %w", info.DownloadURL, err)
+	}
+	if archiveErr != nil {
+		return fmt.Errorf("%s: %w", info.DownloadURL, archiveErr)
+	}
+	return nil
+}
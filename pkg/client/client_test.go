@@ -0,0 +1,195 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/omniviewdev/registry-cli/pkg/signing"
+)
+
+// testRegistry serves the files, by path, as a registry would.
+func testRegistry(t *testing.T, files map[string][]byte) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, ok := files[strings.TrimPrefix(r.URL.Path, "/")]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(b)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// testKey generates a signing key for a test.
+func testKey(t *testing.T) *signing.PrivateKey {
+	t.Helper()
+	key, err := signing.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+// testClient creates a client of the registry trusting the key.
+func testClient(t *testing.T, url string, key *signing.PrivateKey, timestamps string) *Client {
+	t.Helper()
+	opts := ClientOpts{BaseURL: url, TimestampsFile: timestamps}
+	if key != nil {
+		opts.TrustedKeys = []signing.PublicKey{key.Public()}
+	}
+	c, err := New(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestFetchVerified(t *testing.T) {
+	key := testKey(t)
+	registry := []byte(`{"plugins":[]}`)
+	plugin := []byte(`{"id":"demo"}`)
+	files := map[string][]byte{
+		"index.json":          registry,
+		"index.json.sig":      key.Sign(registry, "index.json"),
+		"demo/index.json":     plugin,
+		"demo/index.json.sig": key.Sign(plugin, "demo/index.json"),
+		// the registry index and its signature served in place of the plugin index
+		"other/index.json":     registry,
+		"other/index.json.sig": key.Sign(registry, "index.json"),
+		"unsigned/index.json":  plugin,
+	}
+	server := testRegistry(t, files)
+
+	tests := []struct {
+		path    string
+		key     *signing.PrivateKey
+		wantErr error
+	}{
+		{path: "index.json", key: key},
+		{path: "demo/index.json", key: key},
+		{path: "other/index.json", key: key, wantErr: signing.ErrWrongFile},
+		{path: "unsigned/index.json", key: key, wantErr: signing.ErrNoSignature},
+		{path: "index.json", key: testKey(t), wantErr: signing.ErrUntrustedKey},
+		{path: "unsigned/index.json"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			c := testClient(t, server.URL, tt.key, "")
+			_, err := c.FetchVerified(t.Context(), tt.path)
+			if tt.wantErr == nil && err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFetchVerifiedReplay(t *testing.T) {
+	key := testKey(t)
+	registry := []byte(`{"plugins":[]}`)
+	server := testRegistry(t, map[string][]byte{
+		"index.json":     registry,
+		"index.json.sig": key.Sign(registry, "index.json"),
+	})
+	timestamps := filepath.Join(t.TempDir(), "timestamps.json")
+
+	if _, err := testClient(t, server.URL, key, timestamps).FetchVerified(
+		t.Context(),
+		"index.json",
+	); err != nil {
+		t.Fatal(err)
+	}
+	// the same copy is accepted again by later runs
+	if _, err := testClient(t, server.URL, key, timestamps).FetchVerified(
+		t.Context(),
+		"index.json",
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	// a later run accepted a copy signed after the one served now
+	later := fmt.Sprintf(`{"index.json":%d}`, time.Now().Add(time.Hour).Unix())
+	if err := os.WriteFile(timestamps, []byte(later), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	_, err := testClient(t, server.URL, key, timestamps).FetchVerified(t.Context(), "index.json")
+	if !errors.Is(err, ErrStaleSignature) {
+		t.Fatalf("got %v, want %v", err, ErrStaleSignature)
+	}
+}
+
+func TestTimestampsAccept(t *testing.T) {
+	now := time.Now()
+	var ts timestamps
+	steps := []struct {
+		path   string
+		signed time.Time
+		stale  bool
+	}{
+		{path: "index.json", signed: now},
+		{path: "index.json", signed: now},
+		{path: "index.json", signed: now.Add(time.Minute)},
+		{path: "index.json", signed: now, stale: true},
+		{path: "demo/index.json", signed: now},
+		{path: "index.json", signed: time.Time{}, stale: true},
+	}
+	for _, step := range steps {
+		err := ts.accept(step.path, step.signed)
+		if step.stale != errors.Is(err, ErrStaleSignature) {
+			t.Fatalf("%s signed at %s: got %v, stale %v", step.path, step.signed, err, step.stale)
+		}
+	}
+}
+
+func TestTimestampsRetire(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "timestamps.json")
+	earliest := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	ts := &timestamps{path: path}
+	for _, retires := range []time.Time{earliest.Add(time.Hour), earliest, earliest.Add(time.Hour)} {
+		if err := ts.retire(map[string]time.Time{"key": retires}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// read back by another client
+	retired, err := (&timestamps{path: path}).retirements()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := retired["key"]; !got.Equal(earliest) {
+		t.Fatalf("key retires at %s, want the earliest retirement %s", got, earliest)
+	}
+}
+
+func TestPluginIndexLayout(t *testing.T) {
+	key := testKey(t)
+	layout := []byte(`{"artifact":"","index":"indexes/{{.Plugin}}.json"}`)
+	plugin := []byte(`{"id":"demo","versions":[]}`)
+	server := testRegistry(t, map[string][]byte{
+		"layout.json":           layout,
+		"layout.json.sig":       key.Sign(layout, "layout.json"),
+		"indexes/demo.json":     plugin,
+		"indexes/demo.json.sig": key.Sign(plugin, "indexes/demo.json"),
+		"demo/index.json":       []byte(`{"id":"stale"}`),
+		"demo/index.json.sig":   key.Sign([]byte(`{"id":"stale"}`), "demo/index.json"),
+	})
+
+	index, err := testClient(t, server.URL, key, "").PluginIndex(t.Context(), "demo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if index.ID != "demo" {
+		t.Fatalf("read the index of %q, want the one under the published layout", index.ID)
+	}
+}
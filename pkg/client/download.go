@@ -0,0 +1,210 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/omniviewdev/registry-cli/pkg/encryption"
+	"github.com/omniviewdev/registry-cli/pkg/signing"
+	"github.com/omniviewdev/registry-cli/pkg/types"
+)
+
+// DefaultDownloadRetries is how many times an interrupted download is resumed before giving up.
+const DefaultDownloadRetries = 5
+
+// PartialExt is appended to the path of a download while it's in progress.
+const PartialExt = ".part"
+
+type DownloadOpts struct {
+	// Retries is how many times an interrupted download is resumed. Defaults to
+	// DefaultDownloadRetries.
+	Retries int
+
+	// OnRetry is called before an interrupted download is resumed
+	OnRetry func(attempt int, offset int64, err error)
+
+	// Decrypter decrypts the encrypted builds of private registries once they're verified.
+	// Encrypted builds are downloaded as they are without it.
+	Decrypter *encryption.Decrypter
+}
+
+// Download downloads an artifact listed in a (verified) plugin index to dest, checking it against
+// the checksum recorded in the index. The download is written next to dest until it's verified,
+// and resumed with ranged requests when it's interrupted, including by an earlier run. Encrypted
+// artifacts are decrypted into dest once verified when the options have a decrypter.
+func (c *Client) Download(
+	ctx context.Context,
+	info types.PluginArchitectureInformation,
+	dest string,
+	opts DownloadOpts,
+) error {
+	if opts.Retries <= 0 {
+		opts.Retries = DefaultDownloadRetries
+	}
+	partial := dest + PartialExt
+
+	for attempt := 0; ; attempt++ {
+		offset, err := c.downloadRange(ctx, info, partial)
+		if err == nil {
+			break
+		}
+		if errors.Is(err, ErrNotFound) || ctx.Err() != nil || attempt >= opts.Retries {
+			return err
+		}
+		if opts.OnRetry != nil {
+			opts.OnRetry(attempt+1, offset, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Duration(attempt+1) * time.Second):
+		}
+	}
+
+	if err := c.verifyDownload(ctx, info, partial); err != nil {
+		// a corrupt partial download can't be resumed
+		os.Remove(partial)
+		return fmt.Errorf("%s: %w", info.DownloadURL, err)
+	}
+	if info.Encryption == "" || opts.Decrypter == nil {
+		return os.Rename(partial, dest)
+	}
+	if info.Encryption != encryption.Format {
+		return fmt.Errorf("%s: unsupported encryption %q", info.DownloadURL, info.Encryption)
+	}
+	if err := opts.Decrypter.DecryptFile(partial, dest); err != nil {
+		return err
+	}
+	return os.Remove(partial)
+}
+
+// verifyDownload checks a downloaded artifact against the checksum in the index, and against
+// its signature when the client has trusted keys and the artifact is signed, which must be for
+// the key of the artifact in the registry.
+func (c *Client) verifyDownload(
+	ctx context.Context,
+	info types.PluginArchitectureInformation,
+	path string,
+) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	err = VerifyChecksum(file, info.Checksum)
+	file.Close()
+	if err != nil {
+		return err
+	}
+	if len(c.trustedKeys) == 0 {
+		return nil
+	}
+
+	sig, err := c.Fetch(ctx, info.DownloadURL+signing.SignatureExt)
+	if errors.Is(err, ErrNotFound) {
+		// the checksum is covered by the index signature
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	name, _ := artifactKey(c, info.DownloadURL)
+	if _, err := c.verify(ctx, name, data, sig); err != nil {
+		return fmt.Errorf("invalid signature: %w", err)
+	}
+	return nil
+}
+
+// downloadRange downloads the rest of an artifact onto the partial download, returning how much
+// of it has been downloaded.
+func (c *Client) downloadRange(
+	ctx context.Context,
+	info types.PluginArchitectureInformation,
+	partial string,
+) (int64, error) {
+	var offset int64
+	if stat, err := os.Stat(partial); err == nil {
+		offset = stat.Size()
+	}
+	if info.Size > 0 && offset == info.Size {
+		return offset, nil
+	}
+	if info.Size > 0 && offset > info.Size {
+		offset = 0
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.URL(info.DownloadURL), nil)
+	if err != nil {
+		return offset, err
+	}
+	if offset > 0 {
+		req.Header.Set("Range", "bytes="+strconv.FormatInt(offset, 10)+"-")
+	}
+	c.authorize(req)
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return offset, fmt.Errorf("couldn't fetch %s: %w", info.DownloadURL, err)
+	}
+	defer resp.Body.Close()
+
+	flags := os.O_CREATE | os.O_WRONLY
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+		flags |= os.O_APPEND
+	case http.StatusOK:
+		// the server ignored the range, start over
+		offset = 0
+		flags |= os.O_TRUNC
+	case http.StatusRequestedRangeNotSatisfiable:
+		// the partial download is already complete, or doesn't match the artifact anymore, which
+		// the checksum catches
+		return offset, nil
+	case http.StatusNotFound, http.StatusForbidden:
+		return offset, fmt.Errorf("%s: %w", info.DownloadURL, ErrNotFound)
+	default:
+		return offset, fmt.Errorf(
+			"couldn't fetch %s: unexpected status %s",
+			info.DownloadURL,
+			resp.Status,
+		)
+	}
+
+	file, err := os.OpenFile(partial, flags, 0o644)
+	if err != nil {
+		return offset, fmt.Errorf("couldn't write %s: %w", partial, err)
+	}
+	defer file.Close()
+
+	n, err := io.Copy(file, resp.Body)
+	offset += n
+	if err != nil {
+		return offset, fmt.Errorf("download of %s interrupted: %w", info.DownloadURL, err)
+	}
+	return offset, nil
+}
+
+// artifactKey returns the key of an artifact in the registry from its download URL.
+func artifactKey(upstream *Client, downloadURL string) (string, bool) {
+	resolved := upstream.URL(downloadURL)
+	if artifact, ok := strings.CutPrefix(resolved, upstream.URL("")); ok {
+		return artifact, true
+	}
+	// served from elsewhere, e.g. a CDN in front of the bucket with the same keys
+	u, err := url.Parse(resolved)
+	if err != nil {
+		return "", false
+	}
+	return strings.TrimPrefix(u.Path, "/"), true
+}
@@ -0,0 +1,106 @@
+package client
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/omniviewdev/registry-cli/pkg/signing"
+	"github.com/omniviewdev/registry-cli/pkg/types"
+)
+
+func checksum(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+func TestVerifyChecksum(t *testing.T) {
+	artifact := "plugin build"
+	var mismatch *ChecksumError
+	if err := VerifyChecksum(strings.NewReader(artifact), checksum([]byte(artifact))); err != nil {
+		t.Fatal(err)
+	}
+	upper := strings.ToUpper(checksum([]byte(artifact)))
+	if err := VerifyChecksum(strings.NewReader(artifact), upper); err != nil {
+		t.Fatal(err)
+	}
+	err := VerifyChecksum(strings.NewReader("tampered"), checksum([]byte(artifact)))
+	if !errors.As(err, &mismatch) || mismatch.Actual != checksum([]byte("tampered")) {
+		t.Fatalf("got %v, want a checksum mismatch", err)
+	}
+	if err := VerifyChecksum(strings.NewReader(artifact), ""); err == nil {
+		t.Fatal("expected an error without a checksum")
+	}
+}
+
+func TestDownload(t *testing.T) {
+	key := testKey(t)
+	artifact := []byte("plugin build")
+	const path = "demo/1.0.0/linux-amd64.tar.gz"
+	server := testRegistry(t, map[string][]byte{
+		path:                      artifact,
+		"signed/" + path:          artifact,
+		"signed/" + path + ".sig": key.Sign(artifact, "signed/"+path),
+		// the signature of another build
+		"other/" + path:          artifact,
+		"other/" + path + ".sig": key.Sign(artifact, path),
+	})
+
+	tests := []struct {
+		name     string
+		path     string
+		checksum string
+		key      *signing.PrivateKey
+		wantErr  bool
+	}{
+		{name: "match", path: path, checksum: checksum(artifact)},
+		{name: "mismatch", path: path, checksum: checksum([]byte("other")), wantErr: true},
+		{name: "missing checksum", path: path, wantErr: true},
+		{name: "missing artifact", path: "missing.tar.gz", checksum: checksum(artifact),
+			wantErr: true},
+		// the checksum is covered by the signature of the index
+		{name: "unsigned with trusted keys", path: path, checksum: checksum(artifact), key: key},
+		{name: "signed", path: "signed/" + path, checksum: checksum(artifact), key: key},
+		{name: "signed by another key", path: "signed/" + path, checksum: checksum(artifact),
+			key: testKey(t), wantErr: true},
+		{name: "signature of another file", path: "other/" + path, checksum: checksum(artifact),
+			key: key, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := testClient(t, server.URL, tt.key, "")
+			dest := filepath.Join(t.TempDir(), "plugin.tar.gz")
+			err := c.Download(t.Context(), types.PluginArchitectureInformation{
+				DownloadURL: tt.path,
+				Checksum:    tt.checksum,
+			}, dest, DownloadOpts{Retries: 1})
+
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected the download to be refused")
+				}
+				if _, err := os.Stat(dest); err == nil {
+					t.Fatal("a refused download was kept")
+				}
+				if _, err := os.Stat(dest + PartialExt); err == nil {
+					t.Fatal("a refused download was left to resume")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, err := os.ReadFile(dest)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(b) != string(artifact) {
+				t.Fatalf("downloaded %q, want %q", b, artifact)
+			}
+		})
+	}
+}
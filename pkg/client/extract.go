@@ -0,0 +1,204 @@
+package client
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// maxLinkDepth is how many symlinks are followed resolving a path, like the limit of most
+// kernels.
+const maxLinkDepth = 40
+
+// maxExtractedSize is the most a plugin tarball may expand to, so a crafted one can't fill the
+// disk it's extracted onto
+var maxExtractedSize int64 = 4 << 30
+
+// ErrUnsafeArchive is returned for archives with entries that would be written outside of the
+// directory they're extracted into.
+var ErrUnsafeArchive = errors.New("unsafe archive")
+
+// Extract extracts a plugin tarball into an empty dir. Archives with entries that would land
+// outside of dir, through `..`, absolute paths or symlinks, or that expand to more than
+// maxExtractedSize, are refused. Symlinks are only
+// created once every file is written, so nothing is ever written through one.
+func Extract(archive, dir string) error {
+	file, err := os.Open(archive)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	links, err := walkArchive(file, func(header *tar.Header, r io.Reader) error {
+		target := filepath.Join(dir, filepath.FromSlash(header.Name))
+		if header.Typeflag == tar.TypeDir {
+			return os.MkdirAll(target, 0o755)
+		}
+		return extractFile(r, target, header.FileInfo().Mode().Perm())
+	})
+	if err != nil {
+		return fmt.Errorf("couldn't extract %s: %w", archive, err)
+	}
+
+	for _, link := range links {
+		target := filepath.Join(dir, filepath.FromSlash(link.Name))
+		if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+			return fmt.Errorf("couldn't extract %s: %w", archive, err)
+		}
+		os.Remove(target)
+		if err := os.Symlink(link.Linkname, target); err != nil {
+			return fmt.Errorf("couldn't extract %s: %w", archive, err)
+		}
+	}
+	return nil
+}
+
+// CheckArchive reads a plugin tarball to the end, checking every entry would be extracted safely.
+func CheckArchive(r io.Reader) error {
+	_, err := walkArchive(r, func(*tar.Header, io.Reader) error { return nil })
+	return err
+}
+
+// walkArchive calls fn for every directory and regular file of a gzipped tarball, after checking
+// the entry stays within the directory it's extracted into, then returns the symlinks of the
+// tarball once they're checked to resolve within it too. Any other kind of entry is refused.
+func walkArchive(
+	r io.Reader,
+	fn func(header *tar.Header, r io.Reader) error,
+) ([]*tar.Header, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+
+	var links []*tar.Header
+	var entries []string
+	var size int64
+	linkTargets := make(map[string]string)
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if !isLocalPath(header.Name) {
+			return nil, fmt.Errorf(
+				"%w: %s is outside of the plugin",
+				ErrUnsafeArchive,
+				header.Name,
+			)
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir, tar.TypeReg:
+			if size += header.Size; size > maxExtractedSize {
+				return nil, fmt.Errorf(
+					"%w: expands to more than %d bytes",
+					ErrUnsafeArchive,
+					maxExtractedSize,
+				)
+			}
+			entries = append(entries, path.Clean(header.Name))
+			if err := fn(header, tr); err != nil {
+				return nil, fmt.Errorf("%s: %w", header.Name, err)
+			}
+		case tar.TypeSymlink:
+			if path.IsAbs(header.Linkname) || strings.Contains(header.Linkname, `\`) {
+				return nil, fmt.Errorf(
+					"%w: %s links to %s, outside of the plugin",
+					ErrUnsafeArchive,
+					header.Name,
+					header.Linkname,
+				)
+			}
+			links = append(links, header)
+			linkTargets[path.Clean(header.Name)] = header.Linkname
+		default:
+			return nil, fmt.Errorf(
+				"%w: %s is not a regular file, directory or symlink",
+				ErrUnsafeArchive,
+				header.Name,
+			)
+		}
+	}
+
+	for _, link := range links {
+		// resolved as is, a link can't be cleaned before the links it goes through are followed
+		target := path.Dir(link.Name) + "/" + link.Linkname
+		if _, err := resolveLinks(linkTargets, target, 0); err != nil {
+			return nil, fmt.Errorf("%s links to %s: %w", link.Name, link.Linkname, err)
+		}
+	}
+	for _, entry := range entries {
+		if resolved, err := resolveLinks(linkTargets, entry, 0); err != nil || resolved != entry {
+			return nil, fmt.Errorf("%w: %s is written through a symlink", ErrUnsafeArchive, entry)
+		}
+	}
+	return links, nil
+}
+
+// resolveLinks resolves a path within an archive, following the symlinks of the archive, and
+// errors if it leaves the archive on the way.
+func resolveLinks(linkTargets map[string]string, name string, depth int) (string, error) {
+	elems := strings.Split(name, "/")
+	var resolved []string
+	for idx, elem := range elems {
+		switch elem {
+		case "", ".":
+			continue
+		case "..":
+			if len(resolved) == 0 {
+				return "", fmt.Errorf("%w: resolves outside of the plugin", ErrUnsafeArchive)
+			}
+			resolved = resolved[:len(resolved)-1]
+			continue
+		}
+
+		resolved = append(resolved, elem)
+		target, ok := linkTargets[strings.Join(resolved, "/")]
+		if !ok {
+			continue
+		}
+		if depth >= maxLinkDepth {
+			return "", fmt.Errorf("%w: too many levels of symlinks", ErrUnsafeArchive)
+		}
+		parent := strings.Join(resolved[:len(resolved)-1], "/")
+		rest := strings.Join(elems[idx+1:], "/")
+		return resolveLinks(linkTargets, parent+"/"+target+"/"+rest, depth+1)
+	}
+	return strings.Join(resolved, "/"), nil
+}
+
+// isLocalPath reports whether a slash separated path in a tarball is relative and doesn't
+// escape the directory it's relative to, on any platform.
+func isLocalPath(name string) bool {
+	return name != "" &&
+		!strings.Contains(name, `\`) &&
+		!strings.Contains(name, ":") &&
+		filepath.IsLocal(filepath.FromSlash(name))
+}
+
+func extractFile(r io.Reader, path string, perm os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(file, r); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,179 @@
+package client
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// entry is an entry of a crafted tarball. Regular files are written with their body.
+type entry struct {
+	name     string
+	typeflag byte
+	linkname string
+	body     string
+}
+
+func tarFile(name, body string) entry {
+	return entry{name: name, typeflag: tar.TypeReg, body: body}
+}
+
+func tarDir(name string) entry {
+	return entry{name: name, typeflag: tar.TypeDir}
+}
+
+func tarSymlink(name, target string) entry {
+	return entry{name: name, typeflag: tar.TypeSymlink, linkname: target}
+}
+
+// writeArchive writes a gzipped tarball of the entries, returning its path.
+func writeArchive(t *testing.T, entries ...entry) string {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for _, e := range entries {
+		header := &tar.Header{
+			Name:     e.name,
+			Typeflag: e.typeflag,
+			Linkname: e.linkname,
+			Mode:     0o644,
+			Size:     int64(len(e.body)),
+		}
+		if e.typeflag == tar.TypeDir {
+			header.Mode = 0o755
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(e.body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "plugin.tar.gz")
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestExtract(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []entry
+		unsafe  bool
+	}{
+		{name: "plugin", entries: []entry{
+			tarDir("bin"),
+			tarFile("bin/plugin", "#!/bin/sh"),
+			tarFile("plugin.yaml", "id: demo"),
+			tarSymlink("current", "bin/plugin"),
+		}},
+		{name: "dot segments within", entries: []entry{tarFile("a/../plugin.yaml", "id: demo")}},
+		{name: "parent directory", entries: []entry{tarFile("../escape", "x")}, unsafe: true},
+		{name: "nested parent directory", entries: []entry{tarFile("a/../../escape", "x")},
+			unsafe: true},
+		{name: "absolute path", entries: []entry{tarFile("/tmp/escape", "x")}, unsafe: true},
+		{name: "windows path", entries: []entry{tarFile(`..\escape`, "x")}, unsafe: true},
+		{name: "drive letter", entries: []entry{tarFile("C:/escape", "x")}, unsafe: true},
+		{name: "absolute symlink", entries: []entry{tarSymlink("etc", "/etc")}, unsafe: true},
+		{name: "symlink out", entries: []entry{tarSymlink("up", "../..")}, unsafe: true},
+		{name: "symlink out through symlink", entries: []entry{
+			tarDir("a"),
+			tarSymlink("a/up", ".."),
+			tarSymlink("out", "a/up/.."),
+		}, unsafe: true},
+		{name: "file through symlink", entries: []entry{
+			tarSymlink("bin", "."),
+			tarFile("bin/plugin", "x"),
+		}, unsafe: true},
+		{name: "file through symlink out", entries: []entry{
+			tarSymlink("tmp", "../../tmp"),
+			tarFile("tmp/escape", "x"),
+		}, unsafe: true},
+		{name: "symlink loop", entries: []entry{
+			tarSymlink("a", "b"),
+			tarSymlink("b", "a"),
+			tarSymlink("c", "a/x"),
+		}, unsafe: true},
+		{name: "hardlink", entries: []entry{
+			tarFile("plugin.yaml", "x"),
+			{name: "passwd", typeflag: tar.TypeLink, linkname: "/etc/passwd"},
+		}, unsafe: true},
+		{name: "device", entries: []entry{{name: "null", typeflag: tar.TypeChar}}, unsafe: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			archive := writeArchive(t, tt.entries...)
+			root := t.TempDir()
+			dest := filepath.Join(root, "plugin")
+			if err := os.Mkdir(dest, 0o755); err != nil {
+				t.Fatal(err)
+			}
+
+			err := Extract(archive, dest)
+			if tt.unsafe {
+				if !errors.Is(err, ErrUnsafeArchive) {
+					t.Fatalf("got %v, want %v", err, ErrUnsafeArchive)
+				}
+				if _, err := os.Stat(filepath.Join(root, "escape")); err == nil {
+					t.Fatal("wrote outside of the plugin")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, e := range tt.entries {
+				if _, err := os.Lstat(filepath.Join(dest, e.name)); err != nil {
+					t.Fatalf("%s wasn't extracted: %v", e.name, err)
+				}
+			}
+		})
+	}
+}
+
+func TestExtractSizeLimit(t *testing.T) {
+	limit := maxExtractedSize
+	maxExtractedSize = 8
+	t.Cleanup(func() { maxExtractedSize = limit })
+
+	fits := writeArchive(t, tarFile("a", "1234"), tarFile("b", "5678"))
+	if err := Extract(fits, t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	bomb := writeArchive(t, tarFile("a", "1234"), tarFile("b", "56789"))
+	if err := Extract(bomb, t.TempDir()); !errors.Is(err, ErrUnsafeArchive) {
+		t.Fatalf("got %v, want %v", err, ErrUnsafeArchive)
+	}
+}
+
+func TestCheckArchive(t *testing.T) {
+	safe, err := os.Open(writeArchive(t, tarFile("plugin.yaml", "id: demo")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer safe.Close()
+	if err := CheckArchive(safe); err != nil {
+		t.Fatal(err)
+	}
+
+	unsafe, err := os.Open(writeArchive(t, tarFile("../escape", "x")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unsafe.Close()
+	if err := CheckArchive(unsafe); !errors.Is(err, ErrUnsafeArchive) {
+		t.Fatalf("got %v, want %v", err, ErrUnsafeArchive)
+	}
+}
@@ -0,0 +1,188 @@
+package client
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// ErrStaleSignature is returned when a signed file was signed before the copy of it the client
+// last accepted, e.g. an old index replayed by a mirror or an attacker.
+var ErrStaleSignature = errors.New("file was signed before the copy last accepted")
+
+// DefaultTimestampsFile returns the default path of the file remembering when the signed files
+// of the registry at url last accepted were signed, in the user's cache directory.
+func DefaultTimestampsFile(url string) string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	sum := sha256.Sum256([]byte(normalizeURL(url)))
+	name := hex.EncodeToString(sum[:6]) + ".json"
+	return filepath.Join(dir, "registry-cli", "timestamps", name)
+}
+
+// timestamps remembers when the signed files last accepted were signed, so the signing time of
+// a file never goes backwards, and the retirements of the keys accepted, so a key can't come
+// back from retirement. They're kept in a file when the client has one, otherwise for the life
+// of the client.
+type timestamps struct {
+	mu     sync.Mutex
+	path   string
+	loaded bool
+
+	// signed maps the paths of the files to the unix time they were signed at
+	signed map[string]int64
+
+	// retired maps the IDs of keys to the earliest retirement accepted for them
+	retired map[string]time.Time
+}
+
+// timestampsFile is the file timestamps are kept in. Files written before retirements were
+// kept are only the map of signed.
+type timestampsFile struct {
+	Signed  map[string]int64     `json:"signed"`
+	Retired map[string]time.Time `json:"retired,omitempty"`
+}
+
+// accept records the signing time of a file, failing with ErrStaleSignature when it's earlier
+// than the one of the copy last accepted.
+func (t *timestamps) accept(path string, signed time.Time) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if err := t.load(); err != nil {
+		return err
+	}
+
+	last, ok := t.signed[path]
+	switch {
+	case ok && signed.Unix() < last:
+		return fmt.Errorf(
+			"%w: %s was signed at %s, the copy last accepted at %s",
+			ErrStaleSignature,
+			path,
+			signed.UTC().Format(time.RFC3339),
+			time.Unix(last, 0).UTC().Format(time.RFC3339),
+		)
+	case ok && signed.Unix() == last:
+		return nil
+	}
+	t.signed[path] = signed.Unix()
+	return t.save()
+}
+
+// accepted reports whether a copy of the file was accepted before.
+func (t *timestamps) accepted(path string) (bool, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if err := t.load(); err != nil {
+		return false, err
+	}
+	_, ok := t.signed[path]
+	return ok, nil
+}
+
+// retirements returns the retirements of keys accepted, by key ID.
+func (t *timestamps) retirements() (map[string]time.Time, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if err := t.load(); err != nil {
+		return nil, err
+	}
+	return maps.Clone(t.retired), nil
+}
+
+// retire records the retirements of keys, by key ID, keeping the earliest accepted for each.
+func (t *timestamps) retire(retirements map[string]time.Time) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if err := t.load(); err != nil {
+		return err
+	}
+
+	changed := false
+	for id, retires := range retirements {
+		if last, ok := t.retired[id]; !ok || retires.Before(last) {
+			t.retired[id] = retires
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+	return t.save()
+}
+
+func (t *timestamps) load() error {
+	if t.loaded {
+		return nil
+	}
+	t.signed = make(map[string]int64)
+	t.retired = make(map[string]time.Time)
+	if t.path != "" {
+		b, err := os.ReadFile(t.path)
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+		case err != nil:
+			return fmt.Errorf("couldn't read signing times: %w", err)
+		default:
+			if err := t.decode(b); err != nil {
+				return fmt.Errorf("couldn't decode signing times in %s: %w", t.path, err)
+			}
+		}
+	}
+	t.loaded = true
+	return nil
+}
+
+func (t *timestamps) decode(b []byte) error {
+	var file timestampsFile
+	if err := json.Unmarshal(b, &file); err != nil {
+		return err
+	}
+	if file.Signed == nil {
+		// written before retirements were kept
+		return json.Unmarshal(b, &t.signed)
+	}
+	maps.Copy(t.signed, file.Signed)
+	maps.Copy(t.retired, file.Retired)
+	return nil
+}
+
+func (t *timestamps) save() error {
+	if t.path == "" {
+		return nil
+	}
+	b, err := json.Marshal(timestampsFile{Signed: t.signed, Retired: t.retired})
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(t.path), 0o755); err != nil {
+		return fmt.Errorf("couldn't save signing times: %w", err)
+	}
+	// written aside and renamed, so other clients never read half of it
+	tmp, err := os.CreateTemp(filepath.Dir(t.path), filepath.Base(t.path)+".*")
+	if err != nil {
+		return fmt.Errorf("couldn't save signing times: %w", err)
+	}
+	_, err = tmp.Write(b)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), t.path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("couldn't save signing times: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,273 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/omniviewdev/registry-cli/pkg/progress"
+	"github.com/omniviewdev/registry-cli/pkg/types"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultInstallConcurrency is how many plugins are installed at once.
+const DefaultInstallConcurrency = 4
+
+// InstallRequest is a plugin to install, at its latest version when no version is given.
+type InstallRequest struct {
+	Plugin  string `json:"id"                yaml:"id"`
+	Version string `json:"version,omitempty" yaml:"version,omitempty"`
+}
+
+// ParseInstallRequest parses a plugin to install, given as plugin or plugin@version.
+func ParseInstallRequest(s string) InstallRequest {
+	plugin, version, _ := strings.Cut(s, "@")
+	return InstallRequest{Plugin: plugin, Version: version}
+}
+
+func (r InstallRequest) String() string {
+	if r.Version == "" {
+		return r.Plugin
+	}
+	return r.Plugin + "@" + r.Version
+}
+
+// Lockfile pins the plugins (and their versions) to install, in YAML or JSON. Installs record
+// every plugin they resolved, dependencies included, in the order they were installed in:
+//
+//	plugins:
+//	  - id: kubernetes
+//	    version: 0.2.0
+type Lockfile struct {
+	Plugins []InstallRequest `json:"plugins" yaml:"plugins"`
+}
+
+// LoadLockfile reads the lockfile at path.
+func LoadLockfile(path string) (Lockfile, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return Lockfile{}, fmt.Errorf("couldn't read lockfile: %w", err)
+	}
+	var lock Lockfile
+	if err := yaml.Unmarshal(b, &lock); err != nil {
+		return Lockfile{}, fmt.Errorf("invalid lockfile %s: %w", path, err)
+	}
+	for _, plugin := range lock.Plugins {
+		if plugin.Plugin == "" {
+			return Lockfile{}, fmt.Errorf("invalid lockfile %s: plugin without an id", path)
+		}
+	}
+	return lock, nil
+}
+
+// Save writes the lockfile to path, as YAML.
+func (l Lockfile) Save(path string) error {
+	b, err := yaml.Marshal(l)
+	if err != nil {
+		return fmt.Errorf("couldn't encode lockfile: %w", err)
+	}
+	if err := os.WriteFile(path, b, 0o644); err != nil {
+		return fmt.Errorf("couldn't write lockfile: %w", err)
+	}
+	return nil
+}
+
+type InstallOpts struct {
+	// Dir is the directory plugins are installed into, each in a directory named after it
+	Dir string
+
+	// Platform is the platform to install the builds of (e.g. linux_amd64). Architectures named
+	// by other build systems are normalized, e.g. linux_x86_64 installs the linux_amd64 builds.
+	Platform string
+
+	// Concurrency is how many plugins are installed at once. Defaults to
+	// DefaultInstallConcurrency.
+	Concurrency int
+
+	// Download configures the downloads of the builds
+	Download DownloadOpts
+
+	// Tracker reports the progress of each install
+	Tracker progress.Tracker
+}
+
+// InstallResult is the outcome of installing a plugin.
+type InstallResult struct {
+	Plugin string
+
+	// Version is the version installed
+	Version string
+
+	// Path is the directory the plugin was installed into
+	Path string
+
+	Duration time.Duration
+	Err      error
+}
+
+// Install resolves the plugins along with the plugins they depend on, each from the highest
+// priority registry that has it, then downloads, verifies and extracts them concurrently, every
+// plugin only once the plugins it depends on are installed. The result of each install is
+// returned in the order they were installed in, which is the order to record the plugins in a
+// lockfile. A failed install only stops the plugins depending on it.
+func (r Registries) Install(
+	ctx context.Context,
+	requests []InstallRequest,
+	opts InstallOpts,
+) ([]InstallResult, error) {
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = DefaultInstallConcurrency
+	}
+	if opts.Tracker == nil {
+		opts.Tracker = progress.Plain(io.Discard)
+	}
+	opts.Platform = types.NormalizePlatform(opts.Platform)
+
+	levels, resolved, err := r.resolve(ctx, requests)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []InstallResult
+	failed := make(map[string]bool)
+	for _, level := range levels {
+		levelResults := make([]InstallResult, len(level))
+		pool := make(chan struct{}, opts.Concurrency)
+		var wg sync.WaitGroup
+		for idx, request := range level {
+			plugin := resolved[request.Plugin]
+			if dep := slices.IndexFunc(plugin.requires, func(dep string) bool {
+				return failed[dep]
+			}); dep != -1 {
+				levelResults[idx] = InstallResult{
+					Plugin:  request.Plugin,
+					Version: request.Version,
+					Err:     fmt.Errorf("dependency %s failed to install", plugin.requires[dep]),
+				}
+				continue
+			}
+
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				pool <- struct{}{}
+				defer func() { <-pool }()
+
+				task := request.String()
+				start := time.Now()
+				result := plugin.registry.install(ctx, request, plugin.version, task, opts)
+				result.Duration = time.Since(start)
+				if result.Err != nil {
+					opts.Tracker.Fail(task, result.Err)
+				} else {
+					opts.Tracker.Done(task, "installed "+result.Version+" to "+result.Path)
+				}
+				levelResults[idx] = result
+			}()
+		}
+		wg.Wait()
+
+		for _, result := range levelResults {
+			if result.Err != nil {
+				failed[result.Plugin] = true
+			}
+		}
+		results = append(results, levelResults...)
+	}
+	return results, nil
+}
+
+func (c *Client) install(
+	ctx context.Context,
+	request InstallRequest,
+	versionInfo types.PluginVersionInformation,
+	task string,
+	opts InstallOpts,
+) InstallResult {
+	result := InstallResult{Plugin: request.Plugin, Version: versionInfo.Version}
+
+	info, ok := versionInfo.Architecture(opts.Platform)
+	if !ok {
+		result.Err = fmt.Errorf(
+			"version %s of %s has no %s build",
+			versionInfo.Version,
+			request.Plugin,
+			opts.Platform,
+		)
+		return result
+	}
+	if info.Encryption != "" && opts.Download.Decrypter == nil {
+		result.Err = fmt.Errorf(
+			"version %s of %s is encrypted, give the keys to decrypt it with",
+			versionInfo.Version,
+			request.Plugin,
+		)
+		return result
+	}
+
+	// downloads are kept until extracted, so a failed install resumes them
+	downloads := filepath.Join(opts.Dir, ".downloads")
+	if err := os.MkdirAll(downloads, 0o755); err != nil {
+		result.Err = fmt.Errorf("couldn't create %s: %w", downloads, err)
+		return result
+	}
+	archive := filepath.Join(
+		downloads,
+		fmt.Sprintf("%s-%s-%s.tar.gz", request.Plugin, versionInfo.Version, opts.Platform),
+	)
+
+	download := opts.Download
+	download.OnRetry = func(attempt int, offset int64, err error) {
+		opts.Tracker.Start(task, fmt.Sprintf("resuming download (attempt %d)", attempt))
+		if opts.Download.OnRetry != nil {
+			opts.Download.OnRetry(attempt, offset, err)
+		}
+	}
+	opts.Tracker.Start(task, "downloading "+versionInfo.Version)
+	if err := c.Download(ctx, info, archive, download); err != nil {
+		result.Err = err
+		return result
+	}
+
+	opts.Tracker.Start(task, "extracting "+versionInfo.Version)
+	result.Path = filepath.Join(opts.Dir, request.Plugin)
+	if err := replaceWithArchive(archive, result.Path); err != nil {
+		result.Err = err
+		return result
+	}
+	os.Remove(archive)
+	return result
+}
+
+// replaceWithArchive extracts the archive into dir, replacing what's there only once it's
+// fully extracted.
+func replaceWithArchive(archive, dir string) error {
+	staging, err := os.MkdirTemp(filepath.Dir(dir), "."+filepath.Base(dir)+"-*")
+	if err != nil {
+		return fmt.Errorf("couldn't extract %s: %w", archive, err)
+	}
+	if err := os.Chmod(staging, 0o755); err != nil {
+		os.RemoveAll(staging)
+		return err
+	}
+	if err := Extract(archive, staging); err != nil {
+		os.RemoveAll(staging)
+		return err
+	}
+	if err := os.RemoveAll(dir); err != nil && !errors.Is(err, os.ErrNotExist) {
+		os.RemoveAll(staging)
+		return fmt.Errorf("couldn't replace %s: %w", dir, err)
+	}
+	if err := os.Rename(staging, dir); err != nil {
+		os.RemoveAll(staging)
+		return fmt.Errorf("couldn't replace %s: %w", dir, err)
+	}
+	return nil
+}
@@ -0,0 +1,248 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/omniviewdev/registry-cli/pkg/signing"
+)
+
+const (
+	// DefaultIndexTTL is how long the proxy serves cached indexes before refreshing them.
+	DefaultIndexTTL = 5 * time.Minute
+
+	// DefaultArtifactTTL is how long the proxy serves cached artifacts before refreshing them.
+	// Published artifacts don't change, so they're kept much longer than indexes.
+	DefaultArtifactTTL = 7 * 24 * time.Hour
+)
+
+// Proxy serves a registry over HTTP from a cache on disk, reading through to the upstream
+// registry on misses. Cached files past their TTL are refreshed from the upstream, and served
+// stale when it can't be reached.
+type Proxy struct {
+	upstream    *Client
+	dir         string
+	indexTTL    time.Duration
+	artifactTTL time.Duration
+	onError     func(error)
+	metrics     *proxyMetrics
+	tokens      map[string]string
+
+	accessMu sync.Mutex
+	acl      *proxyAccess
+
+	mu    sync.Mutex
+	locks map[string]*sync.Mutex
+}
+
+type ProxyOpts struct {
+	// CacheDir is the directory to cache the registry in
+	CacheDir string
+
+	// IndexTTL is how long indexes, pointers and their signatures are cached. Defaults to
+	// DefaultIndexTTL.
+	IndexTTL time.Duration
+
+	// ArtifactTTL is how long artifacts and their checksum files are cached. Defaults to
+	// DefaultArtifactTTL.
+	ArtifactTTL time.Duration
+
+	// OnError is called when the upstream fails and a stale copy is served instead
+	OnError func(error)
+
+	// Tokens maps the bearer tokens requests are authorized with to the principal they're made
+	// as. Private plugins (see types.RegistryIndexPlugins.Access) are only served to the
+	// principals on their access list, and left out of the registry index served to the others
+	// when it isn't signed.
+	Tokens map[string]string
+}
+
+// NewProxy creates a caching proxy in front of the registry the client reads from.
+func (c *Client) NewProxy(opts ProxyOpts) (*Proxy, error) {
+	if opts.CacheDir == "" {
+		return nil, errors.New("a cache directory is required")
+	}
+	if err := os.MkdirAll(opts.CacheDir, 0o755); err != nil {
+		return nil, fmt.Errorf("couldn't create cache directory %s: %w", opts.CacheDir, err)
+	}
+	if opts.IndexTTL <= 0 {
+		opts.IndexTTL = DefaultIndexTTL
+	}
+	if opts.ArtifactTTL <= 0 {
+		opts.ArtifactTTL = DefaultArtifactTTL
+	}
+	if opts.OnError == nil {
+		opts.OnError = func(error) {}
+	}
+
+	return &Proxy{
+		upstream:    c,
+		dir:         opts.CacheDir,
+		indexTTL:    opts.IndexTTL,
+		artifactTTL: opts.ArtifactTTL,
+		onError:     opts.OnError,
+		metrics:     newProxyMetrics(),
+		tokens:      opts.Tokens,
+		locks:       make(map[string]*sync.Mutex),
+	}, nil
+}
+
+// ServeHTTP serves a file of the registry, from the cache when it's fresh.
+func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	key := strings.TrimPrefix(path.Clean("/"+r.URL.Path), "/")
+	if key == "" {
+		key = registryIndexPath
+	}
+	recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+	defer func() { p.metrics.request(key, recorder.status) }()
+	w = recorder
+
+	principal, ok := p.principal(r)
+	if !ok {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="registry"`)
+		http.Error(w, "invalid token", http.StatusUnauthorized)
+		return
+	}
+	access, err := p.access(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	if !p.allowed(access, key, principal) {
+		// the same as a missing file, not to tell which private plugins exist
+		http.NotFound(w, r)
+		return
+	}
+
+	file, err := p.open(r.Context(), key)
+	if errors.Is(err, ErrNotFound) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if key == registryIndexPath && p.serveFilteredIndex(w, r, access, principal, file) {
+		return
+	}
+	if isIndex(key) {
+		p.metrics.learn(p.upstream, key, file)
+		w.Header().Set("Cache-Control", fmt.Sprintf("max-age=%d", int(p.indexTTL.Seconds())))
+	}
+	if _, ok := access.files[key]; ok {
+		// shared caches mustn't serve the files of private plugins to anyone else
+		w.Header().Add("Cache-Control", "private")
+	}
+	http.ServeContent(w, r, key, info.ModTime(), file)
+}
+
+// open opens the cached copy of a file, fetching it from the upstream first when it isn't
+// cached or has expired.
+func (p *Proxy) open(ctx context.Context, key string) (*os.File, error) {
+	lock := p.lock(key)
+	lock.Lock()
+	defer lock.Unlock()
+
+	cached := filepath.Join(p.dir, filepath.FromSlash(key))
+	info, statErr := os.Stat(cached)
+	if statErr == nil && time.Since(info.ModTime()) < p.ttl(key) {
+		p.metrics.cached(key, cacheHit)
+		return os.Open(cached)
+	}
+
+	err := p.fetch(ctx, key, cached)
+	if err == nil {
+		p.metrics.cached(key, cacheMiss)
+		return os.Open(cached)
+	}
+	if errors.Is(err, ErrNotFound) {
+		// removed upstream, e.g. by gc or unpublish
+		os.Remove(cached)
+		return nil, err
+	}
+	if statErr != nil {
+		return nil, err
+	}
+	p.onError(fmt.Errorf("serving stale %s: %w", key, err))
+	p.metrics.cached(key, cacheStale)
+	return os.Open(cached)
+}
+
+// fetch downloads a file from the upstream into the cache, replacing the cached copy only once
+// it's fully downloaded.
+func (p *Proxy) fetch(ctx context.Context, key, cached string) error {
+	body, err := p.upstream.Open(ctx, key)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	if err := os.MkdirAll(filepath.Dir(cached), 0o755); err != nil {
+		return fmt.Errorf("couldn't create cache directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(cached), ".fetch-*")
+	if err != nil {
+		return fmt.Errorf("couldn't cache %s: %w", key, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, body); err != nil {
+		tmp.Close()
+		return fmt.Errorf("couldn't download %s: %w", key, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("couldn't cache %s: %w", key, err)
+	}
+	if err := os.Rename(tmp.Name(), cached); err != nil {
+		return fmt.Errorf("couldn't cache %s: %w", key, err)
+	}
+	return nil
+}
+
+// lock returns the lock for a file, so concurrent requests for it share one fetch.
+func (p *Proxy) lock(key string) *sync.Mutex {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	lock, ok := p.locks[key]
+	if !ok {
+		lock = &sync.Mutex{}
+		p.locks[key] = lock
+	}
+	return lock
+}
+
+func (p *Proxy) ttl(key string) time.Duration {
+	if isIndex(key) {
+		return p.indexTTL
+	}
+	return p.artifactTTL
+}
+
+// isIndex reports whether a file is one of the registry's indexes, pointers or their
+// signatures, which change with every publish, rather than a published artifact.
+func isIndex(key string) bool {
+	return strings.HasSuffix(strings.TrimSuffix(key, signing.SignatureExt), ".json")
+}
@@ -0,0 +1,215 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/omniviewdev/registry-cli/pkg/signing"
+	"github.com/omniviewdev/registry-cli/pkg/types"
+)
+
+// registryIndexPath is the key of the registry index
+const registryIndexPath = "index.json"
+
+// proxyAccess is the access control of the registry the proxy enforces, read from the registry
+// index and the indexes of its private plugins.
+type proxyAccess struct {
+	// read is when the cached registry index it was read from was fetched
+	read time.Time
+
+	// signed reports whether the registry index is signed, in which case it's served whole as
+	// clients would refuse it filtered
+	signed bool
+
+	// plugins are the private plugins by ID
+	plugins map[string]types.RegistryIndexPlugins
+
+	// files maps the files of the private plugins to their ID: their indexes, pointers, builds
+	// and the checksums and signatures of those
+	files map[string]string
+}
+
+// principal returns the principal a request is made as, from its bearer token. Requests
+// without a token are anonymous, the principal "". False is returned for unknown tokens.
+func (p *Proxy) principal(r *http.Request) (string, bool) {
+	auth := r.Header.Get("Authorization")
+	if auth == "" {
+		return "", true
+	}
+	token, ok := strings.CutPrefix(auth, "Bearer ")
+	if !ok {
+		return "", false
+	}
+	principal, ok := p.tokens[strings.TrimSpace(token)]
+	return principal, ok
+}
+
+// allowed reports whether the principal may read the file. Files of private plugins are only
+// allowed to the principals on their access list.
+func (p *Proxy) allowed(access *proxyAccess, key, principal string) bool {
+	if plugin, ok := access.files[key]; ok && !access.plugins[plugin].Allows(principal) {
+		return false
+	}
+	// files the indexes don't list, e.g. the history of the indexes, under the default layout
+	for _, segment := range strings.Split(key, "/") {
+		if plugin, ok := access.plugins[segment]; ok && !plugin.Allows(principal) {
+			return false
+		}
+	}
+	return true
+}
+
+// access returns the access control of the registry, read again whenever the registry index
+// is refreshed.
+func (p *Proxy) access(ctx context.Context) (*proxyAccess, error) {
+	file, err := p.open(ctx, registryIndexPath)
+	if errors.Is(err, ErrNotFound) {
+		return &proxyAccess{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("couldn't read the access control of the registry: %w", err)
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	p.accessMu.Lock()
+	defer p.accessMu.Unlock()
+	if p.acl != nil && p.acl.read.Equal(info.ModTime()) {
+		return p.acl, nil
+	}
+
+	b, err := io.ReadAll(file)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't read %s: %w", registryIndexPath, err)
+	}
+	var index types.RegistryIndex
+	if err := types.DecodeIndex(b, &index); err != nil {
+		return nil, fmt.Errorf("couldn't decode %s: %w", registryIndexPath, err)
+	}
+
+	access := &proxyAccess{
+		read:    info.ModTime(),
+		plugins: make(map[string]types.RegistryIndexPlugins),
+		files:   make(map[string]string),
+	}
+	for _, plugin := range index.Plugins {
+		if !plugin.Private() {
+			continue
+		}
+		access.plugins[plugin.ID] = plugin
+		files, err := p.pluginFiles(ctx, plugin.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, key := range files {
+			access.files[key] = plugin.ID
+			access.files[key+signing.SignatureExt] = plugin.ID
+		}
+	}
+	if len(access.plugins) > 0 {
+		sig, err := p.open(ctx, registryIndexPath+signing.SignatureExt)
+		switch {
+		case err == nil:
+			sig.Close()
+			access.signed = true
+		case !errors.Is(err, ErrNotFound):
+			return nil, fmt.Errorf("couldn't read the access control of the registry: %w", err)
+		}
+	}
+	p.acl = access
+	return access, nil
+}
+
+// pluginFiles returns the keys of the files of a plugin: its index, its pointers and badge, and
+// the builds its index lists along with their checksums.
+func (p *Proxy) pluginFiles(ctx context.Context, plugin string) ([]string, error) {
+	layout, err := p.upstream.Layout(ctx)
+	if err != nil {
+		return nil, err
+	}
+	files := []string{
+		layout.IndexPath(plugin),
+		types.LatestVersionPath(plugin),
+		types.VersionBadgePath(plugin),
+	}
+	for _, channel := range types.Channels {
+		files = append(files, types.ChannelPath(plugin, channel))
+	}
+
+	file, err := p.open(ctx, layout.IndexPath(plugin))
+	if errors.Is(err, ErrNotFound) {
+		return files, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("couldn't read the access control of %s: %w", plugin, err)
+	}
+	defer file.Close()
+	var index types.PluginIndex
+	if err := json.NewDecoder(file).Decode(&index); err != nil {
+		return nil, fmt.Errorf("couldn't decode the index of %s: %w", plugin, err)
+	}
+	for _, version := range index.Versions {
+		for _, arch := range version.Architectures {
+			artifact, ok := artifactKey(p.upstream, arch.DownloadURL)
+			if !ok {
+				continue
+			}
+			files = append(files, artifact, artifact+types.ChecksumExt)
+			if checksum, ok := artifactKey(p.upstream, arch.ChecksumURL); ok && checksum != "" {
+				files = append(files, checksum)
+			}
+		}
+	}
+	return files, nil
+}
+
+// serveFilteredIndex serves the registry index without the private plugins the principal isn't
+// allowed. Signed registry indexes are served whole, as clients would refuse them filtered.
+func (p *Proxy) serveFilteredIndex(
+	w http.ResponseWriter,
+	r *http.Request,
+	access *proxyAccess,
+	principal string,
+	file *os.File,
+) bool {
+	if access.signed || len(access.plugins) == 0 {
+		return false
+	}
+	b, err := io.ReadAll(file)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return true
+	}
+	var index types.RegistryIndex
+	if err := types.DecodeIndex(b, &index); err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return true
+	}
+	plugins := index.Plugins[:0]
+	for _, plugin := range index.Plugins {
+		if plugin.Allows(principal) {
+			plugins = append(plugins, plugin)
+		}
+	}
+	index.Plugins = plugins
+	if b, err = json.Marshal(index); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return true
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", fmt.Sprintf("private, max-age=%d", int(p.indexTTL.Seconds())))
+	http.ServeContent(w, r, registryIndexPath, access.read, bytes.NewReader(b))
+	return true
+}
@@ -0,0 +1,203 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/omniviewdev/registry-cli/pkg/types"
+)
+
+// cache results of the requests served by the proxy
+const (
+	cacheHit   = "hit"
+	cacheMiss  = "miss"
+	cacheStale = "stale"
+)
+
+// proxyMetrics counts what the proxy serves, for the Prometheus metrics endpoint.
+type proxyMetrics struct {
+	started time.Time
+
+	mu sync.Mutex
+	// requests counts the requests served by the kind of file and the response status
+	requests map[[2]string]uint64
+	// cache counts the requests read from the cache by the kind of file and the cache result
+	cache map[[2]string]uint64
+	// downloads counts the artifacts served by plugin
+	downloads map[string]uint64
+
+	// plugins maps the artifacts to their plugin, learned from the plugin indexes served,
+	// along with when each index was learned from
+	plugins map[string]string
+	learned map[string]time.Time
+}
+
+func newProxyMetrics() *proxyMetrics {
+	return &proxyMetrics{
+		started:   time.Now(),
+		requests:  make(map[[2]string]uint64),
+		cache:     make(map[[2]string]uint64),
+		downloads: make(map[string]uint64),
+		plugins:   make(map[string]string),
+		learned:   make(map[string]time.Time),
+	}
+}
+
+// Metrics returns the handler exposing the metrics of the proxy in the Prometheus text format:
+// the requests served, the artifacts downloaded by plugin and the cache hits and misses.
+func (p *Proxy) Metrics() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		p.metrics.write(w)
+	})
+}
+
+// kind describes a file of the registry in the labels of the metrics.
+func kind(key string) string {
+	if isIndex(key) {
+		return "index"
+	}
+	return "artifact"
+}
+
+func (m *proxyMetrics) request(key string, status int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.requests[[2]string{kind(key), strconv.Itoa(status)}]++
+	// checksum files are fetched along with their artifact, they aren't downloads of their own
+	download := kind(key) == "artifact" && !strings.HasSuffix(key, types.ChecksumExt)
+	if download && status == http.StatusOK {
+		plugin, ok := m.plugins[key]
+		if !ok {
+			plugin = "unknown"
+		}
+		m.downloads[plugin]++
+	}
+}
+
+func (m *proxyMetrics) cached(key, result string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.cache[[2]string{kind(key), result}]++
+}
+
+// learn maps the artifacts of a plugin index served by the proxy to the plugin, so their
+// downloads can be counted by plugin whatever the layout of the registry. Each index is only
+// read again once it changes.
+func (m *proxyMetrics) learn(upstream *Client, key string, file *os.File) {
+	if !strings.HasSuffix(key, ".json") {
+		return
+	}
+	info, err := file.Stat()
+	if err != nil {
+		return
+	}
+	m.mu.Lock()
+	learned := m.learned[key].Equal(info.ModTime())
+	m.mu.Unlock()
+	if learned {
+		return
+	}
+
+	var index types.PluginIndex
+	err = json.NewDecoder(io.NewSectionReader(file, 0, info.Size())).Decode(&index)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.learned[key] = info.ModTime()
+	if err != nil || index.ID == "" {
+		// not a plugin index
+		return
+	}
+	for _, version := range index.Versions {
+		for _, arch := range version.Architectures {
+			if artifact, ok := artifactKey(upstream, arch.DownloadURL); ok {
+				m.plugins[artifact] = index.ID
+			}
+		}
+	}
+}
+
+func (m *proxyMetrics) write(w io.Writer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	fmt.Fprintln(w, "# HELP registry_proxy_requests_total Requests served by the proxy.")
+	fmt.Fprintln(w, "# TYPE registry_proxy_requests_total counter")
+	for _, labels := range slices.SortedFunc(maps.Keys(m.requests), compareLabels) {
+		fmt.Fprintf(
+			w,
+			"registry_proxy_requests_total{kind=%q,code=%q} %d\n",
+			labels[0],
+			labels[1],
+			m.requests[labels],
+		)
+	}
+
+	fmt.Fprintln(w, "# HELP registry_proxy_downloads_total Artifacts downloaded through the proxy.")
+	fmt.Fprintln(w, "# TYPE registry_proxy_downloads_total counter")
+	for _, plugin := range slices.Sorted(maps.Keys(m.downloads)) {
+		fmt.Fprintf(w, "registry_proxy_downloads_total{plugin=%q} %d\n", plugin, m.downloads[plugin])
+	}
+
+	fmt.Fprintln(
+		w,
+		"# HELP registry_proxy_cache_requests_total Requests read from the cache, by result "+
+			"(hit, miss or stale).",
+	)
+	fmt.Fprintln(w, "# TYPE registry_proxy_cache_requests_total counter")
+	for _, labels := range slices.SortedFunc(maps.Keys(m.cache), compareLabels) {
+		fmt.Fprintf(
+			w,
+			"registry_proxy_cache_requests_total{kind=%q,result=%q} %d\n",
+			labels[0],
+			labels[1],
+			m.cache[labels],
+		)
+	}
+
+	fmt.Fprintln(
+		w,
+		"# HELP registry_proxy_cache_hit_ratio Share of the requests served from the cache.",
+	)
+	fmt.Fprintln(w, "# TYPE registry_proxy_cache_hit_ratio gauge")
+	for _, k := range []string{"index", "artifact"} {
+		hits := m.cache[[2]string{k, cacheHit}] + m.cache[[2]string{k, cacheStale}]
+		total := hits + m.cache[[2]string{k, cacheMiss}]
+		ratio := 0.0
+		if total > 0 {
+			ratio = float64(hits) / float64(total)
+		}
+		fmt.Fprintf(w, "registry_proxy_cache_hit_ratio{kind=%q} %g\n", k, ratio)
+	}
+
+	fmt.Fprintln(w, "# HELP registry_proxy_start_time_seconds When the proxy started.")
+	fmt.Fprintln(w, "# TYPE registry_proxy_start_time_seconds gauge")
+	fmt.Fprintf(w, "registry_proxy_start_time_seconds %d\n", m.started.Unix())
+}
+
+func compareLabels(a, b [2]string) int {
+	if c := strings.Compare(a[0], b[0]); c != 0 {
+		return c
+	}
+	return strings.Compare(a[1], b[1])
+}
+
+// statusRecorder records the status of a response, for the request metrics.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
@@ -0,0 +1,132 @@
+package client
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/omniviewdev/registry-cli/pkg/types"
+)
+
+// RegistryConfig configures one of several registries to read from.
+type RegistryConfig struct {
+	// URL is the base URL of the registry
+	URL string `mapstructure:"url" yaml:"url"`
+
+	// Priority orders the registries, plugins are resolved from the highest priority registry
+	// that has them. Registries of the same priority are tried in the order they're listed.
+	Priority int `mapstructure:"priority" yaml:"priority"`
+
+	// Token is the bearer token to read the registry with, when it's a proxy serving private
+	// plugins
+	Token string `mapstructure:"token" yaml:"token,omitempty"`
+}
+
+// SortRegistries sorts the registries by priority, highest first.
+func SortRegistries(configs []RegistryConfig) {
+	slices.SortStableFunc(configs, func(a, b RegistryConfig) int {
+		return cmp.Compare(b.Priority, a.Priority)
+	})
+}
+
+// Registries reads from several registries in priority order, highest first, e.g. a private
+// registry overriding plugins of the official one.
+type Registries []*Client
+
+// RegistryPlugin is a plugin listed in one of several registries.
+type RegistryPlugin struct {
+	types.RegistryIndexPlugins
+
+	// Source is the URL of the registry the plugin is listed from
+	Source string `json:"source"`
+
+	registry *Client
+}
+
+// PluginIndex fetches the index of a plugin from the highest priority registry that has it,
+// returning the client for that registry along with it.
+func (r Registries) PluginIndex(
+	ctx context.Context,
+	plugin string,
+) (types.PluginIndex, *Client, error) {
+	for _, c := range r {
+		index, err := c.PluginIndex(ctx, plugin)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return types.PluginIndex{}, nil, err
+		}
+		return index, c, nil
+	}
+	return types.PluginIndex{}, nil, fmt.Errorf("%s: %w", plugin, ErrNotFound)
+}
+
+// Plugins lists the plugins of every registry, each from the highest priority registry listing
+// it, in the order the registries list them.
+func (r Registries) Plugins(ctx context.Context) ([]RegistryPlugin, error) {
+	var plugins []RegistryPlugin
+	seen := make(map[string]bool)
+	for _, c := range r {
+		index, err := c.RegistryIndex(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", c.URL(""), err)
+		}
+		for _, plugin := range index.Plugins {
+			if seen[plugin.ID] {
+				continue
+			}
+			seen[plugin.ID] = true
+			plugins = append(plugins, RegistryPlugin{
+				RegistryIndexPlugins: plugin,
+				Source:               c.URL(""),
+				registry:             c,
+			})
+		}
+	}
+	return plugins, nil
+}
+
+// Federate builds a federated index of the plugins of every registry, each from the highest
+// priority registry listing it, with the URLs of its index and downloads made absolute.
+func (r Registries) Federate(ctx context.Context) (types.FederationIndex, error) {
+	plugins, err := r.Plugins(ctx)
+	if err != nil {
+		return types.FederationIndex{}, err
+	}
+
+	federation := types.FederationIndex{
+		Generated: time.Now().UTC(),
+		Plugins:   make([]types.FederatedPlugin, 0, len(plugins)),
+	}
+	for _, c := range r {
+		federation.Registries = append(federation.Registries, c.URL(""))
+	}
+	for _, plugin := range plugins {
+		layout, err := plugin.registry.Layout(ctx)
+		if err != nil {
+			return types.FederationIndex{}, err
+		}
+		latest := plugin.LatestVersion
+		archs := make(map[string]types.PluginArchitectureInformation, len(latest.Architectures))
+		for arch, info := range latest.Architectures {
+			info.DownloadURL = plugin.registry.URL(info.DownloadURL)
+			if info.ChecksumURL != "" {
+				info.ChecksumURL = plugin.registry.URL(info.ChecksumURL)
+			}
+			archs[arch] = info
+		}
+		latest.Architectures = archs
+		plugin.LatestVersion = latest
+
+		federation.Plugins = append(federation.Plugins, types.FederatedPlugin{
+			RegistryIndexPlugins: plugin.RegistryIndexPlugins,
+			Source:               plugin.Source,
+			IndexURL:             plugin.registry.URL(layout.IndexPath(plugin.ID)),
+		})
+	}
+	return federation, nil
+}
@@ -0,0 +1,219 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/omniviewdev/registry-cli/pkg/types"
+)
+
+// resolvedPlugin is a plugin pinned to the version to install.
+type resolvedPlugin struct {
+	version types.PluginVersionInformation
+
+	// registry is the client of the registry the plugin is installed from
+	registry *Client
+
+	// requires are the plugins it depends on
+	requires []string
+
+	// level is how deep its dependencies go. A plugin is installed once every plugin of a lower
+	// level is.
+	level int
+}
+
+// resolve resolves the plugins requested along with their dependencies, pinning each plugin
+// to a version satisfying every plugin depending on it, from the highest priority registry that
+// has it. The plugins are returned by level, in the order to install them in.
+func (r Registries) resolve(
+	ctx context.Context,
+	requests []InstallRequest,
+) ([][]InstallRequest, map[string]*resolvedPlugin, error) {
+	type requirement struct {
+		by         string
+		constraint string
+	}
+	resolved := make(map[string]*resolvedPlugin)
+	requirements := make(map[string][]requirement)
+	moved := make(map[string]string)
+
+	queue := slices.Clone(requests)
+	for len(queue) > 0 {
+		request := queue[0]
+		queue = queue[1:]
+		if !filepath.IsLocal(request.Plugin) || strings.ContainsAny(request.Plugin, `/\`) {
+			return nil, nil, fmt.Errorf("invalid plugin id %q", request.Plugin)
+		}
+
+		if existing, ok := resolved[request.Plugin]; ok {
+			if request.Version != "" && !satisfies(existing.version.Version, request.Version) {
+				return nil, nil, fmt.Errorf(
+					"conflicting versions of %s: %s was resolved, but %s is required",
+					request.Plugin,
+					existing.version.Version,
+					request.Version,
+				)
+			}
+			continue
+		}
+
+		index, registry, err := r.PluginIndex(ctx, request.Plugin)
+		if err != nil {
+			return nil, nil, err
+		}
+		if index.ID != request.Plugin {
+			// transferred, so it's installed under its new id
+			moved[request.Plugin] = index.ID
+			requirements[index.ID] = append(requirements[index.ID], requirements[request.Plugin]...)
+			queue = append(queue, InstallRequest{Plugin: index.ID, Version: request.Version})
+			continue
+		}
+		version, ok := resolveVersion(index, request.Version)
+		if !ok {
+			return nil, nil, fmt.Errorf(
+				"no version of %s satisfies %s",
+				request.Plugin,
+				request.Version,
+			)
+		}
+		for _, req := range requirements[request.Plugin] {
+			if !satisfies(version.Version, req.constraint) {
+				return nil, nil, fmt.Errorf(
+					"conflicting versions of %s: %s requires %s, but %s was resolved",
+					request.Plugin,
+					req.by,
+					req.constraint,
+					version.Version,
+				)
+			}
+		}
+
+		requires, err := version.Metadata.Requires()
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s %s: %w", request.Plugin, version.Version, err)
+		}
+		plugin := &resolvedPlugin{version: version, registry: registry}
+		for _, dep := range sortedKeys(requires) {
+			plugin.requires = append(plugin.requires, dep)
+			requirements[dep] = append(
+				requirements[dep],
+				requirement{by: request.Plugin, constraint: requires[dep]},
+			)
+			queue = append(queue, InstallRequest{Plugin: dep, Version: requires[dep]})
+		}
+		resolved[request.Plugin] = plugin
+	}
+
+	// plugins depend on transferred plugins by their old ids
+	for _, plugin := range resolved {
+		for idx, dep := range plugin.requires {
+			for moved[dep] != "" {
+				dep = moved[dep]
+			}
+			plugin.requires[idx] = dep
+		}
+	}
+
+	var levels [][]InstallRequest
+	visiting := make(map[string]bool)
+	var visit func(plugin string, path []string) (int, error)
+	visit = func(plugin string, path []string) (int, error) {
+		p := resolved[plugin]
+		if visiting[plugin] {
+			return 0, fmt.Errorf(
+				"dependency cycle: %s",
+				strings.Join(append(path, plugin), " -> "),
+			)
+		}
+		if p.level > 0 {
+			return p.level, nil
+		}
+
+		visiting[plugin] = true
+		level := 1
+		for _, dep := range p.requires {
+			depLevel, err := visit(dep, append(path, plugin))
+			if err != nil {
+				return 0, err
+			}
+			level = max(level, depLevel+1)
+		}
+		visiting[plugin] = false
+
+		p.level = level
+		for len(levels) < level {
+			levels = append(levels, nil)
+		}
+		levels[level-1] = append(
+			levels[level-1],
+			InstallRequest{Plugin: plugin, Version: p.version.Version},
+		)
+		return level, nil
+	}
+	for _, plugin := range sortedKeys(resolved) {
+		if _, err := visit(plugin, nil); err != nil {
+			return nil, nil, err
+		}
+	}
+	return levels, resolved, nil
+}
+
+// Pin resolves the plugins a plugin depends on, along with the plugins they depend on, to the
+// exact versions installing them would resolve to, mapped by plugin id.
+func (r Registries) Pin(ctx context.Context, requires map[string]string) (map[string]string, error) {
+	requests := make([]InstallRequest, 0, len(requires))
+	for _, plugin := range sortedKeys(requires) {
+		requests = append(requests, InstallRequest{Plugin: plugin, Version: requires[plugin]})
+	}
+	_, resolved, err := r.resolve(ctx, requests)
+	if err != nil {
+		return nil, err
+	}
+	pins := make(map[string]string, len(resolved))
+	for plugin, p := range resolved {
+		pins[plugin] = p.version.Version
+	}
+	return pins, nil
+}
+
+// resolveVersion resolves a version (or version constraint) of a plugin, picking the latest
+// version when none is given. Yanked versions are only picked when pinned exactly.
+func resolveVersion(
+	index types.PluginIndex,
+	version string,
+) (types.PluginVersionInformation, bool) {
+	idx := slices.IndexFunc(index.Versions, func(v types.PluginVersionInformation) bool {
+		return v.Version == version
+	})
+	if idx != -1 {
+		return index.Versions[idx], true
+	}
+	return index.Resolve(version)
+}
+
+// satisfies reports whether the version is or satisfies the version constraint.
+func satisfies(version, constraint string) bool {
+	if constraint == "" || version == constraint {
+		return true
+	}
+	c, err := semver.NewConstraint(constraint)
+	if err != nil {
+		return false
+	}
+	v, err := semver.NewVersion(version)
+	return err == nil && c.Check(v)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,195 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/omniviewdev/registry-cli/pkg/types"
+)
+
+// testPlugin is a version of a plugin of a test registry, depending on other plugins.
+type testPlugin struct {
+	id       string
+	version  string
+	requires map[string]any
+}
+
+// pluginRegistry serves the indexes of the plugins.
+func pluginRegistry(t *testing.T, plugins ...testPlugin) Registries {
+	t.Helper()
+	indexes := make(map[string]types.PluginIndex)
+	for _, p := range plugins {
+		index, ok := indexes[p.id]
+		if !ok {
+			index.ID = p.id
+			index.Name = p.id
+		}
+		meta := types.PluginMeta{ID: p.id, Version: p.version}
+		if p.requires != nil {
+			meta.Dependencies = p.requires
+		}
+		index.SetVersion(types.PluginVersionInformation{Version: p.version, Metadata: meta})
+		indexes[p.id] = index
+	}
+
+	files := make(map[string][]byte)
+	for _, index := range indexes {
+		b, err := json.Marshal(index)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[types.Layout{}.IndexPath(index.ID)] = b
+	}
+	return Registries{testClient(t, testRegistry(t, files).URL, nil, "")}
+}
+
+func TestResolve(t *testing.T) {
+	tests := []struct {
+		name     string
+		plugins  []testPlugin
+		requests []InstallRequest
+		want     [][]string
+		wantErr  string
+	}{
+		{
+			name:     "no dependencies",
+			plugins:  []testPlugin{{id: "a", version: "1.0.0"}},
+			requests: []InstallRequest{{Plugin: "a"}},
+			want:     [][]string{{"a@1.0.0"}},
+		},
+		{
+			name: "chain",
+			plugins: []testPlugin{
+				{id: "a", version: "1.0.0", requires: map[string]any{"b": nil}},
+				{id: "b", version: "1.0.0", requires: map[string]any{"c": "^1"}},
+				{id: "c", version: "1.0.0"},
+				{id: "c", version: "1.2.0"},
+				{id: "c", version: "2.0.0"},
+			},
+			requests: []InstallRequest{{Plugin: "a"}},
+			want:     [][]string{{"c@1.2.0"}, {"b@1.0.0"}, {"a@1.0.0"}},
+		},
+		{
+			name: "diamond",
+			plugins: []testPlugin{
+				{id: "a", version: "1.0.0", requires: map[string]any{"b": nil, "c": nil}},
+				{id: "b", version: "1.0.0", requires: map[string]any{"d": ">=1.0.0"}},
+				{id: "c", version: "1.0.0", requires: map[string]any{"d": "<2.0.0"}},
+				{id: "d", version: "1.5.0"},
+			},
+			requests: []InstallRequest{{Plugin: "a"}},
+			want:     [][]string{{"d@1.5.0"}, {"b@1.0.0", "c@1.0.0"}, {"a@1.0.0"}},
+		},
+		{
+			name: "requested dependency",
+			plugins: []testPlugin{
+				{id: "a", version: "1.0.0", requires: map[string]any{"b": nil}},
+				{id: "b", version: "1.0.0"},
+			},
+			requests: []InstallRequest{{Plugin: "b"}, {Plugin: "a"}},
+			want:     [][]string{{"b@1.0.0"}, {"a@1.0.0"}},
+		},
+		{
+			name: "cycle",
+			plugins: []testPlugin{
+				{id: "a", version: "1.0.0", requires: map[string]any{"b": nil}},
+				{id: "b", version: "1.0.0", requires: map[string]any{"c": nil}},
+				{id: "c", version: "1.0.0", requires: map[string]any{"a": nil}},
+			},
+			requests: []InstallRequest{{Plugin: "a"}},
+			wantErr:  "dependency cycle: a -> b -> c -> a",
+		},
+		{
+			name: "self dependency",
+			plugins: []testPlugin{
+				{id: "a", version: "1.0.0", requires: map[string]any{"a": nil}},
+			},
+			requests: []InstallRequest{{Plugin: "a"}},
+			wantErr:  "dependency cycle",
+		},
+		{
+			name: "missing dependency",
+			plugins: []testPlugin{
+				{id: "a", version: "1.0.0", requires: map[string]any{"missing": nil}},
+			},
+			requests: []InstallRequest{{Plugin: "a"}},
+			wantErr:  "missing: " + ErrNotFound.Error(),
+		},
+		{
+			name: "unsatisfiable dependency",
+			plugins: []testPlugin{
+				{id: "a", version: "1.0.0", requires: map[string]any{"b": "^2"}},
+				{id: "b", version: "1.0.0"},
+			},
+			requests: []InstallRequest{{Plugin: "a"}},
+			wantErr:  "no version of b satisfies ^2",
+		},
+		{
+			name: "conflicting constraints",
+			plugins: []testPlugin{
+				{id: "a", version: "1.0.0", requires: map[string]any{"c": "^1"}},
+				{id: "b", version: "1.0.0", requires: map[string]any{"c": "^2"}},
+				{id: "c", version: "1.0.0"},
+				{id: "c", version: "2.0.0"},
+			},
+			requests: []InstallRequest{{Plugin: "a"}, {Plugin: "b"}},
+			wantErr:  "conflicting versions of c",
+		},
+		{
+			name:     "invalid plugin id",
+			plugins:  []testPlugin{{id: "a", version: "1.0.0"}},
+			requests: []InstallRequest{{Plugin: "../a"}},
+			wantErr:  `invalid plugin id "../a"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			levels, _, err := pluginRegistry(t, tt.plugins...).resolve(t.Context(), tt.requests)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("got %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got [][]string
+			for _, level := range levels {
+				var plugins []string
+				for _, request := range level {
+					plugins = append(plugins, request.Plugin+"@"+request.Version)
+				}
+				got = append(got, plugins)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("installed in order %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPin(t *testing.T) {
+	registries := pluginRegistry(t,
+		testPlugin{id: "a", version: "1.0.0", requires: map[string]any{"b": "~1.1"}},
+		testPlugin{id: "b", version: "1.1.0"},
+		testPlugin{id: "b", version: "1.1.3"},
+		testPlugin{id: "b", version: "1.2.0"},
+	)
+	pins, err := registries.Pin(t.Context(), map[string]string{"a": ""})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"a": "1.0.0", "b": "1.1.3"}
+	if !reflect.DeepEqual(pins, want) {
+		t.Fatalf("pinned %v, want %v", pins, want)
+	}
+
+	_, err = registries.Pin(t.Context(), map[string]string{"missing": ""})
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("got %v, want %v", err, ErrNotFound)
+	}
+}
@@ -0,0 +1,220 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/omniviewdev/registry-cli/pkg/signing"
+	"github.com/omniviewdev/registry-cli/pkg/types"
+)
+
+// trustedKeysTTL is how long the trusted keys of the registry are used before they are fetched
+// again, so long running clients like the proxy follow rotations
+const trustedKeysTTL = 5 * time.Minute
+
+// ErrRetiredKey is returned when a file is signed with a key that retired at the end of the
+// grace period of a rotation.
+var ErrRetiredKey = errors.New("file is signed with a retired key")
+
+// verify checks that sig is a valid signature of data, made for the file with the given name,
+// by a key trusted for the registry: one of the pinned keys, or a key the registry rotated to
+// from one of them (see types.TrustedKeys). Keys are no longer trusted once they retire, even
+// when pinned. The parsed signature is returned.
+func (c *Client) verify(
+	ctx context.Context,
+	name string,
+	data, sig []byte,
+) (*signing.Signature, error) {
+	keys, trusted, err := c.keys(ctx)
+	if err != nil {
+		return nil, err
+	}
+	verified, err := signing.VerifyFile(keys, data, sig, name)
+	if !errors.Is(err, signing.ErrUntrustedKey) {
+		return verified, err
+	}
+	parsed, parseErr := signing.ParseSignature(sig)
+	if parseErr != nil {
+		return nil, err
+	}
+	now := time.Now()
+	key, ok := trusted.Find(parsed.KeyID.String())
+	if ok && key.Retired(now) {
+		return nil, fmt.Errorf(
+			"%w: %s retired on %s, trust the current key of the registry instead",
+			ErrRetiredKey,
+			key.ID,
+			key.Retires.Format(time.DateOnly),
+		)
+	}
+	for _, pinned := range c.trustedKeys {
+		// the rotations from a retired key aren't followed anymore
+		if key, ok := trusted.Find(pinned.ID.String()); ok && key.Retired(now) {
+			return nil, fmt.Errorf(
+				"%w (the pinned key %s retired on %s, trust the current key of the registry instead)",
+				err,
+				key.ID,
+				key.Retires.Format(time.DateOnly),
+			)
+		}
+	}
+	return nil, err
+}
+
+// keys returns the keys signatures are verified against, along with the trusted keys of the
+// registry they were derived from.
+func (c *Client) keys(ctx context.Context) ([]signing.PublicKey, types.TrustedKeys, error) {
+	c.keysMu.Lock()
+	defer c.keysMu.Unlock()
+	if c.keysFetched.IsZero() || time.Since(c.keysFetched) > trustedKeysTTL {
+		trusted, err := c.fetchTrustedKeys(ctx)
+		if err != nil {
+			return nil, types.TrustedKeys{}, err
+		}
+		c.trusted = trusted
+		c.keysFetched = time.Now()
+	}
+	return followRotations(c.trustedKeys, c.trusted, time.Now()), c.trusted, nil
+}
+
+// fetchTrustedKeys fetches the trusted keys of the registry. They are ignored unless signed
+// with a key the pinned keys lead to through them, registries that never rotated their key have
+// none. Once the keys of the registry were accepted, they must be there and verify, and can't
+// be replaced by an older copy nor bring back a key whose retirement was accepted.
+func (c *Client) fetchTrustedKeys(ctx context.Context) (types.TrustedKeys, error) {
+	accepted, err := c.timestamps.accepted(types.TrustedKeysPath)
+	if err != nil {
+		return types.TrustedKeys{}, err
+	}
+	retired, err := c.timestamps.retirements()
+	if err != nil {
+		return types.TrustedKeys{}, err
+	}
+	// refuses the keys when some were accepted before, else the registry has none
+	refuse := func(err error) (types.TrustedKeys, error) {
+		if !accepted {
+			return types.TrustedKeys{}, nil
+		}
+		return types.TrustedKeys{}, fmt.Errorf(
+			"refusing to use %s, which was accepted before: %w",
+			types.TrustedKeysPath,
+			err,
+		)
+	}
+
+	b, err := c.Fetch(ctx, types.TrustedKeysPath)
+	if errors.Is(err, ErrNotFound) {
+		return refuse(err)
+	}
+	if err != nil {
+		return types.TrustedKeys{}, err
+	}
+	sig, err := c.Fetch(ctx, types.TrustedKeysPath+signing.SignatureExt)
+	if errors.Is(err, ErrNotFound) {
+		return refuse(signing.ErrNoSignature)
+	}
+	if err != nil {
+		return types.TrustedKeys{}, err
+	}
+
+	var trusted types.TrustedKeys
+	if err := json.Unmarshal(b, &trusted); err != nil {
+		return types.TrustedKeys{}, fmt.Errorf(
+			"couldn't decode %s: %w",
+			types.TrustedKeysPath,
+			err,
+		)
+	}
+	// the keys are signed with the current key, which retired keys still lead to: that's how
+	// clients pinning them learn they retired. Only the retirements accepted before count, so a
+	// retired key can't sign keys dropping its own retirement.
+	keys := followRotations(c.trustedKeys, withRetirements(trusted, retired), time.Now())
+	verified, err := signing.VerifyFile(keys, b, sig, types.TrustedKeysPath)
+	if err != nil {
+		return refuse(err)
+	}
+	if err := c.timestamps.accept(types.TrustedKeysPath, verified.Timestamp); err != nil {
+		return types.TrustedKeys{}, fmt.Errorf(
+			"refusing to use %s: %w",
+			types.TrustedKeysPath,
+			err,
+		)
+	}
+
+	retires := make(map[string]time.Time)
+	for _, key := range trusted.Keys {
+		if key.Retires != nil {
+			retires[key.ID] = *key.Retires
+		}
+	}
+	if err := c.timestamps.retire(retires); err != nil {
+		return types.TrustedKeys{}, err
+	}
+	if retired, err = c.timestamps.retirements(); err != nil {
+		return types.TrustedKeys{}, err
+	}
+	return withRetirements(trusted, retired), nil
+}
+
+// withRetirements returns the trusted keys retiring as given, by key ID, rather than as they
+// say. Retired keys the trusted keys don't list are added to them.
+func withRetirements(trusted types.TrustedKeys, retired map[string]time.Time) types.TrustedKeys {
+	keys := trusted.Keys
+	trusted.Keys = make([]types.TrustedKey, 0, len(keys))
+	for _, key := range keys {
+		key.Retires = nil
+		trusted.Keys = append(trusted.Keys, key)
+	}
+	for id, retires := range retired {
+		key, ok := trusted.Find(id)
+		if !ok {
+			key = types.TrustedKey{ID: id}
+		}
+		key.Retires = &retires
+		trusted.Set(key)
+	}
+	return trusted
+}
+
+// followRotations returns the pinned keys that haven't retired at the given time, along with the
+// keys endorsed by them through the rotations of the registry's key, that haven't retired
+// either. No key has retired at the zero time.
+func followRotations(
+	pinned []signing.PublicKey,
+	trusted types.TrustedKeys,
+	at time.Time,
+) []signing.PublicKey {
+	retired := func(id signing.KeyID) bool {
+		key, ok := trusted.Find(id.String())
+		return ok && key.Retired(at)
+	}
+
+	var keys []signing.PublicKey
+	known := make(map[signing.KeyID]bool)
+	for _, key := range pinned {
+		if !retired(key.ID) && !known[key.ID] {
+			keys = append(keys, key)
+			known[key.ID] = true
+		}
+	}
+	for endorsed := true; endorsed; {
+		endorsed = false
+		for _, candidate := range trusted.Keys {
+			key, err := signing.ParsePublicKey(candidate.Key)
+			if err != nil || key.ID.String() != candidate.ID || known[key.ID] || retired(key.ID) {
+				continue
+			}
+			_, err = signing.Verify(keys, []byte(candidate.Key), []byte(candidate.Endorsement))
+			if err != nil {
+				continue
+			}
+			keys = append(keys, key)
+			known[key.ID] = true
+			endorsed = true
+		}
+	}
+	return keys
+}
@@ -0,0 +1,177 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/omniviewdev/registry-cli/pkg/signing"
+	"github.com/omniviewdev/registry-cli/pkg/types"
+)
+
+// rotatedFiles returns the files of a registry whose key rotated from previous to next, the
+// previous key retiring at the given time, with its index and trusted keys signed by next.
+func rotatedFiles(
+	t *testing.T,
+	previous, next *signing.PrivateKey,
+	retires *time.Time,
+) map[string][]byte {
+	t.Helper()
+	keys, err := json.Marshal(types.TrustedKeys{
+		Keys: []types.TrustedKey{
+			{
+				ID:      previous.Public().ID.String(),
+				Key:     previous.Public().String(),
+				Retires: retires,
+			},
+			{
+				ID:         next.Public().ID.String(),
+				Key:        next.Public().String(),
+				EndorsedBy: previous.Public().ID.String(),
+				Endorsement: string(previous.Sign(
+					[]byte(next.Public().String()),
+					previous.Public().ID.String(),
+				)),
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	registry := []byte(`{"plugins":[]}`)
+	return map[string][]byte{
+		"index.json":     registry,
+		"index.json.sig": next.Sign(registry, "index.json"),
+		"keys.json":      keys,
+		"keys.json.sig":  next.Sign(keys, "keys.json"),
+	}
+}
+
+func TestTrustedKeysWithdrawn(t *testing.T) {
+	previous, next := testKey(t), testKey(t)
+	retired := time.Now().Add(-time.Hour)
+
+	tests := map[string]func(files map[string][]byte){
+		"removed": func(files map[string][]byte) {
+			delete(files, "keys.json")
+			delete(files, "keys.json.sig")
+		},
+		"unsigned": func(files map[string][]byte) {
+			delete(files, "keys.json.sig")
+		},
+		"signed by another key": func(files map[string][]byte) {
+			files["keys.json.sig"] = testKey(t).Sign(files["keys.json"], "keys.json")
+		},
+	}
+	for name, withdraw := range tests {
+		t.Run(name, func(t *testing.T) {
+			timestamps := filepath.Join(t.TempDir(), "timestamps.json")
+			newClient := func(files map[string][]byte) *Client {
+				c, err := New(ClientOpts{
+					BaseURL:        testRegistry(t, files).URL,
+					TrustedKeys:    []signing.PublicKey{previous.Public(), next.Public()},
+					TimestampsFile: timestamps,
+				})
+				if err != nil {
+					t.Fatal(err)
+				}
+				return c
+			}
+			files := rotatedFiles(t, previous, next, &retired)
+			if _, err := newClient(files).FetchVerified(t.Context(), "index.json"); err != nil {
+				t.Fatal(err)
+			}
+
+			// without the trusted keys, the retirement of the previous key would be forgotten
+			files = rotatedFiles(t, previous, next, &retired)
+			files["index.json.sig"] = previous.Sign(files["index.json"], "index.json")
+			withdraw(files)
+			if _, err := newClient(files).FetchVerified(t.Context(), "index.json"); err == nil {
+				t.Fatal("expected the trusted keys accepted before to be required")
+			}
+		})
+	}
+}
+
+func TestTrustedKeysRetirementKept(t *testing.T) {
+	tests := []struct {
+		name string
+		// next returns the client fetching the forged keys after c accepted the retirement
+		next func(t *testing.T, c *Client, opts ClientOpts) *Client
+	}{
+		{
+			name: "same client",
+			next: func(t *testing.T, c *Client, opts ClientOpts) *Client {
+				c.keysFetched = time.Time{}
+				return c
+			},
+		},
+		{
+			name: "another client on the same timestamps file",
+			next: func(t *testing.T, c *Client, opts ClientOpts) *Client {
+				c, err := New(opts)
+				if err != nil {
+					t.Fatal(err)
+				}
+				return c
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			previous, next := testKey(t), testKey(t)
+			retired := time.Now().Add(-time.Hour)
+			files := rotatedFiles(t, previous, next, &retired)
+			server := testRegistry(t, files)
+
+			opts := ClientOpts{
+				BaseURL:        server.URL,
+				TrustedKeys:    []signing.PublicKey{previous.Public(), next.Public()},
+				TimestampsFile: filepath.Join(t.TempDir(), "timestamps.json"),
+			}
+			c, err := New(opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := c.FetchVerified(t.Context(), "index.json"); err != nil {
+				t.Fatal(err)
+			}
+
+			// the retired key signs trusted keys dropping its retirement, and an index
+			forged := rotatedFiles(t, previous, next, nil)
+			forged["keys.json.sig"] = previous.Sign(forged["keys.json"], "keys.json")
+			forged["index.json.sig"] = previous.Sign(forged["index.json"], "index.json")
+			maps.Copy(files, forged)
+
+			_, err = tt.next(t, c, opts).FetchVerified(t.Context(), "index.json")
+			if err == nil {
+				t.Fatal("expected the retired key to stay retired")
+			}
+		})
+	}
+}
+
+func TestTrustedKeysReplay(t *testing.T) {
+	previous, next := testKey(t), testKey(t)
+	retires := time.Now().Add(time.Hour)
+	server := testRegistry(t, rotatedFiles(t, previous, next, &retires))
+	timestamps := filepath.Join(t.TempDir(), "timestamps.json")
+
+	// a later run accepted trusted keys signed after the ones served now
+	later := fmt.Sprintf(`{"keys.json":%d}`, time.Now().Add(time.Hour).Unix())
+	if err := os.WriteFile(timestamps, []byte(later), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	_, err := testClient(t, server.URL, previous, timestamps).FetchVerified(
+		t.Context(),
+		"index.json",
+	)
+	if !errors.Is(err, ErrStaleSignature) {
+		t.Fatalf("got %v, want %v", err, ErrStaleSignature)
+	}
+}
@@ -0,0 +1,99 @@
+package client
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/omniviewdev/registry-cli/pkg/signing"
+)
+
+// TrustConfig pins the keys that each registry's indexes must be signed with.
+type TrustConfig struct {
+	// RequireSignatures rejects unsigned indexes even from registries without pinned keys
+	RequireSignatures bool `mapstructure:"require_signatures" yaml:"require_signatures"`
+
+	// Registries lists the pinned keys per registry
+	Registries []RegistryTrust `mapstructure:"registries" yaml:"registries"`
+}
+
+// RegistryTrust pins the keys trusted for a single registry.
+type RegistryTrust struct {
+	// URL is the base URL of the registry
+	URL string `mapstructure:"url" yaml:"url"`
+
+	// Keys are the trusted public keys, either inline (base64) or paths to public key files
+	Keys []string `mapstructure:"keys" yaml:"keys"`
+}
+
+// KeysFor returns the pinned keys for the registry at url, along with the extra keys trusted
+// for it, e.g. from the command line. An error is returned if a pinned key can't be loaded, or
+// when signatures are required and no keys are pinned nor given.
+func (t TrustConfig) KeysFor(url string, extra ...signing.PublicKey) ([]signing.PublicKey, error) {
+	keys := slices.Clone(extra)
+	for _, registry := range t.Registries {
+		if normalizeURL(registry.URL) != normalizeURL(url) {
+			continue
+		}
+		for _, raw := range registry.Keys {
+			key, err := parseKey(raw)
+			if err != nil {
+				return nil, fmt.Errorf("invalid trusted key for %s: %w", url, err)
+			}
+			keys = append(keys, key)
+		}
+	}
+
+	if len(keys) == 0 && t.RequireSignatures {
+		return nil, fmt.Errorf(
+			"signatures are required but no trusted keys are configured or given for %s",
+			url,
+		)
+	}
+	return keys, nil
+}
+
+func parseKey(raw string) (signing.PublicKey, error) {
+	if key, err := signing.ParsePublicKey(raw); err == nil {
+		return key, nil
+	}
+	if _, err := os.Stat(raw); err == nil {
+		return signing.LoadPublicKey(raw)
+	}
+	return signing.PublicKey{}, fmt.Errorf("%q is neither a public key nor a key file", raw)
+}
+
+func normalizeURL(url string) string {
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(url)), "/")
+}
+
+// ChecksumError is returned when an artifact doesn't match the checksum it's verified against.
+type ChecksumError struct {
+	Expected string
+	Actual   string
+}
+
+func (e *ChecksumError) Error() string {
+	return fmt.Sprintf("checksum mismatch: expected %s, got %s", e.Expected, e.Actual)
+}
+
+// VerifyChecksum reads r to the end and checks its sha256 checksum matches expected.
+func VerifyChecksum(r io.Reader, expected string) error {
+	if expected == "" {
+		return errors.New("no checksum to verify against")
+	}
+	h := sha256.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return fmt.Errorf("couldn't read artifact: %w", err)
+	}
+	actual := hex.EncodeToString(h.Sum(nil))
+	if !strings.EqualFold(actual, expected) {
+		return &ChecksumError{Expected: expected, Actual: actual}
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/omniviewdev/registry-cli/pkg/signing"
+)
+
+func TestKeysFor(t *testing.T) {
+	pinned := testKey(t).Public()
+	given := testKey(t).Public()
+	trust := TrustConfig{Registries: []RegistryTrust{{
+		URL:  "https://registry.example.com/",
+		Keys: []string{pinned.String()},
+	}}}
+	required := TrustConfig{RequireSignatures: true}
+
+	tests := []struct {
+		name    string
+		trust   TrustConfig
+		url     string
+		given   []signing.PublicKey
+		want    int
+		wantErr bool
+	}{
+		{name: "pinned", trust: trust, url: "https://REGISTRY.example.com", want: 1},
+		{name: "pinned and given", trust: trust, url: "https://registry.example.com",
+			given: []signing.PublicKey{given}, want: 2},
+		{name: "other registry", trust: trust, url: "https://other.example.com", want: 0},
+		{name: "required without keys", trust: required, url: "https://other.example.com",
+			wantErr: true},
+		{name: "required with given keys", trust: required, url: "https://other.example.com",
+			given: []signing.PublicKey{given}, want: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keys, err := tt.trust.KeysFor(tt.url, tt.given...)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(keys) != tt.want {
+				t.Fatalf("got %d keys, want %d", len(keys), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/omniviewdev/registry-cli/pkg/types"
+)
+
+// DefaultWatchInterval is how often the registry is polled for new releases
+const DefaultWatchInterval = time.Minute
+
+// Release is a version that appeared in the registry while watching it.
+type Release struct {
+	Plugin  string    `json:"plugin"`
+	Version string    `json:"version"`
+	Seen    time.Time `json:"seen"`
+}
+
+// WatchOpts configures a Watch.
+type WatchOpts struct {
+	// Plugins limits the watch to these plugins, noticing every new version of them. When
+	// empty, every plugin in the registry is watched for new latest versions.
+	Plugins []string
+
+	// Interval is how often the registry is polled. Defaults to DefaultWatchInterval.
+	Interval time.Duration
+}
+
+// Watch polls the registry until the context is done, calling fn for each version that
+// appears after the first poll. Errors polling the registry are passed to onError, and the
+// watch carries on with the next poll.
+func (c *Client) Watch(
+	ctx context.Context,
+	opts WatchOpts,
+	fn func(Release) error,
+	onError func(error),
+) error {
+	if opts.Interval <= 0 {
+		opts.Interval = DefaultWatchInterval
+	}
+
+	var seen map[string][]string
+	ticker := time.NewTicker(opts.Interval)
+	defer ticker.Stop()
+
+	for {
+		current, err := c.versions(ctx, opts.Plugins)
+		switch {
+		case err != nil && ctx.Err() == nil:
+			onError(err)
+		case err == nil && seen == nil:
+			// the first poll is the baseline
+			seen = current
+		case err == nil:
+			now := time.Now().UTC()
+			for plugin, versions := range current {
+				for _, version := range versions {
+					if slices.Contains(seen[plugin], version) {
+						continue
+					}
+					if err := fn(Release{Plugin: plugin, Version: version, Seen: now}); err != nil {
+						return err
+					}
+				}
+				seen[plugin] = versions
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// versions returns the versions of each plugin: every version of the given plugins, or the
+// latest version of every plugin in the registry.
+func (c *Client) versions(ctx context.Context, plugins []string) (map[string][]string, error) {
+	versions := make(map[string][]string)
+
+	if len(plugins) == 0 {
+		registry, err := c.RegistryIndex(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, plugin := range registry.Plugins {
+			if plugin.LatestVersion.Version != "" {
+				versions[plugin.ID] = []string{plugin.LatestVersion.Version}
+			}
+		}
+		return versions, nil
+	}
+
+	for _, plugin := range plugins {
+		index, err := c.PluginIndex(ctx, plugin)
+		if errors.Is(err, ErrNotFound) {
+			// not published yet, its first version will show up as new
+			versions[plugin] = nil
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, version := range index.Versions {
+			versions[plugin] = append(versions[plugin], version.Version)
+		}
+		slices.SortFunc(versions[plugin], types.CompareVersions)
+	}
+	return versions, nil
+}
@@ -0,0 +1,205 @@
+// Package console is where the CLI's output goes. It colors status lines when writing to a
+// terminal, and strips emoji and colors when it isn't (or NO_COLOR is set), so output stays
+// readable in CI logs and files.
+package console
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"golang.org/x/term"
+)
+
+const (
+	// ColorAuto styles output only when writing to a terminal and NO_COLOR isn't set
+	ColorAuto = "auto"
+
+	// ColorAlways always styles output
+	ColorAlways = "always"
+
+	// ColorNever never styles output
+	ColorNever = "never"
+)
+
+// ColorModes are the accepted values of --color.
+var ColorModes = []string{ColorAuto, ColorAlways, ColorNever}
+
+const (
+	red    = "\x1b[31m"
+	green  = "\x1b[32m"
+	yellow = "\x1b[33m"
+	reset  = "\x1b[0m"
+)
+
+// statusColors colors lines by the status emoji they start with
+var statusColors = map[string]string{
+	"✅":  green,
+	"❌":  red,
+	"⚠️": yellow,
+	"🆕":  green,
+}
+
+var (
+	// Stdout writes to standard output
+	Stdout = &Writer{file: os.Stdout}
+
+	// Stderr writes to standard error
+	Stderr = &Writer{file: os.Stderr}
+
+	mode = ColorAuto
+
+	quiet bool
+)
+
+// SetColorMode sets when output is styled, one of ColorModes.
+func SetColorMode(m string) error {
+	switch m {
+	case ColorAuto, ColorAlways, ColorNever:
+		mode = m
+		return nil
+	}
+	return fmt.Errorf("invalid color mode %q, must be one of %s", m, strings.Join(ColorModes, ", "))
+}
+
+// SetQuiet discards everything written to Stdout, for machine readable output written
+// straight to os.Stdout instead. Stderr is left alone.
+func SetQuiet(q bool) {
+	quiet = q
+}
+
+// Writer styles or plainifies what's written to a file.
+type Writer struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// Styled reports whether output is styled with colors and emoji.
+func (w *Writer) Styled() bool {
+	switch mode {
+	case ColorAlways:
+		return true
+	case ColorNever:
+		return false
+	}
+	if os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	return w.IsTerminal()
+}
+
+// IsTerminal reports whether the writer writes to a terminal.
+func (w *Writer) IsTerminal() bool {
+	return term.IsTerminal(int(w.file.Fd()))
+}
+
+// File returns the file written to.
+func (w *Writer) File() *os.File {
+	return w.file
+}
+
+func (w *Writer) Write(p []byte) (int, error) {
+	if quiet && w == Stdout {
+		return len(p), nil
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	var out []byte
+	if w.Styled() {
+		out = colorize(p)
+	} else {
+		out = Plain(p)
+	}
+	if _, err := w.file.Write(out); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// colorize colors each line starting with a status emoji.
+func colorize(p []byte) []byte {
+	lines := bytes.SplitAfter(p, []byte("\n"))
+	var b bytes.Buffer
+	for _, line := range lines {
+		color := ""
+		for emoji, c := range statusColors {
+			if bytes.HasPrefix(line, []byte(emoji)) {
+				color = c
+				break
+			}
+		}
+		if color == "" {
+			b.Write(line)
+			continue
+		}
+		text, newline := bytes.CutSuffix(line, []byte("\n"))
+		b.WriteString(color)
+		b.Write(text)
+		b.WriteString(reset)
+		if newline {
+			b.WriteByte('\n')
+		}
+	}
+	return b.Bytes()
+}
+
+// Plain strips ANSI escapes and emoji from p, along with the space following an emoji.
+func Plain(p []byte) []byte {
+	out := make([]byte, 0, len(p))
+	for i := 0; i < len(p); {
+		// ANSI escapes, e.g. \x1b[31m
+		if p[i] == 0x1b && i+1 < len(p) && p[i+1] == '[' {
+			j := i + 2
+			for j < len(p) && (p[j] < 0x40 || p[j] > 0x7e) {
+				j++
+			}
+			i = j + 1
+			continue
+		}
+
+		r, size := utf8.DecodeRune(p[i:])
+		if !isEmoji(r) {
+			out = append(out, p[i:i+size]...)
+			i += size
+			continue
+		}
+		i += size
+		// drop the modifiers and padding following the emoji
+		for i < len(p) {
+			r, size := utf8.DecodeRune(p[i:])
+			if r != '\ufe0f' && r != '\u200d' && !isEmoji(r) {
+				break
+			}
+			i += size
+		}
+		for i < len(p) && p[i] == ' ' {
+			i++
+		}
+	}
+	return out
+}
+
+func isEmoji(r rune) bool {
+	return (r >= 0x1f000 && r <= 0x1faff) || // pictographs, emoticons, transport...
+		(r >= 0x2600 && r <= 0x27bf) // misc symbols and dingbats (✅ ❌ ⚠)
+}
+
+// Printf formats to Stdout.
+func Printf(format string, args ...any) {
+	fmt.Fprintf(Stdout, format, args...)
+}
+
+// Println prints the args to Stdout, followed by a newline.
+func Println(args ...any) {
+	fmt.Fprintln(Stdout, args...)
+}
+
+// Print prints the args to Stdout.
+func Print(args ...any) {
+	fmt.Fprint(Stdout, args...)
+}
@@ -0,0 +1,148 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// corsRuleID identifies the CORS rule managed by the registry, so other rules on the bucket are
+// left alone
+const corsRuleID = "omniview-registry"
+
+var (
+	// corsMethods are the methods the Omniview web UI reads the registry with
+	corsMethods = []string{"GET", "HEAD"}
+
+	// corsExposedHeaders are the response headers the Omniview web UI reads, for caching and
+	// resuming tarball downloads
+	corsExposedHeaders = []string{"ETag", "Content-Length", "Content-Range", "Accept-Ranges"}
+)
+
+// registryCORSRule is the CORS rule letting browsers on the origins read the indexes and
+// tarballs.
+func registryCORSRule(origins []string) s3types.CORSRule {
+	return s3types.CORSRule{
+		ID:             aws.String(corsRuleID),
+		AllowedMethods: corsMethods,
+		AllowedOrigins: origins,
+		AllowedHeaders: []string{"*"},
+		ExposeHeaders:  corsExposedHeaders,
+		MaxAgeSeconds:  aws.Int32(3000),
+	}
+}
+
+// corsRules returns the CORS rules of the bucket, or none if it has no CORS configuration.
+func (i *Indexer) corsRules(ctx context.Context, bucket bucketStore) ([]s3types.CORSRule, error) {
+	rules, err := bucket.CORSRules(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't get CORS rules of %s: %v", i.bucket, err)
+	}
+	return rules, nil
+}
+
+// ApplyCORS sets the CORS rule that lets the Omniview web UI on the origins read the registry
+// straight from the bucket. Any other CORS rules on the bucket are kept.
+func (i *Indexer) ApplyCORS(ctx context.Context, origins []string) error {
+	if len(origins) == 0 {
+		origins = []string{"*"}
+	}
+
+	bucket, err := storeFeature[bucketStore](i.objects, "configuring CORS")
+	if err != nil {
+		return err
+	}
+	rules, err := i.corsRules(ctx, bucket)
+	if err != nil {
+		return err
+	}
+	rules = slices.DeleteFunc(rules, func(rule s3types.CORSRule) bool {
+		return aws.ToString(rule.ID) == corsRuleID
+	})
+	rules = append(rules, registryCORSRule(origins))
+
+	if err := bucket.SetCORSRules(ctx, rules); err != nil {
+		return fmt.Errorf("couldn't configure CORS on %s: %v", i.bucket, err)
+	}
+	return nil
+}
+
+// VerifyCORS checks that the CORS rules of the bucket let the Omniview web UI on the origins
+// read the registry, returning what's missing.
+func (i *Indexer) VerifyCORS(ctx context.Context, origins []string) ([]string, error) {
+	if len(origins) == 0 {
+		origins = []string{"*"}
+	}
+
+	bucket, err := storeFeature[bucketStore](i.objects, "checking the CORS rules")
+	if err != nil {
+		return nil, err
+	}
+	rules, err := i.corsRules(ctx, bucket)
+	if err != nil {
+		return nil, err
+	}
+	if len(rules) == 0 {
+		return []string{"no CORS rules, browsers can't read the registry"}, nil
+	}
+
+	var gaps []string
+	for _, origin := range origins {
+		for _, method := range corsMethods {
+			if corsRuleFor(rules, origin, method) == nil {
+				gaps = append(gaps, fmt.Sprintf("%s isn't allowed from %s", method, origin))
+			}
+		}
+
+		rule := corsRuleFor(rules, origin, "GET")
+		if rule == nil {
+			continue
+		}
+		for _, header := range corsExposedHeaders {
+			if !slices.ContainsFunc(rule.ExposeHeaders, func(exposed string) bool {
+				return strings.EqualFold(exposed, header)
+			}) {
+				gaps = append(gaps, fmt.Sprintf("%s isn't exposed to %s", header, origin))
+			}
+		}
+	}
+	return gaps, nil
+}
+
+// corsRuleFor returns the first rule that allows the method from the origin, the same way S3
+// picks the rule to apply.
+func corsRuleFor(rules []s3types.CORSRule, origin, method string) *s3types.CORSRule {
+	for idx, rule := range rules {
+		if !slices.Contains(rule.AllowedMethods, method) {
+			continue
+		}
+		for _, allowed := range rule.AllowedOrigins {
+			if matchOrigin(allowed, origin) {
+				return &rules[idx]
+			}
+		}
+	}
+	return nil
+}
+
+// matchOrigin matches an origin against an allowed origin, which may contain one '*' wildcard
+// like S3 allows.
+func matchOrigin(allowed, origin string) bool {
+	if allowed == "*" {
+		return true
+	}
+	if origin == "*" {
+		return false
+	}
+	prefix, suffix, wildcard := strings.Cut(allowed, "*")
+	if !wildcard {
+		return allowed == origin
+	}
+	return len(origin) >= len(prefix)+len(suffix) &&
+		strings.HasPrefix(origin, prefix) &&
+		strings.HasSuffix(origin, suffix)
+}
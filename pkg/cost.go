@@ -0,0 +1,131 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+const bytesPerGB = 1 << 30
+
+// Pricing is the price of storage and egress used to estimate the cost of a registry, in
+// dollars.
+type Pricing struct {
+	// Storage is the price per GB-month of each storage class
+	Storage map[string]float64 `mapstructure:"storage" yaml:"storage"`
+
+	// Egress is the price per GB downloaded
+	Egress float64 `mapstructure:"egress" yaml:"egress"`
+}
+
+// DefaultPricing returns the S3 list prices of us-east-1.
+func DefaultPricing() Pricing {
+	return Pricing{
+		Storage: map[string]float64{
+			string(s3types.StorageClassStandard):           0.023,
+			string(s3types.StorageClassIntelligentTiering): 0.023,
+			string(s3types.StorageClassReducedRedundancy):  0.024,
+			string(s3types.StorageClassStandardIa):         0.0125,
+			string(s3types.StorageClassOnezoneIa):          0.01,
+			string(s3types.StorageClassGlacierIr):          0.004,
+			string(s3types.StorageClassGlacier):            0.0036,
+			string(s3types.StorageClassDeepArchive):        0.00099,
+		},
+		Egress: 0.09,
+	}
+}
+
+// PluginUsage is the storage used by a plugin (or other top level folder of the registry).
+type PluginUsage struct {
+	// Plugin is the plugin ID, or the folder name for registry files
+	Plugin string
+
+	// Objects is the number of objects stored
+	Objects int
+
+	// Bytes is the number of bytes stored in each storage class
+	Bytes map[string]int64
+
+	// DownloadBytes is the average size of the latest version's artifacts, i.e. what a single
+	// download of the plugin costs in egress
+	DownloadBytes int64
+}
+
+// TotalBytes is the number of bytes stored across all storage classes.
+func (u PluginUsage) TotalBytes() int64 {
+	var total int64
+	for _, b := range u.Bytes {
+		total += b
+	}
+	return total
+}
+
+// StorageCost estimates the monthly storage cost.
+func (u PluginUsage) StorageCost(pricing Pricing) float64 {
+	var cost float64
+	for class, b := range u.Bytes {
+		price, ok := pricing.Storage[class]
+		if !ok {
+			price = pricing.Storage[string(s3types.StorageClassStandard)]
+		}
+		cost += float64(b) / bytesPerGB * price
+	}
+	return cost
+}
+
+// EgressCost estimates the monthly egress cost for the given number of downloads.
+func (u PluginUsage) EgressCost(pricing Pricing, downloads int) float64 {
+	return float64(u.DownloadBytes) * float64(downloads) / bytesPerGB * pricing.Egress
+}
+
+// Usage lists the bucket and sums the size of the objects per plugin and storage class. Files
+// at the root of the bucket are grouped under "(registry)".
+func (i *Indexer) Usage(ctx context.Context) ([]PluginUsage, error) {
+	usage := make(map[string]*PluginUsage)
+
+	objects, err := i.objects.List(ctx, "")
+	if err != nil {
+		return nil, fmt.Errorf("couldn't list bucket %s: %v", i.bucket, err)
+	}
+	for _, object := range objects {
+		plugin, _, ok := strings.Cut(object.Key, "/")
+		if !ok {
+			plugin = "(registry)"
+		}
+		u, ok := usage[plugin]
+		if !ok {
+			u = &PluginUsage{Plugin: plugin, Bytes: make(map[string]int64)}
+			usage[plugin] = u
+		}
+		u.Objects++
+		u.Bytes[string(object.StorageClass)] += object.Size
+	}
+
+	registry, err := i.getRegistryIndex(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, plugin := range registry.Plugins {
+		u, ok := usage[plugin.ID]
+		if !ok || len(plugin.LatestVersion.Architectures) == 0 {
+			continue
+		}
+		var total int64
+		for _, info := range plugin.LatestVersion.Architectures {
+			total += info.Size
+		}
+		u.DownloadBytes = total / int64(len(plugin.LatestVersion.Architectures))
+	}
+
+	result := make([]PluginUsage, 0, len(usage))
+	for _, u := range usage {
+		result = append(result, *u)
+	}
+	sort.Slice(result, func(a, b int) bool {
+		return result[a].TotalBytes() > result[b].TotalBytes()
+	})
+	return result, nil
+}
@@ -0,0 +1,357 @@
+// Package encryption encrypts the tarballs of private registries before they're uploaded, for
+// registries distributing proprietary plugins over storage that isn't trusted with them, and
+// decrypts them on install. Tarballs are encrypted in the age format, so they can also be
+// decrypted with the age tool when encrypted to age keys. The key of each tarball is encrypted to
+// every recipient it's encrypted to, any of:
+//
+//   - an age X25519 public key (age1...), decrypted with its age identity file
+//   - a key shared by the publishers and installers, 32 random bytes hex encoded in a file (e.g.
+//     made with openssl rand -hex 32), encrypting it with AES-256-GCM
+//   - an AWS KMS key (kms:<key id, ARN or alias>), the key of the tarball being a data key the
+//     KMS key encrypts, decrypted with the AWS credentials of the installer
+package encryption
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"filippo.io/age"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+)
+
+// Format is the format encrypted tarballs are in, recorded by the indexes for their builds.
+const Format = "age"
+
+// KMSPrefix prefixes the AWS KMS keys tarballs are encrypted to.
+const KMSPrefix = "kms:"
+
+const (
+	// sharedKeyStanza is the type of the age stanzas holding the key of a tarball encrypted
+	// with a shared key
+	sharedKeyStanza = "registry-aes-gcm"
+
+	// kmsStanza is the type of the age stanzas holding the key of a tarball encrypted with an
+	// AWS KMS key
+	kmsStanza = "registry-kms"
+
+	// sharedKeySize is the size of shared keys, AES-256 keys
+	sharedKeySize = 32
+)
+
+// kmsContext is the encryption context of the keys encrypted with KMS, which AWS CloudTrail
+// records their decryptions with
+var kmsContext = map[string]string{"purpose": "registry-cli"}
+
+// ErrNoKey is returned when a tarball isn't encrypted to any of the keys decrypting it.
+var ErrNoKey = errors.New("none of the decryption keys can decrypt it")
+
+// Encrypter encrypts tarballs to a set of recipients.
+type Encrypter struct {
+	recipients []age.Recipient
+}
+
+// NewEncrypter creates an Encrypter for the recipients: age public keys (age1...), AWS KMS keys
+// (kms:<key id, ARN or alias>) and files holding either a shared key or age public keys, one
+// per line.
+func NewEncrypter(ctx context.Context, recipients []string) (*Encrypter, error) {
+	if len(recipients) == 0 {
+		return nil, errors.New("no recipients to encrypt to")
+	}
+	e := &Encrypter{}
+	var client *kms.Client
+	for _, recipient := range recipients {
+		switch {
+		case strings.HasPrefix(recipient, "age1"):
+			r, err := age.ParseX25519Recipient(recipient)
+			if err != nil {
+				return nil, fmt.Errorf("invalid recipient %s: %w", recipient, err)
+			}
+			e.recipients = append(e.recipients, r)
+		case strings.HasPrefix(recipient, KMSPrefix):
+			keyID := strings.TrimPrefix(recipient, KMSPrefix)
+			if keyID == "" || strings.ContainsAny(keyID, " \t\n") {
+				return nil, fmt.Errorf("invalid KMS key %q", keyID)
+			}
+			if client == nil {
+				var err error
+				if client, err = newKMSClient(ctx); err != nil {
+					return nil, err
+				}
+			}
+			e.recipients = append(e.recipients, &kmsRecipient{ctx: ctx, client: client, keyID: keyID})
+		default:
+			fileRecipients, err := loadRecipients(recipient)
+			if err != nil {
+				return nil, err
+			}
+			e.recipients = append(e.recipients, fileRecipients...)
+		}
+	}
+	return e, nil
+}
+
+// loadRecipients reads a file holding a shared key or age public keys.
+func loadRecipients(path string) ([]age.Recipient, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't read recipients: %w", err)
+	}
+	if key, ok := parseSharedKey(b); ok {
+		return []age.Recipient{key}, nil
+	}
+	recipients, err := age.ParseRecipients(bytes.NewReader(b))
+	if err != nil {
+		return nil, fmt.Errorf(
+			"%s holds neither a shared key nor age public keys: %w",
+			path,
+			err,
+		)
+	}
+	return recipients, nil
+}
+
+// EncryptFile encrypts the file at src into dst.
+func (e *Encrypter) EncryptFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("couldn't open %s: %w", src, err)
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return fmt.Errorf("couldn't create %s: %w", dst, err)
+	}
+	defer out.Close()
+
+	w, err := age.Encrypt(out, e.recipients...)
+	if err != nil {
+		return fmt.Errorf("couldn't encrypt %s: %w", src, err)
+	}
+	if _, err := io.Copy(w, in); err != nil {
+		return fmt.Errorf("couldn't encrypt %s: %w", src, err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("couldn't encrypt %s: %w", src, err)
+	}
+	return out.Close()
+}
+
+// Decrypter decrypts tarballs with a set of keys.
+type Decrypter struct {
+	identities []age.Identity
+}
+
+// NewDecrypter creates a Decrypter for the keys: files holding either a shared key or age
+// identities (AGE-SECRET-KEY-1...), one per line. Tarballs encrypted to AWS KMS keys are also
+// decrypted, with the default AWS credentials, when none of the keys decrypt them.
+func NewDecrypter(ctx context.Context, keys []string) (*Decrypter, error) {
+	d := &Decrypter{}
+	for _, path := range keys {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't read decryption key: %w", err)
+		}
+		if key, ok := parseSharedKey(b); ok {
+			d.identities = append(d.identities, key)
+			continue
+		}
+		identities, err := age.ParseIdentities(bytes.NewReader(b))
+		if err != nil {
+			return nil, fmt.Errorf(
+				"%s holds neither a shared key nor age identities: %w",
+				path,
+				err,
+			)
+		}
+		d.identities = append(d.identities, identities...)
+	}
+	// last, as it fails the decryption when KMS can't be reached
+	d.identities = append(d.identities, &kmsIdentity{ctx: ctx})
+	return d, nil
+}
+
+// Decrypt returns a reader decrypting the encrypted tarball read from src.
+func (d *Decrypter) Decrypt(src io.Reader) (io.Reader, error) {
+	r, err := age.Decrypt(src, d.identities...)
+	var noMatch *age.NoIdentityMatchError
+	if errors.As(err, &noMatch) {
+		return nil, ErrNoKey
+	}
+	return r, err
+}
+
+// DecryptFile decrypts the encrypted tarball at src into dst.
+func (d *Decrypter) DecryptFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("couldn't open %s: %w", src, err)
+	}
+	defer in.Close()
+
+	r, err := d.Decrypt(bufio.NewReader(in))
+	if err != nil {
+		return fmt.Errorf("couldn't decrypt %s: %w", src, err)
+	}
+	out, err := os.Create(dst)
+	if err != nil {
+		return fmt.Errorf("couldn't create %s: %w", dst, err)
+	}
+	defer out.Close()
+
+	if _, err := io.Copy(out, r); err != nil {
+		out.Close()
+		os.Remove(dst)
+		return fmt.Errorf("couldn't decrypt %s: %w", src, err)
+	}
+	return out.Close()
+}
+
+// sharedKey is a key shared by the publishers and installers, encrypting the keys of tarballs
+// with AES-256-GCM. Stanzas name the key they're encrypted with by its ID, the start of its
+// sha256 hash.
+type sharedKey struct {
+	id  string
+	key []byte
+}
+
+// parseSharedKey parses the contents of a shared key file, 32 bytes hex encoded.
+func parseSharedKey(b []byte) (*sharedKey, bool) {
+	key, err := hex.DecodeString(strings.TrimSpace(string(b)))
+	if err != nil || len(key) != sharedKeySize {
+		return nil, false
+	}
+	sum := sha256.Sum256(key)
+	return &sharedKey{id: hex.EncodeToString(sum[:8]), key: key}, true
+}
+
+func (k *sharedKey) aead() (cipher.AEAD, error) {
+	block, err := aes.NewCipher(k.key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func (k *sharedKey) Wrap(fileKey []byte) ([]*age.Stanza, error) {
+	aead, err := k.aead()
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	body := aead.Seal(nonce, nonce, fileKey, []byte(sharedKeyStanza))
+	return []*age.Stanza{{Type: sharedKeyStanza, Args: []string{k.id}, Body: body}}, nil
+}
+
+func (k *sharedKey) Unwrap(stanzas []*age.Stanza) ([]byte, error) {
+	aead, err := k.aead()
+	if err != nil {
+		return nil, err
+	}
+	for _, stanza := range stanzas {
+		if stanza.Type != sharedKeyStanza || len(stanza.Args) != 1 || stanza.Args[0] != k.id {
+			continue
+		}
+		if len(stanza.Body) < aead.NonceSize() {
+			return nil, errors.New("invalid shared key stanza")
+		}
+		nonce, sealed := stanza.Body[:aead.NonceSize()], stanza.Body[aead.NonceSize():]
+		fileKey, err := aead.Open(nil, nonce, sealed, []byte(sharedKeyStanza))
+		if err != nil {
+			return nil, fmt.Errorf("couldn't decrypt with shared key %s: %w", k.id, err)
+		}
+		return fileKey, nil
+	}
+	return nil, age.ErrIncorrectIdentity
+}
+
+// kmsRecipient encrypts the keys of tarballs with an AWS KMS key.
+type kmsRecipient struct {
+	ctx    context.Context
+	client *kms.Client
+	keyID  string
+}
+
+func (r *kmsRecipient) Wrap(fileKey []byte) ([]*age.Stanza, error) {
+	out, err := r.client.Encrypt(r.ctx, &kms.EncryptInput{
+		KeyId:             aws.String(r.keyID),
+		Plaintext:         fileKey,
+		EncryptionContext: kmsContext,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("couldn't encrypt with KMS key %s: %w", r.keyID, err)
+	}
+	return []*age.Stanza{{Type: kmsStanza, Args: []string{r.keyID}, Body: out.CiphertextBlob}}, nil
+}
+
+// kmsIdentity decrypts the keys of tarballs encrypted with AWS KMS keys. Its client is only
+// created once a tarball encrypted with KMS is decrypted.
+type kmsIdentity struct {
+	ctx context.Context
+
+	once   sync.Once
+	client *kms.Client
+	err    error
+}
+
+func (i *kmsIdentity) Unwrap(stanzas []*age.Stanza) ([]byte, error) {
+	var errs []error
+	for _, stanza := range stanzas {
+		if stanza.Type != kmsStanza || len(stanza.Args) != 1 {
+			continue
+		}
+		i.once.Do(func() {
+			i.client, i.err = newKMSClient(i.ctx)
+		})
+		if i.err != nil {
+			return nil, i.err
+		}
+		out, err := i.client.Decrypt(i.ctx, &kms.DecryptInput{
+			KeyId:             aws.String(stanza.Args[0]),
+			CiphertextBlob:    stanza.Body,
+			EncryptionContext: kmsContext,
+		})
+		if err != nil {
+			// the tarball may be encrypted to several KMS keys, not all of them usable
+			errs = append(errs, fmt.Errorf(
+				"couldn't decrypt with KMS key %s: %w",
+				stanza.Args[0],
+				err,
+			))
+			continue
+		}
+		return out.Plaintext, nil
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return nil, age.ErrIncorrectIdentity
+}
+
+func newKMSClient(ctx context.Context) (*kms.Client, error) {
+	cfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"couldn't load the AWS configuration for KMS, have you set up your AWS account? %w",
+			err,
+		)
+	}
+	return kms.NewFromConfig(cfg), nil
+}
@@ -0,0 +1,236 @@
+package encryption
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"filippo.io/age"
+)
+
+// writeFile writes a file into the test's temporary directory, returning its path.
+func writeFile(t *testing.T, name string, b []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, b, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// ageKey generates an age identity, returning its public key and the path of its identity file.
+func ageKey(t *testing.T) (string, string) {
+	t.Helper()
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return identity.Recipient().String(), writeFile(t, "key.txt", []byte(identity.String()+"\n"))
+}
+
+// sharedKeyFile writes a random shared key file, returning its path.
+func sharedKeyFile(t *testing.T) string {
+	t.Helper()
+	key := make([]byte, sharedKeySize)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatal(err)
+	}
+	return writeFile(t, "shared.key", []byte(hex.EncodeToString(key)+"\n"))
+}
+
+// fakeKMS serves the Encrypt and Decrypt operations of AWS KMS for the default AWS
+// configuration, "encrypting" by prefixing the plaintext with the key ID.
+func fakeKMS(t *testing.T) {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			KeyId             string
+			Plaintext         []byte
+			CiphertextBlob    []byte
+			EncryptionContext map[string]string
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if req.EncryptionContext["purpose"] != "registry-cli" {
+			http.Error(w, "unexpected encryption context", http.StatusBadRequest)
+			return
+		}
+		var resp any
+		switch r.Header.Get("X-Amz-Target") {
+		case "TrentService.Encrypt":
+			blob := append([]byte(req.KeyId+":"), req.Plaintext...)
+			resp = map[string]any{"KeyId": req.KeyId, "CiphertextBlob": blob}
+		case "TrentService.Decrypt":
+			plaintext, ok := bytes.CutPrefix(req.CiphertextBlob, []byte(req.KeyId+":"))
+			if !ok {
+				w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(`{"__type":"IncorrectKeyException","message":"wrong key"}`))
+				return
+			}
+			resp = map[string]any{"KeyId": req.KeyId, "Plaintext": plaintext}
+		default:
+			http.Error(w, "unexpected operation", http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+		json.NewEncoder(w).Encode(resp)
+	}))
+	t.Cleanup(server.Close)
+	t.Setenv("AWS_ENDPOINT_URL", server.URL)
+	t.Setenv("AWS_ACCESS_KEY_ID", "test")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "test")
+	t.Setenv("AWS_REGION", "us-east-1")
+	t.Setenv("AWS_CONFIG_FILE", filepath.Join(t.TempDir(), "none"))
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", filepath.Join(t.TempDir(), "none"))
+}
+
+// roundTrip encrypts the plaintext to the recipients and decrypts it with the keys.
+func roundTrip(t *testing.T, plaintext []byte, recipients, keys []string) ([]byte, error) {
+	t.Helper()
+	ctx := t.Context()
+	encrypter, err := NewEncrypter(ctx, recipients)
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := writeFile(t, "plugin.tar.gz", plaintext)
+	encrypted := filepath.Join(t.TempDir(), "plugin.tar.gz.age")
+	if err := encrypter.EncryptFile(src, encrypted); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(encrypted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(b, plaintext) {
+		t.Fatal("the encrypted build holds the plaintext")
+	}
+
+	decrypter, err := NewDecrypter(ctx, keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decrypted := filepath.Join(t.TempDir(), "plugin.tar.gz")
+	if err := decrypter.DecryptFile(encrypted, decrypted); err != nil {
+		return nil, err
+	}
+	return os.ReadFile(decrypted)
+}
+
+func TestRoundTrip(t *testing.T) {
+	fakeKMS(t)
+	plaintext := []byte(strings.Repeat("plugin build ", 1000))
+	ageRecipient, ageIdentity := ageKey(t)
+	otherRecipient, otherIdentity := ageKey(t)
+	recipientsFile := writeFile(t, "recipients.txt", []byte(ageRecipient+"\n"+otherRecipient+"\n"))
+	shared := sharedKeyFile(t)
+
+	tests := []struct {
+		name       string
+		recipients []string
+		keys       []string
+		wantErr    error
+	}{
+		{name: "age", recipients: []string{ageRecipient}, keys: []string{ageIdentity}},
+		{name: "recipients file", recipients: []string{recipientsFile}, keys: []string{otherIdentity}},
+		{name: "shared key", recipients: []string{shared}, keys: []string{shared}},
+		{name: "kms", recipients: []string{KMSPrefix + "alias/registry"}},
+		{name: "any recipient", recipients: []string{ageRecipient, shared},
+			keys: []string{shared}},
+		{name: "any key", recipients: []string{shared},
+			keys: []string{ageIdentity, shared}},
+		{name: "wrong age key", recipients: []string{ageRecipient}, keys: []string{otherIdentity},
+			wantErr: ErrNoKey},
+		{name: "wrong shared key", recipients: []string{shared}, keys: []string{sharedKeyFile(t)},
+			wantErr: ErrNoKey},
+		{name: "no key", recipients: []string{ageRecipient}, wantErr: ErrNoKey},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decrypted, err := roundTrip(t, plaintext, tt.recipients, tt.keys)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("got %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(decrypted, plaintext) {
+				t.Fatal("the decrypted build differs from the build encrypted")
+			}
+		})
+	}
+}
+
+func TestDecryptTampered(t *testing.T) {
+	ctx := t.Context()
+	shared := sharedKeyFile(t)
+	encrypter, err := NewEncrypter(ctx, []string{shared})
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := writeFile(t, "plugin.tar.gz", []byte("plugin build"))
+	encrypted := filepath.Join(t.TempDir(), "plugin.tar.gz.age")
+	if err := encrypter.EncryptFile(src, encrypted); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(encrypted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b[len(b)-1] ^= 0xff
+	tampered := writeFile(t, "tampered.age", b)
+
+	decrypter, err := NewDecrypter(ctx, []string{shared})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dst := filepath.Join(t.TempDir(), "plugin.tar.gz")
+	if err := decrypter.DecryptFile(tampered, dst); err == nil {
+		t.Fatal("expected a tampered build to be refused")
+	}
+	if _, err := os.Stat(dst); err == nil {
+		t.Fatal("a tampered build was decrypted")
+	}
+}
+
+func TestNewEncrypter(t *testing.T) {
+	ctx := t.Context()
+	invalid := []struct {
+		name       string
+		recipients []string
+	}{
+		{name: "none"},
+		{name: "invalid age key", recipients: []string{"age1nope"}},
+		{name: "empty KMS key", recipients: []string{KMSPrefix}},
+		{name: "missing file", recipients: []string{filepath.Join(t.TempDir(), "missing")}},
+		{name: "neither", recipients: []string{writeFile(t, "junk", []byte("junk"))}},
+	}
+	for _, tt := range invalid {
+		if _, err := NewEncrypter(ctx, tt.recipients); err == nil {
+			t.Errorf("%s: expected the recipients to be refused", tt.name)
+		}
+	}
+
+	_, identity := ageKey(t)
+	for _, keys := range [][]string{{identity}, {sharedKeyFile(t)}} {
+		if _, err := NewDecrypter(ctx, keys); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := NewDecrypter(ctx, []string{writeFile(t, "junk", []byte("junk"))}); err == nil {
+		t.Fatal("expected a junk key to be refused")
+	}
+}
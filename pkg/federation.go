@@ -0,0 +1,23 @@
+package pkg
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/omniviewdev/registry-cli/pkg/console"
+	"github.com/omniviewdev/registry-cli/pkg/types"
+)
+
+// PublishFederation uploads a federated index of several registries to the root of the bucket,
+// signed when the indexer has a signing key.
+func (i *Indexer) PublishFederation(ctx context.Context, index types.FederationIndex) error {
+	b, err := json.Marshal(index)
+	if err != nil {
+		return fmt.Errorf("failed to upload federated index: %v", err)
+	}
+
+	console.Printf("uploading federated index to %s...\n", types.FederationPath)
+	_, err = i.storeSigned(ctx, b, types.FederationPath)
+	return err
+}
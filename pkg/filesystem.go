@@ -0,0 +1,376 @@
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// fileScheme prefixes the buckets given as file:// URLs
+const fileScheme = "file://"
+
+// fileInternalPrefix prefixes the files the filesystem store keeps in a registry directory for
+// itself, such as the temporary files objects are written to, which aren't objects
+const fileInternalPrefix = ".registry-"
+
+// fileStore is the ObjectStore of a directory of the local filesystem, the bucket of the
+// registry being the directory (a path, or a file:// URL) holding the objects at their keys.
+// The registry can then be published to without any cloud credentials, and served by any static
+// file server. Files are written to a temporary file renamed into place, so readers never see a
+// partial object, and PutIfAbsent links the file into place, which fails if it exists. A
+// directory has no counterpart for the settings of buckets, the versions of objects or their
+// storage classes, which fail with ErrNotSupported.
+type fileStore struct {
+	root string
+}
+
+// newFileStore returns the ObjectStore of the directory of the bucket.
+func newFileStore(bucket string) (*fileStore, error) {
+	root := fileBucketDir(bucket)
+	if root == "" {
+		return nil, errors.New("no registry directory given, give its path as the bucket")
+	}
+	return &fileStore{root: root}, nil
+}
+
+// fileBucketDir returns the directory of a bucket given as a path or a file:// URL.
+func fileBucketDir(bucket string) string {
+	if dir, ok := strings.CutPrefix(bucket, fileScheme); ok {
+		if u, err := url.Parse(bucket); err == nil && u.Host == "" {
+			dir = u.Path
+		}
+		bucket = dir
+	}
+	return filepath.FromSlash(bucket)
+}
+
+// fileKeyValid reports whether a key names a file within the bucket, and not one of the files
+// of the store.
+func fileKeyValid(key string) bool {
+	if !filepath.IsLocal(filepath.FromSlash(key)) {
+		return false
+	}
+	return !slices.ContainsFunc(strings.Split(key, "/"), func(segment string) bool {
+		return strings.HasPrefix(segment, fileInternalPrefix)
+	})
+}
+
+// file returns the file of the object at key.
+func (s *fileStore) file(key string) (string, error) {
+	if !fileKeyValid(key) {
+		return "", fmt.Errorf("invalid key %q", key)
+	}
+	return filepath.Join(s.root, filepath.FromSlash(key)), nil
+}
+
+// checkRoot fails when the directory of the registry doesn't exist. Writes don't create it, so
+// a mistyped path isn't published to.
+func (s *fileStore) checkRoot() error {
+	info, err := os.Stat(s.root)
+	if errors.Is(err, fs.ErrNotExist) || (err == nil && !info.IsDir()) {
+		return fmt.Errorf("the registry directory %s doesn't exist", s.root)
+	}
+	return err
+}
+
+func (s *fileStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	name, err := s.file(key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, fileNotFound(err, key)
+	}
+	info, err := f.Stat()
+	if err == nil && info.IsDir() {
+		err = fs.ErrNotExist
+	}
+	if err != nil {
+		f.Close()
+		return nil, fileNotFound(err, key)
+	}
+	return f, nil
+}
+
+func (s *fileStore) Put(ctx context.Context, key string, b []byte, opts PutOptions) error {
+	_, err := s.write(key, bytes.NewReader(b), false, nil)
+	return err
+}
+
+func (s *fileStore) PutIfAbsent(ctx context.Context, key string, b []byte) (string, error) {
+	return s.write(key, bytes.NewReader(b), true, nil)
+}
+
+// Upload writes the object like Put, hashing it as it's written, the part size being of no use
+// for a file. An object that isn't the size given, or doesn't match the checksum, isn't put in
+// place.
+func (s *fileStore) Upload(
+	ctx context.Context,
+	key string,
+	body io.ReadSeeker,
+	size int64,
+	opts UploadOptions,
+) (string, error) {
+	if opts.StorageClass != "" && opts.StorageClass != s3types.StorageClassStandard {
+		return "", unsupported(
+			ProviderFile,
+			"storing objects in the "+string(opts.StorageClass)+" class",
+		)
+	}
+
+	hash := sha256.New()
+	var checksum string
+	_, err := s.write(key, io.TeeReader(body, hash), false, func(written int64) error {
+		if written != size {
+			return fmt.Errorf("wrote %d bytes of %s, expected %d", written, key, size)
+		}
+		checksum = hex.EncodeToString(hash.Sum(nil))
+		if opts.Checksum != "" && !strings.EqualFold(opts.Checksum, checksum) {
+			return fmt.Errorf(
+				"checksum mismatch for %s: expected %s, wrote %s",
+				key,
+				opts.Checksum,
+				checksum,
+			)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return checksum, nil
+}
+
+// write writes the content of an object to a temporary file renamed over its file, or linked
+// into place when exclusive, which fails with ErrPreconditionFailed if the file exists. The
+// temporary file is checked with verify, when given, before it's put in place. It returns the
+// entity tag of the file written.
+func (s *fileStore) write(
+	key string,
+	body io.Reader,
+	exclusive bool,
+	verify func(written int64) error,
+) (string, error) {
+	name, err := s.file(key)
+	if err != nil {
+		return "", err
+	}
+	if err := s.checkRoot(); err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		return "", err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(name), fileInternalPrefix+"tmp-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+	written, err := io.Copy(tmp, body)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return "", err
+	}
+	if verify != nil {
+		if err := verify(written); err != nil {
+			return "", err
+		}
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return "", err
+	}
+	// the file linked or renamed is the same file, described before another writer replaces it
+	info, err := os.Stat(tmp.Name())
+	if err != nil {
+		return "", err
+	}
+
+	if exclusive {
+		if err := os.Link(tmp.Name(), name); err != nil {
+			if errors.Is(err, fs.ErrExist) {
+				return "", fmt.Errorf("%s exists: %w", key, ErrPreconditionFailed)
+			}
+			return "", err
+		}
+	} else if err := os.Rename(tmp.Name(), name); err != nil {
+		return "", err
+	}
+	return fileETag(info), nil
+}
+
+func (s *fileStore) Head(ctx context.Context, key string) (ObjectInfo, error) {
+	name, err := s.file(key)
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+	info, err := os.Stat(name)
+	if err == nil && info.IsDir() {
+		err = fs.ErrNotExist
+	}
+	if err != nil {
+		return ObjectInfo{}, fileNotFound(err, key)
+	}
+	return fileObject(key, info), nil
+}
+
+// List walks the directory of the registry, or only the directory the prefix is in.
+func (s *fileStore) List(ctx context.Context, prefix string) ([]ObjectInfo, error) {
+	if err := s.checkRoot(); err != nil {
+		return nil, err
+	}
+	walkRoot := s.root
+	if dir := path.Dir(prefix); strings.Contains(prefix, "/") && fileKeyValid(dir) {
+		walkRoot = filepath.Join(s.root, filepath.FromSlash(dir))
+	}
+
+	var objects []ObjectInfo
+	err := filepath.WalkDir(walkRoot, func(name string, entry fs.DirEntry, err error) error {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if strings.HasPrefix(entry.Name(), fileInternalPrefix) {
+			if entry.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if entry.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(s.root, name)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+		if !strings.HasPrefix(key, prefix) {
+			return nil
+		}
+		info, err := entry.Info()
+		if errors.Is(err, fs.ErrNotExist) {
+			// deleted while listing
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		objects = append(objects, fileObject(key, info))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	// walked in the order of the names of the files, not of their keys
+	slices.SortFunc(objects, func(a, b ObjectInfo) int {
+		return strings.Compare(a.Key, b.Key)
+	})
+	return objects, nil
+}
+
+func (s *fileStore) Copy(ctx context.Context, src, dst string) error {
+	if src == dst {
+		_, err := s.Head(ctx, src)
+		return err
+	}
+	f, err := s.Get(ctx, src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = s.write(dst, f, false, nil)
+	return err
+}
+
+// Transition fails for any class but STANDARD, the only class of a file.
+func (s *fileStore) Transition(ctx context.Context, key string, class s3types.StorageClass) error {
+	if class != s3types.StorageClassStandard {
+		return unsupported(ProviderFile, "moving objects to the "+string(class)+" class")
+	}
+	_, err := s.Head(ctx, key)
+	return err
+}
+
+// Delete removes the file of an object, and the directories left empty by it up to the
+// directory of the registry.
+func (s *fileStore) Delete(ctx context.Context, key string) error {
+	name, err := s.file(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(name); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	for dir := filepath.Dir(name); dir != filepath.Clean(s.root); dir = filepath.Dir(dir) {
+		if os.Remove(dir) != nil {
+			// not empty
+			break
+		}
+	}
+	return nil
+}
+
+func (s *fileStore) DeleteIfMatch(ctx context.Context, key, etag string) error {
+	info, err := s.Head(ctx, key)
+	if errors.Is(err, ErrObjectNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if info.ETag != etag {
+		return fmt.Errorf("%s was replaced: %w", key, ErrPreconditionFailed)
+	}
+	return s.Delete(ctx, key)
+}
+
+// Location returns the file of the object at key.
+func (s *fileStore) Location(key string) string {
+	return filepath.Join(s.root, filepath.FromSlash(key))
+}
+
+func (s *fileStore) Provider() string {
+	return ProviderFile
+}
+
+// fileObject describes the file of the object at key.
+func fileObject(key string, info fs.FileInfo) ObjectInfo {
+	return ObjectInfo{
+		Key:          key,
+		Size:         info.Size(),
+		ETag:         fileETag(info),
+		LastModified: info.ModTime().UTC(),
+		StorageClass: s3types.StorageClassStandard,
+	}
+}
+
+// fileETag returns the entity tag of a file, of its size and modification time, which change
+// every time the file is written as files are replaced rather than written in place.
+func fileETag(info fs.FileInfo) string {
+	return fmt.Sprintf(`"%x-%x"`, info.Size(), info.ModTime().UnixNano())
+}
+
+// fileNotFound reports the errors of the filesystem for missing files as ErrObjectNotFound.
+func fileNotFound(err error, key string) error {
+	if errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("%s: %w", key, ErrObjectNotFound)
+	}
+	return err
+}
@@ -0,0 +1,234 @@
+package pkg
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// testFileStore returns the store of a registry directory of the test.
+func testFileStore(t *testing.T) *fileStore {
+	t.Helper()
+	objects, err := newFileStore(fileScheme + filepath.ToSlash(t.TempDir()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return objects
+}
+
+func TestFileStore(t *testing.T) {
+	ctx := t.Context()
+	objects := testFileStore(t)
+
+	if err := objects.Put(ctx, "demo/index.json", []byte("index"), PutOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if got := read(t, objects, "demo/index.json"); string(got) != "index" {
+		t.Fatalf("read %q, want %q", got, "index")
+	}
+	if _, err := os.Stat(objects.Location("demo/index.json")); err != nil {
+		t.Fatalf("the location of the object isn't its file: %v", err)
+	}
+	if _, err := objects.Get(ctx, "demo/missing.json"); !errors.Is(err, ErrObjectNotFound) {
+		t.Fatalf("got %v, want %v", err, ErrObjectNotFound)
+	}
+	if _, err := objects.Head(ctx, "demo"); !errors.Is(err, ErrObjectNotFound) {
+		t.Fatalf("a directory is an object: %v", err)
+	}
+	for _, key := range []string{"../outside", "/etc/passwd", ".registry-tmp-1", "a/../../b"} {
+		if err := objects.Put(ctx, key, nil, PutOptions{}); err == nil {
+			t.Errorf("wrote invalid key %q", key)
+		}
+	}
+
+	// PutIfAbsent and DeleteIfMatch guard the lock
+	etag, err := objects.PutIfAbsent(ctx, lockKey, []byte("held"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := objects.PutIfAbsent(ctx, lockKey, []byte("taken")); !errors.Is(
+		err,
+		ErrPreconditionFailed,
+	) {
+		t.Fatalf("got %v, want %v", err, ErrPreconditionFailed)
+	}
+	info, err := objects.Head(ctx, lockKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.ETag != etag {
+		t.Fatalf("described etag %s, put %s", info.ETag, etag)
+	}
+	if err := objects.DeleteIfMatch(ctx, lockKey, `"other"`); !errors.Is(
+		err,
+		ErrPreconditionFailed,
+	) {
+		t.Fatalf("got %v, want %v", err, ErrPreconditionFailed)
+	}
+	if err := objects.DeleteIfMatch(ctx, lockKey, etag); err != nil {
+		t.Fatal(err)
+	}
+	if err := objects.DeleteIfMatch(ctx, lockKey, etag); err != nil {
+		t.Fatalf("deleting a missing object failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(objects.root, ".locks")); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("the emptied directory of the lock was left: %v", err)
+	}
+
+	if err := objects.Copy(ctx, "demo/index.json", "demo/1.0.0/index.json"); err != nil {
+		t.Fatal(err)
+	}
+	if err := objects.Transition(ctx, "demo/index.json", s3types.StorageClassGlacierIr); !errors.Is(
+		err,
+		ErrNotSupported,
+	) {
+		t.Fatalf("got %v, want %v", err, ErrNotSupported)
+	}
+
+	// a temporary file left by a write that was killed isn't an object
+	tmp := filepath.Join(objects.root, "demo", fileInternalPrefix+"tmp-1")
+	if err := os.WriteFile(tmp, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	listed, err := objects.List(ctx, "demo/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var keys []string
+	for _, object := range listed {
+		keys = append(keys, object.Key)
+		if object.StorageClass != s3types.StorageClassStandard {
+			t.Fatalf("%s has storage class %s", object.Key, object.StorageClass)
+		}
+	}
+	if want := []string{"demo/1.0.0/index.json", "demo/index.json"}; !slices.Equal(keys, want) {
+		t.Fatalf("listed %v, want %v", keys, want)
+	}
+}
+
+func TestFileStoreUpload(t *testing.T) {
+	build := []byte(strings.Repeat("plugin build ", 100))
+	sum := sha256.Sum256(build)
+	checksum := hex.EncodeToString(sum[:])
+
+	tests := []struct {
+		name    string
+		size    int64
+		opts    UploadOptions
+		wantErr error
+	}{
+		{name: "hashed", size: int64(len(build))},
+		{name: "checked", size: int64(len(build)), opts: UploadOptions{Checksum: checksum}},
+		{
+			name: "parts",
+			size: int64(len(build)),
+			opts: UploadOptions{PartSize: 100, Concurrency: 2},
+		},
+		{
+			name: "mismatch",
+			size: int64(len(build)),
+			opts: UploadOptions{Checksum: strings.Repeat("0", 64)},
+		},
+		{name: "short", size: int64(len(build)) + 1},
+		{
+			name:    "storage class",
+			size:    int64(len(build)),
+			opts:    UploadOptions{StorageClass: s3types.StorageClassGlacierIr},
+			wantErr: ErrNotSupported,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objects := testFileStore(t)
+			got, err := objects.Upload(
+				t.Context(),
+				"demo/1.0.0/linux-amd64.tar.gz",
+				bytes.NewReader(build),
+				tt.size,
+				tt.opts,
+			)
+			valid := tt.size == int64(len(build)) &&
+				(tt.opts.Checksum == "" || tt.opts.Checksum == checksum) && tt.wantErr == nil
+			if !valid {
+				if err == nil || (tt.wantErr != nil && !errors.Is(err, tt.wantErr)) {
+					t.Fatalf("got %v, want an error", err)
+				}
+				if keys, _ := objects.List(t.Context(), ""); len(keys) != 0 {
+					t.Fatalf("a failed upload left %v", keys)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != checksum {
+				t.Fatalf("got checksum %s, want %s", got, checksum)
+			}
+			if b := read(t, objects, "demo/1.0.0/linux-amd64.tar.gz"); !bytes.Equal(b, build) {
+				t.Fatal("the uploaded build differs from the build")
+			}
+		})
+	}
+}
+
+func TestFileStoreMissingDirectory(t *testing.T) {
+	objects, err := newFileStore(filepath.Join(t.TempDir(), "missing"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := objects.Put(t.Context(), "index.json", nil, PutOptions{}); err == nil ||
+		!strings.Contains(err.Error(), "doesn't exist") {
+		t.Fatalf("got %v, want the missing directory reported", err)
+	}
+	if _, err := newFileStore(""); err == nil {
+		t.Fatal("expected a registry without a directory to be refused")
+	}
+}
+
+func TestFileProviderUnsupported(t *testing.T) {
+	ctx := t.Context()
+	indexer, err := NewIndexer(ctx, IndexerOpts{Bucket: t.TempDir(), Provider: ProviderFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		run  func() error
+	}{
+		{name: "bootstrap", run: func() error {
+			return indexer.Bootstrap(ctx, BootstrapOpts{})
+		}},
+		{name: "check bucket", run: func() error {
+			_, err := indexer.AuditBucket(ctx, AuditOpts{})
+			return err
+		}},
+		{name: "cors", run: func() error { return indexer.ApplyCORS(ctx, nil) }},
+		{name: "verify cors", run: func() error {
+			_, err := indexer.VerifyCORS(ctx, nil)
+			return err
+		}},
+		{name: "stale uploads", run: func() error {
+			_, err := indexer.AbortStaleUploads(ctx, time.Hour, true)
+			return err
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.run()
+			if !errors.Is(err, ErrNotSupported) ||
+				!strings.Contains(err.Error(), "not supported by the file provider") {
+				t.Fatalf("got %v, want it not supported by the file provider", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,303 @@
+package pkg
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"time"
+
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/omniviewdev/registry-cli/pkg/console"
+	"github.com/omniviewdev/registry-cli/pkg/types"
+)
+
+// Removal is a plugin version removed (or to be removed) by garbage collection.
+type Removal struct {
+	Plugin  string
+	Version string
+
+	// Reason is why the policy removes the version
+	Reason string
+
+	// Artifacts are the bucket paths of the version's tarballs
+	Artifacts []string
+}
+
+// Policy returns the retention policy of the registry, or the zero policy if none is set.
+func (i *Indexer) Policy(ctx context.Context) (types.RetentionPolicy, error) {
+	body, err := readObject(ctx, i.objects, types.PolicyPath)
+	if errors.Is(err, ErrObjectNotFound) {
+		return types.RetentionPolicy{}, nil
+	}
+	if err != nil {
+		return types.RetentionPolicy{}, fmt.Errorf("couldn't get retention policy: %v", err)
+	}
+
+	var policy types.RetentionPolicy
+	if err := json.Unmarshal(body, &policy); err != nil {
+		return types.RetentionPolicy{}, fmt.Errorf("invalid retention policy: %v", err)
+	}
+	return policy, nil
+}
+
+// SetPolicy updates the retention policy of the registry.
+func (i *Indexer) SetPolicy(ctx context.Context, policy types.RetentionPolicy) error {
+	b, err := json.MarshalIndent(policy, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to upload retention policy: %v", err)
+	}
+	_, err = i.storeObject(ctx, b, types.PolicyPath, "application/json")
+	return err
+}
+
+// GCOpts configures garbage collection.
+type GCOpts struct {
+	// DryRun reports what would be collected without changing anything
+	DryRun bool
+
+	// TransitionTo moves the artifacts of versions the policy no longer keeps to this storage
+	// class instead of deleting them. The versions stay in the indexes.
+	TransitionTo s3types.StorageClass
+
+	// Checkpoint records the progress of the collection, so a collection that failed part way
+	// deletes (or transitions) the rest of the artifacts when resumed
+	Checkpoint *Checkpoint
+}
+
+// CollectGarbage removes the versions of every plugin that the retention policy no longer
+// keeps, returning what was removed (or transitioned). Indexes are updated before the
+// artifacts are deleted, so they never point at missing artifacts.
+func (i *Indexer) CollectGarbage(ctx context.Context, opts GCOpts) ([]Removal, error) {
+	if opts.DryRun || opts.TransitionTo != "" {
+		// the indexes are left untouched
+		return i.collectGarbage(ctx, opts)
+	}
+
+	var removals []Removal
+	err := i.withLock(ctx, func() error {
+		var err error
+		removals, err = i.collectGarbage(ctx, opts)
+		return err
+	})
+	return removals, err
+}
+
+func (i *Indexer) collectGarbage(ctx context.Context, opts GCOpts) ([]Removal, error) {
+	policy, err := i.Policy(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if policy.IsZero() {
+		return nil, nil
+	}
+
+	if !opts.DryRun && opts.TransitionTo == "" {
+		// the versions are already gone from the indexes, only their artifacts are left
+		if err := i.resumeDeletions(ctx, opts.Checkpoint); err != nil {
+			return nil, err
+		}
+	}
+
+	registry, err := i.getRegistryIndex(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var removals []Removal
+	now := time.Now()
+	for _, plugin := range registry.Plugins {
+		index, err := i.loadPluginIndex(ctx, plugin.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		expired := policy.Expired(index, now)
+		if len(expired) == 0 {
+			continue
+		}
+
+		pluginRemovals := make([]Removal, 0, len(expired))
+		for _, version := range index.Versions {
+			reason, ok := expired[version.Version]
+			if !ok {
+				continue
+			}
+			removal := Removal{Plugin: index.ID, Version: version.Version, Reason: reason}
+			for _, arch := range sortedArchs(version) {
+				artifact := i.artifactKey(version.Architectures[arch].DownloadURL)
+				if opts.TransitionTo != "" {
+					if opts.Checkpoint.Done("transitioned/"+artifact, nil) {
+						continue
+					}
+					class, err := i.storageClass(ctx, artifact)
+					if err != nil {
+						return nil, err
+					}
+					if class == opts.TransitionTo {
+						continue
+					}
+				}
+				removal.Artifacts = append(removal.Artifacts, artifact)
+			}
+			if opts.TransitionTo != "" && len(removal.Artifacts) == 0 {
+				// already transitioned
+				continue
+			}
+			pluginRemovals = append(pluginRemovals, removal)
+		}
+		removals = append(removals, pluginRemovals...)
+
+		if opts.DryRun {
+			continue
+		}
+
+		if opts.TransitionTo != "" {
+			for _, removal := range pluginRemovals {
+				for _, artifact := range removal.Artifacts {
+					if err := i.transition(ctx, artifact, opts.TransitionTo); err != nil {
+						return nil, err
+					}
+					if err := opts.Checkpoint.Complete("transitioned/"+artifact, nil); err != nil {
+						return nil, err
+					}
+				}
+			}
+			continue
+		}
+
+		before := index
+		index.Versions = slices.DeleteFunc(
+			slices.Clone(index.Versions),
+			func(v types.PluginVersionInformation) bool {
+				_, ok := expired[v.Version]
+				return ok
+			},
+		)
+		var artifacts []string
+		for _, removal := range pluginRemovals {
+			artifacts = append(artifacts, removal.Artifacts...)
+		}
+		// recorded before the index update, as the artifacts can't be found once it's done
+		if err := opts.Checkpoint.Complete("delete/"+index.ID, artifacts); err != nil {
+			return nil, err
+		}
+		if err := i.commitPluginIndex(ctx, "gc", before, index); err != nil {
+			return nil, err
+		}
+		if err := i.deleteCollected(ctx, artifacts, opts.Checkpoint); err != nil {
+			return nil, err
+		}
+	}
+	return removals, nil
+}
+
+// resumeDeletions deletes the artifacts an earlier collection removed from the indexes but
+// failed to delete.
+func (i *Indexer) resumeDeletions(ctx context.Context, checkpoint *Checkpoint) error {
+	for _, step := range checkpoint.Steps("delete/") {
+		var artifacts []string
+		if !checkpoint.Done(step, &artifacts) {
+			continue
+		}
+		if err := i.deleteCollected(ctx, artifacts, checkpoint); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deleteCollected deletes the artifacts of collected versions along with their checksum files,
+// skipping the ones the checkpoint has as deleted.
+func (i *Indexer) deleteCollected(
+	ctx context.Context,
+	artifacts []string,
+	checkpoint *Checkpoint,
+) error {
+	for _, artifact := range artifacts {
+		step := "deleted/" + artifact
+		if checkpoint.Done(step, nil) {
+			continue
+		}
+		if err := i.delete(ctx, artifact); err != nil {
+			return err
+		}
+		if err := i.delete(ctx, artifact+types.ChecksumExt); err != nil {
+			return err
+		}
+		if err := checkpoint.Complete(step, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// warnRetention warns when the retention policy will remove versions of the plugin on the
+// next garbage collection.
+func (i *Indexer) warnRetention(ctx context.Context, index types.PluginIndex) {
+	policy, err := i.Policy(ctx)
+	if err != nil {
+		console.Printf("⚠️ couldn't check the retention policy: %v\n", err)
+		return
+	}
+	if policy.IsZero() {
+		return
+	}
+
+	if policy.PrereleaseTTLDays > 0 && types.IsPrerelease(index.LatestVersion.Version) {
+		console.Printf(
+			"⚠️ %s is a prerelease, the retention policy removes prereleases after %d days\n",
+			index.LatestVersion.Version,
+			policy.PrereleaseTTLDays,
+		)
+	}
+
+	expired := policy.Expired(index, time.Now())
+	if len(expired) == 0 {
+		return
+	}
+	versions := make([]string, 0, len(expired))
+	for version := range expired {
+		versions = append(versions, version)
+	}
+	sort.Strings(versions)
+	console.Printf(
+		"⚠️ the retention policy will remove %d versions of %s on the next gc: %v\n",
+		len(versions),
+		index.ID,
+		versions,
+	)
+}
+
+// storageClass returns the storage class of an object in the bucket
+func (i *Indexer) storageClass(ctx context.Context, bucketPath string) (s3types.StorageClass, error) {
+	info, err := i.objects.Head(ctx, bucketPath)
+	if err != nil {
+		return "", fmt.Errorf("couldn't get %v:%v: %v", i.bucket, bucketPath, err)
+	}
+	return info.StorageClass, nil
+}
+
+// transition moves an object in the bucket to another storage class
+func (i *Indexer) transition(
+	ctx context.Context,
+	bucketPath string,
+	class s3types.StorageClass,
+) error {
+	console.Printf("moving %s to %s...\n", bucketPath, class)
+	if err := i.objects.Transition(ctx, bucketPath, class); err != nil {
+		return fmt.Errorf("couldn't move %v:%v to %s: %v", i.bucket, bucketPath, class, err)
+	}
+	return nil
+}
+
+// delete deletes an object from the bucket
+func (i *Indexer) delete(ctx context.Context, bucketPath string) error {
+	console.Printf("deleting %s...\n", bucketPath)
+	if err := i.objects.Delete(ctx, bucketPath); err != nil {
+		return fmt.Errorf("couldn't delete %v:%v: %v", i.bucket, bucketPath, err)
+	}
+	return nil
+}
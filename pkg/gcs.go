@@ -0,0 +1,209 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go/middleware"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+)
+
+// gcsEndpoint is the endpoint of the XML API of Google Cloud Storage, which is compatible with
+// the S3 API
+const gcsEndpoint = "https://storage.googleapis.com"
+
+// gcsScope is the OAuth scope the requests to Google Cloud Storage are authorized with
+const gcsScope = "https://www.googleapis.com/auth/devstorage.read_write"
+
+// gcsStore is the ObjectStore of a Google Cloud Storage bucket, whose objects are stored and read
+// through the XML API like those of an S3 bucket. Only the objects are: the settings of buckets,
+// the versions of objects, multipart uploads and presigned URLs are S3 features the XML API takes
+// differently, if at all, and fail with ErrNotSupported, as do the storage classes of S3 but
+// STANDARD. A failed upload in parts is still aborted.
+type gcsStore struct {
+	// the store of the bucket through the XML API, of which only the methods of an ObjectStore
+	// are promoted
+	ObjectStore
+	bucket string
+}
+
+// newGCSStore returns the ObjectStore of the bucket.
+func newGCSStore(ctx context.Context, bucket string, pacer *requestPacer) (*gcsStore, error) {
+	client, err := newGCSClient(ctx, pacer)
+	if err != nil {
+		return nil, err
+	}
+	return &gcsStore{ObjectStore: newS3Store(client, bucket), bucket: bucket}, nil
+}
+
+// Upload uploads the object like to S3, in the default storage class of the bucket.
+func (s *gcsStore) Upload(
+	ctx context.Context,
+	key string,
+	body io.ReadSeeker,
+	size int64,
+	opts UploadOptions,
+) (string, error) {
+	if opts.StorageClass != "" && opts.StorageClass != s3types.StorageClassStandard {
+		return "", unsupported(
+			ProviderGCS,
+			"storing objects in the "+string(opts.StorageClass)+" class",
+		)
+	}
+	return s.ObjectStore.Upload(ctx, key, body, size, opts)
+}
+
+// Transition fails for any class but STANDARD, the only class of S3 the XML API takes.
+func (s *gcsStore) Transition(ctx context.Context, key string, class s3types.StorageClass) error {
+	if class != s3types.StorageClassStandard {
+		return unsupported(ProviderGCS, "moving objects to the "+string(class)+" class")
+	}
+	return s.ObjectStore.Transition(ctx, key, class)
+}
+
+// Location returns the gs:// URL of the object at key.
+func (s *gcsStore) Location(key string) string {
+	return fmt.Sprintf("gs://%s/%s", s.bucket, key)
+}
+
+func (s *gcsStore) Provider() string {
+	return ProviderGCS
+}
+
+// newGCSClient creates a client of the XML API of Google Cloud Storage, which serves buckets
+// like S3 does. The requests are authorized with an OAuth token of the Application Default
+// Credentials (GOOGLE_APPLICATION_CREDENTIALS, gcloud auth application-default login, or the
+// service account of the instance) rather than signed with AWS credentials.
+func newGCSClient(ctx context.Context, pacer *requestPacer) (*s3.Client, error) {
+	credentials, err := google.FindDefaultCredentials(ctx, gcsScope)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"couldn't find Google credentials, have you set up Application Default Credentials? %v",
+			err,
+		)
+	}
+
+	tokens := oauth2.ReuseTokenSource(nil, credentials.TokenSource)
+	return gcsClient(tokens, gcsEndpoint, pacer), nil
+}
+
+// gcsClient creates a client of the XML API at the endpoint, authorizing its requests with the
+// tokens. Requests are paced by the pacer, unless it's nil.
+func gcsClient(tokens oauth2.TokenSource, endpoint string, pacer *requestPacer) *s3.Client {
+	return s3.New(s3.Options{
+		Region:       "auto",
+		BaseEndpoint: aws.String(endpoint),
+		UsePathStyle: true,
+		Credentials:  aws.AnonymousCredentials{},
+		// the XML API doesn't take the checksums of the S3 API, and rejects the chunked uploads
+		// they are sent with
+		RequestChecksumCalculation: aws.RequestChecksumCalculationWhenRequired,
+		ResponseChecksumValidation: aws.ResponseChecksumValidationWhenRequired,
+		APIOptions: []func(*middleware.Stack) error{
+			func(stack *middleware.Stack) error {
+				return stack.Initialize.Add(gcsCompatibility{}, middleware.Before)
+			},
+			func(stack *middleware.Stack) error {
+				return stack.Build.Add(gcsPreconditions{}, middleware.After)
+			},
+			func(stack *middleware.Stack) error {
+				return stack.Finalize.Add(&gcsAuthorization{tokens: tokens}, middleware.After)
+			},
+		},
+	}, func(o *s3.Options) {
+		if pacer != nil {
+			o.APIOptions = append(o.APIOptions, pacer.addMiddleware)
+		}
+	})
+}
+
+// gcsCompatibility adapts the requests to the XML API: the checksums of uploads are left to
+// the XML API, which checks uploads against their MD5 instead.
+type gcsCompatibility struct{}
+
+func (gcsCompatibility) ID() string {
+	return "GCSCompatibility"
+}
+
+func (gcsCompatibility) HandleInitialize(
+	ctx context.Context,
+	in middleware.InitializeInput,
+	next middleware.InitializeHandler,
+) (middleware.InitializeOutput, middleware.Metadata, error) {
+	switch input := in.Parameters.(type) {
+	case *s3.PutObjectInput:
+		input.ChecksumAlgorithm = ""
+		input.ChecksumSHA256 = nil
+	case *s3.CreateMultipartUploadInput:
+		input.ChecksumAlgorithm = ""
+	case *s3.UploadPartInput:
+		input.ChecksumAlgorithm = ""
+		input.ChecksumSHA256 = nil
+	case *s3.CompleteMultipartUploadInput:
+		input.ChecksumSHA256 = nil
+		if input.MultipartUpload != nil {
+			for idx := range input.MultipartUpload.Parts {
+				input.MultipartUpload.Parts[idx].ChecksumSHA256 = nil
+			}
+		}
+	}
+	return next.HandleInitialize(ctx, in)
+}
+
+// gcsPreconditions translates the preconditions of the S3 API the XML API doesn't take: an
+// upload only creating the object (If-None-Match: *), as the index lock is taken with.
+type gcsPreconditions struct{}
+
+func (gcsPreconditions) ID() string {
+	return "GCSPreconditions"
+}
+
+func (gcsPreconditions) HandleBuild(
+	ctx context.Context,
+	in middleware.BuildInput,
+	next middleware.BuildHandler,
+) (middleware.BuildOutput, middleware.Metadata, error) {
+	if req, ok := in.Request.(*smithyhttp.Request); ok && req.Header.Get("If-None-Match") == "*" {
+		req.Header.Del("If-None-Match")
+		req.Header.Set("x-goog-if-generation-match", "0")
+	}
+	return next.HandleBuild(ctx, in)
+}
+
+// gcsAuthorization authorizes the requests with an OAuth token, refreshed when it expires.
+type gcsAuthorization struct {
+	tokens oauth2.TokenSource
+}
+
+func (*gcsAuthorization) ID() string {
+	return "GCSAuthorization"
+}
+
+func (a *gcsAuthorization) HandleFinalize(
+	ctx context.Context,
+	in middleware.FinalizeInput,
+	next middleware.FinalizeHandler,
+) (middleware.FinalizeOutput, middleware.Metadata, error) {
+	req, ok := in.Request.(*smithyhttp.Request)
+	if !ok {
+		return middleware.FinalizeOutput{}, middleware.Metadata{}, fmt.Errorf(
+			"unexpected request type %T",
+			in.Request,
+		)
+	}
+	token, err := a.tokens.Token()
+	if err != nil {
+		return middleware.FinalizeOutput{}, middleware.Metadata{}, fmt.Errorf(
+			"couldn't get a Google access token: %v",
+			err,
+		)
+	}
+	token.SetAuthHeader(req.Request)
+	return next.HandleFinalize(ctx, in)
+}
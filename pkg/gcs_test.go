@@ -0,0 +1,260 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"golang.org/x/oauth2"
+)
+
+// gcsRequest is a request sent to the XML API.
+type gcsRequest struct {
+	header http.Header
+	body   string
+}
+
+// testGCSClient returns a client of an XML API recording the requests sent to it, authorized
+// with the tokens.
+func testGCSClient(t *testing.T, tokens oauth2.TokenSource) (*s3.Client, func() []gcsRequest) {
+	t.Helper()
+	var mu sync.Mutex
+	var requests []gcsRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		requests = append(requests, gcsRequest{header: r.Header.Clone(), body: string(body)})
+		mu.Unlock()
+		w.Header().Set("ETag", `"1"`)
+		if r.Method == http.MethodPost {
+			w.Write([]byte("<CompleteMultipartUploadResult/>"))
+		}
+	}))
+	t.Cleanup(server.Close)
+	return gcsClient(tokens, server.URL, nil), func() []gcsRequest {
+		mu.Lock()
+		defer mu.Unlock()
+		return requests
+	}
+}
+
+func TestGCSClient(t *testing.T) {
+	checksum := "n2yYhDLX9rNNHD4xb9+dM3l3bOAPVm0EaU7Ym0H8Xqs="
+	tests := []struct {
+		name       string
+		send       func(ctx context.Context, client *s3.Client) error
+		wantHeader map[string]string
+	}{
+		{
+			name: "put if absent",
+			send: func(ctx context.Context, client *s3.Client) error {
+				_, err := client.PutObject(ctx, &s3.PutObjectInput{
+					Bucket:      aws.String("registry"),
+					Key:         aws.String(lockKey),
+					Body:        strings.NewReader("held"),
+					IfNoneMatch: aws.String("*"),
+				})
+				return err
+			},
+			wantHeader: map[string]string{
+				"x-goog-if-generation-match": "0",
+				"If-None-Match":              "",
+			},
+		},
+		{
+			name: "put if not the etag",
+			send: func(ctx context.Context, client *s3.Client) error {
+				_, err := client.PutObject(ctx, &s3.PutObjectInput{
+					Bucket:      aws.String("registry"),
+					Key:         aws.String(lockKey),
+					Body:        strings.NewReader("held"),
+					IfNoneMatch: aws.String(`"1"`),
+				})
+				return err
+			},
+			wantHeader: map[string]string{
+				"x-goog-if-generation-match": "",
+				"If-None-Match":              `"1"`,
+			},
+		},
+		{
+			name: "upload with a checksum",
+			send: func(ctx context.Context, client *s3.Client) error {
+				_, err := client.PutObject(ctx, &s3.PutObjectInput{
+					Bucket:            aws.String("registry"),
+					Key:               aws.String("demo/1.0.0/linux-amd64.tar.gz"),
+					Body:              strings.NewReader("build"),
+					ChecksumAlgorithm: s3types.ChecksumAlgorithmSha256,
+					ChecksumSHA256:    aws.String(checksum),
+				})
+				return err
+			},
+			wantHeader: map[string]string{"Content-Encoding": ""},
+		},
+		{
+			name: "upload a part with a checksum",
+			send: func(ctx context.Context, client *s3.Client) error {
+				_, err := client.UploadPart(ctx, &s3.UploadPartInput{
+					Bucket:            aws.String("registry"),
+					Key:               aws.String("demo/1.0.0/linux-amd64.tar.gz"),
+					UploadId:          aws.String("upload"),
+					PartNumber:        aws.Int32(1),
+					Body:              strings.NewReader("build"),
+					ChecksumAlgorithm: s3types.ChecksumAlgorithmSha256,
+					ChecksumSHA256:    aws.String(checksum),
+				})
+				return err
+			},
+			wantHeader: map[string]string{"Content-Encoding": ""},
+		},
+		{
+			name: "complete an upload with checksums",
+			send: func(ctx context.Context, client *s3.Client) error {
+				_, err := client.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+					Bucket:         aws.String("registry"),
+					Key:            aws.String("demo/1.0.0/linux-amd64.tar.gz"),
+					UploadId:       aws.String("upload"),
+					ChecksumSHA256: aws.String(checksum),
+					MultipartUpload: &s3types.CompletedMultipartUpload{
+						Parts: []s3types.CompletedPart{{
+							ETag:           aws.String(`"1"`),
+							PartNumber:     aws.Int32(1),
+							ChecksumSHA256: aws.String(checksum),
+						}},
+					},
+				})
+				return err
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, requests := testGCSClient(
+				t,
+				oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "token"}),
+			)
+			if err := tt.send(t.Context(), client); err != nil {
+				t.Fatal(err)
+			}
+			sent := requests()
+			if len(sent) != 1 {
+				t.Fatalf("sent %d requests, want 1", len(sent))
+			}
+			header := sent[0].header
+			if got := header.Get("Authorization"); got != "Bearer token" {
+				t.Fatalf("authorized with %q, want the bearer token", got)
+			}
+			for name, want := range tt.wantHeader {
+				if got := header.Get(name); got != want {
+					t.Fatalf("sent %s: %q, want %q", name, got, want)
+				}
+			}
+			// the checksums of S3 are left out, the XML API rejecting them
+			for name := range header {
+				name = strings.ToLower(name)
+				if strings.HasPrefix(name, "x-amz-checksum-") ||
+					name == "x-amz-sdk-checksum-algorithm" || name == "x-amz-trailer" ||
+					name == "x-amz-decoded-content-length" {
+					t.Fatalf("sent the checksum header %s", name)
+				}
+			}
+			if strings.Contains(sent[0].body, "ChecksumSHA256") {
+				t.Fatalf("sent the checksums of the parts: %s", sent[0].body)
+			}
+		})
+	}
+}
+
+// failingTokens is a token source failing to get tokens.
+type failingTokens struct{}
+
+func (failingTokens) Token() (*oauth2.Token, error) {
+	return nil, errors.New("token expired")
+}
+
+func TestGCSClientTokenFailure(t *testing.T) {
+	client, requests := testGCSClient(t, failingTokens{})
+	_, err := client.HeadObject(t.Context(), &s3.HeadObjectInput{
+		Bucket: aws.String("registry"),
+		Key:    aws.String("index.json"),
+	})
+	if err == nil || !strings.Contains(err.Error(), "couldn't get a Google access token") {
+		t.Fatalf("got %v, want the token failure", err)
+	}
+	if sent := requests(); len(sent) != 0 {
+		t.Fatalf("sent %d unauthorized requests", len(sent))
+	}
+}
+
+func TestGCSStoreUnsupported(t *testing.T) {
+	ctx := t.Context()
+	client, requests := testGCSClient(
+		t,
+		oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "token"}),
+	)
+	objects := &gcsStore{ObjectStore: newS3Store(client, "registry"), bucket: "registry"}
+	indexer := &Indexer{objects: objects, bucket: "registry"}
+	if got := objects.Location("index.json"); got != "gs://registry/index.json" {
+		t.Fatalf("located the object at %s, want its gs:// URL", got)
+	}
+
+	tests := []struct {
+		name string
+		run  func() error
+	}{
+		{name: "bootstrap", run: func() error {
+			return indexer.Bootstrap(ctx, BootstrapOpts{})
+		}},
+		{name: "check bucket", run: func() error {
+			_, err := indexer.AuditBucket(ctx, AuditOpts{})
+			return err
+		}},
+		{name: "cors", run: func() error { return indexer.ApplyCORS(ctx, nil) }},
+		{name: "stale uploads", run: func() error {
+			_, err := indexer.AbortStaleUploads(ctx, time.Hour, true)
+			return err
+		}},
+		{name: "revisions", run: func() error {
+			_, err := indexer.IndexRevisions(ctx, "demo")
+			return err
+		}},
+		{name: "presign", run: func() error {
+			_, err := indexer.Presign(ctx, "demo", "", "linux_amd64", "", time.Hour)
+			return err
+		}},
+		{name: "storage class", run: func() error {
+			_, err := objects.Upload(
+				ctx,
+				"demo/1.0.0/linux-amd64.tar.gz",
+				strings.NewReader("build"),
+				int64(len("build")),
+				UploadOptions{StorageClass: s3types.StorageClassGlacierIr},
+			)
+			return err
+		}},
+		{name: "transition", run: func() error {
+			return objects.Transition(ctx, "demo/index.json", s3types.StorageClassGlacier)
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.run()
+			if !errors.Is(err, ErrNotSupported) ||
+				!strings.Contains(err.Error(), "not supported by the gcs provider") {
+				t.Fatalf("got %v, want it not supported by the gcs provider", err)
+			}
+		})
+	}
+	if sent := requests(); len(sent) != 0 {
+		t.Fatalf("sent %d requests for features the XML API doesn't have", len(sent))
+	}
+}
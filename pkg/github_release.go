@@ -0,0 +1,294 @@
+package pkg
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/omniviewdev/registry-cli/pkg/console"
+	"github.com/omniviewdev/registry-cli/pkg/types"
+)
+
+// GitHubRelease is a release of a GitHub repository holding the builds of a plugin as assets,
+// e.g. made by goreleaser.
+type GitHubRelease struct {
+	Owner string
+	Repo  string
+	Tag   string
+}
+
+// ParseGitHubRelease parses a release given as owner/repo@tag.
+func ParseGitHubRelease(ref string) (GitHubRelease, error) {
+	repository, tag, ok := strings.Cut(ref, "@")
+	owner, repo, _ := strings.Cut(repository, "/")
+	if !ok || tag == "" || owner == "" || repo == "" || strings.Contains(repo, "/") {
+		return GitHubRelease{}, fmt.Errorf("invalid GitHub release %q, expected owner/repo@tag", ref)
+	}
+	return GitHubRelease{Owner: owner, Repo: repo, Tag: tag}, nil
+}
+
+func (r GitHubRelease) String() string {
+	return fmt.Sprintf("%s/%s@%s", r.Owner, r.Repo, r.Tag)
+}
+
+// GitHubBuilds are the builds of a plugin downloaded from a GitHub release.
+type GitHubBuilds struct {
+	// Dir is the temporary directory holding the builds, the caller is responsible for removing
+	// it
+	Dir string
+
+	// Paths are the paths of the downloaded tarballs by platform (e.g. linux_amd64)
+	Paths map[string]string
+
+	// Artifacts are the checksums and sizes of the tarballs by platform, verified against the
+	// checksums of the release
+	Artifacts map[string]types.Artifact
+}
+
+type githubAsset struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+// DownloadGitHubRelease downloads the platform tarballs of a GitHub release, named after their
+// platform like goreleaser names archives (e.g. plugin_1.0.0_linux_x86_64.tar.gz), verifying
+// each against the checksums of the release: a checksums.txt asset, or a .sha256 asset next to
+// the tarball. The token, when given, authenticates the requests, for private repositories.
+func DownloadGitHubRelease(
+	ctx context.Context,
+	release GitHubRelease,
+	token string,
+) (GitHubBuilds, error) {
+	assets, err := githubReleaseAssets(ctx, release, token)
+	if err != nil {
+		return GitHubBuilds{}, err
+	}
+
+	tarballs := make(map[string]githubAsset)
+	for _, asset := range assets {
+		platform, ok := assetPlatform(asset.Name)
+		if !ok {
+			continue
+		}
+		if existing, ok := tarballs[platform]; ok {
+			return GitHubBuilds{}, fmt.Errorf(
+				"%s has several %s builds: %s and %s",
+				release,
+				platform,
+				existing.Name,
+				asset.Name,
+			)
+		}
+		tarballs[platform] = asset
+	}
+	if len(tarballs) == 0 {
+		return GitHubBuilds{}, fmt.Errorf("%s has no platform tarballs", release)
+	}
+
+	checksums, err := releaseChecksums(ctx, assets, token)
+	if err != nil {
+		return GitHubBuilds{}, err
+	}
+	for _, asset := range tarballs {
+		if _, ok := checksums[asset.Name]; !ok {
+			return GitHubBuilds{}, fmt.Errorf("%s has no checksum for %s", release, asset.Name)
+		}
+	}
+
+	dir, err := os.MkdirTemp("", "registry-github-*")
+	if err != nil {
+		return GitHubBuilds{}, fmt.Errorf("couldn't create temporary directory: %w", err)
+	}
+	builds := GitHubBuilds{
+		Dir:       dir,
+		Paths:     make(map[string]string, len(tarballs)),
+		Artifacts: make(map[string]types.Artifact, len(tarballs)),
+	}
+	for _, platform := range types.Platforms {
+		asset, ok := tarballs[platform]
+		if !ok {
+			continue
+		}
+		console.Printf("[%s] downloading %s...\n", platform, asset.Name)
+		path := filepath.Join(dir, platform+".tar.gz")
+		artifact, err := downloadAsset(ctx, asset, token, path)
+		if err != nil {
+			os.RemoveAll(dir)
+			return GitHubBuilds{}, err
+		}
+		if checksum := checksums[asset.Name]; !strings.EqualFold(artifact.Checksum, checksum) {
+			os.RemoveAll(dir)
+			return GitHubBuilds{}, fmt.Errorf(
+				"checksum mismatch for %s: expected %s, got %s",
+				asset.Name,
+				checksum,
+				artifact.Checksum,
+			)
+		}
+		builds.Paths[platform] = path
+		builds.Artifacts[platform] = artifact
+	}
+	return builds, nil
+}
+
+// assetPlatform returns the platform of a tarball named after it, with the architecture named
+// either way (e.g. linux_amd64, Linux_x86_64 or linux-aarch64).
+func assetPlatform(name string) (string, bool) {
+	name = strings.ToLower(name)
+	base, ok := strings.CutSuffix(name, ".tar.gz")
+	if !ok {
+		if base, ok = strings.CutSuffix(name, ".tgz"); !ok {
+			return "", false
+		}
+	}
+	fields := strings.FieldsFunc(base, func(r rune) bool {
+		return r == '_' || r == '-' || r == '.'
+	})
+	for idx := 0; idx < len(fields)-1; idx++ {
+		archs := []string{fields[idx+1]}
+		if idx+2 < len(fields) {
+			// architectures with a separator in their name, like x86_64
+			archs = append(archs, fields[idx+1]+"_"+fields[idx+2])
+		}
+		for _, arch := range archs {
+			platform := fields[idx] + "_" + types.NormalizeArch(arch)
+			if slices.Contains(types.Platforms, platform) {
+				return platform, true
+			}
+		}
+	}
+	return "", false
+}
+
+func githubReleaseAssets(
+	ctx context.Context,
+	release GitHubRelease,
+	token string,
+) ([]githubAsset, error) {
+	u := fmt.Sprintf(
+		"%s/repos/%s/%s/releases/tags/%s",
+		githubAPI,
+		url.PathEscape(release.Owner),
+		url.PathEscape(release.Repo),
+		url.PathEscape(release.Tag),
+	)
+	resp, err := githubGet(ctx, u, "application/vnd.github+json", token)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't get the GitHub release %s: %w", release, err)
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Assets []githubAsset `json:"assets"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("couldn't decode the GitHub release %s: %w", release, err)
+	}
+	return result.Assets, nil
+}
+
+// releaseChecksums returns the sha256 checksums of the assets of a release by asset name, from
+// its checksums.txt (sha256sum output) and .sha256 assets.
+func releaseChecksums(
+	ctx context.Context,
+	assets []githubAsset,
+	token string,
+) (map[string]string, error) {
+	checksums := make(map[string]string)
+	for _, asset := range assets {
+		name := strings.ToLower(asset.Name)
+		checksumFile := strings.HasSuffix(name, "checksums.txt")
+		sidecar, isSidecar := strings.CutSuffix(asset.Name, types.ChecksumExt)
+		if !checksumFile && !isSidecar {
+			continue
+		}
+
+		b, err := readAsset(ctx, asset, token)
+		if err != nil {
+			return nil, err
+		}
+		for line := range strings.Lines(string(b)) {
+			fields := strings.Fields(line)
+			switch {
+			case len(fields) == 0:
+			case isSidecar:
+				// the sidecar may have just the checksum, or the line of sha256sum
+				checksums[sidecar] = fields[0]
+			case len(fields) == 2:
+				checksums[strings.TrimPrefix(fields[1], "*")] = fields[0]
+			}
+		}
+	}
+	return checksums, nil
+}
+
+func readAsset(ctx context.Context, asset githubAsset, token string) ([]byte, error) {
+	resp, err := githubGet(ctx, asset.URL, "application/octet-stream", token)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't download %s: %w", asset.Name, err)
+	}
+	defer resp.Body.Close()
+
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't download %s: %w", asset.Name, err)
+	}
+	return b, nil
+}
+
+// downloadAsset downloads an asset to path, returning its checksum and size.
+func downloadAsset(
+	ctx context.Context,
+	asset githubAsset,
+	token, path string,
+) (types.Artifact, error) {
+	resp, err := githubGet(ctx, asset.URL, "application/octet-stream", token)
+	if err != nil {
+		return types.Artifact{}, fmt.Errorf("couldn't download %s: %w", asset.Name, err)
+	}
+	defer resp.Body.Close()
+
+	f, err := os.Create(path)
+	if err != nil {
+		return types.Artifact{}, fmt.Errorf("couldn't create %s: %w", path, err)
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	size, err := io.Copy(io.MultiWriter(f, h), resp.Body)
+	if err != nil {
+		return types.Artifact{}, fmt.Errorf("couldn't download %s: %w", asset.Name, err)
+	}
+	return types.Artifact{Checksum: hex.EncodeToString(h.Sum(nil)), Size: size}, nil
+}
+
+// githubGet requests a GitHub API url. The assets of releases are downloaded from their API url
+// with the application/octet-stream media type, which works for private repositories too.
+func githubGet(ctx context.Context, u, accept, token string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", accept)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return resp, nil
+}
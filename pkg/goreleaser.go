@@ -0,0 +1,118 @@
+package pkg
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/omniviewdev/registry-cli/pkg/types"
+)
+
+// goreleaserArtifact is an entry of the artifacts.json goreleaser writes into its dist directory.
+type goreleaserArtifact struct {
+	Name    string `json:"name"`
+	Path    string `json:"path"`
+	GOOS    string `json:"goos"`
+	GOARCH  string `json:"goarch"`
+	GOAMD64 string `json:"goamd64"`
+	Type    string `json:"type"`
+	Extra   struct {
+		Checksum string `json:"Checksum"`
+		Format   string `json:"Format"`
+	} `json:"extra"`
+}
+
+// GoReleaserBuilds are the builds of a plugin found in the dist directory of goreleaser.
+type GoReleaserBuilds struct {
+	// Paths are the paths of the tarballs by platform (e.g. linux_amd64)
+	Paths map[string]string
+
+	// Artifacts are the checksums and sizes of the tarballs by platform, verified against the
+	// checksums goreleaser recorded when it has
+	Artifacts map[string]types.Artifact
+}
+
+// LoadGoReleaserBuilds finds the platform tarballs goreleaser archived in its dist directory,
+// from the artifacts.json listing them. Archives that aren't tarballs, and the amd64
+// microarchitecture variants other than the baseline, are left out.
+func LoadGoReleaserBuilds(dist string) (GoReleaserBuilds, error) {
+	path := filepath.Join(dist, "artifacts.json")
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return GoReleaserBuilds{}, fmt.Errorf("couldn't read goreleaser artifacts: %w", err)
+	}
+	var artifacts []goreleaserArtifact
+	if err := json.Unmarshal(b, &artifacts); err != nil {
+		return GoReleaserBuilds{}, fmt.Errorf("couldn't decode %s: %w", path, err)
+	}
+
+	builds := GoReleaserBuilds{
+		Paths:     make(map[string]string),
+		Artifacts: make(map[string]types.Artifact),
+	}
+	for _, artifact := range artifacts {
+		if artifact.Type != "Archive" || !isTarball(artifact) {
+			continue
+		}
+		if artifact.GOAMD64 != "" && artifact.GOAMD64 != "v1" {
+			continue
+		}
+		platform := types.NormalizePlatform(artifact.GOOS + "_" + artifact.GOARCH)
+		if !slices.Contains(types.Platforms, platform) {
+			continue
+		}
+		if existing, ok := builds.Paths[platform]; ok {
+			return GoReleaserBuilds{}, fmt.Errorf(
+				"goreleaser archived several %s tarballs: %s and %s",
+				platform,
+				existing,
+				artifact.Path,
+			)
+		}
+
+		tarball := goreleaserPath(dist, artifact.Path)
+		checksum, size, err := hashFile(tarball)
+		if err != nil {
+			return GoReleaserBuilds{}, err
+		}
+		if expected, ok := strings.CutPrefix(artifact.Extra.Checksum, "sha256:"); ok &&
+			!strings.EqualFold(expected, checksum) {
+			return GoReleaserBuilds{}, fmt.Errorf(
+				"checksum mismatch for %s: expected %s, got %s",
+				tarball,
+				expected,
+				checksum,
+			)
+		}
+		builds.Paths[platform] = tarball
+		builds.Artifacts[platform] = types.Artifact{Checksum: checksum, Size: size}
+	}
+	if len(builds.Paths) == 0 {
+		return GoReleaserBuilds{}, fmt.Errorf("%s lists no platform tarballs", path)
+	}
+	return builds, nil
+}
+
+func isTarball(artifact goreleaserArtifact) bool {
+	if artifact.Extra.Format != "" {
+		return artifact.Extra.Format == "tar.gz" || artifact.Extra.Format == "tgz"
+	}
+	return strings.HasSuffix(artifact.Name, ".tar.gz") || strings.HasSuffix(artifact.Name, ".tgz")
+}
+
+// goreleaserPath resolves the path of an artifact, which goreleaser records relative to the
+// directory it ran in, the parent of the dist directory by default.
+func goreleaserPath(dist, path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	resolved := filepath.Join(filepath.Dir(filepath.Clean(dist)), path)
+	if _, err := os.Stat(resolved); err != nil {
+		// a dist directory moved, or configured elsewhere
+		return filepath.Join(dist, filepath.Base(path))
+	}
+	return resolved
+}
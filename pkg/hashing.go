@@ -0,0 +1,101 @@
+package pkg
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+
+	"github.com/omniviewdev/registry-cli/pkg/types"
+)
+
+// hashingReader hashes a file as it's read for an upload, so the checksum going into the
+// index doesn't need another pass over the file. The upload may rewind the body to retry or
+// to sign it, which restarts the hash.
+type hashingReader struct {
+	file *os.File
+	hash hash.Hash
+	read int64
+
+	// skipped is set when the upload seeked away from where the hash is at
+	skipped bool
+}
+
+func newHashingReader(file *os.File) *hashingReader {
+	return &hashingReader{file: file, hash: sha256.New()}
+}
+
+func (r *hashingReader) Read(p []byte) (int, error) {
+	n, err := r.file.Read(p)
+	r.hash.Write(p[:n])
+	r.read += int64(n)
+	return n, err
+}
+
+func (r *hashingReader) Seek(offset int64, whence int) (int64, error) {
+	pos, err := r.file.Seek(offset, whence)
+	if err != nil {
+		return pos, err
+	}
+	if pos == 0 {
+		r.hash.Reset()
+		r.read = 0
+		r.skipped = false
+	} else if pos != r.read {
+		r.skipped = true
+	}
+	return pos, nil
+}
+
+// artifact returns the checksum and size of the file. When the upload didn't read the whole
+// file in one go, it's hashed again from the start.
+func (r *hashingReader) artifact() (types.Artifact, error) {
+	info, err := r.file.Stat()
+	if err != nil {
+		return types.Artifact{}, err
+	}
+	if r.skipped || r.read != info.Size() {
+		if _, err := r.file.Seek(0, io.SeekStart); err != nil {
+			return types.Artifact{}, err
+		}
+		r.hash.Reset()
+		if _, err := io.Copy(r.hash, r.file); err != nil {
+			return types.Artifact{}, err
+		}
+	}
+	return types.Artifact{
+		Checksum: hex.EncodeToString(r.hash.Sum(nil)),
+		Size:     info.Size(),
+	}, nil
+}
+
+// hashFile returns the sha256 checksum and the size of a file.
+func hashFile(path string) (string, int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", 0, fmt.Errorf("couldn't open %s: %w", path, err)
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	size, err := io.Copy(h, f)
+	if err != nil {
+		return "", 0, fmt.Errorf("couldn't read %s: %w", path, err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), size, nil
+}
+
+// checksumBase64 converts a hex sha256 checksum to the base64 form S3 takes.
+func checksumBase64(checksum string) (string, error) {
+	b, err := hex.DecodeString(checksum)
+	if err != nil {
+		return "", err
+	}
+	if len(b) != sha256.Size {
+		return "", fmt.Errorf("expected a %d byte sha256 checksum, got %d bytes", sha256.Size, len(b))
+	}
+	return base64.StdEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,221 @@
+package pkg
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/user"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/omniviewdev/registry-cli/pkg/types"
+)
+
+const (
+	// HistoryPrefix is the folder of the bucket holding the snapshots of every index write
+	HistoryPrefix = "_history/"
+
+	// snapshotIDFormat names snapshots so they sort in the order they were taken
+	snapshotIDFormat = "20060102T150405.000000000Z"
+)
+
+// ErrSnapshotNotFound is returned when the requested snapshot doesn't exist
+var ErrSnapshotNotFound = errors.New("snapshot not found")
+
+// Snapshot is a copy of an index as it was written, along with who wrote it and why.
+type Snapshot struct {
+	// ID identifies the snapshot amongst those of the same index
+	ID string `json:"id"`
+
+	// Time is when the index was written
+	Time time.Time `json:"time"`
+
+	// Actor is who wrote the index
+	Actor string `json:"actor"`
+
+	// Summary describes the change made to the index
+	Summary string `json:"summary"`
+
+	// Path is the bucket path of the index
+	Path string `json:"path"`
+
+	// Index is the content of the index that was written
+	Index json.RawMessage `json:"index,omitempty"`
+}
+
+// Actor returns who is making changes to the registry: REGISTRY_ACTOR when set, the CI actor
+// when running in GitHub Actions, otherwise the local user and host.
+func Actor() string {
+	if actor := os.Getenv("REGISTRY_ACTOR"); actor != "" {
+		return actor
+	}
+	if actor := os.Getenv("GITHUB_ACTOR"); actor != "" {
+		return actor + " (github actions)"
+	}
+
+	name := "unknown"
+	if u, err := user.Current(); err == nil {
+		name = u.Username
+	}
+	if host, err := os.Hostname(); err == nil {
+		name += "@" + host
+	}
+	return name
+}
+
+// historyDir returns the history folder of the index at the bucket path.
+func historyDir(path string) string {
+	return HistoryPrefix + strings.TrimSuffix(path, ".json") + "/"
+}
+
+// historyIndexPath returns the bucket path of the index whose history is kept for a plugin,
+// or of the registry index when plugin is empty.
+func (i *Indexer) historyIndexPath(plugin string) string {
+	if plugin == "" {
+		return "index.json"
+	}
+	return i.layout.IndexPath(plugin)
+}
+
+// recordHistory stores a snapshot of the index written to the bucket path.
+func (i *Indexer) recordHistory(ctx context.Context, path string, b []byte, summary string) error {
+	now := time.Now().UTC()
+	snapshot := Snapshot{
+		ID:      now.Format(snapshotIDFormat),
+		Time:    now,
+		Actor:   Actor(),
+		Summary: summary,
+		Path:    path,
+		Index:   b,
+	}
+
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return fmt.Errorf("failed to upload snapshot of %s: %v", path, err)
+	}
+	if _, err := i.storeObject(
+		ctx,
+		data,
+		historyDir(path)+snapshot.ID+".json",
+		"application/json",
+	); err != nil {
+		return fmt.Errorf("failed to upload snapshot of %s: %w", path, err)
+	}
+	return nil
+}
+
+// History lists the snapshots of a plugin's index, or of the registry index when plugin is
+// empty, most recent first. At most limit snapshots are returned when limit is positive.
+// The contents of the indexes are left out, see Snapshot.
+func (i *Indexer) History(ctx context.Context, plugin string, limit int) ([]Snapshot, error) {
+	dir := historyDir(i.historyIndexPath(plugin))
+
+	objects, err := i.objects.List(ctx, dir)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't list %s: %v", dir, err)
+	}
+	var ids []string
+	for _, object := range objects {
+		name := strings.TrimPrefix(object.Key, dir)
+		if strings.Contains(name, "/") {
+			// not a snapshot of this index
+			continue
+		}
+		ids = append(ids, strings.TrimSuffix(name, ".json"))
+	}
+
+	slices.Sort(ids)
+	slices.Reverse(ids)
+	if limit > 0 && len(ids) > limit {
+		ids = ids[:limit]
+	}
+
+	snapshots := make([]Snapshot, 0, len(ids))
+	for _, id := range ids {
+		snapshot, err := i.Snapshot(ctx, plugin, id)
+		if err != nil {
+			return nil, err
+		}
+		snapshot.Index = nil
+		snapshots = append(snapshots, snapshot)
+	}
+	return snapshots, nil
+}
+
+// Snapshot returns a snapshot of a plugin's index, or of the registry index when plugin is
+// empty.
+func (i *Indexer) Snapshot(ctx context.Context, plugin, id string) (Snapshot, error) {
+	key := historyDir(i.historyIndexPath(plugin)) + id + ".json"
+
+	b, err := readObject(ctx, i.objects, key)
+	if errors.Is(err, ErrObjectNotFound) {
+		return Snapshot{}, fmt.Errorf("%s: %w", id, ErrSnapshotNotFound)
+	}
+	if err != nil {
+		return Snapshot{}, fmt.Errorf("couldn't get snapshot %s: %v", key, err)
+	}
+	var snapshot Snapshot
+	if err := json.Unmarshal(b, &snapshot); err != nil {
+		return Snapshot{}, fmt.Errorf("invalid snapshot %s: %v", key, err)
+	}
+	return snapshot, nil
+}
+
+// versionChanges compares the versions of a plugin index before and after a change, returning
+// the versions that were added, updated and removed, in version order.
+func versionChanges(before, after types.PluginIndex) (added, updated, removed []string) {
+	previous := make(map[string]types.PluginVersionInformation, len(before.Versions))
+	for _, version := range before.Versions {
+		previous[version.Version] = version
+	}
+
+	for _, version := range after.Versions {
+		existing, ok := previous[version.Version]
+		switch {
+		case !ok:
+			added = append(added, version.Version)
+		case !reflect.DeepEqual(existing, version):
+			updated = append(updated, version.Version)
+		}
+		delete(previous, version.Version)
+	}
+	for version := range previous {
+		removed = append(removed, version)
+	}
+
+	for _, versions := range [][]string{added, updated, removed} {
+		slices.SortFunc(versions, types.CompareVersions)
+	}
+	return added, updated, removed
+}
+
+// summarizeChange describes the change made to a plugin index, for its history.
+func summarizeChange(before, after types.PluginIndex) string {
+	added, updated, removed := versionChanges(before, after)
+
+	var changes []string
+	for _, change := range []struct {
+		verb     string
+		versions []string
+	}{
+		{"added", added},
+		{"updated", updated},
+		{"removed", removed},
+	} {
+		if len(change.versions) > 0 {
+			changes = append(changes, change.verb+" "+strings.Join(change.versions, ", "))
+		}
+	}
+	if fields := types.ChangedFields(before, after); len(fields) > 0 {
+		changes = append(changes, "changed "+strings.Join(fields, ", "))
+	}
+
+	if len(changes) == 0 {
+		return "no changes"
+	}
+	return strings.Join(changes, "; ")
+}
@@ -0,0 +1,361 @@
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/omniviewdev/registry-cli/pkg/types"
+)
+
+// DefaultHookTimeout is how long a publish hook gets to vet a tarball.
+const DefaultHookTimeout = 5 * time.Minute
+
+// PublishHook vets each tarball before a publish uploads anything, e.g. a secret scanner or an
+// antivirus. Returning an error vetoes the publish.
+type PublishHook interface {
+	// Name identifies the hook in vetoes
+	Name() string
+
+	// Check vets the tarball of a release
+	Check(ctx context.Context, release types.Release) error
+}
+
+// VetoError is returned when a publish hook vetoes a tarball.
+type VetoError struct {
+	Hook     string
+	Platform string
+	Reason   string
+}
+
+func (e *VetoError) Error() string {
+	return fmt.Sprintf("%s vetoed the %s build: %s", e.Hook, e.Platform, e.Reason)
+}
+
+// PublishEvent describes a version that was published, to the hooks run after the publish.
+type PublishEvent struct {
+	Plugin    string   `json:"plugin"`
+	Version   string   `json:"version"`
+	Platforms []string `json:"platforms"`
+
+	// Pending is set when the version awaits the approval of a registry admin rather than
+	// being published
+	Pending bool `json:"pending"`
+}
+
+// Message describes the event to people, e.g. in a chat notification.
+func (e PublishEvent) Message() string {
+	action := "was published"
+	if e.Pending {
+		action = "was submitted for review"
+	}
+	return fmt.Sprintf(
+		"%s %s %s (%s)",
+		e.Plugin,
+		e.Version,
+		action,
+		strings.Join(e.Platforms, ", "),
+	)
+}
+
+// PostPublishHook is run once a version is published, e.g. to trigger a deployment or announce
+// the release. Its failures are reported, but can't undo the publish.
+type PostPublishHook interface {
+	// Name identifies the hook in failures
+	Name() string
+
+	// Run runs the hook for the published version
+	Run(ctx context.Context, event PublishEvent) error
+}
+
+// HookConfig configures a publish hook, running either a command or an HTTP request.
+type HookConfig struct {
+	// Name identifies the hook. Defaults to the command or URL.
+	Name string `mapstructure:"name" yaml:"name"`
+
+	// Command is a shell command run for each tarball, with the tarball as $1 and in
+	// REGISTRY_ARTIFACT. It vetoes the tarball by exiting with an error.
+	Command string `mapstructure:"command" yaml:"command"`
+
+	// URL is POSTed each tarball, with the release in X-Registry-* headers. Anything but a 2xx
+	// response vetoes the tarball. It's expanded from the environment, so secret URLs like
+	// those of chat webhooks stay out of config files checked in.
+	URL string `mapstructure:"url" yaml:"url"`
+
+	// Headers are added to the requests of an HTTP hook, e.g. for authentication. Values are
+	// expanded from the environment.
+	Headers map[string]string `mapstructure:"headers" yaml:"headers"`
+
+	// Timeout bounds each check. Defaults to DefaultHookTimeout.
+	Timeout time.Duration `mapstructure:"timeout" yaml:"timeout"`
+}
+
+// Hook returns the publish hook the config describes.
+func (c HookConfig) Hook() (PublishHook, error) {
+	return c.hook()
+}
+
+// PostPublishHook returns the hook run after a publish the config describes. A command is run
+// with the version in REGISTRY_* variables, and a URL is POSTed the PublishEvent as JSON.
+func (c HookConfig) PostPublishHook() (PostPublishHook, error) {
+	return c.hook()
+}
+
+// Notification returns the hook announcing the publish the config describes, which POSTs a
+// chat message to its URL like Slack incoming webhooks take ({"text": "..."}), along with the
+// fields of the PublishEvent.
+func (c HookConfig) Notification() (PostPublishHook, error) {
+	if c.Command != "" {
+		return nil, fmt.Errorf("notification %s has a command, expected a url", c.Name)
+	}
+	hook, err := c.hook()
+	if err != nil {
+		return nil, err
+	}
+	return &notificationHook{httpHook: hook.(*httpHook)}, nil
+}
+
+// configuredHook is a hook a config describes, which runs before or after a publish alike.
+type configuredHook interface {
+	PublishHook
+	PostPublishHook
+}
+
+func (c HookConfig) hook() (configuredHook, error) {
+	if c.Timeout <= 0 {
+		c.Timeout = DefaultHookTimeout
+	}
+	switch {
+	case c.Command != "" && c.URL != "":
+		return nil, fmt.Errorf("hook %s has both a command and a url", c.Name)
+	case c.Command != "":
+		if c.Name == "" {
+			c.Name = c.Command
+		}
+		return &commandHook{config: c}, nil
+	case c.URL != "":
+		if c.Name == "" {
+			c.Name = c.URL
+		}
+		c.URL = os.ExpandEnv(c.URL)
+		return &httpHook{config: c}, nil
+	}
+	return nil, fmt.Errorf("hook %s has neither a command nor a url", c.Name)
+}
+
+// runHooks runs every hook on every tarball of the publish, returning the vetoes.
+func runHooks(ctx context.Context, hooks []PublishHook, opts types.PublishOpts) error {
+	var errs []error
+	for _, release := range opts.ToReleases() {
+		release.Artifact = opts.Artifacts[release.OSArch()]
+		for _, hook := range hooks {
+			err := hook.Check(ctx, release)
+			if err == nil {
+				continue
+			}
+			var veto *VetoError
+			if !errors.As(err, &veto) {
+				err = fmt.Errorf(
+					"publish hook %s failed on the %s build: %w",
+					hook.Name(),
+					release.OSArch(),
+					err,
+				)
+			}
+			if opts.Report != nil {
+				opts.Report.Platform(release.OSArch()).Error = err.Error()
+			}
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// RunPostPublishHooks runs every hook after a publish, returning their failures.
+func RunPostPublishHooks(ctx context.Context, hooks []PostPublishHook, event PublishEvent) error {
+	var errs []error
+	for _, hook := range hooks {
+		if err := hook.Run(ctx, event); err != nil {
+			errs = append(errs, fmt.Errorf("%s failed: %w", hook.Name(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// hookEnv describes the release to a hook command.
+func hookEnv(release types.Release) []string {
+	return []string{
+		"REGISTRY_ARTIFACT=" + release.Path,
+		"REGISTRY_PLUGIN=" + release.Plugin,
+		"REGISTRY_VERSION=" + release.Version,
+		"REGISTRY_PLATFORM=" + release.OSArch(),
+		"REGISTRY_CHECKSUM=" + release.Artifact.Checksum,
+	}
+}
+
+type commandHook struct {
+	config HookConfig
+}
+
+func (h *commandHook) Name() string {
+	return h.config.Name
+}
+
+func (h *commandHook) Check(ctx context.Context, release types.Release) error {
+	ctx, cancel := context.WithTimeout(ctx, h.config.Timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", h.config.Command, "sh", release.Path)
+	cmd.Env = append(os.Environ(), hookEnv(release)...)
+	out, err := cmd.CombinedOutput()
+	if err == nil {
+		return nil
+	}
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) || ctx.Err() != nil {
+		return fmt.Errorf("couldn't run %s: %w", h.config.Command, errors.Join(err, ctx.Err()))
+	}
+	return &VetoError{
+		Hook:     h.config.Name,
+		Platform: release.OSArch(),
+		Reason:   hookReason(out, exitErr.Error()),
+	}
+}
+
+func (h *commandHook) Run(ctx context.Context, event PublishEvent) error {
+	ctx, cancel := context.WithTimeout(ctx, h.config.Timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", h.config.Command)
+	cmd.Env = append(
+		os.Environ(),
+		"REGISTRY_PLUGIN="+event.Plugin,
+		"REGISTRY_VERSION="+event.Version,
+		"REGISTRY_PLATFORMS="+strings.Join(event.Platforms, ","),
+		"REGISTRY_PENDING="+strconv.FormatBool(event.Pending),
+	)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return errors.New(hookReason(out, errors.Join(err, ctx.Err()).Error()))
+	}
+	return nil
+}
+
+type httpHook struct {
+	config HookConfig
+}
+
+func (h *httpHook) Name() string {
+	return h.config.Name
+}
+
+func (h *httpHook) Check(ctx context.Context, release types.Release) error {
+	ctx, cancel := context.WithTimeout(ctx, h.config.Timeout)
+	defer cancel()
+
+	file, err := os.Open(release.Path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.config.URL, file)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = info.Size()
+	req.Header.Set("Content-Type", "application/gzip")
+	req.Header.Set("X-Registry-Plugin", release.Plugin)
+	req.Header.Set("X-Registry-Version", release.Version)
+	req.Header.Set("X-Registry-Platform", release.OSArch())
+	req.Header.Set("X-Registry-Artifact", release.Path)
+	if release.Artifact.Checksum != "" {
+		req.Header.Set("X-Registry-Checksum", release.Artifact.Checksum)
+	}
+	for key, value := range h.config.Headers {
+		req.Header.Set(key, os.ExpandEnv(value))
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	return &VetoError{
+		Hook:     h.config.Name,
+		Platform: release.OSArch(),
+		Reason:   hookReason(body, resp.Status),
+	}
+}
+
+func (h *httpHook) Run(ctx context.Context, event PublishEvent) error {
+	return h.post(ctx, event)
+}
+
+// post POSTs a JSON body to the hook, failing on anything but a 2xx response.
+func (h *httpHook) post(ctx context.Context, body any) error {
+	ctx, cancel := context.WithTimeout(ctx, h.config.Timeout)
+	defer cancel()
+
+	b, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.config.URL, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range h.config.Headers {
+		req.Header.Set(key, os.ExpandEnv(value))
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	out, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	return errors.New(hookReason(out, resp.Status))
+}
+
+type notificationHook struct {
+	*httpHook
+}
+
+func (h *notificationHook) Run(ctx context.Context, event PublishEvent) error {
+	return h.post(ctx, struct {
+		Text string `json:"text"`
+		PublishEvent
+	}{Text: event.Message(), PublishEvent: event})
+}
+
+// hookReason returns the last line of a hook's output as the reason for its veto, or the
+// fallback when it didn't output anything.
+func hookReason(out []byte, fallback string) string {
+	lines := strings.Split(strings.TrimSpace(string(bytes.ToValidUTF8(out, nil))), "\n")
+	if reason := strings.TrimSpace(lines[len(lines)-1]); reason != "" {
+		return reason
+	}
+	return fallback
+}
@@ -1,36 +1,88 @@
 package pkg
 
 import (
-	"bytes"
 	"context"
-	"crypto/sha256"
-	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
 	"log"
+	"net/url"
 	"os"
+	"slices"
+	"strings"
 	"time"
 
-	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/config"
-	"github.com/aws/aws-sdk-go-v2/service/s3"
-	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
 	"github.com/aws/smithy-go"
+	"github.com/omniviewdev/registry-cli/pkg/console"
+	"github.com/omniviewdev/registry-cli/pkg/signing"
 	"github.com/omniviewdev/registry-cli/pkg/types"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // Indexer is responsible for updating the index based on a release
 type Indexer struct {
-	ctx      context.Context
-	s3Client *s3.Client
-	bucket   string
+	ctx         context.Context
+	objects     ObjectStore
+	bucket      string
+	signingKey  *signing.PrivateKey
+	lock        indexLock
+	lockTimeout time.Duration
+	records     *records
+	baseURL     string
+
+	// layout is the object key layout of the registry, checked against the one it published
+	// once layoutChecked
+	layout        types.Layout
+	layoutChecked bool
 }
 
 type IndexerOpts struct {
 	Bucket  string
 	Version string
+
+	// Provider hosts the bucket, one of the Provider constants. Defaults to ProviderS3.
+	Provider string
+
+	// Endpoint is where the S3 compatible store hosting the bucket is, when it's hosted in one
+	// rather than S3
+	Endpoint Endpoint
+
+	// Pacing limits the requests made to the bucket. They aren't limited when zero.
+	Pacing Pacing
+
+	// SigningKey is the path to the key used to sign index files. Indexes are left unsigned
+	// when no key is given.
+	SigningKey string
+
+	// LockMode guards index updates with a lock so concurrent publishes don't overwrite each
+	// other's changes. One of LockModeS3, LockModeDynamoDB, or empty for no locking.
+	LockMode string
+
+	// LockTable is the DynamoDB table holding the lock when using LockModeDynamoDB. The table
+	// needs a string partition key named "id".
+	LockTable string
+
+	// LockTTL is how long a lock can be held before it's considered abandoned
+	LockTTL time.Duration
+
+	// LockTimeout is how long to wait for the lock
+	LockTimeout time.Duration
+
+	// IndexTable is a DynamoDB table holding the plugin and version records. When set, the
+	// records are the source of truth and the JSON indexes in the bucket are materialized from
+	// them, so concurrent publishes never lose each other's updates.
+	IndexTable string
+
+	// BaseURL is the URL the bucket is served from (e.g. the CDN or bucket website domain).
+	// When set, the index holds fully-qualified download URLs, otherwise they're relative to
+	// the registry.
+	BaseURL string
+
+	// Layout is the object key layout of the registry's bucket, published at types.LayoutPath
+	// for clients when it isn't the default one. Defaults to the default layout.
+	Layout types.Layout
 }
 
 func (p *IndexerOpts) Defaulter() {
@@ -41,86 +93,239 @@ func (p *IndexerOpts) Defaulter() {
 	if p.Bucket == "" {
 		p.Bucket = os.Getenv("AWS_S3_BUCKET")
 	}
+	p.Endpoint.Defaulter()
+	if p.SigningKey == "" {
+		p.SigningKey = os.Getenv("REGISTRY_SIGNING_KEY")
+	}
+	if p.LockMode == "" {
+		p.LockMode = os.Getenv("REGISTRY_LOCK")
+	}
+	if p.LockTable == "" {
+		p.LockTable = os.Getenv("REGISTRY_LOCK_TABLE")
+	}
+	if p.IndexTable == "" {
+		p.IndexTable = os.Getenv("REGISTRY_INDEX_TABLE")
+	}
+	if p.BaseURL == "" {
+		p.BaseURL = os.Getenv("REGISTRY_BASE_URL")
+	}
+	if p.LockTTL == 0 {
+		p.LockTTL = DefaultLockTTL
+	}
+	if p.LockTimeout == 0 {
+		p.LockTimeout = DefaultLockTimeout
+	}
 }
 
 // NewIndexer creates a new indexing service for updating after a release
 func NewIndexer(ctx context.Context, opts IndexerOpts) (*Indexer, error) {
-	sdkConfig, err := config.LoadDefaultConfig(ctx)
+	opts.Defaulter()
+
+	objects, err := newObjectStore(ctx, storeOpts{
+		bucket:   opts.Bucket,
+		provider: opts.Provider,
+		endpoint: opts.Endpoint,
+		pacing:   opts.Pacing,
+	})
 	if err != nil {
-		return nil, errors.New(
-			"couldn't load default configuration, have you set up your AWS account?",
-		)
+		return nil, err
 	}
-	s3Client := s3.NewFromConfig(sdkConfig)
 
-	opts.Defaulter()
+	var signingKey *signing.PrivateKey
+	if opts.SigningKey != "" {
+		if signingKey, err = signing.LoadPrivateKey(opts.SigningKey); err != nil {
+			return nil, err
+		}
+	}
+
+	if opts.BaseURL != "" {
+		base, err := url.Parse(opts.BaseURL)
+		if err != nil || !base.IsAbs() || base.Host == "" {
+			return nil, fmt.Errorf("invalid base url %q, expected e.g. https://registry.example.com", opts.BaseURL)
+		}
+	}
+
+	layout, err := types.ParseLayout(opts.Layout)
+	if err != nil {
+		return nil, err
+	}
+
+	var lock indexLock
+	switch opts.LockMode {
+	case "", "none":
+	case LockModeS3:
+		lock = &objectLock{objects: objects, ttl: opts.LockTTL}
+	case LockModeDynamoDB:
+		if opts.LockTable == "" {
+			return nil, errors.New("a lock table is required to lock with dynamodb")
+		}
+		dynamoClient, err := newDynamoClient(ctx)
+		if err != nil {
+			return nil, err
+		}
+		lock = &dynamoLock{
+			client: dynamoClient,
+			table:  opts.LockTable,
+			bucket: opts.Bucket,
+			ttl:    opts.LockTTL,
+		}
+	default:
+		return nil, fmt.Errorf("unknown lock mode %q", opts.LockMode)
+	}
+
+	var indexRecords *records
+	if opts.IndexTable != "" {
+		dynamoClient, err := newDynamoClient(ctx)
+		if err != nil {
+			return nil, err
+		}
+		indexRecords = &records{client: dynamoClient, table: opts.IndexTable}
+	}
 
 	return &Indexer{
-		ctx:      ctx,
-		s3Client: s3Client,
-		bucket:   opts.Bucket,
+		ctx:         ctx,
+		objects:     objects,
+		bucket:      opts.Bucket,
+		signingKey:  signingKey,
+		lock:        lock,
+		lockTimeout: opts.LockTimeout,
+		records:     indexRecords,
+		baseURL:     strings.TrimSuffix(opts.BaseURL, "/"),
+		layout:      layout,
 	}, nil
 }
 
 // UpdateIndex updates the plugin index with the new release
-func (i *Indexer) UpdateIndex(ctx context.Context, opts types.PublishOpts) error {
+func (i *Indexer) UpdateIndex(ctx context.Context, opts types.PublishOpts) (err error) {
+	ctx, span := tracer.Start(ctx, "index.update", trace.WithAttributes(
+		attribute.String("plugin", opts.Plugin),
+		attribute.String("version", opts.Version),
+	))
+	start := time.Now()
+	defer func() {
+		indexUpdateDuration.Record(ctx, since(start), metric.WithAttributes(outcome(err)))
+		endSpan(span, err)
+	}()
+	return i.withLock(ctx, func() error { return i.updateIndexes(ctx, opts) })
+}
+
+func (i *Indexer) updateIndexes(ctx context.Context, opts types.PublishOpts) error {
 	// get the metadata file
-	metadata := types.LoadMetadata(opts.MetadataPath)
-	index, err := i.getPluginIndex(ctx, opts.Plugin)
+	metadata, err := types.LoadMetadata(opts.MetadataPath)
+	if err != nil {
+		return err
+	}
+	// the index is read by the app, a malformed color mustn't make it there
+	if err := metadata.Theme.Validate(); err != nil {
+		return fmt.Errorf("invalid plugin metadata: %w", err)
+	}
+	index, err := i.loadPluginIndex(ctx, opts.Plugin)
 	if err != nil {
 		return err
 	}
+	if index.Tombstone != nil && index.Tombstone.Successor != "" {
+		return fmt.Errorf(
+			"%s was transferred to %s, publish it under its new id",
+			opts.Plugin,
+			index.Tombstone.Successor,
+		)
+	}
 
 	// build out our release objects
 	releases := opts.ToReleases()
-	pluginIndex := i.updateIndex(index, releases, metadata)
-	_, err = i.setPluginIndex(ctx, pluginIndex)
+	before := index
+	before.Versions = slices.Clone(index.Versions)
+	pluginIndex, err := i.updateIndex(index, releases, metadata, opts.Compatibility)
 	if err != nil {
 		return err
 	}
+	if opts.Report != nil {
+		opts.Report.Index = types.NewIndexDiff(before, pluginIndex, releases[0].Version)
+		idx := slices.IndexFunc(pluginIndex.Versions, func(v types.PluginVersionInformation) bool {
+			return v.Version == releases[0].Version
+		})
+		if idx >= 0 {
+			for arch, info := range pluginIndex.Versions[idx].Architectures {
+				platReport := opts.Report.Platform(arch)
+				platReport.Checksum = info.Checksum
+				platReport.Size = info.Size
+			}
+		}
+	}
+
+	// update the plugin and registry indexes
+	if err := i.commitPluginIndex(ctx, "publish", before, pluginIndex); err != nil {
+		return err
+	}
+	i.warnRetention(ctx, pluginIndex)
+
+	// all good!
+	return nil
+}
 
-	// update the registry index
+// updateRegistryIndex adds or replaces the plugin's entry in the registry index.
+func (i *Indexer) updateRegistryIndex(
+	ctx context.Context,
+	pluginIndex types.PluginIndex,
+	summary string,
+) error {
 	registryIndex, err := i.getRegistryIndex(ctx)
 	if err != nil {
 		return err
 	}
 
-	found := false
-	for idx, plugin := range registryIndex.Plugins {
-		if plugin.ID == pluginIndex.ID {
-			found = true
-
-			registryIndex.Plugins[idx] = types.RegistryIndexPlugins{
-				ID:            pluginIndex.ID,
-				Name:          pluginIndex.Name,
-				Icon:          pluginIndex.Icon,
-				Description:   pluginIndex.Description,
-				Official:      true,
-				LatestVersion: pluginIndex.LatestVersion,
-			}
+	registryIndex.SetPlugin(types.RegistryIndexPlugins{
+		ID:            pluginIndex.ID,
+		Name:          pluginIndex.Name,
+		Icon:          pluginIndex.Icon,
+		Description:   pluginIndex.Description,
+		Official:      true,
+		LatestVersion: pluginIndex.LatestVersion,
+		Access:        pluginIndex.Access,
+	})
 
-			break
-		}
-	}
+	_, err = i.setRegistryIndex(ctx, registryIndex, fmt.Sprintf("%s: %s", pluginIndex.ID, summary))
+	return err
+}
 
-	if !found {
-		registryIndex.Plugins = append(registryIndex.Plugins, types.RegistryIndexPlugins{
-			ID:            pluginIndex.ID,
-			Name:          pluginIndex.Name,
-			Icon:          pluginIndex.Icon,
-			Description:   pluginIndex.Description,
-			Official:      true,
-			LatestVersion: pluginIndex.LatestVersion,
-		})
+// ImportVersions merges already built versions (e.g. from another registry) into the plugin's
+// index, replacing any existing entries for the same versions, and updates the registry index.
+// The plugin details are taken from source.
+func (i *Indexer) ImportVersions(
+	ctx context.Context,
+	source types.PluginIndex,
+	versions []types.PluginVersionInformation,
+) error {
+	if len(versions) == 0 {
+		return nil
 	}
+	return i.withLock(ctx, func() error {
+		return i.importVersions(ctx, "import", source, versions)
+	})
+}
 
-	_, err = i.setRegistryIndex(ctx, registryIndex)
+// importVersions adds the versions to the plugin's index, recording the change as the action.
+func (i *Indexer) importVersions(
+	ctx context.Context,
+	action string,
+	source types.PluginIndex,
+	versions []types.PluginVersionInformation,
+) error {
+	index, err := i.loadPluginIndex(ctx, source.ID)
 	if err != nil {
 		return err
 	}
 
-	// all good!
-	return nil
+	before := index
+	before.Versions = slices.Clone(index.Versions)
+	for _, version := range versions {
+		index.SetVersion(i.qualifyVersion(version))
+	}
+	index.Name = source.Name
+	index.Icon = source.Icon
+	index.Description = source.Description
+
+	return i.commitPluginIndex(ctx, action, before, index)
 }
 
 // updateIndex updates the index based on the plugin and passed in versions. It is expected the
@@ -129,9 +334,10 @@ func (i *Indexer) updateIndex(
 	index types.PluginIndex,
 	releases []types.Release,
 	metadata types.PluginMeta,
-) types.PluginIndex {
+	compatibility types.Compatibility,
+) (types.PluginIndex, error) {
 	if len(releases) < 1 {
-		panic("cannot submit an empty number of releases")
+		return index, errors.New("cannot submit an empty number of releases")
 	}
 
 	versionInfo := types.PluginVersionInformation{
@@ -140,6 +346,7 @@ func (i *Indexer) updateIndex(
 		Created:       time.Now(),
 		Updated:       time.Now(),
 		Metadata:      metadata,
+		Compatibility: compatibility,
 	}
 
 	// build the versions out
@@ -150,58 +357,81 @@ func (i *Indexer) updateIndex(
 			continue
 		}
 		info := types.PluginArchitectureInformation{
-			Checksum:    "TODO",
-			DownloadURL: release.BucketPath(),
-		}
-
-		// Calculate Checksum
-		f, err := os.Open(release.Path)
-		if err != nil {
-			log.Fatal(err)
-		}
-		defer f.Close()
-
-		h := sha256.New()
-		if _, err := io.Copy(h, f); err != nil {
-			log.Fatal(err)
+			Checksum:    release.Artifact.Checksum,
+			Size:        release.Artifact.Size,
+			Encryption:  release.Artifact.Encryption,
+			DownloadURL: i.downloadURL(i.layout.ArtifactPath(release)),
+			ChecksumURL: i.downloadURL(i.layout.ArtifactPath(release) + types.ChecksumExt),
 		}
-		info.Checksum = hex.EncodeToString(h.Sum(nil))
 
-		// Calculate file info
-		fileInfo, err := os.Stat(release.Path)
-		if err != nil {
-			fmt.Println("Failed to calculate size: ", err)
-		} else {
-			info.Size = fileInfo.Size()
+		if info.Checksum == "" {
+			// not hashed while it was uploaded
+			checksum, size, err := hashFile(release.Path)
+			if err != nil {
+				return index, err
+			}
+			info.Checksum, info.Size = checksum, size
 		}
 
 		versionInfo.Architectures[release.OSArch()] = info
 	}
 
-	index.LatestVersion = versionInfo
-	index.Versions = append(index.Versions, versionInfo)
+	index.SetVersion(versionInfo)
 
 	// update the info using the metadata
 	index.Description = metadata.Description
 	index.Icon = metadata.Icon
 	index.Name = metadata.Name
 
-	return index
+	return index, nil
 }
 
-// getPluginIndex returns a plugin index either from the bucket if it exists, or a new one
-func (i *Indexer) getPluginIndex(ctx context.Context, plugin string) (types.PluginIndex, error) {
-	// first check the s3 bucket
-	result, err := i.s3Client.GetObject(ctx, &s3.GetObjectInput{
-		Bucket: aws.String(i.bucket),
-		Key:    aws.String(fmt.Sprintf("%s/index.json", plugin)),
-	})
-	if err != nil {
-		var noKey *s3types.NoSuchKey
-		if !errors.As(err, &noKey) {
-			return types.PluginIndex{}, fmt.Errorf("couldn't get plugin index: %v", err)
+// downloadURL returns the URL an artifact at the bucket path is downloaded from, fully
+// qualified when a base URL is configured.
+func (i *Indexer) downloadURL(bucketPath string) string {
+	if i.baseURL == "" {
+		return bucketPath
+	}
+	if u, err := url.Parse(bucketPath); err == nil && u.IsAbs() {
+		return bucketPath
+	}
+	return i.baseURL + "/" + strings.TrimPrefix(bucketPath, "/")
+}
+
+// qualifyVersion qualifies the download URLs of a version that are relative to the registry.
+func (i *Indexer) qualifyVersion(
+	version types.PluginVersionInformation,
+) types.PluginVersionInformation {
+	archs := make(map[string]types.PluginArchitectureInformation, len(version.Architectures))
+	for arch, info := range version.Architectures {
+		info.DownloadURL = i.downloadURL(info.DownloadURL)
+		if info.ChecksumURL != "" {
+			info.ChecksumURL = i.downloadURL(info.ChecksumURL)
+		}
+		archs[arch] = info
+	}
+	version.Architectures = archs
+	return version
+}
+
+// artifactKey returns the bucket path of an artifact from its download URL, which may be
+// relative to the registry or fully qualified.
+func (i *Indexer) artifactKey(downloadURL string) string {
+	if i.baseURL != "" {
+		if key, ok := strings.CutPrefix(downloadURL, i.baseURL+"/"); ok {
+			return key
 		}
+	}
+	if u, err := url.Parse(downloadURL); err == nil && u.IsAbs() {
+		return strings.TrimPrefix(u.Path, "/")
+	}
+	return strings.TrimPrefix(downloadURL, "/")
+}
 
+// getPluginIndex returns a plugin index either from the bucket if it exists, or a new one
+func (i *Indexer) getPluginIndex(ctx context.Context, plugin string) (types.PluginIndex, error) {
+	body, err := readObject(ctx, i.objects, i.layout.IndexPath(plugin))
+	if errors.Is(err, ErrObjectNotFound) {
 		// don't have an index yet, create one and return it (though it will be minimal)
 		return types.PluginIndex{
 			RegistryIndexPlugins: types.RegistryIndexPlugins{
@@ -210,19 +440,13 @@ func (i *Indexer) getPluginIndex(ctx context.Context, plugin string) (types.Plug
 			},
 		}, nil
 	}
-
-	// at this point we have an index
-
-	defer result.Body.Close()
-
-	body, err := io.ReadAll(result.Body)
 	if err != nil {
-		return types.PluginIndex{}, fmt.Errorf("couldn't read object body: %v", err)
+		return types.PluginIndex{}, fmt.Errorf("couldn't get plugin index: %v", err)
 	}
 
 	var index types.PluginIndex
-	if err := json.Unmarshal(body, &index); err != nil {
-		return index, fmt.Errorf("couldn't decode object body to json: %v", err)
+	if err := types.DecodeIndex(body, &index); err != nil {
+		return index, fmt.Errorf("couldn't decode plugin index of %s: %w", plugin, err)
 	}
 
 	return index, nil
@@ -230,69 +454,199 @@ func (i *Indexer) getPluginIndex(ctx context.Context, plugin string) (types.Plug
 
 // getRegistryIindex returns the registry index
 func (i *Indexer) getRegistryIndex(ctx context.Context) (types.RegistryIndex, error) {
-	// first check the s3 bucket
-	result, err := i.s3Client.GetObject(ctx, &s3.GetObjectInput{
-		Bucket: aws.String(i.bucket),
-		Key:    aws.String("index.json"),
-	})
-	if err != nil {
-		var noKey *s3types.NoSuchKey
-		if !errors.As(err, &noKey) {
-			return types.RegistryIndex{}, fmt.Errorf("couldn't get registry index: %v", err)
-		}
-
+	body, err := readObject(ctx, i.objects, "index.json")
+	if errors.Is(err, ErrObjectNotFound) {
 		// don't have an index yet, create one and return it (though it will be minimal)
 		return types.RegistryIndex{
 			Plugins: make([]types.RegistryIndexPlugins, 0),
 		}, nil
 	}
-
-	// at this point we have an index
-
-	defer result.Body.Close()
-
-	body, err := io.ReadAll(result.Body)
 	if err != nil {
-		return types.RegistryIndex{}, fmt.Errorf("couldn't read object body: %v", err)
+		return types.RegistryIndex{}, fmt.Errorf("couldn't get registry index: %v", err)
 	}
 
 	var index types.RegistryIndex
-	if err := json.Unmarshal(body, &index); err != nil {
-		return index, fmt.Errorf("couldn't decode object body to json: %v", err)
+	if err := types.DecodeIndex(body, &index); err != nil {
+		return index, fmt.Errorf("couldn't decode registry index: %w", err)
 	}
 
 	return index, nil
 }
 
-// setPluginIndex updates the plugin index within the storage bucket
-func (i *Indexer) setPluginIndex(ctx context.Context, index types.PluginIndex) (string, error) {
+// setPluginIndex updates the plugin index within the storage bucket, along with the latest
+// version pointer next to it and the channel pointers, recording the change in its history
+func (i *Indexer) setPluginIndex(
+	ctx context.Context,
+	index types.PluginIndex,
+	summary string,
+) (string, error) {
+	if err := i.checkLayout(ctx); err != nil {
+		return "", err
+	}
+	index.SchemaVersion = types.IndexSchemaVersion
 	b, err := json.Marshal(index)
 	if err != nil {
 		return "", fmt.Errorf("failed to upload plugin index: %v", err)
 	}
 
-	fmt.Printf("uploading plugin index to %s...\n", index.BucketPath())
-	return i.store(ctx, b, index.BucketPath())
+	path := i.layout.IndexPath(index.ID)
+	console.Printf("uploading plugin index to %s...\n", path)
+	if _, err := i.storeSigned(ctx, b, path); err != nil {
+		return "", err
+	}
+	if err := i.recordHistory(ctx, path, b, summary); err != nil {
+		return "", err
+	}
+
+	latest, err := json.Marshal(types.NewLatestVersion(index))
+	if err != nil {
+		return "", fmt.Errorf("failed to upload latest version: %v", err)
+	}
+	if _, err := i.storeSigned(ctx, latest, types.LatestVersionPath(index.ID)); err != nil {
+		return "", err
+	}
+	if err := i.setChannels(ctx, index); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// checkLayout makes sure the registry's indexes are written under the layout it published at
+// types.LayoutPath, publishing the layout of the indexer there when the registry has none and
+// it isn't the default one, so clients find the indexes.
+func (i *Indexer) checkLayout(ctx context.Context) error {
+	if i.layoutChecked {
+		return nil
+	}
+	body, err := readObject(ctx, i.objects, types.LayoutPath)
+	switch {
+	case errors.Is(err, ErrObjectNotFound):
+		if i.layout.IsDefault() {
+			break
+		}
+		b, err := json.Marshal(i.layout)
+		if err != nil {
+			return fmt.Errorf("failed to upload layout: %v", err)
+		}
+		console.Printf("uploading layout to %s...\n", types.LayoutPath)
+		if _, err := i.storeSigned(ctx, b, types.LayoutPath); err != nil {
+			return err
+		}
+	case err != nil:
+		return fmt.Errorf("couldn't get the layout of the registry: %v", err)
+	default:
+		var layout types.Layout
+		if err := json.Unmarshal(body, &layout); err != nil {
+			return fmt.Errorf("couldn't decode %s: %w", types.LayoutPath, err)
+		}
+		if !layout.Equal(i.layout) {
+			return fmt.Errorf(
+				"the registry uses the artifact layout %q and index layout %q (see %s), not %q and %q",
+				layout.Artifact,
+				layout.Index,
+				types.LayoutPath,
+				i.layout.Artifact,
+				i.layout.Index,
+			)
+		}
+	}
+	i.layoutChecked = true
+	return nil
+}
+
+// setChannels updates the pointer of each release channel of the plugin, removing the pointers
+// of channels without any versions
+func (i *Indexer) setChannels(ctx context.Context, index types.PluginIndex) error {
+	for _, channel := range types.Channels {
+		path := types.ChannelPath(index.ID, channel)
+
+		pointer, ok := types.NewChannelPointer(index, channel)
+		if !ok {
+			if err := i.delete(ctx, path); err != nil {
+				return err
+			}
+			if err := i.delete(ctx, path+signing.SignatureExt); err != nil {
+				return err
+			}
+			continue
+		}
+
+		b, err := json.Marshal(pointer)
+		if err != nil {
+			return fmt.Errorf("failed to upload %s channel: %v", channel, err)
+		}
+		if _, err := i.storeSigned(ctx, b, path); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 // setGlobalIndex updates the global index within the storage bucket
-func (i *Indexer) setRegistryIndex(ctx context.Context, index types.RegistryIndex) (string, error) {
+func (i *Indexer) setRegistryIndex(
+	ctx context.Context,
+	index types.RegistryIndex,
+	summary string,
+) (string, error) {
+	index.SchemaVersion = types.IndexSchemaVersion
 	b, err := json.Marshal(index)
 	if err != nil {
 		return "", fmt.Errorf("failed to upload plugin index: %v", err)
 	}
 
-	fmt.Printf("uploading registry index...\n")
-	return i.store(ctx, b, "index.json")
+	console.Printf("uploading registry index...\n")
+	if _, err := i.storeSigned(ctx, b, "index.json"); err != nil {
+		return "", err
+	}
+	if err := i.recordHistory(ctx, "index.json", b, summary); err != nil {
+		return "", err
+	}
+	return "index.json", nil
+}
+
+// setVersionBadge updates the latest version badge of the plugin within the storage bucket
+func (i *Indexer) setVersionBadge(ctx context.Context, index types.PluginIndex) error {
+	b, err := json.Marshal(types.NewVersionBadge(index))
+	if err != nil {
+		return fmt.Errorf("failed to upload version badge: %v", err)
+	}
+
+	path := types.VersionBadgePath(index.ID)
+	console.Printf("uploading version badge to %s...\n", path)
+	_, err = i.storeObject(ctx, b, path, "application/json")
+	return err
 }
 
-// store stores into the S3 bucket
+// storeSigned stores the index file, along with a signature alongside it when the indexer
+// has a signing key.
+func (i *Indexer) storeSigned(ctx context.Context, b []byte, bucketPath string) (string, error) {
+	if _, err := i.store(ctx, b, bucketPath); err != nil {
+		return "", err
+	}
+	if i.signingKey == nil {
+		return bucketPath, nil
+	}
+
+	sig := i.signingKey.Sign(b, bucketPath)
+	if _, err := i.store(ctx, sig, bucketPath+signing.SignatureExt); err != nil {
+		return "", fmt.Errorf("failed to upload signature for %s: %w", bucketPath, err)
+	}
+	return bucketPath, nil
+}
+
+// store stores into the bucket
 func (i *Indexer) store(ctx context.Context, b []byte, bucketPath string) (string, error) {
-	_, err := i.s3Client.PutObject(ctx, &s3.PutObjectInput{
-		Bucket: aws.String(i.bucket),
-		Key:    aws.String(bucketPath),
-		Body:   bytes.NewBuffer(b),
-	})
+	return i.storeObject(ctx, b, bucketPath, "")
+}
+
+// storeObject stores into the bucket with the given content type, leaving it to the store when
+// empty
+func (i *Indexer) storeObject(
+	ctx context.Context,
+	b []byte,
+	bucketPath, contentType string,
+) (string, error) {
+	err := i.objects.Put(ctx, bucketPath, b, PutOptions{ContentType: contentType})
 	if err != nil {
 		var apiErr smithy.APIError
 		if errors.As(err, &apiErr) && apiErr.ErrorCode() == "EntityTooLarge" {
@@ -309,15 +663,74 @@ func (i *Indexer) store(ctx context.Context, b []byte, bucketPath string) (strin
 			err,
 		)
 	}
-	err = s3.NewObjectExistsWaiter(i.s3Client).Wait(
-		ctx,
-		&s3.HeadObjectInput{
-			Bucket: aws.String(i.bucket),
-			Key:    aws.String(bucketPath),
-		}, time.Minute)
+
+	return bucketPath, nil
+}
+
+// removePlugin removes the plugin from the registry index, along with its index and pointers,
+// leaving the stub holding its tombstone in place of its index when it has one. The artifacts
+// are left to the caller.
+func (i *Indexer) removePlugin(ctx context.Context, stub types.PluginIndex, summary string) error {
+	var tombstoned *types.PluginIndex
+	if stub.Tombstone != nil {
+		tombstoned = &stub
+	}
+	if err := i.removePluginFiles(ctx, stub.ID, summary, tombstoned); err != nil {
+		return err
+	}
+
+	registryIndex, err := i.getRegistryIndex(ctx)
 	if err != nil {
-		return "", fmt.Errorf("failed attempt to wait for object %s to exist", bucketPath)
+		return err
+	}
+	removed := registryIndex.RemovePlugin(stub.ID)
+	if stub.Tombstone != nil {
+		registryIndex.SetTombstone(*stub.Tombstone)
+	} else if !removed {
+		return nil
 	}
+	_, err = i.setRegistryIndex(ctx, registryIndex, fmt.Sprintf("%s: %s", stub.ID, summary))
+	return err
+}
 
-	return bucketPath, nil
+// removePluginFiles deletes the index of the plugin and the pointers next to it, recording the
+// removal in its history. The index is replaced by the stub holding the plugin's tombstone, when
+// given.
+func (i *Indexer) removePluginFiles(
+	ctx context.Context,
+	plugin, summary string,
+	stub *types.PluginIndex,
+) error {
+	if err := i.checkLayout(ctx); err != nil {
+		return err
+	}
+	path := i.layout.IndexPath(plugin)
+	signed := []string{path, types.LatestVersionPath(plugin)}
+	for _, channel := range types.Channels {
+		signed = append(signed, types.ChannelPath(plugin, channel))
+	}
+	for _, path := range signed {
+		if err := i.delete(ctx, path); err != nil {
+			return err
+		}
+		if err := i.delete(ctx, path+signing.SignatureExt); err != nil {
+			return err
+		}
+	}
+	if err := i.delete(ctx, types.VersionBadgePath(plugin)); err != nil {
+		return err
+	}
+	if stub != nil {
+		stub.SchemaVersion = types.IndexSchemaVersion
+		b, err := json.Marshal(stub)
+		if err != nil {
+			return fmt.Errorf("failed to upload tombstone: %v", err)
+		}
+		console.Printf("uploading tombstone to %s...\n", path)
+		if _, err := i.storeSigned(ctx, b, path); err != nil {
+			return err
+		}
+		return i.recordHistory(ctx, path, b, summary)
+	}
+	return i.recordHistory(ctx, path, nil, summary)
 }
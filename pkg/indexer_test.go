@@ -0,0 +1,257 @@
+package pkg
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/omniviewdev/registry-cli/pkg/types"
+)
+
+// fuzzVersions are published out of order, and include prereleases and a v prefix
+var fuzzVersions = []string{
+	"1.0.0",
+	"1.10.0",
+	"1.2.0",
+	"0.9.0",
+	"2.0.0-beta.1",
+	"2.0.0-rc.1",
+	"2.0.0",
+	"v1.5.0",
+}
+
+var fuzzPlugins = []string{"alpha", "beta"}
+
+// publishRelease runs updateIndex for a single linux/amd64 release of the version.
+func publishRelease(
+	t *testing.T,
+	index types.PluginIndex,
+	version string,
+) types.PluginIndex {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "linux_amd64.tar.gz")
+	if err := os.WriteFile(path, []byte(index.ID+version), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	i := &Indexer{}
+	index, err := i.updateIndex(index, []types.Release{{
+		Plugin:  index.ID,
+		Version: version,
+		OS:      "linux",
+		Arch:    "amd64",
+		Path:    path,
+	}}, types.PluginMeta{ID: index.ID, Name: index.ID, Version: version}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return index
+}
+
+// yank marks the version as yanked, as an index edit would.
+func yank(index types.PluginIndex, version string) types.PluginIndex {
+	for _, v := range index.Versions {
+		if v.Version == version {
+			v.Yanked = true
+			index.SetVersion(v)
+			break
+		}
+	}
+	return index
+}
+
+// maxVersion is the expected latest version, computed independently of the index.
+func maxVersion(t *testing.T, versions map[string]bool) string {
+	t.Helper()
+	var latest *semver.Version
+	latestRaw := ""
+	for raw, yanked := range versions {
+		if yanked {
+			continue
+		}
+		v, err := semver.NewVersion(raw)
+		if err != nil {
+			t.Fatalf("fuzz version %q is invalid: %v", raw, err)
+		}
+		if latest == nil || v.GreaterThan(latest) {
+			latest, latestRaw = v, raw
+		}
+	}
+	return latestRaw
+}
+
+func checkIndexInvariants(t *testing.T, index types.PluginIndex, want map[string]bool) {
+	t.Helper()
+
+	seen := make(map[string]bool, len(index.Versions))
+	for _, v := range index.Versions {
+		if seen[v.Version] {
+			t.Fatalf("%s: duplicate entry for version %s", index.ID, v.Version)
+		}
+		seen[v.Version] = true
+
+		yanked, ok := want[v.Version]
+		if !ok {
+			t.Fatalf("%s: unexpected version %s", index.ID, v.Version)
+		}
+		if v.Yanked != yanked {
+			t.Fatalf("%s: version %s yanked=%v, want %v", index.ID, v.Version, v.Yanked, yanked)
+		}
+		if _, ok := v.Architectures["linux_amd64"]; !ok {
+			t.Fatalf("%s: version %s lost its architectures", index.ID, v.Version)
+		}
+	}
+	if len(seen) != len(want) {
+		t.Fatalf("%s: index has %d versions, want %d", index.ID, len(seen), len(want))
+	}
+
+	if latest := maxVersion(t, want); index.LatestVersion.Version != latest {
+		t.Fatalf(
+			"%s: latest version is %q, want the max non-yanked version %q",
+			index.ID,
+			index.LatestVersion.Version,
+			latest,
+		)
+	}
+}
+
+func checkRegistryInvariants(
+	t *testing.T,
+	registry types.RegistryIndex,
+	indexes map[string]types.PluginIndex,
+) {
+	t.Helper()
+
+	seen := make(map[string]bool, len(registry.Plugins))
+	for _, plugin := range registry.Plugins {
+		if seen[plugin.ID] {
+			t.Fatalf("registry index has duplicate entries for %s", plugin.ID)
+		}
+		seen[plugin.ID] = true
+
+		index, ok := indexes[plugin.ID]
+		if !ok {
+			t.Fatalf("registry index has unpublished plugin %s", plugin.ID)
+		}
+		if plugin.LatestVersion.Version != index.LatestVersion.Version {
+			t.Fatalf(
+				"registry index has %s@%s, plugin index has %s",
+				plugin.ID,
+				plugin.LatestVersion.Version,
+				index.LatestVersion.Version,
+			)
+		}
+	}
+	if len(seen) != len(indexes) {
+		t.Fatalf("registry index has %d plugins, want %d", len(seen), len(indexes))
+	}
+}
+
+// FuzzUpdateIndex replays random sequences of publishes (including republishes of existing
+// versions) and yanks across plugins, checking the index invariants after every step. Each
+// byte is an operation: the low bits pick the version, bit 4 the plugin, and bit 7 yanks
+// the version instead of publishing it.
+func FuzzUpdateIndex(f *testing.F) {
+	f.Add([]byte{0, 1, 2, 3})
+	f.Add([]byte{6, 5, 4, 6, 0, 0})
+	f.Add([]byte{7, 1, 0x81, 0x87, 0x17, 0x12})
+	f.Add([]byte{2, 2, 2, 0x82, 2})
+	f.Add([]byte{0x10, 0x04, 0x16, 0x86, 0x96, 0x05})
+
+	f.Fuzz(func(t *testing.T, ops []byte) {
+		if len(ops) > 64 {
+			ops = ops[:64]
+		}
+
+		indexes := make(map[string]types.PluginIndex)
+		published := make(map[string]map[string]bool)
+		registry := types.RegistryIndex{}
+
+		for _, op := range ops {
+			plugin := fuzzPlugins[int(op>>4&1)]
+			version := fuzzVersions[int(op&0x7)]
+
+			index, ok := indexes[plugin]
+			if !ok {
+				index = types.PluginIndex{
+					RegistryIndexPlugins: types.RegistryIndexPlugins{ID: plugin, Name: plugin},
+				}
+				published[plugin] = make(map[string]bool)
+			}
+
+			if op&0x80 != 0 {
+				if _, ok := published[plugin][version]; !ok {
+					continue
+				}
+				index = yank(index, version)
+				published[plugin][version] = true
+			} else {
+				index = publishRelease(t, index, version)
+				// republishing replaces the entry, un-yanking it
+				published[plugin][version] = false
+			}
+
+			indexes[plugin] = index
+			registry.SetPlugin(types.RegistryIndexPlugins{
+				ID:            index.ID,
+				Name:          index.Name,
+				LatestVersion: index.LatestVersion,
+			})
+
+			checkIndexInvariants(t, index, published[plugin])
+			checkRegistryInvariants(t, registry, indexes)
+		}
+	})
+}
+
+func TestUpdateIndexHealsDuplicates(t *testing.T) {
+	// indexes written before versions were deduplicated can hold the same version twice
+	archs := map[string]types.PluginArchitectureInformation{"linux_amd64": {}}
+	index := types.PluginIndex{
+		RegistryIndexPlugins: types.RegistryIndexPlugins{ID: "alpha", Name: "alpha"},
+		Versions: []types.PluginVersionInformation{
+			{Version: "1.0.0", Architectures: archs},
+			{Version: "1.1.0", Architectures: archs},
+			{Version: "1.0.0", Architectures: archs},
+		},
+	}
+
+	index = publishRelease(t, index, "1.0.0")
+	checkIndexInvariants(t, index, map[string]bool{"1.0.0": false, "1.1.0": false})
+}
+
+func TestIndexerHistory(t *testing.T) {
+	objects := newMemStore()
+	publisher, indexer := testRegistry(objects, PartialFailureAbort)
+	for _, version := range []string{"1.0.0", "1.1.0"} {
+		if err := PublishVersion(t.Context(), publisher, indexer, testPublish(t, version)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if index := pluginIndex(t, objects); index.LatestVersion.Version != "1.1.0" {
+		t.Fatalf("latest version is %s, want 1.1.0", index.LatestVersion.Version)
+	}
+
+	snapshots, err := indexer.History(t.Context(), "demo", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snapshots) != 2 {
+		t.Fatalf("got %d snapshots, want 2", len(snapshots))
+	}
+	if !strings.Contains(snapshots[0].Summary, "1.1.0") {
+		t.Fatalf("most recent snapshot is %q, want the publish of 1.1.0", snapshots[0].Summary)
+	}
+
+	usage, err := indexer.Usage(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	idx := slices.IndexFunc(usage, func(u PluginUsage) bool { return u.Plugin == "demo" })
+	if idx < 0 || usage[idx].Bytes["STANDARD"] == 0 {
+		t.Fatalf("demo has no bytes in standard storage: %+v", usage)
+	}
+}
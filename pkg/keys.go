@@ -0,0 +1,171 @@
+package pkg
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/omniviewdev/registry-cli/pkg/console"
+	"github.com/omniviewdev/registry-cli/pkg/signing"
+	"github.com/omniviewdev/registry-cli/pkg/types"
+)
+
+// DefaultKeyGracePeriod is how long the previous signing key stays trusted after a rotation
+const DefaultKeyGracePeriod = 30 * 24 * time.Hour
+
+// KeyRotation describes a rotation of the signing key.
+type KeyRotation struct {
+	Previous signing.PublicKey
+	Next     signing.PublicKey
+
+	// Retires is when the previous key stops being trusted by clients
+	Retires time.Time
+
+	// Resigned are the bucket paths of the files signed again with the next key
+	Resigned []string
+}
+
+// TrustedKeys returns the keys the registry's files are signed with.
+func (i *Indexer) TrustedKeys(ctx context.Context) (types.TrustedKeys, error) {
+	body, err := readObject(ctx, i.objects, types.TrustedKeysPath)
+	if errors.Is(err, ErrObjectNotFound) {
+		return types.TrustedKeys{}, nil
+	}
+	if err != nil {
+		return types.TrustedKeys{}, fmt.Errorf("couldn't get trusted keys: %v", err)
+	}
+	var keys types.TrustedKeys
+	if err := json.Unmarshal(body, &keys); err != nil {
+		return keys, fmt.Errorf("couldn't decode trusted keys: %v", err)
+	}
+	return keys, nil
+}
+
+// RotateKey replaces the signing key of the indexer with the next key. The next key is
+// endorsed with the current one and added to the trusted keys, the current key retiring once
+// the grace period is over, and every signed file in the bucket is signed again with the next
+// key. Clients pinning the current key follow the rotation until it retires.
+func (i *Indexer) RotateKey(
+	ctx context.Context,
+	next *signing.PrivateKey,
+	grace time.Duration,
+) (KeyRotation, error) {
+	if i.signingKey == nil {
+		return KeyRotation{}, errors.New("the current signing key is required to rotate it")
+	}
+	if grace < 0 {
+		return KeyRotation{}, errors.New("the grace period can't be negative")
+	}
+
+	rotation := KeyRotation{
+		Previous: i.signingKey.Public(),
+		Next:     next.Public(),
+	}
+	if rotation.Previous.ID == rotation.Next.ID {
+		return rotation, errors.New("the next signing key is the current one")
+	}
+
+	err := i.withLock(ctx, func() error {
+		keys, err := i.TrustedKeys(ctx)
+		if err != nil {
+			return err
+		}
+		if _, ok := keys.Find(rotation.Next.ID.String()); ok {
+			return fmt.Errorf("key %s was already used by the registry", rotation.Next.ID)
+		}
+
+		now := time.Now().UTC().Truncate(time.Second)
+		previous, ok := keys.Find(rotation.Previous.ID.String())
+		if !ok {
+			// signing since before the registry had trusted keys
+			previous = types.TrustedKey{
+				ID:    rotation.Previous.ID.String(),
+				Key:   rotation.Previous.String(),
+				Added: now,
+			}
+		}
+		if previous.Retires != nil {
+			return fmt.Errorf(
+				"%s was already rotated to another key, rotate the current key instead",
+				rotation.Previous.ID,
+			)
+		}
+		rotation.Retires = now.Add(grace)
+		previous.Retires = &rotation.Retires
+
+		keys.Set(previous)
+		keys.Set(types.TrustedKey{
+			ID:          rotation.Next.ID.String(),
+			Key:         rotation.Next.String(),
+			Added:       now,
+			EndorsedBy:  previous.ID,
+			Endorsement: string(i.signingKey.Sign([]byte(rotation.Next.String()), previous.ID)),
+		})
+		keys.Updated = now
+
+		b, err := json.Marshal(keys)
+		if err != nil {
+			return fmt.Errorf("failed to upload trusted keys: %v", err)
+		}
+		i.signingKey = next
+		console.Printf("uploading trusted keys to %s...\n", types.TrustedKeysPath)
+		if _, err := i.storeSigned(ctx, b, types.TrustedKeysPath); err != nil {
+			return err
+		}
+
+		rotation.Resigned, err = i.resign(ctx)
+		return err
+	})
+	return rotation, err
+}
+
+// Resign signs every signed file in the bucket again with the signing key of the indexer,
+// returning their bucket paths. It finishes a rotation of the signing key that was interrupted.
+func (i *Indexer) Resign(ctx context.Context) ([]string, error) {
+	if i.signingKey == nil {
+		return nil, errors.New("a signing key is required to sign the files again")
+	}
+	var resigned []string
+	err := i.withLock(ctx, func() error {
+		var err error
+		resigned, err = i.resign(ctx)
+		return err
+	})
+	return resigned, err
+}
+
+func (i *Indexer) resign(ctx context.Context) ([]string, error) {
+	objects, err := i.objects.List(ctx, "")
+	if err != nil {
+		return nil, fmt.Errorf("couldn't list %s: %v", i.bucket, err)
+	}
+	var paths []string
+	for _, object := range objects {
+		if path, ok := strings.CutSuffix(object.Key, signing.SignatureExt); ok {
+			paths = append(paths, path)
+		}
+	}
+
+	resigned := make([]string, 0, len(paths))
+	for _, path := range paths {
+		b, err := readObject(ctx, i.objects, path)
+		if errors.Is(err, ErrObjectNotFound) {
+			// a signature left behind by a removed file
+			continue
+		}
+		if err != nil {
+			return resigned, fmt.Errorf("couldn't get %s: %v", path, err)
+		}
+
+		console.Printf("signing %s...\n", path)
+		sig := i.signingKey.Sign(b, path)
+		if _, err := i.store(ctx, sig, path+signing.SignatureExt); err != nil {
+			return resigned, fmt.Errorf("failed to upload signature for %s: %w", path, err)
+		}
+		resigned = append(resigned, path)
+	}
+	return resigned, nil
+}
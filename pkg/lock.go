@@ -0,0 +1,259 @@
+package pkg
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	dynamotypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/smithy-go"
+	"github.com/omniviewdev/registry-cli/pkg/console"
+)
+
+const (
+	// LockModeS3 locks with a lock object created by a conditional put in the bucket
+	LockModeS3 = "s3"
+
+	// LockModeDynamoDB locks with a lease item in a DynamoDB table
+	LockModeDynamoDB = "dynamodb"
+
+	// lockKey is the key of the lock, both the object in the bucket and the DynamoDB item
+	lockKey = ".locks/index.lock"
+
+	// DefaultLockTTL is how long a lock is held before others may break it, in case the
+	// holder died without releasing it
+	DefaultLockTTL = 5 * time.Minute
+
+	// DefaultLockTimeout is how long to wait for a lock before giving up
+	DefaultLockTimeout = 2 * time.Minute
+)
+
+// ErrLockTimeout is returned when the index lock couldn't be acquired in time.
+var ErrLockTimeout = errors.New("timed out waiting for the index lock")
+
+// indexLock serializes the read-modify-write of the indexes between publishers.
+type indexLock interface {
+	// acquire blocks until the lock is held or ctx is done
+	acquire(ctx context.Context) error
+
+	// release releases the lock, if it's still held by us
+	release(ctx context.Context) error
+}
+
+// lease is the content of a lock
+type lease struct {
+	Owner   string    `json:"owner"`
+	Expires time.Time `json:"expires"`
+}
+
+func newLease(ttl time.Duration) lease {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	host, _ := os.Hostname()
+	return lease{
+		Owner:   fmt.Sprintf("%s/%d/%s", host, os.Getpid(), hex.EncodeToString(b)),
+		Expires: time.Now().Add(ttl),
+	}
+}
+
+// withLock runs fn while holding the index lock, if the indexer is configured with one.
+func (i *Indexer) withLock(ctx context.Context, fn func() error) error {
+	if i.lock == nil {
+		return fn()
+	}
+
+	acquireCtx, cancel := context.WithTimeout(ctx, i.lockTimeout)
+	defer cancel()
+	if err := i.lock.acquire(acquireCtx); err != nil {
+		if errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil {
+			return ErrLockTimeout
+		}
+		return err
+	}
+
+	err := fn()
+	// release even if the publish was cancelled
+	if releaseErr := i.lock.release(context.WithoutCancel(ctx)); releaseErr != nil {
+		console.Printf("⚠️ failed to release the index lock: %v\n", releaseErr)
+	}
+	return err
+}
+
+// waitForLock sleeps before retrying to acquire a lock, returning early when ctx is done.
+func waitForLock(ctx context.Context, attempt int) error {
+	delay := time.Duration(250*(1<<min(attempt, 4))) * time.Millisecond
+	if attempt == 1 {
+		console.Println("waiting for the index lock...")
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(delay):
+		return nil
+	}
+}
+
+// objectLock is a lock object in the bucket created with a conditional put, so only one writer
+// can create it.
+type objectLock struct {
+	objects ObjectStore
+	ttl     time.Duration
+	etag    string
+}
+
+func (l *objectLock) acquire(ctx context.Context) error {
+	for attempt := 0; ; attempt++ {
+		held := newLease(l.ttl)
+		b, err := json.Marshal(held)
+		if err != nil {
+			return err
+		}
+
+		etag, err := l.objects.PutIfAbsent(ctx, lockKey, b)
+		if err == nil {
+			l.etag = etag
+			return nil
+		}
+		if !errors.Is(err, ErrPreconditionFailed) {
+			return fmt.Errorf("couldn't acquire the index lock: %v", err)
+		}
+
+		// held by someone else, break it if it has expired
+		if err := l.breakExpired(ctx); err != nil {
+			return err
+		}
+		if err := waitForLock(ctx, attempt+1); err != nil {
+			return err
+		}
+	}
+}
+
+// breakExpired deletes the lock object if its lease has expired. The lease read is only
+// deleted if it's still the one described, so a lock taken in between is left alone.
+func (l *objectLock) breakExpired(ctx context.Context) error {
+	info, err := l.objects.Head(ctx, lockKey)
+	if errors.Is(err, ErrObjectNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("couldn't read the index lock: %v", err)
+	}
+	b, err := readObject(ctx, l.objects, lockKey)
+	if errors.Is(err, ErrObjectNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("couldn't read the index lock: %v", err)
+	}
+
+	var held lease
+	err = json.Unmarshal(b, &held)
+	if err == nil && time.Now().Before(held.Expires) {
+		return nil
+	}
+
+	console.Printf("breaking expired index lock held by %s\n", held.Owner)
+	err = l.objects.DeleteIfMatch(ctx, lockKey, info.ETag)
+	if err != nil && !errors.Is(err, ErrPreconditionFailed) {
+		return fmt.Errorf("couldn't break the expired index lock: %v", err)
+	}
+	return nil
+}
+
+func (l *objectLock) release(ctx context.Context) error {
+	err := l.objects.DeleteIfMatch(ctx, lockKey, l.etag)
+	if err != nil && !errors.Is(err, ErrPreconditionFailed) {
+		return err
+	}
+	return nil
+}
+
+// dynamoLock is a lease item in a DynamoDB table with a string partition key named "id".
+type dynamoLock struct {
+	client *dynamodb.Client
+	table  string
+	bucket string
+	ttl    time.Duration
+	owner  string
+}
+
+func (l *dynamoLock) id() string {
+	// the table can be shared between registries
+	return l.bucket + "/" + lockKey
+}
+
+func (l *dynamoLock) acquire(ctx context.Context) error {
+	for attempt := 0; ; attempt++ {
+		held := newLease(l.ttl)
+		_, err := l.client.PutItem(ctx, &dynamodb.PutItemInput{
+			TableName: aws.String(l.table),
+			Item: map[string]dynamotypes.AttributeValue{
+				"id":      &dynamotypes.AttributeValueMemberS{Value: l.id()},
+				"owner":   &dynamotypes.AttributeValueMemberS{Value: held.Owner},
+				"expires": unixAttribute(held.Expires),
+			},
+			ConditionExpression: aws.String("attribute_not_exists(id) OR expires < :now"),
+			ExpressionAttributeValues: map[string]dynamotypes.AttributeValue{
+				":now": unixAttribute(time.Now()),
+			},
+		})
+		if err == nil {
+			l.owner = held.Owner
+			return nil
+		}
+		var conditionFailed *dynamotypes.ConditionalCheckFailedException
+		if !errors.As(err, &conditionFailed) {
+			return fmt.Errorf("couldn't acquire the index lock: %v", err)
+		}
+		if err := waitForLock(ctx, attempt+1); err != nil {
+			return err
+		}
+	}
+}
+
+func (l *dynamoLock) release(ctx context.Context) error {
+	_, err := l.client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName: aws.String(l.table),
+		Key: map[string]dynamotypes.AttributeValue{
+			"id": &dynamotypes.AttributeValueMemberS{Value: l.id()},
+		},
+		ConditionExpression: aws.String("#owner = :owner"),
+		ExpressionAttributeNames: map[string]string{
+			"#owner": "owner",
+		},
+		ExpressionAttributeValues: map[string]dynamotypes.AttributeValue{
+			":owner": &dynamotypes.AttributeValueMemberS{Value: l.owner},
+		},
+	})
+	var conditionFailed *dynamotypes.ConditionalCheckFailedException
+	if err != nil && !errors.As(err, &conditionFailed) {
+		return err
+	}
+	return nil
+}
+
+func unixAttribute(t time.Time) dynamotypes.AttributeValue {
+	return &dynamotypes.AttributeValueMemberN{Value: strconv.FormatInt(t.Unix(), 10)}
+}
+
+// isAPIError checks whether err is an API error with one of the codes.
+func isAPIError(err error, codes ...string) bool {
+	var apiErr smithy.APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	for _, code := range codes {
+		if apiErr.ErrorCode() == code {
+			return true
+		}
+	}
+	return false
+}
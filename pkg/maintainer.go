@@ -0,0 +1,244 @@
+package pkg
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/omniviewdev/registry-cli/pkg/console"
+	"github.com/omniviewdev/registry-cli/pkg/types"
+)
+
+// ErrChallengeNotMet is returned when a maintainer hasn't (yet) met their challenge.
+var ErrChallengeNotMet = errors.New("challenge not met")
+
+// githubAPI is the base URL of the GitHub API
+const githubAPI = "https://api.github.com"
+
+// Maintainers returns the verification records of the registry's maintainers.
+func (i *Indexer) Maintainers(ctx context.Context) (types.MaintainerIndex, error) {
+	body, err := readObject(ctx, i.objects, types.MaintainersPath)
+	if errors.Is(err, ErrObjectNotFound) {
+		return types.MaintainerIndex{}, nil
+	}
+	if err != nil {
+		return types.MaintainerIndex{}, fmt.Errorf("couldn't get maintainer records: %v", err)
+	}
+	var index types.MaintainerIndex
+	if err := json.Unmarshal(body, &index); err != nil {
+		return index, fmt.Errorf("couldn't decode maintainer records: %v", err)
+	}
+	return index, nil
+}
+
+// RequestVerification issues a new challenge for the maintainer to prove their identity with,
+// recording it as pending and replacing any earlier record of the maintainer. The subject is the
+// GitHub account for github, and defaults to the email domain for dns. The challenge is returned
+// to be handed to the maintainer, only its hash is recorded.
+func (i *Indexer) RequestVerification(
+	ctx context.Context,
+	email string,
+	method types.VerificationMethod,
+	subject string,
+) (types.MaintainerRecord, string, error) {
+	address, err := mail.ParseAddress(email)
+	if err != nil {
+		return types.MaintainerRecord{}, "", fmt.Errorf("invalid email address %q: %w", email, err)
+	}
+	email = address.Address
+
+	switch method {
+	case types.VerifyDNS:
+		if subject == "" {
+			subject = email[strings.LastIndex(email, "@")+1:]
+		}
+	case types.VerifyGitHub:
+		if subject == "" {
+			return types.MaintainerRecord{}, "", errors.New("the GitHub account to verify is required")
+		}
+	case types.VerifyEmail:
+		subject = email
+	}
+
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return types.MaintainerRecord{}, "", err
+	}
+	challenge := hex.EncodeToString(b)
+	record := types.MaintainerRecord{
+		Email:     email,
+		Method:    method,
+		Subject:   subject,
+		Status:    types.MaintainerPending,
+		Challenge: types.HashChallenge(challenge),
+		Requested: time.Now().UTC().Truncate(time.Second),
+	}
+
+	err = i.updateMaintainers(ctx, func(index *types.MaintainerIndex) error {
+		index.Set(record)
+		return nil
+	})
+	if err != nil {
+		return types.MaintainerRecord{}, "", err
+	}
+	return record, challenge, nil
+}
+
+// CompleteVerification checks the pending challenge of the maintainer is met, recording them as
+// verified when it is. For the email method, the challenge the maintainer sent back is given,
+// the other methods look the challenge up themselves.
+func (i *Indexer) CompleteVerification(
+	ctx context.Context,
+	email, challenge string,
+) (types.MaintainerRecord, error) {
+	var verified types.MaintainerRecord
+	err := i.updateMaintainers(ctx, func(index *types.MaintainerIndex) error {
+		record, ok := index.Find(email)
+		if !ok {
+			return fmt.Errorf("no verification was requested for %s", email)
+		}
+		if record.Status == types.MaintainerVerified {
+			verified = record
+			return nil
+		}
+
+		if err := checkChallenge(ctx, record, challenge); err != nil {
+			return err
+		}
+		now := time.Now().UTC().Truncate(time.Second)
+		record.Status = types.MaintainerVerified
+		record.Challenge = ""
+		record.Verified = &now
+		record.VerifiedBy = Actor()
+		index.Set(record)
+		verified = record
+		return nil
+	})
+	return verified, err
+}
+
+// RevokeMaintainer removes the verification record of the maintainer.
+func (i *Indexer) RevokeMaintainer(ctx context.Context, email string) error {
+	return i.updateMaintainers(ctx, func(index *types.MaintainerIndex) error {
+		if !index.Remove(email) {
+			return fmt.Errorf("no verification record for %s", email)
+		}
+		return nil
+	})
+}
+
+// updateMaintainers updates the maintainer records under the index lock.
+func (i *Indexer) updateMaintainers(
+	ctx context.Context,
+	update func(index *types.MaintainerIndex) error,
+) error {
+	return i.withLock(ctx, func() error {
+		index, err := i.Maintainers(ctx)
+		if err != nil {
+			return err
+		}
+		if err := update(&index); err != nil {
+			return err
+		}
+		index.Updated = time.Now().UTC()
+
+		b, err := json.Marshal(index)
+		if err != nil {
+			return fmt.Errorf("failed to upload maintainer records: %v", err)
+		}
+		console.Printf("uploading maintainer records to %s...\n", types.MaintainersPath)
+		_, err = i.storeSigned(ctx, b, types.MaintainersPath)
+		return err
+	})
+}
+
+// checkChallenge checks the pending challenge of the record is met.
+func checkChallenge(ctx context.Context, record types.MaintainerRecord, challenge string) error {
+	var candidates []string
+	switch record.Method {
+	case types.VerifyEmail:
+		candidates = []string{challenge}
+	case types.VerifyDNS:
+		name := types.DNSChallengeName(record.Subject)
+		txts, err := net.DefaultResolver.LookupTXT(ctx, name)
+		var dnsErr *net.DNSError
+		if err != nil && !(errors.As(err, &dnsErr) && dnsErr.IsNotFound) {
+			return fmt.Errorf("couldn't look up the TXT records of %s: %w", name, err)
+		}
+		for _, txt := range txts {
+			if c, ok := strings.CutPrefix(strings.TrimSpace(txt), types.ChallengePrefix); ok {
+				candidates = append(candidates, c)
+			}
+		}
+	case types.VerifyGitHub:
+		descriptions, err := gistDescriptions(ctx, record.Subject)
+		if err != nil {
+			return err
+		}
+		for _, description := range descriptions {
+			for _, field := range strings.Fields(description) {
+				if c, ok := strings.CutPrefix(field, types.ChallengePrefix); ok {
+					candidates = append(candidates, c)
+				}
+			}
+		}
+	default:
+		return fmt.Errorf("unknown verification method %q", record.Method)
+	}
+
+	for _, c := range candidates {
+		if record.MatchesChallenge(c) {
+			return nil
+		}
+	}
+	return fmt.Errorf(
+		"%w for %s (%s %s)",
+		ErrChallengeNotMet,
+		record.Email,
+		record.Method,
+		record.Subject,
+	)
+}
+
+// gistDescriptions returns the descriptions of the public gists of a GitHub account.
+func gistDescriptions(ctx context.Context, account string) ([]string, error) {
+	u := fmt.Sprintf("%s/users/%s/gists?per_page=100", githubAPI, url.PathEscape(account))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't list the gists of %s: %w", account, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf(
+			"couldn't list the gists of %s: unexpected status %s",
+			account,
+			resp.Status,
+		)
+	}
+
+	var gists []struct {
+		Description string `json:"description"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&gists); err != nil {
+		return nil, fmt.Errorf("couldn't decode the gists of %s: %w", account, err)
+	}
+	descriptions := make([]string, 0, len(gists))
+	for _, gist := range gists {
+		descriptions = append(descriptions, gist.Description)
+	}
+	return descriptions, nil
+}
@@ -0,0 +1,245 @@
+package pkg
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/omniviewdev/registry-cli/pkg/client"
+	"github.com/omniviewdev/registry-cli/pkg/console"
+	"github.com/omniviewdev/registry-cli/pkg/types"
+)
+
+// MirrorTarget selects a plugin, and optionally specific versions of it, to mirror.
+type MirrorTarget struct {
+	Plugin string
+
+	// Versions pins the versions to mirror. All versions are mirrored when empty.
+	Versions []string
+}
+
+// selectVersions returns the versions of the index selected by the target.
+func (t MirrorTarget) selectVersions(
+	index types.PluginIndex,
+) ([]types.PluginVersionInformation, error) {
+	if len(t.Versions) == 0 {
+		return index.Versions, nil
+	}
+
+	versions := make([]types.PluginVersionInformation, 0, len(t.Versions))
+	for _, want := range t.Versions {
+		idx := slices.IndexFunc(index.Versions, func(v types.PluginVersionInformation) bool {
+			return v.Version == want
+		})
+		if idx == -1 {
+			return nil, fmt.Errorf("version %s of %s not found upstream", want, t.Plugin)
+		}
+		versions = append(versions, index.Versions[idx])
+	}
+	return versions, nil
+}
+
+// ParseMirrorTargets parses plugin selectors of the form `plugin` or `plugin@version`. Pinning
+// multiple versions of a plugin is done by repeating it.
+func ParseMirrorTargets(selectors []string) []MirrorTarget {
+	targets := make([]MirrorTarget, 0, len(selectors))
+	byPlugin := make(map[string]int)
+
+	for _, selector := range selectors {
+		plugin, version, _ := strings.Cut(strings.TrimSpace(selector), "@")
+		if plugin == "" {
+			continue
+		}
+		idx, ok := byPlugin[plugin]
+		if !ok {
+			idx = len(targets)
+			byPlugin[plugin] = idx
+			targets = append(targets, MirrorTarget{Plugin: plugin})
+		}
+		if version != "" {
+			targets[idx].Versions = append(targets[idx].Versions, version)
+		}
+	}
+	return targets
+}
+
+// Mirror copies plugins from an upstream registry into the bucket of this registry, rewriting
+// download URLs to point at the mirrored artifacts.
+type Mirror struct {
+	upstream   *client.Client
+	publisher  *Publisher
+	indexer    *Indexer
+	checkpoint *Checkpoint
+}
+
+// NewMirror creates a mirror from the upstream registry into the publisher/indexer's bucket.
+func NewMirror(upstream *client.Client, publisher *Publisher, indexer *Indexer) *Mirror {
+	return &Mirror{upstream: upstream, publisher: publisher, indexer: indexer}
+}
+
+// WithCheckpoint records the progress of the mirror, skipping the plugins and artifacts an
+// earlier run already mirrored.
+func (m *Mirror) WithCheckpoint(checkpoint *Checkpoint) *Mirror {
+	m.checkpoint = checkpoint
+	return m
+}
+
+// Run mirrors each of the targets.
+func (m *Mirror) Run(ctx context.Context, targets []MirrorTarget) error {
+	for _, target := range targets {
+		if err := m.mirrorPlugin(ctx, target); err != nil {
+			return fmt.Errorf("failed to mirror %s: %w", target.Plugin, err)
+		}
+	}
+	return nil
+}
+
+func (m *Mirror) mirrorPlugin(ctx context.Context, target MirrorTarget) error {
+	step := "plugin/" + target.Plugin
+	if m.checkpoint.Done(step, nil) {
+		console.Printf("skipping %s, already mirrored\n", target.Plugin)
+		return nil
+	}
+
+	index, err := m.upstream.PluginIndex(ctx, target.Plugin)
+	if err != nil {
+		return err
+	}
+
+	versions, err := target.selectVersions(index)
+	if err != nil {
+		return err
+	}
+
+	mirrored := make([]types.PluginVersionInformation, 0, len(versions))
+	for _, version := range versions {
+		console.Printf("mirroring %s[%s]...\n", target.Plugin, version.Version)
+		mv, err := m.mirrorVersion(ctx, target.Plugin, version)
+		if err != nil {
+			return err
+		}
+		mirrored = append(mirrored, mv)
+	}
+
+	if err := m.indexer.ImportVersions(ctx, index, mirrored); err != nil {
+		return err
+	}
+	return m.checkpoint.Complete(step, nil)
+}
+
+// mirrorVersion copies every artifact of the version, returning the version information with
+// the download URLs rewritten to the mirrored locations.
+func (m *Mirror) mirrorVersion(
+	ctx context.Context,
+	plugin string,
+	version types.PluginVersionInformation,
+) (types.PluginVersionInformation, error) {
+	archs := sortedArchs(version)
+
+	mirrored := version
+	mirrored.Architectures = make(map[string]types.PluginArchitectureInformation, len(archs))
+
+	for _, arch := range archs {
+		step := fmt.Sprintf("artifact/%s/%s/%s", plugin, version.Version, arch)
+		var done types.PluginArchitectureInformation
+		if m.checkpoint.Done(step, &done) {
+			mirrored.Architectures[arch] = done
+			continue
+		}
+
+		info := version.Architectures[arch]
+		release, err := releaseFor(plugin, version.Version, arch)
+		if err != nil {
+			return mirrored, err
+		}
+		path, err := downloadArtifact(ctx, m.upstream, info)
+		if err != nil {
+			return mirrored, err
+		}
+		release.Path = path
+
+		key, err := m.publisher.Upload(ctx, release)
+		os.Remove(path)
+		if err != nil {
+			return mirrored, err
+		}
+
+		info.DownloadURL = key
+		info.ChecksumURL = key + types.ChecksumExt
+		mirrored.Architectures[arch] = info
+		if err := m.checkpoint.Complete(step, info); err != nil {
+			return mirrored, err
+		}
+	}
+
+	return mirrored, nil
+}
+
+// releaseFor builds the release for an architecture key (os_arch) of a plugin version.
+func releaseFor(plugin, version, arch string) (types.Release, error) {
+	osName, archName, ok := strings.Cut(arch, "_")
+	if !ok {
+		return types.Release{}, fmt.Errorf("unexpected architecture key %q", arch)
+	}
+	return types.Release{
+		Plugin:  plugin,
+		Version: version,
+		OS:      osName,
+		Arch:    archName,
+		Created: time.Now(),
+	}, nil
+}
+
+// sortedArchs returns the architecture keys of a version in a stable order.
+func sortedArchs(version types.PluginVersionInformation) []string {
+	archs := make([]string, 0, len(version.Architectures))
+	for arch := range version.Architectures {
+		archs = append(archs, arch)
+	}
+	sort.Strings(archs)
+	return archs
+}
+
+// downloadArtifact fetches an artifact into a temporary file, verifying its checksum. The
+// caller is responsible for removing the file.
+func downloadArtifact(
+	ctx context.Context,
+	c *client.Client,
+	info types.PluginArchitectureInformation,
+) (string, error) {
+	body, err := c.Open(ctx, info.DownloadURL)
+	if err != nil {
+		return "", err
+	}
+	defer body.Close()
+
+	f, err := os.CreateTemp("", "registry-mirror-*.tar.gz")
+	if err != nil {
+		return "", fmt.Errorf("couldn't create temporary file: %w", err)
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(f, h), body); err != nil {
+		os.Remove(f.Name())
+		return "", fmt.Errorf("couldn't download %s: %w", info.DownloadURL, err)
+	}
+	if sum := hex.EncodeToString(h.Sum(nil)); !strings.EqualFold(sum, info.Checksum) {
+		os.Remove(f.Name())
+		return "", fmt.Errorf(
+			"checksum mismatch for %s: expected %s, got %s",
+			info.DownloadURL,
+			info.Checksum,
+			sum,
+		)
+	}
+
+	return f.Name(), nil
+}
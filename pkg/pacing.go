@@ -0,0 +1,119 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/aws/smithy-go/middleware"
+)
+
+// Pacing limits the requests made to the bucket, so bulk operations on large registries (e.g.
+// mirroring, gc, checking the bucket or seeding the index) don't trip the throttling of the
+// storage provider. Every attempt of a request is paced, including retries. The indexer and the
+// publisher each pace their own requests.
+type Pacing struct {
+	// Rate is the most requests started per second, unlimited when zero
+	Rate float64
+
+	// Concurrency is the most requests in flight at once, unlimited when zero
+	Concurrency int
+}
+
+// Validate checks that neither the rate nor the concurrency is negative.
+func (p Pacing) Validate() error {
+	if p.Rate < 0 {
+		return errors.New("the request rate can't be negative")
+	}
+	if p.Concurrency < 0 {
+		return errors.New("the request concurrency can't be negative")
+	}
+	return nil
+}
+
+// newRequestPacer returns the pacer of the requests of a store, nil when they're not paced.
+func newRequestPacer(pacing Pacing) (*requestPacer, error) {
+	if err := pacing.Validate(); err != nil {
+		return nil, err
+	}
+	if pacing.Rate == 0 && pacing.Concurrency == 0 {
+		return nil, nil
+	}
+
+	pacer := &requestPacer{}
+	if pacing.Rate > 0 {
+		pacer.interval = time.Duration(float64(time.Second) / pacing.Rate)
+	}
+	if pacing.Concurrency > 0 {
+		pacer.slots = make(chan struct{}, pacing.Concurrency)
+	}
+	return pacer, nil
+}
+
+// requestPacer spaces requests out by an interval, and bounds how many are in flight.
+type requestPacer struct {
+	interval time.Duration
+	slots    chan struct{}
+
+	mu   sync.Mutex
+	next time.Time
+}
+
+// wait waits for the request's turn, returning the func to call once it's done.
+func (p *requestPacer) wait(ctx context.Context) (func(), error) {
+	if p.slots != nil {
+		select {
+		case p.slots <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	done := func() {
+		if p.slots != nil {
+			<-p.slots
+		}
+	}
+	if p.interval == 0 {
+		return done, nil
+	}
+
+	p.mu.Lock()
+	now := time.Now()
+	turn := p.next
+	if turn.Before(now) {
+		turn = now
+	}
+	p.next = turn.Add(p.interval)
+	p.mu.Unlock()
+
+	timer := time.NewTimer(time.Until(turn))
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return done, nil
+	case <-ctx.Done():
+		done()
+		return nil, ctx.Err()
+	}
+}
+
+// addMiddleware adds the pacing to a client's middleware stack, after the retries so each
+// attempt waits its turn.
+func (p *requestPacer) addMiddleware(stack *middleware.Stack) error {
+	return stack.Finalize.Add(middleware.FinalizeMiddlewareFunc(
+		"RequestPacing",
+		func(
+			ctx context.Context,
+			in middleware.FinalizeInput,
+			next middleware.FinalizeHandler,
+		) (middleware.FinalizeOutput, middleware.Metadata, error) {
+			done, err := p.wait(ctx)
+			if err != nil {
+				return middleware.FinalizeOutput{}, middleware.Metadata{}, err
+			}
+			defer done()
+			return next.HandleFinalize(ctx, in)
+		},
+	), middleware.After)
+}
@@ -0,0 +1,26 @@
+package pkg
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNewRequestPacer(t *testing.T) {
+	if pacer, err := newRequestPacer(Pacing{}); err != nil || pacer != nil {
+		t.Fatalf("got %v, %v, want no pacer when unpaced", pacer, err)
+	}
+
+	pacer, err := newRequestPacer(Pacing{Rate: 4, Concurrency: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pacer.interval != 250*time.Millisecond || cap(pacer.slots) != 2 {
+		t.Fatalf("got an interval of %v and %d slots, want 250ms and 2", pacer.interval, cap(pacer.slots))
+	}
+
+	for _, pacing := range []Pacing{{Rate: -1}, {Concurrency: -1}} {
+		if _, err := newRequestPacer(pacing); err == nil {
+			t.Errorf("%+v: got no error", pacing)
+		}
+	}
+}
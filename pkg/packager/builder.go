@@ -5,46 +5,228 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"slices"
+	"strings"
 	"sync"
+	"time"
+
+	"github.com/omniviewdev/registry-cli/pkg/console"
+	"github.com/omniviewdev/registry-cli/pkg/progress"
 )
 
 type BuildResult struct {
 	Platform  Platform
 	OutputDir string
+	Duration  time.Duration
 	Err       error
+
+	// Log is the path of the build log
+	Log string
+}
+
+// UIBuildResult is the result of the shared UI build.
+type UIBuildResult struct {
+	Duration time.Duration
+	Err      error
+
+	// Log is the path of the build log, holding the output of every UI target's build
+	Log string
+}
+
+// LogDir is the directory under the output directory the build logs are written to.
+const LogDir = "logs"
+
+// BuildOpts configures a BuildAll run.
+type BuildOpts struct {
+	PluginDir string
+	Version   string
+	OutDir    string
+	Platforms []Platform
+
+	// GoCache overrides GOCACHE for the binary builds, allowing a shared build cache
+	GoCache string
+
+	// GoModCache overrides GOMODCACHE for the binary builds, allowing a shared module cache
+	GoModCache string
+
+	// GoCacheProg sets GOCACHEPROG for the binary builds, delegating caching to an external
+	// cache backend program
+	GoCacheProg string
+
+	// PluginID and Commit are injected into the binaries along with Version
+	PluginID string
+	Commit   string
+
+	// LDFlags controls which variables the build info is injected into
+	LDFlags LDFlagVars
+
+	// Metadata, if set, is written into each package in place of the plugin's plugin.yaml
+	Metadata *PluginMetadata
+
+	// Progress reports the status of the builds. Defaults to plain lines on stdout.
+	Progress progress.Tracker
+}
+
+// LDFlagVars holds the fully qualified variable paths (e.g. main.pluginVersion) that the
+// plugin ID, version and commit are injected into with -ldflags -X. Empty paths are skipped.
+type LDFlagVars struct {
+	ID      string
+	Version string
+	Commit  string
+}
+
+// DefaultLDFlagVars are the variable paths used when none are configured.
+var DefaultLDFlagVars = LDFlagVars{
+	ID:      "main.pluginID",
+	Version: "main.pluginVersion",
+	Commit:  "main.pluginCommit",
+}
+
+// ldflags builds the -ldflags value injecting the build info into the binary.
+func (o BuildOpts) ldflags() string {
+	var flags []string
+	for _, v := range []struct{ path, value string }{
+		{o.LDFlags.ID, o.PluginID},
+		{o.LDFlags.Version, o.Version},
+		{o.LDFlags.Commit, o.Commit},
+	} {
+		if v.path == "" || v.value == "" {
+			continue
+		}
+		flags = append(flags, fmt.Sprintf("-X '%s=%s'", v.path, v.value))
+	}
+	return strings.Join(flags, " ")
+}
+
+// goEnv returns the extra environment for the go toolchain based on the cache settings.
+func (o BuildOpts) goEnv() ([]string, error) {
+	var env []string
+
+	for _, v := range []struct{ name, path string }{
+		{"GOCACHE", o.GoCache},
+		{"GOMODCACHE", o.GoModCache},
+	} {
+		if v.path == "" {
+			continue
+		}
+		// the go toolchain requires these to be absolute, and builds run within the plugin dir
+		abs, err := filepath.Abs(v.path)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s path %q: %w", v.name, v.path, err)
+		}
+		if err := os.MkdirAll(abs, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create %s directory: %w", v.name, err)
+		}
+		env = append(env, v.name+"="+abs)
+	}
+
+	if o.GoCacheProg != "" {
+		env = append(env, "GOCACHEPROG="+o.GoCacheProg)
+	}
+
+	return env, nil
+}
+
+// uiEnv returns the build info exported to the UI build so bundles can display their version.
+// Custom build commands get it as well.
+func (o BuildOpts) uiEnv() []string {
+	return []string{
+		"PLUGIN_ID=" + o.PluginID,
+		"PLUGIN_VERSION=" + o.Version,
+		"COMMIT=" + o.Commit,
+	}
+}
+
+// ui returns the UI build config of the plugin, if any.
+func (o BuildOpts) ui() *UIConfig {
+	if o.Metadata == nil {
+		return nil
+	}
+	return o.Metadata.UI
+}
+
+// tracker returns the tracker reporting the builds.
+func (o BuildOpts) tracker() progress.Tracker {
+	if o.Progress == nil {
+		return progress.Plain(console.Stdout)
+	}
+	return o.Progress
+}
+
+// build returns the binary build config of the plugin, if any.
+func (o BuildOpts) build() *BuildConfig {
+	if o.Metadata == nil {
+		return nil
+	}
+	return o.Metadata.Build
 }
 
-// BuildAll builds binaries concurrently and runs the UI build once.
-// It places the UI and binaries into per-platform directories under `outdir`.
-func BuildAll(pluginDir, version, outdir string, platforms []Platform) []BuildResult {
+// BuildAll builds binaries concurrently and runs the UI build once (or once per UI target).
+// It places the UI and binaries into per-platform directories under `outdir`. The result of
+// each platform is the one of its binary build, see Packageable for the platforms to package.
+func BuildAll(opts BuildOpts) ([]BuildResult, UIBuildResult) {
+	pluginDir, outdir, platforms := opts.PluginDir, opts.OutDir, opts.Platforms
+	tracker := opts.tracker()
+
+	goEnv, err := opts.goEnv()
+	if err != nil {
+		// nothing will build without a valid toolchain environment
+		results := make([]BuildResult, len(platforms))
+		for i, plat := range platforms {
+			results[i] = BuildResult{Platform: plat, Err: err}
+		}
+		return results, UIBuildResult{}
+	}
+
 	// Step 1: Prepare all output dirs
+	logDir := filepath.Join(pluginDir, outdir, LogDir)
+	if err := os.RemoveAll(logDir); err != nil {
+		tracker.Logf("❌ Failed to clear the build logs: %v", err)
+	}
+	if err := os.MkdirAll(logDir, 0755); err != nil {
+		tracker.Logf("❌ Failed to create the build log dir: %v", err)
+	}
 	outputDirs := map[string]string{}
 	for _, plat := range platforms {
 		dir := filepath.Join(pluginDir, outdir, plat.Key())
 		if err := os.MkdirAll(filepath.Join(dir, "bin"), 0755); err != nil {
-			fmt.Printf("❌ Failed to create output dir for %s: %v\n", plat.Key(), err)
+			tracker.Logf("❌ Failed to create output dir for %s: %v", plat.Key(), err)
 			continue
 		}
 		outputDirs[plat.Key()] = dir
 	}
 
-	// Step 2: Copy plugin.yaml meta into root of package
-	pluginMeta := filepath.Join(pluginDir, "plugin.yaml")
+	// Step 2: Copy plugin.yaml meta into root of package, always as YAML
+	meta := opts.Metadata
+	if meta == nil {
+		metaFile, err := FindMetadataFile(pluginDir)
+		if err == nil {
+			meta, err = LoadPluginMetadata(metaFile)
+		}
+		if err != nil {
+			tracker.Logf("❌ Failed to load the plugin metadata: %v", err)
+		}
+	}
 	for _, plat := range platforms {
+		if meta == nil {
+			break
+		}
 		dest := filepath.Join(outputDirs[plat.Key()], "plugin.yaml")
-		if err := CopyFile(pluginMeta, dest); err != nil {
-			fmt.Printf("❌ Failed to copy plugin.yaml to %s: %v\n", plat.Key(), err)
+		if err := meta.Save(dest); err != nil {
+			tracker.Logf("❌ Failed to copy plugin.yaml to %s: %v", plat.Key(), err)
 		}
 	}
 
 	// Step 3: Build UI once (concurrently)
-	uiErrChan := make(chan error, 1)
+	uiResultChan := make(chan UIBuildResult, 1)
 	var wg sync.WaitGroup
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		err := buildUIAndCopy(pluginDir, platforms, outdir)
-		uiErrChan <- err
+		start := time.Now()
+		log := filepath.Join(logDir, "ui.log")
+		err := buildUI(pluginDir, platforms, outdir, opts.uiEnv(), opts.ui(), log, tracker)
+		uiResultChan <- UIBuildResult{Duration: time.Since(start), Err: err, Log: log}
 	}()
 
 	// Step 4: Build binaries concurrently
@@ -54,83 +236,101 @@ func BuildAll(pluginDir, version, outdir string, platforms []Platform) []BuildRe
 		go func(i int, plat Platform) {
 			defer wg.Done()
 			dir := outputDirs[plat.Key()]
-			err := buildBinary(pluginDir, dir, plat)
-			binResults[i] = BuildResult{Platform: plat, OutputDir: dir, Err: err}
+			start := time.Now()
+			log := filepath.Join(logDir, plat.Key()+".log")
+			var err error
+			if build := opts.build(); build != nil && build.Command != "" {
+				err = buildWithCommand(pluginDir, dir, plat, build, opts.uiEnv(), log, tracker)
+			} else {
+				err = buildBinary(pluginDir, dir, plat, goEnv, opts.ldflags(), log, tracker)
+			}
+			binResults[i] = BuildResult{
+				Platform:  plat,
+				OutputDir: dir,
+				Duration:  time.Since(start),
+				Err:       err,
+				Log:       log,
+			}
 		}(i, plat)
 	}
 
 	wg.Wait()
 
-	if err := <-uiErrChan; err != nil {
-		fmt.Println("❌ UI build failed:", err)
-		for i := range binResults {
-			if binResults[i].Err == nil {
-				binResults[i].Err = fmt.Errorf("UI build failed: %v", err)
-			}
+	return binResults, <-uiResultChan
+}
+
+// Packageable returns the results of the builds with the failure of the UI build, which every
+// platform packages, applied to the platforms whose binary built.
+func Packageable(results []BuildResult, ui UIBuildResult) []BuildResult {
+	if ui.Err == nil {
+		return results
+	}
+	packageable := slices.Clone(results)
+	for i := range packageable {
+		if packageable[i].Err == nil {
+			packageable[i].Err = fmt.Errorf("UI build failed: %v", ui.Err)
 		}
 	}
-
-	return binResults
+	return packageable
 }
 
-func buildBinary(pluginDir, output string, plat Platform) error {
-	binName := "plugin"
+// binaryName is the name of the plugin binary on the platform
+func binaryName(plat Platform) string {
 	if plat.OS == "windows" {
-		binName += ".exe"
+		return "plugin.exe"
 	}
-	outPath := filepath.Join(output, "bin", binName)
+	return "plugin"
+}
+
+func buildBinary(
+	pluginDir, output string,
+	plat Platform,
+	goEnv []string,
+	ldflags string,
+	log string,
+	tracker progress.Tracker,
+) error {
+	outPath := filepath.Join(output, "bin", binaryName(plat))
 
 	if _, err := os.Stat(outPath); err == nil {
-		fmt.Printf("⚠️  Skipping %s (already built)\n", plat.Key())
+		tracker.Done(plat.Key(), "skipped (already built)")
 		return nil
 	}
 
-	fmt.Printf("Building binary for %s...\n", plat.Key())
+	tracker.Start(plat.Key(), "building binary")
 
-	cmd := exec.Command("go", "build", "-o", outPath, "./pkg")
+	args := []string{"build", "-o", outPath}
+	if ldflags != "" {
+		args = append(args, "-ldflags", ldflags)
+	}
+	cmd := exec.Command("go", append(args, "./pkg")...)
 	cmd.Dir = pluginDir
 	cmd.Env = append(os.Environ(), "GOOS="+plat.OS, "GOARCH="+plat.Arch)
+	cmd.Env = append(cmd.Env, goEnv...)
 
-	if out, err := cmd.CombinedOutput(); err != nil {
-		return fmt.Errorf("binary build failed for %s: %w\n%s", plat.Key(), err, string(out))
+	if err := runLogged(cmd, log); err != nil {
+		err = fmt.Errorf("binary build failed for %s: %w", plat.Key(), err)
+		tracker.Fail(plat.Key(), err)
+		return err
 	}
-	fmt.Printf("✅ Built binary for %s\n", plat.Key())
+	tracker.Done(plat.Key(), "built binary")
 	return nil
 }
 
-func buildUIAndCopy(pluginDir string, platforms []Platform, outdir string) error {
-	fmt.Printf("Building ui...\n")
-
-	uiPath := filepath.Join(pluginDir, "ui")
-
-	// Run `pnpm run build`
-	cmd := exec.Command("pnpm", "run", "build")
-	cmd.Dir = uiPath
-	if out, err := cmd.CombinedOutput(); err != nil {
-		return fmt.Errorf("UI build error: %s\n%s", err, out)
+// runLogged runs the command with its output appended to the log file, so concurrent builds
+// don't interleave on the console.
+func runLogged(cmd *exec.Cmd, log string) error {
+	f, err := os.OpenFile(log, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open build log: %w", err)
 	}
+	defer f.Close()
 
-	// Copy dist/assets/* into each platform dir
-	srcAssets := filepath.Join(uiPath, "dist", "assets")
-
-	for _, plat := range platforms {
-		destAssets := filepath.Join(pluginDir, outdir, plat.Key(), "assets")
-		if err := os.MkdirAll(destAssets, 0755); err != nil {
-			return fmt.Errorf("failed to create assets dir: %w", err)
-		}
-
-		err := filepath.Walk(srcAssets, func(path string, info os.FileInfo, err error) error {
-			if err != nil || info.IsDir() {
-				return err
-			}
-			rel, _ := filepath.Rel(srcAssets, path)
-			dest := filepath.Join(destAssets, rel)
-			return CopyFile(path, dest)
-		})
-		if err != nil {
-			return fmt.Errorf("failed to copy UI to %s: %w", plat.Key(), err)
-		}
+	fmt.Fprintf(f, "$ %s\n", strings.Join(cmd.Args, " "))
+	cmd.Stdout = f
+	cmd.Stderr = f
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%w (see %s)", err, log)
 	}
-	fmt.Println("✅ Built and distributed UI assets")
 	return nil
 }
@@ -0,0 +1,132 @@
+package packager
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestBuildOptsLDFlags(t *testing.T) {
+	vars := LDFlagVars{
+		ID:      "main.pluginID",
+		Version: "github.com/acme/plugin/pkg/build.Version",
+		Commit:  "main.commit",
+	}
+	tests := []struct {
+		name string
+		opts BuildOpts
+		want string
+	}{
+		{
+			name: "all set",
+			opts: BuildOpts{LDFlags: vars, PluginID: "demo", Version: "1.2.3", Commit: "abc123"},
+			want: "-X 'main.pluginID=demo' " +
+				"-X 'github.com/acme/plugin/pkg/build.Version=1.2.3' " +
+				"-X 'main.commit=abc123'",
+		},
+		{
+			name: "value with spaces",
+			opts: BuildOpts{LDFlags: LDFlagVars{Version: "main.version"}, Version: "1.2.3 beta"},
+			want: "-X 'main.version=1.2.3 beta'",
+		},
+		{
+			name: "no path",
+			opts: BuildOpts{
+				LDFlags:  LDFlagVars{Version: "main.version"},
+				PluginID: "demo",
+				Version:  "1.2.3",
+			},
+			want: "-X 'main.version=1.2.3'",
+		},
+		{
+			name: "no value",
+			opts: BuildOpts{LDFlags: vars, PluginID: "demo", Version: "1.2.3"},
+			want: "-X 'main.pluginID=demo' -X 'github.com/acme/plugin/pkg/build.Version=1.2.3'",
+		},
+		{
+			name: "none",
+			opts: BuildOpts{PluginID: "demo", Version: "1.2.3", Commit: "abc123"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.opts.ldflags(); got != tt.want {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildOptsGoEnv(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+
+	tests := []struct {
+		name string
+		opts BuildOpts
+		want []string
+	}{
+		{name: "none"},
+		{
+			name: "relative caches",
+			opts: BuildOpts{GoCache: "cache/go", GoModCache: "cache/mod"},
+			want: []string{
+				"GOCACHE=" + filepath.Join(dir, "cache", "go"),
+				"GOMODCACHE=" + filepath.Join(dir, "cache", "mod"),
+			},
+		},
+		{
+			name: "only the module cache",
+			opts: BuildOpts{GoModCache: filepath.Join(dir, "mod")},
+			want: []string{"GOMODCACHE=" + filepath.Join(dir, "mod")},
+		},
+		{
+			name: "cache program",
+			opts: BuildOpts{GoCacheProg: "cacheprog --remote"},
+			want: []string{"GOCACHEPROG=cacheprog --remote"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.opts.goEnv()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+			for _, path := range []string{tt.opts.GoCache, tt.opts.GoModCache} {
+				if path == "" {
+					continue
+				}
+				if info, err := os.Stat(path); err != nil || !info.IsDir() {
+					t.Fatalf("%s wasn't created: %v", path, err)
+				}
+			}
+		})
+	}
+}
+
+func TestPackageable(t *testing.T) {
+	linux := Platform{OS: "linux", Arch: "amd64"}
+	darwin := Platform{OS: "darwin", Arch: "arm64"}
+	binaryErr := errors.New("exit status 1")
+	results := []BuildResult{{Platform: linux}, {Platform: darwin, Err: binaryErr}}
+
+	if got := Packageable(results, UIBuildResult{}); got[0].Err != nil || got[1].Err != binaryErr {
+		t.Fatalf("without a UI failure got %+v, want the results of the binaries", got)
+	}
+
+	got := Packageable(results, UIBuildResult{Err: errors.New("npm failed")})
+	if got[0].Err == nil {
+		t.Fatal("packaging a platform whose UI failed to build")
+	}
+	if got[1].Err != binaryErr {
+		t.Fatalf("got %v for a failed binary, want its own failure", got[1].Err)
+	}
+	if results[0].Err != nil {
+		t.Fatal("the UI failure was applied to the results of the builds")
+	}
+}
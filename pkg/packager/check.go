@@ -0,0 +1,151 @@
+package packager
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// BuildPlan describes what packaging a plugin would build, without building anything.
+type BuildPlan struct {
+	PluginID string
+	Version  string
+
+	// Platforms are the platforms binaries are built for
+	Platforms []Platform
+
+	// Entrypoint is the Go package the binaries are built from, relative to the plugin
+	Entrypoint string
+
+	// LDFlags are the flags injecting the build info into the binaries
+	LDFlags string
+
+	// BuildCommand is the custom command building the binaries in place of go build, in
+	// which case there's no Entrypoint or LDFlags
+	BuildCommand string
+
+	// UIDir is the directory the UI is built in
+	UIDir string
+
+	// UI are the runs of the UI build, one per UI target
+	UI []UIStep
+
+	// Toolchains maps each tool the build needs to where it was found
+	Toolchains map[string]string
+}
+
+// UIStep is a run of the UI build in a build plan.
+type UIStep struct {
+	// Target is the UI target built, empty for the shared build
+	Target    string
+	Command   []string
+	Platforms []Platform
+}
+
+// CheckPackage validates the plugin's metadata and resolves its build plan, checking that the
+// sources and toolchains the build needs are present, without building anything. Every
+// problem found is returned, not just the first.
+func CheckPackage(opts PackOpts) (*BuildPlan, error) {
+	metaFile, err := opts.metadataFile()
+	if err != nil {
+		return nil, err
+	}
+	meta, err := LoadPluginMetadata(metaFile)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", filepath.Base(metaFile), err)
+	}
+	// validated the same way RunPackCommand does
+	resolved := meta.WithDefaults(opts.OrgDefaults)
+	var errs []error
+	if err := setPackageVersion(resolved, opts.Version); err != nil {
+		errs = append(errs, err)
+	}
+	if err := resolved.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+
+	plan := &BuildPlan{
+		PluginID:   resolved.ID,
+		Version:    resolved.Version,
+		Platforms:  opts.platforms(),
+		Entrypoint: "./pkg",
+		UIDir:      "ui",
+	}
+	if err := resolved.UI.Validate(plan.Platforms); err != nil {
+		errs = append(errs, err)
+	}
+	var scripts []string
+	for _, build := range resolved.UI.builds(plan.Platforms) {
+		plan.UI = append(plan.UI, UIStep{
+			Target:    build.Name,
+			Command:   []string{"pnpm", "run", build.Target.Script},
+			Platforms: build.Platforms,
+		})
+		scripts = append(scripts, build.Target.Script)
+	}
+
+	if resolved.Build != nil && resolved.Build.Command != "" {
+		plan.Entrypoint = ""
+		plan.BuildCommand = resolved.Build.Command
+	} else {
+		plan.LDFlags = BuildOpts{
+			Version:  resolved.Version,
+			PluginID: resolved.ID,
+			Commit:   opts.Commit,
+			LDFlags:  opts.LDFlags,
+		}.ldflags()
+		errs = append(errs, checkGoSources(opts.PluginDir, plan.Entrypoint)...)
+	}
+
+	errs = append(errs, checkUISources(filepath.Join(opts.PluginDir, plan.UIDir), scripts)...)
+	toolchains, toolErrs := checkToolchains(opts.PluginDir, resolved)
+	plan.Toolchains = toolchains
+	errs = append(errs, toolErrs...)
+	outDir := filepath.Join(opts.PluginDir, opts.OutDir)
+	if err := checkDiskSpace(opts.PluginDir, outDir, plan.Platforms); err != nil {
+		errs = append(errs, err)
+	}
+
+	return plan, errors.Join(errs...)
+}
+
+// checkGoSources checks that the plugin is a Go module with Go files in the entrypoint.
+func checkGoSources(pluginDir, entrypoint string) []error {
+	var errs []error
+
+	if _, err := os.Stat(filepath.Join(pluginDir, "go.mod")); err != nil {
+		errs = append(errs, fmt.Errorf("couldn't read go.mod: %w", err))
+	}
+
+	sources, err := filepath.Glob(filepath.Join(pluginDir, entrypoint, "*.go"))
+	if err == nil && len(sources) == 0 {
+		errs = append(errs, fmt.Errorf("no Go files in the %s entrypoint", entrypoint))
+	}
+	return errs
+}
+
+// checkUISources checks that the UI has a package.json with the scripts building it.
+func checkUISources(uiDir string, scripts []string) []error {
+	data, err := os.ReadFile(filepath.Join(uiDir, "package.json"))
+	if err != nil {
+		return []error{fmt.Errorf("couldn't read the UI package.json: %w", err)}
+	}
+
+	var pkg struct {
+		Scripts map[string]string `json:"scripts"`
+	}
+	if err := json.Unmarshal(data, &pkg); err != nil {
+		return []error{fmt.Errorf("invalid UI package.json: %w", err)}
+	}
+	var errs []error
+	for _, script := range slices.Compact(slices.Sorted(slices.Values(scripts))) {
+		if strings.TrimSpace(pkg.Scripts[script]) == "" {
+			errs = append(errs, fmt.Errorf("the UI package.json has no %s script", script))
+		}
+	}
+	return errs
+}
@@ -0,0 +1,90 @@
+package packager
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+
+	"github.com/omniviewdev/registry-cli/pkg/progress"
+)
+
+// BuildConfig overrides how the plugin binary is built, for plugins that aren't written in Go
+// or ship prebuilt binaries.
+type BuildConfig struct {
+	// Command builds the binary of a platform in place of go build. It's run with sh -c in the
+	// plugin directory, with the platform given in TARGET_OS, TARGET_ARCH and TARGET_TRIPLE
+	// (as well as GOOS and GOARCH), and the path to write the binary to in OUTPUT.
+	Command string `yaml:"command,omitempty"`
+
+	// Env is passed to the command on top of the build info
+	Env map[string]string `yaml:"env,omitempty"`
+}
+
+// targetTriples are the target triples of the platforms, as rustc and zig name them.
+var targetTriples = map[string]string{
+	"darwin_amd64":  "x86_64-apple-darwin",
+	"darwin_arm64":  "aarch64-apple-darwin",
+	"linux_amd64":   "x86_64-unknown-linux-gnu",
+	"linux_arm64":   "aarch64-unknown-linux-gnu",
+	"windows_amd64": "x86_64-pc-windows-msvc",
+	"windows_arm64": "aarch64-pc-windows-msvc",
+}
+
+// commandEnv returns the environment of the build command for the platform.
+func (c *BuildConfig) commandEnv(plat Platform, output string) []string {
+	env := []string{
+		"TARGET_OS=" + plat.OS,
+		"TARGET_ARCH=" + plat.Arch,
+		"TARGET_TRIPLE=" + targetTriples[plat.Key()],
+		"GOOS=" + plat.OS,
+		"GOARCH=" + plat.Arch,
+		"OUTPUT=" + output,
+	}
+	for _, k := range slices.Sorted(maps.Keys(c.Env)) {
+		env = append(env, k+"="+c.Env[k])
+	}
+	return env
+}
+
+// buildWithCommand builds the binary of the platform with the configured build command.
+func buildWithCommand(
+	pluginDir, output string,
+	plat Platform,
+	config *BuildConfig,
+	env []string,
+	log string,
+	tracker progress.Tracker,
+) error {
+	outPath, err := filepath.Abs(filepath.Join(output, "bin", binaryName(plat)))
+	if err != nil {
+		return err
+	}
+
+	if _, err := os.Stat(outPath); err == nil {
+		tracker.Done(plat.Key(), "skipped (already built)")
+		return nil
+	}
+
+	tracker.Start(plat.Key(), "running build command")
+
+	cmd := exec.Command("sh", "-c", config.Command)
+	cmd.Dir = pluginDir
+	cmd.Env = append(os.Environ(), env...)
+	cmd.Env = append(cmd.Env, config.commandEnv(plat, outPath)...)
+
+	err = runLogged(cmd, log)
+	if err != nil {
+		err = fmt.Errorf("binary build failed for %s: %w", plat.Key(), err)
+	} else if _, statErr := os.Stat(outPath); statErr != nil {
+		err = fmt.Errorf("build command didn't write the %s binary to $OUTPUT", plat.Key())
+	}
+	if err != nil {
+		tracker.Fail(plat.Key(), err)
+		return err
+	}
+	tracker.Done(plat.Key(), "built binary")
+	return nil
+}
@@ -0,0 +1,145 @@
+package packager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/omniviewdev/registry-cli/pkg/console"
+	"github.com/omniviewdev/registry-cli/pkg/types"
+)
+
+// DefaultCompatTimeout is how long a core gets to complete the handshake with the plugin
+const DefaultCompatTimeout = 2 * time.Minute
+
+// DefaultCompatArgs runs the core's plugin handshake test. {plugin} is replaced with the
+// directory of the packaged plugin.
+var DefaultCompatArgs = []string{"plugin", "handshake", "{plugin}"}
+
+// CompatTest launches the packaged plugin against an Omniview core, passing when the core
+// completes the plugin handshake (exits successfully).
+type CompatTest struct {
+	// CoreVersion is the core version (or range) the result is recorded under. Defaults to the
+	// tag of the image.
+	CoreVersion string
+
+	// Core is the path to a local core binary
+	Core string
+
+	// Image is a container image of the core, run with docker, when Core isn't set
+	Image string
+
+	// Args are the arguments to run the core with. Defaults to DefaultCompatArgs.
+	Args []string
+
+	// Timeout bounds the handshake. Defaults to DefaultCompatTimeout.
+	Timeout time.Duration
+}
+
+func (t *CompatTest) Defaulter() {
+	if t.CoreVersion == "" && t.Image != "" {
+		if idx := strings.LastIndex(t.Image, ":"); idx != -1 && !strings.Contains(t.Image[idx:], "/") {
+			t.CoreVersion = strings.TrimPrefix(t.Image[idx+1:], "v")
+		}
+	}
+	if len(t.Args) == 0 {
+		t.Args = DefaultCompatArgs
+	}
+	if t.Timeout == 0 {
+		t.Timeout = DefaultCompatTimeout
+	}
+}
+
+// platform is the build the core can run: the host's for a local core, linux for a container.
+func (t CompatTest) platform() Platform {
+	if t.Core != "" {
+		return Platform{runtime.GOOS, runtime.GOARCH}
+	}
+	return Platform{"linux", runtime.GOARCH}
+}
+
+// RunCompatTests runs the compatibility tests against the builds, returning the outcome of each
+// keyed by core version. A test whose platform wasn't built is an error, a failed handshake is
+// recorded as failed.
+func RunCompatTests(tests []CompatTest, builds []BuildResult) (types.Compatibility, error) {
+	compatibility := make(types.Compatibility, len(tests))
+	for _, test := range tests {
+		test.Defaulter()
+		if test.CoreVersion == "" {
+			return nil, errors.New("a core version is required for each compatibility test")
+		}
+		if test.Core == "" && test.Image == "" {
+			return nil, fmt.Errorf("no core binary or image given to test %s against", test.CoreVersion)
+		}
+
+		plat := test.platform()
+		var dir string
+		for _, build := range builds {
+			if build.Platform == plat && build.Err == nil {
+				dir = build.OutputDir
+			}
+		}
+		if dir == "" {
+			return nil, fmt.Errorf(
+				"no %s build to test against core %s", plat.Key(), test.CoreVersion,
+			)
+		}
+
+		console.Printf("Testing handshake with core %s...\n", test.CoreVersion)
+		if err := test.run(dir); err != nil {
+			console.Printf("❌ Handshake with core %s failed: %v\n", test.CoreVersion, err)
+			compatibility[test.CoreVersion] = types.CompatibilityFailed
+			continue
+		}
+		console.Printf("✅ Handshake with core %s passed\n", test.CoreVersion)
+		compatibility[test.CoreVersion] = types.CompatibilityTested
+	}
+	return compatibility, nil
+}
+
+func (t CompatTest) run(pluginDir string) error {
+	pluginDir, err := filepath.Abs(pluginDir)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), t.Timeout)
+	defer cancel()
+
+	var cmd *exec.Cmd
+	if t.Core != "" {
+		cmd = exec.CommandContext(ctx, t.Core, expandCompatArgs(t.Args, pluginDir)...)
+		cmd.Env = os.Environ()
+	} else {
+		args := []string{
+			"run", "--rm",
+			"--platform", "linux/" + runtime.GOARCH,
+			"-v", pluginDir + ":/plugin:ro",
+			t.Image,
+		}
+		cmd = exec.CommandContext(ctx, "docker", append(args, expandCompatArgs(t.Args, "/plugin")...)...)
+	}
+
+	out, err := cmd.CombinedOutput()
+	if ctx.Err() != nil {
+		return fmt.Errorf("timed out after %s", t.Timeout)
+	}
+	if err != nil {
+		return fmt.Errorf("%w\n%s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+func expandCompatArgs(args []string, pluginDir string) []string {
+	expanded := make([]string, len(args))
+	for i, arg := range args {
+		expanded[i] = strings.ReplaceAll(arg, "{plugin}", pluginDir)
+	}
+	return expanded
+}
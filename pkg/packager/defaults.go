@@ -0,0 +1,74 @@
+package packager
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/omniviewdev/registry-cli/pkg/types"
+	"gopkg.in/yaml.v3"
+)
+
+// OrgDefaults holds organization wide metadata that plugin.yaml inherits from. Anything set in
+// plugin.yaml takes precedence over the defaults.
+type OrgDefaults struct {
+	Maintainers []types.PluginMaintainer `yaml:"maintainers,omitempty"`
+	Website     string                   `yaml:"website,omitempty"`
+	Theme       *types.PluginTheme       `yaml:"theme,omitempty"`
+
+	// RepositoryPrefix is joined with the plugin ID to form the repository when plugin.yaml
+	// doesn't set one, e.g. https://github.com/acme/omniview-plugin-
+	RepositoryPrefix string `yaml:"repository_prefix,omitempty"`
+}
+
+// LoadOrgDefaults loads an org defaults file
+func LoadOrgDefaults(path string) (*OrgDefaults, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read org defaults: %w", err)
+	}
+
+	dec := yaml.NewDecoder(strings.NewReader(string(data)))
+	dec.KnownFields(true)
+
+	var defaults OrgDefaults
+	if err := dec.Decode(&defaults); err != nil {
+		return nil, fmt.Errorf("failed to parse org defaults %s: %w", path, err)
+	}
+	return &defaults, nil
+}
+
+// WithDefaults returns a copy of the metadata with any unset fields filled in from the org
+// defaults. Theme colors are merged individually.
+func (m *PluginMetadata) WithDefaults(d *OrgDefaults) *PluginMetadata {
+	merged := *m
+	if d == nil {
+		return &merged
+	}
+
+	if len(merged.Maintainers) == 0 {
+		merged.Maintainers = slices.Clone(d.Maintainers)
+	}
+	if merged.Website == "" {
+		merged.Website = d.Website
+	}
+	if merged.Repository == "" && d.RepositoryPrefix != "" {
+		merged.Repository = d.RepositoryPrefix + merged.ID
+	}
+
+	if d.Theme != nil && len(d.Theme.Colors) > 0 {
+		colors := make(map[string]string, len(d.Theme.Colors))
+		for k, v := range d.Theme.Colors {
+			colors[k] = v
+		}
+		if merged.Theme != nil {
+			for k, v := range merged.Theme.Colors {
+				colors[k] = v
+			}
+		}
+		merged.Theme = &types.PluginTheme{Colors: colors}
+	}
+
+	return &merged
+}
@@ -0,0 +1,14 @@
+//go:build linux || darwin
+
+package packager
+
+import "syscall"
+
+// freeSpace returns the bytes available to us on the volume holding path.
+func freeSpace(path string) (uint64, bool) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return 0, false
+	}
+	return uint64(stat.Bavail) * uint64(stat.Bsize), true
+}
@@ -0,0 +1,18 @@
+//go:build windows
+
+package packager
+
+import "golang.org/x/sys/windows"
+
+// freeSpace returns the bytes available to us on the volume holding path.
+func freeSpace(path string) (uint64, bool) {
+	p, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return 0, false
+	}
+	var free uint64
+	if err := windows.GetDiskFreeSpaceEx(p, &free, nil, nil); err != nil {
+		return 0, false
+	}
+	return free, true
+}
@@ -0,0 +1,87 @@
+package packager
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"time"
+)
+
+type junitTestSuites struct {
+	XMLName xml.Name         `xml:"testsuites"`
+	Suites  []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Time     string          `xml:"time,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Output  string `xml:",chardata"`
+}
+
+// WriteJUnitReport writes a JUnit XML report to path where the UI build and each platform
+// build is a test case, so CI systems can surface exactly which build broke. The results are
+// the ones of BuildAll, each platform failing only when its binary did, and elapsed the wall
+// clock time of the builds, which ran concurrently.
+func WriteJUnitReport(
+	path string,
+	results []BuildResult,
+	ui UIBuildResult,
+	elapsed time.Duration,
+) error {
+	suite := junitTestSuite{Name: "registry-cli package", Time: junitSeconds(elapsed)}
+
+	addCase := func(name string, duration time.Duration, err error, log string) {
+		tc := junitTestCase{
+			Name:      name,
+			Classname: "build",
+			Time:      junitSeconds(duration),
+		}
+		if err != nil {
+			tc.Failure = &junitFailure{
+				Message: fmt.Sprintf("%s build failed", name),
+				Output:  err.Error(),
+			}
+			// the full build output is more useful to CI than the summary
+			if out, readErr := os.ReadFile(log); readErr == nil && len(out) > 0 {
+				tc.Failure.Output = string(out)
+			}
+			suite.Failures++
+		}
+		suite.Tests++
+		suite.Cases = append(suite.Cases, tc)
+	}
+
+	addCase("ui", ui.Duration, ui.Err, ui.Log)
+	for _, result := range results {
+		addCase(result.Platform.Key(), result.Duration, result.Err, result.Log)
+	}
+
+	out, err := xml.MarshalIndent(junitTestSuites{Suites: []junitTestSuite{suite}}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal junit report: %w", err)
+	}
+	out = append([]byte(xml.Header), out...)
+
+	if err := os.WriteFile(path, out, 0644); err != nil {
+		return fmt.Errorf("failed to write junit report: %w", err)
+	}
+	return nil
+}
+
+func junitSeconds(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
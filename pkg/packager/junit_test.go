@@ -0,0 +1,86 @@
+package packager
+
+import (
+	"encoding/xml"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWriteJUnitReport(t *testing.T) {
+	linux := Platform{OS: "linux", Arch: "amd64"}
+	darwin := Platform{OS: "darwin", Arch: "arm64"}
+	tests := []struct {
+		name     string
+		results  []BuildResult
+		ui       UIBuildResult
+		failed   []string
+		failures int
+	}{
+		{
+			name: "all built",
+			results: []BuildResult{
+				{Platform: linux, Duration: time.Second},
+				{Platform: darwin, Duration: 2 * time.Second},
+			},
+			ui: UIBuildResult{Duration: 3 * time.Second},
+		},
+		{
+			name: "binary failed",
+			results: []BuildResult{
+				{Platform: linux, Duration: time.Second, Err: errors.New("exit status 1")},
+				{Platform: darwin, Duration: 2 * time.Second},
+			},
+			ui:       UIBuildResult{Duration: 3 * time.Second},
+			failed:   []string{"linux_amd64"},
+			failures: 1,
+		},
+		{
+			name: "ui failed",
+			results: []BuildResult{
+				{Platform: linux, Duration: time.Second},
+				{Platform: darwin, Duration: 2 * time.Second},
+			},
+			ui:       UIBuildResult{Duration: 3 * time.Second, Err: errors.New("exit status 1")},
+			failed:   []string{"ui"},
+			failures: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "junit.xml")
+			if err := WriteJUnitReport(path, tt.results, tt.ui, 3*time.Second); err != nil {
+				t.Fatal(err)
+			}
+			b, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var report junitTestSuites
+			if err := xml.Unmarshal(b, &report); err != nil {
+				t.Fatal(err)
+			}
+
+			suite := report.Suites[0]
+			if suite.Tests != len(tt.results)+1 || suite.Failures != tt.failures {
+				t.Fatalf("got %d tests, %d failures, want %d, %d",
+					suite.Tests, suite.Failures, len(tt.results)+1, tt.failures)
+			}
+			// the builds ran concurrently, the suite took as long as the slowest
+			if suite.Time != "3.000" {
+				t.Fatalf("suite took %s, want the wall clock time 3.000", suite.Time)
+			}
+			var failed []string
+			for _, tc := range suite.Cases {
+				if tc.Failure != nil {
+					failed = append(failed, tc.Name)
+				}
+			}
+			if len(failed) != len(tt.failed) || (len(failed) > 0 && failed[0] != tt.failed[0]) {
+				t.Fatalf("failed %v, want %v", failed, tt.failed)
+			}
+		})
+	}
+}
@@ -0,0 +1,263 @@
+package packager
+
+import (
+	"bufio"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// LintIssue is a problem with the layout of a plugin project, along with how to fix it.
+type LintIssue struct {
+	// Check is the name of the check that found the issue (e.g. entrypoint, ui, gitignore)
+	Check string
+
+	// Problem describes what's wrong
+	Problem string
+
+	// Fix describes how to fix it
+	Fix string
+
+	// Warning marks issues that don't keep the plugin from packaging
+	Warning bool
+}
+
+// Lint checks the layout of a plugin project against what packaging expects: a Go module with
+// a main package in the entrypoint, a UI with the scripts building it, and a .gitignore keeping
+// the build output out of the repository. Unlike CheckPackage, it doesn't look for toolchains,
+// so it's suited to running before the first package attempt, or in CI.
+func Lint(opts PackOpts) []LintIssue {
+	var issues []LintIssue
+
+	meta := &PluginMetadata{}
+	metaFile, err := opts.metadataFile()
+	if err == nil {
+		meta, err = LoadPluginMetadata(metaFile)
+		if err != nil {
+			meta = &PluginMetadata{}
+			err = fmt.Errorf("invalid %s: %w", filepath.Base(metaFile), err)
+		}
+	}
+	if err != nil {
+		issues = append(issues, LintIssue{
+			Check:   "metadata",
+			Problem: err.Error(),
+			Fix:     "run 'registry-cli meta init' to create a plugin.yaml, or fix the reported fields",
+		})
+	}
+
+	if meta.Build == nil || meta.Build.Command == "" {
+		issues = append(issues, lintGoModule(opts.PluginDir, meta.ID)...)
+		issues = append(issues, lintEntrypoint(opts.PluginDir, "./pkg")...)
+	}
+
+	var scripts []string
+	for _, build := range meta.UI.builds(DefaultPlatforms) {
+		scripts = append(scripts, build.Target.Script)
+	}
+	for _, err := range checkUISources(filepath.Join(opts.PluginDir, "ui"), scripts) {
+		issues = append(issues, LintIssue{
+			Check:   "ui",
+			Problem: err.Error(),
+			Fix: fmt.Sprintf(
+				"add a %s script to ui/package.json, building the UI into ui/dist/assets",
+				strings.Join(slices.Compact(slices.Sorted(slices.Values(scripts))), ", "),
+			),
+		})
+	}
+
+	outDir := opts.OutDir
+	if outDir == "" {
+		outDir = "build"
+	}
+	issues = append(issues, lintGitignore(opts.PluginDir, outDir)...)
+	return issues
+}
+
+// lintGoModule checks the plugin is a Go module with a module path go can build and fetch.
+func lintGoModule(pluginDir, pluginID string) []LintIssue {
+	module, err := goModulePath(filepath.Join(pluginDir, "go.mod"))
+	if err != nil {
+		return []LintIssue{{
+			Check:   "go.mod",
+			Problem: err.Error(),
+			Fix:     "run 'go mod init <module path>' in the plugin directory",
+		}}
+	}
+
+	if pluginID == "" {
+		pluginID = "<plugin>"
+	}
+	fix := "use the path of the plugin's repository, e.g. github.com/<org>/" + pluginID
+	switch {
+	case module == "":
+		return []LintIssue{{
+			Check:   "go.mod",
+			Problem: "go.mod has no module path",
+			Fix:     fix,
+		}}
+	case module == "main" || module == "command-line-arguments" || strings.HasPrefix(module, "."):
+		return []LintIssue{{
+			Check:   "go.mod",
+			Problem: fmt.Sprintf("%q isn't a valid module path", module),
+			Fix:     fix,
+		}}
+	case !strings.Contains(strings.Split(module, "/")[0], "."):
+		return []LintIssue{{
+			Check: "go.mod",
+			Problem: fmt.Sprintf(
+				"module path %q doesn't start with a domain, so it can't be fetched",
+				module,
+			),
+			Fix:     fix,
+			Warning: true,
+		}}
+	}
+	return nil
+}
+
+// goModulePath reads the module path out of a go.mod file.
+func goModulePath(gomod string) (string, error) {
+	file, err := os.Open(gomod)
+	if err != nil {
+		return "", fmt.Errorf("couldn't read go.mod: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		fields := strings.Fields(line)
+		if len(fields) == 0 || fields[0] != "module" {
+			continue
+		}
+		if len(fields) == 1 {
+			return "", nil
+		}
+		return strings.Trim(fields[1], "\"`"), nil
+	}
+	return "", scanner.Err()
+}
+
+// lintEntrypoint checks the entrypoint holds the main package the binaries are built from.
+func lintEntrypoint(pluginDir, entrypoint string) []LintIssue {
+	sources, _ := filepath.Glob(filepath.Join(pluginDir, entrypoint, "*.go"))
+	fset := token.NewFileSet()
+	var packages []string
+	for _, source := range sources {
+		if strings.HasSuffix(source, "_test.go") {
+			continue
+		}
+		file, err := parser.ParseFile(fset, source, nil, parser.PackageClauseOnly)
+		if err != nil {
+			return []LintIssue{{
+				Check:   "entrypoint",
+				Problem: err.Error(),
+				Fix:     "fix the syntax error",
+			}}
+		}
+		if file.Name.Name == "main" {
+			return nil
+		}
+		packages = append(packages, file.Name.Name)
+	}
+
+	fix := fmt.Sprintf("add a main.go with 'package main' and a main function to %s", entrypoint)
+	if len(packages) == 0 {
+		return []LintIssue{{
+			Check:   "entrypoint",
+			Problem: fmt.Sprintf("no Go files in the %s entrypoint", entrypoint),
+			Fix:     fix + ", or set build.command in plugin.yaml to build the binaries another way",
+		}}
+	}
+	return []LintIssue{{
+		Check:   "entrypoint",
+		Problem: fmt.Sprintf("the %s entrypoint is package %s, not main", entrypoint, packages[0]),
+		Fix:     fix,
+	}}
+}
+
+// lintGitignore checks the build output, UI build and UI dependencies are ignored by git.
+func lintGitignore(pluginDir, outDir string) []LintIssue {
+	patterns := map[string][]string{
+		"":   gitignorePatterns(filepath.Join(pluginDir, ".gitignore")),
+		"ui": gitignorePatterns(filepath.Join(pluginDir, "ui", ".gitignore")),
+	}
+	if len(patterns[""]) == 0 && len(patterns["ui"]) == 0 {
+		return []LintIssue{{
+			Check:   "gitignore",
+			Problem: "the plugin has no .gitignore",
+			Fix: fmt.Sprintf(
+				"add a .gitignore with %s/, ui/dist/ and ui/node_modules/",
+				filepath.ToSlash(filepath.Clean(outDir)),
+			),
+		}}
+	}
+
+	var issues []LintIssue
+	outputs := []string{filepath.ToSlash(filepath.Clean(outDir)), "ui/dist", "ui/node_modules"}
+	for _, ignored := range outputs {
+		if isGitignored(patterns, ignored) {
+			continue
+		}
+		issues = append(issues, LintIssue{
+			Check:   "gitignore",
+			Problem: fmt.Sprintf("%s isn't git ignored, so it may get committed", ignored),
+			Fix:     fmt.Sprintf("add %s/ to .gitignore", ignored),
+		})
+	}
+	return issues
+}
+
+// gitignorePatterns reads the patterns of a .gitignore, leaving out comments and negations.
+func gitignorePatterns(gitignore string) []string {
+	data, err := os.ReadFile(gitignore)
+	if err != nil {
+		return nil
+	}
+	var patterns []string
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "!") {
+			continue
+		}
+		patterns = append(patterns, line)
+	}
+	return patterns
+}
+
+// isGitignored reports whether a directory of the plugin, as a slash separated path, is matched
+// by the patterns of the .gitignore files, keyed by the directory they're in. It covers the
+// common patterns only: a name matching at any depth, or a path anchored to the .gitignore.
+func isGitignored(patterns map[string][]string, dir string) bool {
+	for base, list := range patterns {
+		rel := dir
+		if base != "" {
+			var ok bool
+			if rel, ok = strings.CutPrefix(dir, base+"/"); !ok {
+				continue
+			}
+		}
+		for _, pattern := range list {
+			pattern = strings.TrimSuffix(pattern, "/**")
+			pattern = strings.TrimSuffix(pattern, "/")
+			if anchored := strings.TrimPrefix(pattern, "/"); strings.Contains(pattern, "/") {
+				if ok, _ := path.Match(anchored, rel); ok {
+					return true
+				}
+				continue
+			}
+			for _, elem := range strings.Split(rel, "/") {
+				if ok, _ := path.Match(pattern, elem); ok {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
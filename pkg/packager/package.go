@@ -1,9 +1,20 @@
 package packager
 
 import (
+	"errors"
 	"fmt"
+	"io/fs"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/omniviewdev/registry-cli/pkg/console"
+	"github.com/omniviewdev/registry-cli/pkg/progress"
+	"github.com/omniviewdev/registry-cli/pkg/types"
 )
 
 type PackOpts struct {
@@ -11,10 +22,103 @@ type PackOpts struct {
 	Version   string
 	OutDir    string
 	Clean     bool
+
+	// Report, if set, collects the per-platform build results
+	Report *types.PublishReport
+
+	// JUnitReport, if set, is the path to write a JUnit XML report of the builds to
+	JUnitReport string
+
+	// GoCache, GoModCache and GoCacheProg configure the go build caches (see BuildOpts)
+	GoCache     string
+	GoModCache  string
+	GoCacheProg string
+
+	// Commit is the commit being packaged. Detected from git when empty.
+	Commit string
+
+	// LDFlags controls which variables the build info is injected into
+	LDFlags LDFlagVars
+
+	// OrgDefaults, if set, fills in metadata fields that plugin.yaml leaves unset
+	OrgDefaults *OrgDefaults
+
+	// WriteVersion writes the version back into the source plugin.yaml. Otherwise it's only
+	// stamped into the packaged copy.
+	WriteVersion bool
+
+	// Stamp stamps the build metadata (commit and build time) into the packaged plugin.yaml
+	Stamp bool
+
+	// MetadataFile is the plugin metadata file. Looked for in the plugin directory and its
+	// parents when empty (see FindMetadataFile).
+	MetadataFile string
+
+	// CompatTests are run against the builds once packaged, with the outcomes recorded in the
+	// report's compatibility matrix
+	CompatTests []CompatTest
+
+	// PinDependencies, if set, resolves the dependencies of the plugin (as returned by
+	// PluginMeta.Requires) to exact versions, which are pinned in the packaged plugin.yaml so
+	// the published metadata records what the plugin was built against
+	PinDependencies func(requires map[string]string) (map[string]string, error)
+
+	// Scan, if set, scans the plugin for known vulnerabilities before building, with the
+	// findings recorded in the result and the report
+	Scan *ScanOpts
+
+	// Platforms are the platforms to package for. Defaults to DefaultPlatforms.
+	Platforms []Platform
+}
+
+// PackResult is the outcome of packaging a plugin.
+type PackResult struct {
+	// Metadata is the resolved metadata the plugin was packaged with
+	Metadata *PluginMetadata
+
+	// Platforms holds the outcome of each platform, in build order
+	Platforms []PlatformResult
+
+	// UI is the outcome of the UI build
+	UI UIBuildResult
+
+	// LogDir is the directory the build logs were written to
+	LogDir string
+
+	// Scan holds the findings of the vulnerability scans, when they were run
+	Scan *types.ScanReport
+}
+
+// PlatformResult is the outcome of packaging a platform.
+type PlatformResult struct {
+	Platform Platform
+
+	// Artifact is the path of the tarball, empty when the build failed
+	Artifact string
+	Checksum string
+	Size     int64
+
+	Duration time.Duration
+	Err      error
+
+	// Log is the path of the build log
+	Log string
+}
+
+// Packaged returns the platforms that built and were packaged.
+func (r *PackResult) Packaged() []PlatformResult {
+	packaged := make([]PlatformResult, 0, len(r.Platforms))
+	for _, platform := range r.Platforms {
+		if platform.Err == nil {
+			packaged = append(packaged, platform)
+		}
+	}
+	return packaged
 }
 
-// RunPackCommand runs the packaging step
-func RunPackCommand(opts PackOpts) (*PluginMetadata, error) {
+// RunPackCommand runs the packaging step, returning the outcome of every platform. Failed
+// builds are recorded in the result, but don't fail packaging.
+func RunPackCommand(opts PackOpts) (*PackResult, error) {
 	if opts.OutDir == "" {
 		return nil, fmt.Errorf("cannot build to empty directory")
 	}
@@ -28,52 +132,279 @@ func RunPackCommand(opts PackOpts) (*PluginMetadata, error) {
 		}
 	}
 
-	meta, err := LoadPluginMetadata(filepath.Join(opts.PluginDir, "plugin.yaml"))
+	metaFile, err := opts.metadataFile()
+	if err != nil {
+		return nil, err
+	}
+	meta, err := LoadPluginMetadata(metaFile)
 	if err != nil {
-		return nil, fmt.Errorf("invalid plugin.yaml: %w", err)
+		return nil, fmt.Errorf("invalid %s: %w", filepath.Base(metaFile), err)
 	}
 
-	if err := meta.Validate(); err != nil {
+	// the resolved metadata is what gets packaged, while the source file only ever has
+	// the version written back into it, and only when asked to
+	resolved := meta.WithDefaults(opts.OrgDefaults)
+	if err := setPackageVersion(resolved, opts.Version); err != nil {
+		return nil, err
+	}
+	if err := resolved.Validate(); err != nil {
+		return nil, err
+	}
+	platforms := opts.platforms()
+	if err := resolved.UI.Validate(platforms); err != nil {
 		return nil, err
 	}
+	if err := Preflight(opts.PluginDir, opts.OutDir, resolved, platforms); err != nil {
+		return nil, fmt.Errorf("pre-flight checks failed:\n%w", err)
+	}
+
+	var scan *types.ScanReport
+	if opts.Scan != nil {
+		console.Println("scanning for known vulnerabilities...")
+		if scan, err = Scan(opts.PluginDir, *opts.Scan); err != nil {
+			return nil, fmt.Errorf("vulnerability scan failed: %w", err)
+		}
+		if opts.Report != nil {
+			opts.Report.Scan = scan
+		}
+	}
 
 	meta.SetVersion(opts.Version)
+	if opts.WriteVersion && opts.Version != "" {
+		if err := meta.Save(metaFile); err != nil {
+			return nil, err
+		}
+	}
+	meta = resolved
 
-	// You can optionally write it back out before packaging
-	if err := meta.Save(filepath.Join(opts.PluginDir, "plugin.yaml")); err != nil {
-		return nil, err
+	commit := opts.Commit
+	if commit == "" {
+		commit = GitCommit(opts.PluginDir)
+	}
+	if opts.Stamp {
+		meta.Stamp = &types.Stamp{Commit: commit, Built: time.Now().UTC().Truncate(time.Second)}
+	}
+	if opts.PinDependencies != nil {
+		if err := pinDependencies(meta, opts.PinDependencies); err != nil {
+			return nil, err
+		}
 	}
 
-	// Supported platforms
-	targets := []Platform{
-		{"darwin", "amd64"},
-		{"darwin", "arm64"},
-		{"linux", "amd64"},
-		{"linux", "arm64"},
-		{"windows", "amd64"},
-		{"windows", "arm64"},
+	// keep a copy of the resolved metadata next to the packages for publishing
+	if err := os.MkdirAll(filepath.Join(opts.PluginDir, opts.OutDir), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create output directory: %w", err)
+	}
+	if err := meta.Save(filepath.Join(opts.PluginDir, opts.OutDir, "plugin.yaml")); err != nil {
+		return nil, err
 	}
 
 	// Run all builds concurrently
-	buildResults := BuildAll(opts.PluginDir, opts.Version, opts.OutDir, targets)
+	if opts.Report != nil {
+		opts.Report.Build = &types.BuildInfo{
+			PluginID: meta.ID,
+			Version:  meta.Version,
+			Commit:   commit,
+		}
+	}
+
+	tracker := progress.New(console.Stdout)
+	started := time.Now()
+	builds, uiResult := BuildAll(BuildOpts{
+		PluginDir:   opts.PluginDir,
+		Version:     meta.Version,
+		OutDir:      opts.OutDir,
+		Platforms:   platforms,
+		GoCache:     opts.GoCache,
+		GoModCache:  opts.GoModCache,
+		GoCacheProg: opts.GoCacheProg,
+		PluginID:    meta.ID,
+		Commit:      commit,
+		LDFlags:     opts.LDFlags,
+		Metadata:    meta,
+		Progress:    tracker,
+	})
+	tracker.Stop()
+	elapsed := time.Since(started)
+
+	// the report has the failures of the builds themselves, the rest what's packaged
+	if opts.JUnitReport != "" {
+		if err := WriteJUnitReport(opts.JUnitReport, builds, uiResult, elapsed); err != nil {
+			return nil, err
+		}
+	}
+	buildResults := Packageable(builds, uiResult)
+
+	// a build missing what the capabilities declare would install but fail to load
+	var layoutErrs []error
+	for _, result := range buildResults {
+		if result.Err == nil {
+			layoutErrs = append(layoutErrs, checkLayout(meta, result.OutputDir, result.Platform))
+		}
+	}
+	if err := errors.Join(layoutErrs...); err != nil {
+		return nil, fmt.Errorf("packages don't match the declared capabilities:\n%w", err)
+	}
+
+	// test the builds against the cores before they're compressed (and removed)
+	if len(opts.CompatTests) > 0 {
+		compatibility, err := RunCompatTests(opts.CompatTests, buildResults)
+		if err != nil {
+			return nil, err
+		}
+		if opts.Report != nil {
+			opts.Report.Compatibility = compatibility
+		}
+	}
 
 	// Compress each successful build
+	packResult := &PackResult{
+		Metadata:  meta,
+		Platforms: make([]PlatformResult, 0, len(buildResults)),
+		UI:        uiResult,
+		LogDir:    filepath.Join(opts.PluginDir, opts.OutDir, LogDir),
+		Scan:      scan,
+	}
 	for _, result := range buildResults {
-		if result.Err != nil {
-			fmt.Printf("❌ Build failed for %s: %v\n", result.Platform, result.Err)
-			continue
+		platResult := PlatformResult{
+			Platform: result.Platform,
+			Duration: result.Duration,
+			Err:      result.Err,
+			Log:      result.Log,
 		}
-		out := filepath.Join(
-			opts.PluginDir,
-			fmt.Sprintf("%s/%s.tar.gz", opts.OutDir, result.Platform.Key()),
-		)
-		if _, _, err := TarGz(result.OutputDir, out); err != nil {
-			return nil, fmt.Errorf("compression failed for %s: %w", result.Platform.Key(), err)
+
+		if result.Err == nil {
+			out := filepath.Join(
+				opts.PluginDir,
+				fmt.Sprintf("%s/%s.tar.gz", opts.OutDir, result.Platform.Key()),
+			)
+			_, shaFile, err := TarGz(result.OutputDir, out)
+			if err != nil {
+				return nil, fmt.Errorf("compression failed for %s: %w", result.Platform.Key(), err)
+			}
+			platResult.Artifact = out
+			if info, err := os.Stat(out); err == nil {
+				platResult.Size = info.Size()
+			}
+			if checksum, err := os.ReadFile(shaFile); err == nil {
+				platResult.Checksum = strings.TrimSpace(string(checksum))
+			}
+		}
+		packResult.Platforms = append(packResult.Platforms, platResult)
+
+		if opts.Report != nil {
+			platReport := opts.Report.Platform(result.Platform.Key())
+			platReport.BuildDurationMS = result.Duration.Milliseconds()
+			platReport.BuildLog = result.Log
+			if result.Err != nil {
+				platReport.Error = result.Err.Error()
+				continue
+			}
+			platReport.Artifact = platResult.Artifact
+			platReport.Size = platResult.Size
+			platReport.Checksum = platResult.Checksum
 		}
-		fmt.Printf("✅ Packaged %s → %s\n", result.Platform.Key(), out)
 	}
 
-	fmt.Printf("\nSuccessfully packaged plugin for distribution\n")
+	return packResult, nil
+}
+
+// metadataFile returns the plugin metadata file to package with.
+func (o PackOpts) metadataFile() (string, error) {
+	if o.MetadataFile == "" {
+		return FindMetadataFile(o.PluginDir)
+	}
+	if _, err := os.Stat(o.MetadataFile); err != nil {
+		return "", fmt.Errorf("invalid metadata file: %w", err)
+	}
+	return o.MetadataFile, nil
+}
+
+// platforms returns the platforms to package for.
+func (o PackOpts) platforms() []Platform {
+	if len(o.Platforms) == 0 {
+		return DefaultPlatforms
+	}
+	return o.Platforms
+}
+
+// setPackageVersion sets the version to package, defaulting to the one in plugin.yaml, and
+// checks it's a semantic version. A missing version is left for Validate to report.
+func setPackageVersion(meta *PluginMetadata, version string) error {
+	source := "--version"
+	if version == "" {
+		version, source = meta.Version, "plugin.yaml"
+	}
+	if version == "" {
+		return nil
+	}
+	if _, err := semver.NewVersion(version); err != nil {
+		return fmt.Errorf(
+			"invalid version %q from %s, expected a semantic version (e.g. 1.2.3)",
+			version,
+			source,
+		)
+	}
+	meta.SetVersion(version)
+	return nil
+}
+
+// pinDependencies pins the dependencies of the plugin to the exact versions they resolve to.
+// Pins already in plugin.yaml are replaced, they're only ever meant for the packaged copy.
+func pinDependencies(
+	meta *PluginMetadata,
+	pin func(requires map[string]string) (map[string]string, error),
+) error {
+	meta.Pins = nil
+	requires, err := meta.Requires()
+	if err != nil || len(requires) == 0 {
+		return err
+	}
+	pins, err := pin(requires)
+	if err != nil {
+		return fmt.Errorf("couldn't pin the dependencies: %w", err)
+	}
+	for _, id := range slices.Sorted(maps.Keys(pins)) {
+		console.Printf("pinned %s to %s\n", id, pins[id])
+	}
+	meta.Pins = pins
+	return nil
+}
+
+// checkLayout checks a platform build contains what the plugin's capabilities declare: UI
+// assets for a ui plugin, and the plugin binary for a backend one.
+func checkLayout(meta *PluginMetadata, dir string, plat Platform) error {
+	var errs []error
+	if meta.HasUICapabilities() && !hasFiles(filepath.Join(dir, "assets")) {
+		errs = append(errs, fmt.Errorf(
+			"%s: the ui capability is declared, but the package has no UI assets",
+			plat.Key(),
+		))
+	}
+	if meta.HasBackendCapabilities() {
+		bin := filepath.Join(dir, "bin", binaryName(plat))
+		if info, err := os.Stat(bin); err != nil || !info.Mode().IsRegular() {
+			errs = append(errs, fmt.Errorf(
+				"%s: backend capabilities are declared (%s), but the package has no bin/%s",
+				plat.Key(),
+				strings.Join(meta.Capabilities, ", "),
+				binaryName(plat),
+			))
+		}
+	}
+	return errors.Join(errs...)
+}
 
-	return meta, nil
+// hasFiles reports whether there's at least one file in the directory tree.
+func hasFiles(dir string) bool {
+	found := false
+	filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || found {
+			return filepath.SkipAll
+		}
+		if !d.IsDir() {
+			found = true
+		}
+		return nil
+	})
+	return found
 }
@@ -0,0 +1,141 @@
+package packager
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/omniviewdev/registry-cli/pkg/types"
+)
+
+func TestSetPackageVersion(t *testing.T) {
+	tests := []struct {
+		name    string
+		meta    string
+		version string
+		want    string
+		wantErr string
+	}{
+		{name: "from the flag", meta: "1.0.0", version: "1.2.3", want: "1.2.3"},
+		{name: "from plugin.yaml", meta: "1.0.0", want: "1.0.0"},
+		{name: "prerelease", version: "2.0.0-rc.1", want: "2.0.0-rc.1"},
+		{name: "none"},
+		{name: "invalid flag", meta: "1.0.0", version: "latest", wantErr: `"latest" from --version`},
+		{name: "invalid plugin.yaml", meta: "one", wantErr: `"one" from plugin.yaml`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			meta := &PluginMetadata{PluginMeta: types.PluginMeta{Version: tt.meta}}
+			err := setPackageVersion(meta, tt.version)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("got %v, want an error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if meta.Version != tt.want {
+				t.Fatalf("set version %q, want %q", meta.Version, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckLayout(t *testing.T) {
+	linux := Platform{OS: "linux", Arch: "amd64"}
+	windows := Platform{OS: "windows", Arch: "amd64"}
+
+	tests := []struct {
+		name         string
+		capabilities []string
+		files        []string
+		platform     Platform
+		wantErrs     []string
+	}{
+		{
+			name:         "backend and ui",
+			capabilities: []string{"resource", "ui"},
+			files:        []string{"bin/plugin", "assets/js/index.js"},
+			platform:     linux,
+		},
+		{
+			name:         "windows binary",
+			capabilities: []string{"exec"},
+			files:        []string{"bin/plugin.exe"},
+			platform:     windows,
+		},
+		{
+			name:         "ui only",
+			capabilities: []string{"ui"},
+			files:        []string{"assets/index.html"},
+			platform:     linux,
+		},
+		{
+			name:         "no binary",
+			capabilities: []string{"resource", "ui"},
+			files:        []string{"assets/index.html"},
+			platform:     linux,
+			wantErrs:     []string{"linux_amd64: backend capabilities are declared (resource, ui)"},
+		},
+		{
+			name:         "binary of another platform",
+			capabilities: []string{"exec"},
+			files:        []string{"bin/plugin"},
+			platform:     windows,
+			wantErrs:     []string{"no bin/plugin.exe"},
+		},
+		{
+			name:         "empty assets",
+			capabilities: []string{"ui"},
+			files:        []string{"assets/"},
+			platform:     linux,
+			wantErrs:     []string{"the ui capability is declared, but the package has no UI assets"},
+		},
+		{
+			name:         "neither",
+			capabilities: []string{"settings", "ui"},
+			platform:     linux,
+			wantErrs:     []string{"no UI assets", "no bin/plugin"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for _, file := range tt.files {
+				path := filepath.Join(dir, filepath.FromSlash(file))
+				if strings.HasSuffix(file, "/") {
+					if err := os.MkdirAll(path, 0o755); err != nil {
+						t.Fatal(err)
+					}
+					continue
+				}
+				if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, []byte(file), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			meta := &PluginMetadata{PluginMeta: types.PluginMeta{Capabilities: tt.capabilities}}
+			err := checkLayout(meta, dir, tt.platform)
+			if len(tt.wantErrs) == 0 {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("got no error")
+			}
+			for _, want := range tt.wantErrs {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("got %v, want an error containing %q", err, want)
+				}
+			}
+		})
+	}
+}
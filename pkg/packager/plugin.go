@@ -3,94 +3,99 @@ package packager
 import (
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 
+	"github.com/omniviewdev/registry-cli/pkg/schema"
+	"github.com/omniviewdev/registry-cli/pkg/types"
 	"gopkg.in/yaml.v3"
 )
 
+// PluginMetadata is the plugin.yaml of a plugin: the metadata recorded in the index, along
+// with how the plugin is packaged.
 type PluginMetadata struct {
-	ID           string       `yaml:"id"`
-	Version      string       `yaml:"version"`
-	Name         string       `yaml:"name"`
-	Icon         string       `yaml:"icon"`
-	Description  string       `yaml:"description"`
-	Repository   string       `yaml:"repository"`
-	Website      string       `yaml:"website"`
-	Maintainers  []Maintainer `yaml:"maintainers"`
-	Tags         []string     `yaml:"tags,omitempty"`
-	Dependencies any          `yaml:"dependencies,omitempty"`
-	Capabilities []string     `yaml:"capabilities"`
-	Theme        *Theme       `yaml:"theme,omitempty"`
+	types.PluginMeta `yaml:",inline"`
+
+	UI      *UIConfig    `yaml:"ui,omitempty"`
+	Build   *BuildConfig `yaml:"build,omitempty"`
+	Engines *Engines     `yaml:"engines,omitempty"`
 }
 
-type Maintainer struct {
-	Name  string `yaml:"name"`
-	Email string `yaml:"email"`
+// MetadataFiles are the names the plugin metadata is looked for under, in order. JSON is
+// parsed as YAML, being a subset of it.
+var MetadataFiles = []string{
+	"plugin.yaml",
+	"plugin.yml",
+	"plugin.json",
+	"omniview.yaml",
+	"omniview.yml",
+	"omniview.json",
 }
 
-type Theme struct {
-	Colors map[string]string `yaml:"colors,omitempty"`
+// FindMetadataFile returns the path of the plugin's metadata file, looking in the plugin
+// directory and then its parents, up to the root of the git repository it's in. Outside of a
+// repository, only the plugin directory is looked in.
+func FindMetadataFile(pluginDir string) (string, error) {
+	dir, err := filepath.Abs(pluginDir)
+	if err != nil {
+		return "", err
+	}
+	if path := FindInRepository(dir, MetadataFiles...); path != "" {
+		return path, nil
+	}
+	return "", fmt.Errorf(
+		"no plugin metadata in %s or its parents, expected one of %s",
+		pluginDir,
+		strings.Join(MetadataFiles, ", "),
+	)
 }
 
-// LoadPlugin loads and parses plugin.yaml, returning structured metadata
+// LoadPlugin loads and parses plugin.yaml (or plugin.json), returning structured metadata
 func LoadPluginMetadata(path string) (*PluginMetadata, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read plugin metadata: %w", err)
 	}
 
-	var meta PluginMetadata
-	if err := yaml.Unmarshal(data, &meta); err != nil {
+	var doc any
+	if err := yaml.Unmarshal(data, &doc); err != nil {
 		return nil, fmt.Errorf("failed to parse plugin.yaml: %w", err)
 	}
-
-	return &meta, nil
-}
-
-// Validate checks for required fields
-func (m *PluginMetadata) Validate() error {
-	var missing []string
-
-	if m.ID == "" {
-		missing = append(missing, "id")
-	}
-	if m.Name == "" {
-		missing = append(missing, "name")
+	// required fields are checked by Validate, once any org defaults have been applied
+	var errs []schema.ValidationError
+	for _, verr := range schema.Validate(PluginMetadataSchema(), doc) {
+		if !verr.Missing {
+			errs = append(errs, verr)
+		}
 	}
-	if m.Version == "" {
-		missing = append(missing, "version")
-	}
-	if m.Description == "" {
-		missing = append(missing, "description")
-	}
-	if m.Repository == "" {
-		missing = append(missing, "repository")
-	}
-	if m.Website == "" {
-		missing = append(missing, "website")
-	}
-	if len(m.Maintainers) == 0 {
-		missing = append(missing, "maintainers")
-	}
-	if len(m.Capabilities) == 0 {
-		missing = append(missing, "capabilities")
+	if err := schema.Join(errs); err != nil {
+		return nil, fmt.Errorf("schema validation failed:\n%w", err)
 	}
 
-	if len(missing) > 0 {
-		return fmt.Errorf("plugin.yaml is missing required fields: %v", missing)
+	var meta PluginMetadata
+	if err := yaml.Unmarshal(data, &meta); err != nil {
+		return nil, fmt.Errorf("failed to parse plugin.yaml: %w", err)
 	}
-	return nil
+
+	return &meta, nil
 }
 
-// SetVersion sets the version and returns updated YAML
-func (m *PluginMetadata) SetVersion(version string) {
-	m.Version = version
+// PluginMetadataSchema returns the JSON Schema describing plugin.yaml
+func PluginMetadataSchema() *schema.Schema {
+	return schema.Generate(PluginMetadata{}, "yaml", "Omniview plugin metadata")
 }
 
-// Save writes the plugin.yaml back out to disk (optional step)
+// Save writes the plugin.yaml back out to disk (optional step), as JSON when the path is a
+// .json file
 func (m *PluginMetadata) Save(path string) error {
 	out, err := yaml.Marshal(m)
 	if err != nil {
 		return fmt.Errorf("failed to marshal plugin.yaml: %w", err)
 	}
+	if format, err := types.MetaFormatFromPath(path); err == nil && format == types.PluginMetaFormatJSON {
+		if out, err = types.ConvertMetadata(out, format); err != nil {
+			return err
+		}
+	}
 	return os.WriteFile(path, out, 0644)
 }
@@ -0,0 +1,168 @@
+package packager
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFindMetadataFile(t *testing.T) {
+	tmp := t.TempDir()
+	repo := filepath.Join(tmp, "repo")
+	plugin := filepath.Join(repo, "plugins", "demo")
+	outside := filepath.Join(tmp, "outside", "demo")
+	for _, dir := range []string{filepath.Join(repo, ".git"), plugin, outside} {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write := func(path string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte("id: demo\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// never looked in: above the repository, and above a directory outside of one
+	write(filepath.Join(tmp, "plugin.yaml"))
+	write(filepath.Join(tmp, "outside", "plugin.yaml"))
+
+	tests := []struct {
+		name  string
+		files []string
+		dir   string
+		want  string
+	}{
+		{
+			name: "none in the repository",
+			dir:  plugin,
+		},
+		{
+			name:  "in the plugin directory",
+			files: []string{filepath.Join(plugin, "plugin.json"), filepath.Join(repo, "plugin.yaml")},
+			dir:   plugin,
+			want:  filepath.Join(plugin, "plugin.json"),
+		},
+		{
+			name:  "in the order of the names",
+			files: []string{filepath.Join(plugin, "plugin.json"), filepath.Join(plugin, "plugin.yml")},
+			dir:   plugin,
+			want:  filepath.Join(plugin, "plugin.yml"),
+		},
+		{
+			name:  "at the root of the repository",
+			files: []string{filepath.Join(repo, "omniview.yaml")},
+			dir:   plugin,
+			want:  filepath.Join(repo, "omniview.yaml"),
+		},
+		{
+			name: "none outside of a repository",
+			dir:  outside,
+		},
+		{
+			name:  "in a directory outside of a repository",
+			files: []string{filepath.Join(outside, "plugin.yaml")},
+			dir:   outside,
+			want:  filepath.Join(outside, "plugin.yaml"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, file := range tt.files {
+				write(file)
+				t.Cleanup(func() { os.Remove(file) })
+			}
+			got, err := FindMetadataFile(tt.dir)
+			if tt.want == "" {
+				if err == nil {
+					t.Fatalf("found %q", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Fatalf("found %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadPluginMetadata(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		wantErr string
+	}{
+		{
+			name: "valid",
+			file: "plugin.yaml",
+			content: `id: demo
+version: 1.0.0
+name: Demo
+capabilities: [resource, ui]
+maintainers:
+  - name: Jane Doe
+    email: jane@example.com
+`,
+		},
+		{
+			name:    "json",
+			file:    "plugin.json",
+			content: `{"id": "demo", "version": "1.0.0", "capabilities": ["ui"]}`,
+		},
+		{
+			// required fields are checked once the org defaults are applied
+			name:    "missing fields",
+			file:    "plugin.yaml",
+			content: "id: demo\n",
+		},
+		{
+			name:    "wrong type",
+			file:    "plugin.yaml",
+			content: "id: demo\ncapabilities: ui\n",
+			wantErr: "capabilities: expected a list, got string",
+		},
+		{
+			name:    "nested wrong type",
+			file:    "plugin.yaml",
+			content: "id: demo\nmaintainers:\n  - name: [Jane]\n",
+			wantErr: "maintainers[0].name: expected a string",
+		},
+		{
+			name:    "unknown field",
+			file:    "plugin.yaml",
+			content: "id: demo\ncapabilites: [ui]\n",
+			wantErr: `capabilites: unknown field (did you mean "capabilities"?)`,
+		},
+		{
+			name:    "invalid yaml",
+			file:    "plugin.yaml",
+			content: "id: [demo\n",
+			wantErr: "failed to parse plugin.yaml",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.file)
+			if err := os.WriteFile(path, []byte(tt.content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			meta, err := LoadPluginMetadata(path)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("got %v, want an error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if meta.ID != "demo" {
+				t.Fatalf("loaded id %q, want demo", meta.ID)
+			}
+		})
+	}
+}
@@ -0,0 +1,195 @@
+package packager
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+)
+
+// Engines declares the toolchain versions the plugin builds with, as semver constraints
+// (e.g. >=1.22) or bare minimum versions (e.g. 9.0.0).
+type Engines struct {
+	Go   string `yaml:"go,omitempty"`
+	Pnpm string `yaml:"pnpm,omitempty"`
+}
+
+// binarySizeEstimate is how much room a plugin binary is assumed to need when estimating the
+// disk space of a build
+const binarySizeEstimate = 64 << 20
+
+// Preflight checks that the toolchains the build needs are installed at the versions the
+// plugin declares, and that the output volume has room for the platform trees, so a build
+// that can't succeed fails before starting rather than halfway through.
+func Preflight(pluginDir, outDir string, meta *PluginMetadata, platforms []Platform) error {
+	_, errs := checkToolchains(pluginDir, meta)
+	if err := checkDiskSpace(pluginDir, filepath.Join(pluginDir, outDir), platforms); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// requiredTools returns the toolchains building the plugin needs.
+func requiredTools(meta *PluginMetadata) []string {
+	if meta.Build != nil && meta.Build.Command != "" {
+		return []string{"sh", "pnpm"}
+	}
+	return []string{"go", "pnpm"}
+}
+
+// checkToolchains looks up the toolchains the build needs, checking their versions against
+// the plugin's engines. It returns where each tool was found.
+func checkToolchains(pluginDir string, meta *PluginMetadata) (map[string]string, []error) {
+	var engines Engines
+	if meta.Engines != nil {
+		engines = *meta.Engines
+	}
+	constraints := map[string]string{"go": engines.Go, "pnpm": engines.Pnpm}
+
+	found := make(map[string]string)
+	var errs []error
+	for _, tool := range requiredTools(meta) {
+		path, err := exec.LookPath(tool)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s isn't installed (or isn't on the PATH)", tool))
+			continue
+		}
+		found[tool] = path
+
+		if tool == "sh" {
+			continue
+		}
+		installed, err := toolchainVersion(pluginDir, tool)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if err := checkEngine(tool, constraints[tool], installed); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return found, errs
+}
+
+// checkEngine checks the installed version of the tool meets the constraint declared in the
+// plugin's engines. Versions that can't be compared are let through, the build will tell.
+func checkEngine(tool, constraint, installed string) error {
+	if constraint == "" {
+		return nil
+	}
+	c, err := semver.NewConstraint(constraint)
+	if v, verr := semver.NewVersion(constraint); verr == nil {
+		// a bare version is a minimum
+		c, err = semver.NewConstraint(">= " + v.String())
+	}
+	if err != nil {
+		return fmt.Errorf("invalid %s engine %q in plugin.yaml: %w", tool, constraint, err)
+	}
+
+	v, err := semver.NewVersion(installed)
+	if err != nil {
+		return nil
+	}
+	if !c.Check(v) {
+		return fmt.Errorf(
+			"plugin.yaml requires %s %s, but %s %s is installed",
+			tool,
+			constraint,
+			tool,
+			installed,
+		)
+	}
+	return nil
+}
+
+// toolchainVersion returns the version of the tool building the plugin. It's resolved within
+// the plugin, as go.mod may select another go toolchain, failing when that toolchain is newer
+// than the installed one and can't be downloaded.
+func toolchainVersion(pluginDir, tool string) (string, error) {
+	cmd := exec.Command(tool, "--version")
+	if tool == "go" {
+		cmd = exec.Command("go", "env", "GOVERSION")
+	}
+	cmd.Dir = pluginDir
+
+	out, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+			err = errors.New(strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return "", fmt.Errorf("couldn't get the %s version: %w", tool, err)
+	}
+	return strings.TrimPrefix(strings.TrimSpace(string(out)), "go"), nil
+}
+
+// checkDiskSpace checks the volume holding the output has room for the platform trees. The
+// UI assets are estimated from the last UI build, if there's one.
+func checkDiskSpace(pluginDir, outDir string, platforms []Platform) error {
+	free, ok := freeSpace(existingParent(outDir))
+	if !ok {
+		return nil
+	}
+
+	assets := dirSize(filepath.Join(pluginDir, "ui", "dist", "assets"))
+	needed := uint64(len(platforms)) * uint64(binarySizeEstimate+assets)
+	if free < needed {
+		return fmt.Errorf(
+			"not enough disk space for %d platform builds in %s: %s free, about %s needed",
+			len(platforms),
+			outDir,
+			formatBytes(int64(free)),
+			formatBytes(int64(needed)),
+		)
+	}
+	return nil
+}
+
+// existingParent returns the closest directory to path that exists, as the output directory
+// may not have been created yet.
+func existingParent(path string) string {
+	for {
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+		parent := filepath.Dir(path)
+		if parent == path {
+			return path
+		}
+		path = parent
+	}
+}
+
+// dirSize returns the total size of the files in dir, 0 if it doesn't exist.
+func dirSize(dir string) int64 {
+	var size int64
+	_ = filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		if info, err := d.Info(); err == nil {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size
+}
+
+// formatBytes formats a size in bytes for humans.
+func formatBytes(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
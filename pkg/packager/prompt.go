@@ -0,0 +1,228 @@
+package packager
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net/mail"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/omniviewdev/registry-cli/pkg/types"
+)
+
+// KnownCapabilities are the capabilities a plugin can declare in plugin.yaml
+var KnownCapabilities = []string{"ui", "resource", "exec", "networker", "settings"}
+
+var (
+	pluginIDPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+	versionPattern  = regexp.MustCompile(
+		`^v?\d+\.\d+\.\d+(-[0-9A-Za-z.-]+)?(\+[0-9A-Za-z.-]+)?$`,
+	)
+)
+
+// ValidatePluginID checks the plugin ID is lowercase alphanumeric words separated by dashes.
+func ValidatePluginID(id string) error {
+	if !pluginIDPattern.MatchString(id) {
+		return fmt.Errorf("plugin ID must be lowercase letters and numbers separated by dashes")
+	}
+	return nil
+}
+
+// ValidateVersion checks the version is a semantic version.
+func ValidateVersion(version string) error {
+	if !versionPattern.MatchString(version) {
+		return fmt.Errorf("%q is not a valid semantic version (e.g. 1.2.3)", version)
+	}
+	return nil
+}
+
+// ValidateCapabilities checks every capability is known.
+func ValidateCapabilities(capabilities []string) error {
+	if len(capabilities) == 0 {
+		return fmt.Errorf("at least one capability is required")
+	}
+	for _, capability := range capabilities {
+		if !slices.Contains(KnownCapabilities, capability) {
+			return fmt.Errorf(
+				"unknown capability %q, expected one of %s",
+				capability,
+				strings.Join(KnownCapabilities, ", "),
+			)
+		}
+	}
+	return nil
+}
+
+// ValidateColor checks the color is a hex color (#rgb, #rrggbb or #rrggbbaa).
+func ValidateColor(color string) error {
+	return types.ValidateColor(color)
+}
+
+// ValidateEmail checks the email address is well formed.
+func ValidateEmail(email string) error {
+	if _, err := mail.ParseAddress(email); err != nil {
+		return fmt.Errorf("%q is not a valid email address", email)
+	}
+	return nil
+}
+
+// Prompter asks questions on an input/output pair, re-asking until answers are valid.
+type Prompter struct {
+	in  *bufio.Reader
+	out io.Writer
+}
+
+// NewPrompter creates a prompter reading answers from in and writing questions to out.
+func NewPrompter(in io.Reader, out io.Writer) *Prompter {
+	return &Prompter{in: bufio.NewReader(in), out: out}
+}
+
+// Ask asks a question, returning def when the answer is empty. The question is repeated
+// until validate (if given) accepts the answer.
+func (p *Prompter) Ask(label, def string, validate func(string) error) (string, error) {
+	for {
+		if def != "" {
+			fmt.Fprintf(p.out, "%s [%s]: ", label, def)
+		} else {
+			fmt.Fprintf(p.out, "%s: ", label)
+		}
+
+		line, err := p.in.ReadString('\n')
+		if err != nil && (!errors.Is(err, io.EOF) || line == "") {
+			return "", fmt.Errorf("failed to read answer: %w", err)
+		}
+		answer := strings.TrimSpace(line)
+		if answer == "" {
+			answer = def
+		}
+
+		if validate != nil {
+			if err := validate(answer); err != nil {
+				fmt.Fprintf(p.out, "  ✗ %v\n", err)
+				continue
+			}
+		}
+		return answer, nil
+	}
+}
+
+// Confirm asks a yes/no question.
+func (p *Prompter) Confirm(label string, def bool) (bool, error) {
+	defAnswer := "y/N"
+	if def {
+		defAnswer = "Y/n"
+	}
+	answer, err := p.Ask(label+" ("+defAnswer+")", "", func(s string) error {
+		switch strings.ToLower(s) {
+		case "", "y", "yes", "n", "no":
+			return nil
+		}
+		return fmt.Errorf("please answer yes or no")
+	})
+	if err != nil {
+		return false, err
+	}
+	switch strings.ToLower(answer) {
+	case "y", "yes":
+		return true, nil
+	case "n", "no":
+		return false, nil
+	}
+	return def, nil
+}
+
+func required(name string) func(string) error {
+	return func(s string) error {
+		if s == "" {
+			return fmt.Errorf("%s is required", name)
+		}
+		return nil
+	}
+}
+
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// PromptPluginMetadata interactively builds plugin metadata, validating each answer as it goes.
+func PromptPluginMetadata(p *Prompter) (*PluginMetadata, error) {
+	var (
+		meta PluginMetadata
+		err  error
+	)
+
+	ask := func(dest *string, label, def string, validate func(string) error) {
+		if err != nil {
+			return
+		}
+		*dest, err = p.Ask(label, def, validate)
+	}
+
+	ask(&meta.ID, "Plugin ID", "", ValidatePluginID)
+	ask(&meta.Name, "Name", "", required("name"))
+	ask(&meta.Version, "Version", "0.1.0", ValidateVersion)
+	ask(&meta.Description, "Description", "", required("description"))
+	ask(&meta.Icon, "Icon", "", nil)
+	ask(&meta.Repository, "Repository URL", "", required("repository"))
+	ask(&meta.Website, "Website", meta.Repository, required("website"))
+
+	var capabilities string
+	ask(&capabilities, "Capabilities (comma separated: "+strings.Join(KnownCapabilities, ", ")+")",
+		"ui", func(s string) error { return ValidateCapabilities(splitList(s)) })
+	meta.Capabilities = splitList(capabilities)
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		var m types.PluginMaintainer
+		ask(&m.Name, "Maintainer name", "", required("maintainer name"))
+		ask(&m.Email, "Maintainer email", "", ValidateEmail)
+		if err != nil {
+			return nil, err
+		}
+		meta.Maintainers = append(meta.Maintainers, m)
+
+		more, err := p.Confirm("Add another maintainer?", false)
+		if err != nil {
+			return nil, err
+		}
+		if !more {
+			break
+		}
+	}
+
+	colors := map[string]string{}
+	for _, name := range []string{"primary", "secondary", "tertiary"} {
+		var color string
+		ask(&color, "Theme "+name+" color (optional)", "", func(s string) error {
+			if s == "" {
+				return nil
+			}
+			return ValidateColor(s)
+		})
+		if color != "" {
+			colors[name] = color
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(colors) > 0 {
+		meta.Theme = &types.PluginTheme{Colors: colors}
+	}
+
+	if err := meta.Validate(); err != nil {
+		return nil, err
+	}
+	return &meta, nil
+}
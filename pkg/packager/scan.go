@@ -0,0 +1,191 @@
+package packager
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/omniviewdev/registry-cli/pkg/types"
+)
+
+// ScanOpts configures the vulnerability scans run while packaging.
+type ScanOpts struct {
+	// UI also audits the dependencies of the UI with pnpm audit
+	UI bool
+}
+
+// Scan scans the plugin for known vulnerabilities: its Go module with govulncheck, and the
+// dependencies of its UI with pnpm audit when asked to. govulncheck doesn't grade
+// vulnerabilities, so the ones the plugin calls into are reported as critical.
+func Scan(pluginDir string, opts ScanOpts) (*types.ScanReport, error) {
+	report := &types.ScanReport{}
+
+	if _, err := os.Stat(filepath.Join(pluginDir, "go.mod")); err == nil {
+		findings, err := govulncheck(pluginDir)
+		if err != nil {
+			return nil, err
+		}
+		report.Scanners = append(report.Scanners, "govulncheck")
+		report.Findings = append(report.Findings, findings...)
+	}
+
+	if opts.UI {
+		findings, err := pnpmAudit(filepath.Join(pluginDir, "ui"))
+		if err != nil {
+			return nil, err
+		}
+		report.Scanners = append(report.Scanners, "pnpm-audit")
+		report.Findings = append(report.Findings, findings...)
+	}
+
+	// most severe first
+	slices.SortStableFunc(report.Findings, func(a, b types.ScanFinding) int {
+		return slices.Index(types.Severities, b.Severity) - slices.Index(types.Severities, a.Severity)
+	})
+	return report, nil
+}
+
+// govulncheck runs govulncheck on the plugin module, returning the vulnerabilities the plugin
+// calls into.
+func govulncheck(pluginDir string) ([]types.ScanFinding, error) {
+	if _, err := exec.LookPath("govulncheck"); err != nil {
+		return nil, errors.New("govulncheck isn't installed (or isn't on the PATH), install it " +
+			"with 'go install golang.org/x/vuln/cmd/govulncheck@latest'")
+	}
+
+	cmd := exec.Command("govulncheck", "-format", "json", "./...")
+	cmd.Dir = pluginDir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf(
+			"govulncheck failed: %w: %s",
+			err,
+			strings.TrimSpace(stderr.String()),
+		)
+	}
+
+	type osv struct {
+		ID      string `json:"id"`
+		Summary string `json:"summary"`
+	}
+	type finding struct {
+		OSV          string `json:"osv"`
+		FixedVersion string `json:"fixed_version"`
+		Trace        []struct {
+			Module   string `json:"module"`
+			Package  string `json:"package"`
+			Function string `json:"function"`
+		} `json:"trace"`
+	}
+
+	osvs := make(map[string]osv)
+	var findings []types.ScanFinding
+	seen := make(map[string]bool)
+	dec := json.NewDecoder(bytes.NewReader(out))
+	for {
+		var msg struct {
+			OSV     *osv     `json:"osv"`
+			Finding *finding `json:"finding"`
+		}
+		if err := dec.Decode(&msg); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("couldn't parse the govulncheck output: %w", err)
+		}
+
+		if msg.OSV != nil {
+			osvs[msg.OSV.ID] = *msg.OSV
+		}
+		f := msg.Finding
+		// only the vulnerable functions the plugin calls into have a function in their trace
+		if f == nil || len(f.Trace) == 0 || f.Trace[0].Function == "" || seen[f.OSV] {
+			continue
+		}
+		seen[f.OSV] = true
+
+		pkg := f.Trace[0].Package
+		if pkg == "" {
+			pkg = f.Trace[0].Module
+		}
+		findings = append(findings, types.ScanFinding{
+			Scanner:  "govulncheck",
+			ID:       f.OSV,
+			Package:  pkg,
+			Severity: types.SeverityCritical,
+			Summary:  osvs[f.OSV].Summary,
+			URL:      "https://pkg.go.dev/vuln/" + f.OSV,
+			Fixed:    f.FixedVersion,
+		})
+	}
+	return findings, nil
+}
+
+// pnpmAudit audits the dependencies of the UI with pnpm audit.
+func pnpmAudit(uiDir string) ([]types.ScanFinding, error) {
+	cmd := exec.Command("pnpm", "audit", "--json")
+	cmd.Dir = uiDir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	// pnpm audit exits with an error when it finds vulnerabilities, the output tells
+	out, err := cmd.Output()
+	var exitErr *exec.ExitError
+	if err != nil && !errors.As(err, &exitErr) {
+		return nil, fmt.Errorf("pnpm audit failed: %w", err)
+	}
+
+	var audit struct {
+		Advisories map[string]struct {
+			ID              any    `json:"id"`
+			GHSA            string `json:"github_advisory_id"`
+			ModuleName      string `json:"module_name"`
+			Severity        string `json:"severity"`
+			Title           string `json:"title"`
+			URL             string `json:"url"`
+			PatchedVersions string `json:"patched_versions"`
+		} `json:"advisories"`
+	}
+	if jerr := json.Unmarshal(out, &audit); jerr != nil {
+		if err != nil {
+			return nil, fmt.Errorf(
+				"pnpm audit failed: %w: %s",
+				err,
+				strings.TrimSpace(stderr.String()),
+			)
+		}
+		return nil, fmt.Errorf("couldn't parse the pnpm audit output: %w", jerr)
+	}
+
+	findings := make([]types.ScanFinding, 0, len(audit.Advisories))
+	for _, key := range slices.Sorted(maps.Keys(audit.Advisories)) {
+		advisory := audit.Advisories[key]
+		severity, err := types.ParseSeverity(advisory.Severity)
+		if err != nil {
+			// info
+			severity = types.SeverityLow
+		}
+		id := advisory.GHSA
+		if id == "" {
+			id = fmt.Sprint(advisory.ID)
+		}
+		findings = append(findings, types.ScanFinding{
+			Scanner:  "pnpm-audit",
+			ID:       id,
+			Package:  advisory.ModuleName,
+			Severity: severity,
+			Summary:  advisory.Title,
+			URL:      advisory.URL,
+			Fixed:    advisory.PatchedVersions,
+		})
+	}
+	return findings, nil
+}
@@ -0,0 +1,161 @@
+package packager
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// TemplatePlaceholders are replaced with the plugin's details in the files (and file names) of
+// a template repository.
+var TemplatePlaceholders = []string{
+	"{{plugin.id}}",
+	"{{plugin.name}}",
+	"{{plugin.description}}",
+	"{{plugin.version}}",
+	"{{plugin.repository}}",
+	"{{plugin.website}}",
+	"{{plugin.maintainer}}",
+	"{{plugin.maintainer_email}}",
+	"{{plugin.maintainers}}",
+}
+
+// TemplateURL returns the URL to clone a template repository from, given as a URL, a local
+// path or a host/org/repo path (e.g. github.com/org/plugin-template).
+func TemplateURL(template string) string {
+	if strings.Contains(template, "://") || strings.HasPrefix(template, "git@") {
+		return template
+	}
+	if _, err := os.Stat(template); err == nil {
+		return template
+	}
+	return "https://" + template
+}
+
+// HydrateTemplate clones a template repository into dir, which mustn't exist yet, and replaces
+// the placeholders in its files with the plugin's details. The template starts a new history,
+// without the template's. When the template has a plugin metadata file, the plugin's details
+// are written into it, keeping the template's packaging settings, otherwise a plugin.yaml is
+// written.
+func HydrateTemplate(template, dir string, meta *PluginMetadata) (err error) {
+	if _, err := os.Stat(dir); err == nil {
+		return fmt.Errorf("%s already exists", dir)
+	}
+
+	url := TemplateURL(template)
+	defer func() {
+		if err != nil {
+			os.RemoveAll(dir)
+		}
+	}()
+	clone := exec.Command("git", "clone", "--depth", "1", "--quiet", url, dir)
+	if out, err := clone.CombinedOutput(); err != nil {
+		return fmt.Errorf(
+			"couldn't clone template %s: %v: %s",
+			url,
+			err,
+			strings.TrimSpace(string(out)),
+		)
+	}
+	if err := os.RemoveAll(filepath.Join(dir, ".git")); err != nil {
+		return fmt.Errorf("couldn't remove the template's history: %w", err)
+	}
+
+	if err := hydrate(dir, templateReplacer(meta)); err != nil {
+		return fmt.Errorf("couldn't hydrate template: %w", err)
+	}
+
+	metaFile := filepath.Join(dir, "plugin.yaml")
+	for _, name := range MetadataFiles {
+		path := filepath.Join(dir, name)
+		if _, err := os.Stat(path); err != nil {
+			continue
+		}
+		templateMeta, err := LoadPluginMetadata(path)
+		if err != nil {
+			return fmt.Errorf("invalid template metadata: %w", err)
+		}
+		templateMeta.PluginMeta = meta.PluginMeta
+		meta, metaFile = templateMeta, path
+		break
+	}
+	return meta.Save(metaFile)
+}
+
+func templateReplacer(meta *PluginMetadata) *strings.Replacer {
+	var maintainer, maintainerEmail string
+	maintainers := make([]string, 0, len(meta.Maintainers))
+	for idx, m := range meta.Maintainers {
+		if idx == 0 {
+			maintainer, maintainerEmail = m.Name, m.Email
+		}
+		maintainers = append(maintainers, fmt.Sprintf("%s <%s>", m.Name, m.Email))
+	}
+
+	values := []string{
+		meta.ID,
+		meta.Name,
+		meta.Description,
+		meta.Version,
+		meta.Repository,
+		meta.Website,
+		maintainer,
+		maintainerEmail,
+		strings.Join(maintainers, ", "),
+	}
+	pairs := make([]string, 0, 2*len(TemplatePlaceholders))
+	for idx, placeholder := range TemplatePlaceholders {
+		pairs = append(pairs, placeholder, values[idx])
+	}
+	return strings.NewReplacer(pairs...)
+}
+
+// hydrate replaces the placeholders in the names and contents of the files in dir, leaving
+// binary files untouched.
+func hydrate(dir string, replacer *strings.Replacer) error {
+	var renames [][2]string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if renamed := replacer.Replace(d.Name()); renamed != d.Name() {
+			renames = append(renames, [2]string{path, filepath.Join(filepath.Dir(path), renamed)})
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if bytes.IndexByte(b[:min(len(b), 8000)], 0) != -1 {
+			// binary
+			return nil
+		}
+		hydrated := replacer.Replace(string(b))
+		if hydrated == string(b) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(path, []byte(hydrated), info.Mode().Perm())
+	})
+	if err != nil {
+		return err
+	}
+
+	// deepest first, so the paths of the renames left are still valid
+	for idx := len(renames) - 1; idx >= 0; idx-- {
+		if err := os.Rename(renames[idx][0], renames[idx][1]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package packager
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/omniviewdev/registry-cli/pkg/types"
+)
+
+const (
+	// ThemePreviewANSI previews the theme colors as swatches in the terminal
+	ThemePreviewANSI = "ansi"
+
+	// ThemePreviewHTML previews the theme colors as an HTML snippet
+	ThemePreviewHTML = "html"
+)
+
+// ThemePreviews are the formats a theme can be previewed in.
+var ThemePreviews = []string{ThemePreviewANSI, ThemePreviewHTML}
+
+// PreviewTheme writes a preview of the theme colors in the format, one of ThemePreviews. The
+// ANSI swatches need a terminal with 24-bit color.
+func PreviewTheme(w io.Writer, theme *types.PluginTheme, format string) error {
+	if err := theme.Validate(); err != nil {
+		return err
+	}
+	var names []string
+	if theme != nil {
+		names = make([]string, 0, len(theme.Colors))
+		for name := range theme.Colors {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+	}
+
+	switch format {
+	case ThemePreviewANSI:
+		for _, name := range names {
+			r, g, b, _ := types.ParseColor(theme.Colors[name])
+			fmt.Fprintf(
+				w,
+				"\x1b[48;2;%d;%d;%dm      \x1b[0m %-10s %s\n",
+				r,
+				g,
+				b,
+				name,
+				theme.Colors[name],
+			)
+		}
+	case ThemePreviewHTML:
+		var sb strings.Builder
+		sb.WriteString(`<div class="plugin-theme" style="display:flex;gap:8px">` + "\n")
+		for _, name := range names {
+			fmt.Fprintf(
+				&sb,
+				`  <div title="%[1]s"><div style="width:48px;height:48px;border-radius:4px;background:%[2]s"></div><code>%[1]s %[2]s</code></div>`+"\n",
+				html.EscapeString(name),
+				theme.Colors[name],
+			)
+		}
+		sb.WriteString("</div>\n")
+		_, err := io.WriteString(w, sb.String())
+		return err
+	default:
+		return fmt.Errorf(
+			"unknown theme preview %q, expected one of %s",
+			format,
+			strings.Join(ThemePreviews, ", "),
+		)
+	}
+	return nil
+}
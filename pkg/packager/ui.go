@@ -0,0 +1,179 @@
+package packager
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+
+	"github.com/omniviewdev/registry-cli/pkg/progress"
+)
+
+// DefaultUIScript is the package.json script building the UI.
+const DefaultUIScript = "build"
+
+// UIConfig configures how the UI of the plugin is built.
+type UIConfig struct {
+	// Targets are platform specific variants of the UI, keyed by platform (e.g. windows_amd64)
+	// or OS (e.g. windows). Platforms without a target get the shared build.
+	Targets map[string]UITarget `yaml:"targets,omitempty"`
+}
+
+// UITarget is a platform specific variant of the UI.
+type UITarget struct {
+	// Script is the package.json script building the variant. Defaults to build.
+	Script string `yaml:"script,omitempty"`
+
+	// Env is passed to the build on top of the build info
+	Env map[string]string `yaml:"env,omitempty"`
+}
+
+// Defaulter fills in the defaults of the target.
+func (t *UITarget) Defaulter() {
+	if t.Script == "" {
+		t.Script = DefaultUIScript
+	}
+}
+
+// uiBuild is a single run of the UI build, shared by the platforms it's packaged into.
+type uiBuild struct {
+	// Name is the target the build is for, empty for the shared build
+	Name      string
+	Target    UITarget
+	Platforms []Platform
+}
+
+// Validate checks the targets match the platforms being packaged.
+func (c *UIConfig) Validate(platforms []Platform) error {
+	if c == nil {
+		return nil
+	}
+	for name := range c.Targets {
+		if !slices.ContainsFunc(platforms, func(p Platform) bool {
+			return p.Key() == name || p.OS == name
+		}) {
+			return fmt.Errorf("ui target %q doesn't match any platform", name)
+		}
+	}
+	return nil
+}
+
+// target returns the name and target building the UI for the platform. A platform target
+// takes precedence over an OS target, and the shared build has an empty name.
+func (c *UIConfig) target(plat Platform) (string, UITarget) {
+	var target UITarget
+	name := ""
+	if c != nil {
+		for _, key := range []string{plat.Key(), plat.OS} {
+			if t, ok := c.Targets[key]; ok {
+				name, target = key, t
+				break
+			}
+		}
+	}
+	target.Defaulter()
+	return name, target
+}
+
+// builds groups the platforms by the UI build they're packaged with, shared build first.
+func (c *UIConfig) builds(platforms []Platform) []uiBuild {
+	byName := make(map[string]*uiBuild)
+	for _, plat := range platforms {
+		name, target := c.target(plat)
+		build, ok := byName[name]
+		if !ok {
+			build = &uiBuild{Name: name, Target: target}
+			byName[name] = build
+		}
+		build.Platforms = append(build.Platforms, plat)
+	}
+
+	builds := make([]uiBuild, 0, len(byName))
+	for _, name := range slices.Sorted(maps.Keys(byName)) {
+		builds = append(builds, *byName[name])
+	}
+	return builds
+}
+
+// buildUI runs every UI build the platforms need, one after the other as they share the
+// dist directory, placing each build's assets into its platform dirs.
+func buildUI(
+	pluginDir string,
+	platforms []Platform,
+	outdir string,
+	env []string,
+	config *UIConfig,
+	log string,
+	tracker progress.Tracker,
+) error {
+	for idx, build := range config.builds(platforms) {
+		task := "ui"
+		if build.Name != "" {
+			task += ":" + build.Name
+		}
+		tracker.Start(task, "building ui")
+
+		err := func() error {
+			if idx > 0 {
+				// the previous build's assets are linked into its packages, so a build
+				// writing over them in place would change those packages too
+				assets := filepath.Join(pluginDir, "ui", "dist", "assets")
+				if err := os.RemoveAll(assets); err != nil {
+					return fmt.Errorf("failed to clear the UI assets: %w", err)
+				}
+			}
+			buildEnv := slices.Clone(env)
+			for _, k := range slices.Sorted(maps.Keys(build.Target.Env)) {
+				buildEnv = append(buildEnv, k+"="+build.Target.Env[k])
+			}
+			return buildUIAndCopy(pluginDir, build, outdir, buildEnv, log)
+		}()
+		if err != nil {
+			tracker.Fail(task, err)
+			return err
+		}
+		tracker.Done(task, "built and distributed ui assets")
+	}
+	return nil
+}
+
+func buildUIAndCopy(pluginDir string, build uiBuild, outdir string, env []string, log string) error {
+	uiPath := filepath.Join(pluginDir, "ui")
+
+	// Run `pnpm run <script>`
+	cmd := exec.Command("pnpm", "run", build.Target.Script)
+	cmd.Dir = uiPath
+	cmd.Env = append(os.Environ(), env...)
+	if err := runLogged(cmd, log); err != nil {
+		return fmt.Errorf("UI build error: %w", err)
+	}
+
+	// Link dist/assets/* into each platform dir. The assets are the same for every platform
+	// of the build, so they're stored once rather than copied over and over.
+	srcAssets := filepath.Join(uiPath, "dist", "assets")
+
+	for _, plat := range build.Platforms {
+		destAssets := filepath.Join(pluginDir, outdir, plat.Key(), "assets")
+		// a previous variant's assets mustn't linger
+		if err := os.RemoveAll(destAssets); err != nil {
+			return fmt.Errorf("failed to clear assets of %s: %w", plat.Key(), err)
+		}
+		err := filepath.Walk(srcAssets, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			rel, _ := filepath.Rel(srcAssets, path)
+			dest := filepath.Join(destAssets, rel)
+			if info.IsDir() {
+				return os.MkdirAll(dest, 0755)
+			}
+			return LinkFile(path, dest)
+		})
+		if err != nil {
+			return fmt.Errorf("failed to copy UI to %s: %w", plat.Key(), err)
+		}
+	}
+	return nil
+}
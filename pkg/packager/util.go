@@ -4,16 +4,49 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/omniviewdev/registry-cli/pkg/types"
 )
 
 type Platform struct {
 	OS, Arch string
 }
 
+// DefaultPlatforms are the platforms plugins are packaged for.
+var DefaultPlatforms = []Platform{
+	{"darwin", "amd64"},
+	{"darwin", "arm64"},
+	{"linux", "amd64"},
+	{"linux", "arm64"},
+	{"windows", "amd64"},
+	{"windows", "arm64"},
+}
+
 func (p Platform) Key() string {
 	return fmt.Sprintf("%s_%s", p.OS, p.Arch)
 }
 
+// ParsePlatforms parses platforms given as os_arch keys (e.g. linux_amd64), with the
+// architecture named either way (e.g. linux-x86_64), each one of DefaultPlatforms.
+func ParsePlatforms(keys []string) ([]Platform, error) {
+	platforms := make([]Platform, 0, len(keys))
+	for _, key := range keys {
+		goos, arch, _ := strings.Cut(types.NormalizePlatform(key), "_")
+		platform := Platform{OS: goos, Arch: arch}
+		if !slices.Contains(DefaultPlatforms, platform) {
+			return nil, fmt.Errorf("unsupported platform %q", key)
+		}
+		if !slices.Contains(platforms, platform) {
+			platforms = append(platforms, platform)
+		}
+	}
+	return platforms, nil
+}
+
 func CopyFile(src, dst string) error {
 	in, err := os.Open(src)
 	if err != nil {
@@ -29,3 +62,62 @@ func CopyFile(src, dst string) error {
 	_, err = io.Copy(out, in)
 	return err
 }
+
+// LinkFile hard links src to dst, so the file is stored on disk once however many packages
+// hold it. It falls back to copying when the two can't be linked, e.g. across filesystems.
+func LinkFile(src, dst string) error {
+	if err := os.Remove(dst); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := os.Link(src, dst); err == nil {
+		return nil
+	}
+	return CopyFile(src, dst)
+}
+
+// GitCommit returns the commit hash checked out in dir, or an empty string if dir is not
+// within a git repository.
+func GitCommit(dir string) string {
+	cmd := exec.Command("git", "rev-parse", "HEAD")
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// FindInRepository returns the path of the closest file with one of the names from dir up to
+// the root of its repository (the directory holding .git), looking in dir alone when it isn't
+// in a repository. Names are tried in order within each directory. It's empty when there's none.
+func FindInRepository(dir string, names ...string) string {
+	root := RepositoryRoot(dir)
+	for {
+		for _, name := range names {
+			path := filepath.Join(dir, name)
+			if _, err := os.Stat(path); err == nil {
+				return path
+			}
+		}
+		parent := filepath.Dir(dir)
+		if root == "" || dir == root || parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// RepositoryRoot returns the closest directory holding .git from dir up, or an empty path when
+// dir isn't in a repository.
+func RepositoryRoot(dir string) string {
+	for {
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
@@ -0,0 +1,147 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/omniviewdev/registry-cli/pkg/encryption"
+	"github.com/omniviewdev/registry-cli/pkg/types"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// PublishVersion uploads the builds of a version and adds it to the indexes. The builds are
+// hashed while they upload, or checked against the checksums packaging left alongside them, so
+// the index update doesn't read them again, and the index lock is only taken once the uploads
+// are done. Nothing is uploaded when a publish hook vetoes any of the builds. A publisher
+// encrypting builds uploads them encrypted, once the hooks have vetted them. For a moderated
+// publisher, the builds are uploaded to the pending area and the release is submitted for review
+// instead of being indexed. When only some of the builds upload, the publisher's partial failure
+// policy decides whether the uploaded builds are deleted, left behind or indexed on their own.
+func PublishVersion(
+	ctx context.Context,
+	publisher *Publisher,
+	indexer *Indexer,
+	opts types.PublishOpts,
+) (err error) {
+	ctx, span := tracer.Start(ctx, "publish", trace.WithAttributes(
+		attribute.String("plugin", opts.Plugin),
+		attribute.String("version", opts.Version),
+		attribute.Bool("moderated", publisher.moderated),
+	))
+	start := time.Now()
+	defer func() {
+		publishDuration.Record(ctx, since(start), metric.WithAttributes(
+			attribute.String("plugin", opts.Plugin),
+			outcome(err),
+		))
+		endSpan(span, err)
+	}()
+
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+	if opts.Created.IsZero() {
+		// both the uploads and the index need the same keys
+		opts.Created = time.Now()
+	}
+	if err := opts.VerifyArtifacts(); err != nil {
+		return err
+	}
+	if err := runHooks(ctx, publisher.hooks, opts); err != nil {
+		return fmt.Errorf("publish vetoed:\n%w", err)
+	}
+	if publisher.encrypter != nil {
+		// the hooks vet the builds as they are, the bucket only gets them encrypted
+		dir, err := encryptBuilds(publisher.encrypter, &opts)
+		defer os.RemoveAll(dir)
+		if err != nil {
+			return err
+		}
+	}
+	if !publisher.layout.Equal(indexer.layout) {
+		return errors.New("the publisher and the indexer don't use the same layout")
+	}
+	if err := indexer.checkLayout(ctx); err != nil {
+		return err
+	}
+	artifacts, err := publisher.Publish(ctx, opts)
+	var partial *PartialPublishError
+	if err != nil {
+		switch {
+		case len(artifacts) == 0:
+			return err
+		case publisher.onPartialFailure == PartialFailureAtomic:
+			cleanup, cancel := context.WithTimeout(context.WithoutCancel(ctx), time.Minute)
+			defer cancel()
+			if rerr := publisher.removeUploads(cleanup, opts.ToReleases(), artifacts); rerr != nil {
+				return fmt.Errorf("%w\ncouldn't remove the builds that were uploaded:\n%w", err, rerr)
+			}
+			return fmt.Errorf("%w\nremoved the builds that were uploaded", err)
+		case publisher.onPartialFailure == PartialFailureBestEffort:
+			partial = &PartialPublishError{Err: err}
+			for _, release := range opts.ToReleases() {
+				if _, ok := artifacts[release.OSArch()]; !ok {
+					partial.Failed = append(partial.Failed, release.OSArch())
+					opts.SetPlatform(release.OSArch(), "")
+				}
+			}
+		default:
+			return err
+		}
+	}
+
+	opts.Artifacts = artifacts
+	if publisher.moderated {
+		err = indexer.SubmitForReview(ctx, opts)
+	} else {
+		err = indexer.UpdateIndex(ctx, opts)
+	}
+	if err != nil {
+		return err
+	}
+	if partial != nil {
+		return partial
+	}
+	return nil
+}
+
+// encryptBuilds encrypts the builds of a publish into a temporary directory, pointing the publish
+// at the encrypted builds, hashed for the index. The caller is responsible for removing the
+// directory.
+func encryptBuilds(encrypter *encryption.Encrypter, opts *types.PublishOpts) (string, error) {
+	dir, err := os.MkdirTemp("", "registry-encrypted-*")
+	if err != nil {
+		return "", fmt.Errorf("couldn't create temporary directory: %w", err)
+	}
+	artifacts := make(map[string]types.Artifact)
+	for _, release := range opts.ToReleases() {
+		path := filepath.Join(dir, release.OSArch()+".tar.gz")
+		if err := encrypter.EncryptFile(release.Path, path); err != nil {
+			return dir, err
+		}
+		checksum, size, err := hashFile(path)
+		if err != nil {
+			return dir, err
+		}
+		artifacts[release.OSArch()] = types.Artifact{
+			Checksum:   checksum,
+			Size:       size,
+			Encryption: encryption.Format,
+		}
+		if opts.Report != nil {
+			// the report points at the build packaged, not the encrypted copy removed after
+			if platReport := opts.Report.Platform(release.OSArch()); platReport.Artifact == "" {
+				platReport.Artifact = release.Path
+			}
+		}
+		opts.SetPlatform(release.OSArch(), path)
+	}
+	opts.Artifacts = artifacts
+	return dir, nil
+}
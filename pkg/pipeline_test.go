@@ -0,0 +1,88 @@
+package pkg
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/omniviewdev/registry-cli/pkg/encryption"
+	"github.com/omniviewdev/registry-cli/pkg/types"
+)
+
+func TestEncryptBuildsIndex(t *testing.T) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatal(err)
+	}
+	keyFile := filepath.Join(t.TempDir(), "shared.key")
+	if err := os.WriteFile(keyFile, []byte(hex.EncodeToString(key)), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	build := []byte("plugin build")
+	buildPath := filepath.Join(t.TempDir(), "linux_amd64.tar.gz")
+	if err := os.WriteFile(buildPath, build, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	encrypter, err := encryption.NewEncrypter(t.Context(), []string{keyFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := types.PublishOpts{Plugin: "demo", Version: "1.0.0", LinuxAMD64: buildPath}
+	dir, err := encryptBuilds(encrypter, &opts)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opts.LinuxAMD64 == buildPath {
+		t.Fatal("the encrypted copy isn't the build published")
+	}
+
+	var index types.PluginIndex
+	index.ID = "demo"
+	i := &Indexer{}
+	index, err = i.updateIndex(
+		index,
+		opts.ToReleases(),
+		types.PluginMeta{ID: "demo", Name: "demo", Version: "1.0.0"},
+		nil,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info := index.Versions[0].Architectures["linux_amd64"]
+	if info.Encryption != encryption.Format {
+		t.Fatalf("recorded encryption %q, want %q", info.Encryption, encryption.Format)
+	}
+	checksum, size, err := hashFile(opts.LinuxAMD64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Checksum != checksum || info.Size != size {
+		t.Fatalf(
+			"recorded %s (%d bytes), the encrypted build is %s (%d bytes)",
+			info.Checksum,
+			info.Size,
+			checksum,
+			size,
+		)
+	}
+
+	decrypter, err := encryption.NewDecrypter(t.Context(), []string{keyFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+	decrypted := filepath.Join(t.TempDir(), "plugin.tar.gz")
+	if err := decrypter.DecryptFile(opts.LinuxAMD64, decrypted); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(decrypted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != string(build) {
+		t.Fatalf("decrypted %q, want %q", b, build)
+	}
+}
@@ -0,0 +1,180 @@
+package progress
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	refreshInterval = 100 * time.Millisecond
+
+	// ANSI escapes moving the cursor up and clearing to the end of the screen
+	cursorUp    = "\x1b[%dA"
+	clearScreen = "\x1b[J"
+)
+
+var spinner = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+
+type taskState int
+
+const (
+	running taskState = iota
+	done
+	failed
+)
+
+type task struct {
+	name   string
+	status string
+	state  taskState
+	start  time.Time
+	end    time.Time
+}
+
+// live redraws the list of tasks in place, with a spinner and the elapsed time of each.
+type live struct {
+	mu    sync.Mutex
+	w     io.Writer
+	width int
+	tasks []*task
+	drawn int
+	frame int
+
+	stop    chan struct{}
+	stopped chan struct{}
+	once    sync.Once
+}
+
+func newLive(w io.Writer, width int) *live {
+	l := &live{
+		w:       w,
+		width:   width,
+		stop:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	go l.run()
+	return l
+}
+
+func (l *live) run() {
+	defer close(l.stopped)
+	ticker := time.NewTicker(refreshInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-ticker.C:
+			l.mu.Lock()
+			l.frame++
+			l.render()
+			l.mu.Unlock()
+		}
+	}
+}
+
+// task returns the task by name, adding it if it's new
+func (l *live) task(name string) *task {
+	for _, t := range l.tasks {
+		if t.name == name {
+			return t
+		}
+	}
+	t := &task{name: name, start: time.Now()}
+	l.tasks = append(l.tasks, t)
+	return t
+}
+
+func (l *live) Start(name, status string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	t := l.task(name)
+	t.status, t.state = status, running
+	l.render()
+}
+
+func (l *live) Done(name, status string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	t := l.task(name)
+	t.status, t.state, t.end = status, done, time.Now()
+	l.render()
+}
+
+func (l *live) Fail(name string, err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	t := l.task(name)
+	// only the first line, the rest is in the logs
+	t.status, _, _ = strings.Cut(fmt.Sprintf("failed: %v", err), "\n")
+	t.state, t.end = failed, time.Now()
+	l.render()
+}
+
+func (l *live) Logf(format string, args ...any) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	// print above the tasks, which are redrawn below
+	l.clear()
+	fmt.Fprintf(l.w, format+"\n", args...)
+	l.render()
+}
+
+func (l *live) Stop() {
+	l.once.Do(func() {
+		close(l.stop)
+		<-l.stopped
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		l.render()
+	})
+}
+
+// clear moves the cursor back over the drawn tasks, clearing them
+func (l *live) clear() {
+	if l.drawn > 0 {
+		fmt.Fprintf(l.w, cursorUp, l.drawn)
+	}
+	fmt.Fprint(l.w, clearScreen)
+	l.drawn = 0
+}
+
+func (l *live) render() {
+	width := 0
+	for _, t := range l.tasks {
+		width = max(width, len(t.name))
+	}
+
+	var b strings.Builder
+	for _, t := range l.tasks {
+		var icon string
+		end := time.Now()
+		switch t.state {
+		case running:
+			icon = spinner[l.frame%len(spinner)]
+		case done:
+			icon, end = "✓", t.end
+		case failed:
+			icon, end = "✗", t.end
+		}
+		line := fmt.Sprintf(
+			"%s %-*s  %-6s %s",
+			icon,
+			width,
+			t.name,
+			formatElapsed(end.Sub(t.start)),
+			t.status,
+		)
+		// a wrapped line would throw off redrawing
+		if runes := []rune(line); len(runes) >= l.width {
+			line = string(runes[:max(l.width-1, 0)])
+		}
+		b.WriteString(line + "\n")
+	}
+	l.clear()
+	fmt.Fprint(l.w, b.String())
+	l.drawn = len(l.tasks)
+}
@@ -0,0 +1,99 @@
+// Package progress reports the status of long running tasks, like the platform builds and
+// uploads, either live on a terminal or as plain lines for CI logs.
+package progress
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/omniviewdev/registry-cli/pkg/console"
+	"golang.org/x/term"
+)
+
+// Tracker reports the status of tasks running concurrently.
+type Tracker interface {
+	// Start marks the task as running, with the status describing what it's doing
+	Start(task, status string)
+
+	// Done marks the task as finished, with the status describing the outcome
+	Done(task, status string)
+
+	// Fail marks the task as failed
+	Fail(task string, err error)
+
+	// Logf prints a message that isn't about a single task
+	Logf(format string, args ...any)
+
+	// Stop stops reporting, leaving the final state of the tasks behind
+	Stop()
+}
+
+// New returns a tracker rendering live to a terminal, or one printing plain lines when out
+// isn't a styled terminal or we're running in CI.
+func New(out *console.Writer) Tracker {
+	if out.Styled() && out.IsTerminal() && os.Getenv("CI") == "" {
+		width, _, err := term.GetSize(int(out.File().Fd()))
+		if err != nil || width <= 0 {
+			width = 80
+		}
+		// the live rendering is already styled
+		return newLive(out.File(), width)
+	}
+	return Plain(out)
+}
+
+// Plain returns a tracker printing a line for every change of the tasks.
+func Plain(w io.Writer) Tracker {
+	return &plain{w: w, started: make(map[string]time.Time)}
+}
+
+type plain struct {
+	mu      sync.Mutex
+	w       io.Writer
+	started map[string]time.Time
+}
+
+func (p *plain) Start(task, status string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, ok := p.started[task]; !ok {
+		p.started[task] = time.Now()
+	}
+	fmt.Fprintf(p.w, "[%s] %s...\n", task, status)
+}
+
+func (p *plain) Done(task, status string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	fmt.Fprintf(p.w, "[%s] %s%s\n", task, status, p.elapsed(task))
+}
+
+func (p *plain) Fail(task string, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	fmt.Fprintf(p.w, "[%s] failed%s: %v\n", task, p.elapsed(task), err)
+}
+
+func (p *plain) Logf(format string, args ...any) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	fmt.Fprintf(p.w, format+"\n", args...)
+}
+
+func (p *plain) Stop() {}
+
+// elapsed formats the time since the task started, if it did
+func (p *plain) elapsed(task string) string {
+	start, ok := p.started[task]
+	if !ok {
+		return ""
+	}
+	return " in " + formatElapsed(time.Since(start))
+}
+
+func formatElapsed(d time.Duration) string {
+	return fmt.Sprintf("%.1fs", d.Seconds())
+}
@@ -4,29 +4,131 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io/fs"
 	"os"
+	"slices"
+	"strings"
+	"sync"
 	"time"
 
-	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/config"
-	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
 	"github.com/aws/smithy-go"
+	"github.com/omniviewdev/registry-cli/pkg/console"
+	"github.com/omniviewdev/registry-cli/pkg/encryption"
+	"github.com/omniviewdev/registry-cli/pkg/progress"
 	"github.com/omniviewdev/registry-cli/pkg/types"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
 )
 
-// Publisher is responsible for publishing a new version of a plugin to a registry. Currently,
-// registries must be an aws S3 object store.
+// Publisher is responsible for publishing a new version of a plugin to a registry, kept in the
+// ObjectStore of the bucket of its provider.
 type Publisher struct {
-	ctx      context.Context
-	s3Client *s3.Client
-	bucket   string
+	ctx                    context.Context
+	objects                ObjectStore
+	bucket                 string
+	storageClass           s3types.StorageClass
+	prereleaseStorageClass s3types.StorageClass
+	hooks                  []PublishHook
+	moderated              bool
+	partSize               int64
+	concurrency            int
+	onPartialFailure       PartialFailurePolicy
+	encrypter              *encryption.Encrypter
+	layout                 types.Layout
 }
 
 type PublisherOpts struct {
 	Bucket  string
 	Version string
+
+	// Provider hosts the bucket, one of the Provider constants. Defaults to ProviderS3.
+	Provider string
+
+	// Endpoint is where the S3 compatible store hosting the bucket is, when it's hosted in one
+	// rather than S3
+	Endpoint Endpoint
+
+	// Pacing limits the requests made to the bucket. They aren't limited when zero.
+	Pacing Pacing
+
+	// StorageClass is the S3 storage class to upload artifacts with. Uses the bucket default
+	// when empty.
+	StorageClass string
+
+	// PrereleaseStorageClass is the S3 storage class to upload prerelease artifacts with.
+	// Uses StorageClass when empty.
+	PrereleaseStorageClass string
+
+	// Hooks vet every tarball of a publish before any is uploaded, any of them can veto it
+	Hooks []PublishHook
+
+	// Moderated uploads the builds into the pending area instead, leaving the release to be
+	// approved by a registry admin before it's added to the indexes (or REGISTRY_MODERATED)
+	Moderated bool
+
+	// PartSize uploads builds larger than it in parts of that many bytes, uploaded
+	// concurrently. Builds are uploaded in a single request when zero. See 'registry-cli bench
+	// upload' to find the part size the bucket is fastest with.
+	PartSize int64
+
+	// Concurrency is how many parts of a build are uploaded at once. Defaults to
+	// DefaultUploadConcurrency.
+	Concurrency int
+
+	// OnPartialFailure is what a publish does when the builds of some platforms fail to upload
+	// while others succeed. Defaults to PartialFailureAbort.
+	OnPartialFailure PartialFailurePolicy
+
+	// EncryptTo encrypts the builds to the recipients before they're uploaded, for private
+	// registries: age public keys, AWS KMS keys (kms:<key>) or files holding a shared key or age
+	// public keys. See the encryption package. Builds are uploaded as they are when empty.
+	EncryptTo []string
+
+	// Layout is the object key layout of the registry's bucket, which must be the one of the
+	// indexer. Defaults to the default layout.
+	Layout types.Layout
+}
+
+// PartialFailurePolicy is what a publish does when some of its builds fail to upload.
+type PartialFailurePolicy string
+
+const (
+	// PartialFailureAbort fails the publish, leaving the builds that were uploaded in the bucket
+	// for a retry to overwrite
+	PartialFailureAbort PartialFailurePolicy = ""
+
+	// PartialFailureAtomic fails the publish, deleting the builds that were uploaded so the
+	// bucket is left as it was
+	PartialFailureAtomic PartialFailurePolicy = "atomic"
+
+	// PartialFailureBestEffort indexes the builds that were uploaded, leaving out the platforms
+	// that failed
+	PartialFailureBestEffort PartialFailurePolicy = "best-effort"
+)
+
+// PartialPublishError is returned when a best effort publish indexed the builds of only some
+// of the platforms.
+type PartialPublishError struct {
+	// Failed are the platforms left out of the release, e.g. linux_amd64
+	Failed []string
+
+	Err error
+}
+
+func (e *PartialPublishError) Error() string {
+	return fmt.Sprintf("published without %s: %v", strings.Join(e.Failed, ", "), e.Err)
 }
 
+func (e *PartialPublishError) Unwrap() error {
+	return e.Err
+}
+
+// DefaultUploadConcurrency is how many parts of a build are uploaded at once by default.
+const DefaultUploadConcurrency = manager.DefaultUploadConcurrency
+
 func (p *PublisherOpts) Defaulter() {
 	if p == nil {
 		p = &PublisherOpts{}
@@ -35,41 +137,160 @@ func (p *PublisherOpts) Defaulter() {
 	if p.Bucket == "" {
 		p.Bucket = os.Getenv("AWS_S3_BUCKET")
 	}
+	p.Endpoint.Defaulter()
+	if !p.Moderated {
+		p.Moderated = os.Getenv("REGISTRY_MODERATED") == "true"
+	}
 }
 
 // NewPublisher published a new release to the registry
 func NewPublisher(ctx context.Context, opts PublisherOpts) (*Publisher, error) {
-	sdkConfig, err := config.LoadDefaultConfig(ctx)
+	opts.Defaulter()
+
+	objects, err := newObjectStore(ctx, storeOpts{
+		bucket:   opts.Bucket,
+		provider: opts.Provider,
+		endpoint: opts.Endpoint,
+		pacing:   opts.Pacing,
+	})
 	if err != nil {
-		return nil, errors.New(
-			"couldn't load default configuration, have you set up your AWS account?",
-		)
+		return nil, err
 	}
-	s3Client := s3.NewFromConfig(sdkConfig)
 
-	opts.Defaulter()
+	storageClass, err := ParseStorageClass(opts.StorageClass)
+	if err != nil {
+		return nil, err
+	}
+	prereleaseStorageClass, err := ParseStorageClass(opts.PrereleaseStorageClass)
+	if err != nil {
+		return nil, err
+	}
+	if prereleaseStorageClass == "" {
+		prereleaseStorageClass = storageClass
+	}
+	if opts.PartSize != 0 && opts.PartSize < manager.MinUploadPartSize {
+		return nil, fmt.Errorf(
+			"the part size can't be under %d bytes, the smallest part S3 accepts",
+			manager.MinUploadPartSize,
+		)
+	}
+	if opts.Concurrency < 0 {
+		return nil, errors.New("the upload concurrency can't be negative")
+	}
+	if opts.Concurrency == 0 {
+		opts.Concurrency = DefaultUploadConcurrency
+	}
+	switch opts.OnPartialFailure {
+	case PartialFailureAbort, PartialFailureAtomic, PartialFailureBestEffort:
+	default:
+		return nil, fmt.Errorf("unknown partial failure policy %q", opts.OnPartialFailure)
+	}
+	var encrypter *encryption.Encrypter
+	if len(opts.EncryptTo) > 0 {
+		if encrypter, err = encryption.NewEncrypter(ctx, opts.EncryptTo); err != nil {
+			return nil, err
+		}
+	}
+	layout, err := types.ParseLayout(opts.Layout)
+	if err != nil {
+		return nil, err
+	}
 
 	return &Publisher{
-		ctx:      ctx,
-		s3Client: s3Client,
-		bucket:   opts.Bucket,
+		ctx:                    ctx,
+		objects:                objects,
+		bucket:                 opts.Bucket,
+		storageClass:           storageClass,
+		prereleaseStorageClass: prereleaseStorageClass,
+		hooks:                  opts.Hooks,
+		moderated:              opts.Moderated,
+		partSize:               opts.PartSize,
+		concurrency:            opts.Concurrency,
+		onPartialFailure:       opts.OnPartialFailure,
+		encrypter:              encrypter,
+		layout:                 layout,
 	}, nil
 }
 
+// Moderated reports whether publishes are submitted for review rather than indexed.
+func (p *Publisher) Moderated() bool {
+	return p.moderated
+}
+
+// ParseStorageClass parses an S3 storage class name (e.g. STANDARD_IA, GLACIER_IR), returning
+// an empty class for an empty name.
+func ParseStorageClass(name string) (s3types.StorageClass, error) {
+	if name == "" {
+		return "", nil
+	}
+	class := s3types.StorageClass(strings.ToUpper(name))
+	if !slices.Contains(class.Values(), class) {
+		return "", fmt.Errorf("unknown storage class %q", name)
+	}
+	return class, nil
+}
+
 // Publish runs a publish of the plugin with the opts given. Used for publishing a version
-// with all builds of the plugin in one command.
-func (p *Publisher) Publish(ctx context.Context, opts types.PublishOpts) error {
+// with all builds of the plugin in one command. The builds are uploaded concurrently and
+// hashed as they're uploaded (when their checksums aren't already in the opts), returning
+// their checksums and sizes by platform.
+func (p *Publisher) Publish(
+	ctx context.Context,
+	opts types.PublishOpts,
+) (map[string]types.Artifact, error) {
+	tracker := progress.New(console.Stdout)
+	defer tracker.Stop()
+
+	type result struct {
+		path     string
+		artifact types.Artifact
+		err      error
+	}
 	releases := opts.ToReleases()
-	for _, release := range releases {
-		releasePath, err := p.Upload(ctx, release)
-		if err != nil {
-			return err
-		}
+	results := make([]result, len(releases))
 
-		fmt.Printf("uploaded release %s: %s\n", release, releasePath)
+	var wg sync.WaitGroup
+	for idx, release := range releases {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			tracker.Start(release.OSArch(), "uploading to "+p.key(release))
+			path, artifact, err := p.upload(ctx, release)
+			if err != nil {
+				tracker.Fail(release.OSArch(), err)
+			} else {
+				tracker.Done(release.OSArch(), "uploaded to "+path)
+			}
+			results[idx] = result{path: path, artifact: artifact, err: err}
+		}()
 	}
+	wg.Wait()
 
-	return nil
+	artifacts := make(map[string]types.Artifact, len(releases))
+	var errs []error
+	for idx, release := range releases {
+		result := results[idx]
+		var platReport *types.PlatformReport
+		if opts.Report != nil {
+			platReport = opts.Report.Platform(release.OSArch())
+		}
+		if result.err != nil {
+			errs = append(errs, result.err)
+			if platReport != nil {
+				platReport.Error = result.err.Error()
+			}
+			continue
+		}
+
+		artifacts[release.OSArch()] = result.artifact
+		if platReport != nil {
+			platReport.UploadedURL = p.objects.Location(result.path)
+			if platReport.Artifact == "" {
+				platReport.Artifact = release.Path
+			}
+		}
+	}
+	return artifacts, errors.Join(errs...)
 }
 
 // Upload uploads the release to the location given the opts
@@ -77,41 +298,180 @@ func (p *Publisher) Upload(
 	ctx context.Context,
 	release types.Release,
 ) (string, error) {
+	console.Printf("uploading release to %s...\n", p.key(release))
+	path, _, err := p.upload(ctx, release)
+	return path, err
+}
+
+// removeUploads deletes the builds of the releases a failed publish uploaded, along with their
+// checksum files.
+func (p *Publisher) removeUploads(
+	ctx context.Context,
+	releases []types.Release,
+	artifacts map[string]types.Artifact,
+) error {
+	var errs []error
+	for _, release := range releases {
+		if _, ok := artifacts[release.OSArch()]; !ok {
+			// failed to upload, whatever is at its key isn't from this publish
+			continue
+		}
+		key := p.key(release)
+		for _, k := range []string{key, key + types.ChecksumExt} {
+			console.Printf("deleting %s...\n", k)
+			if err := p.objects.Delete(ctx, k); err != nil {
+				errs = append(errs, fmt.Errorf("couldn't delete %v:%v: %v", p.bucket, k, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// key returns the bucket path the release is uploaded to, in the pending area for a moderated
+// registry.
+func (p *Publisher) key(release types.Release) string {
+	if p.moderated {
+		return types.PendingArtifactPath(p.layout.ArtifactPath(release))
+	}
+	return p.layout.ArtifactPath(release)
+}
+
+// upload uploads the release, returning its bucket path along with the checksum and size of
+// the tarball, hashed as it's read for the upload.
+func (p *Publisher) upload(
+	ctx context.Context,
+	release types.Release,
+) (_ string, _ types.Artifact, err error) {
+	ctx, span := tracer.Start(ctx, "upload", trace.WithAttributes(
+		attribute.String("plugin", release.Plugin),
+		attribute.String("version", release.Version),
+		attribute.String("platform", release.OSArch()),
+	))
+	defer func() { endSpan(span, err) }()
+
 	file, err := os.Open(release.Path)
 	if err != nil {
-		return "", fmt.Errorf("couldn't open file %v to upload: %v", release.Path, err)
+		return "", types.Artifact{}, fmt.Errorf(
+			"couldn't open file %v to upload: %v",
+			release.Path,
+			err,
+		)
 	}
+	defer file.Close()
+	key := p.key(release)
 
-	fmt.Printf("uploading release to %s...\n", release.BucketPath())
+	// hashed as it uploads even when the checksum is known from packaging: S3 doesn't check it
+	// for objects uploaded in parts, nor GCS for any, and the tarball may have changed since
+	hashing := newHashingReader(file)
 
-	defer file.Close()
-	_, err = p.s3Client.PutObject(ctx, &s3.PutObjectInput{
-		Bucket: aws.String(p.bucket),
-		Key:    aws.String(release.BucketPath()),
-		Body:   file,
-	})
+	info, err := file.Stat()
+	if err != nil {
+		return "", types.Artifact{}, fmt.Errorf("couldn't stat file %v to upload: %v", release.Path, err)
+	}
+
+	// the store checks the upload against the checksum, or computes it when it isn't known
+	uploadOpts := UploadOptions{
+		ContentType:  "application/gzip",
+		StorageClass: p.storageClass,
+		Checksum:     release.Artifact.Checksum,
+		PartSize:     p.partSize,
+		Concurrency:  p.concurrency,
+	}
+	if types.IsPrerelease(release.Version) {
+		uploadOpts.StorageClass = p.prereleaseStorageClass
+	}
+	start := time.Now()
+	checksum, err := p.objects.Upload(ctx, key, hashing, info.Size(), uploadOpts)
 	if err != nil {
 		var apiErr smithy.APIError
 		if errors.As(err, &apiErr) && apiErr.ErrorCode() == "EntityTooLarge" {
-			return "", fmt.Errorf(
+			return "", types.Artifact{}, fmt.Errorf(
 				"error while uploading object to %s: the object is too large",
 				p.bucket,
 			)
 		}
 
-		return "", fmt.Errorf(
+		return "", types.Artifact{}, fmt.Errorf(
 			"couldn't upload file %v to %v:%v: %v",
 			release.Path,
 			p.bucket,
-			release.BucketPath(),
+			key,
 			err,
 		)
 	}
-	err = s3.NewObjectExistsWaiter(p.s3Client).Wait(
-		ctx, &s3.HeadObjectInput{Bucket: aws.String(p.bucket), Key: aws.String(release.BucketPath())}, time.Minute)
+	// measured before waiting for the object, which isn't part of the transfer
+	platform := metric.WithAttributes(attribute.String("platform", release.OSArch()))
+	if elapsed := since(start); elapsed > 0 {
+		uploadThroughput.Record(ctx, float64(info.Size())/elapsed, platform)
+	}
+	uploadSize.Add(ctx, info.Size(), platform)
+	span.SetAttributes(attribute.Int64("size", info.Size()))
+
+	if err := waitForObject(ctx, p.objects, key); err != nil {
+		return "", types.Artifact{}, fmt.Errorf("failed attempt to wait for object %s to exist", key)
+	}
+
+	hashed, err := hashing.artifact()
 	if err != nil {
-		return "", fmt.Errorf("failed attempt to wait for object %s to exist", release.BucketPath())
+		return "", types.Artifact{}, fmt.Errorf("couldn't hash file %v: %v", release.Path, err)
+	}
+	if err := verifyUpload(key, release.Artifact.Checksum, checksum, hashed.Checksum); err != nil {
+		if deleteErr := p.objects.Delete(ctx, key); deleteErr != nil {
+			err = fmt.Errorf("%w (couldn't delete %v:%v: %v)", err, p.bucket, key, deleteErr)
+		}
+		return "", types.Artifact{}, err
 	}
+	artifact := release.Artifact
+	artifact.Checksum, artifact.Size = hashed.Checksum, hashed.Size
 
-	return release.BucketPath(), nil
+	if err := p.uploadChecksum(ctx, release, key, artifact); err != nil {
+		return "", types.Artifact{}, err
+	}
+	return key, artifact, nil
+}
+
+// verifyUpload checks the checksum hashed of the tarball uploaded to key against the one the
+// store computed, when it computed one, and the one recorded when it was packaged, if any.
+func verifyUpload(key, recorded, stored, hashed string) error {
+	if stored != "" && !strings.EqualFold(stored, hashed) {
+		return fmt.Errorf("checksum mismatch for %v: uploaded %s, hashed %s", key, stored, hashed)
+	}
+	if recorded != "" && !strings.EqualFold(recorded, hashed) {
+		return fmt.Errorf(
+			"checksum mismatch for %v: packaged %s, uploaded %s, was the tarball changed since?",
+			key,
+			recorded,
+			hashed,
+		)
+	}
+	return nil
+}
+
+// uploadChecksum uploads the sha256 checksum file of the release next to the uploaded tarball,
+// using the one packaging wrote alongside the tarball when there's one.
+func (p *Publisher) uploadChecksum(
+	ctx context.Context,
+	release types.Release,
+	key string,
+	artifact types.Artifact,
+) error {
+	b, err := os.ReadFile(release.Path + types.ChecksumExt)
+	if errors.Is(err, fs.ErrNotExist) {
+		b, err = []byte(artifact.Checksum), nil
+	}
+	if err != nil {
+		return fmt.Errorf("couldn't read checksum file of %v: %v", release.Path, err)
+	}
+
+	err = p.objects.Put(ctx, key+types.ChecksumExt, b, PutOptions{ContentType: "text/plain"})
+	if err != nil {
+		return fmt.Errorf(
+			"couldn't upload checksum file %v to %v:%v: %v",
+			release.Path+types.ChecksumExt,
+			p.bucket,
+			key+types.ChecksumExt,
+			err,
+		)
+	}
+	return nil
 }
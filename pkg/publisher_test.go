@@ -0,0 +1,289 @@
+package pkg
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/omniviewdev/registry-cli/pkg/types"
+)
+
+// testPublish writes the metadata and the linux/amd64 and darwin/arm64 builds of a version of
+// the demo plugin, returning the opts publishing them.
+func testPublish(t *testing.T, version string) types.PublishOpts {
+	t.Helper()
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	return types.PublishOpts{
+		Plugin:       "demo",
+		Version:      version,
+		MetadataPath: write("plugin.yaml", "id: demo\nname: Demo\nversion: "+version+"\n"),
+		LinuxAMD64:   write("linux_amd64.tar.gz", "linux build "+version),
+		DarwinARM64:  write("darwin_arm64.tar.gz", "darwin build "+version),
+		Created:      time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC),
+	}
+}
+
+// testRegistry returns a publisher and an indexer of a registry kept in the store.
+func testRegistry(objects ObjectStore, policy PartialFailurePolicy) (*Publisher, *Indexer) {
+	publisher := &Publisher{
+		objects:          objects,
+		bucket:           "test",
+		concurrency:      DefaultUploadConcurrency,
+		onPartialFailure: policy,
+	}
+	indexer := &Indexer{
+		objects:     objects,
+		bucket:      "test",
+		lock:        &objectLock{objects: objects, ttl: DefaultLockTTL},
+		lockTimeout: DefaultLockTimeout,
+	}
+	return publisher, indexer
+}
+
+// pluginIndex reads the index of the demo plugin from the store.
+func pluginIndex(t *testing.T, objects ObjectStore) types.PluginIndex {
+	t.Helper()
+	var index types.PluginIndex
+	if err := json.Unmarshal(read(t, objects, types.Layout{}.IndexPath("demo")), &index); err != nil {
+		t.Fatal(err)
+	}
+	return index
+}
+
+func TestPublishVersion(t *testing.T) {
+	stores := map[string]func(t *testing.T) ObjectStore{
+		"memory": func(t *testing.T) ObjectStore { return newMemStore() },
+		"file":   func(t *testing.T) ObjectStore { return testFileStore(t) },
+	}
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			testPublishVersion(t, store(t))
+		})
+	}
+}
+
+// testPublishVersion publishes a version to the store, checking the builds, checksums and
+// indexes it's left with.
+func testPublishVersion(t *testing.T, objects ObjectStore) {
+	publisher, indexer := testRegistry(objects, PartialFailureAbort)
+	opts := testPublish(t, "1.0.0")
+	opts.Report = &types.PublishReport{}
+	if err := PublishVersion(t.Context(), publisher, indexer, opts); err != nil {
+		t.Fatal(err)
+	}
+
+	index := pluginIndex(t, objects)
+	if index.LatestVersion.Version != "1.0.0" || len(index.Versions) != 1 {
+		t.Fatalf("indexed %+v, want 1.0.0", index.Versions)
+	}
+	for _, release := range opts.ToReleases() {
+		info, ok := index.Versions[0].Architectures[release.OSArch()]
+		if !ok {
+			t.Fatalf("%s wasn't indexed", release.OSArch())
+		}
+		build, err := os.ReadFile(release.Path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sum := sha256.Sum256(build)
+		checksum := hex.EncodeToString(sum[:])
+		if info.Checksum != checksum || info.Size != int64(len(build)) {
+			t.Fatalf(
+				"indexed %s (%d bytes) for %s, want %s (%d bytes)",
+				info.Checksum,
+				info.Size,
+				release.OSArch(),
+				checksum,
+				len(build),
+			)
+		}
+
+		key := types.Layout{}.ArtifactPath(release)
+		reported := opts.Report.Platforms[release.OSArch()].UploadedURL
+		if want := objects.Location(key); reported != want {
+			t.Fatalf("reported %s uploaded to %s, want %s", release.OSArch(), reported, want)
+		}
+		if got := read(t, objects, key); string(got) != string(build) {
+			t.Fatalf("uploaded %q to %s, want %q", got, key, build)
+		}
+		if got := read(t, objects, key+types.ChecksumExt); string(got) != checksum {
+			t.Fatalf("uploaded checksum %q for %s, want %q", got, key, checksum)
+		}
+	}
+
+	var registry types.RegistryIndex
+	if err := json.Unmarshal(read(t, objects, "index.json"), &registry); err != nil {
+		t.Fatal(err)
+	}
+	if len(registry.Plugins) != 1 || registry.Plugins[0].LatestVersion.Version != "1.0.0" {
+		t.Fatalf("registry index has %+v, want demo@1.0.0", registry.Plugins)
+	}
+	read(t, objects, types.LatestVersionPath("demo"))
+	if _, err := objects.Head(t.Context(), lockKey); err == nil {
+		t.Fatal("the index lock wasn't released")
+	}
+}
+
+func TestPublishVersionPartialFailure(t *testing.T) {
+	failDarwin := func(key string) error {
+		if strings.Contains(key, "darwin") {
+			return errors.New("upload failed")
+		}
+		return nil
+	}
+
+	tests := []struct {
+		name        string
+		policy      PartialFailurePolicy
+		wantBuilds  []string
+		wantIndexed []string
+	}{
+		{name: "abort", policy: PartialFailureAbort, wantBuilds: []string{"linux_amd64"}},
+		{name: "atomic", policy: PartialFailureAtomic},
+		{
+			name:        "best effort",
+			policy:      PartialFailureBestEffort,
+			wantBuilds:  []string{"linux_amd64"},
+			wantIndexed: []string{"linux_amd64"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objects := newMemStore()
+			publisher, indexer := testRegistry(objects, tt.policy)
+			objects.failUpload = failDarwin
+			opts := testPublish(t, "1.0.0")
+			err := PublishVersion(t.Context(), publisher, indexer, opts)
+			if err == nil {
+				t.Fatal("expected the failed upload to fail the publish")
+			}
+			var partial *PartialPublishError
+			if isPartial := errors.As(err, &partial); isPartial != (tt.wantIndexed != nil) {
+				t.Fatalf("got %v, want a partial publish: %v", err, tt.wantIndexed != nil)
+			}
+
+			var builds []string
+			for _, release := range opts.ToReleases() {
+				_, err := objects.Head(t.Context(), types.Layout{}.ArtifactPath(release))
+				if err == nil {
+					builds = append(builds, release.OSArch())
+				}
+			}
+			if !slices.Equal(builds, tt.wantBuilds) {
+				t.Fatalf("left builds %v in the bucket, want %v", builds, tt.wantBuilds)
+			}
+
+			var indexed []string
+			if _, err := objects.Head(t.Context(), types.Layout{}.IndexPath("demo")); err == nil {
+				for arch := range pluginIndex(t, objects).Versions[0].Architectures {
+					indexed = append(indexed, arch)
+				}
+			}
+			if !slices.Equal(indexed, tt.wantIndexed) {
+				t.Fatalf("indexed %v, want %v", indexed, tt.wantIndexed)
+			}
+		})
+	}
+}
+
+func TestPublishChecksumMismatch(t *testing.T) {
+	objects := newMemStore()
+	publisher, indexer := testRegistry(objects, PartialFailureAbort)
+	opts := testPublish(t, "1.0.0")
+	// packaging left a checksum the build doesn't hash to
+	if err := os.WriteFile(opts.LinuxAMD64+types.ChecksumExt, []byte(strings.Repeat("0", 64)),
+		0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := PublishVersion(t.Context(), publisher, indexer, opts); err == nil {
+		t.Fatal("expected the checksum mismatch to fail the publish")
+	}
+	if keys := objects.keys(); len(keys) != 0 {
+		t.Fatalf("uploaded %v despite the mismatch", keys)
+	}
+}
+
+func TestPublishChangedSincePackaging(t *testing.T) {
+	for _, unchecked := range []bool{false, true} {
+		t.Run(fmt.Sprintf("unchecked uploads %v", unchecked), func(t *testing.T) {
+			objects := newMemStore()
+			objects.uncheckedUploads = unchecked
+			publisher, indexer := testRegistry(objects, PartialFailureAbort)
+			opts := testPublish(t, "1.0.0")
+			// the checksum packaging computed, of a build rewritten since
+			sum := sha256.Sum256([]byte("linux build as packaged"))
+			opts.Artifacts = map[string]types.Artifact{
+				"linux_amd64": {Checksum: hex.EncodeToString(sum[:]), Size: 23},
+			}
+			if err := PublishVersion(t.Context(), publisher, indexer, opts); err == nil {
+				t.Fatal("expected the changed build to fail the publish")
+			}
+			for _, key := range objects.keys() {
+				if strings.HasPrefix(key, "demo/1.0.0/linux-amd64") ||
+					key == (types.Layout{}).IndexPath("demo") {
+					t.Fatalf("left %s despite the mismatch", key)
+				}
+			}
+		})
+	}
+}
+
+func TestPublishLayout(t *testing.T) {
+	objects := newMemStore()
+	layout, err := types.ParseLayout(types.Layout{
+		Artifact: `{{.Date.Format "2006"}}/{{.Plugin}}/{{.Version}}/{{.OS}}_{{.Arch}}.tgz`,
+		Index:    "indexes/{{.Plugin}}.json",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	publisher, indexer := testRegistry(objects, PartialFailureAbort)
+	publisher.layout, indexer.layout = layout, layout
+	if err := PublishVersion(t.Context(), publisher, indexer, testPublish(t, "1.0.0")); err != nil {
+		t.Fatal(err)
+	}
+
+	// clients find the indexes with the layout the registry published
+	var published types.Layout
+	if err := json.Unmarshal(read(t, objects, types.LayoutPath), &published); err != nil {
+		t.Fatal(err)
+	}
+	if !published.Equal(layout) {
+		t.Fatalf("published the layout %+v, want %+v", published, layout)
+	}
+	var index types.PluginIndex
+	if err := json.Unmarshal(read(t, objects, "indexes/demo.json"), &index); err != nil {
+		t.Fatal(err)
+	}
+	url := index.Versions[0].Architectures["linux_amd64"].DownloadURL
+	if url != "2026/demo/1.0.0/linux_amd64.tgz" {
+		t.Fatalf("indexed the build at %s", url)
+	}
+
+	// publishing under another layout would leave indexes clients can't find
+	publisher, indexer = testRegistry(objects, PartialFailureAbort)
+	err = PublishVersion(t.Context(), publisher, indexer, testPublish(t, "1.1.0"))
+	if err == nil || !strings.Contains(err.Error(), types.LayoutPath) {
+		t.Fatalf("got %v, want the publish refused for its layout", err)
+	}
+	for _, key := range objects.keys() {
+		if strings.Contains(key, "1.1.0") {
+			t.Fatalf("uploaded %s under the wrong layout", key)
+		}
+	}
+}
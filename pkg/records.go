@@ -0,0 +1,419 @@
+package pkg
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	dynamotypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/omniviewdev/registry-cli/pkg/console"
+	"github.com/omniviewdev/registry-cli/pkg/types"
+)
+
+const (
+	recordPluginPrefix  = "plugin#"
+	recordVersionPrefix = "version#"
+	recordDetails       = "details"
+
+	// maxMaterializeAttempts bounds how many times the indexes are rebuilt when other
+	// publishes keep changing the records underneath
+	maxMaterializeAttempts = 5
+)
+
+// records stores plugin and version records in a DynamoDB table, as the source of truth the
+// JSON indexes are materialized from. Every version is its own item, so concurrent publishes
+// never overwrite each other.
+//
+// The table needs a string partition key named "pk" and a string sort key named "sk". Each
+// plugin has a details item (sk "details") and an item per version (sk "version#<version>"),
+// all under the partition "plugin#<id>". A revision counter under the partition "registry"
+// is bumped on every change.
+type records struct {
+	client *dynamodb.Client
+	table  string
+}
+
+type pluginDetails struct {
+	ID          string   `json:"id"`
+	Name        string   `json:"name"`
+	Icon        string   `json:"icon"`
+	Description string   `json:"description"`
+	Access      []string `json:"access,omitempty"`
+}
+
+func recordKey(pk, sk string) map[string]dynamotypes.AttributeValue {
+	return map[string]dynamotypes.AttributeValue{
+		"pk": &dynamotypes.AttributeValueMemberS{Value: pk},
+		"sk": &dynamotypes.AttributeValueMemberS{Value: sk},
+	}
+}
+
+func (r *records) put(ctx context.Context, pk, sk string, v any) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	item := recordKey(pk, sk)
+	item["data"] = &dynamotypes.AttributeValueMemberS{Value: string(b)}
+
+	_, err = r.client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(r.table),
+		Item:      item,
+	})
+	if err != nil {
+		return fmt.Errorf("couldn't write %s %s to %s: %v", pk, sk, r.table, err)
+	}
+	return nil
+}
+
+// revision returns the current revision of the records.
+func (r *records) revision(ctx context.Context) (int64, error) {
+	result, err := r.client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName:      aws.String(r.table),
+		Key:            recordKey("registry", "revision"),
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return 0, fmt.Errorf("couldn't read the revision from %s: %v", r.table, err)
+	}
+	rev, ok := result.Item["rev"].(*dynamotypes.AttributeValueMemberN)
+	if !ok {
+		return 0, nil
+	}
+	return strconv.ParseInt(rev.Value, 10, 64)
+}
+
+// bump increments the revision of the records.
+func (r *records) bump(ctx context.Context) error {
+	_, err := r.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName:        aws.String(r.table),
+		Key:              recordKey("registry", "revision"),
+		UpdateExpression: aws.String("ADD rev :one"),
+		ExpressionAttributeValues: map[string]dynamotypes.AttributeValue{
+			":one": &dynamotypes.AttributeValueMemberN{Value: "1"},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("couldn't update the revision in %s: %v", r.table, err)
+	}
+	return nil
+}
+
+// apply writes the changes made from before to after: new and updated versions are put,
+// removed versions are deleted, and the plugin details are replaced.
+func (r *records) apply(ctx context.Context, before, after types.PluginIndex) error {
+	pk := recordPluginPrefix + after.ID
+
+	previous := make(map[string]types.PluginVersionInformation, len(before.Versions))
+	for _, version := range before.Versions {
+		previous[version.Version] = version
+	}
+
+	for _, version := range after.Versions {
+		if existing, ok := previous[version.Version]; ok && reflect.DeepEqual(existing, version) {
+			delete(previous, version.Version)
+			continue
+		}
+		delete(previous, version.Version)
+		if err := r.put(ctx, pk, recordVersionPrefix+version.Version, version); err != nil {
+			return err
+		}
+	}
+
+	for version := range previous {
+		_, err := r.client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+			TableName: aws.String(r.table),
+			Key:       recordKey(pk, recordVersionPrefix+version),
+		})
+		if err != nil {
+			return fmt.Errorf("couldn't delete %s %s from %s: %v", pk, version, r.table, err)
+		}
+	}
+
+	if len(after.Versions) == 0 {
+		// the plugin is gone
+		_, err := r.client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+			TableName: aws.String(r.table),
+			Key:       recordKey(pk, recordDetails),
+		})
+		if err != nil {
+			return fmt.Errorf("couldn't delete %s from %s: %v", pk, r.table, err)
+		}
+		return r.bump(ctx)
+	}
+
+	if err := r.put(ctx, pk, recordDetails, pluginDetails{
+		ID:          after.ID,
+		Name:        after.Name,
+		Icon:        after.Icon,
+		Description: after.Description,
+		Access:      after.Access,
+	}); err != nil {
+		return err
+	}
+	return r.bump(ctx)
+}
+
+// pluginIndex builds the index of a plugin from its records, or a minimal one if it has none.
+func (r *records) pluginIndex(ctx context.Context, plugin string) (types.PluginIndex, error) {
+	index := types.PluginIndex{
+		RegistryIndexPlugins: types.RegistryIndexPlugins{ID: plugin, Name: plugin},
+	}
+
+	paginator := dynamodb.NewQueryPaginator(r.client, &dynamodb.QueryInput{
+		TableName:              aws.String(r.table),
+		KeyConditionExpression: aws.String("pk = :pk"),
+		ExpressionAttributeValues: map[string]dynamotypes.AttributeValue{
+			":pk": &dynamotypes.AttributeValueMemberS{Value: recordPluginPrefix + plugin},
+		},
+		ConsistentRead: aws.Bool(true),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return types.PluginIndex{}, fmt.Errorf("couldn't query %s: %v", r.table, err)
+		}
+		for _, item := range page.Items {
+			if err := addRecord(&index, item); err != nil {
+				return types.PluginIndex{}, err
+			}
+		}
+	}
+	return index, nil
+}
+
+// all builds the index of every plugin from the records.
+func (r *records) all(ctx context.Context) (map[string]*types.PluginIndex, error) {
+	indexes := make(map[string]*types.PluginIndex)
+
+	paginator := dynamodb.NewScanPaginator(r.client, &dynamodb.ScanInput{
+		TableName:      aws.String(r.table),
+		ConsistentRead: aws.Bool(true),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't scan %s: %v", r.table, err)
+		}
+		for _, item := range page.Items {
+			pk, _ := item["pk"].(*dynamotypes.AttributeValueMemberS)
+			if pk == nil || !strings.HasPrefix(pk.Value, recordPluginPrefix) {
+				continue
+			}
+			id := strings.TrimPrefix(pk.Value, recordPluginPrefix)
+			index, ok := indexes[id]
+			if !ok {
+				index = &types.PluginIndex{
+					RegistryIndexPlugins: types.RegistryIndexPlugins{ID: id, Name: id},
+				}
+				indexes[id] = index
+			}
+			if err := addRecord(index, item); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return indexes, nil
+}
+
+// addRecord adds a plugin record to its index.
+func addRecord(index *types.PluginIndex, item map[string]dynamotypes.AttributeValue) error {
+	sk, _ := item["sk"].(*dynamotypes.AttributeValueMemberS)
+	data, _ := item["data"].(*dynamotypes.AttributeValueMemberS)
+	if sk == nil || data == nil {
+		return nil
+	}
+
+	switch {
+	case sk.Value == recordDetails:
+		var details pluginDetails
+		if err := json.Unmarshal([]byte(data.Value), &details); err != nil {
+			return fmt.Errorf("invalid details record for %s: %v", index.ID, err)
+		}
+		index.Name = details.Name
+		index.Icon = details.Icon
+		index.Description = details.Description
+		index.Access = details.Access
+	case strings.HasPrefix(sk.Value, recordVersionPrefix):
+		var version types.PluginVersionInformation
+		if err := json.Unmarshal([]byte(data.Value), &version); err != nil {
+			return fmt.Errorf("invalid version record %s for %s: %v", sk.Value, index.ID, err)
+		}
+		index.SetVersion(version)
+	}
+	return nil
+}
+
+// loadPluginIndex returns the current index of the plugin, from the records when the indexer
+// is backed by DynamoDB, otherwise from the bucket.
+func (i *Indexer) loadPluginIndex(ctx context.Context, plugin string) (types.PluginIndex, error) {
+	if i.records != nil {
+		return i.records.pluginIndex(ctx, plugin)
+	}
+	return i.getPluginIndex(ctx, plugin)
+}
+
+// commitPluginIndex saves the changes to a plugin index made since it was loaded, updating the
+// registry index to match. A plugin left without any versions is removed from the registry.
+// The action that made the changes is recorded in the history along with them.
+func (i *Indexer) commitPluginIndex(
+	ctx context.Context,
+	action string,
+	before, after types.PluginIndex,
+) error {
+	summary := action + ": " + summarizeChange(before, after)
+	if len(after.Versions) > 0 {
+		// (re)published, so it's no longer removed
+		after.Tombstone = nil
+	} else if len(before.Versions) > 0 {
+		after = buryPlugin(after, action)
+	}
+
+	if i.records == nil {
+		if len(after.Versions) == 0 {
+			return i.removePlugin(ctx, after, summary)
+		}
+		if _, err := i.setPluginIndex(ctx, after, summary); err != nil {
+			return err
+		}
+		if err := i.setVersionBadge(ctx, after); err != nil {
+			return err
+		}
+		return i.updateRegistryIndex(ctx, after, summary)
+	}
+
+	if err := i.records.apply(ctx, before, after); err != nil {
+		return err
+	}
+	var stub *types.PluginIndex
+	if len(after.Versions) == 0 && after.Tombstone != nil {
+		stub = &after
+	}
+	return i.materialize(ctx, after.ID, summary, stub)
+}
+
+// buryPlugin turns the index of a plugin left without any versions into the stub left in its
+// place, holding its tombstone. The reason defaults to the action removing the plugin.
+func buryPlugin(index types.PluginIndex, action string) types.PluginIndex {
+	tombstone := types.Tombstone{}
+	if index.Tombstone != nil {
+		tombstone = *index.Tombstone
+	}
+	tombstone.ID = index.ID
+	if tombstone.Reason == "" {
+		tombstone.Reason = action
+	}
+	tombstone.Removed = time.Now().UTC()
+	tombstone.RemovedBy = Actor()
+
+	index.Tombstone = &tombstone
+	index.Versions = []types.PluginVersionInformation{}
+	index.LatestVersion = types.PluginVersionInformation{}
+	return index
+}
+
+// materialize rebuilds the plugin's index and the registry index in the bucket from the
+// records. It's repeated while the records change underneath, so the last publish to finish
+// always leaves indexes that include every publish. When the plugin was removed, the stub holding
+// its tombstone is left in place of its index.
+func (i *Indexer) materialize(
+	ctx context.Context,
+	plugin, summary string,
+	stub *types.PluginIndex,
+) error {
+	for attempt := 0; attempt < maxMaterializeAttempts; attempt++ {
+		rev, err := i.records.revision(ctx)
+		if err != nil {
+			return err
+		}
+		indexes, err := i.records.all(ctx)
+		if err != nil {
+			return err
+		}
+
+		if index, ok := indexes[plugin]; ok && len(index.Versions) > 0 {
+			if _, err := i.setPluginIndex(ctx, *index, summary); err != nil {
+				return err
+			}
+			if err := i.setVersionBadge(ctx, *index); err != nil {
+				return err
+			}
+		} else if err := i.removePluginFiles(ctx, plugin, summary, stub); err != nil {
+			return err
+		}
+
+		// the tombstones aren't in the records, they're kept from the current registry index
+		current, err := i.getRegistryIndex(ctx)
+		if err != nil {
+			return err
+		}
+		registry := types.RegistryIndex{
+			Plugins:    make([]types.RegistryIndexPlugins, 0, len(indexes)),
+			Tombstones: current.Tombstones,
+		}
+		if stub != nil {
+			registry.SetTombstone(*stub.Tombstone)
+		}
+		for _, index := range indexes {
+			if len(index.Versions) == 0 {
+				continue
+			}
+			registry.SetPlugin(types.RegistryIndexPlugins{
+				ID:            index.ID,
+				Name:          index.Name,
+				Icon:          index.Icon,
+				Description:   index.Description,
+				Official:      true,
+				LatestVersion: index.LatestVersion,
+				Access:        index.Access,
+			})
+		}
+		slices.SortFunc(registry.Plugins, func(a, b types.RegistryIndexPlugins) int {
+			return strings.Compare(a.ID, b.ID)
+		})
+		if _, err := i.setRegistryIndex(ctx, registry, fmt.Sprintf("%s: %s", plugin, summary)); err != nil {
+			return err
+		}
+
+		latest, err := i.records.revision(ctx)
+		if err != nil {
+			return err
+		}
+		if latest == rev {
+			return nil
+		}
+		console.Println("records changed while materializing, rebuilding the indexes...")
+	}
+	return fmt.Errorf("records kept changing, gave up materializing the indexes")
+}
+
+// SeedRecords copies the indexes in the bucket into the DynamoDB records, for switching an
+// existing registry over to DynamoDB. Existing records for the same versions are replaced.
+func (i *Indexer) SeedRecords(ctx context.Context) (int, error) {
+	if i.records == nil {
+		return 0, fmt.Errorf("the indexer isn't backed by a DynamoDB table")
+	}
+
+	registry, err := i.getRegistryIndex(ctx)
+	if err != nil {
+		return 0, err
+	}
+	for _, plugin := range registry.Plugins {
+		index, err := i.getPluginIndex(ctx, plugin.ID)
+		if err != nil {
+			return 0, err
+		}
+		console.Printf("seeding %s (%d versions)...\n", index.ID, len(index.Versions))
+		if err := i.records.apply(ctx, types.PluginIndex{}, index); err != nil {
+			return 0, err
+		}
+	}
+	return len(registry.Plugins), nil
+}
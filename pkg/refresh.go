@@ -0,0 +1,145 @@
+package pkg
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"slices"
+	"time"
+
+	"github.com/omniviewdev/registry-cli/pkg/types"
+)
+
+// Refresh describes a version whose index entry was rewritten from its artifacts (or would be,
+// for a dry run).
+type Refresh struct {
+	Plugin  string
+	Version string
+
+	// Artifacts are the refreshed builds of the version, by architecture order
+	Artifacts []RefreshedArtifact
+}
+
+// RefreshedArtifact is a build of a refreshed version.
+type RefreshedArtifact struct {
+	// Arch is the architecture key of the build, os_arch
+	Arch string
+
+	// Key is the bucket path of the build's tarball
+	Key string
+
+	// Changed are the fields of the index entry that didn't match the artifact, e.g. checksum
+	// or size, empty when the entry was up to date
+	Changed []string
+}
+
+// RefreshVersion re-downloads the artifacts of a published version from the bucket, recomputes
+// their checksums and sizes, rewrites their checksum files and rewrites the version's index
+// entry, signing the indexes again. It repairs entries after a key rotation, and backfills the
+// fields added by newer versions of the index schema to versions published before them.
+func (i *Indexer) RefreshVersion(
+	ctx context.Context,
+	plugin, version string,
+	dryRun bool,
+) (Refresh, error) {
+	refresh := Refresh{Plugin: plugin, Version: version}
+	err := i.withLock(ctx, func() error {
+		index, err := i.loadPluginIndex(ctx, plugin)
+		if err != nil {
+			return err
+		}
+		idx := slices.IndexFunc(index.Versions, func(v types.PluginVersionInformation) bool {
+			return v.Version == version
+		})
+		if idx == -1 {
+			return fmt.Errorf("%s %s: %w", plugin, version, ErrVersionNotFound)
+		}
+
+		current := index.Versions[idx]
+		refreshed := current
+		refreshed.Architectures = make(
+			map[string]types.PluginArchitectureInformation,
+			len(current.Architectures),
+		)
+		for _, arch := range sortedArchs(current) {
+			info := current.Architectures[arch]
+			key := i.artifactKey(info.DownloadURL)
+			checksum, size, err := i.hashObject(ctx, key)
+			if err != nil {
+				return err
+			}
+
+			updated := info
+			updated.Checksum = checksum
+			updated.Size = size
+			updated.DownloadURL = i.downloadURL(info.DownloadURL)
+			updated.ChecksumURL = i.downloadURL(info.ChecksumURL)
+			if info.ChecksumURL == "" {
+				updated.ChecksumURL = updated.DownloadURL + types.ChecksumExt
+			}
+			refreshed.Architectures[arch] = updated
+			refresh.Artifacts = append(refresh.Artifacts, RefreshedArtifact{
+				Arch:    arch,
+				Key:     key,
+				Changed: changedFields(info, updated),
+			})
+		}
+		if dryRun {
+			return nil
+		}
+
+		// the checksum files are rewritten even when the entry was up to date, they may be
+		// missing or stale
+		for _, artifact := range refresh.Artifacts {
+			checksum := []byte(refreshed.Architectures[artifact.Arch].Checksum)
+			path := artifact.Key + types.ChecksumExt
+			if _, err := i.storeObject(ctx, checksum, path, "text/plain"); err != nil {
+				return err
+			}
+		}
+
+		before := index
+		refreshed.Updated = time.Now()
+		index.Versions = slices.Clone(index.Versions)
+		index.Versions[idx] = refreshed
+		index.LatestVersion = index.Latest()
+		return i.commitPluginIndex(ctx, "refresh", before, index)
+	})
+	return refresh, err
+}
+
+// hashObject returns the sha256 checksum and the size of an object in the bucket.
+func (i *Indexer) hashObject(ctx context.Context, key string) (string, int64, error) {
+	body, err := i.objects.Get(ctx, key)
+	if err != nil {
+		return "", 0, fmt.Errorf("couldn't get artifact %s: %v", key, err)
+	}
+	defer body.Close()
+
+	h := sha256.New()
+	size, err := io.Copy(h, body)
+	if err != nil {
+		return "", 0, fmt.Errorf("couldn't read artifact %s: %v", key, err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), size, nil
+}
+
+// changedFields lists the fields of an index entry that differ once refreshed.
+func changedFields(before, after types.PluginArchitectureInformation) []string {
+	var changed []string
+	if before.Checksum != after.Checksum {
+		changed = append(changed, "checksum")
+	}
+	if before.Size != after.Size {
+		changed = append(changed, "size")
+	}
+	if before.DownloadURL != after.DownloadURL {
+		changed = append(changed, "download_url")
+	}
+	if before.ChecksumURL != after.ChecksumURL {
+		changed = append(changed, "checksum_url")
+	}
+	return changed
+}
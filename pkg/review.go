@@ -0,0 +1,210 @@
+package pkg
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/omniviewdev/registry-cli/pkg/console"
+	"github.com/omniviewdev/registry-cli/pkg/types"
+)
+
+// ErrNotPending is returned when a release isn't awaiting review
+var ErrNotPending = errors.New("no pending release")
+
+// SubmitForReview records a moderated publish as awaiting review, once its builds are uploaded
+// to the pending area. The indexes are left untouched until the release is approved.
+func (i *Indexer) SubmitForReview(ctx context.Context, opts types.PublishOpts) error {
+	metadata, err := types.LoadMetadata(opts.MetadataPath)
+	if err != nil {
+		return err
+	}
+	if err := metadata.Theme.Validate(); err != nil {
+		return fmt.Errorf("invalid plugin metadata: %w", err)
+	}
+
+	// the entry the release gets once approved, built the way a publish builds it
+	index, err := i.updateIndex(
+		types.PluginIndex{RegistryIndexPlugins: types.RegistryIndexPlugins{ID: opts.Plugin}},
+		opts.ToReleases(),
+		metadata,
+		opts.Compatibility,
+	)
+	if err != nil {
+		return err
+	}
+	idx := slices.IndexFunc(index.Versions, func(v types.PluginVersionInformation) bool {
+		return v.Version == opts.Version
+	})
+	if idx == -1 {
+		return fmt.Errorf("no builds of %s %s to submit for review", opts.Plugin, opts.Version)
+	}
+
+	pending := types.PendingRelease{
+		Plugin:      opts.Plugin,
+		Name:        index.Name,
+		Icon:        index.Icon,
+		Description: index.Description,
+		Version:     index.Versions[idx],
+		Submitted:   time.Now().UTC(),
+		SubmittedBy: Actor(),
+	}
+	if opts.Report != nil {
+		for arch, info := range pending.Version.Architectures {
+			platReport := opts.Report.Platform(arch)
+			platReport.Checksum = info.Checksum
+			platReport.Size = info.Size
+		}
+	}
+
+	b, err := json.Marshal(pending)
+	if err != nil {
+		return fmt.Errorf("failed to upload pending release: %v", err)
+	}
+	path := types.PendingReleasePath(opts.Plugin, opts.Version)
+	console.Printf("uploading pending release to %s...\n", path)
+	_, err = i.storeObject(ctx, b, path, "application/json")
+	return err
+}
+
+// PendingReleases lists the releases awaiting review, oldest first.
+func (i *Indexer) PendingReleases(ctx context.Context) ([]types.PendingRelease, error) {
+	objects, err := i.objects.List(ctx, types.PendingReleasesPrefix)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't list %s: %v", types.PendingReleasesPrefix, err)
+	}
+	var releases []types.PendingRelease
+	for _, object := range objects {
+		if !strings.HasSuffix(object.Key, ".json") {
+			continue
+		}
+		release, err := i.getPendingRelease(ctx, object.Key)
+		if err != nil {
+			return nil, err
+		}
+		releases = append(releases, release)
+	}
+
+	slices.SortFunc(releases, func(a, b types.PendingRelease) int {
+		return a.Submitted.Compare(b.Submitted)
+	})
+	return releases, nil
+}
+
+// PendingRelease returns a release awaiting review.
+func (i *Indexer) PendingRelease(
+	ctx context.Context,
+	plugin, version string,
+) (types.PendingRelease, error) {
+	return i.getPendingRelease(ctx, types.PendingReleasePath(plugin, version))
+}
+
+func (i *Indexer) getPendingRelease(ctx context.Context, key string) (types.PendingRelease, error) {
+	body, err := readObject(ctx, i.objects, key)
+	if errors.Is(err, ErrObjectNotFound) {
+		return types.PendingRelease{}, fmt.Errorf("%s: %w", key, ErrNotPending)
+	}
+	if err != nil {
+		return types.PendingRelease{}, fmt.Errorf("couldn't get pending release %s: %v", key, err)
+	}
+	var release types.PendingRelease
+	if err := json.Unmarshal(body, &release); err != nil {
+		return release, fmt.Errorf("couldn't decode pending release %s: %v", key, err)
+	}
+	return release, nil
+}
+
+// Approve promotes a release awaiting review into the registry: its builds are copied from the
+// pending area to their final keys, and the release is added to the indexes the way a publish
+// adds it. The pending release is removed once it's in the indexes.
+func (i *Indexer) Approve(
+	ctx context.Context,
+	plugin, version string,
+) (types.PendingRelease, error) {
+	pending, err := i.PendingRelease(ctx, plugin, version)
+	if err != nil {
+		return pending, err
+	}
+
+	artifacts := i.pendingArtifacts(pending)
+	for _, artifact := range artifacts {
+		for _, key := range []string{artifact, artifact + types.ChecksumExt} {
+			if err := i.copy(ctx, types.PendingArtifactPath(key), key); err != nil {
+				return pending, err
+			}
+		}
+	}
+
+	source := types.PluginIndex{RegistryIndexPlugins: types.RegistryIndexPlugins{
+		ID:          pending.Plugin,
+		Name:        pending.Name,
+		Icon:        pending.Icon,
+		Description: pending.Description,
+	}}
+	versions := []types.PluginVersionInformation{pending.Version}
+	err = i.withLock(ctx, func() error {
+		return i.importVersions(ctx, "approve", source, versions)
+	})
+	if err != nil {
+		return pending, err
+	}
+	return pending, i.removePending(ctx, pending, artifacts)
+}
+
+// Reject discards a release awaiting review, deleting its builds from the pending area.
+func (i *Indexer) Reject(
+	ctx context.Context,
+	plugin, version string,
+) (types.PendingRelease, error) {
+	pending, err := i.PendingRelease(ctx, plugin, version)
+	if err != nil {
+		return pending, err
+	}
+	return pending, i.removePending(ctx, pending, i.pendingArtifacts(pending))
+}
+
+// pendingArtifacts returns the final keys of the builds of a pending release.
+func (i *Indexer) pendingArtifacts(pending types.PendingRelease) []string {
+	artifacts := make([]string, 0, len(pending.Version.Architectures))
+	for _, arch := range sortedArchs(pending.Version) {
+		artifacts = append(
+			artifacts,
+			i.artifactKey(pending.Version.Architectures[arch].DownloadURL),
+		)
+	}
+	return artifacts
+}
+
+// removePending deletes a pending release along with its builds in the pending area.
+func (i *Indexer) removePending(
+	ctx context.Context,
+	pending types.PendingRelease,
+	artifacts []string,
+) error {
+	var errs []error
+	for _, artifact := range artifacts {
+		for _, key := range []string{artifact, artifact + types.ChecksumExt} {
+			if err := i.delete(ctx, types.PendingArtifactPath(key)); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	path := types.PendingReleasePath(pending.Plugin, pending.Version.Version)
+	if err := i.delete(ctx, path); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// copy copies an object within the bucket
+func (i *Indexer) copy(ctx context.Context, from, to string) error {
+	console.Printf("copying %s to %s...\n", from, to)
+	if err := i.objects.Copy(ctx, from, to); err != nil {
+		return fmt.Errorf("couldn't copy %v:%v to %v: %v", i.bucket, from, to, err)
+	}
+	return nil
+}
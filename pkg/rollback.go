@@ -0,0 +1,170 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/omniviewdev/registry-cli/pkg/signing"
+	"github.com/omniviewdev/registry-cli/pkg/types"
+)
+
+// rollbackTimeFormats are the formats accepted for a point in time to roll back to
+var rollbackTimeFormats = []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02"}
+
+// IndexRevision is a version of an index kept by the versioning of the bucket.
+type IndexRevision struct {
+	VersionID    string
+	LastModified time.Time
+	Size         int64
+	IsLatest     bool
+}
+
+// IndexRevisions lists the revisions the bucket keeps of a plugin's index, or of the registry
+// index when plugin is empty, most recent first. The bucket must have versioning enabled.
+func (i *Indexer) IndexRevisions(ctx context.Context, plugin string) ([]IndexRevision, error) {
+	versions, err := storeFeature[versionedStore](i.objects, "listing index revisions")
+	if err != nil {
+		return nil, err
+	}
+	versioning, err := versions.Versioning(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't get versioning of %s: %v", i.bucket, err)
+	}
+	if !versioning {
+		return nil, fmt.Errorf("versioning isn't enabled on %s, so there are no revisions to roll back to", i.bucket)
+	}
+
+	path := i.historyIndexPath(plugin)
+	revisions, err := versions.Versions(ctx, path)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't list revisions of %s: %v", path, err)
+	}
+	return revisions, nil
+}
+
+// RollbackIndex restores a previous revision of a plugin's index, or of the registry index when
+// plugin is empty. to is either the version ID of the revision or a point in time, in which case
+// the last revision written by then is restored. The restored index is signed and written like
+// any other index update, so it's recorded in the history and the pointers are updated to match.
+func (i *Indexer) RollbackIndex(ctx context.Context, plugin, to string) (IndexRevision, error) {
+	if plugin == "" && i.records != nil {
+		return IndexRevision{}, errors.New(
+			"the registry index is materialized from the index table, roll back the plugin indexes instead",
+		)
+	}
+	if err := i.checkCanSign(ctx, i.historyIndexPath(plugin)); err != nil {
+		return IndexRevision{}, err
+	}
+
+	revisions, err := i.IndexRevisions(ctx, plugin)
+	if err != nil {
+		return IndexRevision{}, err
+	}
+	revision, err := findRevision(revisions, to)
+	if err != nil {
+		return IndexRevision{}, err
+	}
+	if revision.IsLatest {
+		return IndexRevision{}, fmt.Errorf("revision %s is already the current index", revision.VersionID)
+	}
+
+	b, err := i.getRevision(ctx, i.historyIndexPath(plugin), revision.VersionID)
+	if err != nil {
+		return IndexRevision{}, err
+	}
+	summary := fmt.Sprintf(
+		"rolled back to revision %s from %s",
+		revision.VersionID,
+		revision.LastModified.UTC().Format(time.RFC3339),
+	)
+
+	err = i.withLock(ctx, func() error {
+		if plugin == "" {
+			var registry types.RegistryIndex
+			if err := types.DecodeIndex(b, &registry); err != nil {
+				return fmt.Errorf("revision %s holds an invalid registry index: %w", revision.VersionID, err)
+			}
+			_, err := i.setRegistryIndex(ctx, registry, summary)
+			return err
+		}
+
+		var restored types.PluginIndex
+		if err := types.DecodeIndex(b, &restored); err != nil {
+			return fmt.Errorf("revision %s holds an invalid plugin index: %w", revision.VersionID, err)
+		}
+		current, err := i.loadPluginIndex(ctx, plugin)
+		if err != nil {
+			return err
+		}
+		return i.commitPluginIndex(ctx, summary, current, restored)
+	})
+	if err != nil {
+		return IndexRevision{}, err
+	}
+	return revision, nil
+}
+
+// findRevision finds the revision with the version ID, or the last one written by the time.
+func findRevision(revisions []IndexRevision, to string) (IndexRevision, error) {
+	for _, revision := range revisions {
+		if revision.VersionID == to {
+			return revision, nil
+		}
+	}
+
+	for _, format := range rollbackTimeFormats {
+		at, err := time.Parse(format, to)
+		if err != nil {
+			continue
+		}
+		if format == "2006-01-02" {
+			// a date includes the whole day
+			at = at.Add(24*time.Hour - time.Nanosecond)
+		}
+		for _, revision := range revisions {
+			if !revision.LastModified.After(at) {
+				return revision, nil
+			}
+		}
+		return IndexRevision{}, fmt.Errorf("no revision was written by %s", to)
+	}
+	return IndexRevision{}, fmt.Errorf("%q is neither a revision version ID nor a time", to)
+}
+
+func (i *Indexer) getRevision(ctx context.Context, path, versionID string) ([]byte, error) {
+	versions, err := storeFeature[versionedStore](i.objects, "reading index revisions")
+	if err != nil {
+		return nil, err
+	}
+	body, err := versions.GetVersion(ctx, path, versionID)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't get revision %s of %s: %v", versionID, path, err)
+	}
+	defer body.Close()
+
+	b, err := io.ReadAll(body)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't read revision %s of %s: %v", versionID, path, err)
+	}
+	return b, nil
+}
+
+// checkCanSign makes sure an index that's currently signed won't be rewritten without a
+// signature, which clients would reject.
+func (i *Indexer) checkCanSign(ctx context.Context, path string) error {
+	if i.signingKey != nil {
+		return nil
+	}
+	_, err := i.objects.Head(ctx, path+signing.SignatureExt)
+	switch {
+	case err == nil:
+		return fmt.Errorf("%s is signed, a signing key is required to rewrite it", path)
+	case errors.Is(err, ErrObjectNotFound):
+		return nil
+	default:
+		return fmt.Errorf("couldn't check for a signature of %s: %v", path, err)
+	}
+}
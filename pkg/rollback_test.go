@@ -0,0 +1,188 @@
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// versionedMemStore is a memStore keeping the previous versions of the objects written with
+// Put, as a bucket with versioning enabled does.
+type versionedMemStore struct {
+	*memStore
+
+	// now is the time the next versions are written at
+	now time.Time
+
+	// versions are the versions of each object, newest first
+	versions map[string][]memVersion
+	written  int
+}
+
+type memVersion struct {
+	IndexRevision
+	b []byte
+}
+
+func newVersionedMemStore() *versionedMemStore {
+	return &versionedMemStore{memStore: newMemStore(), versions: make(map[string][]memVersion)}
+}
+
+func (m *versionedMemStore) Put(ctx context.Context, key string, b []byte, opts PutOptions) error {
+	if err := m.memStore.Put(ctx, key, b, opts); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.written++
+	version := memVersion{
+		IndexRevision: IndexRevision{
+			VersionID:    fmt.Sprintf("v%d", m.written),
+			LastModified: m.now,
+			Size:         int64(len(b)),
+		},
+		b: slices.Clone(b),
+	}
+	m.versions[key] = slices.Insert(m.versions[key], 0, version)
+	return nil
+}
+
+func (m *versionedMemStore) Versioning(ctx context.Context) (bool, error) {
+	return true, nil
+}
+
+func (m *versionedMemStore) Versions(ctx context.Context, key string) ([]IndexRevision, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var revisions []IndexRevision
+	for i, version := range m.versions[key] {
+		revision := version.IndexRevision
+		revision.IsLatest = i == 0
+		revisions = append(revisions, revision)
+	}
+	return revisions, nil
+}
+
+func (m *versionedMemStore) GetVersion(
+	ctx context.Context,
+	key, versionID string,
+) (io.ReadCloser, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, version := range m.versions[key] {
+		if version.VersionID == versionID {
+			return io.NopCloser(bytes.NewReader(version.b)), nil
+		}
+	}
+	return nil, fmt.Errorf("%s version %s: %w", key, versionID, ErrObjectNotFound)
+}
+
+func TestFindRevision(t *testing.T) {
+	revisions := []IndexRevision{
+		{VersionID: "v3", LastModified: time.Date(2026, 1, 3, 10, 0, 0, 0, time.UTC), IsLatest: true},
+		{VersionID: "v2", LastModified: time.Date(2026, 1, 2, 12, 0, 0, 0, time.UTC)},
+		{VersionID: "v1", LastModified: time.Date(2026, 1, 1, 8, 0, 0, 0, time.UTC)},
+	}
+
+	tests := []struct {
+		to      string
+		want    string
+		wantErr string
+	}{
+		{to: "v2", want: "v2"},
+		{to: "2026-01-02T12:00:00Z", want: "v2"},
+		{to: "2026-01-02T11:59:59Z", want: "v1"},
+		{to: "2026-01-02T13:00:00+02:00", want: "v1"},
+		{to: "2026-01-02T13:00:00", want: "v2"},
+		{to: "2026-01-02 13:00:00", want: "v2"},
+		// a date is the end of the day, not its start
+		{to: "2026-01-02", want: "v2"},
+		{to: "2026-01-03", want: "v3"},
+		{to: "2025-12-31", wantErr: "no revision was written by 2025-12-31"},
+		{to: "yesterday", wantErr: "neither a revision version ID nor a time"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.to, func(t *testing.T) {
+			got, err := findRevision(revisions, tt.to)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("got %v, want an error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.VersionID != tt.want {
+				t.Fatalf("found %s, want %s", got.VersionID, tt.want)
+			}
+		})
+	}
+}
+
+func TestRollbackIndex(t *testing.T) {
+	objects := newVersionedMemStore()
+	publisher, indexer := testRegistry(objects, PartialFailureAbort)
+	published := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	for day, version := range []string{"1.0.0", "1.1.0"} {
+		objects.now = published.AddDate(0, 0, day)
+		if err := PublishVersion(t.Context(), publisher, indexer, testPublish(t, version)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	objects.now = published.AddDate(0, 0, 2)
+
+	revision, err := indexer.RollbackIndex(t.Context(), "demo", "2026-01-01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !revision.LastModified.Equal(published) {
+		t.Fatalf("rolled back to the revision from %s, want %s", revision.LastModified, published)
+	}
+	index := pluginIndex(t, objects)
+	if len(index.Versions) != 1 || index.LatestVersion.Version != "1.0.0" {
+		t.Fatalf("rolled back to %+v, want 1.0.0", index.Versions)
+	}
+	snapshots, err := indexer.History(t.Context(), "demo", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snapshots) != 1 || !strings.Contains(snapshots[0].Summary, revision.VersionID) {
+		t.Fatalf("recorded %+v, want the rollback", snapshots)
+	}
+
+	// the rollback is itself the current revision now
+	revisions, err := indexer.IndexRevisions(t.Context(), "demo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := indexer.RollbackIndex(
+		t.Context(),
+		"demo",
+		revisions[0].VersionID,
+	); err == nil || !strings.Contains(err.Error(), "already the current index") {
+		t.Fatalf("got %v, want the current revision refused", err)
+	}
+
+	// the registry index is materialized from the records when there are any
+	indexer.records = &records{}
+	if _, err := indexer.RollbackIndex(
+		t.Context(),
+		"",
+		revisions[1].VersionID,
+	); err == nil || !strings.Contains(err.Error(), "materialized from the index table") {
+		t.Fatalf("got %v, want the registry index refused", err)
+	}
+}
+
+func TestRollbackIndexUnversioned(t *testing.T) {
+	_, indexer := testRegistry(newMemStore(), PartialFailureAbort)
+	if _, err := indexer.RollbackIndex(t.Context(), "demo", "2026-01-01"); err == nil {
+		t.Fatal("rolled back without versioning")
+	}
+}
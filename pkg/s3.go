@@ -0,0 +1,137 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+const (
+	// ProviderS3 hosts the registry in an S3 bucket, or a bucket of an S3 compatible store
+	ProviderS3 = "s3"
+
+	// ProviderGCS hosts the registry in a Google Cloud Storage bucket
+	ProviderGCS = "gcs"
+
+	// ProviderAzure hosts the registry in an Azure Blob Storage container
+	ProviderAzure = "azure"
+
+	// ProviderFile hosts the registry in a directory of the local filesystem, the bucket being
+	// its path or file:// URL
+	ProviderFile = "file"
+)
+
+// ParseProvider validates the name of a provider, ProviderS3, ProviderGCS, ProviderAzure or
+// ProviderFile. Empty is ProviderS3.
+func ParseProvider(name string) (string, error) {
+	switch name {
+	case "":
+		return ProviderS3, nil
+	case ProviderS3, ProviderGCS, ProviderAzure, ProviderFile:
+		return name, nil
+	default:
+		return "", fmt.Errorf(
+			"unsupported provider %q, expected %s, %s, %s or %s",
+			name,
+			ProviderS3,
+			ProviderGCS,
+			ProviderAzure,
+			ProviderFile,
+		)
+	}
+}
+
+// Endpoint is where the S3 compatible store hosting the bucket is, for self-hosted registries on
+// MinIO, Ceph, Cloudflare R2 and the like. Buckets of the other providers aren't affected.
+type Endpoint struct {
+	// URL is the endpoint of the store, e.g. https://<account>.r2.cloudflarestorage.com. Uses
+	// AWS_ENDPOINT_URL, or the S3 endpoint of the region, when empty.
+	URL string
+
+	// Region is the region of the bucket, auto for R2. Uses the region of the default AWS
+	// configuration when empty.
+	Region string
+
+	// PathStyle addresses buckets by path (<endpoint>/<bucket>/<key>) rather than by host name,
+	// as most S3 compatible stores require. Also set by AWS_S3_FORCE_PATH_STYLE=true.
+	PathStyle bool
+}
+
+// Defaulter sets the URL from AWS_ENDPOINT_URL and PathStyle from AWS_S3_FORCE_PATH_STYLE when
+// they aren't set.
+func (e *Endpoint) Defaulter() {
+	if e.URL == "" {
+		e.URL = os.Getenv("AWS_ENDPOINT_URL")
+	}
+	if !e.PathStyle {
+		e.PathStyle, _ = strconv.ParseBool(os.Getenv("AWS_S3_FORCE_PATH_STYLE"))
+	}
+}
+
+// Validate checks that the URL of the endpoint, if any, is an http or https URL.
+func (e Endpoint) Validate() error {
+	if e.URL != "" {
+		u, err := url.Parse(e.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid endpoint %q, expected an http or https URL", e.URL)
+		}
+	}
+	return nil
+}
+
+// loadAWSConfig loads the default AWS configuration.
+func loadAWSConfig(ctx context.Context) (aws.Config, error) {
+	sdkConfig, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		return aws.Config{}, errors.New(
+			"couldn't load default configuration, have you set up your AWS account?",
+		)
+	}
+	return sdkConfig, nil
+}
+
+// newS3Client creates an S3 client from the default AWS configuration, for the bucket at the
+// endpoint. Requests are paced by the pacer, unless it's nil.
+func newS3Client(
+	ctx context.Context,
+	endpoint Endpoint,
+	pacer *requestPacer,
+) (*s3.Client, error) {
+	if err := endpoint.Validate(); err != nil {
+		return nil, err
+	}
+	sdkConfig, err := loadAWSConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return s3.NewFromConfig(sdkConfig, func(o *s3.Options) {
+		o.UsePathStyle = endpoint.PathStyle
+		if endpoint.URL != "" {
+			o.BaseEndpoint = aws.String(endpoint.URL)
+		}
+		if endpoint.Region != "" {
+			o.Region = endpoint.Region
+		}
+		if pacer != nil {
+			o.APIOptions = append(o.APIOptions, pacer.addMiddleware)
+		}
+	}), nil
+}
+
+// newDynamoClient creates a DynamoDB client from the default AWS configuration.
+func newDynamoClient(ctx context.Context) (*dynamodb.Client, error) {
+	sdkConfig, err := loadAWSConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return dynamodb.NewFromConfig(sdkConfig), nil
+}
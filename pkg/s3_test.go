@@ -0,0 +1,36 @@
+package pkg
+
+import "testing"
+
+func TestEndpointDefaulter(t *testing.T) {
+	t.Setenv("AWS_ENDPOINT_URL", "http://localhost:9000")
+	t.Setenv("AWS_S3_FORCE_PATH_STYLE", "true")
+
+	var fromEnv Endpoint
+	fromEnv.Defaulter()
+	if fromEnv.URL != "http://localhost:9000" || !fromEnv.PathStyle {
+		t.Fatalf("got %+v, want the endpoint of the environment", fromEnv)
+	}
+
+	given := Endpoint{URL: "https://account.r2.cloudflarestorage.com", Region: "auto"}
+	given.Defaulter()
+	if given.URL != "https://account.r2.cloudflarestorage.com" || given.Region != "auto" {
+		t.Fatalf("got %+v, want the endpoint given", given)
+	}
+}
+
+func TestEndpointValidate(t *testing.T) {
+	tests := map[string]bool{
+		"":                       true,
+		"http://localhost:9000":  true,
+		"https://s3.example.com": true,
+		"localhost:9000":         false,
+		"ftp://example.com":      false,
+		"https://":               false,
+	}
+	for url, valid := range tests {
+		if err := (Endpoint{URL: url}).Validate(); (err == nil) != valid {
+			t.Errorf("Validate(%q) = %v, want valid %v", url, err, valid)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package schema
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Draft is the JSON Schema dialect the generated schemas conform to.
+const Draft = "https://json-schema.org/draft/2020-12/schema"
+
+// Schema is a (subset of a) JSON Schema document.
+type Schema struct {
+	Schema               string             `json:"$schema,omitempty"`
+	Title                string             `json:"title,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties any                `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AnyOf                []*Schema          `json:"anyOf,omitempty"`
+	Defs                 map[string]*Schema `json:"$defs,omitempty"`
+}
+
+// JSON returns the schema as indented JSON.
+func (s *Schema) JSON() ([]byte, error) {
+	return json.MarshalIndent(s, "", "  ")
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// Generate builds a JSON Schema for the type of v by reflection. Property names are taken from
+// the given struct tag (e.g. "yaml" or "json"), and fields tagged with `schema:"required"` are
+// listed as required. Objects are closed (no additional properties), so unknown keys such as
+// typos are reported by Validate.
+func Generate(v any, tag string, title string) *Schema {
+	s := generator{tag: tag}.generate(reflect.TypeOf(v))
+	s.Schema = Draft
+	s.Title = title
+	return s
+}
+
+// GenerateDocument builds a JSON Schema for the type of v by reflection, describing the JSON
+// documents it's encoded to for other programs to read, such as the indexes of the registry.
+// Property names are taken from the json struct tag, and fields that aren't omitempty are
+// listed as required. Objects are open, so documents with the fields newer versions add still
+// validate.
+func GenerateDocument(v any, title string) *Schema {
+	s := generator{tag: "json", document: true}.generate(reflect.TypeOf(v))
+	s.Title = title
+	return s
+}
+
+// generator generates the schemas of types, for the given struct tag.
+type generator struct {
+	tag string
+
+	// document generates the schemas of encoded documents, see GenerateDocument
+	document bool
+}
+
+func (g generator) generate(t reflect.Type) *Schema {
+	s := g.generateType(t)
+	switch t.Kind() {
+	case reflect.Pointer, reflect.Slice, reflect.Map:
+		if g.document {
+			// encoded as null when nil
+			return &Schema{AnyOf: []*Schema{s, {Type: "null"}}}
+		}
+	}
+	return s
+}
+
+func (g generator) generateType(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	if t == timeType {
+		return &Schema{Type: "string", Format: "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: g.generate(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: g.generate(t.Elem())}
+	case reflect.Struct:
+		return g.generateStruct(t)
+	default:
+		// interfaces and anything else we can't describe accept any value
+		return &Schema{}
+	}
+}
+
+func (g generator) generateStruct(t reflect.Type) *Schema {
+	s := &Schema{
+		Type:       "object",
+		Properties: make(map[string]*Schema),
+	}
+	if !g.document {
+		s.AdditionalProperties = false
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name, inline, omitEmpty := fieldName(field, g.tag)
+		if name == "-" {
+			continue
+		}
+
+		fieldSchema := g.generate(field.Type)
+		if inline || (field.Anonymous && name == "") {
+			// flatten embedded/inlined structs into the parent
+			for k, v := range fieldSchema.Properties {
+				s.Properties[k] = v
+			}
+			s.Required = append(s.Required, fieldSchema.Required...)
+			continue
+		}
+		if name == "" && g.document {
+			name = field.Name
+		} else if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+
+		s.Properties[name] = fieldSchema
+		if field.Tag.Get("schema") == "required" || (g.document && !omitEmpty) {
+			s.Required = append(s.Required, name)
+		}
+	}
+
+	return s
+}
+
+// fieldName returns the serialized name of the field for the tag, and whether it is inlined or
+// left out when empty.
+func fieldName(field reflect.StructField, tag string) (string, bool, bool) {
+	value, ok := field.Tag.Lookup(tag)
+	if !ok {
+		return "", false, false
+	}
+	parts := strings.Split(value, ",")
+	inline, omitEmpty := false, false
+	for _, opt := range parts[1:] {
+		switch opt {
+		case "inline":
+			inline = true
+		case "omitempty":
+			omitEmpty = true
+		}
+	}
+	return parts[0], inline, omitEmpty
+}
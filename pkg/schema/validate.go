@@ -0,0 +1,227 @@
+package schema
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ValidationError describes a single violation of a schema.
+type ValidationError struct {
+	// Path is the dotted path to the offending value, e.g. maintainers[0].email
+	Path string
+
+	// Message describes what is wrong
+	Message string
+
+	// Missing is true when the error is a required field that is not present
+	Missing bool
+}
+
+func (e ValidationError) Error() string {
+	if e.Path == "" {
+		return e.Message
+	}
+	return fmt.Sprintf("%s: %s", e.Path, e.Message)
+}
+
+// Validate checks a decoded document (as produced by decoding YAML or JSON into an any) against
+// the schema, returning every violation found.
+func Validate(s *Schema, doc any) []ValidationError {
+	var errs []ValidationError
+	validate(s, doc, "", &errs)
+	return errs
+}
+
+// Join combines validation errors into a single error, or nil if there are none.
+func Join(errs []ValidationError) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	all := make([]error, 0, len(errs))
+	for _, err := range errs {
+		all = append(all, err)
+	}
+	return errors.Join(all...)
+}
+
+func validate(s *Schema, doc any, path string, errs *[]ValidationError) {
+	if s == nil || doc == nil {
+		return
+	}
+
+	fail := func(format string, args ...any) {
+		*errs = append(*errs, ValidationError{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	switch s.Type {
+	case "":
+		// untyped schemas accept anything
+	case "string":
+		switch doc.(type) {
+		case string:
+		case time.Time:
+			// YAML decodes timestamps natively
+		default:
+			fail("expected a string, got %s", typeName(doc))
+			return
+		}
+		if str, ok := doc.(string); ok && len(s.Enum) > 0 && !slices.Contains(s.Enum, str) {
+			fail("must be one of %s", strings.Join(s.Enum, ", "))
+		}
+	case "boolean":
+		if _, ok := doc.(bool); !ok {
+			fail("expected a boolean, got %s", typeName(doc))
+		}
+	case "integer":
+		switch doc.(type) {
+		case int, int64, uint64:
+		case float64:
+			if f := doc.(float64); f != float64(int64(f)) {
+				fail("expected an integer, got %v", f)
+			}
+		default:
+			fail("expected an integer, got %s", typeName(doc))
+		}
+	case "number":
+		switch doc.(type) {
+		case int, int64, uint64, float64:
+		default:
+			fail("expected a number, got %s", typeName(doc))
+		}
+	case "array":
+		items, ok := doc.([]any)
+		if !ok {
+			fail("expected a list, got %s", typeName(doc))
+			return
+		}
+		for i, item := range items {
+			validate(s.Items, item, fmt.Sprintf("%s[%d]", path, i), errs)
+		}
+	case "object":
+		obj, ok := toObject(doc)
+		if !ok {
+			fail("expected an object, got %s", typeName(doc))
+			return
+		}
+		validateObject(s, obj, path, errs)
+	}
+}
+
+func validateObject(s *Schema, obj map[string]any, path string, errs *[]ValidationError) {
+	for _, req := range s.Required {
+		if _, ok := obj[req]; !ok {
+			*errs = append(*errs, ValidationError{
+				Path:    joinPath(path, req),
+				Message: "required field is missing",
+				Missing: true,
+			})
+		}
+	}
+
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		value := obj[key]
+		if prop, ok := s.Properties[key]; ok {
+			validate(prop, value, joinPath(path, key), errs)
+			continue
+		}
+
+		switch additional := s.AdditionalProperties.(type) {
+		case *Schema:
+			validate(additional, value, joinPath(path, key), errs)
+		case bool:
+			if additional {
+				continue
+			}
+			msg := "unknown field"
+			if suggestion := closest(key, s.Properties); suggestion != "" {
+				msg = fmt.Sprintf("unknown field (did you mean %q?)", suggestion)
+			}
+			*errs = append(*errs, ValidationError{Path: joinPath(path, key), Message: msg})
+		}
+	}
+}
+
+// toObject normalizes the map types produced by the YAML and JSON decoders.
+func toObject(doc any) (map[string]any, bool) {
+	switch m := doc.(type) {
+	case map[string]any:
+		return m, true
+	case map[any]any:
+		obj := make(map[string]any, len(m))
+		for k, v := range m {
+			obj[fmt.Sprint(k)] = v
+		}
+		return obj, true
+	default:
+		return nil, false
+	}
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func typeName(doc any) string {
+	switch doc.(type) {
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case int, int64, uint64, float64:
+		return "number"
+	case []any:
+		return "list"
+	case map[string]any, map[any]any:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", doc)
+	}
+}
+
+// closest returns the property name closest to key, if it's close enough to be a likely typo.
+func closest(key string, properties map[string]*Schema) string {
+	best, bestDist := "", -1
+	for name := range properties {
+		d := levenshtein(key, name)
+		if bestDist == -1 || d < bestDist || (d == bestDist && name < best) {
+			best, bestDist = name, d
+		}
+	}
+	if bestDist == -1 || bestDist > 2+len(key)/5 {
+		return ""
+	}
+	return best
+}
+
+func levenshtein(a, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
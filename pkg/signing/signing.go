@@ -0,0 +1,280 @@
+// Package signing signs and verifies registry files with Ed25519 keys. Public keys and
+// signatures use the minisign format, so they can also be checked with the minisign tool.
+package signing
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SignatureExt is appended to the path of a signed file to get the path of its signature.
+const SignatureExt = ".sig"
+
+// algorithm is the minisign identifier for pure Ed25519 signatures
+var algorithm = [2]byte{'E', 'd'}
+
+var (
+	// ErrNoSignature is returned when a file that must be signed has no signature.
+	ErrNoSignature = errors.New("file is not signed")
+
+	// ErrUntrustedKey is returned when a file is signed with a key that isn't trusted.
+	ErrUntrustedKey = errors.New("file is signed with an untrusted key")
+
+	// ErrWrongFile is returned when a signature was made for another file than the one it's
+	// verified for, e.g. a signed index served in place of another.
+	ErrWrongFile = errors.New("signature is for another file")
+)
+
+// KeyID identifies a key. It is derived from the public key.
+type KeyID [8]byte
+
+func (id KeyID) String() string {
+	return strings.ToUpper(hex.EncodeToString(id[:]))
+}
+
+// PublicKey is a public key used to verify signatures.
+type PublicKey struct {
+	ID  KeyID
+	Key ed25519.PublicKey
+}
+
+// String returns the key in the minisign public key encoding.
+func (k PublicKey) String() string {
+	b := make([]byte, 0, 2+8+ed25519.PublicKeySize)
+	b = append(b, algorithm[:]...)
+	b = append(b, k.ID[:]...)
+	b = append(b, k.Key...)
+	return base64.StdEncoding.EncodeToString(b)
+}
+
+// File returns the contents of a minisign public key file for the key.
+func (k PublicKey) File() []byte {
+	return []byte(fmt.Sprintf("untrusted comment: registry-cli public key %s\n%s\n", k.ID, k))
+}
+
+// ParsePublicKey parses a public key, either the bare base64 encoding or the contents of a
+// public key file.
+func ParsePublicKey(s string) (PublicKey, error) {
+	encoded := ""
+	for _, line := range strings.Split(strings.TrimSpace(s), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "untrusted comment:") {
+			continue
+		}
+		encoded = line
+		break
+	}
+
+	b, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(b) != 2+8+ed25519.PublicKeySize {
+		return PublicKey{}, fmt.Errorf("invalid public key")
+	}
+	if !bytes.Equal(b[:2], algorithm[:]) {
+		return PublicKey{}, fmt.Errorf("unsupported public key algorithm %q", b[:2])
+	}
+
+	var key PublicKey
+	copy(key.ID[:], b[2:10])
+	key.Key = ed25519.PublicKey(b[10:])
+	return key, nil
+}
+
+// LoadPublicKey loads a public key from a file.
+func LoadPublicKey(path string) (PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return PublicKey{}, fmt.Errorf("failed to read public key: %w", err)
+	}
+	return ParsePublicKey(string(data))
+}
+
+// PrivateKey is a key used to sign registry files.
+type PrivateKey struct {
+	Key ed25519.PrivateKey
+}
+
+// GenerateKey creates a new random signing key.
+func GenerateKey() (*PrivateKey, error) {
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate key: %w", err)
+	}
+	return &PrivateKey{Key: priv}, nil
+}
+
+// Public returns the public half of the key.
+func (k *PrivateKey) Public() PublicKey {
+	pub := k.Key.Public().(ed25519.PublicKey)
+	sum := sha256.Sum256(pub)
+
+	var id KeyID
+	copy(id[:], sum[:8])
+	return PublicKey{ID: id, Key: pub}
+}
+
+// PEM encodes the key as a PKCS #8 PEM block.
+func (k *PrivateKey) PEM() ([]byte, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(k.Key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode private key: %w", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), nil
+}
+
+// ParsePrivateKey parses a PKCS #8 PEM encoded Ed25519 key.
+func ParsePrivateKey(data []byte) (*PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("invalid private key: no PEM block found")
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid private key: %w", err)
+	}
+	priv, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("invalid private key: expected an ed25519 key, got %T", key)
+	}
+	return &PrivateKey{Key: priv}, nil
+}
+
+// LoadPrivateKey loads a private key from a file.
+func LoadPrivateKey(path string) (*PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read signing key: %w", err)
+	}
+	return ParsePrivateKey(data)
+}
+
+// Signature is a parsed signature file.
+type Signature struct {
+	KeyID          KeyID
+	Signature      []byte
+	TrustedComment string
+	GlobalSig      []byte
+
+	// Timestamp and File are when and as which file the data was signed, read from the trusted
+	// comment. They're zero when the trusted comment doesn't record them.
+	Timestamp time.Time
+	File      string
+}
+
+// Sign signs data, returning the contents of a signature file. The trusted comment is
+// covered by the signature, and the signing time and name are recorded in it.
+func (k *PrivateKey) Sign(data []byte, name string) []byte {
+	pub := k.Public()
+	sig := ed25519.Sign(k.Key, data)
+	trusted := fmt.Sprintf("timestamp:%d\tfile:%s", time.Now().Unix(), name)
+	global := ed25519.Sign(k.Key, append(append([]byte{}, sig...), trusted...))
+
+	b := make([]byte, 0, 2+8+ed25519.SignatureSize)
+	b = append(b, algorithm[:]...)
+	b = append(b, pub.ID[:]...)
+	b = append(b, sig...)
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "untrusted comment: signature from registry-cli key %s\n", pub.ID)
+	fmt.Fprintf(&out, "%s\n", base64.StdEncoding.EncodeToString(b))
+	fmt.Fprintf(&out, "trusted comment: %s\n", trusted)
+	fmt.Fprintf(&out, "%s\n", base64.StdEncoding.EncodeToString(global))
+	return out.Bytes()
+}
+
+// ParseSignature parses the contents of a signature file.
+func ParseSignature(data []byte) (*Signature, error) {
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 4 {
+		return nil, fmt.Errorf("invalid signature: expected 4 lines, got %d", len(lines))
+	}
+
+	b, err := base64.StdEncoding.DecodeString(strings.TrimSpace(lines[1]))
+	if err != nil || len(b) != 2+8+ed25519.SignatureSize {
+		return nil, fmt.Errorf("invalid signature encoding")
+	}
+	if !bytes.Equal(b[:2], algorithm[:]) {
+		return nil, fmt.Errorf("unsupported signature algorithm %q", b[:2])
+	}
+
+	trusted, ok := strings.CutPrefix(strings.TrimRight(lines[2], "\r"), "trusted comment: ")
+	if !ok {
+		return nil, fmt.Errorf("invalid signature: missing trusted comment")
+	}
+	global, err := base64.StdEncoding.DecodeString(strings.TrimSpace(lines[3]))
+	if err != nil || len(global) != ed25519.SignatureSize {
+		return nil, fmt.Errorf("invalid signature: bad global signature")
+	}
+
+	sig := &Signature{
+		Signature:      b[10:],
+		TrustedComment: trusted,
+		GlobalSig:      global,
+	}
+	copy(sig.KeyID[:], b[2:10])
+	for _, field := range strings.Split(trusted, "\t") {
+		name, value, _ := strings.Cut(field, ":")
+		switch name {
+		case "timestamp":
+			if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+				sig.Timestamp = time.Unix(seconds, 0)
+			}
+		case "file":
+			sig.File = value
+		}
+	}
+	return sig, nil
+}
+
+// Verify checks that sig is a valid signature of data by one of the trusted keys, returning
+// the parsed signature along with its trusted comment. The file the data was signed as isn't
+// checked, see VerifyFile.
+func Verify(keys []PublicKey, data, sig []byte) (*Signature, error) {
+	if len(sig) == 0 {
+		return nil, ErrNoSignature
+	}
+	parsed, err := ParseSignature(sig)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, key := range keys {
+		if key.ID != parsed.KeyID {
+			continue
+		}
+		if !ed25519.Verify(key.Key, data, parsed.Signature) {
+			return nil, fmt.Errorf("signature verification failed for key %s", key.ID)
+		}
+		signed := append(append([]byte{}, parsed.Signature...), parsed.TrustedComment...)
+		if !ed25519.Verify(key.Key, signed, parsed.GlobalSig) {
+			return nil, fmt.Errorf("trusted comment verification failed for key %s", key.ID)
+		}
+		return parsed, nil
+	}
+
+	return nil, fmt.Errorf("%w: %s", ErrUntrustedKey, parsed.KeyID)
+}
+
+// VerifyFile checks that sig is a valid signature of data by one of the trusted keys, made for
+// the file with the given name, returning the parsed signature.
+func VerifyFile(keys []PublicKey, data, sig []byte, name string) (*Signature, error) {
+	parsed, err := Verify(keys, data, sig)
+	if err != nil {
+		return nil, err
+	}
+	if parsed.File != name {
+		return nil, fmt.Errorf("%w: signed as %q rather than %q", ErrWrongFile, parsed.File, name)
+	}
+	return parsed, nil
+}
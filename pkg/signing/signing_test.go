@@ -0,0 +1,112 @@
+package signing
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+)
+
+func generateKey(t *testing.T) *PrivateKey {
+	t.Helper()
+	key, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestSignVerify(t *testing.T) {
+	key := generateKey(t)
+	other := generateKey(t)
+	data := []byte(`{"plugins":[]}`)
+	sig := key.Sign(data, "index.json")
+
+	tampered := bytes.Clone(data)
+	tampered[0] = '['
+	// the trusted comment is covered by the global signature
+	forged := bytes.Replace(sig, []byte("file:index.json"), []byte("file:other.json"), 1)
+
+	tests := []struct {
+		name    string
+		keys    []PublicKey
+		data    []byte
+		sig     []byte
+		file    string
+		valid   bool
+		wantErr error
+	}{
+		{name: "valid", keys: []PublicKey{key.Public()}, data: data, sig: sig, file: "index.json",
+			valid: true},
+		{name: "one of the keys", keys: []PublicKey{other.Public(), key.Public()}, data: data,
+			sig: sig, file: "index.json", valid: true},
+		{name: "tampered data", keys: []PublicKey{key.Public()}, data: tampered, sig: sig,
+			file: "index.json"},
+		{name: "wrong key", keys: []PublicKey{other.Public()}, data: data, sig: sig,
+			file: "index.json", wantErr: ErrUntrustedKey},
+		{name: "wrong file", keys: []PublicKey{key.Public()}, data: data, sig: sig,
+			file: "demo/index.json", wantErr: ErrWrongFile},
+		{name: "forged trusted comment", keys: []PublicKey{key.Public()}, data: data, sig: forged,
+			file: "other.json"},
+		{name: "no signature", keys: []PublicKey{key.Public()}, data: data, file: "index.json",
+			wantErr: ErrNoSignature},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parsed, err := VerifyFile(tt.keys, tt.data, tt.sig, tt.file)
+			switch {
+			case tt.valid && err != nil:
+				t.Fatalf("expected a valid signature, got %v", err)
+			case tt.valid:
+				if parsed.File != tt.file {
+					t.Fatalf("signed as %q, want %q", parsed.File, tt.file)
+				}
+			case err == nil:
+				t.Fatal("expected the signature to be refused")
+			case tt.wantErr != nil && !errors.Is(err, tt.wantErr):
+				t.Fatalf("got %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSignatureTrustedComment(t *testing.T) {
+	key := generateKey(t)
+	before := time.Now().Truncate(time.Second)
+	sig, err := Verify([]PublicKey{key.Public()}, []byte("data"), key.Sign([]byte("data"), "a/b"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sig.File != "a/b" {
+		t.Fatalf("signed as %q, want a/b", sig.File)
+	}
+	if sig.Timestamp.Before(before) || sig.Timestamp.After(time.Now()) {
+		t.Fatalf("signed at %s, expected around %s", sig.Timestamp, before)
+	}
+	if sig.KeyID != key.Public().ID {
+		t.Fatalf("signed by %s, want %s", sig.KeyID, key.Public().ID)
+	}
+}
+
+func TestParseKeys(t *testing.T) {
+	key := generateKey(t)
+	pub, err := ParsePublicKey(string(key.Public().File()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pub.ID != key.Public().ID || !pub.Key.Equal(key.Public().Key) {
+		t.Fatal("public key changed through its file")
+	}
+
+	pem, err := key.PEM()
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := ParsePrivateKey(pem)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !parsed.Key.Equal(key.Key) {
+		t.Fatal("private key changed through its PEM encoding")
+	}
+}
@@ -0,0 +1,52 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/omniviewdev/registry-cli/pkg/console"
+	"github.com/omniviewdev/registry-cli/pkg/types"
+)
+
+// SitePrefix is the folder of the bucket the static registry site is uploaded to.
+const SitePrefix = "site/"
+
+// Indexes returns the registry index along with the index of every plugin in it.
+func (i *Indexer) Indexes(ctx context.Context) (types.RegistryIndex, []types.PluginIndex, error) {
+	registry, err := i.getRegistryIndex(ctx)
+	if err != nil {
+		return types.RegistryIndex{}, nil, err
+	}
+
+	plugins := make([]types.PluginIndex, 0, len(registry.Plugins))
+	for _, plugin := range registry.Plugins {
+		index, err := i.getPluginIndex(ctx, plugin.ID)
+		if err != nil {
+			return types.RegistryIndex{}, nil, err
+		}
+		plugins = append(plugins, index)
+	}
+	return registry, plugins, nil
+}
+
+// PublishSite uploads the given files of a generated site (relative to dir) to the site
+// folder of the bucket.
+func (i *Indexer) PublishSite(ctx context.Context, dir string, files []string) error {
+	for _, file := range files {
+		b, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(file)))
+		if err != nil {
+			return fmt.Errorf("couldn't read site file %s: %w", file, err)
+		}
+
+		bucketPath := SitePrefix + file
+		console.Printf("uploading %s...\n", bucketPath)
+		if _, err := i.storeObject(ctx, b, bucketPath, mime.TypeByExtension(path.Ext(file))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
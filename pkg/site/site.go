@@ -0,0 +1,181 @@
+// Package site renders a registry into a static website that can be browsed without the
+// Omniview client, with a page per plugin listing its versions and how to install them.
+package site
+
+import (
+	"embed"
+	"fmt"
+	"html/template"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/omniviewdev/registry-cli/pkg/types"
+)
+
+//go:embed templates
+var templates embed.FS
+
+// Opts configures site generation.
+type Opts struct {
+	// Title is the title shown on every page
+	Title string
+
+	// ArtifactBaseURL is prefixed to relative download URLs. When empty, download links are
+	// relative to the site being served from the /site/ folder of the registry bucket.
+	ArtifactBaseURL string
+}
+
+func (o *Opts) Defaulter() {
+	if o.Title == "" {
+		o.Title = "Omniview Plugin Registry"
+	}
+	if o.ArtifactBaseURL == "" {
+		// plugin pages are at site/plugins/<id>/index.html
+		o.ArtifactBaseURL = "../../../"
+	} else if !strings.HasSuffix(o.ArtifactBaseURL, "/") {
+		o.ArtifactBaseURL += "/"
+	}
+}
+
+type page struct {
+	Title     string
+	Generated time.Time
+	Root      string
+}
+
+type indexPage struct {
+	page
+	Plugins []types.RegistryIndexPlugins
+}
+
+type pluginPage struct {
+	page
+	Plugin   types.PluginIndex
+	Versions []types.PluginVersionInformation
+}
+
+// Generate renders the registry index and plugin indexes into outDir, returning the paths of
+// the files written relative to outDir.
+func Generate(
+	registry types.RegistryIndex,
+	plugins []types.PluginIndex,
+	outDir string,
+	opts Opts,
+) ([]string, error) {
+	opts.Defaulter()
+
+	tmpl, err := template.New("").Funcs(funcs(opts)).ParseFS(templates, "templates/*.html")
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse site templates: %w", err)
+	}
+
+	generated := time.Now().UTC()
+	var written []string
+
+	write := func(name, templateName string, data any) error {
+		path := filepath.Join(outDir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return fmt.Errorf("failed to create %s: %w", filepath.Dir(path), err)
+		}
+		f, err := os.Create(path)
+		if err != nil {
+			return fmt.Errorf("failed to create %s: %w", path, err)
+		}
+		defer f.Close()
+
+		if err := tmpl.ExecuteTemplate(f, templateName, data); err != nil {
+			return fmt.Errorf("failed to render %s: %w", name, err)
+		}
+		written = append(written, name)
+		return f.Close()
+	}
+
+	listed := append([]types.RegistryIndexPlugins(nil), registry.Plugins...)
+	sort.Slice(listed, func(i, j int) bool {
+		return strings.ToLower(listed[i].Name) < strings.ToLower(listed[j].Name)
+	})
+	if err := write("index.html", "index.html", indexPage{
+		page:    page{Title: opts.Title, Generated: generated, Root: ""},
+		Plugins: listed,
+	}); err != nil {
+		return nil, err
+	}
+
+	for _, plugin := range plugins {
+		if err := write("plugins/"+plugin.ID+"/index.html", "plugin.html", pluginPage{
+			page:     page{Title: opts.Title, Generated: generated, Root: "../../"},
+			Plugin:   plugin,
+			Versions: sortedVersions(plugin.Versions),
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	css, err := templates.ReadFile("templates/style.css")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(outDir, "style.css"), css, 0644); err != nil {
+		return nil, fmt.Errorf("failed to write style.css: %w", err)
+	}
+	written = append(written, "style.css")
+
+	return written, nil
+}
+
+// sortedVersions returns the versions newest first.
+func sortedVersions(versions []types.PluginVersionInformation) []types.PluginVersionInformation {
+	sorted := append([]types.PluginVersionInformation(nil), versions...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Created.After(sorted[j].Created)
+	})
+	return sorted
+}
+
+func funcs(opts Opts) template.FuncMap {
+	return template.FuncMap{
+		"download": func(url string) string {
+			if strings.Contains(url, "://") {
+				return url
+			}
+			return opts.ArtifactBaseURL + strings.TrimPrefix(url, "/")
+		},
+		"absolute": func(url string) bool {
+			return strings.Contains(url, "://")
+		},
+		"base": path.Base,
+		"archs": func(version types.PluginVersionInformation) []string {
+			archs := make([]string, 0, len(version.Architectures))
+			for arch := range version.Architectures {
+				archs = append(archs, arch)
+			}
+			sort.Strings(archs)
+			return archs
+		},
+		"platform": func(arch string) string {
+			return strings.Replace(arch, "_", "/", 1)
+		},
+		"size": func(n int64) string {
+			switch {
+			case n <= 0:
+				return ""
+			case n < 1<<10:
+				return fmt.Sprintf("%d B", n)
+			case n < 1<<20:
+				return fmt.Sprintf("%.1f KiB", float64(n)/(1<<10))
+			default:
+				return fmt.Sprintf("%.1f MiB", float64(n)/(1<<20))
+			}
+		},
+		"date": func(t time.Time) string {
+			if t.IsZero() {
+				return ""
+			}
+			return t.Format("2006-01-02")
+		},
+	}
+}
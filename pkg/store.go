@@ -0,0 +1,846 @@
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+var (
+	// ErrObjectNotFound is returned by an ObjectStore for keys it holds no object at.
+	ErrObjectNotFound = errors.New("object not found")
+
+	// ErrPreconditionFailed is returned by an ObjectStore for conditional writes whose
+	// condition doesn't hold, as when another writer got there first.
+	ErrPreconditionFailed = errors.New("precondition failed")
+
+	// ErrNotSupported is returned for operations the provider of the bucket has no counterpart
+	// for, like the bucket policies of S3.
+	ErrNotSupported = errors.New("not supported")
+)
+
+const (
+	// storeMaxAttempts is how many times a request to the store is attempted before giving up
+	storeMaxAttempts = 5
+
+	// storeMaxBackoff is the longest wait between the attempts of a request to the store
+	storeMaxBackoff = 20 * time.Second
+
+	// storeWaitTimeout is how long a stored object may take to become readable
+	storeWaitTimeout = time.Minute
+)
+
+// ObjectInfo describes an object of an ObjectStore.
+type ObjectInfo struct {
+	Key          string
+	Size         int64
+	ETag         string
+	LastModified time.Time
+
+	// StorageClass is the storage class of the object, STANDARD unless it was moved to another
+	StorageClass s3types.StorageClass
+}
+
+// PutOptions are the options an object is stored with.
+type PutOptions struct {
+	// ContentType is the content type of the object, left to the store when empty
+	ContentType string
+}
+
+// UploadOptions are the options a build is uploaded with.
+type UploadOptions struct {
+	// ContentType is the content type of the object, left to the store when empty
+	ContentType string
+
+	// StorageClass is the storage class to upload the object with, the default of the bucket
+	// when empty
+	StorageClass s3types.StorageClass
+
+	// Checksum is the hex encoded sha256 checksum of the object, which the store checks the
+	// upload against when it's given and it can, S3 only checking objects uploaded whole
+	Checksum string
+
+	// PartSize uploads objects larger than it in parts of that many bytes, Concurrency parts at
+	// once. Objects are uploaded in a single request when zero.
+	PartSize    int64
+	Concurrency int
+}
+
+// ObjectStore is the storage a registry is kept in: the builds, along with the indexes,
+// pointers and checksums. Missing objects are reported with ErrObjectNotFound, and the store
+// retries failed requests itself so its callers don't have to. Features only some providers
+// have, like the versions of objects and the settings of buckets, are optional interfaces
+// checked for with storeFeature.
+type ObjectStore interface {
+	// Get returns the contents of the object at key, to be closed by the caller.
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+
+	// Put stores the object at key, returning once it can be read back.
+	Put(ctx context.Context, key string, b []byte, opts PutOptions) error
+
+	// PutIfAbsent stores the object at key unless there's one already, failing with
+	// ErrPreconditionFailed when there is. It returns the entity tag of the object stored.
+	PutIfAbsent(ctx context.Context, key string, b []byte) (string, error)
+
+	// Upload uploads the size bytes of body to key, returning the hex encoded sha256 checksum
+	// the store computed of the object, or "" when it didn't compute one. Unlike Put, it returns
+	// without waiting for the object to be readable.
+	Upload(
+		ctx context.Context,
+		key string,
+		body io.ReadSeeker,
+		size int64,
+		opts UploadOptions,
+	) (string, error)
+
+	// Head describes the object at key.
+	Head(ctx context.Context, key string) (ObjectInfo, error)
+
+	// List describes the objects whose keys start with prefix, in the order of their keys.
+	List(ctx context.Context, prefix string) ([]ObjectInfo, error)
+
+	// Copy copies the object at src to dst.
+	Copy(ctx context.Context, src, dst string) error
+
+	// Transition moves the object at key to the storage class.
+	Transition(ctx context.Context, key string, class s3types.StorageClass) error
+
+	// Delete deletes the object at key. Deleting a missing object isn't an error.
+	Delete(ctx context.Context, key string) error
+
+	// DeleteIfMatch deletes the object at key if its entity tag is etag, failing with
+	// ErrPreconditionFailed when it isn't. Deleting a missing object isn't an error.
+	DeleteIfMatch(ctx context.Context, key, etag string) error
+
+	// Location returns where the object at key is stored, as a URL of the provider or the path
+	// of a file, for reports.
+	Location(key string) string
+
+	// Provider returns the provider hosting the bucket, one of the Provider constants.
+	Provider() string
+}
+
+// versionedStore is an ObjectStore keeping the previous versions of its objects.
+type versionedStore interface {
+	// Versioning reports whether the store keeps the previous versions of its objects.
+	Versioning(ctx context.Context) (bool, error)
+
+	// Versions lists the versions kept of the object at key, newest first.
+	Versions(ctx context.Context, key string) ([]IndexRevision, error)
+
+	// GetVersion returns the contents of a version of the object at key, to be closed by the
+	// caller.
+	GetVersion(ctx context.Context, key, versionID string) (io.ReadCloser, error)
+}
+
+// multipartStore is an ObjectStore uploading objects in parts, which are billed for until the
+// upload is completed or aborted.
+type multipartStore interface {
+	// Uploads lists the multipart uploads neither completed nor aborted, their parts left
+	// uncounted.
+	Uploads(ctx context.Context) ([]StaleUpload, error)
+
+	// Parts counts the parts uploaded by a multipart upload, and their size. An upload that's
+	// no longer in progress has none.
+	Parts(ctx context.Context, key, uploadID string) (int, int64, error)
+
+	// AbortUpload aborts a multipart upload, discarding its parts. Aborting an upload that's no
+	// longer in progress isn't an error.
+	AbortUpload(ctx context.Context, key, uploadID string) error
+}
+
+// bucketStore is an ObjectStore of a bucket whose settings the registry configures, as it does
+// for S3 buckets.
+type bucketStore interface {
+	// CheckBucket checks that the bucket exists and can be accessed.
+	CheckBucket(ctx context.Context) error
+
+	// CreateBucket creates the bucket, unless it exists already.
+	CreateBucket(ctx context.Context) error
+
+	// Versioning reports whether the bucket keeps the previous versions of its objects.
+	Versioning(ctx context.Context) (bool, error)
+
+	// EnableVersioning turns on the versioning of the objects of the bucket.
+	EnableVersioning(ctx context.Context) error
+
+	// Encryption returns the algorithm objects are encrypted with by default, "" when default
+	// encryption isn't configured.
+	Encryption(ctx context.Context) (string, error)
+
+	// Policy returns the policy of the bucket, "" when it has none, and whether it makes the
+	// bucket public.
+	Policy(ctx context.Context) (string, bool, error)
+
+	// SetPolicy sets the policy of the bucket, which makes the bucket public when public is set
+	// and blocks public access otherwise.
+	SetPolicy(ctx context.Context, policy string, public bool) error
+
+	// CORSRules returns the CORS rules of the bucket, none when it has no CORS configuration.
+	CORSRules(ctx context.Context) ([]s3types.CORSRule, error)
+
+	// SetCORSRules replaces the CORS rules of the bucket.
+	SetCORSRules(ctx context.Context, rules []s3types.CORSRule) error
+
+	// SetLifecycle replaces the lifecycle rules of the bucket.
+	SetLifecycle(ctx context.Context, rules []s3types.LifecycleRule) error
+}
+
+// presignStore is an ObjectStore handing out URLs downloading its objects without credentials.
+type presignStore interface {
+	// PresignGet returns a URL downloading the object at key that expires after expires.
+	PresignGet(ctx context.Context, key string, expires time.Duration) (string, error)
+}
+
+// storeFeature returns the store as the optional interface T, failing with ErrNotSupported
+// when the provider of the bucket has no counterpart for it. what describes the operation
+// needing it, e.g. "configuring CORS".
+func storeFeature[T any](objects ObjectStore, what string) (T, error) {
+	feature, ok := objects.(T)
+	if !ok {
+		return feature, unsupported(objects.Provider(), what)
+	}
+	return feature, nil
+}
+
+// unsupported is the error of an operation the provider of the bucket has no counterpart for.
+func unsupported(provider, what string) error {
+	return fmt.Errorf("%s is %w by the %s provider", what, ErrNotSupported, provider)
+}
+
+// storeOpts configures the ObjectStore of the bucket of a registry.
+type storeOpts struct {
+	bucket string
+
+	// provider hosts the bucket, ProviderS3 when empty
+	provider string
+
+	// endpoint is where the S3 compatible store hosting the bucket of ProviderS3 is
+	endpoint Endpoint
+
+	// pacing limits the requests made to the bucket
+	pacing Pacing
+}
+
+// newObjectStore creates the ObjectStore of the bucket, hosted by the provider of the options.
+func newObjectStore(ctx context.Context, opts storeOpts) (ObjectStore, error) {
+	provider, err := ParseProvider(opts.provider)
+	if err != nil {
+		return nil, err
+	}
+	pacer, err := newRequestPacer(opts.pacing)
+	if err != nil {
+		return nil, err
+	}
+	switch provider {
+	case ProviderFile:
+		objects, err := newFileStore(opts.bucket)
+		if err != nil {
+			return nil, err
+		}
+		return objects, nil
+	case ProviderAzure:
+		objects, err := newAzureStore(opts.bucket, pacer)
+		if err != nil {
+			return nil, err
+		}
+		return objects, nil
+	case ProviderGCS:
+		objects, err := newGCSStore(ctx, opts.bucket, pacer)
+		if err != nil {
+			return nil, err
+		}
+		return objects, nil
+	}
+	client, err := newS3Client(ctx, opts.endpoint, pacer)
+	if err != nil {
+		return nil, err
+	}
+	return newS3Store(client, opts.bucket), nil
+}
+
+// s3Store is the ObjectStore of an S3 bucket, or of any provider behind the S3 API.
+type s3Store struct {
+	client  *s3.Client
+	bucket  string
+	retryer aws.RetryerV2
+}
+
+// newS3Store returns the ObjectStore of the bucket.
+func newS3Store(client *s3.Client, bucket string) *s3Store {
+	return &s3Store{
+		client: client,
+		bucket: bucket,
+		retryer: retry.NewStandard(func(o *retry.StandardOptions) {
+			o.MaxAttempts = storeMaxAttempts
+			o.MaxBackoff = storeMaxBackoff
+			o.Backoff = storeBackoff()
+		}),
+	}
+}
+
+// storeBackoff returns the backoff between the attempts of requests to the store, and between
+// the checks for a stored object.
+func storeBackoff() retry.BackoffDelayer {
+	return retry.NewExponentialJitterBackoff(storeMaxBackoff)
+}
+
+// withRetries applies the retry policy of the store to a request.
+func (s *s3Store) withRetries(o *s3.Options) {
+	o.Retryer = s.retryer
+}
+
+func (s *s3Store) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	result, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	}, s.withRetries)
+	if err != nil {
+		return nil, notFound(err, key)
+	}
+	return result.Body, nil
+}
+
+func (s *s3Store) Put(ctx context.Context, key string, b []byte, opts PutOptions) error {
+	input := &s3.PutObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+		Body:   bytes.NewReader(b),
+	}
+	if opts.ContentType != "" {
+		input.ContentType = aws.String(opts.ContentType)
+	}
+	if _, err := s.client.PutObject(ctx, input, s.withRetries); err != nil {
+		return err
+	}
+	return waitForObject(ctx, s, key)
+}
+
+func (s *s3Store) PutIfAbsent(ctx context.Context, key string, b []byte) (string, error) {
+	result, err := s.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(s.bucket),
+		Key:         aws.String(key),
+		Body:        bytes.NewReader(b),
+		IfNoneMatch: aws.String("*"),
+	}, s.withRetries)
+	if err != nil {
+		return "", preconditionFailed(err)
+	}
+	return aws.ToString(result.ETag), nil
+}
+
+// Upload uploads the object in a single request, or with the upload manager of the SDK when
+// it's larger than the part size. S3 checks the upload against the checksum when it's given,
+// and computes it otherwise, though only objects uploaded in a single request get a checksum
+// of the whole object. A failed upload in parts is aborted.
+func (s *s3Store) Upload(
+	ctx context.Context,
+	key string,
+	body io.ReadSeeker,
+	size int64,
+	opts UploadOptions,
+) (string, error) {
+	input := &s3.PutObjectInput{
+		Bucket:        aws.String(s.bucket),
+		Key:           aws.String(key),
+		Body:          body,
+		ContentLength: aws.Int64(size),
+		StorageClass:  opts.StorageClass,
+	}
+	if opts.ContentType != "" {
+		input.ContentType = aws.String(opts.ContentType)
+	}
+
+	if opts.PartSize == 0 || size <= opts.PartSize {
+		if opts.Checksum != "" {
+			checksum, err := checksumBase64(opts.Checksum)
+			if err != nil {
+				return "", fmt.Errorf("invalid checksum for %s: %v", key, err)
+			}
+			input.ChecksumSHA256 = aws.String(checksum)
+		} else {
+			input.ChecksumAlgorithm = s3types.ChecksumAlgorithmSha256
+		}
+		output, err := s.client.PutObject(ctx, input, s.withRetries)
+		if err != nil {
+			return "", err
+		}
+		return checksumHex(aws.ToString(output.ChecksumSHA256)), nil
+	}
+
+	// parts are checked against checksums of their own, a checksum of the whole object can't
+	// be given
+	input.ChecksumAlgorithm = s3types.ChecksumAlgorithmSha256
+	uploader := manager.NewUploader(s.client, func(u *manager.Uploader) {
+		u.PartSize = opts.PartSize
+		u.Concurrency = opts.Concurrency
+		u.ClientOptions = append(u.ClientOptions, s.withRetries)
+		// aborted below instead, as the uploader can't once ctx is canceled
+		u.LeavePartsOnError = true
+	})
+	if _, err := uploader.Upload(ctx, input); err != nil {
+		var failure manager.MultiUploadFailure
+		if errors.As(err, &failure) {
+			uploadID := failure.UploadID()
+			// aborted even once ctx is canceled, so its parts aren't left to be billed for
+			abortCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), abortTimeout)
+			defer cancel()
+			if abortErr := s.AbortUpload(abortCtx, key, uploadID); abortErr != nil {
+				err = fmt.Errorf(
+					"%w (the upload %s couldn't be aborted, 'registry-cli gc' will abort it: %v)",
+					err,
+					uploadID,
+					abortErr,
+				)
+			}
+		}
+		return "", err
+	}
+	return "", nil
+}
+
+func (s *s3Store) Head(ctx context.Context, key string) (ObjectInfo, error) {
+	result, err := s.client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	}, s.withRetries)
+	if err != nil {
+		return ObjectInfo{}, notFound(err, key)
+	}
+	return ObjectInfo{
+		Key:          key,
+		Size:         aws.ToInt64(result.ContentLength),
+		ETag:         aws.ToString(result.ETag),
+		LastModified: aws.ToTime(result.LastModified),
+		StorageClass: standardClass(result.StorageClass),
+	}, nil
+}
+
+func (s *s3Store) List(ctx context.Context, prefix string) ([]ObjectInfo, error) {
+	input := &s3.ListObjectsV2Input{Bucket: aws.String(s.bucket)}
+	if prefix != "" {
+		input.Prefix = aws.String(prefix)
+	}
+	var objects []ObjectInfo
+	paginator := s3.NewListObjectsV2Paginator(s.client, input)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx, s.withRetries)
+		if err != nil {
+			return nil, err
+		}
+		for _, object := range page.Contents {
+			objects = append(objects, ObjectInfo{
+				Key:          aws.ToString(object.Key),
+				Size:         aws.ToInt64(object.Size),
+				ETag:         aws.ToString(object.ETag),
+				LastModified: aws.ToTime(object.LastModified),
+				StorageClass: standardClass(s3types.StorageClass(object.StorageClass)),
+			})
+		}
+	}
+	return objects, nil
+}
+
+func (s *s3Store) Copy(ctx context.Context, src, dst string) error {
+	_, err := s.client.CopyObject(ctx, &s3.CopyObjectInput{
+		Bucket:     aws.String(s.bucket),
+		Key:        aws.String(dst),
+		CopySource: aws.String(copySource(s.bucket, src)),
+	}, s.withRetries)
+	return notFound(err, src)
+}
+
+// Transition copies the object onto itself in the storage class.
+func (s *s3Store) Transition(ctx context.Context, key string, class s3types.StorageClass) error {
+	_, err := s.client.CopyObject(ctx, &s3.CopyObjectInput{
+		Bucket:            aws.String(s.bucket),
+		Key:               aws.String(key),
+		CopySource:        aws.String(copySource(s.bucket, key)),
+		StorageClass:      class,
+		MetadataDirective: s3types.MetadataDirectiveCopy,
+	}, s.withRetries)
+	return notFound(err, key)
+}
+
+func (s *s3Store) Delete(ctx context.Context, key string) error {
+	_, err := s.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	}, s.withRetries)
+	return err
+}
+
+func (s *s3Store) DeleteIfMatch(ctx context.Context, key, etag string) error {
+	_, err := s.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket:  aws.String(s.bucket),
+		Key:     aws.String(key),
+		IfMatch: aws.String(etag),
+	}, s.withRetries)
+	if isAPIError(err, "NoSuchKey") {
+		return nil
+	}
+	return preconditionFailed(err)
+}
+
+func (s *s3Store) Location(key string) string {
+	return fmt.Sprintf("s3://%s/%s", s.bucket, key)
+}
+
+func (s *s3Store) Provider() string {
+	return ProviderS3
+}
+
+func (s *s3Store) Versioning(ctx context.Context) (bool, error) {
+	result, err := s.client.GetBucketVersioning(ctx, &s3.GetBucketVersioningInput{
+		Bucket: aws.String(s.bucket),
+	}, s.withRetries)
+	if err != nil {
+		return false, err
+	}
+	return result.Status == s3types.BucketVersioningStatusEnabled, nil
+}
+
+func (s *s3Store) Versions(ctx context.Context, key string) ([]IndexRevision, error) {
+	var versions []IndexRevision
+	paginator := s3.NewListObjectVersionsPaginator(s.client, &s3.ListObjectVersionsInput{
+		Bucket: aws.String(s.bucket),
+		Prefix: aws.String(key),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx, s.withRetries)
+		if err != nil {
+			return nil, err
+		}
+		for _, version := range page.Versions {
+			// the prefix also matches longer keys
+			if aws.ToString(version.Key) != key {
+				continue
+			}
+			versions = append(versions, IndexRevision{
+				VersionID:    aws.ToString(version.VersionId),
+				LastModified: aws.ToTime(version.LastModified),
+				Size:         aws.ToInt64(version.Size),
+				IsLatest:     aws.ToBool(version.IsLatest),
+			})
+		}
+	}
+	// S3 lists the versions of a key newest first
+	return versions, nil
+}
+
+func (s *s3Store) GetVersion(ctx context.Context, key, versionID string) (io.ReadCloser, error) {
+	result, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket:    aws.String(s.bucket),
+		Key:       aws.String(key),
+		VersionId: aws.String(versionID),
+	}, s.withRetries)
+	if err != nil {
+		return nil, notFound(err, key)
+	}
+	return result.Body, nil
+}
+
+func (s *s3Store) Uploads(ctx context.Context) ([]StaleUpload, error) {
+	var uploads []StaleUpload
+	paginator := s3.NewListMultipartUploadsPaginator(s.client, &s3.ListMultipartUploadsInput{
+		Bucket: aws.String(s.bucket),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx, s.withRetries)
+		if err != nil {
+			return nil, err
+		}
+		for _, upload := range page.Uploads {
+			uploads = append(uploads, StaleUpload{
+				Key:       aws.ToString(upload.Key),
+				UploadID:  aws.ToString(upload.UploadId),
+				Initiated: aws.ToTime(upload.Initiated),
+			})
+		}
+	}
+	return uploads, nil
+}
+
+func (s *s3Store) Parts(ctx context.Context, key, uploadID string) (int, int64, error) {
+	var (
+		parts int
+		size  int64
+	)
+	paginator := s3.NewListPartsPaginator(s.client, &s3.ListPartsInput{
+		Bucket:   aws.String(s.bucket),
+		Key:      aws.String(key),
+		UploadId: aws.String(uploadID),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx, s.withRetries)
+		var noUpload *s3types.NoSuchUpload
+		if errors.As(err, &noUpload) {
+			// completed or aborted since it was listed
+			return 0, 0, nil
+		}
+		if err != nil {
+			return 0, 0, err
+		}
+		for _, part := range page.Parts {
+			parts++
+			size += aws.ToInt64(part.Size)
+		}
+	}
+	return parts, size, nil
+}
+
+func (s *s3Store) AbortUpload(ctx context.Context, key, uploadID string) error {
+	_, err := s.client.AbortMultipartUpload(ctx, &s3.AbortMultipartUploadInput{
+		Bucket:   aws.String(s.bucket),
+		Key:      aws.String(key),
+		UploadId: aws.String(uploadID),
+	}, s.withRetries)
+	var noUpload *s3types.NoSuchUpload
+	if errors.As(err, &noUpload) {
+		return nil
+	}
+	return err
+}
+
+func (s *s3Store) CheckBucket(ctx context.Context) error {
+	_, err := s.client.HeadBucket(ctx, &s3.HeadBucketInput{
+		Bucket: aws.String(s.bucket),
+	}, s.withRetries)
+	return err
+}
+
+func (s *s3Store) CreateBucket(ctx context.Context) error {
+	input := &s3.CreateBucketInput{Bucket: aws.String(s.bucket)}
+
+	// us-east-1 is the default location and can't be given as a constraint
+	if region := s.client.Options().Region; region != "" && region != "us-east-1" {
+		input.CreateBucketConfiguration = &s3types.CreateBucketConfiguration{
+			LocationConstraint: s3types.BucketLocationConstraint(region),
+		}
+	}
+
+	_, err := s.client.CreateBucket(ctx, input, s.withRetries)
+	var owned *s3types.BucketAlreadyOwnedByYou
+	if errors.As(err, &owned) {
+		return nil
+	}
+	return err
+}
+
+func (s *s3Store) EnableVersioning(ctx context.Context) error {
+	_, err := s.client.PutBucketVersioning(ctx, &s3.PutBucketVersioningInput{
+		Bucket: aws.String(s.bucket),
+		VersioningConfiguration: &s3types.VersioningConfiguration{
+			Status: s3types.BucketVersioningStatusEnabled,
+		},
+	}, s.withRetries)
+	return err
+}
+
+func (s *s3Store) Encryption(ctx context.Context) (string, error) {
+	result, err := s.client.GetBucketEncryption(ctx, &s3.GetBucketEncryptionInput{
+		Bucket: aws.String(s.bucket),
+	}, s.withRetries)
+	if isAPIError(err, "ServerSideEncryptionConfigurationNotFoundError") {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if result.ServerSideEncryptionConfiguration != nil {
+		for _, rule := range result.ServerSideEncryptionConfiguration.Rules {
+			if rule.ApplyServerSideEncryptionByDefault != nil {
+				return string(rule.ApplyServerSideEncryptionByDefault.SSEAlgorithm), nil
+			}
+		}
+	}
+	return "", nil
+}
+
+func (s *s3Store) Policy(ctx context.Context) (string, bool, error) {
+	status, err := s.client.GetBucketPolicyStatus(ctx, &s3.GetBucketPolicyStatusInput{
+		Bucket: aws.String(s.bucket),
+	}, s.withRetries)
+	if isAPIError(err, "NoSuchBucketPolicy") {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	public := status.PolicyStatus != nil && aws.ToBool(status.PolicyStatus.IsPublic)
+
+	policy, err := s.client.GetBucketPolicy(ctx, &s3.GetBucketPolicyInput{
+		Bucket: aws.String(s.bucket),
+	}, s.withRetries)
+	if isAPIError(err, "NoSuchBucketPolicy") {
+		return "", public, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return aws.ToString(policy.Policy), public, nil
+}
+
+// SetPolicy sets the policy of the bucket, lifting the public access block first when the
+// policy makes the bucket public.
+func (s *s3Store) SetPolicy(ctx context.Context, policy string, public bool) error {
+	block := !public
+	_, err := s.client.PutPublicAccessBlock(ctx, &s3.PutPublicAccessBlockInput{
+		Bucket: aws.String(s.bucket),
+		PublicAccessBlockConfiguration: &s3types.PublicAccessBlockConfiguration{
+			BlockPublicAcls:       aws.Bool(true),
+			IgnorePublicAcls:      aws.Bool(true),
+			BlockPublicPolicy:     aws.Bool(block),
+			RestrictPublicBuckets: aws.Bool(block),
+		},
+	}, s.withRetries)
+	if err != nil {
+		return fmt.Errorf("couldn't set the public access block: %v", err)
+	}
+
+	_, err = s.client.PutBucketPolicy(ctx, &s3.PutBucketPolicyInput{
+		Bucket: aws.String(s.bucket),
+		Policy: aws.String(policy),
+	}, s.withRetries)
+	return err
+}
+
+func (s *s3Store) CORSRules(ctx context.Context) ([]s3types.CORSRule, error) {
+	result, err := s.client.GetBucketCors(ctx, &s3.GetBucketCorsInput{
+		Bucket: aws.String(s.bucket),
+	}, s.withRetries)
+	if isAPIError(err, "NoSuchCORSConfiguration") {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return result.CORSRules, nil
+}
+
+func (s *s3Store) SetCORSRules(ctx context.Context, rules []s3types.CORSRule) error {
+	_, err := s.client.PutBucketCors(ctx, &s3.PutBucketCorsInput{
+		Bucket:            aws.String(s.bucket),
+		CORSConfiguration: &s3types.CORSConfiguration{CORSRules: rules},
+	}, s.withRetries)
+	return err
+}
+
+func (s *s3Store) SetLifecycle(ctx context.Context, rules []s3types.LifecycleRule) error {
+	_, err := s.client.PutBucketLifecycleConfiguration(
+		ctx,
+		&s3.PutBucketLifecycleConfigurationInput{
+			Bucket:                 aws.String(s.bucket),
+			LifecycleConfiguration: &s3types.BucketLifecycleConfiguration{Rules: rules},
+		},
+		s.withRetries,
+	)
+	return err
+}
+
+func (s *s3Store) PresignGet(
+	ctx context.Context,
+	key string,
+	expires time.Duration,
+) (string, error) {
+	req, err := s3.NewPresignClient(s.client).PresignGetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	}, s3.WithPresignExpires(expires))
+	if err != nil {
+		return "", err
+	}
+	return req.URL, nil
+}
+
+// notFound reports the errors of S3 for missing objects as ErrObjectNotFound.
+func notFound(err error, key string) error {
+	var noKey *s3types.NoSuchKey
+	var missing *s3types.NotFound
+	if errors.As(err, &noKey) || errors.As(err, &missing) {
+		return fmt.Errorf("%s: %w", key, ErrObjectNotFound)
+	}
+	return err
+}
+
+// preconditionFailed reports the errors of S3 for failed conditional writes as
+// ErrPreconditionFailed.
+func preconditionFailed(err error) error {
+	if isAPIError(err, "PreconditionFailed", "ConditionalRequestConflict") {
+		return fmt.Errorf("%w: %v", ErrPreconditionFailed, err)
+	}
+	return err
+}
+
+// standardClass returns the storage class of an object, which S3 omits for standard storage.
+func standardClass(class s3types.StorageClass) s3types.StorageClass {
+	if class == "" {
+		return s3types.StorageClassStandard
+	}
+	return class
+}
+
+// copySource builds the url encoded source of a copy within the bucket
+func copySource(bucket, key string) string {
+	segments := strings.Split(bucket+"/"+key, "/")
+	for idx, segment := range segments {
+		segments[idx] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+// checksumHex converts a base64 sha256 checksum of S3 to the hex form of the indexes, "" for
+// an invalid or missing one.
+func checksumHex(checksum string) string {
+	b, err := base64.StdEncoding.DecodeString(checksum)
+	if err != nil || len(b) == 0 {
+		return ""
+	}
+	return hex.EncodeToString(b)
+}
+
+// waitForObject waits for an object just stored to be readable, checking for it with the
+// backoff of the store.
+func waitForObject(ctx context.Context, objects ObjectStore, key string) error {
+	ctx, cancel := context.WithTimeout(ctx, storeWaitTimeout)
+	defer cancel()
+	backoff := storeBackoff()
+	for attempt := 1; ; attempt++ {
+		_, err := objects.Head(ctx, key)
+		if !errors.Is(err, ErrObjectNotFound) {
+			return err
+		}
+		delay, err := backoff.BackoffDelay(attempt, err)
+		if err != nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%s isn't readable yet: %w", key, ctx.Err())
+		case <-time.After(delay):
+		}
+	}
+}
+
+// readObject reads the whole object at key.
+func readObject(ctx context.Context, objects ObjectStore, key string) ([]byte, error) {
+	body, err := objects.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	return io.ReadAll(body)
+}
@@ -0,0 +1,302 @@
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// memObject is an object of a memStore.
+type memObject struct {
+	b        []byte
+	etag     string
+	class    s3types.StorageClass
+	modified time.Time
+}
+
+// memStore is an ObjectStore held in memory, with none of the optional features.
+type memStore struct {
+	mu      sync.Mutex
+	objects map[string]memObject
+	version int
+
+	// failUpload fails the uploads of the keys it returns an error for, when set
+	failUpload func(key string) error
+
+	// uncheckedUploads ignores the checksum of uploads and computes none, as S3 does for
+	// uploads in parts and GCS for any
+	uncheckedUploads bool
+}
+
+func newMemStore() *memStore {
+	return &memStore{objects: make(map[string]memObject)}
+}
+
+// put stores the object, the caller holding the lock.
+func (m *memStore) put(key string, b []byte, class s3types.StorageClass) string {
+	m.version++
+	etag := fmt.Sprintf(`"%d"`, m.version)
+	if class == "" {
+		class = s3types.StorageClassStandard
+	}
+	m.objects[key] = memObject{b: slices.Clone(b), etag: etag, class: class, modified: time.Now()}
+	return etag
+}
+
+// keys returns the keys of the objects stored, sorted.
+func (m *memStore) keys() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	keys := make([]string, 0, len(m.objects))
+	for key := range m.objects {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// read returns the object at key, failing the test when there's none.
+func read(t *testing.T, objects ObjectStore, key string) []byte {
+	t.Helper()
+	b, err := readObject(t.Context(), objects, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func (m *memStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	object, ok := m.objects[key]
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", key, ErrObjectNotFound)
+	}
+	return io.NopCloser(bytes.NewReader(object.b)), nil
+}
+
+func (m *memStore) Put(ctx context.Context, key string, b []byte, opts PutOptions) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.put(key, b, "")
+	return nil
+}
+
+func (m *memStore) PutIfAbsent(ctx context.Context, key string, b []byte) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.objects[key]; ok {
+		return "", ErrPreconditionFailed
+	}
+	return m.put(key, b, ""), nil
+}
+
+func (m *memStore) Upload(
+	ctx context.Context,
+	key string,
+	body io.ReadSeeker,
+	size int64,
+	opts UploadOptions,
+) (string, error) {
+	if m.failUpload != nil {
+		if err := m.failUpload(key); err != nil {
+			return "", err
+		}
+	}
+	b, err := io.ReadAll(body)
+	if err != nil {
+		return "", err
+	}
+	if int64(len(b)) != size {
+		return "", fmt.Errorf("read %d bytes of %s, want %d", len(b), key, size)
+	}
+	sum := sha256.Sum256(b)
+	checksum := hex.EncodeToString(sum[:])
+	if m.uncheckedUploads {
+		checksum = ""
+	} else if opts.Checksum != "" && opts.Checksum != checksum {
+		return "", fmt.Errorf("checksum mismatch for %s", key)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.put(key, b, opts.StorageClass)
+	return checksum, nil
+}
+
+func (m *memStore) Head(ctx context.Context, key string) (ObjectInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	object, ok := m.objects[key]
+	if !ok {
+		return ObjectInfo{}, fmt.Errorf("%s: %w", key, ErrObjectNotFound)
+	}
+	return object.info(key), nil
+}
+
+func (m *memStore) List(ctx context.Context, prefix string) ([]ObjectInfo, error) {
+	var objects []ObjectInfo
+	for _, key := range m.keys() {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		m.mu.Lock()
+		objects = append(objects, m.objects[key].info(key))
+		m.mu.Unlock()
+	}
+	return objects, nil
+}
+
+func (m *memStore) Copy(ctx context.Context, src, dst string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	object, ok := m.objects[src]
+	if !ok {
+		return fmt.Errorf("%s: %w", src, ErrObjectNotFound)
+	}
+	m.put(dst, object.b, object.class)
+	return nil
+}
+
+func (m *memStore) Transition(ctx context.Context, key string, class s3types.StorageClass) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	object, ok := m.objects[key]
+	if !ok {
+		return fmt.Errorf("%s: %w", key, ErrObjectNotFound)
+	}
+	m.put(key, object.b, class)
+	return nil
+}
+
+func (m *memStore) Delete(ctx context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.objects, key)
+	return nil
+}
+
+func (m *memStore) DeleteIfMatch(ctx context.Context, key, etag string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	object, ok := m.objects[key]
+	if !ok {
+		return nil
+	}
+	if object.etag != etag {
+		return ErrPreconditionFailed
+	}
+	delete(m.objects, key)
+	return nil
+}
+
+func (m *memStore) Location(key string) string {
+	return "mem://" + key
+}
+
+func (m *memStore) Provider() string {
+	return "memory"
+}
+
+func (o memObject) info(key string) ObjectInfo {
+	return ObjectInfo{
+		Key:          key,
+		Size:         int64(len(o.b)),
+		ETag:         o.etag,
+		LastModified: o.modified,
+		StorageClass: o.class,
+	}
+}
+
+func TestStoreFeature(t *testing.T) {
+	objects := newMemStore()
+	i := &Indexer{objects: objects, bucket: "test"}
+	ctx := t.Context()
+
+	unsupported := []struct {
+		name string
+		run  func() error
+	}{
+		{name: "cors", run: func() error { return i.ApplyCORS(ctx, nil) }},
+		{name: "stale uploads", run: func() error {
+			_, err := i.AbortStaleUploads(ctx, time.Hour, true)
+			return err
+		}},
+		{name: "presign", run: func() error {
+			_, err := i.Presign(ctx, "demo", "", "linux_amd64", "", time.Hour)
+			return err
+		}},
+		{name: "revisions", run: func() error {
+			_, err := i.IndexRevisions(ctx, "demo")
+			return err
+		}},
+	}
+	for _, tt := range unsupported {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.run()
+			if !errors.Is(err, ErrNotSupported) {
+				t.Fatalf("got %v, want %v", err, ErrNotSupported)
+			}
+		})
+	}
+}
+
+func TestObjectLock(t *testing.T) {
+	ctx := t.Context()
+	objects := newMemStore()
+
+	held := &objectLock{objects: objects, ttl: time.Minute}
+	if err := held.acquire(ctx); err != nil {
+		t.Fatal(err)
+	}
+	waitCtx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer cancel()
+	other := &objectLock{objects: objects, ttl: time.Minute}
+	if err := other.acquire(waitCtx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("acquired a held lock: %v", err)
+	}
+
+	// a lock taken since isn't released by its previous holder
+	stale := *held
+	if err := held.release(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := other.acquire(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := stale.release(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := objects.Head(ctx, lockKey); err != nil {
+		t.Fatalf("the lock was released by its previous holder: %v", err)
+	}
+	if err := other.release(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := objects.Head(ctx, lockKey); !errors.Is(err, ErrObjectNotFound) {
+		t.Fatalf("the lock wasn't released: %v", err)
+	}
+
+	// a lock abandoned by its holder is broken once its lease expires
+	abandoned := &objectLock{objects: objects, ttl: -time.Minute}
+	if err := abandoned.acquire(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := other.acquire(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if other.etag == abandoned.etag {
+		t.Fatal("the abandoned lock wasn't broken")
+	}
+}
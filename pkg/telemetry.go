@@ -0,0 +1,161 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/metric"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Telemetry configures the export of the metrics and traces of the pipeline (publish durations,
+// upload throughput, index update latency, ...) to an OTLP endpoint, so teams running a
+// registry from CI can monitor it. Telemetry is off unless an endpoint is set.
+type Telemetry struct {
+	// Endpoint is the base URL of the OTLP/HTTP endpoint to export to, e.g.
+	// http://localhost:4318. Falls back to OTEL_EXPORTER_OTLP_ENDPOINT.
+	Endpoint string
+
+	// Headers are added to the export requests, e.g. for authentication
+	Headers map[string]string
+}
+
+const instrumentation = "github.com/omniviewdev/registry-cli"
+
+var (
+	tracer = otel.Tracer(instrumentation)
+	meter  = otel.Meter(instrumentation)
+
+	publishDuration = must(meter.Float64Histogram(
+		"registry.publish.duration",
+		metric.WithUnit("s"),
+		metric.WithDescription("Duration of publishes, from vetting the builds to indexing them"),
+	))
+	uploadThroughput = must(meter.Float64Histogram(
+		"registry.upload.throughput",
+		metric.WithUnit("By/s"),
+		metric.WithDescription("Throughput of the uploads of builds to the bucket"),
+	))
+	uploadSize = must(meter.Int64Counter(
+		"registry.upload.size",
+		metric.WithUnit("By"),
+		metric.WithDescription("Bytes of builds uploaded to the bucket"),
+	))
+	indexUpdateDuration = must(meter.Float64Histogram(
+		"registry.index.update.duration",
+		metric.WithUnit("s"),
+		metric.WithDescription(
+			"Latency of the index updates of publishes, including waiting for the lock",
+		),
+	))
+)
+
+// must returns the instrument, which the global meter only fails to create for invalid names.
+func must[T any](instrument T, err error) T {
+	if err != nil {
+		panic(err)
+	}
+	return instrument
+}
+
+// stopTelemetry flushes and stops the exporters, nil when telemetry is off
+var stopTelemetry func(context.Context) error
+
+// StartTelemetry starts exporting the metrics and traces of the pipeline. It does nothing when
+// neither the endpoint nor OTEL_EXPORTER_OTLP_ENDPOINT is set.
+func StartTelemetry(ctx context.Context, telemetry Telemetry) error {
+	if telemetry.Endpoint == "" && os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") == "" {
+		return nil
+	}
+
+	// the endpoint is a base URL like OTEL_EXPORTER_OTLP_ENDPOINT, each signal has its own path
+	traceOpts := []otlptracehttp.Option{otlptracehttp.WithHeaders(telemetry.Headers)}
+	metricOpts := []otlpmetrichttp.Option{otlpmetrichttp.WithHeaders(telemetry.Headers)}
+	if telemetry.Endpoint != "" {
+		base := strings.TrimSuffix(telemetry.Endpoint, "/")
+		traceOpts = append(traceOpts, otlptracehttp.WithEndpointURL(base+"/v1/traces"))
+		metricOpts = append(metricOpts, otlpmetrichttp.WithEndpointURL(base+"/v1/metrics"))
+	}
+
+	res, err := resource.New(
+		ctx,
+		resource.WithAttributes(attribute.String("service.name", "registry-cli")),
+		resource.WithTelemetrySDK(),
+		// OTEL_SERVICE_NAME and OTEL_RESOURCE_ATTRIBUTES override the defaults
+		resource.WithFromEnv(),
+	)
+	if err != nil {
+		return fmt.Errorf("couldn't set up telemetry: %w", err)
+	}
+	traceExporter, err := otlptracehttp.New(ctx, traceOpts...)
+	if err != nil {
+		return fmt.Errorf("couldn't set up the trace exporter: %w", err)
+	}
+	metricExporter, err := otlpmetrichttp.New(ctx, metricOpts...)
+	if err != nil {
+		return fmt.Errorf("couldn't set up the metric exporter: %w", err)
+	}
+
+	tracerProvider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(traceExporter),
+		sdktrace.WithResource(res),
+	)
+	meterProvider := sdkmetric.NewMeterProvider(
+		sdkmetric.WithReader(sdkmetric.NewPeriodicReader(metricExporter)),
+		sdkmetric.WithResource(res),
+	)
+	otel.SetTracerProvider(tracerProvider)
+	otel.SetMeterProvider(meterProvider)
+	stopTelemetry = func(ctx context.Context) error {
+		return errors.Join(tracerProvider.Shutdown(ctx), meterProvider.Shutdown(ctx))
+	}
+	return nil
+}
+
+// StopTelemetry exports what's left of the metrics and traces, and stops the exporters. The CLI
+// runs briefly, so it must be called before it exits for anything to be exported.
+func StopTelemetry(ctx context.Context) error {
+	if stopTelemetry == nil {
+		return nil
+	}
+	err := stopTelemetry(ctx)
+	stopTelemetry = nil
+	if err != nil {
+		return fmt.Errorf("couldn't export telemetry: %w", err)
+	}
+	return nil
+}
+
+// endSpan ends the span, recording the error it ended with.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// outcome describes how an operation ended, for the attributes of its metrics.
+func outcome(err error) attribute.KeyValue {
+	if err != nil {
+		return attribute.String("outcome", "error")
+	}
+	return attribute.String("outcome", "ok")
+}
+
+// since returns the seconds elapsed since start, for duration metrics.
+func since(start time.Time) float64 {
+	return time.Since(start).Seconds()
+}
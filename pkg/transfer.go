@@ -0,0 +1,146 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/omniviewdev/registry-cli/pkg/types"
+)
+
+// Transfer describes a plugin moved to a new ID (or that would be, for a dry run).
+type Transfer struct {
+	From string
+	To   string
+
+	// Artifacts are the tarballs of the plugin, copied to the keys of the new ID
+	Artifacts []ArtifactCopy
+
+	// Pruned reports whether the tarballs at the old keys were deleted
+	Pruned bool
+}
+
+// ArtifactCopy is a tarball copied from one bucket path to another.
+type ArtifactCopy struct {
+	From string
+	To   string
+}
+
+// TransferPlugin moves a plugin to a new ID: its tarballs are copied to the keys of the new ID,
+// its index is rewritten under the new ID, and the registry index lists it under the new ID
+// only. The old ID is left a tombstone naming the new ID as its successor, so clients can find
+// the plugin under its new ID. The tarballs at the old keys are kept for existing installs to
+// keep downloading them, unless prune is set. The new ID mustn't already have versions.
+func (i *Indexer) TransferPlugin(
+	ctx context.Context,
+	from, to string,
+	prune, dryRun bool,
+) (Transfer, error) {
+	transfer := Transfer{From: from, To: to, Pruned: prune && !dryRun}
+	if from == to {
+		return transfer, fmt.Errorf("%s is already the id of the plugin", to)
+	}
+	// clients install plugins into a directory named after the id
+	if !filepath.IsLocal(to) || strings.ContainsAny(to, `/\`) {
+		return transfer, fmt.Errorf("invalid plugin id %q", to)
+	}
+
+	err := i.withLock(ctx, func() error {
+		index, err := i.loadPluginIndex(ctx, from)
+		if err != nil {
+			return err
+		}
+		if index.Tombstone != nil && index.Tombstone.Successor != "" {
+			return fmt.Errorf("%s was already transferred to %s", from, index.Tombstone.Successor)
+		}
+		if len(index.Versions) == 0 {
+			return fmt.Errorf("%s has no versions to transfer", from)
+		}
+		existing, err := i.loadPluginIndex(ctx, to)
+		if err != nil {
+			return err
+		}
+		if len(existing.Versions) > 0 {
+			return fmt.Errorf("%s already has versions, pick another id", to)
+		}
+
+		moved := index
+		moved.ID = to
+		moved.Versions = make([]types.PluginVersionInformation, 0, len(index.Versions))
+		for _, version := range index.Versions {
+			version, copies := i.transferVersion(version, to)
+			moved.Versions = append(moved.Versions, version)
+			transfer.Artifacts = append(transfer.Artifacts, copies...)
+		}
+		moved.LatestVersion = moved.Latest()
+		if dryRun {
+			return nil
+		}
+
+		for _, artifact := range transfer.Artifacts {
+			if err := i.copy(ctx, artifact.From, artifact.To); err != nil {
+				return err
+			}
+			err := i.copy(ctx, artifact.From+types.ChecksumExt, artifact.To+types.ChecksumExt)
+			if err != nil {
+				return err
+			}
+		}
+
+		if err := i.commitPluginIndex(ctx, "transfer from "+from, existing, moved); err != nil {
+			return err
+		}
+		emptied := index
+		emptied.Versions = nil
+		emptied.Tombstone = &types.Tombstone{Reason: "transferred to " + to, Successor: to}
+		if err := i.commitPluginIndex(ctx, "transfer to "+to, index, emptied); err != nil {
+			return err
+		}
+
+		if !prune {
+			return nil
+		}
+		old := make([]string, 0, len(transfer.Artifacts))
+		for _, artifact := range transfer.Artifacts {
+			old = append(old, artifact.From)
+		}
+		return i.deleteArtifacts(ctx, old)
+	})
+	return transfer, err
+}
+
+// transferVersion rewrites a version of a plugin for its new ID, returning the tarballs to copy
+// to the keys of the new ID.
+func (i *Indexer) transferVersion(
+	version types.PluginVersionInformation,
+	to string,
+) (types.PluginVersionInformation, []ArtifactCopy) {
+	var copies []ArtifactCopy
+	archs := make(map[string]types.PluginArchitectureInformation, len(version.Architectures))
+	for _, arch := range sortedArchs(version) {
+		info := version.Architectures[arch]
+		goos, goarch, _ := strings.Cut(arch, "_")
+		release := types.Release{
+			Plugin:  to,
+			Version: version.Version,
+			OS:      goos,
+			Arch:    goarch,
+			Created: version.Created,
+		}
+		artifact := ArtifactCopy{
+			From: i.artifactKey(info.DownloadURL),
+			To:   i.layout.ArtifactPath(release),
+		}
+		copies = append(copies, artifact)
+
+		info.DownloadURL = i.downloadURL(artifact.To)
+		if info.ChecksumURL != "" {
+			info.ChecksumURL = i.downloadURL(artifact.To + types.ChecksumExt)
+		}
+		archs[arch] = info
+	}
+	version.Architectures = archs
+	version.Metadata.ID = to
+	return version, copies
+}
@@ -0,0 +1,53 @@
+package types
+
+import "strings"
+
+// archAliases maps the names other build systems give architectures to the Go names the
+// registry keys builds by.
+var archAliases = map[string]string{
+	"x86_64":  "amd64",
+	"x86-64":  "amd64",
+	"x64":     "amd64",
+	"aarch64": "arm64",
+	"armv8":   "arm64",
+}
+
+// NormalizeArch returns the Go name of an architecture (e.g. amd64 for x86_64), so builds
+// named by other build systems map to the same index keys.
+func NormalizeArch(arch string) string {
+	arch = strings.ToLower(strings.TrimSpace(arch))
+	if alias, ok := archAliases[arch]; ok {
+		return alias
+	}
+	return arch
+}
+
+// NormalizePlatform returns the architecture key (os_arch) of a platform, with the
+// architecture normalized. The OS and architecture may also be separated by a dash or a slash,
+// e.g. linux-x86_64 and linux/aarch64 are linux_amd64 and linux_arm64.
+func NormalizePlatform(platform string) string {
+	platform = strings.ToLower(strings.TrimSpace(platform))
+	sep := strings.IndexAny(platform, "_-/")
+	if sep == -1 {
+		return platform
+	}
+	return platform[:sep] + "_" + NormalizeArch(platform[sep+1:])
+}
+
+// Architecture returns the build of the version for a platform, matching the architecture
+// keys of the index once normalized, so indexes written with other architecture names still
+// resolve.
+func (v PluginVersionInformation) Architecture(
+	platform string,
+) (PluginArchitectureInformation, bool) {
+	platform = NormalizePlatform(platform)
+	if info, ok := v.Architectures[platform]; ok {
+		return info, true
+	}
+	for arch, info := range v.Architectures {
+		if NormalizePlatform(arch) == platform {
+			return info, true
+		}
+	}
+	return PluginArchitectureInformation{}, false
+}
@@ -0,0 +1,99 @@
+package types
+
+import "testing"
+
+func TestNormalizeArch(t *testing.T) {
+	tests := map[string]string{
+		"amd64":    "amd64",
+		"x86_64":   "amd64",
+		"X86_64":   "amd64",
+		"x86-64":   "amd64",
+		"x64":      "amd64",
+		" amd64 ":  "amd64",
+		"arm64":    "arm64",
+		"aarch64":  "arm64",
+		"AArch64":  "arm64",
+		"armv8":    "arm64",
+		"riscv64":  "riscv64",
+		"":         "",
+		"unknown9": "unknown9",
+	}
+	for arch, want := range tests {
+		if got := NormalizeArch(arch); got != want {
+			t.Errorf("NormalizeArch(%q) = %q, want %q", arch, got, want)
+		}
+	}
+}
+
+func TestNormalizePlatform(t *testing.T) {
+	tests := map[string]string{
+		"linux_amd64":    "linux_amd64",
+		"linux-x86_64":   "linux_amd64",
+		"linux/aarch64":  "linux_arm64",
+		"Darwin_ARM64":   "darwin_arm64",
+		"darwin-aarch64": "darwin_arm64",
+		"windows_x64":    "windows_amd64",
+		"linux":          "linux",
+	}
+	for platform, want := range tests {
+		if got := NormalizePlatform(platform); got != want {
+			t.Errorf("NormalizePlatform(%q) = %q, want %q", platform, got, want)
+		}
+	}
+}
+
+func TestVersionArchitecture(t *testing.T) {
+	version := PluginVersionInformation{Architectures: map[string]PluginArchitectureInformation{
+		"linux_amd64":    {Checksum: "linux"},
+		"darwin-aarch64": {Checksum: "darwin"},
+	}}
+	tests := []struct {
+		platform string
+		want     string
+		found    bool
+	}{
+		{platform: "linux_amd64", want: "linux", found: true},
+		{platform: "linux-x86_64", want: "linux", found: true},
+		// indexes written with other names still resolve
+		{platform: "darwin_arm64", want: "darwin", found: true},
+		{platform: "windows_amd64"},
+	}
+	for _, tt := range tests {
+		info, ok := version.Architecture(tt.platform)
+		if ok != tt.found || info.Checksum != tt.want {
+			t.Errorf(
+				"Architecture(%q) = %q, %v, want %q, %v",
+				tt.platform,
+				info.Checksum,
+				ok,
+				tt.want,
+				tt.found,
+			)
+		}
+	}
+}
+
+func TestSetPlatform(t *testing.T) {
+	var opts PublishOpts
+	for platform, path := range map[string]string{
+		"linux-x86_64":  "linux.tar.gz",
+		"darwin/arm64":  "darwin.tar.gz",
+		"windows_amd64": "windows.tar.gz",
+	} {
+		if err := opts.SetPlatform(platform, path); err != nil {
+			t.Fatalf("SetPlatform(%q): %v", platform, err)
+		}
+	}
+	if opts.LinuxAMD64 != "linux.tar.gz" || opts.DarwinARM64 != "darwin.tar.gz" ||
+		opts.WindowsAMD64 != "windows.tar.gz" {
+		t.Fatalf("platforms set to the wrong builds: %+v", opts)
+	}
+	if err := opts.SetPlatform("plan9_amd64", "plan9.tar.gz"); err == nil {
+		t.Fatal("expected an unsupported platform to be refused")
+	}
+
+	release := Release{Plugin: "demo", Version: "1.0.0", OS: "linux", Arch: "x86_64"}
+	if got := release.OSArch(); got != "linux_amd64" {
+		t.Fatalf("OSArch() = %q, want linux_amd64", got)
+	}
+}
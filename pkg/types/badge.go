@@ -0,0 +1,45 @@
+package types
+
+import "fmt"
+
+// BadgeColor is the color of the version badge
+const BadgeColor = "blue"
+
+// Badge is a badge in the shields.io endpoint format (https://shields.io/badges/endpoint-badge),
+// served from the registry so plugin READMEs can show the latest published version.
+type Badge struct {
+	// SchemaVersion is always 1
+	SchemaVersion int `json:"schemaVersion"`
+
+	// Label is the text on the left of the badge
+	Label string `json:"label"`
+
+	// Message is the text on the right of the badge
+	Message string `json:"message"`
+
+	// Color is the background color of the message
+	Color string `json:"color"`
+}
+
+// NewVersionBadge creates the badge for the latest version of the plugin.
+func NewVersionBadge(index PluginIndex) Badge {
+	return Badge{
+		SchemaVersion: 1,
+		Label:         "omniview",
+		Message:       "v" + trimV(index.LatestVersion.Version),
+		Color:         BadgeColor,
+	}
+}
+
+// VersionBadgePath gets the bucket path for the version badge of a plugin. It's the same
+// whatever the layout of the registry.
+func VersionBadgePath(plugin string) string {
+	return fmt.Sprintf("badges/%s/version.json", plugin)
+}
+
+func trimV(version string) string {
+	if len(version) > 0 && version[0] == 'v' {
+		return version[1:]
+	}
+	return version
+}
@@ -0,0 +1,47 @@
+package types
+
+import "fmt"
+
+const (
+	// ChannelStable follows the latest release that isn't a prerelease
+	ChannelStable = "stable"
+
+	// ChannelBeta follows the latest release, prereleases included
+	ChannelBeta = "beta"
+)
+
+// Channels lists the release channels maintained for every plugin
+var Channels = []string{ChannelStable, ChannelBeta}
+
+// ChannelVersion returns the highest version of the index that hasn't been yanked and belongs to
+// the channel, and false if there is none.
+func (i PluginIndex) ChannelVersion(channel string) (PluginVersionInformation, bool) {
+	var latest PluginVersionInformation
+	for _, version := range i.Versions {
+		if version.Yanked || (channel == ChannelStable && IsPrerelease(version.Version)) {
+			continue
+		}
+		if latest.Version == "" || CompareVersions(version.Version, latest.Version) > 0 {
+			latest = version
+		}
+	}
+	return latest, latest.Version != ""
+}
+
+// NewChannelPointer creates the pointer to the version the channel of the plugin resolves to,
+// and false if no version belongs to the channel.
+func NewChannelPointer(index PluginIndex, channel string) (LatestVersion, bool) {
+	version, ok := index.ChannelVersion(channel)
+	if !ok {
+		return LatestVersion{}, false
+	}
+	pointer := newVersionPointer(index.ID, version)
+	pointer.Channel = channel
+	return pointer, true
+}
+
+// ChannelPath gets the bucket path for the pointer of a channel of a plugin. It's the same
+// whatever the layout of the registry.
+func ChannelPath(plugin, channel string) string {
+	return fmt.Sprintf("channels/%s/%s.json", plugin, channel)
+}
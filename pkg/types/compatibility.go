@@ -0,0 +1,93 @@
+package types
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+)
+
+const (
+	// CompatibilityTested marks an Omniview core version a release was tested against
+	CompatibilityTested = "tested"
+
+	// CompatibilityFailed marks an Omniview core version a release failed testing against
+	CompatibilityFailed = "failed"
+)
+
+// Compatibility records the Omniview core versions (or ranges, like 1.0.x) a release is known
+// to work with, and how that's known.
+type Compatibility map[string]string
+
+// ParseTestedWith parses a list of core versions or ranges (e.g. 0.9.x, 1.0.x) into a
+// compatibility map, marking each as tested.
+func ParseTestedWith(versions []string) (Compatibility, error) {
+	if len(versions) == 0 {
+		return nil, nil
+	}
+
+	compatibility := make(Compatibility, len(versions))
+	for _, version := range versions {
+		version = strings.TrimSpace(version)
+		if version == "" {
+			continue
+		}
+		if _, err := semver.NewConstraint(version); err != nil {
+			return nil, fmt.Errorf("invalid core version %q: %w", version, err)
+		}
+		compatibility[version] = CompatibilityTested
+	}
+	return compatibility, nil
+}
+
+// Versions returns the core versions in the map, in version order.
+func (c Compatibility) Versions() []string {
+	versions := make([]string, 0, len(c))
+	for version := range c {
+		versions = append(versions, version)
+	}
+	slices.SortFunc(versions, func(a, b string) int {
+		// compare ranges by the lowest version they allow
+		return CompareVersions(rangeFloor(a), rangeFloor(b))
+	})
+	return versions
+}
+
+// Merge adds the entries of other to the map, replacing existing entries for the same versions.
+func (c Compatibility) Merge(other Compatibility) Compatibility {
+	if len(other) == 0 {
+		return c
+	}
+	merged := make(Compatibility, len(c)+len(other))
+	for version, status := range c {
+		merged[version] = status
+	}
+	for version, status := range other {
+		merged[version] = status
+	}
+	return merged
+}
+
+// Supports reports whether the core version falls in any of the versions or ranges the release
+// was tested against.
+func (c Compatibility) Supports(core string) bool {
+	v, err := semver.NewVersion(core)
+	if err != nil {
+		return false
+	}
+	for version, status := range c {
+		if status != CompatibilityTested {
+			continue
+		}
+		constraint, err := semver.NewConstraint(version)
+		if err == nil && constraint.Check(v) {
+			return true
+		}
+	}
+	return false
+}
+
+func rangeFloor(version string) string {
+	return strings.NewReplacer("x", "0", "X", "0", "*", "0").Replace(version)
+}
@@ -0,0 +1,32 @@
+package types
+
+import "time"
+
+// FederationPath is the bucket path of the federated index, at the root of the bucket next to
+// the registry index.
+const FederationPath = "federation.json"
+
+// FederationIndex is a catalog of the plugins of several registries, for aggregating internal
+// and public plugins into one. Each plugin references the registry it's served from.
+type FederationIndex struct {
+	// Generated is when the index was generated
+	Generated time.Time `json:"generated"`
+
+	// Registries are the URLs of the federated registries, highest priority first
+	Registries []string `json:"registries"`
+
+	// Plugins lists the plugins of every registry, each from the highest priority registry
+	// listing it
+	Plugins []FederatedPlugin `json:"plugins"`
+}
+
+// FederatedPlugin is a plugin of a federated registry. Its download URLs are absolute.
+type FederatedPlugin struct {
+	RegistryIndexPlugins
+
+	// Source is the URL of the registry serving the plugin
+	Source string `json:"source"`
+
+	// IndexURL is the URL of the plugin's index in its registry
+	IndexURL string `json:"index_url"`
+}
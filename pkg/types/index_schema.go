@@ -0,0 +1,49 @@
+package types
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// IndexSchemaVersion is the version of the index schema this registry-cli reads and writes. It's
+// bumped when the indexes gain fields older versions would misinterpret, not for fields they can
+// safely ignore.
+const IndexSchemaVersion = 1
+
+// ErrUnsupportedSchema is returned when reading an index that needs a newer registry-cli.
+var ErrUnsupportedSchema = errors.New("the index needs a newer version of registry-cli")
+
+// IndexSchema records what's needed to interpret an index, in the index itself.
+type IndexSchema struct {
+	// SchemaVersion is the minimum version of the index schema a reader must support to
+	// interpret the index, 0 for indexes written before it was recorded
+	SchemaVersion int `json:"schema_version,omitempty"`
+}
+
+// Check returns ErrUnsupportedSchema when the index needs a newer schema than this registry-cli
+// supports.
+func (s IndexSchema) Check() error {
+	if s.SchemaVersion <= IndexSchemaVersion {
+		return nil
+	}
+	return fmt.Errorf(
+		"%w: it has schema version %d and this registry-cli supports up to %d, upgrade registry-cli to use it",
+		ErrUnsupportedSchema,
+		s.SchemaVersion,
+		IndexSchemaVersion,
+	)
+}
+
+// DecodeIndex decodes an index into v, checking its schema version first so an index written
+// by a newer registry-cli fails with ErrUnsupportedSchema instead of being misread.
+func DecodeIndex(b []byte, v any) error {
+	var schema IndexSchema
+	if err := json.Unmarshal(b, &schema); err != nil {
+		return err
+	}
+	if err := schema.Check(); err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
@@ -0,0 +1,67 @@
+package types
+
+import (
+	"slices"
+	"time"
+)
+
+// TrustedKeysPath is the bucket path of the registry's trusted keys, at the root of the bucket
+// next to the registry index.
+const TrustedKeysPath = "keys.json"
+
+// TrustedKeys lists the keys the registry's files are signed with, for clients to follow
+// rotations of the signing key. Each key is endorsed by the key it replaced, so clients pinning
+// a previous key come to trust its successor, until the previous key retires at the end of the
+// grace period of the rotation. The list is signed with the current key.
+type TrustedKeys struct {
+	// Updated is when the keys were last rotated
+	Updated time.Time `json:"updated"`
+
+	Keys []TrustedKey `json:"keys"`
+}
+
+// TrustedKey is a signing key of the registry.
+type TrustedKey struct {
+	// ID is the ID of the key, as in its signatures
+	ID string `json:"id"`
+
+	// Key is the public key in the minisign encoding
+	Key string `json:"key"`
+
+	// Added is when the registry started signing with the key
+	Added time.Time `json:"added"`
+
+	// Retires is when the key stops being trusted, at the end of the grace period of the
+	// rotation that replaced it
+	Retires *time.Time `json:"retires,omitempty"`
+
+	// EndorsedBy is the ID of the key this one replaced
+	EndorsedBy string `json:"endorsed_by,omitempty"`
+
+	// Endorsement is the signature of Key by the key it replaced
+	Endorsement string `json:"endorsement,omitempty"`
+}
+
+// Retired reports whether the key is no longer trusted at the given time.
+func (k TrustedKey) Retired(at time.Time) bool {
+	return k.Retires != nil && !at.Before(*k.Retires)
+}
+
+// Find returns the key with the ID.
+func (t TrustedKeys) Find(id string) (TrustedKey, bool) {
+	idx := slices.IndexFunc(t.Keys, func(k TrustedKey) bool { return k.ID == id })
+	if idx == -1 {
+		return TrustedKey{}, false
+	}
+	return t.Keys[idx], true
+}
+
+// Set adds the key, replacing any key with the same ID.
+func (t *TrustedKeys) Set(key TrustedKey) {
+	idx := slices.IndexFunc(t.Keys, func(k TrustedKey) bool { return k.ID == key.ID })
+	if idx == -1 {
+		t.Keys = append(t.Keys, key)
+		return
+	}
+	t.Keys[idx] = key
+}
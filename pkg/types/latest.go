@@ -0,0 +1,54 @@
+package types
+
+import (
+	"fmt"
+	"time"
+)
+
+// LatestVersion is a small pointer to the latest version of a plugin, stored next to the
+// plugin index so update checkers can poll it without fetching the metadata of every version.
+type LatestVersion struct {
+	// ID is the plugin ID
+	ID string `json:"id"`
+
+	// Version is the latest version of the plugin
+	Version string `json:"version"`
+
+	// Checksums maps each architecture to the checksum of its tarball
+	Checksums map[string]string `json:"checksums"`
+
+	// DownloadURLs maps each architecture to the url of its tarball
+	DownloadURLs map[string]string `json:"download_urls"`
+
+	// Updated is when the latest version was last updated
+	Updated time.Time `json:"updated"`
+
+	// Channel is the release channel the pointer follows, empty for the latest version
+	Channel string `json:"channel,omitempty"`
+}
+
+// NewLatestVersion creates the latest version pointer for the plugin index.
+func NewLatestVersion(index PluginIndex) LatestVersion {
+	return newVersionPointer(index.ID, index.LatestVersion)
+}
+
+func newVersionPointer(plugin string, version PluginVersionInformation) LatestVersion {
+	pointer := LatestVersion{
+		ID:           plugin,
+		Version:      version.Version,
+		Checksums:    make(map[string]string, len(version.Architectures)),
+		DownloadURLs: make(map[string]string, len(version.Architectures)),
+		Updated:      version.Updated,
+	}
+	for arch, info := range version.Architectures {
+		pointer.Checksums[arch] = info.Checksum
+		pointer.DownloadURLs[arch] = info.DownloadURL
+	}
+	return pointer
+}
+
+// LatestVersionPath gets the bucket path for the latest version pointer of a plugin. It's the
+// same whatever the layout of the registry.
+func LatestVersionPath(plugin string) string {
+	return fmt.Sprintf("%s/latest.json", plugin)
+}
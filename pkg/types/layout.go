@@ -0,0 +1,189 @@
+package types
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+)
+
+const (
+	// DefaultArtifactLayout is the default object key of a release tarball
+	DefaultArtifactLayout = "{{.Plugin}}/{{.Version}}/{{.OS}}-{{.Arch}}.tar.gz"
+
+	// DefaultIndexLayout is the default object key of a plugin index
+	DefaultIndexLayout = "{{.Plugin}}/index.json"
+
+	// LayoutPath is the bucket path of the layout of a registry that doesn't use the default
+	// one, which clients read to find its plugin indexes
+	LayoutPath = "layout.json"
+)
+
+// Layout is the object key layout of a registry's bucket, as Go templates. Artifact templates
+// are given the Plugin, Version, OS, Arch and Date (when the release was made, a time.Time, e.g.
+// {{.Date.Format "2006/01"}}) of a release. Index templates are given the Plugin. The zero
+// Layout is the default one, others are made with ParseLayout.
+//
+// The pointers and badge of a plugin aren't part of the layout, their keys are fixed so clients
+// that only check for updates don't need it (see LatestVersionPath, ChannelPath and
+// VersionBadgePath).
+type Layout struct {
+	Artifact string `json:"artifact"`
+	Index    string `json:"index"`
+
+	artifact *template.Template
+	index    *template.Template
+}
+
+// layoutVars are the values the layout templates are rendered with
+type layoutVars struct {
+	Plugin  string
+	Version string
+	OS      string
+	Arch    string
+	Date    time.Time
+}
+
+var (
+	defaultArtifactTemplate = template.Must(parseLayout("artifact", DefaultArtifactLayout))
+	defaultIndexTemplate    = template.Must(parseLayout("index", DefaultIndexLayout))
+)
+
+// ParseLayout checks the templates of the layout, using the default for any left empty. The
+// templates must give each release and plugin a key of their own.
+func ParseLayout(layout Layout) (Layout, error) {
+	layout = layout.withDefaults()
+
+	artifact, err := parseLayout("artifact", layout.Artifact)
+	if err != nil {
+		return Layout{}, err
+	}
+	index, err := parseLayout("index", layout.Index)
+	if err != nil {
+		return Layout{}, err
+	}
+
+	// every field of the key needs to be part of it, else releases overwrite each other
+	sample := layoutVars{Plugin: "plugin", Version: "1.0.0", OS: "linux", Arch: "amd64"}
+	variants := map[string]layoutVars{
+		"Plugin":  {Plugin: "other", Version: "1.0.0", OS: "linux", Arch: "amd64"},
+		"Version": {Plugin: "plugin", Version: "2.0.0", OS: "linux", Arch: "amd64"},
+		"OS":      {Plugin: "plugin", Version: "1.0.0", OS: "darwin", Arch: "amd64"},
+		"Arch":    {Plugin: "plugin", Version: "1.0.0", OS: "linux", Arch: "arm64"},
+	}
+
+	key, err := renderLayout(artifact, sample)
+	if err != nil {
+		return Layout{}, err
+	}
+	for field, variant := range variants {
+		other, err := renderLayout(artifact, variant)
+		if err != nil {
+			return Layout{}, err
+		}
+		if other == key {
+			return Layout{}, fmt.Errorf("artifact layout %q doesn't use {{.%s}}", layout.Artifact, field)
+		}
+	}
+	key, err = renderLayout(index, sample)
+	if err != nil {
+		return Layout{}, err
+	}
+	if other, err := renderLayout(index, variants["Plugin"]); err != nil {
+		return Layout{}, err
+	} else if other == key {
+		return Layout{}, fmt.Errorf("index layout %q doesn't use {{.Plugin}}", layout.Index)
+	}
+
+	layout.artifact = artifact
+	layout.index = index
+	return layout, nil
+}
+
+// IsDefault reports whether the layout is the default one.
+func (l Layout) IsDefault() bool {
+	return l.Equal(Layout{})
+}
+
+// Equal reports whether both layouts give the same templates, an empty template being the
+// default one.
+func (l Layout) Equal(other Layout) bool {
+	l, other = l.withDefaults(), other.withDefaults()
+	return l.Artifact == other.Artifact && l.Index == other.Index
+}
+
+// ArtifactPath returns the path in the bucket to the tarball of the release.
+func (l Layout) ArtifactPath(r Release) string {
+	date := r.Created
+	if date.IsZero() {
+		date = time.Now()
+	}
+	key, err := renderLayout(l.parsed().artifact, layoutVars{
+		Plugin:  r.Plugin,
+		Version: r.Version,
+		OS:      r.OS,
+		Arch:    NormalizeArch(r.Arch),
+		Date:    date.UTC(),
+	})
+	if err != nil {
+		// the layout is checked when it's parsed
+		return fmt.Sprintf("%s/%s/%s-%s.tar.gz", r.Plugin, r.Version, r.OS, NormalizeArch(r.Arch))
+	}
+	return key
+}
+
+// IndexPath returns the path in the bucket to the index of a plugin.
+func (l Layout) IndexPath(plugin string) string {
+	key, err := renderLayout(l.parsed().index, layoutVars{Plugin: plugin})
+	if err != nil {
+		// the layout is checked when it's parsed
+		return fmt.Sprintf("%s/index.json", plugin)
+	}
+	return key
+}
+
+// parsed returns the layout with its templates, for layouts that weren't made with ParseLayout,
+// falling back to the default templates when they're invalid
+func (l Layout) parsed() Layout {
+	if l.artifact != nil && l.index != nil {
+		return l
+	}
+	if l.IsDefault() {
+		return Layout{artifact: defaultArtifactTemplate, index: defaultIndexTemplate}
+	}
+	parsed, err := ParseLayout(l)
+	if err != nil {
+		return Layout{artifact: defaultArtifactTemplate, index: defaultIndexTemplate}
+	}
+	return parsed
+}
+
+func (l Layout) withDefaults() Layout {
+	if l.Artifact == "" {
+		l.Artifact = DefaultArtifactLayout
+	}
+	if l.Index == "" {
+		l.Index = DefaultIndexLayout
+	}
+	return l
+}
+
+func parseLayout(name, layout string) (*template.Template, error) {
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(layout)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s layout %q: %w", name, layout, err)
+	}
+	return tmpl, nil
+}
+
+func renderLayout(tmpl *template.Template, vars layoutVars) (string, error) {
+	var b strings.Builder
+	if err := tmpl.Execute(&b, vars); err != nil {
+		return "", fmt.Errorf("invalid %s layout: %w", tmpl.Name(), err)
+	}
+	key := strings.TrimPrefix(b.String(), "/")
+	if key == "" || strings.Contains(key, "//") || strings.HasSuffix(key, "/") {
+		return "", fmt.Errorf("invalid %s layout: renders an invalid key %q", tmpl.Name(), key)
+	}
+	return key, nil
+}
@@ -0,0 +1,67 @@
+package types
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseLayout(t *testing.T) {
+	tests := []struct {
+		name    string
+		layout  Layout
+		wantErr bool
+	}{
+		{name: "default"},
+		{
+			name:   "dated",
+			layout: Layout{Artifact: `{{.Date.Format "2006"}}/{{.Plugin}}/{{.Version}}/{{.OS}}-{{.Arch}}`},
+		},
+		{
+			name:    "without the version",
+			layout:  Layout{Artifact: "{{.Plugin}}/{{.OS}}-{{.Arch}}.tar.gz"},
+			wantErr: true,
+		},
+		{name: "without the plugin", layout: Layout{Index: "index.json"}, wantErr: true},
+		{name: "invalid template", layout: Layout{Index: "{{.Plugin"}, wantErr: true},
+		{name: "unknown field", layout: Layout{Index: "{{.Plugin}}/{{.Name}}.json"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseLayout(tt.layout)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got %v, want an error: %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLayoutPaths(t *testing.T) {
+	release := Release{
+		Plugin:  "demo",
+		Version: "1.0.0",
+		OS:      "linux",
+		Arch:    "x86_64",
+		Created: time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC),
+	}
+	if got := (Layout{}).ArtifactPath(release); got != "demo/1.0.0/linux-amd64.tar.gz" {
+		t.Errorf("default artifact path %s", got)
+	}
+	if got := (Layout{}).IndexPath("demo"); got != "demo/index.json" {
+		t.Errorf("default index path %s", got)
+	}
+
+	// a layout decoded from layout.json is used without being parsed first
+	layout := Layout{
+		Artifact: `{{.Date.Format "2006/01"}}/{{.Plugin}}-{{.Version}}-{{.OS}}-{{.Arch}}.tgz`,
+		Index:    "indexes/{{.Plugin}}.json",
+	}
+	if got := layout.ArtifactPath(release); got != "2026/03/demo-1.0.0-linux-amd64.tgz" {
+		t.Errorf("artifact path %s", got)
+	}
+	if got := layout.IndexPath("demo"); got != "indexes/demo.json" {
+		t.Errorf("index path %s", got)
+	}
+	if layout.IsDefault() || !(Layout{Index: DefaultIndexLayout}).IsDefault() {
+		t.Error("an empty template isn't the default one")
+	}
+}
@@ -0,0 +1,145 @@
+package types
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// MaintainersPath is the bucket path of the maintainer verification records, at the root of the
+// bucket next to the registry index.
+const MaintainersPath = "maintainers.json"
+
+// VerificationMethod is how a maintainer proves their identity.
+type VerificationMethod string
+
+const (
+	// VerifyDNS checks a TXT record on the domain of the maintainer's email address
+	VerifyDNS VerificationMethod = "dns"
+
+	// VerifyGitHub checks a public gist of the maintainer's GitHub account
+	VerifyGitHub VerificationMethod = "github"
+
+	// VerifyEmail checks the challenge emailed to the maintainer, sent back to the operator
+	VerifyEmail VerificationMethod = "email"
+)
+
+// VerificationMethods are the ways a maintainer can be verified.
+var VerificationMethods = []VerificationMethod{VerifyDNS, VerifyGitHub, VerifyEmail}
+
+// ParseVerificationMethod parses a verification method, one of VerificationMethods.
+func ParseVerificationMethod(method string) (VerificationMethod, error) {
+	m := VerificationMethod(strings.ToLower(method))
+	if !slices.Contains(VerificationMethods, m) {
+		return "", fmt.Errorf(
+			"unknown verification method %q, expected one of dns, github or email",
+			method,
+		)
+	}
+	return m, nil
+}
+
+// MaintainerStatus is where a maintainer is in the verification.
+type MaintainerStatus string
+
+const (
+	// MaintainerPending maintainers have been issued a challenge they haven't met yet
+	MaintainerPending MaintainerStatus = "pending"
+
+	// MaintainerVerified maintainers have met their challenge
+	MaintainerVerified MaintainerStatus = "verified"
+)
+
+// ChallengePrefix prefixes the challenge in the TXT record or gist description proving a
+// maintainer's identity.
+const ChallengePrefix = "omniview-verify="
+
+// DNSChallengeName returns the name of the TXT record holding the challenge for a domain.
+func DNSChallengeName(domain string) string {
+	return "_omniview-verify." + domain
+}
+
+// MaintainerRecord is the verification record of a maintainer.
+type MaintainerRecord struct {
+	// Email is the email address the maintainer is listed under in plugin metadata
+	Email string `json:"email"`
+
+	// Method is how the maintainer is verified
+	Method VerificationMethod `json:"method"`
+
+	// Subject is what the maintainer proved control of: the domain for dns, the account for
+	// github and the address for email
+	Subject string `json:"subject"`
+
+	Status MaintainerStatus `json:"status"`
+
+	// Challenge is the SHA-256 of the pending challenge. The challenge itself is only given to
+	// the maintainer, as the records are public.
+	Challenge string `json:"challenge,omitempty"`
+
+	// Requested is when the challenge was issued
+	Requested time.Time `json:"requested"`
+
+	// Verified is when the challenge was met
+	Verified *time.Time `json:"verified,omitempty"`
+
+	// VerifiedBy is who recorded the verification
+	VerifiedBy string `json:"verified_by,omitempty"`
+}
+
+// MatchesChallenge reports whether the challenge is the pending one.
+func (r MaintainerRecord) MatchesChallenge(challenge string) bool {
+	return r.Challenge != "" && HashChallenge(challenge) == r.Challenge
+}
+
+// HashChallenge hashes a challenge the way it's recorded.
+func HashChallenge(challenge string) string {
+	sum := sha256.Sum256([]byte(strings.TrimSpace(challenge)))
+	return hex.EncodeToString(sum[:])
+}
+
+// MaintainerIndex holds the verification records of the registry's maintainers, for the
+// marketplace to show which maintainers are verified.
+type MaintainerIndex struct {
+	Updated     time.Time          `json:"updated"`
+	Maintainers []MaintainerRecord `json:"maintainers"`
+}
+
+// Find returns the record of the maintainer with the email address, if any.
+func (i MaintainerIndex) Find(email string) (MaintainerRecord, bool) {
+	idx := slices.IndexFunc(i.Maintainers, func(r MaintainerRecord) bool {
+		return strings.EqualFold(r.Email, email)
+	})
+	if idx == -1 {
+		return MaintainerRecord{}, false
+	}
+	return i.Maintainers[idx], true
+}
+
+// IsVerified reports whether the maintainer with the email address is verified.
+func (i MaintainerIndex) IsVerified(email string) bool {
+	record, ok := i.Find(email)
+	return ok && record.Status == MaintainerVerified
+}
+
+// Set adds or replaces the record of a maintainer, keeping the records sorted by email.
+func (i *MaintainerIndex) Set(record MaintainerRecord) {
+	i.Remove(record.Email)
+	i.Maintainers = append(i.Maintainers, record)
+	slices.SortFunc(i.Maintainers, func(a, b MaintainerRecord) int {
+		return strings.Compare(strings.ToLower(a.Email), strings.ToLower(b.Email))
+	})
+}
+
+// Remove removes the record of the maintainer with the email address, reporting whether
+// there was one.
+func (i *MaintainerIndex) Remove(email string) bool {
+	before := len(i.Maintainers)
+	i.Maintainers = slices.DeleteFunc(i.Maintainers, func(r MaintainerRecord) bool {
+		return strings.EqualFold(r.Email, email)
+	})
+	return len(i.Maintainers) != before
+}
@@ -1,10 +1,18 @@
 package types
 
 import (
+	"errors"
+	"fmt"
 	"io"
+	"maps"
 	"os"
+	"regexp"
 	"slices"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/Masterminds/semver/v3"
 	"gopkg.in/yaml.v3"
 )
 
@@ -17,21 +25,153 @@ const (
 	PluginMetaFormatJSON
 )
 
-// PluginMeta is the plugin description file located at the root of a plugin.
+// PluginMeta is the plugin description file located at the root of a plugin. It's both what
+// plugin.yaml describes the plugin with and what the index records for each version, so the
+// index always matches what was packaged.
 type PluginMeta struct {
-	ID           string             `json:"id"           yaml:"id"`
-	Version      string             `json:"version"      yaml:"version"`
-	Name         string             `json:"name"         yaml:"name"`
-	Icon         string             `json:"icon"         yaml:"icon"`
-	Description  string             `json:"description"  yaml:"description"`
-	Repository   string             `json:"repository"   yaml:"repository"`
-	Website      string             `json:"website"      yaml:"website"`
-	Markdown     string             `json:"-"            yaml:"-"`
-	Maintainers  []PluginMaintainer `json:"maintainers"  yaml:"maintainers"`
-	Tags         []string           `json:"tags"         yaml:"tags"`
-	Dependencies []string           `json:"dependencies" yaml:"dependencies"`
-	Capabilities []string           `json:"capabilities" yaml:"capabilities"`
-	Theme        PluginTheme        `json:"theme"        yaml:"theme"`
+	ID           string             `json:"id"                     yaml:"id"                     schema:"required"`
+	Version      string             `json:"version"                yaml:"version"                schema:"required"`
+	Name         string             `json:"name"                   yaml:"name"                   schema:"required"`
+	Icon         string             `json:"icon"                   yaml:"icon"`
+	Description  string             `json:"description"            yaml:"description"            schema:"required"`
+	Repository   string             `json:"repository"             yaml:"repository"             schema:"required"`
+	Website      string             `json:"website"                yaml:"website"                schema:"required"`
+	Markdown     string             `json:"-"                      yaml:"-"`
+	Maintainers  []PluginMaintainer `json:"maintainers"            yaml:"maintainers"            schema:"required"`
+	Tags         []string           `json:"tags"                   yaml:"tags,omitempty"`
+	Dependencies any                `json:"dependencies,omitempty" yaml:"dependencies,omitempty"`
+	Pins         map[string]string  `json:"pins,omitempty"         yaml:"pins,omitempty"`
+	Capabilities []string           `json:"capabilities"           yaml:"capabilities"           schema:"required"`
+	Theme        *PluginTheme       `json:"theme,omitempty"        yaml:"theme,omitempty"`
+	Stamp        *Stamp             `json:"stamp,omitempty"        yaml:"stamp,omitempty"`
+}
+
+// Validate checks for required fields
+func (m *PluginMeta) Validate() error {
+	var missing []string
+
+	if m.ID == "" {
+		missing = append(missing, "id")
+	}
+	if m.Name == "" {
+		missing = append(missing, "name")
+	}
+	if m.Version == "" {
+		missing = append(missing, "version")
+	}
+	if m.Description == "" {
+		missing = append(missing, "description")
+	}
+	if m.Repository == "" {
+		missing = append(missing, "repository")
+	}
+	if m.Website == "" {
+		missing = append(missing, "website")
+	}
+	if len(m.Maintainers) == 0 {
+		missing = append(missing, "maintainers")
+	}
+	if len(m.Capabilities) == 0 {
+		missing = append(missing, "capabilities")
+	}
+
+	if len(missing) > 0 {
+		return fmt.Errorf("plugin.yaml is missing required fields: %v", missing)
+	}
+	requires, err := m.Requires()
+	if err != nil {
+		return err
+	}
+	if err := m.validatePins(requires); err != nil {
+		return err
+	}
+	return m.Theme.Validate()
+}
+
+// Requires returns the plugins the plugin depends on, mapped to the version constraint each
+// must satisfy (empty for any version). Dependencies are listed either as a map of plugin ids
+// to constraints, or as a list of plugin ids with an optional constraint after an @:
+//
+//	dependencies:
+//	  kubernetes: ">=0.2.0"
+//
+//	dependencies: [kubernetes@^0.2, helm]
+func (m *PluginMeta) Requires() (map[string]string, error) {
+	requires := make(map[string]string)
+	switch deps := m.Dependencies.(type) {
+	case nil:
+	case map[string]any:
+		for id, constraint := range deps {
+			switch constraint := constraint.(type) {
+			case nil:
+				requires[id] = ""
+			case string:
+				requires[id] = constraint
+			default:
+				return nil, fmt.Errorf(
+					"invalid dependency %s: the version constraint must be a string",
+					id,
+				)
+			}
+		}
+	case []any:
+		for _, dep := range deps {
+			dep, ok := dep.(string)
+			if !ok {
+				return nil, errors.New("invalid dependencies: expected a list of plugin ids")
+			}
+			id, constraint, _ := strings.Cut(dep, "@")
+			requires[id] = constraint
+		}
+	default:
+		return nil, errors.New("invalid dependencies: expected a map or a list of plugin ids")
+	}
+
+	for id, constraint := range requires {
+		if id == "" {
+			return nil, errors.New("invalid dependencies: dependency without a plugin id")
+		}
+		if constraint == "" {
+			continue
+		}
+		if _, err := semver.NewConstraint(constraint); err != nil {
+			return nil, fmt.Errorf("invalid version constraint for dependency %s: %w", id, err)
+		}
+	}
+	return requires, nil
+}
+
+// validatePins checks the pinned versions are versions, satisfying the constraints of the
+// dependencies they pin. Pins are only written into the packaged plugin.yaml, recording the
+// exact versions the dependencies (and theirs) resolved to when the plugin was packaged.
+func (m *PluginMeta) validatePins(requires map[string]string) error {
+	for _, id := range slices.Sorted(maps.Keys(m.Pins)) {
+		version, err := semver.NewVersion(m.Pins[id])
+		if err != nil {
+			return fmt.Errorf("invalid pinned version %q of %s: %w", m.Pins[id], id, err)
+		}
+		constraint, ok := requires[id]
+		if !ok || constraint == "" {
+			continue
+		}
+		if c, err := semver.NewConstraint(constraint); err == nil && !c.Check(version) {
+			return fmt.Errorf(
+				"dependency %s is pinned to %s, which doesn't satisfy %s",
+				id,
+				m.Pins[id],
+				constraint,
+			)
+		}
+	}
+	return nil
+}
+
+// SetVersion sets the version, leaving the version in plugin.yaml when empty
+func (m *PluginMeta) SetVersion(version string) {
+	if version == "" {
+		return
+	}
+	m.Version = version
 }
 
 // HasUICapabilities checks if the plugin has UI capabilities. This is used
@@ -64,11 +204,60 @@ type PluginMaintainer struct {
 }
 
 type PluginTheme struct {
-	Colors struct {
-		Primary   string `json:"primary"   yaml:"primary"`
-		Secondary string `json:"secondary" yaml:"secondary"`
-		Tertiary  string `json:"tertiary"  yaml:"tertiary"`
-	} `json:"colors" yaml:"colors"`
+	Colors map[string]string `json:"colors,omitempty" yaml:"colors,omitempty"`
+}
+
+var colorPattern = regexp.MustCompile(`^#([0-9a-fA-F]{3}|[0-9a-fA-F]{6}|[0-9a-fA-F]{8})$`)
+
+// ValidateColor checks the color is a hex color (#rgb, #rrggbb or #rrggbbaa).
+func ValidateColor(color string) error {
+	if !colorPattern.MatchString(color) {
+		return fmt.Errorf("%q is not a hex color (e.g. #1e90ff)", color)
+	}
+	return nil
+}
+
+// ParseColor returns the red, green and blue components of a hex color, ignoring its alpha.
+func ParseColor(color string) (r, g, b uint8, err error) {
+	if err := ValidateColor(color); err != nil {
+		return 0, 0, 0, err
+	}
+	hex := color[1:]
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	rgb, _ := strconv.ParseUint(hex[:6], 16, 32)
+	return uint8(rgb >> 16), uint8(rgb >> 8), uint8(rgb), nil
+}
+
+// Validate checks every theme color is a hex color. A nil theme is valid.
+func (t *PluginTheme) Validate() error {
+	if t == nil {
+		return nil
+	}
+	names := make([]string, 0, len(t.Colors))
+	for name := range t.Colors {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	var errs []error
+	for _, name := range names {
+		if strings.TrimSpace(name) == "" {
+			errs = append(errs, errors.New("theme color with an empty name"))
+			continue
+		}
+		if err := ValidateColor(t.Colors[name]); err != nil {
+			errs = append(errs, fmt.Errorf("theme color %s: %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Stamp is the build metadata stamped into the packaged plugin.yaml, never the source one.
+type Stamp struct {
+	Commit string    `json:"commit,omitempty" yaml:"commit,omitempty"`
+	Built  time.Time `json:"built"            yaml:"built"`
 }
 
 type PluginComponents struct {
@@ -94,19 +283,23 @@ func (c *PluginMeta) Load(reader io.Reader) error {
 	return yaml.NewDecoder(reader).Decode(c)
 }
 
-// LoadMetadata
-func LoadMetadata(path string) PluginMeta {
+// LoadMetadata loads the plugin metadata file at path, in YAML or JSON
+func LoadMetadata(path string) (PluginMeta, error) {
+	if path == "" {
+		return PluginMeta{}, errors.New("no plugin metadata file given")
+	}
 	file, err := os.Open(path)
 	if err != nil {
-		return PluginMeta{}
+		return PluginMeta{}, fmt.Errorf("couldn't open plugin metadata: %w", err)
 	}
+	defer file.Close()
 
 	meta := PluginMeta{}
 	if err := yaml.NewDecoder(file).Decode(&meta); err != nil {
-		return PluginMeta{}
+		return PluginMeta{}, fmt.Errorf("couldn't parse plugin metadata %s: %w", path, err)
 	}
 
-	return meta
+	return meta, nil
 }
 
 // LoadMarkdown loads a plugin Markdown file from a given path (if it exists)
@@ -0,0 +1,186 @@
+package types
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// String returns the name of the format.
+func (f PluginMetaFormat) String() string {
+	switch f {
+	case PluginMetaFormatJSON:
+		return "json"
+	default:
+		return "yaml"
+	}
+}
+
+// Ext returns the file extension used for the format.
+func (f PluginMetaFormat) Ext() string {
+	return "." + f.String()
+}
+
+// ParseMetaFormat parses a format name (yaml, yml or json).
+func ParseMetaFormat(name string) (PluginMetaFormat, error) {
+	switch strings.ToLower(name) {
+	case "yaml", "yml":
+		return PluginMetaFormatYAML, nil
+	case "json":
+		return PluginMetaFormatJSON, nil
+	default:
+		return 0, fmt.Errorf("unknown metadata format %q, expected yaml or json", name)
+	}
+}
+
+// MetaFormatFromPath determines the metadata format from a file's extension.
+func MetaFormatFromPath(path string) (PluginMetaFormat, error) {
+	return ParseMetaFormat(strings.TrimPrefix(filepath.Ext(path), "."))
+}
+
+// ConvertMetadata converts a plugin metadata document between formats. Field order is preserved,
+// as are comments when the output format supports them.
+func ConvertMetadata(data []byte, to PluginMetaFormat) ([]byte, error) {
+	// YAML is a superset of JSON, so a node tree can be built from either format while keeping
+	// the original key order (and comments, for YAML)
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse metadata: %w", err)
+	}
+	if doc.Kind == 0 {
+		return nil, fmt.Errorf("metadata document is empty")
+	}
+
+	switch to {
+	case PluginMetaFormatJSON:
+		var buf bytes.Buffer
+		if err := writeJSONNode(&buf, &doc, ""); err != nil {
+			return nil, err
+		}
+		buf.WriteByte('\n')
+		return buf.Bytes(), nil
+	default:
+		if len(doc.Content) > 0 && doc.Content[0].Style&yaml.FlowStyle != 0 {
+			// the source was JSON (or flow style YAML)
+			resetStyle(&doc)
+		}
+		var buf bytes.Buffer
+		enc := yaml.NewEncoder(&buf)
+		enc.SetIndent(2)
+		if err := enc.Encode(&doc); err != nil {
+			return nil, fmt.Errorf("failed to encode metadata as yaml: %w", err)
+		}
+		if err := enc.Close(); err != nil {
+			return nil, fmt.Errorf("failed to encode metadata as yaml: %w", err)
+		}
+		return buf.Bytes(), nil
+	}
+}
+
+// resetStyle clears flow and quoting styles carried over from JSON input so the YAML output
+// uses the idiomatic block style.
+func resetStyle(node *yaml.Node) {
+	switch node.Kind {
+	case yaml.MappingNode, yaml.SequenceNode:
+		node.Style &^= yaml.FlowStyle
+	case yaml.ScalarNode:
+		node.Style &^= yaml.DoubleQuotedStyle | yaml.SingleQuotedStyle
+	}
+	for _, child := range node.Content {
+		resetStyle(child)
+	}
+}
+
+// writeJSONNode writes the node tree as indented JSON, keeping mapping keys in document order.
+func writeJSONNode(buf *bytes.Buffer, node *yaml.Node, indent string) error {
+	inner := indent + "  "
+
+	switch node.Kind {
+	case yaml.DocumentNode:
+		if len(node.Content) == 0 {
+			buf.WriteString("null")
+			return nil
+		}
+		return writeJSONNode(buf, node.Content[0], indent)
+	case yaml.AliasNode:
+		return writeJSONNode(buf, node.Alias, indent)
+	case yaml.MappingNode:
+		if len(node.Content) == 0 {
+			buf.WriteString("{}")
+			return nil
+		}
+		buf.WriteString("{\n")
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, err := json.Marshal(node.Content[i].Value)
+			if err != nil {
+				return err
+			}
+			buf.WriteString(inner)
+			buf.Write(key)
+			buf.WriteString(": ")
+			if err := writeJSONNode(buf, node.Content[i+1], inner); err != nil {
+				return err
+			}
+			if i+2 < len(node.Content) {
+				buf.WriteByte(',')
+			}
+			buf.WriteByte('\n')
+		}
+		buf.WriteString(indent + "}")
+	case yaml.SequenceNode:
+		if len(node.Content) == 0 {
+			buf.WriteString("[]")
+			return nil
+		}
+		buf.WriteString("[\n")
+		for i, item := range node.Content {
+			buf.WriteString(inner)
+			if err := writeJSONNode(buf, item, inner); err != nil {
+				return err
+			}
+			if i+1 < len(node.Content) {
+				buf.WriteByte(',')
+			}
+			buf.WriteByte('\n')
+		}
+		buf.WriteString(indent + "]")
+	case yaml.ScalarNode:
+		return writeJSONScalar(buf, node)
+	default:
+		return fmt.Errorf("unsupported yaml node at line %d", node.Line)
+	}
+	return nil
+}
+
+func writeJSONScalar(buf *bytes.Buffer, node *yaml.Node) error {
+	switch node.ShortTag() {
+	case "!!null":
+		buf.WriteString("null")
+		return nil
+	case "!!bool":
+		var b bool
+		if err := node.Decode(&b); err != nil {
+			return err
+		}
+		buf.WriteString(strconv.FormatBool(b))
+		return nil
+	case "!!int", "!!float":
+		if json.Valid([]byte(node.Value)) {
+			buf.WriteString(node.Value)
+			return nil
+		}
+	}
+
+	// everything else (including numbers JSON can't represent) is written as a string
+	out, err := json.Marshal(node.Value)
+	if err != nil {
+		return err
+	}
+	buf.Write(out)
+	return nil
+}
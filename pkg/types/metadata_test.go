@@ -0,0 +1,55 @@
+package types
+
+import (
+	"reflect"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestRequires(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string
+		want    map[string]string
+		wantErr bool
+	}{
+		{name: "none", yaml: "id: a", want: map[string]string{}},
+		{
+			name: "map",
+			yaml: "dependencies:\n  kubernetes: \">=0.2.0\"\n  helm:\n",
+			want: map[string]string{"kubernetes": ">=0.2.0", "helm": ""},
+		},
+		{
+			name: "list",
+			yaml: "dependencies: [kubernetes@^0.2, helm]",
+			want: map[string]string{"kubernetes": "^0.2", "helm": ""},
+		},
+		{name: "invalid constraint", yaml: "dependencies: [kubernetes@nope]", wantErr: true},
+		{name: "constraint not a string", yaml: "dependencies:\n  helm: [1]", wantErr: true},
+		{name: "missing id", yaml: "dependencies: [\"@^1\"]", wantErr: true},
+		{name: "not a list of ids", yaml: "dependencies: [1, 2]", wantErr: true},
+		{name: "scalar", yaml: "dependencies: helm", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var meta PluginMeta
+			if err := yaml.Unmarshal([]byte(tt.yaml), &meta); err != nil {
+				t.Fatal(err)
+			}
+			requires, err := meta.Requires()
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got %v", requires)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(requires, tt.want) {
+				t.Fatalf("got %v, want %v", requires, tt.want)
+			}
+		})
+	}
+}
@@ -1,22 +1,76 @@
 package types
 
 import (
-	"fmt"
 	"time"
+
+	"github.com/Masterminds/semver/v3"
 )
 
 // PluginIndex is the file at the root of the plugin folder that exposes information about
 // what versions are available for a specific plugin and what architectures are supported.
 type PluginIndex struct {
+	IndexSchema
 	RegistryIndexPlugins
 
 	// Versions is the list of version available
 	Versions []PluginVersionInformation `json:"versions"`
+
+	// Tombstone is set on the index left in place of a removed plugin, which has no versions
+	Tombstone *Tombstone `json:"tombstone,omitempty"`
+}
+
+// SetVersion adds the version to the index, replacing any existing entries for it, and updates
+// the latest version.
+func (i *PluginIndex) SetVersion(version PluginVersionInformation) {
+	versions := make([]PluginVersionInformation, 0, len(i.Versions)+1)
+	for _, existing := range i.Versions {
+		if existing.Version != version.Version {
+			versions = append(versions, existing)
+		}
+	}
+	i.Versions = append(versions, version)
+	i.LatestVersion = i.Latest()
 }
 
-// BucketPath get's the bucket path for where the index should be located
-func (i PluginIndex) BucketPath() string {
-	return fmt.Sprintf("%s/index.json", i.ID)
+// Latest returns the highest version that hasn't been yanked, or the zero value if there is
+// none.
+func (i PluginIndex) Latest() PluginVersionInformation {
+	var latest PluginVersionInformation
+	for _, version := range i.Versions {
+		if version.Yanked {
+			continue
+		}
+		if latest.Version == "" || CompareVersions(version.Version, latest.Version) > 0 {
+			latest = version
+		}
+	}
+	return latest
+}
+
+// Resolve returns the highest version that hasn't been yanked satisfying the semver constraint,
+// or the latest version when the constraint is empty. False is returned when no version
+// satisfies it.
+func (i PluginIndex) Resolve(constraint string) (PluginVersionInformation, bool) {
+	if constraint == "" {
+		latest := i.Latest()
+		return latest, latest.Version != ""
+	}
+	c, err := semver.NewConstraint(constraint)
+	if err != nil {
+		return PluginVersionInformation{}, false
+	}
+
+	var resolved PluginVersionInformation
+	for _, version := range i.Versions {
+		v, err := semver.NewVersion(version.Version)
+		if version.Yanked || err != nil || !c.Check(v) {
+			continue
+		}
+		if resolved.Version == "" || CompareVersions(version.Version, resolved.Version) > 0 {
+			resolved = version
+		}
+	}
+	return resolved, resolved.Version != ""
 }
 
 type PluginVersionInformation struct {
@@ -34,6 +88,13 @@ type PluginVersionInformation struct {
 
 	// Updated
 	Updated time.Time `json:"updated"`
+
+	// Yanked versions stay downloadable, but are never picked as the latest version (see
+	// 'registry-cli yank')
+	Yanked bool `json:"yanked,omitempty"`
+
+	// Compatibility records the Omniview core versions the release was tested against
+	Compatibility Compatibility `json:"compatibility,omitempty"`
 }
 
 type PluginArchitectureInformation struct {
@@ -43,6 +104,14 @@ type PluginArchitectureInformation struct {
 	// DownloadURL is the url for which to download the tarball
 	DownloadURL string `json:"download_url"`
 
+	// ChecksumURL is the url of the tarball's sha256 checksum file, for clients verifying
+	// downloads against sidecar files
+	ChecksumURL string `json:"checksum_url,omitempty"`
+
 	// Size is the calculated size of the tarball in bytes
 	Size int64 `json:"size"`
+
+	// Encryption is the format the tarball is encrypted in (age) for private registries, which
+	// clients decrypt once it's verified. The checksum and size are of the encrypted tarball.
+	Encryption string `json:"encryption,omitempty"`
 }
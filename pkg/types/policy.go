@@ -0,0 +1,74 @@
+package types
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// PolicyPath is the bucket path of the registry retention policy
+const PolicyPath = "policy.json"
+
+// RetentionPolicy limits how many versions of each plugin the registry keeps. It's stored at
+// the root of the registry and enforced by `gc`. The zero value keeps everything.
+type RetentionPolicy struct {
+	// KeepVersions is the number of most recent versions of each plugin to keep. All versions
+	// are kept when zero.
+	KeepVersions int `json:"keep_versions,omitempty" yaml:"keep_versions,omitempty"`
+
+	// PrereleaseTTLDays is the number of days prerelease versions are kept for. Prereleases
+	// are kept indefinitely when zero.
+	PrereleaseTTLDays int `json:"prerelease_ttl_days,omitempty" yaml:"prerelease_ttl_days,omitempty"`
+}
+
+// IsZero returns true when the policy doesn't remove anything.
+func (p RetentionPolicy) IsZero() bool {
+	return p.KeepVersions <= 0 && p.PrereleaseTTLDays <= 0
+}
+
+// IsPrerelease returns true when the semantic version has a prerelease component.
+func IsPrerelease(version string) bool {
+	version, _, _ = strings.Cut(version, "+")
+	return strings.Contains(version, "-")
+}
+
+// Expired returns the versions of the index that the policy removes, mapped to the reason
+// they're removed. The latest version is always kept.
+func (p RetentionPolicy) Expired(index PluginIndex, now time.Time) map[string]string {
+	expired := make(map[string]string)
+	if p.IsZero() {
+		return expired
+	}
+
+	// newest first
+	versions := append([]PluginVersionInformation(nil), index.Versions...)
+	sort.SliceStable(versions, func(i, j int) bool {
+		return versions[i].Created.After(versions[j].Created)
+	})
+
+	ttl := time.Duration(p.PrereleaseTTLDays) * 24 * time.Hour
+	kept := 0
+	for _, version := range versions {
+		if version.Version == index.LatestVersion.Version {
+			kept++
+			continue
+		}
+		switch {
+		case p.PrereleaseTTLDays > 0 && IsPrerelease(version.Version) &&
+			now.Sub(version.Created) > ttl:
+			expired[version.Version] = fmt.Sprintf(
+				"prerelease older than %d days",
+				p.PrereleaseTTLDays,
+			)
+		case p.KeepVersions > 0 && kept >= p.KeepVersions:
+			expired[version.Version] = fmt.Sprintf(
+				"not one of the %d most recent versions",
+				p.KeepVersions,
+			)
+		default:
+			kept++
+		}
+	}
+	return expired
+}
@@ -1,10 +1,17 @@
 package types
 
+import "slices"
+
 // RegistryIndex is the file at the root of the plugin registry that exposes information about
 // what plugins are available, for what architectures, and what versions.
 type RegistryIndex struct {
+	IndexSchema
+
 	// Plugins lists the plugins available along with their metadata for viewing within omniview
 	Plugins []RegistryIndexPlugins `json:"plugins"`
+
+	// Tombstones lists the plugins removed from the registry, and why
+	Tombstones []Tombstone `json:"tombstones,omitempty"`
 }
 
 // RegistryIndexPlugins
@@ -15,4 +22,44 @@ type RegistryIndexPlugins struct {
 	Description   string                   `json:"description"`
 	Official      bool                     `json:"official"`
 	LatestVersion PluginVersionInformation `json:"latest_version"`
+
+	// Access lists the principals allowed to see and install the plugin through the proxy and
+	// presigned URLs. The plugin is public when it's empty.
+	Access []string `json:"access,omitempty"`
+}
+
+// Private reports whether the plugin is only available to the principals of its access list.
+func (p RegistryIndexPlugins) Private() bool {
+	return len(p.Access) > 0
+}
+
+// Allows reports whether the principal may see and install the plugin. Public plugins are
+// allowed to anyone, including the anonymous principal "".
+func (p RegistryIndexPlugins) Allows(principal string) bool {
+	return !p.Private() || (principal != "" && slices.Contains(p.Access, principal))
+}
+
+// SetPlugin adds or replaces the plugin's entry in the registry index, along with any
+// tombstone left by an earlier removal of the plugin.
+func (r *RegistryIndex) SetPlugin(plugin RegistryIndexPlugins) {
+	r.RemoveTombstone(plugin.ID)
+	for idx, existing := range r.Plugins {
+		if existing.ID == plugin.ID {
+			r.Plugins[idx] = plugin
+			return
+		}
+	}
+	r.Plugins = append(r.Plugins, plugin)
+}
+
+// RemovePlugin removes the plugin's entry from the registry index, returning false if it wasn't
+// listed.
+func (r *RegistryIndex) RemovePlugin(id string) bool {
+	for idx, existing := range r.Plugins {
+		if existing.ID == id {
+			r.Plugins = append(r.Plugins[:idx], r.Plugins[idx+1:]...)
+			return true
+		}
+	}
+	return false
 }
@@ -0,0 +1,56 @@
+package types
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/omniviewdev/registry-cli/pkg/schema"
+)
+
+// RegistryDocuments are the names of the documents RegistrySchema describes, in order.
+var RegistryDocuments = []string{"registry-index", "plugin-index", "latest-version", "artifact"}
+
+// RegistrySchema returns the JSON Schemas of the documents a registry serves, keyed by the names
+// in RegistryDocuments, for other clients to generate types from and validate responses with.
+// The descriptions give the bucket path of each, following the layout of the registry.
+func RegistrySchema(layout Layout) map[string]*schema.Schema {
+	layout = layout.withDefaults()
+	platforms := strings.Join(Platforms, ", ")
+
+	registry := schema.GenerateDocument(RegistryIndex{}, "Registry index")
+	registry.Description = "The plugins of the registry, at index.json."
+
+	plugin := schema.GenerateDocument(PluginIndex{}, "Plugin index")
+	plugin.Description = fmt.Sprintf(
+		"The versions of a plugin, at %s. The builds of each version are keyed by platform (%s). "+
+			"Readers must refuse indexes whose schema_version is over %d.",
+		layout.Index,
+		platforms,
+		IndexSchemaVersion,
+	)
+
+	latest := schema.GenerateDocument(LatestVersion{}, "Latest version pointer")
+	latest.Description = fmt.Sprintf(
+		"The latest version of a plugin, at %s, and the version each release channel (%s) "+
+			"resolves to, at %s.",
+		LatestVersionPath("{{.Plugin}}"),
+		strings.Join(Channels, ", "),
+		ChannelPath("{{.Plugin}}", "{{.Channel}}"),
+	)
+
+	artifact := schema.GenerateDocument(PluginArchitectureInformation{}, "Plugin build")
+	artifact.Description = fmt.Sprintf(
+		"A build of a version of a plugin for a platform, as listed by the indexes. Its tarball "+
+			"is at %s, with its sha256 checksum in a file of the same name ending in %s. "+
+			"Download URLs are relative to the registry, unless it's served from a base URL.",
+		layout.Artifact,
+		ChecksumExt,
+	)
+
+	return map[string]*schema.Schema{
+		"registry-index": registry,
+		"plugin-index":   plugin,
+		"latest-version": latest,
+		"artifact":       artifact,
+	}
+}
@@ -1,6 +1,16 @@
 package types
 
-import "fmt"
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"strings"
+	"time"
+)
 
 type Release struct {
 	Plugin  string
@@ -8,16 +18,63 @@ type Release struct {
 	OS      string
 	Arch    string
 	Path    string
+
+	// Artifact is the checksum and size of the tarball at Path, if already known
+	Artifact Artifact
+
+	// Created is when the release was made, for layouts with dates. Defaults to now.
+	Created time.Time
+}
+
+// Artifact is the sha256 checksum and size of a release tarball.
+type Artifact struct {
+	Checksum string
+	Size     int64
+
+	// Encryption is the format the tarball is encrypted in, when it's encrypted
+	Encryption string
 }
 
-// Returns the path in the bucket to the release
-func (r Release) BucketPath() string {
-	return fmt.Sprintf("%s/%s/%s-%s.tar.gz", r.Plugin, r.Version, r.OS, r.Arch)
+// ChecksumExt is appended to the path of a tarball to get the path of its sha256 checksum file.
+const ChecksumExt = ".sha256"
+
+// LoadArtifact reads the checksum packaging wrote alongside the tarball at path, in
+// <path>.sha256.
+func LoadArtifact(path string) (Artifact, bool) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return Artifact{}, false
+	}
+	b, err := os.ReadFile(path + ChecksumExt)
+	if err != nil {
+		return Artifact{}, false
+	}
+	fields := strings.Fields(string(b))
+	if len(fields) == 0 {
+		return Artifact{}, false
+	}
+	return Artifact{Checksum: strings.ToLower(fields[0]), Size: info.Size()}, true
 }
 
-// Returns the architecture key used for the index (amongst other things)
+// HashFile returns the sha256 checksum of a file.
+func HashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Returns the architecture key used for the index (amongst other things), with the
+// architecture normalized
 func (r Release) OSArch() string {
-	return fmt.Sprintf("%s_%s", r.OS, r.Arch)
+	return fmt.Sprintf("%s_%s", r.OS, NormalizeArch(r.Arch))
 }
 
 func (r Release) String() string {
@@ -51,6 +108,128 @@ type PublishOpts struct {
 
 	// Path to a linux/amd64 build
 	LinuxAMD64 string
+
+	// Compatibility records the Omniview core versions the release was tested against
+	Compatibility Compatibility
+
+	// Report, if set, collects the upload and index results of the publish
+	Report *PublishReport
+
+	// Created is when the releases were made, for layouts with dates. Defaults to now.
+	Created time.Time
+
+	// Artifacts are the checksums and sizes of the tarballs by platform (e.g. linux_amd64),
+	// when known, sparing the indexer from reading them again
+	Artifacts map[string]Artifact
+}
+
+// Platforms are the platforms a release can have builds for, by their architecture keys.
+var Platforms = []string{
+	"darwin_arm64",
+	"darwin_amd64",
+	"windows_arm64",
+	"windows_amd64",
+	"linux_arm64",
+	"linux_amd64",
+}
+
+// Validate checks the metadata file loads, there's at least one build to publish, and that
+// every build given exists.
+func (p PublishOpts) Validate() error {
+	var errs []error
+	if _, err := LoadMetadata(p.MetadataPath); err != nil {
+		errs = append(errs, err)
+	}
+
+	releases := p.ToReleases()
+	if len(releases) == 0 {
+		errs = append(errs, errors.New("no builds to publish, give the path to the build of at least one platform"))
+	}
+	for _, release := range releases {
+		info, err := os.Stat(release.Path)
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			errs = append(errs, fmt.Errorf("%s build %s doesn't exist", release.OSArch(), release.Path))
+		case err != nil:
+			errs = append(errs, fmt.Errorf("couldn't read %s build %s: %w", release.OSArch(), release.Path, err))
+		case info.IsDir():
+			errs = append(errs, fmt.Errorf("%s build %s is a directory", release.OSArch(), release.Path))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// SetPlatform sets the path of the tarball of a platform (e.g. linux_amd64, or linux-x86_64
+// as named by other build systems).
+func (p *PublishOpts) SetPlatform(platform, path string) error {
+	switch NormalizePlatform(platform) {
+	case "darwin_amd64":
+		p.DarwinAMD64 = path
+	case "darwin_arm64":
+		p.DarwinARM64 = path
+	case "windows_amd64":
+		p.WindowsAMD64 = path
+	case "windows_arm64":
+		p.WindowsARM64 = path
+	case "linux_amd64":
+		p.LinuxAMD64 = path
+	case "linux_arm64":
+		p.LinuxARM64 = path
+	default:
+		return fmt.Errorf("unsupported platform %q", platform)
+	}
+	return nil
+}
+
+// VerifyArtifacts checks the tarballs packaging wrote checksum files for still match them,
+// catching builds corrupted between packaging and publishing, and fills in their checksums and
+// sizes for the uploads to be checked against.
+func (p *PublishOpts) VerifyArtifacts() error {
+	var errs []error
+	for _, release := range p.ToReleases() {
+		if _, ok := p.artifact(release.OSArch()); ok {
+			continue
+		}
+		artifact, ok := LoadArtifact(release.Path)
+		if !ok {
+			continue
+		}
+		checksum, err := HashFile(release.Path)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("couldn't hash %s: %w", release.Path, err))
+			continue
+		}
+		if checksum != artifact.Checksum {
+			errs = append(errs, fmt.Errorf(
+				"checksum mismatch for %s: %s%s has %s, but it hashes to %s",
+				release.Path,
+				release.Path,
+				ChecksumExt,
+				artifact.Checksum,
+				checksum,
+			))
+			continue
+		}
+		if p.Artifacts == nil {
+			p.Artifacts = make(map[string]Artifact)
+		}
+		p.Artifacts[release.OSArch()] = artifact
+	}
+	return errors.Join(errs...)
+}
+
+// artifact returns the checksum and size of the tarball of a platform, when known. The
+// platforms of the artifacts may be named by other build systems.
+func (p PublishOpts) artifact(platform string) (Artifact, bool) {
+	if artifact, ok := p.Artifacts[platform]; ok {
+		return artifact, true
+	}
+	for key, artifact := range p.Artifacts {
+		if NormalizePlatform(key) == platform {
+			return artifact, true
+		}
+	}
+	return Artifact{}, false
 }
 
 func (p PublishOpts) ToReleases() []Release {
@@ -112,5 +291,13 @@ func (p PublishOpts) ToReleases() []Release {
 		})
 	}
 
+	created := p.Created
+	if created.IsZero() {
+		created = time.Now()
+	}
+	for idx := range releases {
+		releases[idx].Artifact, _ = p.artifact(releases[idx].OSArch())
+		releases[idx].Created = created
+	}
 	return releases
 }
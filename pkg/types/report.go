@@ -0,0 +1,175 @@
+package types
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"time"
+)
+
+// PublishReport is a machine-readable summary of a package and publish run, meant to be
+// archived as a CI artifact.
+type PublishReport struct {
+	// Plugin is the ID of the plugin that was published
+	Plugin string `json:"plugin"`
+
+	// Version is the version that was published
+	Version string `json:"version"`
+
+	// Started is when the run began
+	Started time.Time `json:"started"`
+
+	// Finished is when the run completed (successfully or not)
+	Finished time.Time `json:"finished"`
+
+	// Error holds the error that aborted the run, if any
+	Error string `json:"error,omitempty"`
+
+	// Build holds the build info injected into the binaries and UI bundle
+	Build *BuildInfo `json:"build,omitempty"`
+
+	// Platforms holds the per-platform results, keyed by os_arch
+	Platforms map[string]*PlatformReport `json:"platforms"`
+
+	// Index describes how the plugin index changed as a result of the publish
+	Index *IndexDiff `json:"index,omitempty"`
+
+	// Compatibility holds the outcome of the compatibility tests run after packaging
+	Compatibility Compatibility `json:"compatibility,omitempty"`
+
+	// Scan holds the findings of the vulnerability scans run while packaging
+	Scan *ScanReport `json:"scan,omitempty"`
+}
+
+// PlatformReport records the build and upload results for a single platform.
+type PlatformReport struct {
+	// BuildDurationMS is how long the platform build took in milliseconds
+	BuildDurationMS int64 `json:"build_duration_ms"`
+
+	// BuildLog is the local path to the build log
+	BuildLog string `json:"build_log,omitempty"`
+
+	// Artifact is the local path to the packaged tarball
+	Artifact string `json:"artifact,omitempty"`
+
+	// Size is the size of the tarball in bytes
+	Size int64 `json:"size,omitempty"`
+
+	// Checksum is the sha256 checksum of the tarball
+	Checksum string `json:"checksum,omitempty"`
+
+	// UploadedURL is the location the tarball was uploaded to
+	UploadedURL string `json:"uploaded_url,omitempty"`
+
+	// Error holds the build or upload error for this platform, if any
+	Error string `json:"error,omitempty"`
+}
+
+// BuildInfo is the build information injected into the plugin binaries and UI bundle.
+type BuildInfo struct {
+	PluginID string `json:"plugin_id"`
+	Version  string `json:"version"`
+	Commit   string `json:"commit,omitempty"`
+}
+
+// IndexDiff describes the change made to a plugin index by a publish.
+type IndexDiff struct {
+	// NewPlugin is true when the plugin did not exist in the registry before
+	NewPlugin bool `json:"new_plugin"`
+
+	// PreviousLatest is the latest version before the publish
+	PreviousLatest string `json:"previous_latest,omitempty"`
+
+	// Latest is the latest version after the publish
+	Latest string `json:"latest"`
+
+	// Architectures lists the architectures indexed for the published version
+	Architectures []string `json:"architectures"`
+
+	// ChangedFields lists the plugin level fields that changed (name, icon, description, access)
+	ChangedFields []string `json:"changed_fields,omitempty"`
+}
+
+// NewPublishReport creates an empty report with the start time set to now.
+func NewPublishReport() *PublishReport {
+	return &PublishReport{
+		Started:   time.Now(),
+		Platforms: make(map[string]*PlatformReport),
+	}
+}
+
+// Platform returns the report for the platform key, creating it if necessary.
+func (r *PublishReport) Platform(key string) *PlatformReport {
+	if r.Platforms == nil {
+		r.Platforms = make(map[string]*PlatformReport)
+	}
+	p, ok := r.Platforms[key]
+	if !ok {
+		p = &PlatformReport{}
+		r.Platforms[key] = p
+	}
+	return p
+}
+
+// Finish stamps the finish time and records the error that ended the run, if any.
+func (r *PublishReport) Finish(err error) {
+	r.Finished = time.Now()
+	if err != nil {
+		r.Error = err.Error()
+	}
+}
+
+// Write writes the report as indented JSON to the given path.
+func (r *PublishReport) Write(path string) error {
+	b, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal publish report: %w", err)
+	}
+	if err := os.WriteFile(path, b, 0644); err != nil {
+		return fmt.Errorf("failed to write publish report: %w", err)
+	}
+	return nil
+}
+
+// NewIndexDiff computes the difference between a plugin index before and after publishing
+// the version, which needn't be the latest, e.g. a backport.
+func NewIndexDiff(before, after PluginIndex, version string) *IndexDiff {
+	diff := &IndexDiff{
+		NewPlugin:      len(before.Versions) == 0,
+		PreviousLatest: before.LatestVersion.Version,
+		Latest:         after.LatestVersion.Version,
+		Architectures:  []string{},
+		ChangedFields:  ChangedFields(before, after),
+	}
+	idx := slices.IndexFunc(after.Versions, func(v PluginVersionInformation) bool {
+		return v.Version == version
+	})
+	if idx >= 0 {
+		for arch := range after.Versions[idx].Architectures {
+			diff.Architectures = append(diff.Architectures, arch)
+		}
+		sort.Strings(diff.Architectures)
+	}
+	return diff
+}
+
+// ChangedFields lists the plugin level fields that changed between the plugin indexes (name,
+// icon, description, access).
+func ChangedFields(before, after PluginIndex) []string {
+	var changed []string
+	if before.Name != after.Name {
+		changed = append(changed, "name")
+	}
+	if before.Icon != after.Icon {
+		changed = append(changed, "icon")
+	}
+	if before.Description != after.Description {
+		changed = append(changed, "description")
+	}
+	if !slices.Equal(before.Access, after.Access) {
+		changed = append(changed, "access")
+	}
+	return changed
+}
@@ -0,0 +1,83 @@
+package types
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestNewIndexDiff(t *testing.T) {
+	v1 := PluginVersionInformation{
+		Version:       "1.0.0",
+		Architectures: map[string]PluginArchitectureInformation{"linux_amd64": {}},
+	}
+	v2 := PluginVersionInformation{
+		Version: "2.0.0",
+		Architectures: map[string]PluginArchitectureInformation{
+			"darwin_arm64": {},
+			"linux_amd64":  {},
+		},
+	}
+	backport := PluginVersionInformation{
+		Version:       "1.0.1",
+		Architectures: map[string]PluginArchitectureInformation{"windows_amd64": {}},
+	}
+	index := func(name string, versions ...PluginVersionInformation) PluginIndex {
+		index := PluginIndex{Versions: versions}
+		index.Name = name
+		index.LatestVersion = index.Latest()
+		return index
+	}
+
+	tests := []struct {
+		name          string
+		before, after PluginIndex
+		version       string
+		want          IndexDiff
+	}{
+		{
+			name:    "new plugin",
+			after:   index("Demo", v1),
+			version: "1.0.0",
+			want: IndexDiff{
+				NewPlugin:     true,
+				Latest:        "1.0.0",
+				Architectures: []string{"linux_amd64"},
+				ChangedFields: []string{"name"},
+			},
+		},
+		{
+			name:    "new latest",
+			before:  index("Demo", v1),
+			after:   index("Demo", v1, v2),
+			version: "2.0.0",
+			want: IndexDiff{
+				PreviousLatest: "1.0.0",
+				Latest:         "2.0.0",
+				Architectures:  []string{"darwin_arm64", "linux_amd64"},
+			},
+		},
+		{
+			name:    "backport",
+			before:  index("Demo", v1, v2),
+			after:   index("Demo", v1, v2, backport),
+			version: "1.0.1",
+			want: IndexDiff{
+				PreviousLatest: "2.0.0",
+				Latest:         "2.0.0",
+				Architectures:  []string{"windows_amd64"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NewIndexDiff(tt.before, tt.after, tt.version)
+			if got.NewPlugin != tt.want.NewPlugin ||
+				got.PreviousLatest != tt.want.PreviousLatest ||
+				got.Latest != tt.want.Latest ||
+				!slices.Equal(got.Architectures, tt.want.Architectures) ||
+				!slices.Equal(got.ChangedFields, tt.want.ChangedFields) {
+				t.Fatalf("got %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+}
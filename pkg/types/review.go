@@ -0,0 +1,48 @@
+package types
+
+import (
+	"path"
+	"time"
+)
+
+const (
+	// PendingArtifactsPrefix prefixes the keys the builds of a moderated publish are uploaded to,
+	// until they're approved and moved to their final keys.
+	PendingArtifactsPrefix = "pending/artifacts/"
+
+	// PendingReleasesPrefix prefixes the keys of the releases awaiting review.
+	PendingReleasesPrefix = "pending/releases/"
+)
+
+// PendingArtifactPath returns the key a build is held at while its release awaits review, from
+// the key it's published to once approved.
+func PendingArtifactPath(bucketPath string) string {
+	return PendingArtifactsPrefix + bucketPath
+}
+
+// PendingReleasePath returns the key of a release awaiting review.
+func PendingReleasePath(plugin, version string) string {
+	return path.Join(PendingReleasesPrefix, plugin, version+".json")
+}
+
+// PendingRelease is a release of a moderated registry awaiting review. The builds are held in
+// the pending area, and the release is only added to the indexes once a registry admin
+// approves it.
+type PendingRelease struct {
+	Plugin string `json:"plugin"`
+
+	// Name, Icon and Description are taken from the metadata of the release, for the plugin index
+	Name        string `json:"name"`
+	Icon        string `json:"icon"`
+	Description string `json:"description"`
+
+	// Version is the entry the release gets in the plugin index once approved, with the download
+	// URLs of the builds at their final keys
+	Version PluginVersionInformation `json:"version"`
+
+	// Submitted is when the release was published for review
+	Submitted time.Time `json:"submitted"`
+
+	// SubmittedBy is who published the release
+	SubmittedBy string `json:"submitted_by,omitempty"`
+}
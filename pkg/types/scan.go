@@ -0,0 +1,78 @@
+package types
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Severity grades a vulnerability finding.
+type Severity string
+
+const (
+	SeverityLow      Severity = "low"
+	SeverityModerate Severity = "moderate"
+	SeverityHigh     Severity = "high"
+	SeverityCritical Severity = "critical"
+)
+
+// Severities are the severities of findings, least severe first.
+var Severities = []Severity{SeverityLow, SeverityModerate, SeverityHigh, SeverityCritical}
+
+// ParseSeverity parses a severity, one of Severities.
+func ParseSeverity(severity string) (Severity, error) {
+	s := Severity(strings.ToLower(severity))
+	if !slices.Contains(Severities, s) {
+		return "", fmt.Errorf(
+			"unknown severity %q, expected one of low, moderate, high or critical",
+			severity,
+		)
+	}
+	return s, nil
+}
+
+// AtLeast reports whether the severity is as severe as min, or more.
+func (s Severity) AtLeast(min Severity) bool {
+	return slices.Index(Severities, s) >= slices.Index(Severities, min)
+}
+
+// ScanReport holds the findings of the vulnerability scans run while packaging.
+type ScanReport struct {
+	// Scanners are the scanners that were run
+	Scanners []string `json:"scanners"`
+
+	Findings []ScanFinding `json:"findings"`
+}
+
+// ScanFinding is a known vulnerability found in the plugin or its dependencies.
+type ScanFinding struct {
+	// Scanner is the scanner that found it
+	Scanner string `json:"scanner"`
+
+	// ID is the advisory ID (e.g. GO-2024-1234 or a GHSA ID)
+	ID string `json:"id"`
+
+	// Package is the vulnerable module or package
+	Package string `json:"package"`
+
+	Severity Severity `json:"severity"`
+	Summary  string   `json:"summary,omitempty"`
+	URL      string   `json:"url,omitempty"`
+
+	// Fixed is the version (or range) fixing the vulnerability, if any
+	Fixed string `json:"fixed,omitempty"`
+}
+
+// AtLeast returns the findings as severe as min, or more.
+func (r *ScanReport) AtLeast(min Severity) []ScanFinding {
+	if r == nil {
+		return nil
+	}
+	var findings []ScanFinding
+	for _, finding := range r.Findings {
+		if finding.Severity.AtLeast(min) {
+			findings = append(findings, finding)
+		}
+	}
+	return findings
+}
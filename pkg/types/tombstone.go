@@ -0,0 +1,44 @@
+package types
+
+import (
+	"slices"
+	"strings"
+	"time"
+)
+
+// Tombstone records a plugin removed from the registry, so clients can tell a plugin that was
+// removed (or renamed) from one that never existed. It's listed in the registry index, and left
+// as the index of the removed plugin in place of its versions.
+type Tombstone struct {
+	ID string `json:"id"`
+
+	// Reason is why the plugin was removed
+	Reason string `json:"reason"`
+
+	// Successor is the ID of the plugin replacing it, when it was renamed (or superseded)
+	Successor string `json:"successor,omitempty"`
+
+	// Removed is when the plugin was removed
+	Removed time.Time `json:"removed"`
+
+	// RemovedBy is who removed the plugin
+	RemovedBy string `json:"removed_by,omitempty"`
+}
+
+// SetTombstone adds or replaces the tombstone of a plugin, keeping the tombstones sorted by ID.
+func (r *RegistryIndex) SetTombstone(tombstone Tombstone) {
+	r.RemoveTombstone(tombstone.ID)
+	r.Tombstones = append(r.Tombstones, tombstone)
+	slices.SortFunc(r.Tombstones, func(a, b Tombstone) int {
+		return strings.Compare(a.ID, b.ID)
+	})
+}
+
+// RemoveTombstone removes the tombstone of a plugin, returning false if it had none.
+func (r *RegistryIndex) RemoveTombstone(id string) bool {
+	before := len(r.Tombstones)
+	r.Tombstones = slices.DeleteFunc(r.Tombstones, func(t Tombstone) bool {
+		return t.ID == id
+	})
+	return len(r.Tombstones) != before
+}
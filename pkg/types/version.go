@@ -0,0 +1,25 @@
+package types
+
+import (
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+)
+
+// CompareVersions compares two version strings by semantic version precedence, returning -1,
+// 0 or 1. Versions that aren't valid semantic versions sort before valid ones, and are
+// compared as strings amongst themselves.
+func CompareVersions(a, b string) int {
+	va, errA := semver.NewVersion(a)
+	vb, errB := semver.NewVersion(b)
+	switch {
+	case errA == nil && errB == nil:
+		return va.Compare(vb)
+	case errA == nil:
+		return 1
+	case errB == nil:
+		return -1
+	default:
+		return strings.Compare(a, b)
+	}
+}
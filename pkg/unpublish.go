@@ -0,0 +1,240 @@
+package pkg
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/omniviewdev/registry-cli/pkg/types"
+)
+
+// undoHistoryDepth is how many snapshots are searched for the state before the last publish
+const undoHistoryDepth = 50
+
+// ErrVersionNotFound is returned when a plugin doesn't have the requested version
+var ErrVersionNotFound = errors.New("version not found")
+
+// Undo describes a publish that was undone (or would be, for a dry run).
+type Undo struct {
+	Plugin string
+
+	// RestoredFrom identifies the index state that was restored: a history snapshot ID or a
+	// revision version ID
+	RestoredFrom string
+
+	// Removed are the versions the publish added, which were removed
+	Removed []string
+
+	// Reverted are the versions the publish replaced, which were restored to their previous
+	// entries. Their artifacts were overwritten by the publish and can't be restored.
+	Reverted []string
+
+	// Artifacts are the bucket paths of the removed versions' tarballs, which were deleted
+	Artifacts []string
+}
+
+// Unpublish removes a version of a plugin from the indexes and deletes its artifacts. The
+// plugin is removed from the registry when it was its only version, leaving a tombstone in its
+// place.
+func (i *Indexer) Unpublish(ctx context.Context, plugin, version string, dryRun bool) (Removal, error) {
+	var removal Removal
+	err := i.withLock(ctx, func() error {
+		index, err := i.loadPluginIndex(ctx, plugin)
+		if err != nil {
+			return err
+		}
+
+		idx := slices.IndexFunc(index.Versions, func(v types.PluginVersionInformation) bool {
+			return v.Version == version
+		})
+		if idx == -1 {
+			return fmt.Errorf("%s %s: %w", plugin, version, ErrVersionNotFound)
+		}
+
+		removal = Removal{Plugin: plugin, Version: version, Reason: "unpublished"}
+		for _, arch := range sortedArchs(index.Versions[idx]) {
+			removal.Artifacts = append(
+				removal.Artifacts,
+				i.artifactKey(index.Versions[idx].Architectures[arch].DownloadURL),
+			)
+		}
+		if dryRun {
+			return nil
+		}
+
+		before := index
+		index.Versions = slices.Delete(slices.Clone(index.Versions), idx, idx+1)
+		index.LatestVersion = index.Latest()
+		if err := i.commitPluginIndex(ctx, "unpublish", before, index); err != nil {
+			return err
+		}
+		return i.deleteArtifacts(ctx, removal.Artifacts)
+	})
+	return removal, err
+}
+
+// UndoPublish reverts the most recent publish of a plugin: the index is restored to its state
+// before the publish, taken from the history (or the S3 object versions of the index when there
+// is no history), and the artifacts of the versions the publish added are deleted. It refuses to
+// undo a change that wasn't a publish.
+func (i *Indexer) UndoPublish(ctx context.Context, plugin string, dryRun bool) (*Undo, error) {
+	var undo *Undo
+	err := i.withLock(ctx, func() error {
+		current, err := i.loadPluginIndex(ctx, plugin)
+		if err != nil {
+			return err
+		}
+		if len(current.Versions) == 0 {
+			return fmt.Errorf("%s has no versions to undo", plugin)
+		}
+
+		previous, restoredFrom, err := i.previousPluginIndex(ctx, plugin, current)
+		if err != nil {
+			return err
+		}
+
+		added, updated, removed := versionChanges(previous, current)
+		if len(removed) > 0 || len(added)+len(updated) == 0 {
+			return fmt.Errorf(
+				"the last change to %s wasn't a publish (%s), restore it with 'registry-cli index rollback' instead",
+				plugin,
+				summarizeChange(previous, current),
+			)
+		}
+
+		undo = &Undo{
+			Plugin:       plugin,
+			RestoredFrom: restoredFrom,
+			Removed:      added,
+			Reverted:     updated,
+		}
+		for _, version := range current.Versions {
+			if !slices.Contains(added, version.Version) {
+				continue
+			}
+			for _, arch := range sortedArchs(version) {
+				undo.Artifacts = append(
+					undo.Artifacts,
+					i.artifactKey(version.Architectures[arch].DownloadURL),
+				)
+			}
+		}
+		if dryRun {
+			return nil
+		}
+
+		if err := i.commitPluginIndex(ctx, "undo publish", current, previous); err != nil {
+			return err
+		}
+		return i.deleteArtifacts(ctx, undo.Artifacts)
+	})
+	return undo, err
+}
+
+// previousPluginIndex finds the last state of the plugin's index that differs from the current
+// one, returning it along with the ID of the snapshot or revision it came from. The state is an
+// empty index when the plugin had been removed.
+func (i *Indexer) previousPluginIndex(
+	ctx context.Context,
+	plugin string,
+	current types.PluginIndex,
+) (types.PluginIndex, string, error) {
+	empty := types.PluginIndex{RegistryIndexPlugins: types.RegistryIndexPlugins{ID: plugin}}
+
+	snapshots, err := i.History(ctx, plugin, undoHistoryDepth)
+	if err != nil {
+		return types.PluginIndex{}, "", err
+	}
+	for _, listed := range snapshots {
+		snapshot, err := i.Snapshot(ctx, plugin, listed.ID)
+		if err != nil {
+			return types.PluginIndex{}, "", err
+		}
+		if len(snapshot.Index) == 0 {
+			// the plugin had been removed
+			return empty, snapshot.ID, nil
+		}
+		index, differs, err := differentIndex(snapshot.Index, current)
+		if err != nil {
+			return types.PluginIndex{}, "", fmt.Errorf("snapshot %s: %w", snapshot.ID, err)
+		}
+		if differs {
+			return index, snapshot.ID, nil
+		}
+	}
+	if len(snapshots) > 0 {
+		return types.PluginIndex{}, "", errNoPreviousState(plugin)
+	}
+
+	// no history, fall back to the revisions kept by S3
+	revisions, err := i.IndexRevisions(ctx, plugin)
+	if err != nil {
+		return types.PluginIndex{}, "", fmt.Errorf("no history of %s to undo from: %w", plugin, err)
+	}
+	path := i.historyIndexPath(plugin)
+	for _, revision := range revisions {
+		b, err := i.getRevision(ctx, path, revision.VersionID)
+		if err != nil {
+			return types.PluginIndex{}, "", err
+		}
+		index, differs, err := differentIndex(b, current)
+		if err != nil {
+			return types.PluginIndex{}, "", fmt.Errorf("revision %s: %w", revision.VersionID, err)
+		}
+		if differs {
+			return index, revision.VersionID, nil
+		}
+	}
+	return types.PluginIndex{}, "", errNoPreviousState(plugin)
+}
+
+// errNoPreviousState is returned when no earlier state of the plugin's index is known. That's
+// also the case for its very first publish, which isn't undone, as it can't be told apart from
+// history that was never recorded.
+func errNoPreviousState(plugin string) error {
+	return fmt.Errorf(
+		"couldn't find the state of %s before its last publish, if it was its first publish remove it with 'registry-cli unpublish %s <version>'",
+		plugin,
+		plugin,
+	)
+}
+
+// differentIndex decodes a plugin index, reporting whether its versions differ from current.
+func differentIndex(b []byte, current types.PluginIndex) (types.PluginIndex, bool, error) {
+	var index types.PluginIndex
+	if err := types.DecodeIndex(b, &index); err != nil {
+		return types.PluginIndex{}, false, fmt.Errorf("invalid plugin index: %w", err)
+	}
+
+	// compare through JSON, like the indexes were stored
+	encoded, err := json.Marshal(current)
+	if err != nil {
+		return types.PluginIndex{}, false, err
+	}
+	var stored types.PluginIndex
+	if err := json.Unmarshal(encoded, &stored); err != nil {
+		return types.PluginIndex{}, false, err
+	}
+
+	added, updated, removed := versionChanges(index, stored)
+	return index, len(added)+len(updated)+len(removed) > 0, nil
+}
+
+func (i *Indexer) deleteArtifacts(ctx context.Context, artifacts []string) error {
+	var errs []string
+	for _, artifact := range artifacts {
+		if err := i.delete(ctx, artifact); err != nil {
+			errs = append(errs, err.Error())
+		}
+		if err := i.delete(ctx, artifact+types.ChecksumExt); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("the indexes were updated, but some artifacts weren't deleted: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,179 @@
+package pkg
+
+import (
+	"errors"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/omniviewdev/registry-cli/pkg/types"
+)
+
+func TestUndoPublish(t *testing.T) {
+	// rebuild publishes a version again with different builds
+	rebuild := func(t *testing.T, version string) types.PublishOpts {
+		opts := testPublish(t, version)
+		for _, path := range []string{opts.LinuxAMD64, opts.DarwinARM64} {
+			if err := os.WriteFile(path, []byte("rebuilt "+version), 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return opts
+	}
+
+	tests := []struct {
+		name string
+		// changes are made to the registry in order, before the undo
+		changes      []func(t *testing.T, publisher *Publisher, indexer *Indexer) error
+		wantErr      string
+		wantRemoved  []string
+		wantReverted []string
+		wantVersions []string
+	}{
+		{
+			name: "publish",
+			changes: []func(*testing.T, *Publisher, *Indexer) error{
+				publishing("1.0.0"),
+				publishing("1.1.0"),
+			},
+			wantRemoved:  []string{"1.1.0"},
+			wantVersions: []string{"1.0.0"},
+		},
+		{
+			name: "republish",
+			changes: []func(*testing.T, *Publisher, *Indexer) error{
+				publishing("1.0.0"),
+				func(t *testing.T, publisher *Publisher, indexer *Indexer) error {
+					return PublishVersion(t.Context(), publisher, indexer, rebuild(t, "1.0.0"))
+				},
+			},
+			wantReverted: []string{"1.0.0"},
+			wantVersions: []string{"1.0.0"},
+		},
+		{
+			name:    "first publish",
+			changes: []func(*testing.T, *Publisher, *Indexer) error{publishing("1.0.0")},
+			wantErr: "if it was its first publish",
+		},
+		{
+			name: "unpublish",
+			changes: []func(*testing.T, *Publisher, *Indexer) error{
+				publishing("1.0.0"),
+				publishing("1.1.0"),
+				func(t *testing.T, _ *Publisher, indexer *Indexer) error {
+					_, err := indexer.Unpublish(t.Context(), "demo", "1.1.0", false)
+					return err
+				},
+			},
+			wantErr: "wasn't a publish (removed 1.1.0)",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objects := testFileStore(t)
+			publisher, indexer := testRegistry(objects, PartialFailureAbort)
+			for _, change := range tt.changes {
+				if err := change(t, publisher, indexer); err != nil {
+					t.Fatal(err)
+				}
+			}
+			before := pluginIndex(t, objects)
+
+			// a dry run changes nothing
+			dryRun, dryErr := indexer.UndoPublish(t.Context(), "demo", true)
+			if after := pluginIndex(t, objects); !slices.EqualFunc(
+				before.Versions,
+				after.Versions,
+				func(a, b types.PluginVersionInformation) bool { return a.Version == b.Version },
+			) {
+				t.Fatalf("a dry run changed the versions to %+v", after.Versions)
+			}
+
+			undo, err := indexer.UndoPublish(t.Context(), "demo", false)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("got %v, want an error containing %q", err, tt.wantErr)
+				}
+				if dryErr == nil {
+					t.Fatal("a dry run didn't refuse")
+				}
+				if got := pluginIndex(t, objects); len(got.Versions) != len(before.Versions) {
+					t.Fatalf("a refused undo left %+v", got.Versions)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if dryErr != nil || !slices.Equal(dryRun.Artifacts, undo.Artifacts) {
+				t.Fatalf("a dry run got %+v, %v, want %+v", dryRun, dryErr, undo)
+			}
+			if !slices.Equal(undo.Removed, tt.wantRemoved) ||
+				!slices.Equal(undo.Reverted, tt.wantReverted) {
+				t.Fatalf(
+					"removed %v and reverted %v, want %v and %v",
+					undo.Removed,
+					undo.Reverted,
+					tt.wantRemoved,
+					tt.wantReverted,
+				)
+			}
+
+			index := pluginIndex(t, objects)
+			var versions []string
+			for _, version := range index.Versions {
+				versions = append(versions, version.Version)
+			}
+			if !slices.Equal(versions, tt.wantVersions) {
+				t.Fatalf("left versions %v, want %v", versions, tt.wantVersions)
+			}
+			if index.LatestVersion.Version != tt.wantVersions[len(tt.wantVersions)-1] {
+				t.Fatalf("left %s as the latest version", index.LatestVersion.Version)
+			}
+
+			// only the artifacts of the removed versions are deleted
+			if len(undo.Artifacts) != 2*len(tt.wantRemoved) {
+				t.Fatalf("deleted %v", undo.Artifacts)
+			}
+			for _, artifact := range undo.Artifacts {
+				if _, err := objects.Head(t.Context(), artifact); !errors.Is(err, ErrObjectNotFound) {
+					t.Errorf("%s wasn't deleted: %v", artifact, err)
+				}
+			}
+			for _, version := range index.Versions {
+				for arch, info := range version.Architectures {
+					if _, err := objects.Head(t.Context(), indexer.artifactKey(info.DownloadURL)); err != nil {
+						t.Errorf("the %s build of %s was deleted: %v", arch, version.Version, err)
+					}
+				}
+			}
+		})
+	}
+
+	t.Run("republish restores the index", func(t *testing.T) {
+		objects := testFileStore(t)
+		publisher, indexer := testRegistry(objects, PartialFailureAbort)
+		if err := publishing("1.0.0")(t, publisher, indexer); err != nil {
+			t.Fatal(err)
+		}
+		published := pluginIndex(t, objects).Versions[0].Architectures["linux_amd64"].Checksum
+		if err := PublishVersion(t.Context(), publisher, indexer, rebuild(t, "1.0.0")); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := indexer.UndoPublish(t.Context(), "demo", false); err != nil {
+			t.Fatal(err)
+		}
+		restored := pluginIndex(t, objects).Versions[0].Architectures["linux_amd64"].Checksum
+		if restored != published {
+			t.Fatalf("restored checksum %s, want %s", restored, published)
+		}
+	})
+}
+
+// publishing returns a change of the registry publishing a version of the demo plugin.
+func publishing(version string) func(*testing.T, *Publisher, *Indexer) error {
+	return func(t *testing.T, publisher *Publisher, indexer *Indexer) error {
+		return PublishVersion(t.Context(), publisher, indexer, testPublish(t, version))
+	}
+}
@@ -0,0 +1,67 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// DefaultStaleUploadAge is how long a multipart upload is left incomplete before garbage
+// collection aborts it. Younger uploads may belong to a publish still running.
+const DefaultStaleUploadAge = 24 * time.Hour
+
+// abortTimeout is how long aborting the multipart upload of a failed publish may take
+const abortTimeout = 30 * time.Second
+
+// StaleUpload is an incomplete multipart upload aborted (or to be aborted) by garbage
+// collection, whose parts are billed for until it is.
+type StaleUpload struct {
+	Key       string
+	UploadID  string
+	Initiated time.Time
+
+	// Parts and Size are the number and total size of the parts uploaded
+	Parts int
+	Size  int64
+}
+
+// AbortStaleUploads aborts the multipart uploads of the bucket started more than olderThan ago
+// and never completed, left behind by publishes that were killed or lost their connection,
+// returning the uploads aborted (or that would be, for a dry run).
+func (i *Indexer) AbortStaleUploads(
+	ctx context.Context,
+	olderThan time.Duration,
+	dryRun bool,
+) ([]StaleUpload, error) {
+	uploads, err := storeFeature[multipartStore](i.objects, "aborting incomplete uploads")
+	if err != nil {
+		return nil, err
+	}
+	inProgress, err := uploads.Uploads(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't list the multipart uploads of %s: %v", i.bucket, err)
+	}
+
+	var stale []StaleUpload
+	cutoff := time.Now().Add(-olderThan)
+	for _, upload := range inProgress {
+		if upload.Initiated.After(cutoff) {
+			continue
+		}
+		upload.Parts, upload.Size, err = uploads.Parts(ctx, upload.Key, upload.UploadID)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't list the parts of the upload of %s: %v", upload.Key, err)
+		}
+		stale = append(stale, upload)
+	}
+	if dryRun {
+		return stale, nil
+	}
+
+	for _, upload := range stale {
+		if err := uploads.AbortUpload(ctx, upload.Key, upload.UploadID); err != nil {
+			return nil, fmt.Errorf("couldn't abort the upload of %s: %v", upload.Key, err)
+		}
+	}
+	return stale, nil
+}
@@ -0,0 +1,41 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/omniviewdev/registry-cli/pkg/types"
+)
+
+// Yank marks a version of a plugin as yanked, or as not yanked anymore. Yanked versions stay
+// downloadable for those pinning them, but are never picked as the latest version nor resolved
+// from a constraint. Yanking a version that already is changes nothing.
+func (i *Indexer) Yank(ctx context.Context, plugin, version string, yanked bool) error {
+	return i.withLock(ctx, func() error {
+		index, err := i.loadPluginIndex(ctx, plugin)
+		if err != nil {
+			return err
+		}
+
+		idx := slices.IndexFunc(index.Versions, func(v types.PluginVersionInformation) bool {
+			return v.Version == version
+		})
+		if idx == -1 {
+			return fmt.Errorf("%s %s: %w", plugin, version, ErrVersionNotFound)
+		}
+		if index.Versions[idx].Yanked == yanked {
+			return nil
+		}
+
+		before := index
+		index.Versions = slices.Clone(index.Versions)
+		index.Versions[idx].Yanked = yanked
+		index.LatestVersion = index.Latest()
+		action := "yank"
+		if !yanked {
+			action = "unyank"
+		}
+		return i.commitPluginIndex(ctx, action, before, index)
+	})
+}
@@ -0,0 +1,51 @@
+package pkg
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/omniviewdev/registry-cli/pkg/types"
+)
+
+func TestYank(t *testing.T) {
+	objects := newMemStore()
+	publisher, indexer := testRegistry(objects, PartialFailureAbort)
+	for _, version := range []string{"1.0.0", "1.1.0"} {
+		if err := PublishVersion(t.Context(), publisher, indexer, testPublish(t, version)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	latest := func() (string, string) {
+		var registry types.RegistryIndex
+		if err := json.Unmarshal(read(t, objects, "index.json"), &registry); err != nil {
+			t.Fatal(err)
+		}
+		return pluginIndex(t, objects).LatestVersion.Version,
+			registry.Plugins[0].LatestVersion.Version
+	}
+
+	steps := []struct {
+		version string
+		yanked  bool
+		latest  string
+	}{
+		{version: "1.1.0", yanked: true, latest: "1.0.0"},
+		{version: "1.1.0", yanked: true, latest: "1.0.0"},
+		{version: "1.1.0", yanked: false, latest: "1.1.0"},
+	}
+	for _, step := range steps {
+		if err := indexer.Yank(t.Context(), "demo", step.version, step.yanked); err != nil {
+			t.Fatal(err)
+		}
+		index, registry := latest()
+		if index != step.latest || registry != step.latest {
+			t.Fatalf("yanked=%v %s: latest %s in the plugin index, %s in the registry index, want %s",
+				step.yanked, step.version, index, registry, step.latest)
+		}
+	}
+
+	if err := indexer.Yank(t.Context(), "demo", "2.0.0", true); !errors.Is(err, ErrVersionNotFound) {
+		t.Fatalf("got %v, want %v", err, ErrVersionNotFound)
+	}
+}